@@ -0,0 +1,146 @@
+package ambex
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	v3clusterconfig "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+)
+
+// ClusterDrainMetadataKey is the filter_metadata namespace where diagd puts what ambex needs to
+// know about a cluster. Envoy ignores it. This has to agree with V3Cluster.AmbexMetadataKey in
+// python/ambassador/envoy/v3/v3cluster.py.
+const ClusterDrainMetadataKey = "getambassador.io"
+
+// ClusterDrainTime returns how long a cluster should stay in Envoy after it's gone from our
+// configuration, as set by the `cluster_drain_time_ms` of its Mappings (or the Ambassador
+// Module). Clusters that don't say get 0, meaning they go away at once.
+func ClusterDrainTime(cluster *v3clusterconfig.Cluster) time.Duration {
+	md := cluster.GetMetadata().GetFilterMetadata()[ClusterDrainMetadataKey]
+	ms := md.GetFields()["drain_time_ms"].GetNumberValue()
+	if ms <= 0 {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+type drainingCluster struct {
+	cluster *v3clusterconfig.Cluster
+	until   time.Time
+}
+
+// A ClusterDrainer keeps clusters that have dropped out of the configuration in the snapshots
+// we hand to Envoy until their drain time is up. Nothing routes to a draining cluster anymore,
+// so no new requests go to it, but Envoy doesn't tear down its connection pools out from under
+// the requests it's already sent there. A cluster that comes back while it's draining just
+// stops draining.
+//
+// A drain that's up only takes effect with the next snapshot, so whoever hands snapshots to
+// Envoy should also make a new one when Expiry fires.
+type ClusterDrainer struct {
+	// Now tells the drainer what time it is; tests can replace it.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	previous map[string]*v3clusterconfig.Cluster
+	draining map[string]drainingCluster
+	timer    *time.Timer
+	timerAt  time.Time
+}
+
+// NewClusterDrainer returns a ClusterDrainer with nothing draining.
+func NewClusterDrainer() *ClusterDrainer {
+	return &ClusterDrainer{
+		Now:      time.Now,
+		previous: map[string]*v3clusterconfig.Cluster{},
+		draining: map[string]drainingCluster{},
+	}
+}
+
+// Apply takes the clusters in a new configuration and returns them plus whatever removed
+// clusters are still draining. It must see every configuration, in order, since what's been
+// removed is worked out from the last one.
+func (d *ClusterDrainer) Apply(clusters []*v3clusterconfig.Cluster) []*v3clusterconfig.Cluster {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.Now()
+
+	current := map[string]*v3clusterconfig.Cluster{}
+	for _, cluster := range clusters {
+		current[cluster.Name] = cluster
+		delete(d.draining, cluster.Name)
+	}
+
+	for name, cluster := range d.previous {
+		if _, ok := current[name]; ok {
+			continue
+		}
+		if drainTime := ClusterDrainTime(cluster); drainTime > 0 {
+			d.draining[name] = drainingCluster{cluster: cluster, until: now.Add(drainTime)}
+		}
+	}
+	d.previous = current
+
+	result := append([]*v3clusterconfig.Cluster{}, clusters...)
+	for _, name := range d.drainingNames() {
+		dc := d.draining[name]
+		if !now.Before(dc.until) {
+			delete(d.draining, name)
+			continue
+		}
+		result = append(result, dc.cluster)
+	}
+	return result
+}
+
+// Draining returns the clusters that are draining, and when each is done.
+func (d *ClusterDrainer) Draining() map[string]time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := map[string]time.Time{}
+	for name, dc := range d.draining {
+		result[name] = dc.until
+	}
+	return result
+}
+
+// Expiry returns a channel that fires once the first of the draining clusters is done, or nil
+// (which never fires) if nothing is draining. As long as that's still the first to be done,
+// it's the same channel each time.
+func (d *ClusterDrainer) Expiry() <-chan time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var next time.Time
+	for _, dc := range d.draining {
+		if next.IsZero() || dc.until.Before(next) {
+			next = dc.until
+		}
+	}
+
+	if d.timer != nil && !d.timerAt.Equal(next) {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if next.IsZero() {
+		return nil
+	}
+	if d.timer == nil {
+		d.timer = time.NewTimer(next.Sub(d.Now()))
+		d.timerAt = next
+	}
+	return d.timer.C
+}
+
+// drainingNames is sorted so that snapshots don't churn just because of map ordering.
+func (d *ClusterDrainer) drainingNames() []string {
+	names := make([]string, 0, len(d.draining))
+	for name := range d.draining {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
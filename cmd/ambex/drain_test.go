@@ -0,0 +1,119 @@
+package ambex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	v3clusterconfig "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+	v3core "github.com/datawire/ambassador/v2/pkg/api/envoy/config/core/v3"
+)
+
+func drainCluster(name string, drainMs float64) *v3clusterconfig.Cluster {
+	cluster := &v3clusterconfig.Cluster{Name: name}
+	if drainMs > 0 {
+		cluster.Metadata = &v3core.Metadata{
+			FilterMetadata: map[string]*structpb.Struct{
+				ClusterDrainMetadataKey: {
+					Fields: map[string]*structpb.Value{
+						"drain_time_ms": structpb.NewNumberValue(drainMs),
+					},
+				},
+			},
+		}
+	}
+	return cluster
+}
+
+func clusterNames(clusters []*v3clusterconfig.Cluster) []string {
+	names := []string{}
+	for _, cluster := range clusters {
+		names = append(names, cluster.Name)
+	}
+	return names
+}
+
+func TestClusterDrainer(t *testing.T) {
+	clock := time.Now()
+	d := NewClusterDrainer()
+	d.Now = func() time.Time { return clock }
+
+	foo := drainCluster("foo", 0)
+	bar := drainCluster("bar", 5000)
+
+	assert.Equal(t, []string{"foo", "bar"}, clusterNames(d.Apply([]*v3clusterconfig.Cluster{foo, bar})))
+
+	// bar goes away, but sticks around until its drain time is up.
+	assert.Equal(t, []string{"foo", "bar"}, clusterNames(d.Apply([]*v3clusterconfig.Cluster{foo})))
+	assert.Equal(t, map[string]time.Time{"bar": clock.Add(5 * time.Second)}, d.Draining())
+
+	clock = clock.Add(4 * time.Second)
+	assert.Equal(t, []string{"foo", "bar"}, clusterNames(d.Apply([]*v3clusterconfig.Cluster{foo})))
+
+	clock = clock.Add(time.Second)
+	assert.Equal(t, []string{"foo"}, clusterNames(d.Apply([]*v3clusterconfig.Cluster{foo})))
+	assert.Empty(t, d.Draining())
+
+	// foo has no drain time, so it's gone at once.
+	assert.Empty(t, clusterNames(d.Apply(nil)))
+	assert.Empty(t, d.Draining())
+}
+
+func TestClusterDrainerReAdd(t *testing.T) {
+	clock := time.Now()
+	d := NewClusterDrainer()
+	d.Now = func() time.Time { return clock }
+
+	bar := drainCluster("bar", 5000)
+	d.Apply([]*v3clusterconfig.Cluster{bar})
+	d.Apply(nil)
+	assert.Contains(t, d.Draining(), "bar")
+
+	// Coming back while draining stops the drain, and the new config is the one that's used.
+	newBar := drainCluster("bar", 1000)
+	result := d.Apply([]*v3clusterconfig.Cluster{newBar})
+	assert.Equal(t, []*v3clusterconfig.Cluster{newBar}, result)
+	assert.Empty(t, d.Draining())
+
+	// Going away again drains for the new config's drain time, from now.
+	clock = clock.Add(10 * time.Second)
+	d.Apply(nil)
+	assert.Equal(t, map[string]time.Time{"bar": clock.Add(time.Second)}, d.Draining())
+}
+
+func TestClusterDrainerExpiry(t *testing.T) {
+	clock := time.Now()
+	d := NewClusterDrainer()
+	d.Now = func() time.Time { return clock }
+
+	// Nothing's draining, so there's nothing to wait for.
+	foo := drainCluster("foo", 50)
+	bar := drainCluster("bar", 5000)
+	d.Apply([]*v3clusterconfig.Cluster{foo, bar})
+	assert.Nil(t, d.Expiry())
+
+	// The channel is for whichever drain is done first, and stays the same until that changes.
+	d.Apply(nil)
+	expiry := d.Expiry()
+	assert.NotNil(t, expiry)
+	assert.Equal(t, expiry, d.Expiry())
+
+	select {
+	case <-expiry:
+	case <-time.After(5 * time.Second):
+		t.Fatal("drain expiry never fired")
+	}
+
+	// Once the next snapshot has left foo out, it's bar's turn.
+	clock = clock.Add(50 * time.Millisecond)
+	assert.Equal(t, []string{"bar"}, clusterNames(d.Apply(nil)))
+	next := d.Expiry()
+	assert.NotNil(t, next)
+	assert.NotEqual(t, expiry, next)
+
+	clock = clock.Add(5 * time.Second)
+	assert.Empty(t, clusterNames(d.Apply(nil)))
+	assert.Nil(t, d.Expiry())
+}
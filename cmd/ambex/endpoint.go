@@ -5,6 +5,8 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/golang/protobuf/ptypes/wrappers"
+
 	v2 "github.com/datawire/ambassador/v2/pkg/api/envoy/api/v2"
 	v2core "github.com/datawire/ambassador/v2/pkg/api/envoy/api/v2/core"
 	v2endpoint "github.com/datawire/ambassador/v2/pkg/api/envoy/api/v2/endpoint"
@@ -39,13 +41,20 @@ func (e *Endpoints) RoutesString() string {
 func (e *Endpoints) ToMap_v2() map[string]*v2.ClusterLoadAssignment {
 	result := map[string]*v2.ClusterLoadAssignment{}
 	for name, eps := range e.Entries {
-		var endpoints []*v2endpoint.LbEndpoint
-		for _, ep := range eps {
-			endpoints = append(endpoints, ep.ToLbEndpoint_v2())
+		var localities []*v2endpoint.LocalityLbEndpoints
+		for _, group := range groupByLocality(eps) {
+			var endpoints []*v2endpoint.LbEndpoint
+			for _, ep := range group.endpoints {
+				endpoints = append(endpoints, ep.ToLbEndpoint_v2())
+			}
+			localities = append(localities, &v2endpoint.LocalityLbEndpoints{
+				Locality:    group.locality.toV2(),
+				LbEndpoints: endpoints,
+			})
 		}
 		loadAssignment := &v2.ClusterLoadAssignment{
 			ClusterName: name,
-			Endpoints:   []*v2endpoint.LocalityLbEndpoints{{LbEndpoints: endpoints}},
+			Endpoints:   localities,
 		}
 		result[name] = loadAssignment
 	}
@@ -56,30 +65,114 @@ func (e *Endpoints) ToMap_v2() map[string]*v2.ClusterLoadAssignment {
 func (e *Endpoints) ToMap_v3() map[string]*v3endpointconfig.ClusterLoadAssignment {
 	result := map[string]*v3endpointconfig.ClusterLoadAssignment{}
 	for name, eps := range e.Entries {
-		var endpoints []*v3endpoint.LbEndpoint
-		for _, ep := range eps {
-			endpoints = append(endpoints, ep.ToLbEndpoint_v3())
+		var localities []*v3endpointconfig.LocalityLbEndpoints
+		for _, group := range groupByLocality(eps) {
+			var endpoints []*v3endpoint.LbEndpoint
+			for _, ep := range group.endpoints {
+				endpoints = append(endpoints, ep.ToLbEndpoint_v3())
+			}
+			localities = append(localities, &v3endpointconfig.LocalityLbEndpoints{
+				Locality:    group.locality.toV3(),
+				LbEndpoints: endpoints,
+			})
 		}
 		loadAssignment := &v3endpointconfig.ClusterLoadAssignment{
 			ClusterName: name,
-			Endpoints:   []*v3endpointconfig.LocalityLbEndpoints{{LbEndpoints: endpoints}},
+			Endpoints:   localities,
 		}
 		result[name] = loadAssignment
 	}
 	return result
 }
 
+// groupByLocality splits a cluster's endpoints up by Locality, keeping their order within each
+// Locality. The default Locality comes first, and the rest are sorted, so that the same
+// endpoints always turn into the same load assignment. A cluster with no endpoints still gets
+// one (empty) group, as it always has.
+func groupByLocality(eps []*Endpoint) []localityGroup {
+	if len(eps) == 0 {
+		return []localityGroup{{}}
+	}
+
+	groups := map[Locality][]*Endpoint{}
+	var localities []Locality
+	for _, ep := range eps {
+		if _, ok := groups[ep.Locality]; !ok {
+			localities = append(localities, ep.Locality)
+		}
+		groups[ep.Locality] = append(groups[ep.Locality], ep)
+	}
+
+	sort.Slice(localities, func(i, j int) bool {
+		return localities[i].less(localities[j])
+	})
+
+	result := make([]localityGroup, 0, len(localities))
+	for _, l := range localities {
+		result = append(result, localityGroup{l, groups[l]})
+	}
+	return result
+}
+
+type localityGroup struct {
+	locality  Locality
+	endpoints []*Endpoint
+}
+
+// Locality is where an Endpoint runs, as far as Envoy's locality-aware load balancing is
+// concerned. The zero Locality is Envoy's default locality, for endpoints we know nothing
+// about.
+type Locality struct {
+	Region  string
+	Zone    string
+	SubZone string
+}
+
+func (l Locality) less(other Locality) bool {
+	if l.Region != other.Region {
+		return l.Region < other.Region
+	}
+	if l.Zone != other.Zone {
+		return l.Zone < other.Zone
+	}
+	return l.SubZone < other.SubZone
+}
+
+// toV2 is nil for the default Locality, so that endpoints we know nothing about look exactly
+// the way they always have.
+func (l Locality) toV2() *v2core.Locality {
+	if l == (Locality{}) {
+		return nil
+	}
+	return &v2core.Locality{Region: l.Region, Zone: l.Zone, SubZone: l.SubZone}
+}
+
+// toV3 is nil for the default Locality, like toV2.
+func (l Locality) toV3() *v3core.Locality {
+	if l == (Locality{}) {
+		return nil
+	}
+	return &v3core.Locality{Region: l.Region, Zone: l.Zone, SubZone: l.SubZone}
+}
+
 // Endpoint contains the subset of fields we bother to expose.
 type Endpoint struct {
 	ClusterName string
 	Ip          string
 	Port        uint32
 	Protocol    string
+	// Weight is the endpoint's share of its cluster's traffic relative to the others. Zero
+	// means it wasn't given one, and Envoy weights it the same as any other unweighted
+	// endpoint.
+	Weight uint32
+	// Locality is where the endpoint runs, if we know.
+	Locality Locality
 }
 
 // ToLBEndpoint_v2 translates to envoy v2 frinedly form of the Endpoint data.
 func (e *Endpoint) ToLbEndpoint_v2() *v2endpoint.LbEndpoint {
 	return &v2endpoint.LbEndpoint{
+		LoadBalancingWeight: e.loadBalancingWeight(),
 		HostIdentifier: &v2endpoint.LbEndpoint_Endpoint{
 			Endpoint: &v2endpoint.Endpoint{
 				Address: &v2core.Address{
@@ -102,6 +195,7 @@ func (e *Endpoint) ToLbEndpoint_v2() *v2endpoint.LbEndpoint {
 // ToLBEndpoint_v3 translates to envoy v3 frinedly form of the Endpoint data.
 func (e *Endpoint) ToLbEndpoint_v3() *v3endpoint.LbEndpoint {
 	return &v3endpoint.LbEndpoint{
+		LoadBalancingWeight: e.loadBalancingWeight(),
 		HostIdentifier: &v3endpoint.LbEndpoint_Endpoint{
 			Endpoint: &v3endpoint.Endpoint{
 				Address: &v3core.Address{
@@ -120,3 +214,11 @@ func (e *Endpoint) ToLbEndpoint_v3() *v3endpoint.LbEndpoint {
 		},
 	}
 }
+
+// loadBalancingWeight is nil for an unweighted endpoint, since Envoy won't take a weight of 0.
+func (e *Endpoint) loadBalancingWeight() *wrappers.UInt32Value {
+	if e.Weight == 0 {
+		return nil
+	}
+	return &wrappers.UInt32Value{Value: e.Weight}
+}
@@ -53,6 +53,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	// third-party libraries
 	"github.com/fsnotify/fsnotify"
@@ -101,18 +102,26 @@ import (
 	v3routeconfig "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
 	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/access_loggers/file/v3"
 	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/access_loggers/grpc/v3"
+	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/clusters/aggregate/v3"
+	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/clusters/dynamic_forward_proxy/v3"
 	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/compression/gzip/compressor/v3"
+	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/compression/gzip/decompressor/v3"
+	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/adaptive_concurrency/v3"
 	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/buffer/v3"
 	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/compressor/v3"
+	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/decompressor/v3"
+	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/dynamic_forward_proxy/v3"
 	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/ext_authz/v3"
 	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/grpc_stats/v3"
 	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/gzip/v3"
+	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/jwt_authn/v3"
 	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/lua/v3"
 	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/ratelimit/v3"
 	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/rbac/v3"
 	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/response_map/v3"
 	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/router/v3"
 	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/local_ratelimit/v3"
 	_ "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/tcp_proxy/v3"
 	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/service/cluster/v3"
 	v3discovery "github.com/datawire/ambassador/v2/pkg/api/envoy/service/discovery/v3"
@@ -307,6 +316,7 @@ type v3ExpandedSnapshot struct {
 	Clusters  ecp_v3_cache.Resources `json:"clusters"`
 	Routes    ecp_v3_cache.Resources `json:"routes"`
 	Listeners ecp_v3_cache.Resources `json:"listeners"`
+	Secrets   ecp_v3_cache.Resources `json:"secrets"`
 	Runtimes  ecp_v3_cache.Resources `json:"runtimes"`
 }
 
@@ -316,6 +326,7 @@ func NewV3ExpandedSnapshot(v3snap *ecp_v3_cache.Snapshot) v3ExpandedSnapshot {
 		Clusters:  v3snap.Resources[ecp_cache_types.Cluster],
 		Routes:    v3snap.Resources[ecp_cache_types.Route],
 		Listeners: v3snap.Resources[ecp_cache_types.Listener],
+		Secrets:   v3snap.Resources[ecp_cache_types.Secret],
 		Runtimes:  v3snap.Resources[ecp_cache_types.Runtime],
 	}
 }
@@ -397,6 +408,8 @@ func update(
 	edsEndpoints map[string]*v2.ClusterLoadAssignment,
 	edsEndpointsV3 map[string]*v3endpointconfig.ClusterLoadAssignment,
 	fastpathSnapshot *FastpathSnapshot,
+	drainer *ClusterDrainer,
+	rebalancer *WeightRebalancer,
 	updates chan<- Update,
 ) error {
 	clusters := []ecp_cache_types.Resource{}  // v2.Cluster
@@ -408,6 +421,7 @@ func update(
 	routesv3 := []ecp_cache_types.Resource{}    // v3.RouteConfiguration
 	listenersv3 := []ecp_cache_types.Resource{} // v3.Listener
 	runtimesv3 := []ecp_cache_types.Resource{}  // v3.Runtime
+	secretsv3 := []ecp_cache_types.Resource{}   // v3.Secret
 
 	var filenames []string
 
@@ -502,6 +516,12 @@ func update(
 			for _, cls := range sr.Clusters {
 				clustersv3 = append(clustersv3, proto.Clone(cls).(ecp_cache_types.Resource))
 			}
+			// With the ambassador Module's secret_discovery, diagd has already moved certs
+			// out of the listeners and clusters into secrets, named for the Secrets they came
+			// from, so that a rotated cert only changes its secret.
+			for _, secret := range sr.Secrets {
+				secretsv3 = append(secretsv3, proto.Clone(secret).(ecp_cache_types.Resource))
+			}
 			continue
 		default:
 			dlog.Warnf(ctx, "Unrecognized resource %s: %v", name, e)
@@ -523,6 +543,28 @@ func update(
 		// We intentionally omit endpoints since those are carried separately.
 	}
 
+	// Clusters that just went away may need to hang around for a bit so that requests that
+	// are already using them can finish.
+	typedClustersv3 := make([]*v3clusterconfig.Cluster, 0, len(clustersv3))
+	for _, cls := range clustersv3 {
+		typedClustersv3 = append(typedClustersv3, cls.(*v3clusterconfig.Cluster))
+	}
+	clustersv3 = clustersv3[:0]
+	for _, cls := range drainer.Apply(typedClustersv3) {
+		clustersv3 = append(clustersv3, cls)
+	}
+
+	// Weighted routes that asked for it have their weights shifted away from clusters with no
+	// healthy endpoints.
+	typedRoutesv3 := make([]*v3routeconfig.RouteConfiguration, 0, len(routesv3))
+	for _, rc := range routesv3 {
+		typedRoutesv3 = append(typedRoutesv3, rc.(*v3routeconfig.RouteConfiguration))
+	}
+	routesv3 = routesv3[:0]
+	for _, rc := range rebalancer.Apply(typedRoutesv3) {
+		routesv3 = append(routesv3, rc)
+	}
+
 	// The configuration data that reaches us here arrives via two parallel paths that race each
 	// other. The endpoint data comes in realtime directly from the golang watcher in the entrypoint
 	// package. The cluster configuration comes from the python code. Either one can win which means
@@ -575,6 +617,7 @@ func update(
 		routesv3,
 		listenersv3,
 		runtimesv3)
+	snapshotv3.Resources[ecp_cache_types.Secret] = ecp_v3_cache.NewResources(version, secretsv3)
 
 	if err := snapshotv3.Consistent(); err != nil {
 		bs, _ := json.Marshal(snapshotv3)
@@ -841,6 +884,8 @@ func Main2(
 	var fastpathSnapshot *FastpathSnapshot
 	edsEndpoints := map[string]*v2.ClusterLoadAssignment{}
 	edsEndpointsV3 := map[string]*v3endpointconfig.ClusterLoadAssignment{}
+	drainer := NewClusterDrainer()
+	rebalancer := NewWeightRebalancer()
 
 	// We always start by updating with a totally empty snapshot.
 	//
@@ -857,12 +902,19 @@ func Main2(
 		edsEndpoints,
 		edsEndpointsV3,
 		fastpathSnapshot,
+		drainer,
+		rebalancer,
 		updates,
 	)
 	if err != nil {
 		return err
 	}
 
+	// Weighted routes that want to be rebalanced on cluster health need to hear about it, so
+	// keep asking Envoy.
+	healthTicker := time.NewTicker(RebalanceInterval)
+	defer healthTicker.Stop()
+
 	// This is the main loop where the magic happens. The fact that it uses a label
 	// depresses me, though.
 OUTER:
@@ -886,6 +938,8 @@ OUTER:
 					edsEndpoints,
 					edsEndpointsV3,
 					fastpathSnapshot,
+					drainer,
+					rebalancer,
 					updates,
 				)
 				if err != nil {
@@ -912,6 +966,8 @@ OUTER:
 				edsEndpoints,
 				edsEndpointsV3,
 				fastpathSnapshot,
+				drainer,
+				rebalancer,
 				updates,
 			)
 			if err != nil {
@@ -930,6 +986,59 @@ OUTER:
 				edsEndpoints,
 				edsEndpointsV3,
 				fastpathSnapshot,
+				drainer,
+				rebalancer,
+				updates,
+			)
+			if err != nil {
+				return err
+			}
+		case <-healthTicker.C:
+			if !rebalancer.Active() {
+				continue
+			}
+			healthyHosts, err := fetchClusterHealth(ctx)
+			if err != nil {
+				// Envoy may well not be up yet; try again next time.
+				dlog.Debugf(ctx, "Could not fetch cluster health: %v", err)
+				continue
+			}
+			if !rebalancer.SetHealth(healthyHosts) {
+				continue
+			}
+			err = update(
+				ctx,
+				snapdirPath,
+				numsnaps,
+				config,
+				configv3,
+				&generation,
+				args.dirs,
+				edsEndpoints,
+				edsEndpointsV3,
+				fastpathSnapshot,
+				drainer,
+				rebalancer,
+				updates,
+			)
+			if err != nil {
+				return err
+			}
+		case <-drainer.Expiry():
+			// A cluster is done draining, and only a new snapshot can take it out of Envoy.
+			err := update(
+				ctx,
+				snapdirPath,
+				numsnaps,
+				config,
+				configv3,
+				&generation,
+				args.dirs,
+				edsEndpoints,
+				edsEndpointsV3,
+				fastpathSnapshot,
+				drainer,
+				rebalancer,
 				updates,
 			)
 			if err != nil {
@@ -0,0 +1,263 @@
+package ambex
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/datawire/ambassador/v2/pkg/acp"
+	v3routeconfig "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+)
+
+// DefaultRebalanceRecovery is how long a cluster has to stay healthy before a route that's
+// been rebalanced away from it gives it its weight back, unless the route says otherwise.
+const DefaultRebalanceRecovery = 30 * time.Second
+
+// RebalanceInterval is how often ambex asks Envoy how healthy its clusters are, when any
+// route wants to be rebalanced.
+const RebalanceInterval = 5 * time.Second
+
+// RouteRebalance returns whether a route's weighted clusters may be rebalanced on cluster
+// health, as set by the `health_rebalance` of its Mappings, and how long a cluster has to
+// stay healthy to get its weight back. diagd puts this in the route's filter_metadata, under
+// the same key it uses for clusters.
+func RouteRebalance(route *v3routeconfig.Route) (bool, time.Duration) {
+	if route.GetRoute().GetWeightedClusters() == nil {
+		return false, 0
+	}
+
+	fields := route.GetMetadata().GetFilterMetadata()[ClusterDrainMetadataKey].GetFields()
+	if !fields["health_rebalance"].GetBoolValue() {
+		return false, 0
+	}
+
+	recovery := DefaultRebalanceRecovery
+	if value, ok := fields["recovery_ms"]; ok {
+		recovery = time.Duration(value.GetNumberValue() * float64(time.Millisecond))
+	}
+	return true, recovery
+}
+
+// A WeightRebalancer shifts traffic in the weighted routes that ask for it away from clusters
+// that have no healthy endpoints, sharing their weight out among the rest of the route's
+// clusters in proportion to their own weights. If every cluster in a route is unhealthy,
+// the route keeps its configured weights: there's nowhere better to send the traffic.
+//
+// To keep a flapping cluster from flapping the traffic with it, a cluster that's been taken
+// out of a route only gets its weight back once it's been healthy for the route's recovery
+// time. Until the rebalancer has heard anything about health, nothing is taken out.
+type WeightRebalancer struct {
+	// Now tells the rebalancer what time it is; tests can replace it.
+	Now func() time.Time
+
+	mu            sync.Mutex
+	healthyHosts  map[string]int
+	lastUnhealthy map[string]time.Time
+	recovery      map[string]time.Duration
+	applied       map[string]bool
+}
+
+// NewWeightRebalancer returns a WeightRebalancer that hasn't heard about any cluster's
+// health yet.
+func NewWeightRebalancer() *WeightRebalancer {
+	return &WeightRebalancer{
+		Now:           time.Now,
+		lastUnhealthy: map[string]time.Time{},
+		recovery:      map[string]time.Duration{},
+		applied:       map[string]bool{},
+	}
+}
+
+// Active returns true if any route in the last configuration wants to be rebalanced, which is
+// to say, whether cluster health is worth fetching at all.
+func (r *WeightRebalancer) Active() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.recovery) > 0
+}
+
+// SetHealth records how many healthy endpoints each cluster has (a cluster that isn't
+// mentioned has none), and returns true if that changes which clusters should be taken out
+// of their routes -- that is, if the configuration needs to be applied again.
+func (r *WeightRebalancer) SetHealth(healthyHosts map[string]int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.Now()
+
+	r.healthyHosts = healthyHosts
+	for name := range r.recovery {
+		if healthyHosts[name] == 0 {
+			r.lastUnhealthy[name] = now
+		}
+	}
+
+	return !reflect.DeepEqual(r.excluded(now), r.applied)
+}
+
+// Excluded returns the clusters that were taken out of their routes the last time the
+// rebalancer was applied.
+func (r *WeightRebalancer) Excluded() map[string]bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := map[string]bool{}
+	for name := range r.applied {
+		result[name] = true
+	}
+	return result
+}
+
+// excluded works out which clusters should be out of their routes right now. The caller must
+// hold the lock.
+func (r *WeightRebalancer) excluded(now time.Time) map[string]bool {
+	result := map[string]bool{}
+	if r.healthyHosts == nil {
+		return result
+	}
+
+	for name, recovery := range r.recovery {
+		if r.healthyHosts[name] == 0 {
+			result[name] = true
+		} else if t, ok := r.lastUnhealthy[name]; ok && now.Sub(t) < recovery {
+			result[name] = true
+		}
+	}
+	return result
+}
+
+// Apply takes the route configurations in a new configuration and returns them with their
+// rebalanced routes' weights adjusted. Route configurations that don't change are returned
+// as they are; the ones that do are copied first.
+func (r *WeightRebalancer) Apply(routeConfigs []*v3routeconfig.RouteConfiguration) []*v3routeconfig.RouteConfiguration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// A cluster in more than one rebalanced route waits for the longest recovery of them.
+	recovery := map[string]time.Duration{}
+	eachRebalancedRoute(routeConfigs, func(route *v3routeconfig.Route, routeRecovery time.Duration) {
+		for _, cw := range route.GetRoute().GetWeightedClusters().GetClusters() {
+			if d, ok := recovery[cw.Name]; !ok || routeRecovery > d {
+				recovery[cw.Name] = routeRecovery
+			}
+		}
+	})
+	r.recovery = recovery
+
+	excluded := r.excluded(r.Now())
+	r.applied = excluded
+
+	if len(excluded) == 0 {
+		return routeConfigs
+	}
+
+	result := make([]*v3routeconfig.RouteConfiguration, 0, len(routeConfigs))
+	for _, rc := range routeConfigs {
+		rebalanced := proto.Clone(rc).(*v3routeconfig.RouteConfiguration)
+		changed := false
+
+		eachRebalancedRoute([]*v3routeconfig.RouteConfiguration{rebalanced}, func(route *v3routeconfig.Route, _ time.Duration) {
+			if rebalanceWeights(route.GetRoute().GetWeightedClusters(), excluded) {
+				changed = true
+			}
+		})
+
+		if changed {
+			result = append(result, rebalanced)
+		} else {
+			result = append(result, rc)
+		}
+	}
+	return result
+}
+
+// eachRebalancedRoute calls fn for every route that wants to be rebalanced.
+func eachRebalancedRoute(routeConfigs []*v3routeconfig.RouteConfiguration, fn func(*v3routeconfig.Route, time.Duration)) {
+	for _, rc := range routeConfigs {
+		for _, vhost := range rc.GetVirtualHosts() {
+			for _, route := range vhost.GetRoutes() {
+				if ok, recovery := RouteRebalance(route); ok {
+					fn(route, recovery)
+				}
+			}
+		}
+	}
+}
+
+// rebalanceWeights takes the excluded clusters out of a set of weighted clusters and shares
+// the total weight out among the others, in proportion to their weights (or evenly, if they
+// had none), with what rounding leaves over going to the first of them. It leaves them alone,
+// and returns false, if none or all of them are excluded.
+func rebalanceWeights(wc *v3routeconfig.WeightedCluster, excluded map[string]bool) bool {
+	var kept []*v3routeconfig.WeightedCluster_ClusterWeight
+	var total, keptTotal uint32
+
+	for _, cw := range wc.GetClusters() {
+		total += cw.GetWeight().GetValue()
+		if !excluded[cw.Name] {
+			kept = append(kept, cw)
+			keptTotal += cw.GetWeight().GetValue()
+		}
+	}
+
+	if len(kept) == 0 || len(kept) == len(wc.GetClusters()) {
+		return false
+	}
+
+	if wc.GetTotalWeight() != nil {
+		total = wc.GetTotalWeight().GetValue()
+	}
+
+	var assigned uint32
+	for _, cw := range kept {
+		var weight uint32
+		if keptTotal > 0 {
+			weight = uint32(uint64(cw.GetWeight().GetValue()) * uint64(total) / uint64(keptTotal))
+		} else {
+			weight = total / uint32(len(kept))
+		}
+		cw.Weight = wrapperspb.UInt32(weight)
+		assigned += weight
+	}
+	kept[0].Weight = wrapperspb.UInt32(kept[0].GetWeight().GetValue() + total - assigned)
+
+	wc.Clusters = kept
+	return true
+}
+
+// fetchClusterHealth asks Envoy how many healthy endpoints each of its clusters has.
+func fetchClusterHealth(ctx context.Context) (map[string]int, error) {
+	// Envoy's admin interface answers quickly or not at all; see acp.EnvoyWatcher.
+	tctx, tcancel := context.WithTimeout(ctx, 2*time.Second)
+	defer tcancel()
+
+	req, err := http.NewRequestWithContext(tctx, http.MethodGet, "http://localhost:8001/clusters?format=json", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching cluster health: status %d", resp.StatusCode)
+	}
+
+	text, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return acp.ParseClusterHealth(text)
+}
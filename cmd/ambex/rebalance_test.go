@@ -0,0 +1,129 @@
+package ambex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	v3core "github.com/datawire/ambassador/v2/pkg/api/envoy/config/core/v3"
+	v3routeconfig "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+)
+
+func rebalanceRouteConfig(recoveryMs float64, weights map[string]uint32) *v3routeconfig.RouteConfiguration {
+	wc := &v3routeconfig.WeightedCluster{TotalWeight: wrapperspb.UInt32(100)}
+	for _, name := range []string{"stable", "canary"} {
+		if weight, ok := weights[name]; ok {
+			wc.Clusters = append(wc.Clusters, &v3routeconfig.WeightedCluster_ClusterWeight{
+				Name:   name,
+				Weight: wrapperspb.UInt32(weight),
+			})
+		}
+	}
+
+	return &v3routeconfig.RouteConfiguration{
+		Name: "routes",
+		VirtualHosts: []*v3routeconfig.VirtualHost{{
+			Name:    "vhost",
+			Domains: []string{"*"},
+			Routes: []*v3routeconfig.Route{{
+				Action: &v3routeconfig.Route_Route{Route: &v3routeconfig.RouteAction{
+					ClusterSpecifier: &v3routeconfig.RouteAction_WeightedClusters{WeightedClusters: wc},
+				}},
+				Metadata: &v3core.Metadata{
+					FilterMetadata: map[string]*structpb.Struct{
+						ClusterDrainMetadataKey: {
+							Fields: map[string]*structpb.Value{
+								"health_rebalance": structpb.NewBoolValue(true),
+								"recovery_ms":      structpb.NewNumberValue(recoveryMs),
+							},
+						},
+					},
+				},
+			}},
+		}},
+	}
+}
+
+func routeWeights(rcs []*v3routeconfig.RouteConfiguration) map[string]uint32 {
+	weights := map[string]uint32{}
+	for _, cw := range rcs[0].VirtualHosts[0].Routes[0].GetRoute().GetWeightedClusters().GetClusters() {
+		weights[cw.Name] = cw.GetWeight().GetValue()
+	}
+	return weights
+}
+
+func TestWeightRebalancer(t *testing.T) {
+	clock := time.Now()
+	r := NewWeightRebalancer()
+	r.Now = func() time.Time { return clock }
+
+	rcs := []*v3routeconfig.RouteConfiguration{
+		rebalanceRouteConfig(10000, map[string]uint32{"stable": 90, "canary": 10}),
+	}
+	configured := map[string]uint32{"stable": 90, "canary": 10}
+
+	// Until we've heard about health, nothing moves.
+	assert.Equal(t, configured, routeWeights(r.Apply(rcs)))
+	assert.True(t, r.Active())
+
+	// The canary goes unhealthy, so the stable cluster gets everything...
+	assert.True(t, r.SetHealth(map[string]int{"stable": 3}))
+	assert.Equal(t, map[string]uint32{"stable": 100}, routeWeights(r.Apply(rcs)))
+	assert.Equal(t, map[string]bool{"canary": true}, r.Excluded())
+
+	// ...and the configuration we were handed is left alone.
+	assert.Equal(t, configured, routeWeights(rcs))
+
+	// The canary comes back, but has to stay healthy for its recovery time first...
+	clock = clock.Add(time.Second)
+	assert.False(t, r.SetHealth(map[string]int{"stable": 3, "canary": 1}))
+	assert.Equal(t, map[string]uint32{"stable": 100}, routeWeights(r.Apply(rcs)))
+
+	// ...and flapping starts the wait over...
+	clock = clock.Add(5 * time.Second)
+	assert.False(t, r.SetHealth(map[string]int{"stable": 3}))
+	clock = clock.Add(5 * time.Second)
+	assert.False(t, r.SetHealth(map[string]int{"stable": 3, "canary": 1}))
+
+	// ...until it's been healthy long enough.
+	clock = clock.Add(10 * time.Second)
+	assert.True(t, r.SetHealth(map[string]int{"stable": 3, "canary": 1}))
+	assert.Equal(t, configured, routeWeights(r.Apply(rcs)))
+	assert.Empty(t, r.Excluded())
+
+	// With both clusters down, there's nowhere better to go, so the weights stay put.
+	assert.True(t, r.SetHealth(map[string]int{}))
+	assert.Equal(t, configured, routeWeights(r.Apply(rcs)))
+}
+
+func TestRebalanceWeights(t *testing.T) {
+	// Three ways, the weight goes to the others in proportion to what they had, with the
+	// rounding going to the first.
+	wc := &v3routeconfig.WeightedCluster{
+		TotalWeight: wrapperspb.UInt32(100),
+		Clusters: []*v3routeconfig.WeightedCluster_ClusterWeight{
+			{Name: "a", Weight: wrapperspb.UInt32(50)},
+			{Name: "b", Weight: wrapperspb.UInt32(25)},
+			{Name: "c", Weight: wrapperspb.UInt32(25)},
+		},
+	}
+	assert.True(t, rebalanceWeights(wc, map[string]bool{"b": true}))
+	assert.Len(t, wc.Clusters, 2)
+	assert.Equal(t, uint32(67), wc.Clusters[0].GetWeight().GetValue())
+	assert.Equal(t, uint32(33), wc.Clusters[1].GetWeight().GetValue())
+
+	// Clusters that had no weight share it evenly.
+	wc = &v3routeconfig.WeightedCluster{
+		Clusters: []*v3routeconfig.WeightedCluster_ClusterWeight{
+			{Name: "a", Weight: wrapperspb.UInt32(100)},
+			{Name: "b", Weight: wrapperspb.UInt32(0)},
+			{Name: "c", Weight: wrapperspb.UInt32(0)},
+		},
+	}
+	assert.True(t, rebalanceWeights(wc, map[string]bool{"a": true}))
+	assert.Equal(t, uint32(50), wc.Clusters[0].GetWeight().GetValue())
+	assert.Equal(t, uint32(50), wc.Clusters[1].GetWeight().GetValue())
+}
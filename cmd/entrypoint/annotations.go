@@ -23,8 +23,8 @@ func parseAnnotations(ctx context.Context, a *snapshotTypes.KubernetesSnapshot)
 }
 
 // GetAnnotations extracts and converts any parseable annotations from the supplied resource. It
-// omits any malformed annotations and does not report the errors. This is ok for now because the
-// python code will catch and report any errors.
+// omits any malformed annotations and only logs the errors. This is ok for now because the
+// python code will catch and report them against the resource they came from.
 func GetAnnotations(ctx context.Context, resources ...kates.Object) (result []kates.Object) {
 	for _, r := range resources {
 		ann, ok := r.GetAnnotations()["getambassador.io/config"]
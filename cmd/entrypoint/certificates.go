@@ -0,0 +1,79 @@
+package entrypoint
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sort"
+
+	"github.com/datawire/ambassador/v2/pkg/kates"
+	snapshotTypes "github.com/datawire/ambassador/v2/pkg/snapshot/v1"
+)
+
+// certificateStatuses works out when the certificate in each of the given secrets expires, so
+// that diagd can warn about it ahead of time. Only secrets with a tls.crt count: those are the
+// ones Envoy serves or presents.
+func certificateStatuses(secrets []*kates.Secret) []*snapshotTypes.CertificateStatus {
+	var statuses []*snapshotTypes.CertificateStatus
+
+	for _, secret := range secrets {
+		crt, ok := secret.Data["tls.crt"]
+		if !ok {
+			continue
+		}
+
+		status := certificateStatus(crt)
+		status.Name = secret.GetName()
+		status.Namespace = secret.GetNamespace()
+		statuses = append(statuses, status)
+	}
+
+	// s.Secrets comes partly out of a map, so put these in an order that doesn't change from
+	// one snapshot to the next.
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Namespace != statuses[j].Namespace {
+			return statuses[i].Namespace < statuses[j].Namespace
+		}
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses
+}
+
+// certificateStatus parses every certificate in a PEM-encoded chain. The chain is only good
+// until its first certificate expires, and that isn't always the leaf: an intermediate can
+// run out first.
+func certificateStatus(crt []byte) *snapshotTypes.CertificateStatus {
+	status := &snapshotTypes.CertificateStatus{}
+	found := false
+
+	for rest := crt; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			status.Error = fmt.Sprintf("tls.crt has a certificate that can't be parsed: %v", err)
+			return status
+		}
+
+		notAfter := cert.NotAfter.Unix()
+		if !found || notAfter < status.NotAfter {
+			status.NotAfter = notAfter
+			status.Subject = cert.Subject.CommonName
+		}
+		found = true
+	}
+
+	if !found {
+		status.Error = "tls.crt is not a PEM-encoded certificate"
+	}
+
+	return status
+}
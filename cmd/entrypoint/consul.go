@@ -11,6 +11,7 @@ import (
 	"github.com/datawire/ambassador/v2/pkg/consulwatch"
 	snapshotTypes "github.com/datawire/ambassador/v2/pkg/snapshot/v1"
 	"github.com/datawire/ambassador/v2/pkg/watt"
+	"github.com/datawire/dlib/dlog"
 )
 
 // consulMapping contains the necessary subset of Ambassador Mapping and TCPMapping
@@ -21,11 +22,23 @@ type consulMapping struct {
 }
 
 func ReconcileConsul(ctx context.Context, consul *consul, s *snapshotTypes.KubernetesSnapshot) error {
+	// A Mapping without a zero_replicas of its own gets the Module's, as it does in
+	// endpoints.go.
+	var moduleZeroReplicas *amb.ZeroReplicas
+	if module := findAmbassadorModule(ctx, s); module != nil {
+		mr := moduleResolver{}
+		if err := convert(module.Spec.Config, &mr); err != nil {
+			dlog.Errorf(ctx, "error parsing ambassador module: %v", err)
+		} else {
+			moduleZeroReplicas = mr.ZeroReplicas
+		}
+	}
+
 	var mappings []consulMapping
 	for _, a := range s.Annotations {
 		m, ok := a.(*amb.Mapping)
 		if ok && include(m.Spec.AmbassadorID) {
-			mappings = append(mappings, consulMapping{Service: m.Spec.Service, Resolver: m.Spec.Resolver})
+			mappings = append(mappings, consulMappings(m, moduleZeroReplicas)...)
 		}
 
 		tm, ok := a.(*amb.TCPMapping)
@@ -43,7 +56,7 @@ func ReconcileConsul(ctx context.Context, consul *consul, s *snapshotTypes.Kuber
 
 	for _, m := range s.Mappings {
 		if include(m.Spec.AmbassadorID) {
-			mappings = append(mappings, consulMapping{Service: m.Spec.Service, Resolver: m.Spec.Resolver})
+			mappings = append(mappings, consulMappings(m, moduleZeroReplicas)...)
 		}
 	}
 
@@ -56,6 +69,25 @@ func ReconcileConsul(ctx context.Context, consul *consul, s *snapshotTypes.Kuber
 	return consul.reconcile(ctx, s.ConsulResolvers, mappings)
 }
 
+// consulMappings is what a Mapping needs watched: its service with its own resolver, and with
+// each of its fallback resolvers, so that a Consul fallback already has endpoints by the time
+// it's needed. Likewise its zero_replicas hold service, with its own resolver, whether the
+// zero_replicas is the Mapping's or moduleZeroReplicas (which may be nil).
+func consulMappings(m *amb.Mapping, moduleZeroReplicas *amb.ZeroReplicas) []consulMapping {
+	result := []consulMapping{{Service: m.Spec.Service, Resolver: m.Spec.Resolver}}
+	for _, resolver := range m.Spec.ResolverFallback {
+		result = append(result, consulMapping{Service: m.Spec.Service, Resolver: resolver})
+	}
+	zeroReplicas := m.Spec.ZeroReplicas
+	if zeroReplicas == nil {
+		zeroReplicas = moduleZeroReplicas
+	}
+	if zeroReplicas != nil && zeroReplicas.Hold != nil {
+		result = append(result, consulMapping{Service: zeroReplicas.Hold.Service, Resolver: m.Spec.Resolver})
+	}
+	return result
+}
+
 type consul struct {
 	watcher                   Watcher
 	resolvers                 map[string]*resolver
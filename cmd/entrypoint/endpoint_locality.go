@@ -0,0 +1,104 @@
+package entrypoint
+
+import (
+	"context"
+
+	"github.com/datawire/ambassador/v2/cmd/ambex"
+	"github.com/datawire/ambassador/v2/pkg/kates"
+	"github.com/datawire/dlib/dlog"
+)
+
+// The EndpointSlice controller copies these labels from the node an endpoint runs on. Kubernetes
+// doesn't have a label for anything smaller than a zone, so for the subzone we use the one Istio
+// does. The failure-domain labels are what the region and zone used to be called.
+const (
+	serviceNameLabel   = "kubernetes.io/service-name"
+	regionLabel        = "topology.kubernetes.io/region"
+	zoneLabel          = "topology.kubernetes.io/zone"
+	subZoneLabel       = "topology.istio.io/subzone"
+	legacyRegionLabel  = "failure-domain.beta.kubernetes.io/region"
+	legacyZoneLabel    = "failure-domain.beta.kubernetes.io/zone"
+	legacySubZoneLabel = "failure-domain.beta.kubernetes.io/subzone"
+)
+
+// endpointSlice is the part of an EndpointSlice we care about. In discovery.k8s.io/v1beta1 the
+// node's labels are all in topology; v1 keeps the zone in its own field, and everything else in
+// deprecatedTopology.
+//
+// Topology-aware hints (hints.forZones) are deliberately left out: they say which zones should
+// send an endpoint traffic, not where the endpoint is, so they don't change its locality.
+type endpointSlice struct {
+	kates.ObjectMeta `json:"metadata"`
+	Endpoints        []struct {
+		Addresses          []string          `json:"addresses"`
+		Zone               string            `json:"zone"`
+		Topology           map[string]string `json:"topology"`
+		DeprecatedTopology map[string]string `json:"deprecatedTopology"`
+	} `json:"endpoints"`
+}
+
+// endpointLocalities works out the locality of every address in a set of EndpointSlices, keyed
+// by the Service they belong to (as "namespace:name") and then by address. An address that
+// isn't in any slice, or whose slice doesn't say anything about its topology, isn't in the
+// result, and gets Envoy's default locality.
+func endpointLocalities(ctx context.Context, slices []*kates.Unstructured) map[string]map[string]ambex.Locality {
+	result := map[string]map[string]ambex.Locality{}
+
+	for _, un := range slices {
+		var slice endpointSlice
+		if err := convert(un, &slice); err != nil {
+			dlog.Errorf(ctx, "endpointslice %s/%s: ignoring topology: %v", un.GetNamespace(), un.GetName(), err)
+			continue
+		}
+
+		service, ok := slice.Labels[serviceNameLabel]
+		if !ok {
+			continue
+		}
+		svcKey := slice.Namespace + ":" + service
+
+		for _, ep := range slice.Endpoints {
+			topology := map[string]string{}
+			for k, v := range ep.DeprecatedTopology {
+				topology[k] = v
+			}
+			for k, v := range ep.Topology {
+				topology[k] = v
+			}
+			if ep.Zone != "" {
+				topology[zoneLabel] = ep.Zone
+			}
+
+			locality := topologyLocality(topology)
+			if locality == (ambex.Locality{}) {
+				continue
+			}
+
+			if result[svcKey] == nil {
+				result[svcKey] = map[string]ambex.Locality{}
+			}
+			for _, addr := range ep.Addresses {
+				result[svcKey][addr] = locality
+			}
+		}
+	}
+
+	return result
+}
+
+// topologyLocality turns an endpoint's topology labels into a Locality, preferring the current
+// labels to the legacy ones.
+func topologyLocality(topology map[string]string) ambex.Locality {
+	label := func(current, legacy string) string {
+		if v, ok := topology[current]; ok {
+			return v
+		}
+		return topology[legacy]
+	}
+
+	return ambex.Locality{
+		Region:  label(regionLabel, legacyRegionLabel),
+		Zone:    label(zoneLabel, legacyZoneLabel),
+		SubZone: label(subZoneLabel, legacySubZoneLabel),
+	}
+}
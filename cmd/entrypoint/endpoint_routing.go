@@ -2,6 +2,7 @@ package entrypoint
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 
@@ -18,6 +19,8 @@ func makeEndpoints(ctx context.Context, ksnap *snapshot.KubernetesSnapshot, cons
 		k8sServices[key(svc)] = svc
 	}
 
+	localities := endpointLocalities(ctx, ksnap.EndpointSlices)
+
 	result := map[string][]*ambex.Endpoint{}
 
 	for _, k8sEp := range ksnap.Endpoints {
@@ -25,7 +28,7 @@ func makeEndpoints(ctx context.Context, ksnap *snapshot.KubernetesSnapshot, cons
 		if !ok {
 			continue
 		}
-		for _, ep := range k8sEndpointsToAmbex(k8sEp, svc) {
+		for _, ep := range k8sEndpointsToAmbex(ctx, k8sEp, svc, localities[key(k8sEp)]) {
 			result[ep.ClusterName] = append(result[ep.ClusterName], ep)
 		}
 	}
@@ -43,7 +46,35 @@ func key(resource kates.Object) string {
 	return fmt.Sprintf("%s:%s", resource.GetNamespace(), resource.GetName())
 }
 
-func k8sEndpointsToAmbex(ep *kates.Endpoints, svc *kates.Service) (result []*ambex.Endpoint) {
+// endpointWeightsAnnotation lets whatever manages an Endpoints resource (a rollout controller,
+// say, or something watching custom metrics) weight its addresses, as a JSON object mapping
+// each IP to a weight: {"10.0.0.1": 3, "10.0.0.2": 1}. Addresses it leaves out are weighted
+// as 1. An address with weight 0 gets no traffic at all, so it's left out of the load
+// assignment.
+const endpointWeightsAnnotation = "getambassador.io/endpoint-weights"
+
+// endpointWeights parses the endpointWeightsAnnotation on an Endpoints resource, if there is one.
+// If the annotation can't be parsed, every address gets weighted equally.
+func endpointWeights(ctx context.Context, ep *kates.Endpoints) map[string]uint32 {
+	value, ok := ep.GetAnnotations()[endpointWeightsAnnotation]
+	if !ok {
+		return nil
+	}
+
+	weights := map[string]uint32{}
+	if err := json.Unmarshal([]byte(value), &weights); err != nil {
+		dlog.Errorf(ctx, "endpoints %s: ignoring %s: %v", key(ep), endpointWeightsAnnotation, err)
+		return nil
+	}
+
+	return weights
+}
+
+// k8sEndpointsToAmbex translates an Endpoints resource, giving each address the locality it has
+// in localities, if any.
+func k8sEndpointsToAmbex(ctx context.Context, ep *kates.Endpoints, svc *kates.Service, localities map[string]ambex.Locality) (result []*ambex.Endpoint) {
+	weights := endpointWeights(ctx, ep)
+
 	portmap := map[string][]string{}
 	for _, p := range svc.Spec.Ports {
 		port := fmt.Sprintf("%d", p.Port)
@@ -77,6 +108,10 @@ func k8sEndpointsToAmbex(ep *kates.Endpoints, svc *kates.Service) (result []*amb
 					}
 				}
 				for _, addr := range subset.Addresses {
+					weight, weighted := weights[addr.IP]
+					if weighted && weight == 0 {
+						continue
+					}
 					for pn := range portNames {
 						sep := "/"
 						if pn == "" {
@@ -87,6 +122,8 @@ func k8sEndpointsToAmbex(ep *kates.Endpoints, svc *kates.Service) (result []*amb
 							Ip:          addr.IP,
 							Port:        uint32(port.Port),
 							Protocol:    string(port.Protocol),
+							Weight:      weight,
+							Locality:    localities[addr.IP],
 						})
 					}
 				}
@@ -166,8 +166,10 @@ func (eri *endpointRoutingInfo) checkResourcePhase2(ctx context.Context, obj kat
 }
 
 type moduleResolver struct {
-	Resolver                                   string `json:"resolver"`
-	UseAmbassadorNamespaceForServiceResolution bool   `json:"use_ambassador_namespace_for_service_resolution"`
+	Resolver                                   string            `json:"resolver"`
+	ResolverFallback                           []string          `json:"resolver_fallback"`
+	ZeroReplicas                               *amb.ZeroReplicas `json:"zero_replicas"`
+	UseAmbassadorNamespaceForServiceResolution bool              `json:"use_ambassador_namespace_for_service_resolution"`
 }
 
 // checkModule parses the stuff we care about out of the ambassador Module.
@@ -214,8 +216,29 @@ func (eri *endpointRoutingInfo) checkMapping(ctx context.Context, mapping *amb.M
 		dlog.Debugf(ctx, "WATCHER: Mapping %s uses the default resolver (%s)", name, source)
 	}
 
-	if eri.resolverTypes[resolver] == KubernetesEndpointResolver {
-		svc, ns, _ := eri.module.parseService(ctx, mapping, service, mapping.GetNamespace())
+	// The fallback resolvers need their endpoints ready before the Mapping's own resolver
+	// runs dry, not after.
+	fallback := mapping.Spec.ResolverFallback
+	if len(fallback) == 0 {
+		fallback = eri.module.ResolverFallback
+	}
+
+	for _, r := range append([]string{resolver}, fallback...) {
+		if eri.resolverTypes[r] == KubernetesEndpointResolver {
+			svc, ns, _ := eri.module.parseService(ctx, mapping, service, mapping.GetNamespace())
+			eri.endpointWatches[fmt.Sprintf("%s:%s", ns, svc)] = true
+		}
+	}
+
+	// So does a zero_replicas hold service, which is found with the Mapping's own resolver,
+	// whether the zero_replicas is the Mapping's or the Module's.
+	zeroReplicas := mapping.Spec.ZeroReplicas
+	if zeroReplicas == nil {
+		zeroReplicas = eri.module.ZeroReplicas
+	}
+
+	if zeroReplicas != nil && zeroReplicas.Hold != nil && eri.resolverTypes[resolver] == KubernetesEndpointResolver {
+		svc, ns, _ := eri.module.parseService(ctx, mapping, zeroReplicas.Hold.Service, mapping.GetNamespace())
 		eri.endpointWatches[fmt.Sprintf("%s:%s", ns, svc)] = true
 	}
 }
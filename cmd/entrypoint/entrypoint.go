@@ -176,8 +176,9 @@ func Main(ctx context.Context, Version string, args ...string) error {
 	})
 
 	snapshot := &atomic.Value{}
+	exporter := &snapshotExporter{}
 	group.Go("snapshot_server", func(ctx context.Context) error {
-		return snapshotServer(ctx, snapshot)
+		return snapshotServer(ctx, snapshot, exporter)
 	})
 	if !envbool("AMBASSADOR_DISABLE_SNAPSHOT_SERVER") {
 		group.Go("external_snapshot_server", func(ctx context.Context) error {
@@ -189,7 +190,7 @@ func Main(ctx context.Context, Version string, args ...string) error {
 		group.Go("watcher", func(ctx context.Context) error {
 			// We need to pass the AmbassadorWatcher to this (Kubernetes/Consul) watcher, so
 			// that it can tell the AmbassadorWatcher when snapshots are posted.
-			return watcher(ctx, ambwatch, snapshot, fastpathCh, clusterID, Version)
+			return watcher(ctx, ambwatch, snapshot, exporter, fastpathCh, clusterID, Version)
 		})
 	}
 
@@ -136,6 +136,11 @@ func GetSnapshotDir() string {
 	return env("snapshot_dir", path.Join(GetAmbassadorConfigBaseDir(), "snapshots"))
 }
 
+// GetSnapshotExportDir is where the ambassador Module's snapshot_export writes its exports.
+func GetSnapshotExportDir() string {
+	return path.Join(GetSnapshotDir(), "export")
+}
+
 func GetEnvoyConfigFile() string {
 	return env("envoy_config_file", path.Join(GetEnvoyDir(), "envoy.json"))
 }
@@ -192,6 +197,13 @@ func ForceEndpoints() bool {
 	return envbool("AMBASSADOR_FORCE_ENDPOINTS")
 }
 
+// IsStrictSchemaValidation reflects AMBASSADOR_STRICT_SCHEMA_VALIDATION, to determine whether
+// resources with fields that our CRDs don't know about are invalid, or (the default) have those
+// fields ignored.
+func IsStrictSchemaValidation() bool {
+	return envbool("AMBASSADOR_STRICT_SCHEMA_VALIDATION")
+}
+
 func GetDiagdBindPort() string {
 	return env("AMBASSADOR_DIAGD_BIND_PORT", "8004")
 }
@@ -82,6 +82,10 @@ func GetInterestingTypes(ctx context.Context, serverTypeList []kates.APIResource
 		"Services":   {{typename: "services.v1."}},                             // New in Kubernetes 0.16.0 (2015-04-28) (v1beta{1..3} before that)
 		"Endpoints":  {{typename: "endpoints.v1.", fieldselector: endpointFs}}, // New in Kubernetes 0.16.0 (2015-04-28) (v1beta{1..3} before that)
 		"K8sSecrets": {{typename: "secrets.v1."}},                              // New in Kubernetes 0.16.0 (2015-04-28) (v1beta{1..3} before that)
+		"EndpointSlices": {
+			{typename: "endpointslices.v1beta1.discovery.k8s.io", fieldselector: endpointFs}, // New in Kubernetes 1.17.0 (2019-12-09), gone in Kubernetes 1.25.0 (2022-08-23)
+			{typename: "endpointslices.v1.discovery.k8s.io", fieldselector: endpointFs},      // New in Kubernetes 1.21.0 (2021-04-08)
+		},
 		"Ingresses": {
 			{typename: "ingresses.v1beta1.extensions"},        // New in Kubernetes 1.2.0 (2016-03-16), gone in Kubernetes 1.22.0 (2021-08-04)
 			{typename: "ingresses.v1beta1.networking.k8s.io"}, // New in Kubernetes 1.14.0 (2019-03-25), gone in Kubernetes 1.22.0 (2021-08-04)
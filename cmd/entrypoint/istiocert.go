@@ -21,6 +21,9 @@ import (
 // istioCertSource implements IstioCertSource: its Watch() method returns an
 // istioCertWatcher, which implements IstioCertWatcher in turn.
 type istioCertSource struct {
+	// secretDir is where Istio writes its certs. If it's empty, there's nothing
+	// to watch.
+	secretDir string
 }
 
 type istioCertWatcher struct {
@@ -28,7 +31,7 @@ type istioCertWatcher struct {
 }
 
 func newIstioCertSource() IstioCertSource {
-	return &istioCertSource{}
+	return &istioCertSource{secretDir: os.Getenv("AMBASSADOR_ISTIO_SECRET_DIR")}
 }
 
 // Watch sets up to watch for an Istio cert on the filesystem, if need be. This
@@ -41,14 +44,14 @@ func (src *istioCertSource) Watch(ctx context.Context) (IstioCertWatcher, error)
 	// channel to hear about new Istio stuff.
 	//
 	// The actual functionality here is currently keyed off the environment
-	// variable AMBASSADOR_ISTIO_SECRET_DIR, but we set the update channel
-	// either way to keep the select logic below simpler. If the environment
-	// variable is unset, we never instantiate the FSWatcher or IstioCert,
-	// so there will never be any updates on the update channel.
+	// variable AMBASSADOR_ISTIO_SECRET_DIR (see newIstioCertSource), but we set
+	// the update channel either way to keep the select logic below simpler. If
+	// the environment variable is unset, we never instantiate the FSWatcher or
+	// IstioCert, so there will never be any updates on the update channel.
 	istioCertUpdateChannel := make(chan IstioCertUpdate)
 
 	// OK. Are we supposed to watch anything?
-	secretDir := os.Getenv("AMBASSADOR_ISTIO_SECRET_DIR")
+	secretDir := src.secretDir
 
 	if secretDir != "" {
 		// Yup, get to it. First, fire up the IstioCert, and tell it to
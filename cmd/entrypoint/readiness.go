@@ -0,0 +1,131 @@
+package entrypoint
+
+import (
+	"context"
+	"net"
+
+	"github.com/datawire/ambassador/v2/cmd/ambex"
+	"github.com/datawire/ambassador/v2/pkg/acp"
+	v3bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+	amb "github.com/datawire/ambassador/v2/pkg/api/getambassador.io/v3alpha1"
+	snapshotTypes "github.com/datawire/ambassador/v2/pkg/snapshot/v1"
+	"github.com/datawire/dlib/dlog"
+)
+
+// readinessModule is the part of the ambassador Module that health-based readiness cares
+// about. diagd validates it as well, but we read the Module as it was written, so we check
+// the range again here.
+type readinessModule struct {
+	HealthyClusterFraction float64 `json:"readiness_healthy_cluster_fraction"`
+}
+
+// requiredHealthyFraction returns the fraction of upstream clusters that the ambassador
+// Module wants healthy before we're ready. Zero, the default, means readiness only depends on
+// the configuration having been processed. The Module may be nil.
+func requiredHealthyFraction(ctx context.Context, module *amb.Module) float64 {
+	if module == nil {
+		return 0
+	}
+
+	rm := readinessModule{}
+	if err := convert(module.Spec.Config, &rm); err != nil {
+		dlog.Errorf(ctx, "error parsing ambassador module readiness: %v", err)
+		return 0
+	}
+
+	if (rm.HealthyClusterFraction < 0) || (rm.HealthyClusterFraction > 1) {
+		return 0
+	}
+
+	return rm.HealthyClusterFraction
+}
+
+// ambassadorModule finds the ambassador Module in a snapshot that's been decoded from JSON,
+// whether it's a CRD or an annotation, or returns nil if there isn't one for our ambassador_id.
+func ambassadorModule(ctx context.Context, s *snapshotTypes.KubernetesSnapshot) *amb.Module {
+	// Annotations don't survive being sent around as JSON, so find them again.
+	parseAnnotations(ctx, s)
+
+	return findAmbassadorModule(ctx, s)
+}
+
+// findAmbassadorModule is ambassadorModule for a snapshot whose annotations have already been
+// parsed, like the watcher's own.
+func findAmbassadorModule(ctx context.Context, s *snapshotTypes.KubernetesSnapshot) *amb.Module {
+	var module *amb.Module
+
+	for _, a := range s.Annotations {
+		if m, ok := a.(*amb.Module); ok && m.GetName() == "ambassador" && include(GetAmbId(ctx, a)) {
+			module = m
+		}
+	}
+
+	for _, m := range s.Modules {
+		if m.GetName() == "ambassador" && include(m.Spec.AmbassadorID) {
+			module = m
+		}
+	}
+
+	return module
+}
+
+// healthCheckedClusters lists the clusters in an envoy config that readiness counts, and
+// whether each is actively health checked. Ambassador's own clusters -- the ones whose
+// endpoints are all on the loopback interface, like diagd's -- aren't counted unless they're
+// health checked: they'd only ever count as healthy, and make the fraction look better than
+// the upstreams really are.
+func healthCheckedClusters(config *v3bootstrap.Bootstrap) map[string]bool {
+	healthChecked := map[string]bool{}
+
+	for _, cluster := range config.GetStaticResources().GetClusters() {
+		checked := len(cluster.HealthChecks) > 0
+		if !checked && loopbackCluster(cluster) {
+			continue
+		}
+		healthChecked[cluster.Name] = checked
+	}
+
+	return healthChecked
+}
+
+// loopbackCluster returns true IFF a cluster has endpoints, and all of them are on the
+// loopback interface.
+func loopbackCluster(cluster *v3cluster.Cluster) bool {
+	found := false
+
+	for _, lbEndpoints := range cluster.GetLoadAssignment().GetEndpoints() {
+		for _, lbEndpoint := range lbEndpoints.GetLbEndpoints() {
+			address := lbEndpoint.GetEndpoint().GetAddress().GetSocketAddress().GetAddress()
+			if address != "localhost" {
+				ip := net.ParseIP(address)
+				if ip == nil || !ip.IsLoopback() {
+					return false
+				}
+			}
+			found = true
+		}
+	}
+
+	return found
+}
+
+// updateUpstreamReadiness tells the AmbassadorWatcher what health-based readiness looks like
+// for a snapshot that diagd has just finished processing, given the snapshot's ambassador
+// Module. We only bother reading the envoy config if the Module has turned health-based
+// readiness on.
+func updateUpstreamReadiness(ctx context.Context, ambwatch *acp.AmbassadorWatcher, module *amb.Module) {
+	required := requiredHealthyFraction(ctx, module)
+	if required == 0 {
+		ambwatch.SetUpstreamReadiness(0, nil)
+		return
+	}
+
+	msg, err := ambex.Decode(ctx, GetEnvoyConfigFile())
+	if err != nil {
+		dlog.Errorf(ctx, "error decoding envoy config for readiness: %v", err)
+		return
+	}
+
+	ambwatch.SetUpstreamReadiness(required, healthCheckedClusters(msg.(*v3bootstrap.Bootstrap)))
+}
@@ -4,13 +4,20 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
+	"strings"
 
 	"github.com/datawire/ambassador/v2/pkg/kates"
+	"github.com/datawire/dlib/dlog"
 )
 
 type resourceValidator struct {
 	invalid        map[string]*kates.Unstructured
 	katesValidator *kates.Validator
+	// strict makes fields that our CRDs don't know about invalid, rather than ignored.
+	strict bool
+	// warned is the resourceVersion of each resource that's had its misspelled fields warned
+	// about, so that a resource that's seen again without changing isn't warned about again.
+	warned map[string]string
 }
 
 //go:embed crds.yaml
@@ -29,12 +36,29 @@ func newResourceValidator() (*resourceValidator, error) {
 	return &resourceValidator{
 		katesValidator: katesValidator,
 		invalid:        map[string]*kates.Unstructured{},
+		strict:         IsStrictSchemaValidation(),
+		warned:         map[string]string{},
 	}, nil
 }
 
-func (v *resourceValidator) isValid(ctx context.Context, un *kates.Unstructured) bool {
+// resourceKey identifies a resource across the times it's validated.
+func resourceKey(un *kates.Unstructured) string {
 	key := string(un.GetUID())
+	if key == "" {
+		// Resources from the Fake (or a file) don't have UIDs, and if they all shared the
+		// empty key, only the last invalid one would be reported, and any valid one would
+		// clear it.
+		key = fmt.Sprintf("%s %s/%s", un.GetKind(), un.GetNamespace(), un.GetName())
+	}
+	return key
+}
+
+func (v *resourceValidator) isValid(ctx context.Context, un *kates.Unstructured) bool {
+	key := resourceKey(un)
 	err := v.katesValidator.Validate(ctx, un)
+	if err == nil {
+		err = v.checkUnknownFields(ctx, un, key)
+	}
 	if err != nil {
 		fmt.Printf("validation error: %s %s/%s -- %s\n", un.GetKind(), un.GetNamespace(), un.GetName(), err.Error())
 		copy := un.DeepCopy()
@@ -54,3 +78,102 @@ func (v *resourceValidator) getInvalid() []*kates.Unstructured {
 	}
 	return result
 }
+
+// checkUnknownFields looks for fields in a resource that our CRDs don't know about. Those are
+// usually from a newer CRD than we were built with, in the middle of an upgrade, so they only get
+// a debug log and are otherwise ignored (the typed resource just doesn't have them). One that
+// looks like a misspelling of a field we do know gets a warning, though, since then it's
+// probably a mistake that's quietly doing nothing, but only once for each resourceVersion:
+// resources are validated again whenever they're seen, changed or not. In strict mode, any
+// unknown field makes the resource invalid.
+func (v *resourceValidator) checkUnknownFields(ctx context.Context, un *kates.Unstructured, key string) error {
+	unknown, err := v.katesValidator.UnknownFields(ctx, un)
+	if err != nil {
+		return err
+	}
+
+	version := un.GetResourceVersion()
+	warn, misspelled := !v.strict, false
+	// Without a resourceVersion (from the Fake, or a file), there's no telling whether it's
+	// changed, so it's always warned about.
+	if previous, ok := v.warned[key]; ok && version != "" && previous == version {
+		warn = false
+	}
+
+	var errs []string
+	for _, field := range unknown {
+		msg := fmt.Sprintf("unknown field %s", field.Path)
+		if suggestion := misspelledField(field.Name, field.Known); suggestion != "" {
+			msg += fmt.Sprintf(" (did you mean %s?)", suggestion)
+			if warn {
+				dlog.Warnf(ctx, "%s %s/%s: %s", un.GetKind(), un.GetNamespace(), un.GetName(), msg)
+			}
+			misspelled = true
+		} else if !v.strict {
+			dlog.Debugf(ctx, "%s %s/%s: ignoring %s", un.GetKind(), un.GetNamespace(), un.GetName(), msg)
+		}
+		errs = append(errs, msg)
+	}
+
+	if misspelled {
+		v.warned[key] = version
+	} else {
+		delete(v.warned, key)
+	}
+
+	if v.strict && len(errs) > 0 {
+		// The same one-error-per-line format as the kates validator.
+		return fmt.Errorf("%s\n", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// misspelledField returns the field in known that name is most likely a misspelling of, or ""
+// if it isn't close to any of them. Case and underscores don't count, so "retryPolicy" is a
+// misspelling of "retry_policy"; otherwise it's a couple of edits at most, and fewer for short
+// names.
+func misspelledField(name string, known []string) string {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.ReplaceAll(s, "_", ""))
+	}
+
+	best, bestDistance := "", 0
+	for _, candidate := range known {
+		distance := editDistance(normalize(name), normalize(candidate))
+		if distance > 2 || distance*3 > len(candidate) {
+			continue
+		}
+		if best == "" || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,68 @@
+package entrypoint
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/v2/pkg/kates"
+	"github.com/datawire/dlib/dlog"
+)
+
+func TestUnknownFieldWarningsOncePerVersion(t *testing.T) {
+	logger, hook := logtest.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	ctx := dlog.WithLogger(context.Background(), dlog.WrapLogrus(logger))
+
+	v, err := newResourceValidator()
+	require.NoError(t, err)
+	v.strict = false
+
+	objs, err := kates.ParseManifestsToUnstructured(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: typo
+  namespace: default
+  uid: 8d3f5a6e-0000-4000-8000-000000000001
+  resourceVersion: "1"
+spec:
+  hostname: "*"
+  prefix: /typo/
+  service: typo.default
+  retry_policy:
+    retry_onn: 5xx
+`)
+	require.NoError(t, err)
+	require.Len(t, objs, 1)
+	un := objs[0].(*kates.Unstructured)
+
+	warnings := func() int {
+		count := 0
+		for _, entry := range hook.AllEntries() {
+			if entry.Level == logrus.WarnLevel {
+				count++
+			}
+		}
+		hook.Reset()
+		return count
+	}
+
+	// The misspelling is warned about the first time...
+	assert.True(t, v.isValid(ctx, un))
+	assert.Equal(t, 1, warnings())
+
+	// ...but not every time the same resourceVersion comes around again...
+	assert.True(t, v.isValid(ctx, un))
+	assert.Equal(t, 0, warnings())
+
+	// ...only once it's changed.
+	un.SetResourceVersion("2")
+	assert.True(t, v.isValid(ctx, un))
+	assert.Equal(t, 1, warnings())
+}
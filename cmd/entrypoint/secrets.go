@@ -195,6 +195,13 @@ func findSecretRefs(ctx context.Context, resource kates.Object, secretNamespacin
 			secretRef(r.GetNamespace(), r.Spec.CASecret, secretNamespacing, action)
 		}
 
+		for _, secret := range r.Spec.AdditionalSecrets {
+			if r.Spec.SecretNamespacing != nil {
+				secretNamespacing = *r.Spec.SecretNamespacing
+			}
+			secretRef(r.GetNamespace(), secret, secretNamespacing, action)
+		}
+
 	case *amb.Module:
 		// This whole thing is a hack. We probably _should_ check to make sure that
 		// this is an Ambassador Module or a TLS Module, but, well, those're the only
@@ -0,0 +1,209 @@
+package entrypoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	amb "github.com/datawire/ambassador/v2/pkg/api/getambassador.io/v3alpha1"
+	snapshotTypes "github.com/datawire/ambassador/v2/pkg/snapshot/v1"
+	"github.com/datawire/dlib/dlog"
+)
+
+// DefaultSnapshotExportKeep is how many earlier exports are kept around, as path.1, path.2 and
+// so on, when snapshot_export doesn't say.
+const DefaultSnapshotExportKeep = 3
+
+// DefaultSnapshotExportIntervalSeconds is the least time between automatic exports when
+// snapshot_export doesn't say. Exports happen as the watcher hands snapshots to diagd, so
+// exporting every one of them would slow down every reconfiguration.
+const DefaultSnapshotExportIntervalSeconds = 60
+
+// snapshotExportModule is the part of the ambassador Module that snapshot export cares about.
+// As with readiness, diagd validates it too, but we read the Module as it was written.
+type snapshotExportModule struct {
+	SnapshotExport *snapshotExportConfig `json:"snapshot_export"`
+}
+
+type snapshotExportConfig struct {
+	// Path is the name of the file each snapshot is written to, in GetSnapshotExportDir. It
+	// can't be anywhere else: whoever can edit the Module can't have us write over, or move
+	// aside, anything but earlier exports.
+	Path string `json:"path"`
+	// IntervalSeconds is the least time between automatic exports, so that a busy cluster
+	// doesn't rewrite the file with every change. Zero exports every snapshot.
+	IntervalSeconds *float64 `json:"interval_s"`
+	// Keep is how many earlier exports to keep.
+	Keep *int `json:"keep"`
+
+	// fullPath is Path in GetSnapshotExportDir.
+	fullPath string
+}
+
+// errNoSnapshotExport is what exporting on demand gets without a snapshot_export path to
+// export to.
+var errNoSnapshotExport = errors.New("the ambassador Module has no snapshot_export path")
+
+// snapshotExporter writes the snapshots that go to diagd out to a file, for support bundles.
+// Secrets are redacted the same way they are for the external snapshot server.
+type snapshotExporter struct {
+	mutex      sync.Mutex
+	lastExport time.Time
+}
+
+// Notify exports a snapshot that's just been handed to diagd, if the ambassador Module (which
+// may be nil) asks for that and its interval is up. Nothing gets decoded otherwise, so that
+// leaving snapshot_export off costs nothing.
+func (e *snapshotExporter) Notify(ctx context.Context, snapJSON []byte, module *amb.Module) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	config, err := snapshotExport(module)
+	if err != nil {
+		dlog.Errorf(ctx, "error exporting snapshot: %v", err)
+		return
+	}
+	if config == nil {
+		return
+	}
+
+	now := time.Now()
+	if !e.lastExport.IsZero() && now.Sub(e.lastExport).Seconds() < *config.IntervalSeconds {
+		return
+	}
+
+	var snap *snapshotTypes.Snapshot
+	if err := json.Unmarshal(snapJSON, &snap); err != nil {
+		dlog.Errorf(ctx, "error decoding snapshot for export: %v", err)
+		return
+	}
+	if snap == nil {
+		return
+	}
+
+	if err := writeSnapshotExport(snap, config); err != nil {
+		dlog.Errorf(ctx, "error exporting snapshot to %s: %v", config.Path, err)
+		return
+	}
+	e.lastExport = now
+}
+
+// Export exports a snapshot right away, whatever the interval, and returns the path it went to.
+func (e *snapshotExporter) Export(ctx context.Context, snapJSON []byte) (string, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	snap, config, err := decodeSnapshotExport(ctx, snapJSON)
+	if err != nil {
+		return "", err
+	}
+	if config == nil {
+		return "", errNoSnapshotExport
+	}
+
+	if err := writeSnapshotExport(snap, config); err != nil {
+		return "", err
+	}
+	e.lastExport = time.Now()
+	return config.fullPath, nil
+}
+
+// decodeSnapshotExport decodes a snapshot, and the ambassador Module's snapshot_export from it.
+// The config is nil if the Module doesn't have a usable one.
+func decodeSnapshotExport(ctx context.Context, snapJSON []byte) (*snapshotTypes.Snapshot, *snapshotExportConfig, error) {
+	var snap *snapshotTypes.Snapshot
+	if err := json.Unmarshal(snapJSON, &snap); err != nil {
+		return nil, nil, err
+	}
+	if snap == nil || snap.Kubernetes == nil {
+		return snap, nil, nil
+	}
+
+	config, err := snapshotExport(ambassadorModule(ctx, snap.Kubernetes))
+	if err != nil {
+		return nil, nil, err
+	}
+	return snap, config, nil
+}
+
+// snapshotExport returns the ambassador Module's snapshot_export, or nil if the Module (which may
+// be nil) doesn't have a usable one.
+func snapshotExport(module *amb.Module) (*snapshotExportConfig, error) {
+	if module == nil {
+		return nil, nil
+	}
+
+	em := snapshotExportModule{}
+	if err := convert(module.Spec.Config, &em); err != nil {
+		return nil, fmt.Errorf("error parsing ambassador module snapshot_export: %w", err)
+	}
+
+	config := em.SnapshotExport
+	if config == nil || config.Path == "" {
+		return nil, nil
+	}
+	if !validSnapshotExportName(config.Path) {
+		return nil, fmt.Errorf("snapshot_export path %q must be a file name, with no directory", config.Path)
+	}
+	config.fullPath = filepath.Join(GetSnapshotExportDir(), config.Path)
+	if config.IntervalSeconds == nil || *config.IntervalSeconds < 0 {
+		interval := float64(DefaultSnapshotExportIntervalSeconds)
+		config.IntervalSeconds = &interval
+	}
+	if config.Keep == nil || *config.Keep < 0 {
+		keep := DefaultSnapshotExportKeep
+		config.Keep = &keep
+	}
+	return config, nil
+}
+
+// validSnapshotExportName returns whether name is a plain file name, which can only be a file
+// in the export directory.
+func validSnapshotExportName(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name &&
+		!strings.ContainsAny(name, `/\`)
+}
+
+// writeSnapshotExport redacts a snapshot and writes it to the config's path in the export
+// directory, after moving the earlier exports along: path becomes path.1, path.1 becomes
+// path.2, and so on, with whatever was in path.<keep> dropped. The new export is written
+// alongside and then renamed into place, so nobody picking up the file mid-write gets half a
+// snapshot.
+func writeSnapshotExport(snap *snapshotTypes.Snapshot, config *snapshotExportConfig) error {
+	if err := snap.Sanitize(); err != nil {
+		return err
+	}
+	exported, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := config.fullPath
+	if err := ensureDir(GetSnapshotExportDir()); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, exported, 0600); err != nil {
+		return err
+	}
+
+	for i := *config.Keep - 1; i >= 0; i-- {
+		from := path
+		if i > 0 {
+			from = fmt.Sprintf("%s.%d", path, i)
+		}
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,135 @@
+package entrypoint
+
+import (
+	"context"
+	"encoding/json"
+
+	amb "github.com/datawire/ambassador/v2/pkg/api/getambassador.io/v3alpha1"
+	snapshotTypes "github.com/datawire/ambassador/v2/pkg/snapshot/v1"
+	"github.com/datawire/dlib/dlog"
+)
+
+// snapshotLimitModule is the part of the ambassador Module that the snapshot soft limit cares
+// about. As with readiness, diagd validates it too, but we read the Module as it was written.
+type snapshotLimitModule struct {
+	// SoftLimitBytes is the size past which a snapshot is big enough to worry about. Zero
+	// (the default) means there's no limit.
+	SoftLimitBytes int `json:"snapshot_soft_limit_bytes"`
+
+	// Shed determines whether a snapshot over the soft limit gets resources shed from it, or
+	// (the default) is only warned about.
+	Shed bool `json:"snapshot_shed"`
+}
+
+// snapshotLimits returns the ambassador Module's snapshot soft limit settings. The Module may
+// be nil, in which case there's no limit.
+func snapshotLimits(ctx context.Context, module *amb.Module) snapshotLimitModule {
+	lm := snapshotLimitModule{}
+	if module == nil {
+		return lm
+	}
+
+	if err := convert(module.Spec.Config, &lm); err != nil {
+		dlog.Errorf(ctx, "error parsing ambassador module snapshot limits: %v", err)
+		return snapshotLimitModule{}
+	}
+
+	if lm.SoftLimitBytes < 0 {
+		lm.SoftLimitBytes = 0
+	}
+
+	return lm
+}
+
+// snapshotShedSteps is the order in which limitSnapshot sheds things, least important first.
+// Nothing here changes what Envoy ends up doing with a request:
+//
+//   - Pods, Deployments, ConfigMaps and Argo resources are only there for the Ambassador
+//     Agent, which will report less about the cluster without them.
+//
+// Deltas are never shed: diagd only resets its cache when there are deltas, so a snapshot
+// without them would have it keep serving whatever it had cached for the resources that
+// changed. Nor are invalid resources, even though they were never used: they're how diagd
+// reports their errors, and a snapshot too big to fit is exactly when those matter.
+//
+// If the snapshot is still over the limit after all of these, it goes out as it is: shedding
+// anything else would change routing.
+var snapshotShedSteps = []struct {
+	name string
+	// sections returns what shed will drop, so that its size can be known up front.
+	sections func(sn *snapshotTypes.Snapshot) interface{}
+	shed     func(sn *snapshotTypes.Snapshot)
+}{
+	{
+		"agent resources",
+		func(sn *snapshotTypes.Snapshot) interface{} {
+			k := sn.Kubernetes
+			return []interface{}{k.Pods, k.Deployments, k.ConfigMaps, k.ArgoRollouts, k.ArgoApplications}
+		},
+		func(sn *snapshotTypes.Snapshot) {
+			sn.Kubernetes.Pods = nil
+			sn.Kubernetes.Deployments = nil
+			sn.Kubernetes.ConfigMaps = nil
+			sn.Kubernetes.ArgoRollouts = nil
+			sn.Kubernetes.ArgoApplications = nil
+		},
+	},
+}
+
+// limitSnapshot encodes sn, checking it against the ambassador Module's soft limit. Under the
+// limit, the encoding is returned as it is. Over it, there's a warning, and if shedding is on,
+// the things in snapshotShedSteps are dropped from a copy of sn until it looks like it'll
+// fit, using the size of what each step drops, and the copy is encoded once more. The
+// KubernetesSnapshot sn points to is never changed: it's still needed for the next snapshot.
+func limitSnapshot(ctx context.Context, sn *snapshotTypes.Snapshot, module *amb.Module) ([]byte, error) {
+	snapshotJSON, err := json.MarshalIndent(sn, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	limits := snapshotLimits(ctx, module)
+	limit := limits.SoftLimitBytes
+	if limit == 0 || len(snapshotJSON) <= limit {
+		return snapshotJSON, nil
+	}
+
+	if !limits.Shed {
+		dlog.Warnf(ctx, "WATCHER: snapshot is %d bytes, over the soft limit of %d; set snapshot_shed in the ambassador Module to shed resources from it",
+			len(snapshotJSON), limit)
+		return snapshotJSON, nil
+	}
+
+	shed := *sn
+	k8s := *sn.Kubernetes
+	shed.Kubernetes = &k8s
+
+	// The sections are measured without indentation, so this errs toward shedding more than
+	// is needed rather than less.
+	estimate := len(snapshotJSON)
+	for _, step := range snapshotShedSteps {
+		sectionJSON, err := json.Marshal(step.sections(&shed))
+		if err != nil {
+			return nil, err
+		}
+		step.shed(&shed)
+		estimate -= len(sectionJSON)
+
+		dlog.Warnf(ctx, "WATCHER: snapshot is %d bytes, over the soft limit of %d; shedding %s, leaving about %d bytes",
+			len(snapshotJSON), limit, step.name, estimate)
+
+		if estimate <= limit {
+			break
+		}
+	}
+
+	shedJSON, err := json.MarshalIndent(&shed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(shedJSON) > limit {
+		dlog.Warnf(ctx, "WATCHER: snapshot is still %d bytes, over the soft limit of %d, with nothing left that's safe to shed",
+			len(shedJSON), limit)
+	}
+	return shedJSON, nil
+}
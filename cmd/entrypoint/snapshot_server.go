@@ -36,11 +36,33 @@ func externalSnapshotServer(ctx context.Context, snapshot *atomic.Value) error {
 	return s.ListenAndServe(ctx, fmt.Sprintf(":%d", ExternalSnapshotPort))
 }
 
-func snapshotServer(ctx context.Context, snapshot *atomic.Value) error {
+func snapshotServer(ctx context.Context, snapshot *atomic.Value, exporter *snapshotExporter) error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/snapshot", func(w http.ResponseWriter, r *http.Request) {
 		_, _ = w.Write(snapshot.Load().([]byte))
 	})
+	// POSTing here exports the current snapshot to the ambassador Module's snapshot_export
+	// path right away, for when a support case can't wait for the next automatic export.
+	mux.HandleFunc("/snapshot-export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		snapJSON, _ := snapshot.Load().([]byte)
+		if snapJSON == nil {
+			http.Error(w, "no snapshot yet", http.StatusServiceUnavailable)
+			return
+		}
+		path, err := exporter.Export(ctx, snapJSON)
+		if err == errNoSnapshotExport {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = fmt.Fprintln(w, path)
+	})
 
 	s := &dhttp.ServerConfig{
 		Handler: mux,
@@ -0,0 +1,173 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3core "github.com/datawire/ambassador/v2/pkg/api/envoy/config/core/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	http "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// prefixRoutes returns every route in the listener's first virtual host for the given prefix,
+// in the order Envoy tries them.
+func prefixRoutes(listener *v3listener.Listener, prefix string) []*route.Route {
+	var routes []*route.Route
+	for _, fc := range listener.FilterChains {
+		hcm := entrypoint.ChainHCM(fc)
+		if hcm == nil {
+			continue
+		}
+		if rs, ok := hcm.RouteSpecifier.(*http.HttpConnectionManager_RouteConfig); ok {
+			for _, vh := range rs.RouteConfig.VirtualHosts {
+				for _, r := range vh.Routes {
+					if r.GetMatch().GetPrefix() == prefix {
+						routes = append(routes, r)
+					}
+				}
+				return routes
+			}
+		}
+	}
+	return routes
+}
+
+// headerValue returns the value headers sets for name, or "" if it doesn't.
+func headerValue(headers []*v3core.HeaderValueOption, name string) string {
+	for _, h := range headers {
+		if h.GetHeader().GetKey() == name {
+			return h.GetHeader().GetValue()
+		}
+	}
+	return ""
+}
+
+func TestABHeader(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: checkout
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /checkout/
+  service: checkout.default
+  ab_header:
+    name: x-experiment
+    value: new-checkout
+    percent: 20
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: sticky
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /sticky/
+  service: sticky.default
+  ab_header:
+    name: x-experiment
+    value: new-sticky
+    percent: 30
+    cookie: ab-sticky
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: none
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /none/
+  service: none.default
+  ab_header:
+    name: x-experiment
+    value: never
+    percent: 0
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: all
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /all/
+  service: all.default
+  ab_header:
+    name: x-experiment
+    value: always
+    percent: 100
+`, "all", "cluster_all_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// 20% of checkout's requests get the header, and the rest don't, but they all go to the
+	// same cluster.
+	routes := prefixRoutes(listener, "/checkout/")
+	require.Len(t, routes, 1)
+	wc := routes[0].GetRoute().GetWeightedClusters()
+	require.NotNil(t, wc)
+	assert.Equal(t, uint32(100), wc.GetTotalWeight().GetValue())
+	require.Len(t, wc.Clusters, 2)
+	assert.Equal(t, "cluster_checkout_default_default", wc.Clusters[0].Name)
+	assert.Equal(t, uint32(20), wc.Clusters[0].GetWeight().GetValue())
+	assert.Equal(t, "new-checkout", headerValue(wc.Clusters[0].RequestHeadersToAdd, "x-experiment"))
+	assert.Equal(t, "cluster_checkout_default_default", wc.Clusters[1].Name)
+	assert.Equal(t, uint32(80), wc.Clusters[1].GetWeight().GetValue())
+	assert.Empty(t, wc.Clusters[1].RequestHeadersToAdd)
+	assert.Empty(t, wc.Clusters[0].ResponseHeadersToAdd)
+
+	// With a cookie, the draw says which way it went, and a client that already has the
+	// cookie skips the draw and goes the same way again.
+	routes = prefixRoutes(listener, "/sticky/")
+	require.Len(t, routes, 3)
+
+	cookieRegex := func(r *route.Route) string {
+		for _, h := range r.GetMatch().GetHeaders() {
+			if h.Name == "cookie" {
+				return h.GetSafeRegexMatch().GetRegex()
+			}
+		}
+		return ""
+	}
+
+	assert.Equal(t, `(.*;\s*)?ab\-sticky=b(;.*)?`, cookieRegex(routes[0]))
+	assert.Equal(t, "cluster_sticky_default_default", routes[0].GetRoute().GetCluster())
+	assert.Equal(t, "new-sticky", headerValue(routes[0].RequestHeadersToAdd, "x-experiment"))
+
+	assert.Equal(t, `(.*;\s*)?ab\-sticky=a(;.*)?`, cookieRegex(routes[1]))
+	assert.Equal(t, "cluster_sticky_default_default", routes[1].GetRoute().GetCluster())
+	assert.Empty(t, headerValue(routes[1].RequestHeadersToAdd, "x-experiment"))
+
+	assert.Empty(t, cookieRegex(routes[2]))
+	wc = routes[2].GetRoute().GetWeightedClusters()
+	require.NotNil(t, wc)
+	require.Len(t, wc.Clusters, 2)
+	assert.Equal(t, uint32(30), wc.Clusters[0].GetWeight().GetValue())
+	assert.Equal(t, "ab-sticky=b; Path=/", headerValue(wc.Clusters[0].ResponseHeadersToAdd, "set-cookie"))
+	assert.Equal(t, uint32(70), wc.Clusters[1].GetWeight().GetValue())
+	assert.Equal(t, "ab-sticky=a; Path=/", headerValue(wc.Clusters[1].ResponseHeadersToAdd, "set-cookie"))
+
+	// 0% and 100% don't need a draw at all.
+	routes = prefixRoutes(listener, "/none/")
+	require.Len(t, routes, 1)
+	assert.Equal(t, "cluster_none_default_default", routes[0].GetRoute().GetCluster())
+	assert.Empty(t, headerValue(routes[0].RequestHeadersToAdd, "x-experiment"))
+
+	routes = prefixRoutes(listener, "/all/")
+	require.Len(t, routes, 1)
+	assert.Equal(t, "cluster_all_default_default", routes[0].GetRoute().GetCluster())
+	assert.Equal(t, "always", headerValue(routes[0].RequestHeadersToAdd, "x-experiment"))
+}
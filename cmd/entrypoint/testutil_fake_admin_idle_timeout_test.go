@@ -0,0 +1,93 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3core "github.com/datawire/ambassador/v2/pkg/api/envoy/config/core/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// findAdminSocketOption returns the admin server's socket option with the given level and name,
+// if any.
+func findAdminSocketOption(config *bootstrap.Bootstrap, level, name int64) *v3core.SocketOption {
+	for _, opt := range config.Admin.SocketOptions {
+		if opt.Level == level && opt.Name == name {
+			return opt
+		}
+	}
+	return nil
+}
+
+func TestAdminIdleTimeout(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    admin_idle_timeout_ms: 90500
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: foo
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /foo/
+  service: foo.default
+`)
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "foo"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_foo_default_default")) != nil
+	})
+	require.NoError(t, err)
+
+	// The admin server stays where it was...
+	require.NotNil(t, config.Admin)
+	assert.Equal(t, "127.0.0.1", config.Admin.Address.GetSocketAddress().Address)
+	assert.Equal(t, uint32(8001), config.Admin.Address.GetSocketAddress().GetPortValue())
+
+	// ...but idle connections to it get probed, after the timeout rounded up to a second.
+	opt := findAdminSocketOption(config, 1, 9) // SOL_SOCKET, SO_KEEPALIVE
+	require.NotNil(t, opt)
+	assert.Equal(t, int64(1), opt.GetIntValue())
+	opt = findAdminSocketOption(config, 6, 4) // IPPROTO_TCP, TCP_KEEPIDLE
+	require.NotNil(t, opt)
+	assert.Equal(t, int64(91), opt.GetIntValue())
+
+	// Zero means no timeout, just as if it weren't set.
+	err = f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    admin_idle_timeout_ms: 0
+`)
+	require.NoError(t, err)
+	f.Flush()
+
+	config, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return config.Admin != nil && len(config.Admin.SocketOptions) == 0
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, config.Admin.Address)
+}
@@ -0,0 +1,115 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	extauthz "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/ext_authz/v3"
+	envoytype "github.com/datawire/ambassador/v2/pkg/api/envoy/type/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func authFailureModeConfig(t *testing.T, authService string) (*extauthz.ExtAuthz, *v3listener.Listener) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(authService+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("private")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: healthz
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /healthz/
+  service: healthz.default
+  bypass_auth: true
+`, "healthz", "cluster_healthz_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	hcm := entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+
+	for _, filter := range hcm.HttpFilters {
+		if filter.Name == "envoy.filters.http.ext_authz" {
+			authz := &extauthz.ExtAuthz{}
+			require.NoError(t, ptypes.UnmarshalAny(filter.GetTypedConfig(), authz))
+			return authz, listener
+		}
+	}
+
+	require.Fail(t, "no ext_authz filter")
+	return nil, nil
+}
+
+// bypassesAuth checks that the route for a cluster has the ext_authz filter turned off.
+func bypassesAuth(t *testing.T, listener *v3listener.Listener, cluster string) bool {
+	r := findRoute(listener, func(r *route.Route) bool {
+		return r.GetRoute().GetCluster() == cluster
+	})
+	require.NotNil(t, r)
+	cfg, ok := r.TypedPerFilterConfig["envoy.filters.http.ext_authz"]
+	if !ok {
+		return false
+	}
+	perRoute := &extauthz.ExtAuthzPerRoute{}
+	require.NoError(t, ptypes.UnmarshalAny(cfg, perRoute))
+	return perRoute.GetDisabled()
+}
+
+func TestAuthFailureModeClosed(t *testing.T) {
+	authz, listener := authFailureModeConfig(t, `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: AuthService
+metadata:
+  name: auth
+  namespace: default
+spec:
+  auth_service: auth.default:3000
+  proto: http
+  failure_mode_allow: false
+  status_on_error:
+    code: 503
+`)
+
+	// When the AuthService is down, requests fail, with the status we asked for...
+	assert.False(t, authz.FailureModeAllow)
+	assert.Equal(t, envoytype.StatusCode_ServiceUnavailable, authz.GetStatusOnError().GetCode())
+
+	// ...except on routes that never ask it anything.
+	assert.False(t, bypassesAuth(t, listener, "cluster_private_default_default"))
+	assert.True(t, bypassesAuth(t, listener, "cluster_healthz_default_default"))
+}
+
+func TestAuthFailureModeOpen(t *testing.T) {
+	authz, listener := authFailureModeConfig(t, `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: AuthService
+metadata:
+  name: auth
+  namespace: default
+spec:
+  auth_service: auth.default:3000
+  proto: http
+  failure_mode_allow: true
+  status_on_error:
+    code: 599
+`)
+
+	// Requests go through when the AuthService is down, and the status Envoy wouldn't have
+	// taken is dropped rather than breaking the filter.
+	assert.True(t, authz.FailureModeAllow)
+	assert.Nil(t, authz.GetStatusOnError())
+
+	assert.True(t, bypassesAuth(t, listener, "cluster_healthz_default_default"))
+}
@@ -0,0 +1,104 @@
+package entrypoint_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3tls "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/transport_sockets/tls/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hasNotice says whether any of the notices for key mentions substr.
+func hasNotice(diag *entrypoint.Diagnostics, key, substr string) bool {
+	for _, notice := range diag.NoticesFor(key) {
+		if strings.Contains(notice, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAutoHTTPProtocol(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: inventory
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /inventory/
+  service: https://inventory.example.com
+  auto_http_protocol: true
+  cluster_idle_timeout_ms: 30000
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: streams
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /streams.Streams/
+  rewrite: /streams.Streams/
+  service: https://streams.example.com
+  grpc: true
+  auto_http_protocol: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: plain
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /plain/
+  service: plain.default
+  auto_http_protocol: true
+`, "plain", "cluster_plain_default")
+
+	// inventory offers both protocols and lets ALPN pick, with HTTP/1.1 if the upstream
+	// doesn't choose h2. The idle timeout we generate moves into the same options.
+	cluster := FindCluster(config, ClusterNameContains("inventory_example_com"))
+	require.NotNil(t, cluster)
+	assert.Nil(t, cluster.HttpProtocolOptions)
+	assert.Nil(t, cluster.Http2ProtocolOptions)
+	options := httpProtocolOptions(t, cluster)
+	require.NotNil(t, options.GetAutoConfig())
+	assert.NotNil(t, options.GetAutoConfig().GetHttpProtocolOptions())
+	assert.NotNil(t, options.GetAutoConfig().GetHttp2ProtocolOptions())
+	assert.Equal(t, 30*time.Second, options.CommonHttpProtocolOptions.IdleTimeout.AsDuration())
+
+	require.NotNil(t, cluster.TransportSocket)
+	tlsCtx := &v3tls.UpstreamTlsContext{}
+	require.NoError(t, ptypes.UnmarshalAny(cluster.TransportSocket.GetTypedConfig(), tlsCtx))
+	assert.Equal(t, []string{"h2", "http/1.1"}, tlsCtx.CommonTlsContext.AlpnProtocols)
+
+	// streams is gRPC, which only works over HTTP/2, so it stays HTTP/2 only...
+	cluster = FindCluster(config, ClusterNameContains("streams_example_com"))
+	require.NotNil(t, cluster)
+	assert.NotNil(t, cluster.Http2ProtocolOptions)
+	assert.Empty(t, cluster.TypedExtensionProtocolOptions)
+
+	// ...and plain doesn't originate TLS, so there's no ALPN to negotiate with.
+	cluster = FindCluster(config, ClusterNameContains("cluster_plain_default"))
+	require.NotNil(t, cluster)
+	assert.Nil(t, cluster.TransportSocket)
+	assert.Empty(t, cluster.TypedExtensionProtocolOptions)
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Mapping("plain") != nil && len(diag.NoticesFor("plain.default")) > 0
+	})
+	require.NoError(t, err)
+	assert.True(t, hasNotice(diag, "streams.default", "ignoring auto_http_protocol"))
+	assert.True(t, hasNotice(diag, "plain.default", "auto_http_protocol only applies when originating TLS"))
+	assert.False(t, hasNotice(diag, "inventory.default", "auto_http_protocol"))
+}
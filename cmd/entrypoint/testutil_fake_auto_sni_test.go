@@ -0,0 +1,99 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+	v3tls "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/transport_sockets/tls/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upstreamSNI returns the SNI a cluster's TLS context sends when the request has no Host to
+// send instead.
+func upstreamSNI(t *testing.T, cluster *v3cluster.Cluster) string {
+	require.NotNil(t, cluster.TransportSocket)
+	tlsCtx := &v3tls.UpstreamTlsContext{}
+	require.NoError(t, ptypes.UnmarshalAny(cluster.TransportSocket.GetTypedConfig(), tlsCtx))
+	return tlsCtx.Sni
+}
+
+func TestAutoSNI(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: partners
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /partners/
+  service: https://edge.partners.example.com
+  auto_sni: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: legacy
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /legacy/
+  service: https://10.0.0.5
+  host_rewrite: legacy.partners.example.com
+  auto_sni: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: direct
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /direct/
+  service: https://10.0.0.6
+  auto_sni: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: plain
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /plain/
+  service: plain.default
+  auto_sni: true
+`, "plain", "cluster_plain_default")
+
+	// partners sends each request's Host as the SNI, and the service's own host when there's
+	// no Host to send.
+	cluster := FindCluster(config, ClusterNameContains("edge_partners_example_com"))
+	require.NotNil(t, cluster)
+	assert.True(t, cluster.GetUpstreamHttpProtocolOptions().GetAutoSni())
+	assert.Equal(t, "edge.partners.example.com", upstreamSNI(t, cluster))
+
+	// Every legacy request is rewritten to the same Host, and that's the SNI either way...
+	cluster = FindCluster(config, ClusterNameContains("hr_legacy_partners_example_com"))
+	require.NotNil(t, cluster)
+	assert.True(t, cluster.GetUpstreamHttpProtocolOptions().GetAutoSni())
+	assert.Equal(t, "legacy.partners.example.com", upstreamSNI(t, cluster))
+
+	// ...while direct has nothing but an IP address to fall back on, which is no SNI at all.
+	cluster = FindCluster(config, ClusterNameContains("10_0_0_6"))
+	require.NotNil(t, cluster)
+	assert.True(t, cluster.GetUpstreamHttpProtocolOptions().GetAutoSni())
+	assert.Empty(t, upstreamSNI(t, cluster))
+
+	// plain doesn't originate TLS, so there's no SNI to set.
+	cluster = FindCluster(config, ClusterNameContains("cluster_plain_default"))
+	require.NotNil(t, cluster)
+	assert.Nil(t, cluster.TransportSocket)
+	assert.Nil(t, cluster.UpstreamHttpProtocolOptions)
+}
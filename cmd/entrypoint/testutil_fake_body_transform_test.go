@@ -0,0 +1,118 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	lua "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/lua/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyTransform(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: wrapped
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /wrapped/
+  service: wrapped.default
+  body_transform:
+    response: '{"data": {{ body }}, "source": "ambassador"}'
+    max_bytes: 4096
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: legacy
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /legacy/
+  service: legacy.default
+  body_transform:
+    request: '<envelope>{{body}}</envelope>'
+    content_types:
+    - text/xml
+    - application/xml
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: too-big
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /too-big/
+  service: too-big.default
+  body_transform:
+    request: '{"data": {{ body }}}'
+    max_bytes: 2097152
+`+entrypoint.FakeMappingYAML("plain"), "plain", "cluster_plain_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	hcm := entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+
+	// The filter buffers before the router on the way out, and gets responses before gzip
+	// (or anything else) on the way back.
+	filterIdx := map[string]int{}
+	for i, filter := range hcm.HttpFilters {
+		filterIdx[filter.Name] = i
+	}
+	require.Contains(t, filterIdx, "ambassador.body_transform")
+	assert.Equal(t, filterIdx["envoy.filters.http.router"]-1, filterIdx["ambassador.body_transform"])
+
+	perRoute := func(cluster string) *lua.LuaPerRoute {
+		r := findRoute(listener, func(r *route.Route) bool {
+			return r.GetRoute().GetCluster() == cluster
+		})
+		if r == nil {
+			return nil
+		}
+		cfg, ok := r.TypedPerFilterConfig["ambassador.body_transform"]
+		if !ok {
+			return nil
+		}
+		lpr := &lua.LuaPerRoute{}
+		require.NoError(t, ptypes.UnmarshalAny(cfg, lpr))
+		return lpr
+	}
+
+	// JSON responses get wrapped, the template going in around the original body...
+	lpr := perRoute("cluster_wrapped_default_default")
+	require.NotNil(t, lpr)
+	code := lpr.GetSourceCode().GetInlineString()
+	assert.Contains(t, code, `local request_parts = nil`)
+	assert.Contains(t, code, `local response_parts = { "{\"data\": ", ", \"source\": \"ambassador\"}" }`)
+	assert.Contains(t, code, `local content_types = { ["application/json"] = true }`)
+	assert.Contains(t, code, `local max_bytes = 4096`)
+
+	// ...other Mappings pick which (non-JSON) types they transform, and get the default size
+	// limit...
+	lpr = perRoute("cluster_legacy_default_default")
+	require.NotNil(t, lpr)
+	code = lpr.GetSourceCode().GetInlineString()
+	assert.Contains(t, code, `local request_parts = { "<envelope>", "</envelope>" }`)
+	assert.Contains(t, code, `local content_types = { ["text/xml"] = true, ["application/xml"] = true }`)
+	assert.Contains(t, code, `local max_bytes = 65536`)
+
+	// ...a limit past what the listener will buffer is refused outright...
+	assert.Nil(t, FindCluster(config, ClusterNameContains("cluster_too_big_default_default")))
+
+	// ...and Mappings without body_transform are left alone.
+	assert.Nil(t, perRoute("cluster_plain_default_default"))
+}
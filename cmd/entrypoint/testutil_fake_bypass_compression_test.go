@@ -0,0 +1,88 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	lua "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/lua/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBypassCompression(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    gzip:
+      content_type:
+      - application/json
+      - text/event-stream
+`+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: events
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /events/
+  service: events.default
+  bypass_compression: true
+`+entrypoint.FakeMappingYAML("api"), "api", "cluster_api_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	hcm := entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+
+	// The exclusion filter comes right after gzip, so on the way back it gets to responses
+	// first.
+	gzipIdx, exclusionIdx := -1, -1
+	for i, filter := range hcm.HttpFilters {
+		switch filter.Name {
+		case "envoy.filters.http.gzip":
+			gzipIdx = i
+		case "ambassador.compression_exclusion":
+			exclusionIdx = i
+		}
+	}
+	require.NotEqual(t, -1, gzipIdx)
+	require.Equal(t, gzipIdx+1, exclusionIdx)
+
+	perRoute := func(cluster string) *lua.LuaPerRoute {
+		r := findRoute(listener, func(r *route.Route) bool {
+			return r.GetRoute().GetCluster() == cluster
+		})
+		require.NotNil(t, r)
+		cfg, ok := r.TypedPerFilterConfig["ambassador.compression_exclusion"]
+		if !ok {
+			return nil
+		}
+		lpr := &lua.LuaPerRoute{}
+		require.NoError(t, ptypes.UnmarshalAny(cfg, lpr))
+		return lpr
+	}
+
+	// The event stream marks its responses no-transform, so gzip never buffers them...
+	lpr := perRoute("cluster_events_default_default")
+	require.NotNil(t, lpr)
+	assert.Contains(t, lpr.GetSourceCode().GetInlineString(), "no-transform")
+
+	// ...while everything else still gets compressed.
+	assert.Nil(t, perRoute("cluster_api_default_default"))
+}
@@ -0,0 +1,88 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaseInsensitiveHeaders(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: tenant
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /api/
+  service: tenant.default
+  headers:
+    x-tenant: Acme.Corp
+    x-token: AbC123
+  regex_headers:
+    x-plan: "gold|silver"
+  case_insensitive_headers: [ "X-Tenant", "x-plan", "x-missing" ]
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: exact
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /api/
+  service: exact.default
+  headers:
+    x-tenant: Acme.Corp
+`, "exact", "cluster_exact_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	headers := func(cluster string) map[string]*route.HeaderMatcher {
+		r := findRoute(listener, func(r *route.Route) bool {
+			return r.GetRoute().GetCluster() == cluster
+		})
+		require.NotNil(t, r)
+		matchers := map[string]*route.HeaderMatcher{}
+		for _, h := range r.GetMatch().GetHeaders() {
+			matchers[h.Name] = h
+		}
+		return matchers
+	}
+
+	// x-tenant matches Acme.Corp in any case -- and only Acme.Corp, dot included -- while
+	// x-plan's regex ignores case as a whole.
+	matchers := headers("cluster_tenant_default_default")
+	require.Contains(t, matchers, "x-tenant")
+	assert.Empty(t, matchers["x-tenant"].GetExactMatch())
+	assert.Equal(t, `(?i)Acme\.Corp`, matchers["x-tenant"].GetSafeRegexMatch().GetRegex())
+	require.Contains(t, matchers, "x-plan")
+	assert.Equal(t, "(?i)gold|silver", matchers["x-plan"].GetSafeRegexMatch().GetRegex())
+
+	// x-token isn't listed, so its value still has to match exactly.
+	require.Contains(t, matchers, "x-token")
+	assert.Equal(t, "AbC123", matchers["x-token"].GetExactMatch())
+
+	// The same header without case_insensitive_headers is a different route, matched exactly.
+	matchers = headers("cluster_exact_default_default")
+	require.Contains(t, matchers, "x-tenant")
+	assert.Equal(t, "Acme.Corp", matchers["x-tenant"].GetExactMatch())
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Mapping("exact") != nil && len(diag.NoticesFor("tenant.default")) > 0
+	})
+	require.NoError(t, err)
+	assert.True(t, hasNotice(diag, "tenant.default", "x-missing has no value to match"))
+	assert.False(t, hasNotice(diag, "tenant.default", "x-plan"))
+}
@@ -0,0 +1,198 @@
+package entrypoint_test
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCert is a certificate made up on the spot, with its key so that it can sign others.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// makeTestCert makes a certificate for commonName that expires at notAfter, signed by parent,
+// or by itself if parent is nil.
+func makeTestCert(t *testing.T, commonName string, notAfter time.Time, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// tlsSecretWithHost is a TLS Secret holding crt, and a Host that uses it.
+func tlsSecretWithHost(name string, crt []byte) string {
+	return `
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: ` + name + `
+  namespace: default
+type: kubernetes.io/tls
+data:
+  tls.crt: ` + base64.StdEncoding.EncodeToString(crt) + `
+  tls.key: bm90LWEtcmVhbC1rZXk=
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: ` + name + `-host
+  namespace: default
+spec:
+  hostname: ` + name + `.example.com
+  tlsSecret:
+    name: ` + name + `
+`
+}
+
+// certificateExpiryMetric returns what diagd's /metrics says about when the certificate in the
+// named secret expires, or 0 if it says nothing.
+func certificateExpiryMetric(t *testing.T, secret, namespace string) float64 {
+	t.Helper()
+	resp, err := http.Get(entrypoint.GetEventHost() + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	series := `ambassador_certificate_expiry_timestamp_seconds{secret="` + secret + `",namespace="` + namespace + `"} `
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, series) {
+			value, err := strconv.ParseFloat(strings.TrimPrefix(line, series), 64)
+			require.NoError(t, err)
+			return value
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return 0
+}
+
+func TestCertificateExpiry(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+
+	// The leaf runs out long before the intermediate that signed it.
+	intermediate := makeTestCert(t, "Example Intermediate", now.Add(365*day), nil)
+	leaf := makeTestCert(t, "expiring.example.com", now.Add(5*day), intermediate)
+	chain := append(append([]byte{}, leaf.pem...), intermediate.pem...)
+
+	renewing := makeTestCert(t, "renewing.example.com", now.Add(20*day), nil)
+	fresh := makeTestCert(t, "fresh.example.com", now.Add(90*day), nil)
+	expired := makeTestCert(t, "expired.example.com", now.Add(-time.Hour), nil)
+
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    cert_expiry_warning_days: 30
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: ambassador-listener-8443
+  namespace: default
+spec:
+  port: 8443
+  protocol: HTTPS
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+` + tlsSecretWithHost("expiring-cert", chain) +
+		tlsSecretWithHost("renewing-cert", renewing.pem) +
+		tlsSecretWithHost("fresh-cert", fresh.pem) +
+		tlsSecretWithHost("expired-cert", expired.pem) +
+		tlsSecretWithHost("garbage-cert", []byte("not-a-real-cert")) + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: example
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /example/
+  service: example.default
+`)
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "example"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Mapping("example") != nil && diag.Error("garbage-cert.default") != ""
+	})
+	require.NoError(t, err)
+
+	// The chain is only as good as its first certificate to expire, which here is the leaf.
+	assert.True(t, hasNotice(diag, "expiring-cert.default", "certificate expiring.example.com expires at"))
+	assert.False(t, hasNotice(diag, "expiring-cert.default", "Example Intermediate"))
+
+	// 20 days out is only worth a notice because the Module asked for 30 days' warning...
+	assert.True(t, hasNotice(diag, "renewing-cert.default", "certificate renewing.example.com expires at"))
+
+	// ...and 90 days out isn't worth one at all.
+	assert.Empty(t, diag.NoticesFor("fresh-cert.default"))
+	assert.Empty(t, diag.Error("fresh-cert.default"))
+
+	// It's too late for a notice once a certificate has expired, and there's nothing to warn
+	// about in a tls.crt that isn't a certificate.
+	assert.Contains(t, diag.Error("expired-cert.default"), "certificate expired.example.com expired at")
+	assert.Contains(t, diag.Error("garbage-cert.default"), "tls.crt is not a PEM-encoded certificate")
+
+	// The metric says when each one runs out, going by the leaf for the chain.
+	assert.Equal(t, float64(leaf.cert.NotAfter.Unix()), certificateExpiryMetric(t, "expiring-cert", "default"))
+	assert.Equal(t, float64(fresh.cert.NotAfter.Unix()), certificateExpiryMetric(t, "fresh-cert", "default"))
+	assert.Equal(t, float64(expired.cert.NotAfter.Unix()), certificateExpiryMetric(t, "expired-cert", "default"))
+	assert.Zero(t, certificateExpiryMetric(t, "garbage-cert", "default"))
+}
@@ -0,0 +1,244 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	v3tls "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/transport_sockets/tls/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// certFiles maps the name of every TLS certificate secret in a config to the file its cert
+// chain is in.
+func certFiles(config *bootstrap.Bootstrap) map[string]string {
+	files := map[string]string{}
+	for _, secret := range config.GetStaticResources().GetSecrets() {
+		if cert := secret.GetTlsCertificate(); cert != nil {
+			files[secret.Name] = cert.GetCertificateChain().GetFilename()
+		}
+	}
+	return files
+}
+
+func tlsListener(config *bootstrap.Bootstrap) *v3listener.Listener {
+	return findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8443"
+	})
+}
+
+// exampleCert is the example-cert Secret, with the given base64 encoded cert.
+func exampleCert(cert string) string {
+	return `
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: example-cert
+  namespace: default
+type: kubernetes.io/tls
+data:
+  tls.crt: ` + cert + `
+  tls.key: bm90LWEtcmVhbC1rZXk=
+`
+}
+
+// exampleHost is a TLS Listener, and a Host and Mapping using the example-cert Secret, with
+// the given ambassador Module config.
+func exampleHost(moduleConfig string) string {
+	return `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    ` + moduleConfig + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: ambassador-listener-8443
+  namespace: default
+spec:
+  port: 8443
+  protocol: HTTPS
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: example
+  namespace: default
+spec:
+  hostname: example.com
+  tlsSecret:
+    name: example-cert
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: example
+  namespace: default
+spec:
+  hostname: example.com
+  prefix: /example/
+  service: example.default
+`
+}
+
+func TestCertRotation(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(exampleCert("bm90LWEtcmVhbC1jZXJ0") + exampleHost("secret_discovery: true"))
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "example"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return len(certFiles(config)) > 0
+	})
+	require.NoError(t, err)
+
+	before := config
+	beforeListener := tlsListener(before)
+	require.NotNil(t, beforeListener)
+	beforeCerts := certFiles(before)
+	require.Len(t, beforeCerts, 1)
+	assert.Contains(t, beforeCerts, "default/example-cert")
+
+	// The cert is moved out, and named for the Secret it came from, so the filter chain only
+	// has the name of the secret.
+	for _, fc := range beforeListener.FilterChains {
+		if fc.TransportSocket != nil {
+			ctx := &v3tls.DownstreamTlsContext{}
+			require.NoError(t, fc.TransportSocket.GetTypedConfig().UnmarshalTo(ctx))
+			assert.Empty(t, ctx.GetCommonTlsContext().GetTlsCertificates())
+			require.Len(t, ctx.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs(), 1)
+			assert.Equal(t, "default/example-cert", ctx.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs()[0].Name)
+		}
+	}
+
+	// Rotating the cert changes the file it's in...
+	require.NoError(t, f.UpsertYAML(exampleCert("bm90LWEtcmVhbC1jZXJ0LWVpdGhlcg==")))
+	f.Flush()
+
+	config, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		after := certFiles(config)
+		for name, file := range beforeCerts {
+			if after[name] == "" || after[name] == file {
+				return false
+			}
+		}
+		return len(after) == len(beforeCerts)
+	})
+	require.NoError(t, err)
+
+	// ...but the secret keeps its name, and the listener doesn't change at all, so Envoy has
+	// no reason to drain it.
+	after := config
+	afterListener := tlsListener(after)
+	require.NotNil(t, afterListener)
+	assert.True(t, proto.Equal(beforeListener, afterListener), "listener changed on cert rotation")
+}
+
+func TestCertRotationDefault(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(exampleCert("bm90LWEtcmVhbC1jZXJ0") + exampleHost("secret_discovery: false"))
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "example"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return tlsListener(config) != nil
+	})
+	require.NoError(t, err)
+
+	// Without secret_discovery, the certs stay where they are.
+	assert.Empty(t, config.GetStaticResources().GetSecrets())
+
+	found := false
+	for _, fc := range tlsListener(config).FilterChains {
+		if fc.TransportSocket != nil {
+			ctx := &v3tls.DownstreamTlsContext{}
+			require.NoError(t, fc.TransportSocket.GetTypedConfig().UnmarshalTo(ctx))
+			assert.Empty(t, ctx.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs())
+			assert.NotEmpty(t, ctx.GetCommonTlsContext().GetTlsCertificates())
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestCertRotationIstio(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	f.SendIstioCertUpdate(istioCertUpdate("not-a-real-cert"))
+
+	err := f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    mesh_mtls: istio
+    secret_discovery: true
+` + entrypoint.FakeListenerYAML + entrypoint.FakeMappingYAML("in-mesh"))
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "in-mesh"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	inMesh := func(config *bootstrap.Bootstrap) *v3cluster.Cluster {
+		return FindCluster(config, ClusterNameContains("cluster_in_mesh_default"))
+	}
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		c := inMesh(config)
+		return c != nil && c.TransportSocket != nil
+	})
+	require.NoError(t, err)
+	before := config
+	beforeCerts := certFiles(before)
+	require.Len(t, beforeCerts, 1)
+
+	// Istio rotating its certs is the same story: a new secret, and the same cluster, so its
+	// connection pool stays put.
+	f.SendIstioCertUpdate(istioCertUpdate("not-a-real-cert-either"))
+	f.Flush()
+
+	config, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		after := certFiles(config)
+		for name, file := range beforeCerts {
+			if after[name] == "" || after[name] == file {
+				return false
+			}
+		}
+		return true
+	})
+	require.NoError(t, err)
+
+	after := config
+	require.NotNil(t, inMesh(after))
+	assert.True(t, proto.Equal(inMesh(before), inMesh(after)), "cluster changed on cert rotation")
+}
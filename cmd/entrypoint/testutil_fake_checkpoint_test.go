@@ -0,0 +1,87 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+	"github.com/datawire/ambassador/v2/pkg/snapshot/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpointRestore(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(entrypoint.FakeListenerYAML + entrypoint.FakeMappingYAML("kept") + entrypoint.FakeMappingYAML("deleted"))
+	require.NoError(t, err)
+
+	f.Flush()
+	_, err = f.GetSnapshot(HasMapping("default", "deleted"))
+	require.NoError(t, err)
+	_, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_deleted_default_default")) != nil
+	})
+	require.NoError(t, err)
+
+	baseline := f.Checkpoint()
+
+	// Branch off the baseline: delete one Mapping, change another, and add a third.
+	require.NoError(t, f.Delete("Mapping", "default", "deleted"))
+	require.NoError(t, f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: kept
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /kept/
+  service: kept.default
+  connect_timeout_ms: 10000
+`+entrypoint.FakeMappingYAML("added")))
+	f.Flush()
+
+	keptTimeout := func(config *bootstrap.Bootstrap) int64 {
+		cluster := FindCluster(config, func(c *v3cluster.Cluster) bool {
+			return c.Name == "cluster_kept_default_default"
+		})
+		if cluster == nil {
+			return 0
+		}
+		return cluster.ConnectTimeout.GetSeconds()
+	}
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_added_default_default")) != nil
+	})
+	require.NoError(t, err)
+	assert.Nil(t, FindCluster(config, ClusterNameContains("cluster_deleted_default_default")))
+	assert.Equal(t, int64(10), keptTimeout(config))
+
+	// Going back brings the deleted Mapping back, drops the added one, and undoes the change.
+	f.Restore(baseline)
+	snap, err := f.GetSnapshot(func(snap *snapshot.Snapshot) bool {
+		return HasMapping("default", "deleted")(snap) && !HasMapping("default", "added")(snap)
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	config, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_deleted_default_default")) != nil
+	})
+	require.NoError(t, err)
+	assert.Nil(t, FindCluster(config, ClusterNameContains("cluster_added_default_default")))
+	assert.Equal(t, int64(3), keptTimeout(config))
+
+	// The checkpoint can be gone back to more than once.
+	require.NoError(t, f.Delete("Mapping", "default", "kept"))
+	f.Flush()
+	f.Restore(baseline)
+	_, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return keptTimeout(config) == 3 && FindCluster(config, ClusterNameContains("cluster_added_default_default")) == nil
+	})
+	require.NoError(t, err)
+}
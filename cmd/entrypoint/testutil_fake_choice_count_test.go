@@ -0,0 +1,91 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeastRequestChoiceCount(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    load_balancer:
+      policy: least_request
+      choice_count: 3
+`+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("pool")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: fleet
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /fleet/
+  service: fleet.default
+  load_balancer:
+    policy: least_request
+    choice_count: 10
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: random
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /random/
+  service: random.default
+  load_balancer:
+    policy: least_request
+    choice_count: 1
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: rr
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /rr/
+  service: rr.default
+  load_balancer:
+    policy: round_robin
+    choice_count: 4
+`, "rr", "cluster_fleet_default")
+
+	// pool gets the Module's choice count...
+	cluster := FindCluster(config, ClusterNameContains("cluster_pool_default"))
+	require.NotNil(t, cluster)
+	assert.Equal(t, v3cluster.Cluster_LEAST_REQUEST, cluster.LbPolicy)
+	assert.Equal(t, uint32(3), cluster.GetLeastRequestLbConfig().GetChoiceCount().GetValue())
+
+	// ...while fleet, with a big pool of hosts, looks at more of them.
+	cluster = FindCluster(config, ClusterNameContains("cluster_fleet_default"))
+	require.NotNil(t, cluster)
+	assert.Equal(t, v3cluster.Cluster_LEAST_REQUEST, cluster.LbPolicy)
+	assert.Equal(t, uint32(10), cluster.GetLeastRequestLbConfig().GetChoiceCount().GetValue())
+
+	// One choice is no choice at all, and round_robin doesn't make any.
+	assert.Nil(t, FindCluster(config, ClusterNameContains("cluster_random_default")))
+	assert.Nil(t, FindCluster(config, ClusterNameContains("cluster_rr_default")))
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Error("random.default") != "" && diag.Error("rr.default") != ""
+	})
+	require.NoError(t, err)
+	assert.Contains(t, diag.Error("random.default"), "choice_count 1 must be at least 2")
+	assert.Contains(t, diag.Error("rr.default"), "choice_count only applies to policy least_request")
+}
@@ -0,0 +1,157 @@
+package entrypoint_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterDedup(t *testing.T) {
+	const count = 50
+
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+
+	var yaml strings.Builder
+	yaml.WriteString(entrypoint.FakeListenerYAML + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: breakers
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /breakers/
+  service: shared.default
+  circuit_breakers:
+  - max_connections: 10
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: patient-a
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /patient-a/
+  service: shared.default
+  connect_timeout_ms: 10000
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: patient-b
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /patient-b/
+  service: shared.default
+  connect_timeout_ms: 10000
+  timeout_ms: 60000
+`)
+
+	// The same service behind 50 different hosts, with different route settings.
+	for i := 0; i < count; i++ {
+		yaml.WriteString(fmt.Sprintf(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: host-%[1]d
+  namespace: default
+spec:
+  hostname: host-%[1]d.example.com
+  requestPolicy:
+    insecure:
+      action: Route
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: mapping-%[1]d
+  namespace: default
+spec:
+  hostname: host-%[1]d.example.com
+  prefix: /svc/
+  rewrite: /svc-%[1]d/
+  timeout_ms: %[2]d
+  service: shared.default
+`, i, 1000+i))
+	}
+
+	require.NoError(t, f.UpsertYAML(yaml.String()))
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", fmt.Sprintf("mapping-%d", count-1)))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_shared_default_default")) != nil
+	})
+	require.NoError(t, err)
+	stats := entrypoint.NewConfigStats(config)
+
+	var shared []string
+	for _, cluster := range config.StaticResources.Clusters {
+		if strings.HasPrefix(cluster.Name, "cluster_shared_default") {
+			shared = append(shared, cluster.Name)
+		}
+	}
+
+	// Circuit breakers and a different connect timeout each need a cluster of their own, and
+	// every one of the three is named for its settings: none of them gets the plain name just
+	// for having been loaded first.
+	require.Len(t, shared, 3)
+	for _, name := range shared {
+		assert.True(t, strings.HasPrefix(name, "cluster_shared_default_default_"), name)
+	}
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Mapping(fmt.Sprintf("mapping-%d", count-1)) != nil
+	})
+	require.NoError(t, err)
+	byCluster := diag.ClusterMappings()
+
+	// clusterOf returns the one cluster the named Mapping uses.
+	clusterOf := func(mapping string) *v3cluster.Cluster {
+		for name, mappings := range byCluster {
+			for _, m := range mappings {
+				if m == mapping {
+					cluster := FindCluster(config, func(c *v3cluster.Cluster) bool { return c.Name == name })
+					require.NotNil(t, cluster, name)
+					return cluster
+				}
+			}
+		}
+		require.Failf(t, "no cluster", "mapping %s", mapping)
+		return nil
+	}
+
+	// Mappings that only differ in their routes all share the one cluster...
+	common := clusterOf("mapping-0")
+	assert.Len(t, byCluster[common.Name], count)
+	assert.Equal(t, int64(3), common.ConnectTimeout.GetSeconds())
+	assert.GreaterOrEqual(t, stats.ClusterRoutes[common.Name], count)
+
+	// ...and the ones with the same settings share the others.
+	breakers := clusterOf("breakers")
+	assert.NotNil(t, breakers.CircuitBreakers)
+	assert.Equal(t, []string{"breakers"}, byCluster[breakers.Name])
+
+	patient := clusterOf("patient-a")
+	assert.Nil(t, patient.CircuitBreakers)
+	assert.Equal(t, int64(10), patient.ConnectTimeout.GetSeconds())
+	assert.Equal(t, []string{"patient-a", "patient-b"}, byCluster[patient.Name])
+
+	// (The "*" Mappings get a route in every Host's virtual host, so count them against each
+	// other.)
+	assert.Greater(t, stats.ClusterRoutes[breakers.Name], 0)
+	assert.Equal(t, 2*stats.ClusterRoutes[breakers.Name], stats.ClusterRoutes[patient.Name])
+}
@@ -0,0 +1,126 @@
+package entrypoint_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// routedTo says whether any route in the config sends traffic to a cluster whose name starts
+// with prefix, whether directly or as one of its weighted clusters.
+func routedTo(config *bootstrap.Bootstrap, prefix string) bool {
+	return findListener(config, func(l *v3listener.Listener) bool {
+		return findRoute(l, func(r *route.Route) bool {
+			action := r.GetRoute()
+			if strings.HasPrefix(action.GetCluster(), prefix) {
+				return true
+			}
+			for _, wc := range action.GetWeightedClusters().GetClusters() {
+				if strings.HasPrefix(wc.Name, prefix) {
+					return true
+				}
+			}
+			return false
+		}) != nil
+	}) != nil
+}
+
+// TestClusterDrainOnRemoval is TestWeightWithCache's delete/re-add cycle, with a drain time on
+// the bar Mapping: deleting it has to leave its cluster draining rather than gone.
+func TestClusterDrainOnRemoval(t *testing.T) {
+	get_envoy_config := func(f *entrypoint.Fake, want_bar_cluster bool, want_bar_route bool) (*bootstrap.Bootstrap, error) {
+		return f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+			has_bar_cluster := FindCluster(config, ClusterNameContains("cluster_bar_")) != nil
+			has_bar_route := routedTo(config, "cluster_bar_")
+
+			return (FindCluster(config, ClusterNameContains("cluster_foo_")) != nil) &&
+				(has_bar_cluster == want_bar_cluster) && (has_bar_route == want_bar_route)
+		})
+	}
+
+	barYAML := `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: mapping-bar
+  namespace: default
+spec:
+  prefix: /foo/
+  service: bar.default
+  cluster_drain_time_ms: 2000
+`
+
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	assert.NoError(t, f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: mapping-foo
+  namespace: default
+spec:
+  prefix: /foo/
+  service: foo.default
+`))
+	assert.NoError(t, f.UpsertYAML(barYAML))
+	f.Flush()
+
+	config, err := get_envoy_config(f, true, true)
+	require.NoError(t, err)
+	bar := FindCluster(config, ClusterNameContains("cluster_bar_"))
+	require.NotNil(t, bar)
+	assert.Equal(t, float64(2000), bar.GetMetadata().GetFilterMetadata()["getambassador.io"].GetFields()["drain_time_ms"].GetNumberValue())
+	// foo didn't ask to drain.
+	assert.Nil(t, FindCluster(config, ClusterNameContains("cluster_foo_")).GetMetadata())
+
+	// Deleting bar stops routing to it, but its cluster is still there, draining.
+	assert.NoError(t, f.Delete("Mapping", "default", "mapping-bar"))
+	f.Flush()
+
+	_, err = get_envoy_config(f, true, false)
+	require.NoError(t, err)
+	assert.Contains(t, f.DrainingClusters(), bar.Name)
+
+	// Re-adding bar while it drains just puts it back.
+	assert.NoError(t, f.UpsertYAML(barYAML))
+	f.Flush()
+
+	_, err = get_envoy_config(f, true, true)
+	require.NoError(t, err)
+	assert.Empty(t, f.DrainingClusters())
+
+	// Delete it again, and once the drain time is up, the next config doesn't have it at all.
+	assert.NoError(t, f.Delete("Mapping", "default", "mapping-bar"))
+	f.Flush()
+
+	_, err = get_envoy_config(f, true, false)
+	require.NoError(t, err)
+
+	time.Sleep(2 * time.Second)
+	assert.NoError(t, f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: mapping-baz
+  namespace: default
+spec:
+  prefix: /baz/
+  service: baz.default
+`))
+	f.Flush()
+
+	config, err = get_envoy_config(f, false, false)
+	require.NoError(t, err)
+	assert.NotNil(t, FindCluster(config, ClusterNameContains("cluster_baz_")))
+	assert.Empty(t, f.DrainingClusters())
+}
@@ -0,0 +1,98 @@
+package entrypoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+	upstreamhttp "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/upstreams/http/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func httpProtocolOptions(t *testing.T, cluster *v3cluster.Cluster) *upstreamhttp.HttpProtocolOptions {
+	t.Helper()
+	typed, ok := cluster.TypedExtensionProtocolOptions["envoy.extensions.upstreams.http.v3.HttpProtocolOptions"]
+	require.True(t, ok)
+	options := &upstreamhttp.HttpProtocolOptions{}
+	require.NoError(t, ptypes.UnmarshalAny(typed, options))
+	return options
+}
+
+func TestClusterProtocolOptions(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: merged
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /merged/
+  service: merged.default
+  cluster_idle_timeout_ms: 30000
+  cluster_protocol_options:
+    envoy.extensions.upstreams.http.v3.HttpProtocolOptions:
+      "@type": type.googleapis.com/envoy.extensions.upstreams.http.v3.HttpProtocolOptions
+      common_http_protocol_options:
+        max_headers_count: 50
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: grpc-own-protocol
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /grpc.Own/
+  rewrite: /grpc.Own/
+  service: grpc-own.default
+  grpc: true
+  cluster_protocol_options:
+    envoy.extensions.upstreams.http.v3.HttpProtocolOptions:
+      "@type": type.googleapis.com/envoy.extensions.upstreams.http.v3.HttpProtocolOptions
+      explicit_http_config:
+        http2_protocol_options:
+          max_concurrent_streams: 10
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: untyped
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /untyped/
+  service: untyped.default
+  cluster_protocol_options:
+    envoy.extensions.upstreams.http.v3.HttpProtocolOptions:
+      common_http_protocol_options:
+        max_headers_count: 50
+`, "untyped", "cluster_grpc_own_default_default")
+
+	// The Mapping's options merge with the idle timeout we generate, which moves out of the
+	// deprecated cluster field. With no protocol picked, it's still HTTP/1.1.
+	cluster := FindCluster(config, ClusterNameContains("cluster_merged_default_default"))
+	require.NotNil(t, cluster)
+	assert.Nil(t, cluster.CommonHttpProtocolOptions)
+	options := httpProtocolOptions(t, cluster)
+	assert.Equal(t, 30*time.Second, options.CommonHttpProtocolOptions.IdleTimeout.AsDuration())
+	assert.Equal(t, uint32(50), options.CommonHttpProtocolOptions.MaxHeadersCount.GetValue())
+	assert.NotNil(t, options.GetExplicitHttpConfig().GetHttpProtocolOptions())
+
+	// gRPC would get empty HTTP/2 options; the Mapping's own HTTP/2 options win.
+	cluster = FindCluster(config, ClusterNameContains("cluster_grpc_own_default_default"))
+	require.NotNil(t, cluster)
+	assert.Nil(t, cluster.Http2ProtocolOptions)
+	options = httpProtocolOptions(t, cluster)
+	assert.Equal(t, uint32(10), options.GetExplicitHttpConfig().GetHttp2ProtocolOptions().GetMaxConcurrentStreams().GetValue())
+
+	// Options without an @type are rejected, and so is the Mapping.
+	assert.Nil(t, FindCluster(config, ClusterNameContains("cluster_untyped_default_default")))
+}
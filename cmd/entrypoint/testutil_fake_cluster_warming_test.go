@@ -0,0 +1,99 @@
+package entrypoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterWarmingTimeout(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	require.NoError(t, f.Upsert(makeService("default", "eds-default")))
+	require.NoError(t, f.Upsert(makeService("default", "eds-forever")))
+	require.NoError(t, f.Upsert(makeService("default", "eds-quick")))
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    cluster_warming_timeout_ms: 3000
+`+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: eds-default
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /eds-default/
+  service: eds-default
+  resolver: endpoint
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: eds-forever
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /eds-forever/
+  service: eds-forever
+  resolver: endpoint
+  initial_fetch_timeout_ms: 0
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: eds-quick
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /eds-quick/
+  service: eds-quick
+  resolver: endpoint
+  initial_fetch_timeout_ms: 1000
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: unresolvable
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /unresolvable/
+  service: nothing-here.invalid
+`+entrypoint.FakeMappingYAML("unrelated"), "unrelated", "cluster_unrelated_default_default")
+
+	initialFetchTimeout := func(name string) time.Duration {
+		cluster := FindCluster(config, ClusterNameContains(name))
+		require.NotNil(t, cluster, name)
+		require.NotNil(t, cluster.EdsClusterConfig, name)
+		require.NotNil(t, cluster.EdsClusterConfig.EdsConfig.InitialFetchTimeout, name)
+		return cluster.EdsClusterConfig.EdsConfig.InitialFetchTimeout.AsDuration()
+	}
+
+	// An EDS cluster gives up waiting for endpoints at the warming timeout...
+	assert.Equal(t, 3*time.Second, initialFetchTimeout("cluster_eds_default_default"))
+
+	// ...which is a cap, even for a Mapping that asked to wait forever...
+	assert.Equal(t, 3*time.Second, initialFetchTimeout("cluster_eds_forever_default"))
+
+	// ...though a shorter wait is fine.
+	assert.Equal(t, time.Second, initialFetchTimeout("cluster_eds_quick_default"))
+
+	// A DNS cluster is warm after its first lookup, even a failed one, so a name that can't
+	// resolve is still a cluster, and doesn't get in the way of anything else.
+	cluster := FindCluster(config, ClusterNameContains("cluster_nothing_here_invalid"))
+	require.NotNil(t, cluster)
+	assert.Nil(t, cluster.EdsClusterConfig)
+	assert.NotNil(t, FindCluster(config, ClusterNameContains("cluster_unrelated_default_default")))
+}
@@ -0,0 +1,145 @@
+package entrypoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	adaptive "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/adaptive_concurrency/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// concurrencyLimit returns the adaptive concurrency filter config from the listener on 8080,
+// or nil if there isn't one.
+func concurrencyLimit(t *testing.T, config *bootstrap.Bootstrap) *adaptive.AdaptiveConcurrency {
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	hcm := entrypoint.ListenerHCM(listener)
+	if hcm == nil {
+		return nil
+	}
+
+	for _, filter := range hcm.HttpFilters {
+		if filter.Name == "envoy.filters.http.adaptive_concurrency" {
+			ac := &adaptive.AdaptiveConcurrency{}
+			require.NoError(t, ptypes.UnmarshalAny(filter.GetTypedConfig(), ac))
+			return ac
+		}
+	}
+
+	return nil
+}
+
+var concurrencyLimitListener = entrypoint.FakeListenerYAML + entrypoint.FakeMappingYAML("foo")
+
+func TestConcurrencyLimitAdaptive(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(concurrencyLimitListener + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    concurrency_limit:
+      max_concurrency: 2000
+      sample_percentile: 90
+      min_rtt_interval_ms: 30000
+      min_rtt_request_count: 100
+`)
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "foo"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return concurrencyLimit(t, config) != nil
+	})
+	require.NoError(t, err)
+	gradient := concurrencyLimit(t, config).GetGradientControllerConfig()
+	require.NotNil(t, gradient)
+
+	// What was set is used...
+	assert.Equal(t, float64(90), gradient.GetSampleAggregatePercentile().GetValue())
+	assert.Equal(t, uint32(2000), gradient.GetConcurrencyLimitParams().GetMaxConcurrencyLimit().GetValue())
+	assert.Equal(t, 30*time.Second, gradient.GetMinRttCalcParams().GetInterval().AsDuration())
+	assert.Equal(t, uint32(100), gradient.GetMinRttCalcParams().GetRequestCount().GetValue())
+
+	// ...and what wasn't gets the defaults, including the intervals Envoy insists on.
+	assert.Equal(t, 100*time.Millisecond, gradient.GetConcurrencyLimitParams().GetConcurrencyUpdateInterval().AsDuration())
+	assert.Equal(t, float64(10), gradient.GetMinRttCalcParams().GetJitter().GetValue())
+	assert.Equal(t, float64(25), gradient.GetMinRttCalcParams().GetBuffer().GetValue())
+	assert.Equal(t, uint32(3), gradient.GetMinRttCalcParams().GetMinConcurrency().GetValue())
+}
+
+func TestConcurrencyLimitFixed(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(concurrencyLimitListener + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    concurrency_limit:
+      mode: fixed
+      limit: 500
+`)
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "foo"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return concurrencyLimit(t, config) != nil
+	})
+	require.NoError(t, err)
+	gradient := concurrencyLimit(t, config).GetGradientControllerConfig()
+	require.NotNil(t, gradient)
+
+	// The limit is both the ceiling and where the controller sits while it measures, and it
+	// only backs off when latency doubles.
+	assert.Equal(t, uint32(500), gradient.GetConcurrencyLimitParams().GetMaxConcurrencyLimit().GetValue())
+	assert.Equal(t, uint32(500), gradient.GetMinRttCalcParams().GetMinConcurrency().GetValue())
+	assert.Equal(t, float64(100), gradient.GetMinRttCalcParams().GetBuffer().GetValue())
+	assert.Equal(t, float64(0), gradient.GetMinRttCalcParams().GetJitter().GetValue())
+
+	// Adaptive settings make no sense with a fixed limit, so they're an error, and the Module
+	// (along with its limit) goes away.
+	err = f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    concurrency_limit:
+      mode: fixed
+      limit: 500
+      sample_percentile: 90
+`)
+	require.NoError(t, err)
+	f.Flush()
+
+	_, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return concurrencyLimit(t, config) == nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,76 @@
+package entrypoint
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	v3bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	http "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+)
+
+// ConfigStats summarizes the size and complexity of an envoy config. It's meant to let tests put
+// bounds on what a given input generates, so that bugs that blow up the config (e.g. duplicating
+// routes per namespace) get caught even when the resulting config is otherwise correct.
+type ConfigStats struct {
+	Listeners    int // Number of static listeners.
+	FilterChains int // Number of filter chains across all listeners.
+	VirtualHosts int // Number of virtual hosts across all HTTP connection managers.
+	Routes       int // Number of routes across all virtual hosts.
+	Clusters     int // Number of static clusters.
+	SizeBytes    int // Size of the config when serialized as a protobuf.
+
+	// ClusterRoutes is the number of routes that send traffic to each cluster, so tests can
+	// check which Mappings ended up sharing a cluster.
+	ClusterRoutes map[string]int
+}
+
+// NewConfigStats computes the ConfigStats for the supplied envoy config.
+func NewConfigStats(config *v3bootstrap.Bootstrap) *ConfigStats {
+	stats := &ConfigStats{
+		Listeners: len(config.StaticResources.Listeners),
+		Clusters:  len(config.StaticResources.Clusters),
+		SizeBytes: proto.Size(config),
+
+		ClusterRoutes: map[string]int{},
+	}
+
+	for _, l := range config.StaticResources.Listeners {
+		stats.FilterChains += len(l.FilterChains)
+
+		for _, fc := range l.FilterChains {
+			hcm := ChainHCM(fc)
+			if hcm == nil {
+				continue
+			}
+			rs, ok := hcm.RouteSpecifier.(*http.HttpConnectionManager_RouteConfig)
+			if !ok {
+				continue
+			}
+			stats.VirtualHosts += len(rs.RouteConfig.VirtualHosts)
+			for _, vh := range rs.RouteConfig.VirtualHosts {
+				stats.Routes += len(vh.Routes)
+
+				for _, r := range vh.Routes {
+					if cluster := r.GetRoute().GetCluster(); cluster != "" {
+						stats.ClusterRoutes[cluster]++
+					}
+					for _, wc := range r.GetRoute().GetWeightedClusters().GetClusters() {
+						stats.ClusterRoutes[wc.Name]++
+					}
+				}
+			}
+		}
+	}
+
+	return stats
+}
+
+// ConfigStats will return the ConfigStats for the next envoy config that satisfies the supplied
+// predicate.
+func (f *Fake) ConfigStats(predicate func(*v3bootstrap.Bootstrap) bool) (*ConfigStats, error) {
+	f.T.Helper()
+	config, err := f.GetEnvoyConfig(predicate)
+	if err != nil {
+		return nil, err
+	}
+	return NewConfigStats(config), nil
+}
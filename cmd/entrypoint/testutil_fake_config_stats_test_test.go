@@ -0,0 +1,84 @@
+package entrypoint_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+)
+
+// hostAndMappingYAML returns a Host and a Mapping for it, both in their own namespace.
+func hostAndMappingYAML(idx int) string {
+	return fmt.Sprintf(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: host-%[1]d
+  namespace: ns-%[1]d
+spec:
+  hostname: host-%[1]d.example.com
+  requestPolicy:
+    insecure:
+      action: Route
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: mapping-%[1]d
+  namespace: ns-%[1]d
+spec:
+  hostname: host-%[1]d.example.com
+  prefix: /svc-%[1]d/
+  service: svc-%[1]d.ns-%[1]d
+`, idx)
+}
+
+func hasClusterFor(idx int) func(*v3bootstrap.Bootstrap) bool {
+	return func(config *v3bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains(fmt.Sprintf("cluster_svc_%d_ns_%d_", idx, idx))) != nil
+	}
+}
+
+// TestFakeConfigStats checks that the generated config grows linearly as we add Hosts in
+// different namespaces: every Host/Mapping pair should cost the same number of routes and exactly
+// one cluster, no matter how many of them there already are.
+func TestFakeConfigStats(t *testing.T) {
+	const count = 50
+
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	require.NoError(t, f.UpsertYAML(entrypoint.FakeListenerYAML))
+
+	require.NoError(t, f.UpsertYAML(hostAndMappingYAML(0)))
+	f.Flush()
+	one, err := f.ConfigStats(hasClusterFor(0))
+	require.NoError(t, err)
+
+	require.NoError(t, f.UpsertYAML(hostAndMappingYAML(1)))
+	f.Flush()
+	two, err := f.ConfigStats(hasClusterFor(1))
+	require.NoError(t, err)
+
+	perHostRoutes := two.Routes - one.Routes
+	assert.Greater(t, perHostRoutes, 0)
+	assert.Equal(t, one.Clusters+1, two.Clusters)
+
+	var yaml strings.Builder
+	for i := 2; i < count; i++ {
+		yaml.WriteString(hostAndMappingYAML(i))
+	}
+	require.NoError(t, f.UpsertYAML(yaml.String()))
+	f.Flush()
+	all, err := f.ConfigStats(hasClusterFor(count - 1))
+	require.NoError(t, err)
+
+	assert.Equal(t, one.Listeners, all.Listeners)
+	assert.Equal(t, one.Clusters+count-1, all.Clusters)
+	assert.Equal(t, one.Routes+(count-1)*perHostRoutes, all.Routes)
+	assert.Greater(t, all.SizeBytes, two.SizeBytes)
+}
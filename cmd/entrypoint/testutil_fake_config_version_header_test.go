@@ -0,0 +1,107 @@
+package entrypoint_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	http "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// configVersion returns the value of the named response header that the route config on
+// 8080 adds, or "" if it doesn't.
+func configVersion(config *bootstrap.Bootstrap, name string) string {
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	if listener == nil {
+		return ""
+	}
+	for _, fc := range listener.FilterChains {
+		hcm := entrypoint.ChainHCM(fc)
+		if hcm == nil {
+			continue
+		}
+		if rs, ok := hcm.RouteSpecifier.(*http.HttpConnectionManager_RouteConfig); ok {
+			for _, header := range rs.RouteConfig.ResponseHeadersToAdd {
+				if header.GetHeader().GetKey() == name {
+					return header.GetHeader().GetValue()
+				}
+			}
+		}
+	}
+	return ""
+}
+
+const configVersionListener = entrypoint.FakeListenerYAML
+
+func TestConfigVersionHeader(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(configVersionListener + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    config_version_header: true
+` + entrypoint.FakeMappingYAML("foo"))
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "foo"))
+	require.NoError(t, err)
+	require.NotZero(t, snap.Generation)
+
+	// Every response says which build and which snapshot its config came from...
+	suffix := fmt.Sprintf("/%d", snap.Generation)
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return strings.HasSuffix(configVersion(config, "x-emissary-config-version"), suffix)
+	})
+	require.NoError(t, err)
+	version := configVersion(config, "x-emissary-config-version")
+	assert.NotEqual(t, suffix, version)
+
+	// ...and the next change is the next generation.
+	err = f.UpsertYAML(entrypoint.FakeMappingYAML("bar"))
+	require.NoError(t, err)
+	f.Flush()
+
+	snap, err = f.GetSnapshot(HasMapping("default", "bar"))
+	require.NoError(t, err)
+	suffix = fmt.Sprintf("/%d", snap.Generation)
+	_, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return strings.HasSuffix(configVersion(config, "x-emissary-config-version"), suffix)
+	})
+	require.NoError(t, err)
+
+	// Turning it off takes the header away entirely.
+	err = f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    config_version_header: false
+`)
+	require.NoError(t, err)
+	f.Flush()
+
+	_, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return findListener(config, func(l *v3listener.Listener) bool {
+			return l.Name == "ambassador-listener-8080"
+		}) != nil && configVersion(config, "x-emissary-config-version") == ""
+	})
+	require.NoError(t, err)
+}
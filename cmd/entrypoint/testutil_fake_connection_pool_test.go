@@ -0,0 +1,46 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3core "github.com/datawire/ambassador/v2/pkg/api/envoy/config/core/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionPoolLimits(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	require.NoError(t, f.Upsert(makeService("default", "foo")))
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: foo
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /foo
+  service: foo.default
+  circuit_breakers:
+  - max_connections: 1024
+    max_connection_pools: 8
+  - priority: high
+    max_connections: 2048
+`, "foo", "cluster_foo_default_default")
+
+	cluster := FindCluster(config, ClusterNameContains("cluster_foo_default_default"))
+	require.NotNil(t, cluster)
+	require.NotNil(t, cluster.CircuitBreakers)
+
+	thresholds := cluster.CircuitBreakers.Thresholds
+	require.Len(t, thresholds, 2)
+	assert.Equal(t, v3core.RoutingPriority_DEFAULT, thresholds[0].Priority)
+	assert.Equal(t, uint32(1024), thresholds[0].GetMaxConnections().GetValue())
+	assert.Equal(t, uint32(8), thresholds[0].GetMaxConnectionPools().GetValue())
+	assert.Equal(t, v3core.RoutingPriority_HIGH, thresholds[1].Priority)
+	assert.Equal(t, uint32(2048), thresholds[1].GetMaxConnections().GetValue())
+	assert.Nil(t, thresholds[1].MaxConnectionPools)
+}
@@ -0,0 +1,271 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	localratelimit "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/local_ratelimit/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connectionRateLimits returns the token bucket that comes first in each of a listener's filter
+// chains, or nil for a chain that doesn't start with one.
+func connectionRateLimits(t *testing.T, config *bootstrap.Bootstrap, name string) []*localratelimit.LocalRateLimit {
+	t.Helper()
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == name
+	})
+	require.NotNil(t, listener, name)
+	require.NotEmpty(t, listener.FilterChains, name)
+
+	var buckets []*localratelimit.LocalRateLimit
+	for _, fc := range listener.FilterChains {
+		require.NotEmpty(t, fc.Filters)
+		if fc.Filters[0].Name != "envoy.filters.network.local_ratelimit" {
+			buckets = append(buckets, nil)
+			continue
+		}
+		// Nothing else on the chain gets to see the connection first.
+		require.Greater(t, len(fc.Filters), 1)
+		lrl := &localratelimit.LocalRateLimit{}
+		require.NoError(t, ptypes.UnmarshalAny(fc.Filters[0].GetTypedConfig(), lrl))
+		buckets = append(buckets, lrl)
+	}
+	return buckets
+}
+
+// bucketTotals adds up the burst and the rate of every bucket, which is what the listener as a
+// whole admits.
+func bucketTotals(t *testing.T, buckets []*localratelimit.LocalRateLimit) (burst, rate uint32) {
+	t.Helper()
+	for _, lrl := range buckets {
+		require.NotNil(t, lrl)
+		assert.Equal(t, int64(1), lrl.GetTokenBucket().GetFillInterval().GetSeconds())
+		burst += lrl.GetTokenBucket().GetMaxTokens()
+		rate += lrl.GetTokenBucket().GetTokensPerFill().GetValue()
+	}
+	return burst, rate
+}
+
+func TestConnectionRateLimit(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: example-cert
+  namespace: default
+type: kubernetes.io/tls
+data:
+  tls.crt: bm90LWEtcmVhbC1yc2EtY2VydA==
+  tls.key: ` + rsaKeyPEM + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: ambassador-listener-8443
+  namespace: default
+spec:
+  port: 8443
+  protocol: HTTPS
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+  connectionRateLimit:
+    connectionsPerSecond: 50
+    burst: 200
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: ambassador-listener-8080
+  namespace: default
+spec:
+  port: 8080
+  protocol: HTTP
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+  connectionRateLimit:
+    connectionsPerSecond: 20
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: ambassador-listener-8081
+  namespace: default
+spec:
+  port: 8081
+  protocol: HTTP
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+  connectionRateLimit:
+    connectionsPerSecond: 100
+    burst: 10
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: shop
+  namespace: default
+spec:
+  hostname: shop.example.com
+  tlsSecret:
+    name: example-cert
+  requestPolicy:
+    insecure:
+      action: Route
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: api
+  namespace: default
+spec:
+  hostname: api.example.com
+  tlsSecret:
+    name: example-cert
+` + entrypoint.FakeMappingYAML("app"))
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "app"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return findListener(config, func(l *v3listener.Listener) bool {
+			return l.Name == "ambassador-listener-8081"
+		}) != nil
+	})
+	require.NoError(t, err)
+
+	// Every chain on the TLS Listener -- both Hosts', and any cleartext ones -- gets a share
+	// of the Listener's bucket, refilling once a second, and the shares add up to the burst
+	// and the rate.
+	buckets := connectionRateLimits(t, config, "ambassador-listener-8443")
+	require.GreaterOrEqual(t, len(buckets), 2)
+	for _, lrl := range buckets {
+		require.NotNil(t, lrl)
+		assert.Equal(t, "ingress_https", lrl.StatPrefix)
+	}
+	burst, rate := bucketTotals(t, buckets)
+	assert.Equal(t, uint32(200), burst)
+	assert.Equal(t, uint32(50), rate)
+
+	// Without a burst, the bucket holds one second's worth.
+	burst, rate = bucketTotals(t, connectionRateLimits(t, config, "ambassador-listener-8080"))
+	assert.Equal(t, uint32(20), burst)
+	assert.Equal(t, uint32(20), rate)
+
+	// A burst smaller than the rate would quietly be the rate, so it's turned down.
+	for _, lrl := range connectionRateLimits(t, config, "ambassador-listener-8081") {
+		assert.Nil(t, lrl)
+	}
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Error("ambassador-listener-8081.default") != ""
+	})
+	require.NoError(t, err)
+	assert.Contains(t, diag.Error("ambassador-listener-8081.default"),
+		"connectionRateLimit burst 10 can't be less than connectionsPerSecond 100; ignoring it")
+}
+
+func TestConnectionRateLimitAcrossHosts(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: example-cert
+  namespace: default
+type: kubernetes.io/tls
+data:
+  tls.crt: bm90LWEtcmVhbC1yc2EtY2VydA==
+  tls.key: ` + rsaKeyPEM + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: ambassador-listener-8443
+  namespace: default
+spec:
+  port: 8443
+  protocol: HTTPS
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+  connectionRateLimit:
+    connectionsPerSecond: 9
+    burst: 15
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: shop
+  namespace: default
+spec:
+  hostname: shop.example.com
+  tlsSecret:
+    name: example-cert
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: api
+  namespace: default
+spec:
+  hostname: api.example.com
+  tlsSecret:
+    name: example-cert
+` + entrypoint.FakeMappingYAML("app"))
+	require.NoError(t, err)
+
+	f.Flush()
+	_, err = f.GetSnapshot(HasMapping("default", "app"))
+	require.NoError(t, err)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return findListener(config, func(l *v3listener.Listener) bool {
+			return l.Name == "ambassador-listener-8443"
+		}) != nil
+	})
+	require.NoError(t, err)
+
+	// Each Host gets a chain of its own...
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8443"
+	})
+	var serverNames []string
+	for _, fc := range listener.FilterChains {
+		serverNames = append(serverNames, fc.GetFilterChainMatch().GetServerNames()...)
+	}
+	assert.Contains(t, serverNames, "shop.example.com")
+	assert.Contains(t, serverNames, "api.example.com")
+
+	// ...but a client spreading connections across both Hosts still gets no more than the
+	// Listener's one limit, since each chain's bucket is only its share of it.
+	buckets := connectionRateLimits(t, config, "ambassador-listener-8443")
+	require.GreaterOrEqual(t, len(buckets), 2)
+	for _, lrl := range buckets {
+		require.NotNil(t, lrl)
+		assert.Less(t, lrl.GetTokenBucket().GetTokensPerFill().GetValue(), uint32(9))
+		assert.GreaterOrEqual(t, lrl.GetTokenBucket().GetMaxTokens(), lrl.GetTokenBucket().GetTokensPerFill().GetValue())
+	}
+	burst, rate := bucketTotals(t, buckets)
+	assert.Equal(t, uint32(15), burst)
+	assert.Equal(t, uint32(9), rate)
+}
@@ -42,9 +42,55 @@ func (c *ConsulStore) ConsulEndpoint(datacenter, service, address string, port i
 	c.endpoints[key] = ep
 }
 
+// Clear leaves a service with no endpoints, the way consul reports a service when every
+// instance has failed its health checks or deregistered.
+func (c *ConsulStore) Clear(datacenter, service string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.endpoints[ConsulKey{datacenter, service}] = consulwatch.Endpoints{Id: datacenter, Service: service}
+}
+
 func (c *ConsulStore) Get(datacenter, service string) (consulwatch.Endpoints, bool) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	ep, ok := c.endpoints[ConsulKey{datacenter, service}]
 	return ep, ok
 }
+
+// Endpoints returns a copy of all the endpoint data in the store.
+func (c *ConsulStore) Endpoints() map[ConsulKey]consulwatch.Endpoints {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	result := map[ConsulKey]consulwatch.Endpoints{}
+	for key, ep := range c.endpoints {
+		ep.Endpoints = append([]consulwatch.Endpoint{}, ep.Endpoints...)
+		result[key] = ep
+	}
+	return result
+}
+
+// Restore makes the store hold exactly the supplied endpoint data (as returned by Endpoints).
+// Services that aren't in it are left with no endpoints rather than removed, since that's what
+// consul would tell anyone watching them.
+func (c *ConsulStore) Restore(endpoints map[ConsulKey]consulwatch.Endpoints) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key, ep := range c.endpoints {
+		if _, ok := endpoints[key]; !ok {
+			c.endpoints[key] = consulwatch.Endpoints{Id: ep.Id, Service: ep.Service}
+		}
+	}
+	for key, ep := range endpoints {
+		ep.Endpoints = append([]consulwatch.Endpoint{}, ep.Endpoints...)
+		c.endpoints[key] = ep
+	}
+}
+
+// Set replaces all the endpoint data for a service, e.g. with endpoints from a captured snapshot.
+func (c *ConsulStore) Set(datacenter, service string, endpoints consulwatch.Endpoints) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.endpoints[ConsulKey{datacenter, service}] = endpoints
+}
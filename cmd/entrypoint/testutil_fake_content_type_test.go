@@ -0,0 +1,112 @@
+package entrypoint_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// routeSelects says whether a route's header matchers would accept a request with just the
+// given headers. It only understands the regex matches that content_types produces.
+func routeSelects(t *testing.T, r *route.Route, headers map[string]string) bool {
+	t.Helper()
+	for _, h := range r.Match.Headers {
+		value, ok := headers[h.Name]
+		if !ok {
+			return false
+		}
+		regex := h.GetSafeRegexMatch().GetRegex()
+		require.NotEmpty(t, regex, "header %s isn't a regex match", h.Name)
+		if !regexp.MustCompile("^(?:" + regex + ")$").MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestContentTypeRouting(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: api-grpc-web
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /api/
+  service: grpc-web.default
+  content_types:
+  - application/grpc-web*
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: api-json
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /api/
+  service: json.default
+  content_types:
+  - application/json
+  - text/*
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: api-default
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /api/
+  service: default-api.default
+`, "api-default", "cluster_default_api_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// Envoy takes the first route that matches, so route selection is the first route for
+	// /api/ whose headers match.
+	vh := findVirtualHost(listener, "*")
+	require.NotNil(t, vh)
+	var apiRoutes []*route.Route
+	for _, r := range vh.Routes {
+		if r.Match.GetPrefix() == "/api/" {
+			apiRoutes = append(apiRoutes, r)
+		}
+	}
+	require.Len(t, apiRoutes, 3)
+
+	selected := func(contentType string) string {
+		headers := map[string]string{}
+		if contentType != "" {
+			headers["content-type"] = contentType
+		}
+		for _, r := range apiRoutes {
+			if routeSelects(t, r, headers) {
+				return r.GetRoute().GetCluster()
+			}
+		}
+		return ""
+	}
+
+	assert.Equal(t, "cluster_grpc_web_default_default", selected("application/grpc-web"))
+	assert.Equal(t, "cluster_grpc_web_default_default", selected("application/grpc-web-text+proto"))
+	assert.Equal(t, "cluster_json_default_default", selected("Application/JSON; charset=utf-8"))
+	assert.Equal(t, "cluster_json_default_default", selected("text/plain"))
+	assert.Equal(t, "cluster_default_api_default", selected("application/grpc"))
+	assert.Equal(t, "cluster_default_api_default", selected(""))
+
+	// The catch-all has to come last, or it would take everything.
+	assert.Equal(t, "cluster_default_api_default", apiRoutes[2].GetRoute().GetCluster())
+}
@@ -0,0 +1,99 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	lua "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/lua/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCookieAttributes(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    cookie_attributes:
+      secure: true
+`+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("legacy")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: login
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /login/
+  service: login.default
+  cookie_attributes:
+    secure: true
+    same_site: Strict
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: embed
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /embed/
+  service: embed.default
+  cookie_attributes:
+    same_site: None
+`, "embed", "cluster_login_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	hcm := entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+
+	filterIdx := map[string]int{}
+	for i, filter := range hcm.HttpFilters {
+		filterIdx[filter.Name] = i
+	}
+	require.Contains(t, filterIdx, "ambassador.cookie_attributes")
+	assert.Equal(t, filterIdx["envoy.filters.http.router"]-1, filterIdx["ambassador.cookie_attributes"])
+
+	script := func(cluster string) string {
+		r := findRoute(listener, func(r *route.Route) bool {
+			return r.GetRoute().GetCluster() == cluster
+		})
+		require.NotNil(t, r)
+		cfg, ok := r.TypedPerFilterConfig["ambassador.cookie_attributes"]
+		if !ok {
+			return ""
+		}
+		lpr := &lua.LuaPerRoute{}
+		require.NoError(t, ptypes.UnmarshalAny(cfg, lpr))
+		return lpr.GetSourceCode().GetInlineString()
+	}
+
+	// Every Set-Cookie gets looked at, not just the first, and attributes a cookie already
+	// has aren't added twice.
+	legacy := script("cluster_legacy_default_default")
+	assert.Contains(t, legacy, "local secure = true\n")
+	assert.Contains(t, legacy, "local same_site = nil\n")
+	assert.Contains(t, legacy, `for key, value in pairs(headers) do`)
+	assert.Contains(t, legacy, `not string.find(attributes, ";%s*secure%s*;")`)
+
+	assert.Contains(t, script("cluster_login_default_default"), "local same_site = \"Strict\"\n")
+
+	// SameSite=None without Secure is an error, so embed doesn't get the Module's cookie
+	// rewriting -- or anything else.
+	assert.Nil(t, FindCluster(config, ClusterNameContains("cluster_embed_default")))
+}
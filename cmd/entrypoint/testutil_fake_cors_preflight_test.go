@@ -0,0 +1,109 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// corsPreflightYAML is a CORS-enabled Mapping behind an AuthService.
+const corsPreflightYAML = `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: AuthService
+metadata:
+  name: auth
+  namespace: default
+spec:
+  auth_service: auth.default:3000
+  proto: http
+` + entrypoint.FakeListenerYAML + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: foo
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /foo/
+  service: foo.default
+  cors:
+    origins:
+    - https://app.example.com
+    methods:
+    - GET
+    - POST
+`
+
+// corsPreflightFilters returns the position of each HTTP filter on the 8080 Listener, by name,
+// along with the Listener itself.
+func corsPreflightFilters(t *testing.T, yaml string) (map[string]int, *v3listener.Listener) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(yaml, "foo", "cluster_foo_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	hcm := entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+
+	filterIdx := map[string]int{}
+	for i, filter := range hcm.HttpFilters {
+		filterIdx[filter.Name] = i
+	}
+	require.Contains(t, filterIdx, "envoy.filters.http.cors")
+	require.Contains(t, filterIdx, "envoy.filters.http.ext_authz")
+	require.Contains(t, filterIdx, "envoy.filters.http.router")
+	assert.Equal(t, len(hcm.HttpFilters)-1, filterIdx["envoy.filters.http.router"])
+	return filterIdx, listener
+}
+
+func TestCORSPreflightShortCircuit(t *testing.T) {
+	filterIdx, listener := corsPreflightFilters(t, corsPreflightYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    cors_before_auth: true
+`)
+
+	// The CORS filter answers preflights for routes with a CORS policy. With
+	// cors_before_auth it runs before auth: a preflight never carries credentials, so auth
+	// would reject it, and anything that gets past all the filters goes to the upstream
+	// cluster.
+	assert.Less(t, filterIdx["envoy.filters.http.cors"], filterIdx["envoy.filters.http.ext_authz"])
+
+	// The route matches OPTIONS (there's no method restriction), and carries the policy
+	// the filter needs, enabled.
+	r := findRoute(listener, func(r *route.Route) bool {
+		return r.GetRoute().GetCluster() == "cluster_foo_default_default"
+	})
+	require.NotNil(t, r)
+	for _, h := range r.Match.Headers {
+		assert.NotEqual(t, ":method", h.Name)
+	}
+	cors := r.GetRoute().GetCors()
+	require.NotNil(t, cors)
+	assert.Equal(t, "GET, POST", cors.AllowMethods)
+	require.Len(t, cors.AllowOriginStringMatch, 1)
+	assert.Equal(t, "https://app.example.com", cors.AllowOriginStringMatch[0].GetExact())
+	assert.Equal(t, uint32(100), cors.GetFilterEnabled().GetDefaultValue().GetNumerator())
+}
+
+func TestCORSPreflightAfterAuthByDefault(t *testing.T) {
+	// Without cors_before_auth, auth still gets to see everything first, preflights
+	// included.
+	filterIdx, _ := corsPreflightFilters(t, corsPreflightYAML)
+	assert.Less(t, filterIdx["envoy.filters.http.ext_authz"], filterIdx["envoy.filters.http.cors"])
+}
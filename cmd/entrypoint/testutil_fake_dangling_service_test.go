@@ -0,0 +1,133 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serviceWithEndpoints is a Service in the default namespace, and the Endpoints behind it.
+func serviceWithEndpoints(name string) string {
+	return `
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: ` + name + `
+  namespace: default
+spec:
+  ports:
+  - port: 80
+    protocol: TCP
+    targetPort: 8080
+---
+apiVersion: v1
+kind: Endpoints
+metadata:
+  name: ` + name + `
+  namespace: default
+subsets:
+- addresses:
+  - ip: 10.42.0.15
+  ports:
+  - port: 8080
+    protocol: TCP
+`
+}
+
+func TestDanglingServiceReferences(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(serviceWithEndpoints("quote") + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: KubernetesEndpointResolver
+metadata:
+  name: endpoint
+  namespace: default
+---
+apiVersion: getambassador.io/v3alpha1
+kind: ConsulResolver
+metadata:
+  name: consul-dc1
+  namespace: default
+spec:
+  address: consul-server.default:8500
+  datacenter: dc1
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: quote
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /quote/
+  service: quote.default.svc.cluster.local:80
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: qoute
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /qoute/
+  service: qoute
+  resolver: endpoint
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: consul
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /consul/
+  service: consul-only
+  resolver: consul-dc1
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: external
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /external/
+  service: https://api.example.com
+`)
+	require.NoError(t, err)
+
+	f.Flush()
+	_, err = f.GetSnapshot(HasMapping("default", "external"))
+	require.NoError(t, err)
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Mapping("external") != nil
+	})
+	require.NoError(t, err)
+
+	// The typo gets a notice, but it's still a Mapping: the Service might just not be there yet.
+	assert.True(t, hasNotice(diag, "qoute.default", "matches no Kubernetes Service qoute in namespace default"))
+	assert.NotNil(t, diag.Mapping("qoute"))
+	assert.Empty(t, diag.Error("qoute.default"))
+
+	// The Service that's there is found however it's named, and the services Kubernetes doesn't
+	// know about in the first place -- from Consul, or out on the Internet -- aren't checked.
+	assert.Empty(t, diag.NoticesFor("quote.default"))
+	assert.Empty(t, diag.NoticesFor("consul.default"))
+	assert.Empty(t, diag.NoticesFor("external.default"))
+
+	// Once the Service shows up, the notice goes away.
+	require.NoError(t, f.UpsertYAML(serviceWithEndpoints("qoute")))
+	f.Flush()
+
+	diag, err = f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Mapping("qoute") != nil && !hasNotice(diag, "qoute.default", "matches no Kubernetes Service")
+	})
+	require.NoError(t, err)
+	assert.Empty(t, diag.NoticesFor("qoute.default"))
+}
@@ -0,0 +1,107 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	buffer "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/buffer/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecompressorRequestLimit(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    decompressor:
+      decompress_requests: true
+      max_request_bytes: 1048576
+`+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("foo")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: uploads
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /uploads/
+  service: uploads.default
+  decompressor_max_request_bytes: 10485760
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: stream
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /stream/
+  service: stream.default
+  decompressor_max_request_bytes: 0
+`, "stream", "cluster_stream_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	hcm := entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+
+	// The limit is a buffer filter right after the decompressor, so it sees decompressed
+	// bodies and answers 413 for anything bigger.
+	decompressorIdx, limitIdx := -1, -1
+	for i, filter := range hcm.HttpFilters {
+		switch filter.Name {
+		case "envoy.filters.http.decompressor":
+			decompressorIdx = i
+		case "ambassador.decompressor_limit":
+			limitIdx = i
+		}
+	}
+	require.NotEqual(t, -1, decompressorIdx)
+	require.Equal(t, decompressorIdx+1, limitIdx)
+
+	limit := &buffer.Buffer{}
+	require.NoError(t, ptypes.UnmarshalAny(hcm.HttpFilters[limitIdx].GetTypedConfig(), limit))
+	assert.Equal(t, uint32(1048576), limit.MaxRequestBytes.GetValue())
+
+	perRoute := func(cluster string) *buffer.BufferPerRoute {
+		r := findRoute(listener, func(r *route.Route) bool {
+			return r.GetRoute().GetCluster() == cluster
+		})
+		require.NotNil(t, r)
+		cfg, ok := r.TypedPerFilterConfig["ambassador.decompressor_limit"]
+		if !ok {
+			return nil
+		}
+		bpr := &buffer.BufferPerRoute{}
+		require.NoError(t, ptypes.UnmarshalAny(cfg, bpr))
+		return bpr
+	}
+
+	// Mappings that don't say get the Module's limit...
+	assert.Nil(t, perRoute("cluster_foo_default_default"))
+
+	// ...or can raise it...
+	bpr := perRoute("cluster_uploads_default_default")
+	require.NotNil(t, bpr)
+	assert.Equal(t, uint32(10485760), bpr.GetBuffer().GetMaxRequestBytes().GetValue())
+
+	// ...or turn it off.
+	bpr = perRoute("cluster_stream_default_default")
+	require.NotNil(t, bpr)
+	assert.True(t, bpr.GetDisabled())
+}
@@ -0,0 +1,70 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	gzip "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/compression/gzip/decompressor/v3"
+	decompressor "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/decompressor/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleDecompressor(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    gzip:
+      min_content_length: 32
+    decompressor:
+      window_bits: 12
+      chunk_size: 8192
+`+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("foo"), "foo", "cluster_foo_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	hcm := entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+
+	gzipIdx, decompressorIdx := -1, -1
+	for i, filter := range hcm.HttpFilters {
+		switch filter.Name {
+		case "envoy.filters.http.gzip":
+			gzipIdx = i
+		case "envoy.filters.http.decompressor":
+			decompressorIdx = i
+		}
+	}
+	require.NotEqual(t, -1, gzipIdx)
+	require.NotEqual(t, -1, decompressorIdx)
+
+	// Responses pass through the decompressor before they reach gzip.
+	assert.Less(t, gzipIdx, decompressorIdx)
+
+	dc := &decompressor.Decompressor{}
+	require.NoError(t, ptypes.UnmarshalAny(hcm.HttpFilters[decompressorIdx].GetTypedConfig(), dc))
+	assert.Equal(t, "envoy.compression.gzip.decompressor", dc.DecompressorLibrary.Name)
+
+	lib := &gzip.Gzip{}
+	require.NoError(t, ptypes.UnmarshalAny(dc.DecompressorLibrary.TypedConfig, lib))
+	assert.Equal(t, uint32(12), lib.WindowBits.GetValue())
+	assert.Equal(t, uint32(8192), lib.ChunkSize.GetValue())
+
+	// Requests from clients aren't decompressed unless asked.
+	enabled := dc.GetRequestDirectionConfig().GetCommonConfig().GetEnabled()
+	require.NotNil(t, enabled)
+	assert.False(t, enabled.DefaultValue.GetValue())
+}
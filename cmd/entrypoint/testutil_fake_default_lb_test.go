@@ -0,0 +1,50 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleDefaultLoadBalancer(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    load_balancer:
+      policy: least_request
+`+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("foo")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: bar
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /bar/
+  service: bar.default
+  load_balancer:
+    policy: round_robin
+`, "bar", "cluster_bar_default_default")
+
+	// foo has no load_balancer of its own, so it inherits the Module's...
+	cluster := FindCluster(config, ClusterNameContains("cluster_foo_default_default"))
+	require.NotNil(t, cluster)
+	assert.Equal(t, v3cluster.Cluster_LEAST_REQUEST, cluster.LbPolicy)
+
+	// ...but bar overrides it.
+	cluster = FindCluster(config, ClusterNameContains("cluster_bar_default_default"))
+	require.NotNil(t, cluster)
+	assert.Equal(t, v3cluster.Cluster_ROUND_ROBIN, cluster.LbPolicy)
+}
@@ -0,0 +1,110 @@
+package entrypoint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func defaultListener(config *bootstrap.Bootstrap) *v3listener.Listener {
+	return findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-default-listener-8080"
+	})
+}
+
+func TestDefaultListener(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    default_listener:
+      body: "Nothing is configured here yet.\n"
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: acme-solver
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /.well-known/acme-challenge/some-token
+  rewrite: ""
+  service: acme-solver.default:8089
+` + entrypoint.FakeMappingYAML("foo"))
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "foo"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	// With no Listeners and no Hosts, there's still something listening...
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return defaultListener(config) != nil
+	})
+	require.NoError(t, err)
+	listener := defaultListener(config)
+	assert.Equal(t, uint32(8080), listener.GetAddress().GetSocketAddress().GetPortValue())
+
+	// ...that still lets ACME challenges through...
+	acme := findRoute(listener, func(r *route.Route) bool {
+		return strings.HasPrefix(r.GetMatch().GetPrefix(), "/.well-known/acme-challenge/")
+	})
+	require.NotNil(t, acme)
+	assert.Contains(t, acme.GetRoute().GetCluster(), "acme_solver")
+
+	// ...and answers everything else with a 404, including requests for Mappings, since
+	// there's no Host to say who they're for.
+	assert.Nil(t, findRoute(listener, func(r *route.Route) bool {
+		return r.GetMatch().GetPrefix() == "/foo/"
+	}))
+	fallback := findRoute(listener, func(r *route.Route) bool {
+		return r.GetDirectResponse() != nil
+	})
+	require.NotNil(t, fallback)
+	assert.Equal(t, "/", fallback.GetMatch().GetPrefix())
+	assert.Equal(t, uint32(404), fallback.GetDirectResponse().Status)
+	assert.Equal(t, "Nothing is configured here yet.\n", fallback.GetDirectResponse().GetBody().GetInlineString())
+
+	// Once there's real config, the default listener gets out of the way.
+	err = f.UpsertYAML(entrypoint.FakeListenerYAML + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: example
+  namespace: default
+spec:
+  hostname: "*"
+  requestPolicy:
+    insecure:
+      action: Route
+`)
+	require.NoError(t, err)
+	f.Flush()
+
+	config, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return defaultListener(config) == nil
+	})
+	require.NoError(t, err)
+	listener = findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	assert.NotNil(t, findRoute(listener, func(r *route.Route) bool {
+		return r.GetMatch().GetPrefix() == "/foo/"
+	}))
+}
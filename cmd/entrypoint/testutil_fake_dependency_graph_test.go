@@ -0,0 +1,193 @@
+package entrypoint
+
+import (
+	"context"
+	"sort"
+
+	amb "github.com/datawire/ambassador/v2/pkg/api/getambassador.io/v3alpha1"
+	"github.com/datawire/ambassador/v2/pkg/kates"
+	snapshotTypes "github.com/datawire/ambassador/v2/pkg/snapshot/v1"
+)
+
+// DependencyNode identifies one resource in a DependencyGraph.
+type DependencyNode struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// Dependency is one edge of a DependencyGraph: From can't be configured without To. Missing is
+// set when To isn't in the snapshot at all, i.e. From has a dangling reference.
+type Dependency struct {
+	From    DependencyNode
+	To      DependencyNode
+	Missing bool
+}
+
+// DependencyGraph returns the references between resources in a snapshot that the reconciler
+// follows: the Secrets that ReconcileSecrets decides to keep (looking at the same resources,
+// with the same secret namespacing), plus the TLSContexts named by Mappings, Hosts, and other
+// TLSContexts. The edges come back sorted, so that two graphs of the same snapshot compare
+// equal.
+//
+// A Secret counts as present if it's in Secrets, K8sSecrets, or FSSecrets, so this works both
+// before and after ReconcileSecrets has run.
+func DependencyGraph(ctx context.Context, s *snapshotTypes.KubernetesSnapshot) []Dependency {
+	var deps []Dependency
+
+	secrets := map[snapshotTypes.SecretRef]bool{}
+	for ref := range s.FSSecrets {
+		secrets[ref] = true
+	}
+	for _, list := range [][]*kates.Secret{s.K8sSecrets, s.Secrets} {
+		for _, secret := range list {
+			secrets[snapshotTypes.SecretRef{Namespace: secret.GetNamespace(), Name: secret.GetName()}] = true
+		}
+	}
+
+	resources, secretNamespacing := dependencyResources(ctx, s)
+	for _, resource := range resources {
+		from := dependencyNode(resource)
+		findSecretRefs(ctx, resource, secretNamespacing, func(ref snapshotTypes.SecretRef) {
+			deps = append(deps, Dependency{
+				From:    from,
+				To:      DependencyNode{Kind: "Secret", Namespace: ref.Namespace, Name: ref.Name},
+				Missing: !secrets[ref],
+			})
+		})
+	}
+
+	// TLSContexts are looked up by name alone, so prefer one in the referrer's namespace but
+	// take one from anywhere.
+	var contexts []*amb.TLSContext
+	for _, t := range s.TLSContexts {
+		if include(t.Spec.AmbassadorID) {
+			contexts = append(contexts, t)
+		}
+	}
+	tlsContextRef := func(from DependencyNode, name string) {
+		to := DependencyNode{Kind: "TLSContext", Namespace: from.Namespace, Name: name}
+		missing := true
+		for _, t := range contexts {
+			if t.GetName() != name {
+				continue
+			}
+			if missing || t.GetNamespace() == from.Namespace {
+				to.Namespace = t.GetNamespace()
+				missing = false
+			}
+		}
+		deps = append(deps, Dependency{From: from, To: to, Missing: missing})
+	}
+
+	for _, m := range s.Mappings {
+		// "tls: true" just means originate TLS, with no TLSContext involved.
+		if include(m.Spec.AmbassadorID) && m.Spec.TLS != "" && m.Spec.TLS != "true" {
+			tlsContextRef(dependencyNode(m), m.Spec.TLS)
+		}
+	}
+	for _, resource := range resources {
+		switch r := resource.(type) {
+		case *amb.Host:
+			if r.Spec != nil && r.Spec.TLSContext != nil && r.Spec.TLSContext.Name != "" {
+				tlsContextRef(dependencyNode(r), r.Spec.TLSContext.Name)
+			}
+		case *amb.TLSContext:
+			if r.Spec.InheritFrom != "" {
+				tlsContextRef(dependencyNode(r), r.Spec.InheritFrom)
+			}
+		}
+	}
+
+	sort.Slice(deps, func(i, j int) bool {
+		a, b := deps[i], deps[j]
+		if a.From != b.From {
+			return nodeLess(a.From, b.From)
+		}
+		return nodeLess(a.To, b.To)
+	})
+
+	// A resource can name the same thing twice (a TLSContext whose secret is also its
+	// ca_secret, say), but that's still just one dependency.
+	var unique []Dependency
+	for i, dep := range deps {
+		if i == 0 || dep != deps[i-1] {
+			unique = append(unique, dep)
+		}
+	}
+	return unique
+}
+
+// dependencyResources returns the resources that ReconcileSecrets looks for secret references
+// in, and whether those references are namespaced, worked out the same way it does.
+func dependencyResources(ctx context.Context, s *snapshotTypes.KubernetesSnapshot) ([]kates.Object, bool) {
+	var resources []kates.Object
+	for _, a := range s.Annotations {
+		if include(GetAmbId(ctx, a)) {
+			resources = append(resources, a)
+		}
+	}
+	for _, h := range s.Hosts {
+		var id amb.AmbassadorID
+		if len(h.Spec.AmbassadorID) > 0 {
+			id = h.Spec.AmbassadorID
+		}
+		if include(id) {
+			resources = append(resources, h)
+		}
+	}
+	for _, t := range s.TLSContexts {
+		if include(t.Spec.AmbassadorID) {
+			resources = append(resources, t)
+		}
+	}
+	for _, m := range s.Modules {
+		if include(m.Spec.AmbassadorID) {
+			resources = append(resources, m)
+		}
+	}
+	for _, i := range s.Ingresses {
+		resources = append(resources, i)
+	}
+
+	secretNamespacing := true
+	for _, resource := range resources {
+		if mod, ok := resource.(*amb.Module); ok && mod.GetName() == "ambassador" {
+			secs := ModuleSecrets{}
+			if err := convert(mod.Spec.Config, &secs); err != nil {
+				continue
+			}
+			secretNamespacing = secs.Defaults.TLSSecretNamespacing
+			break
+		}
+	}
+	return resources, secretNamespacing
+}
+
+func dependencyNode(resource kates.Object) DependencyNode {
+	kind := resource.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		// Objects built in Go rather than decoded don't always have their TypeMeta filled in.
+		switch resource.(type) {
+		case *amb.Host:
+			kind = "Host"
+		case *amb.Mapping:
+			kind = "Mapping"
+		case *amb.Module:
+			kind = "Module"
+		case *amb.TLSContext:
+			kind = "TLSContext"
+		}
+	}
+	return DependencyNode{Kind: kind, Namespace: resource.GetNamespace(), Name: resource.GetName()}
+}
+
+func nodeLess(a, b DependencyNode) bool {
+	if a.Kind != b.Kind {
+		return a.Kind < b.Kind
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
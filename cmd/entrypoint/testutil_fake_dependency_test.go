@@ -0,0 +1,141 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	"github.com/datawire/ambassador/v2/pkg/snapshot/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDependencyGraph(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: shared-cert
+  namespace: default
+type: kubernetes.io/tls
+data:
+  tls.crt: ""
+  tls.key: ""
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: a
+  namespace: default
+spec:
+  hostname: a.example.com
+  tlsSecret:
+    name: shared-cert
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: b
+  namespace: default
+spec:
+  hostname: b.example.com
+  tlsSecret:
+    name: shared-cert
+  tlsContext:
+    name: b-context
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: b-context
+  namespace: default
+spec:
+  hosts:
+  - b.example.com
+  secret: shared-cert
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: upstream
+  namespace: default
+spec:
+  secret: upstream-cert
+  inherit_from: b-context
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: backend
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /backend/
+  service: https://backend.default
+  tls: upstream
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: typo
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /typo/
+  service: https://typo.default
+  tls: upstrem
+`)
+	require.NoError(t, err)
+	f.Flush()
+
+	deps, err := f.DependencyGraph(HasMapping("default", "typo"))
+	require.NoError(t, err)
+
+	node := func(kind, name string) entrypoint.DependencyNode {
+		return entrypoint.DependencyNode{Kind: kind, Namespace: "default", Name: name}
+	}
+	edge := func(from, to entrypoint.DependencyNode, missing bool) entrypoint.Dependency {
+		return entrypoint.Dependency{From: from, To: to, Missing: missing}
+	}
+
+	// Every Host and TLSContext that uses shared-cert depends on it; the Secret upstream-cert
+	// and the TLSContext upstrem don't exist, so those edges dangle.
+	assert.Equal(t, []entrypoint.Dependency{
+		edge(node("Host", "a"), node("Secret", "shared-cert"), false),
+		edge(node("Host", "b"), node("Secret", "shared-cert"), false),
+		edge(node("Host", "b"), node("TLSContext", "b-context"), false),
+		edge(node("Mapping", "backend"), node("TLSContext", "upstream"), false),
+		edge(node("Mapping", "typo"), node("TLSContext", "upstrem"), true),
+		edge(node("TLSContext", "b-context"), node("Secret", "shared-cert"), false),
+		edge(node("TLSContext", "upstream"), node("Secret", "upstream-cert"), true),
+		edge(node("TLSContext", "upstream"), node("TLSContext", "b-context"), false),
+	}, deps)
+
+	// Once the Secret shows up, the TLSContext that wanted it is satisfied.
+	require.NoError(t, f.UpsertYAML(`
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: upstream-cert
+  namespace: default
+type: kubernetes.io/tls
+data:
+  tls.crt: ""
+  tls.key: ""
+`))
+	f.Flush()
+
+	deps, err = f.DependencyGraph(func(snap *snapshot.Snapshot) bool {
+		for _, s := range snap.Kubernetes.Secrets {
+			if s.GetName() == "upstream-cert" {
+				return true
+			}
+		}
+		return false
+	})
+	require.NoError(t, err)
+	assert.Contains(t, deps, edge(node("TLSContext", "upstream"), node("Secret", "upstream-cert"), false))
+}
@@ -0,0 +1,177 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Diagnostics is the part of diagd's diagnostic overview that tests care about. Labels and
+// Annotations only have the keys asked for with the ambassador Module's diagnostics_labels and
+// diagnostics_annotations, and only the ones the resource actually has.
+type Diagnostics struct {
+	Routes []DiagnosticsRoute           `json:"route_info"`
+	Groups map[string]DiagnosticsGroup  `json:"groups"`
+	Hosts  []DiagnosticsResourceSummary `json:"hosts"`
+
+	// Errors are the configuration errors diagd found, each as the key of the resource it
+	// was found in and the error itself.
+	Errors [][]string `json:"errors"`
+
+	// Notices are diagd's notices, configuration or otherwise.
+	Notices []DiagnosticsNotice `json:"notices"`
+}
+
+// DiagnosticsNotice is one notice in the diagnostics. A notice about a resource has a message
+// that starts with the resource's key.
+type DiagnosticsNotice struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// DiagnosticsRoute is one route in the diagnostic overview.
+type DiagnosticsRoute struct {
+	Key         string            `json:"key"`
+	GroupID     string            `json:"_group_id"`
+	Prefix      string            `json:"prefix"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// DiagnosticsGroup is one mapping group in the diagnostics.
+type DiagnosticsGroup struct {
+	Mappings []DiagnosticsResourceSummary `json:"mappings"`
+}
+
+// DiagnosticsResourceSummary is what the diagnostics say about a single Mapping or Host.
+type DiagnosticsResourceSummary struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Hostname    string            `json:"hostname"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+
+	// ClusterName is the envoy name of a Mapping's cluster; Hosts don't have one.
+	ClusterName string `json:"cluster_name"`
+}
+
+// ClusterMappings returns the names of the Mappings that use each cluster, by the cluster's
+// envoy name, which is how a test sees which Mappings ended up sharing a cluster.
+func (d *Diagnostics) ClusterMappings() map[string][]string {
+	seen := map[string]map[string]bool{}
+	for _, group := range d.Groups {
+		for _, m := range group.Mappings {
+			if m.ClusterName == "" {
+				continue
+			}
+			if seen[m.ClusterName] == nil {
+				seen[m.ClusterName] = map[string]bool{}
+			}
+			seen[m.ClusterName][m.Name] = true
+		}
+	}
+
+	result := map[string][]string{}
+	for cluster, names := range seen {
+		for name := range names {
+			result[cluster] = append(result[cluster], name)
+		}
+		sort.Strings(result[cluster])
+	}
+	return result
+}
+
+// Mapping returns the summary of the named Mapping, or nil if no group has it. The diagnostics
+// don't say which namespace a Mapping is in, so this only goes by name.
+func (d *Diagnostics) Mapping(name string) *DiagnosticsResourceSummary {
+	for _, group := range d.Groups {
+		for i := range group.Mappings {
+			if group.Mappings[i].Name == name {
+				return &group.Mappings[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Host returns the summary of the named Host, or nil if there isn't one.
+func (d *Diagnostics) Host(name, namespace string) *DiagnosticsResourceSummary {
+	for i := range d.Hosts {
+		if d.Hosts[i].Name == name && d.Hosts[i].Namespace == namespace {
+			return &d.Hosts[i]
+		}
+	}
+	return nil
+}
+
+// Error returns the first error diagd has for the resource with the given key, or "" if there
+// isn't one. The key of a resource from a Kubernetes object is "name.namespace".
+func (d *Diagnostics) Error(key string) string {
+	for _, e := range d.Errors {
+		if len(e) == 2 && e[0] == key {
+			return e[1]
+		}
+	}
+	return ""
+}
+
+// NoticesFor returns every notice diagd has for the resource with the given key ("name.namespace",
+// which also covers the "name.namespace.1" a Kubernetes object's resources get), without the key
+// in front.
+func (d *Diagnostics) NoticesFor(key string) []string {
+	var notices []string
+	for _, n := range d.Notices {
+		parts := strings.SplitN(n.Message, ": ", 2)
+		if len(parts) == 2 && (parts[0] == key || strings.HasPrefix(parts[0], key+".")) {
+			notices = append(notices, parts[1])
+		}
+	}
+	return notices
+}
+
+func (f *Fake) fetchDiagnostics() (*Diagnostics, error) {
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%s/ambassador/v0/diag/?json=true", GetDiagdBindPort()))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("diagd returned %s", resp.Status)
+	}
+
+	diag := &Diagnostics{}
+	if err := json.NewDecoder(resp.Body).Decode(diag); err != nil {
+		return nil, err
+	}
+	return diag, nil
+}
+
+// GetDiagnostics asks diagd for its diagnostics until they satisfy the supplied predicate. This
+// only works if the Fake was started with EnvoyConfig, since otherwise there is no diagd. Unlike
+// GetSnapshot and GetEnvoyConfig, there's no history here: diagd only knows its current config.
+func (f *Fake) GetDiagnostics(predicate func(*Diagnostics) bool) (*Diagnostics, error) {
+	f.T.Helper()
+	if !f.config.EnvoyConfig {
+		return nil, fmt.Errorf("GetDiagnostics needs a Fake with EnvoyConfig")
+	}
+
+	deadline := time.Now().Add(f.config.Timeout)
+	var lastErr error
+	for time.Now().Before(deadline) {
+		diag, err := f.fetchDiagnostics()
+		if err == nil && predicate(diag) {
+			return diag, nil
+		}
+		lastErr = err
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if lastErr != nil {
+		return nil, fmt.Errorf("timed out waiting for diagnostics: %w", lastErr)
+	}
+	return nil, fmt.Errorf("timed out waiting for diagnostics to satisfy predicate")
+}
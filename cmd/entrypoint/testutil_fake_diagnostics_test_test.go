@@ -0,0 +1,105 @@
+package entrypoint_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+)
+
+func TestDiagnosticsLabelsAndAnnotations(t *testing.T) {
+	// Lots of labels that nobody asked for, which must stay out of the diagnostics.
+	var noise strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&noise, "    noise-%d: \"%d\"\n", i, i)
+	}
+
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    diagnostics_labels:
+    - team
+    - tier
+    diagnostics_annotations:
+    - example.com/owner
+` + entrypoint.FakeListenerYAML + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: teamhost
+  namespace: default
+  labels:
+    team: payments
+  annotations:
+    example.com/owner: alice@example.com
+spec:
+  hostname: pay.example.com
+  requestPolicy:
+    insecure:
+      action: Route
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: labelled
+  namespace: default
+  labels:
+    team: payments
+` + noise.String() + `  annotations:
+    example.com/owner: alice@example.com
+    example.com/unrelated: "yes"
+spec:
+  hostname: "*"
+  prefix: /labelled/
+  service: labelled.default
+` + entrypoint.FakeMappingYAML("bare"))
+	require.NoError(t, err)
+
+	f.Flush()
+	_, err = f.GetSnapshot(HasMapping("default", "bare"))
+	require.NoError(t, err)
+
+	diag, err := f.GetDiagnostics(func(d *entrypoint.Diagnostics) bool {
+		return d.Mapping("labelled") != nil && d.Mapping("bare") != nil
+	})
+	require.NoError(t, err)
+
+	// Only the selected keys show up; tier isn't there because the Mapping doesn't have it.
+	labelled := diag.Mapping("labelled")
+	assert.Equal(t, map[string]string{"team": "payments"}, labelled.Labels)
+	assert.Equal(t, map[string]string{"example.com/owner": "alice@example.com"}, labelled.Annotations)
+
+	// The route for the Mapping carries them too, for filtering the overview.
+	var route *entrypoint.DiagnosticsRoute
+	for i := range diag.Routes {
+		if diag.Routes[i].Prefix == "/labelled/" {
+			route = &diag.Routes[i]
+		}
+	}
+	require.NotNil(t, route)
+	assert.Equal(t, map[string]string{"team": "payments"}, route.Labels)
+	assert.Equal(t, map[string]string{"example.com/owner": "alice@example.com"}, route.Annotations)
+
+	// A Mapping with none of the keys just has nothing.
+	bare := diag.Mapping("bare")
+	assert.Empty(t, bare.Labels)
+	assert.Empty(t, bare.Annotations)
+
+	host := diag.Host("teamhost", "default")
+	require.NotNil(t, host)
+	assert.Equal(t, "pay.example.com", host.Hostname)
+	assert.Equal(t, map[string]string{"team": "payments"}, host.Labels)
+	assert.Equal(t, map[string]string{"example.com/owner": "alice@example.com"}, host.Annotations)
+}
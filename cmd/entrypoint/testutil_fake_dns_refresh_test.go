@@ -0,0 +1,89 @@
+package entrypoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSFailureRefreshAndInitialFetchTimeout(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	require.NoError(t, f.Upsert(makeService("default", "eds-default")))
+	require.NoError(t, f.Upsert(makeService("default", "eds-forever")))
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    dns_failure_refresh_rate_ms: 2000
+    initial_fetch_timeout_ms: 5000
+`+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("dns-default")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: dns-override
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /dns-override/
+  service: dns-override.default
+  dns_failure_refresh_rate_ms: 500
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: eds-default
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /eds-default/
+  service: eds-default
+  resolver: endpoint
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: eds-forever
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /eds-forever/
+  service: eds-forever
+  resolver: endpoint
+  initial_fetch_timeout_ms: 0
+`, "eds-forever", "cluster_eds_forever_default")
+
+	// DNS clusters get the failure backoff from the Module, unless the Mapping overrides it.
+	cluster := FindCluster(config, ClusterNameContains("cluster_dns_default_default"))
+	require.NotNil(t, cluster)
+	require.NotNil(t, cluster.DnsFailureRefreshRate)
+	assert.Equal(t, 2*time.Second, cluster.DnsFailureRefreshRate.BaseInterval.AsDuration())
+	assert.Nil(t, cluster.EdsClusterConfig)
+
+	cluster = FindCluster(config, ClusterNameContains("cluster_dns_override_default"))
+	require.NotNil(t, cluster)
+	require.NotNil(t, cluster.DnsFailureRefreshRate)
+	assert.Equal(t, 500*time.Millisecond, cluster.DnsFailureRefreshRate.BaseInterval.AsDuration())
+
+	// EDS clusters get the initial fetch timeout instead, and 0 (wait forever) is kept.
+	cluster = FindCluster(config, ClusterNameContains("cluster_eds_default_default"))
+	require.NotNil(t, cluster)
+	assert.Nil(t, cluster.DnsFailureRefreshRate)
+	require.NotNil(t, cluster.EdsClusterConfig)
+	assert.Equal(t, 5*time.Second, cluster.EdsClusterConfig.EdsConfig.InitialFetchTimeout.AsDuration())
+
+	cluster = FindCluster(config, ClusterNameContains("cluster_eds_forever_default"))
+	require.NotNil(t, cluster)
+	require.NotNil(t, cluster.EdsClusterConfig)
+	require.NotNil(t, cluster.EdsClusterConfig.EdsConfig.InitialFetchTimeout)
+	assert.Equal(t, time.Duration(0), cluster.EdsClusterConfig.EdsConfig.InitialFetchTimeout.AsDuration())
+}
@@ -0,0 +1,120 @@
+package entrypoint_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	dfpcluster "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/clusters/dynamic_forward_proxy/v3"
+	dfpfilter "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/dynamic_forward_proxy/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamicForwardProxy(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    dynamic_forward_proxy:
+      dns_refresh_rate_ms: 30000
+      host_ttl_ms: 600000
+      max_hosts: 256
+`+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: egress
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /
+  service: https://*
+  dynamic_forward_proxy:
+    allowed_hosts:
+    - api.example.com
+    - "*.example.org"
+`+entrypoint.FakeMappingYAML("plain"), "plain", "cluster_plain_default")
+
+	// The egress cluster has no endpoints: Envoy resolves the request's host with the DNS
+	// cache, and sends it as the SNI.
+	cluster := FindCluster(config, ClusterNameContains("dfp"))
+	require.NotNil(t, cluster)
+	assert.Equal(t, v3cluster.Cluster_CLUSTER_PROVIDED, cluster.LbPolicy)
+	assert.Nil(t, cluster.LoadAssignment)
+	require.NotNil(t, cluster.GetClusterType())
+	assert.Equal(t, "envoy.clusters.dynamic_forward_proxy", cluster.GetClusterType().Name)
+	require.NotNil(t, cluster.UpstreamHttpProtocolOptions)
+	assert.True(t, cluster.UpstreamHttpProtocolOptions.AutoSni)
+	assert.True(t, cluster.UpstreamHttpProtocolOptions.AutoSanValidation)
+	assert.NotNil(t, cluster.TransportSocket)
+
+	clusterConfig := &dfpcluster.ClusterConfig{}
+	require.NoError(t, ptypes.UnmarshalAny(cluster.GetClusterType().TypedConfig, clusterConfig))
+	dnsCache := clusterConfig.DnsCacheConfig
+	require.NotNil(t, dnsCache)
+	assert.Equal(t, 30*time.Second, dnsCache.DnsRefreshRate.AsDuration())
+	assert.Equal(t, 600*time.Second, dnsCache.HostTtl.AsDuration())
+	assert.Equal(t, uint32(256), dnsCache.MaxHosts.GetValue())
+
+	// The plain cluster is left alone.
+	plain := FindCluster(config, ClusterNameContains("cluster_plain_default"))
+	require.NotNil(t, plain)
+	assert.Nil(t, plain.GetClusterType())
+	assert.NotNil(t, plain.LoadAssignment)
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// The filter has to share the cluster's DNS cache, and run before the router.
+	hcm := entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+	filterIdx := -1
+	for i, filter := range hcm.HttpFilters {
+		if filter.Name == "envoy.filters.http.dynamic_forward_proxy" {
+			filterIdx = i
+			filterConfig := &dfpfilter.FilterConfig{}
+			require.NoError(t, ptypes.UnmarshalAny(filter.GetTypedConfig(), filterConfig))
+			assert.True(t, proto.Equal(dnsCache, filterConfig.DnsCacheConfig))
+		}
+	}
+	require.NotEqual(t, -1, filterIdx)
+	assert.Less(t, filterIdx, len(hcm.HttpFilters)-1)
+
+	// Only requests for the allowed hosts match the egress route; anything else doesn't get
+	// forwarded.
+	r := findRoute(listener, func(r *route.Route) bool {
+		return r.GetRoute().GetCluster() == cluster.Name
+	})
+	require.NotNil(t, r)
+	var allowed *regexp.Regexp
+	for _, h := range r.Match.Headers {
+		if h.Name == ":authority" && h.GetSafeRegexMatch() != nil {
+			allowed = regexp.MustCompile("^(?:" + h.GetSafeRegexMatch().Regex + ")$")
+		}
+	}
+	require.NotNil(t, allowed)
+	assert.True(t, allowed.MatchString("api.example.com"))
+	assert.True(t, allowed.MatchString("API.example.com:443"))
+	assert.True(t, allowed.MatchString("files.example.org"))
+	assert.False(t, allowed.MatchString("example.org"))
+	assert.False(t, allowed.MatchString("api.example.com.evil.net"))
+	assert.False(t, allowed.MatchString("evil.net"))
+}
@@ -0,0 +1,90 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3core "github.com/datawire/ambassador/v2/pkg/api/envoy/config/core/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requestIDEcho returns the x-request-id response header a route adds, or nil if it doesn't.
+func requestIDEcho(r *route.Route) *v3core.HeaderValueOption {
+	for _, header := range r.ResponseHeadersToAdd {
+		if header.GetHeader().GetKey() == "x-request-id" {
+			return header
+		}
+	}
+	return nil
+}
+
+func TestEchoRequestID(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    echo_request_id: true
+    preserve_external_request_id: true
+`+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("orders")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: internal
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /internal/
+  service: internal.default
+  echo_request_id: false
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: custom
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /custom/
+  service: custom.default
+  add_response_headers:
+    x-request-id: "%REQ(x-custom-id)%"
+`, "custom", "cluster_custom_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	routeFor := func(cluster string) *route.Route {
+		r := findRoute(listener, func(r *route.Route) bool {
+			return r.GetRoute().GetCluster() == cluster
+		})
+		require.NotNil(t, r)
+		return r
+	}
+
+	// The Module turns the echo on: whatever request ID the request ended up with (here, the
+	// client's own, since it's preserved) replaces any the upstream sent back...
+	echo := requestIDEcho(routeFor("cluster_orders_default_default"))
+	require.NotNil(t, echo)
+	assert.Equal(t, "%REQ(x-request-id)%", echo.GetHeader().GetValue())
+	assert.False(t, echo.GetAppend().GetValue())
+
+	// ...internal would rather not say...
+	assert.Nil(t, requestIDEcho(routeFor("cluster_internal_default_default")))
+
+	// ...and custom's own x-request-id wins.
+	echo = requestIDEcho(routeFor("cluster_custom_default_default"))
+	require.NotNil(t, echo)
+	assert.Equal(t, "%REQ(x-custom-id)%", echo.GetHeader().GetValue())
+}
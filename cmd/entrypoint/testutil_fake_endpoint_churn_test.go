@@ -0,0 +1,110 @@
+package entrypoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3endpoint "github.com/datawire/ambassador/v2/pkg/api/envoy/config/endpoint/v3"
+)
+
+// loadAssignmentIPs returns the addresses in a ClusterLoadAssignment, or nil if there's no
+// assignment at all.
+func loadAssignmentIPs(cla *v3endpoint.ClusterLoadAssignment) []string {
+	if cla == nil {
+		return nil
+	}
+	ips := []string{}
+	for _, locality := range cla.Endpoints {
+		for _, lbEndpoint := range locality.LbEndpoints {
+			ips = append(ips, lbEndpoint.GetEndpoint().Address.GetSocketAddress().Address)
+		}
+	}
+	return ips
+}
+
+func hasLoadAssignmentIPs(serviceName string, ips ...string) func(map[string]*v3endpoint.ClusterLoadAssignment) bool {
+	return func(assignments map[string]*v3endpoint.ClusterLoadAssignment) bool {
+		got := loadAssignmentIPs(assignments[serviceName])
+		if got == nil || len(got) != len(ips) {
+			return false
+		}
+		for i := range ips {
+			if got[i] != ips[i] {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func TestEndpointChurnIsEDSOnly(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	require.NoError(t, f.Upsert(makeMapping("default", "foo", "/foo/", "foo.default", "endpoint")))
+	require.NoError(t, f.Upsert(makeService("default", "foo")))
+	subset, err := makeSubset(8080, "10.0.0.1")
+	require.NoError(t, err)
+	require.NoError(t, f.Upsert(makeEndpoints("default", "foo", subset)))
+	f.Flush()
+
+	snap, err := f.GetSnapshot(HasMapping("default", "foo"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	config, err := f.GetEnvoyConfig(func(config *v3bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_foo_default_default")) != nil
+	})
+	require.NoError(t, err)
+	cluster := FindCluster(config, ClusterNameContains("cluster_foo_default_default"))
+	require.NotNil(t, cluster)
+	require.NotNil(t, cluster.EdsClusterConfig)
+	serviceName := cluster.EdsClusterConfig.ServiceName
+
+	_, err = f.GetLoadAssignments(hasLoadAssignmentIPs(serviceName, "10.0.0.1"))
+	require.NoError(t, err)
+
+	// Scaling up only changes the load assignment.
+	subset, err = makeSubset(8080, "10.0.0.1", "10.0.0.2")
+	require.NoError(t, err)
+	require.NoError(t, f.Upsert(makeEndpoints("default", "foo", subset)))
+	f.Flush()
+	_, err = f.GetLoadAssignments(hasLoadAssignmentIPs(serviceName, "10.0.0.1", "10.0.0.2"))
+	require.NoError(t, err)
+
+	// Rapid churn between flushes is coalesced: the very next update is the final state.
+	for _, ip := range []string{"10.0.0.3", "10.0.0.4", "10.0.0.5"} {
+		subset, err = makeSubset(8080, ip)
+		require.NoError(t, err)
+		require.NoError(t, f.Upsert(makeEndpoints("default", "foo", subset)))
+	}
+	f.Flush()
+	assignments, err := f.GetLoadAssignments(func(map[string]*v3endpoint.ClusterLoadAssignment) bool {
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.5"}, loadAssignmentIPs(assignments[serviceName]))
+
+	// Scaling to zero leaves the cluster in place with an empty assignment, rather than
+	// dropping it (which would make envoy warm it again when the endpoints come back)...
+	require.NoError(t, f.Upsert(makeEndpoints("default", "foo")))
+	f.Flush()
+	assignments, err = f.GetLoadAssignments(hasLoadAssignmentIPs(serviceName))
+	require.NoError(t, err)
+	require.Contains(t, assignments, serviceName)
+	assert.Empty(t, loadAssignmentIPs(assignments[serviceName]))
+
+	// ...and scaling back up is just another load assignment.
+	subset, err = makeSubset(8080, "10.0.0.6")
+	require.NoError(t, err)
+	require.NoError(t, f.Upsert(makeEndpoints("default", "foo", subset)))
+	f.Flush()
+	_, err = f.GetLoadAssignments(hasLoadAssignmentIPs(serviceName, "10.0.0.6"))
+	require.NoError(t, err)
+
+	// Through all of that, diagd never regenerated the clusters or listeners.
+	f.AssertNoReconfigure(2 * time.Second)
+}
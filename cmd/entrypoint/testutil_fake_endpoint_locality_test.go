@@ -0,0 +1,104 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3core "github.com/datawire/ambassador/v2/pkg/api/envoy/config/core/v3"
+	v3endpoint "github.com/datawire/ambassador/v2/pkg/api/envoy/config/endpoint/v3"
+)
+
+// loadAssignmentLocalities maps each address in a ClusterLoadAssignment to the locality it's in,
+// with nil for the default locality.
+func loadAssignmentLocalities(cla *v3endpoint.ClusterLoadAssignment) map[string]*v3core.Locality {
+	localities := map[string]*v3core.Locality{}
+	if cla == nil {
+		return localities
+	}
+	for _, locality := range cla.Endpoints {
+		for _, lbEndpoint := range locality.LbEndpoints {
+			localities[lbEndpoint.GetEndpoint().Address.GetSocketAddress().Address] = locality.Locality
+		}
+	}
+	return localities
+}
+
+func TestEndpointLocality(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	require.NoError(t, f.Upsert(makeMapping("default", "foo", "/foo/", "foo.default", "endpoint")))
+	require.NoError(t, f.Upsert(makeService("default", "foo")))
+	subset, err := makeSubset(8080, "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4")
+	require.NoError(t, err)
+	require.NoError(t, f.Upsert(makeEndpoints("default", "foo", subset)))
+	f.Flush()
+
+	config, err := f.GetEnvoyConfig(func(config *v3bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_foo_default_default")) != nil
+	})
+	require.NoError(t, err)
+	cluster := FindCluster(config, ClusterNameContains("cluster_foo_default_default"))
+	require.NotNil(t, cluster)
+	require.NotNil(t, cluster.EdsClusterConfig)
+	serviceName := cluster.EdsClusterConfig.ServiceName
+
+	// Without any EndpointSlices, everything is in the default locality, just as it always was.
+	assignments, err := f.GetLoadAssignments(hasLoadAssignmentIPs(serviceName, "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"))
+	require.NoError(t, err)
+	require.Len(t, assignments[serviceName].Endpoints, 1)
+	assert.Nil(t, assignments[serviceName].Endpoints[0].Locality)
+
+	// The slice has the v1 zone field, v1beta1-style topology, an endpoint with nothing but
+	// hints, and an endpoint that isn't in the slice at all.
+	require.NoError(t, f.UpsertYAML(`
+---
+apiVersion: discovery.k8s.io/v1
+kind: EndpointSlice
+metadata:
+  name: foo-abc12
+  namespace: default
+  labels:
+    kubernetes.io/service-name: foo
+addressType: IPv4
+ports:
+- port: 8080
+endpoints:
+- addresses: ["10.0.0.1"]
+  zone: us-east-1a
+  deprecatedTopology:
+    topology.kubernetes.io/region: us-east-1
+    topology.istio.io/subzone: rack-1
+- addresses: ["10.0.0.2"]
+  topology:
+    failure-domain.beta.kubernetes.io/region: us-east-1
+    failure-domain.beta.kubernetes.io/zone: us-east-1b
+- addresses: ["10.0.0.3"]
+  hints:
+    forZones:
+    - name: us-east-1a
+`))
+	f.Flush()
+
+	assignments, err = f.GetLoadAssignments(func(assignments map[string]*v3endpoint.ClusterLoadAssignment) bool {
+		return len(assignments[serviceName].GetEndpoints()) == 3
+	})
+	require.NoError(t, err)
+
+	localities := loadAssignmentLocalities(assignments[serviceName])
+	assert.Equal(t, "us-east-1", localities["10.0.0.1"].GetRegion())
+	assert.Equal(t, "us-east-1a", localities["10.0.0.1"].GetZone())
+	assert.Equal(t, "rack-1", localities["10.0.0.1"].GetSubZone())
+	assert.Equal(t, "us-east-1", localities["10.0.0.2"].GetRegion())
+	assert.Equal(t, "us-east-1b", localities["10.0.0.2"].GetZone())
+	assert.Equal(t, "", localities["10.0.0.2"].GetSubZone())
+
+	// Hints say who should use an endpoint, not where it is, so they don't give it a locality.
+	assert.Nil(t, localities["10.0.0.3"])
+	assert.Nil(t, localities["10.0.0.4"])
+
+	// The default locality comes first.
+	assert.Nil(t, assignments[serviceName].Endpoints[0].Locality)
+}
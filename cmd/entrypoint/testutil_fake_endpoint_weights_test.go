@@ -0,0 +1,79 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3endpoint "github.com/datawire/ambassador/v2/pkg/api/envoy/config/endpoint/v3"
+)
+
+// loadAssignmentWeights maps each address in a ClusterLoadAssignment to its load balancing
+// weight, with 0 for an endpoint that doesn't have one.
+func loadAssignmentWeights(cla *v3endpoint.ClusterLoadAssignment) map[string]uint32 {
+	weights := map[string]uint32{}
+	if cla == nil {
+		return weights
+	}
+	for _, locality := range cla.Endpoints {
+		for _, lbEndpoint := range locality.LbEndpoints {
+			weights[lbEndpoint.GetEndpoint().Address.GetSocketAddress().Address] = lbEndpoint.GetLoadBalancingWeight().GetValue()
+		}
+	}
+	return weights
+}
+
+func TestEndpointWeights(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	require.NoError(t, f.Upsert(makeMapping("default", "foo", "/foo/", "foo.default", "endpoint")))
+	require.NoError(t, f.Upsert(makeService("default", "foo")))
+	subset, err := makeSubset(8080, "10.0.0.1", "10.0.0.2", "10.0.0.3")
+	require.NoError(t, err)
+	require.NoError(t, f.Upsert(makeEndpoints("default", "foo", subset)))
+	f.Flush()
+
+	config, err := f.GetEnvoyConfig(func(config *v3bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_foo_default_default")) != nil
+	})
+	require.NoError(t, err)
+	cluster := FindCluster(config, ClusterNameContains("cluster_foo_default_default"))
+	require.NotNil(t, cluster)
+	require.NotNil(t, cluster.EdsClusterConfig)
+	serviceName := cluster.EdsClusterConfig.ServiceName
+
+	// With no weights, every endpoint is unweighted, which Envoy treats as equal.
+	assignments, err := f.GetLoadAssignments(hasLoadAssignmentIPs(serviceName, "10.0.0.1", "10.0.0.2", "10.0.0.3"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]uint32{
+		"10.0.0.1": 0,
+		"10.0.0.2": 0,
+		"10.0.0.3": 0,
+	}, loadAssignmentWeights(assignments[serviceName]))
+
+	// Weighting the endpoints moves traffic around; the one weighted 0 (a pod being drained,
+	// say) drops out of the assignment entirely, and the one left out keeps Envoy's default.
+	endpoints := makeEndpoints("default", "foo", subset)
+	endpoints.Annotations = map[string]string{
+		"getambassador.io/endpoint-weights": `{"10.0.0.1": 5, "10.0.0.2": 0}`,
+	}
+	require.NoError(t, f.Upsert(endpoints))
+	f.Flush()
+
+	assignments, err = f.GetLoadAssignments(hasLoadAssignmentIPs(serviceName, "10.0.0.1", "10.0.0.3"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]uint32{
+		"10.0.0.1": 5,
+		"10.0.0.3": 0,
+	}, loadAssignmentWeights(assignments[serviceName]))
+
+	// An annotation that doesn't parse is ignored, rather than taking endpoints away.
+	endpoints.Annotations["getambassador.io/endpoint-weights"] = `10.0.0.1=5`
+	require.NoError(t, f.Upsert(endpoints))
+	f.Flush()
+
+	_, err = f.GetLoadAssignments(hasLoadAssignmentIPs(serviceName, "10.0.0.1", "10.0.0.2", "10.0.0.3"))
+	require.NoError(t, err)
+}
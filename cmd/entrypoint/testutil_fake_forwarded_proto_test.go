@@ -0,0 +1,108 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	http "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerForwardedProto(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+
+	// hostsem-basic.yaml has TLS Listeners on 8080 and 8443, with Hosts and Secrets for them.
+	require.NoError(t, f.UpsertFile("testdata/hostsem-basic.yaml"))
+	err := f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: listener-cleartext
+  namespace: default
+spec:
+  port: 8090
+  protocol: HTTP
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: listener-behind-lb
+  namespace: default
+spec:
+  port: 8091
+  protocol: HTTP
+  securityModel: XFP
+  l7Depth: 1
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: listener-forced
+  namespace: default
+spec:
+  port: 8092
+  protocol: HTTP
+  securityModel: SECURE
+  forwardedProto: https
+  hostBinding:
+    namespace:
+      from: ALL
+`)
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "qotm-mapping-1"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return findListener(config, func(l *v3listener.Listener) bool {
+			return l.Name == "listener-forced"
+		}) != nil
+	})
+	require.NoError(t, err)
+
+	hcm := func(name string) *http.HttpConnectionManager {
+		listener := findListener(config, func(l *v3listener.Listener) bool {
+			return l.Name == name
+		})
+		require.NotNil(t, listener, name)
+		hcm := entrypoint.ListenerHCM(listener)
+		require.NotNil(t, hcm, name)
+		return hcm
+	}
+
+	// At the edge, Envoy sets X-Forwarded-Proto from the client's connection: https on the
+	// TLS Listener, http on the cleartext one, whatever the client sent.
+	for _, name := range []string{"ambassador-listener-8443", "listener-cleartext"} {
+		h := hcm(name)
+		assert.True(t, h.GetUseRemoteAddress().GetValue(), name)
+		assert.Equal(t, uint32(0), h.XffNumTrustedHops, name)
+		assert.Empty(t, h.GetRouteConfig().GetRequestHeadersToAdd(), name)
+	}
+
+	// Behind a trusted L7 load balancer, the load balancer's X-Forwarded-Proto is kept.
+	h := hcm("listener-behind-lb")
+	assert.Equal(t, uint32(1), h.XffNumTrustedHops)
+	assert.Empty(t, h.GetRouteConfig().GetRequestHeadersToAdd())
+
+	// forwardedProto overwrites it for the upstream, however the request arrived.
+	h = hcm("listener-forced")
+	added := h.GetRouteConfig().GetRequestHeadersToAdd()
+	require.Len(t, added, 1)
+	assert.Equal(t, "x-forwarded-proto", added[0].Header.Key)
+	assert.Equal(t, "https", added[0].Header.Value)
+	assert.False(t, added[0].GetAppend().GetValue())
+}
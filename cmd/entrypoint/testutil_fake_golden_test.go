@@ -0,0 +1,151 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	v3bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+)
+
+// updateGoldens makes AssertGolden (and so Fake.AssertEnvoyConfigGolden) rewrite golden files
+// instead of checking against them: go test ./cmd/entrypoint/ -run TestWhatever -update
+var updateGoldens = flag.Bool("update", false, "rewrite golden files with what the tests generate")
+
+// GoldenNormalizer rewrites the parts of a string in an envoy config that change from run to run
+// without meaning anything, so that golden files don't have to change with them.
+type GoldenNormalizer struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// GoldenNormalizers are applied, in order, to every string in an envoy config before it's compared
+// against a golden file.
+var GoldenNormalizers = []GoldenNormalizer{
+	// Temporary directories, from python's tempfile and go's testing.T.TempDir.
+	{regexp.MustCompile(`/tmp/tmp[A-Za-z0-9_]{6,}`), "<tmpdir>"},
+	{regexp.MustCompile(`/tmp/Test[A-Za-z0-9_]+/[0-9]+`), "<tmpdir>"},
+	// Content hashes, like the ones in the names of the files secrets get written out to.
+	{regexp.MustCompile(`\b[0-9A-Fa-f]{32,}\b`), "<hash>"},
+}
+
+// NormalizeEnvoyConfig renders an envoy config as indented JSON that's the same from run to run:
+// listeners and clusters are sorted by name (envoy doesn't care what order they're in), object
+// keys are sorted, and strings go through GoldenNormalizers. Routes and filters keep their order,
+// since that's part of what they mean.
+func NormalizeEnvoyConfig(config *v3bootstrap.Bootstrap) ([]byte, error) {
+	config = proto.Clone(config).(*v3bootstrap.Bootstrap)
+
+	if sr := config.StaticResources; sr != nil {
+		sort.SliceStable(sr.Listeners, func(i, j int) bool {
+			return sr.Listeners[i].Name < sr.Listeners[j].Name
+		})
+		sort.SliceStable(sr.Clusters, func(i, j int) bool {
+			return sr.Clusters[i].Name < sr.Clusters[j].Name
+		})
+	}
+
+	bytes, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// protojson deliberately doesn't promise stable output, so go through encoding/json, which
+	// does.
+	var untyped interface{}
+	if err := json.Unmarshal(bytes, &untyped); err != nil {
+		return nil, err
+	}
+
+	// Leave <, >, and & alone, so that the golden files are readable.
+	out := &strings.Builder{}
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(normalizeGoldenValue(untyped)); err != nil {
+		return nil, err
+	}
+
+	return []byte(out.String()), nil
+}
+
+func normalizeGoldenValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		for _, n := range GoldenNormalizers {
+			v = n.Pattern.ReplaceAllString(v, n.Replacement)
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = normalizeGoldenValue(v[i])
+		}
+		return v
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = normalizeGoldenValue(v[k])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// AssertGolden checks got against the golden file at path, or (with -update) writes got to it.
+func AssertGolden(t testing.TB, path string, got []byte) {
+	t.Helper()
+
+	if *updateGoldens {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Errorf("unable to create the directory for golden file %s: %v", path, err)
+			return
+		}
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Errorf("unable to write golden file %s: %v", path, err)
+			return
+		}
+		t.Logf("updated golden file %s", path)
+		return
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Errorf("golden file %s doesn't exist; rerun with -update to create it", path)
+		return
+	} else if err != nil {
+		t.Errorf("unable to read golden file %s: %v", path, err)
+		return
+	}
+
+	assert.Equal(t, string(want), string(got), "golden file %s doesn't match; rerun with -update if the change is expected", path)
+}
+
+// AssertEnvoyConfigGolden checks the most recent envoy config against the golden file at path, after
+// NormalizeEnvoyConfig. Run the test with -update to write the golden file instead. This only works
+// if the Fake was started with EnvoyConfig, and once it has produced a config (so call
+// GetEnvoyConfig first to wait for the one you want).
+func (f *Fake) AssertEnvoyConfigGolden(t *testing.T, path string) {
+	t.Helper()
+
+	config, _ := f.envoyConfigs.Latest().(*v3bootstrap.Bootstrap)
+	if config == nil {
+		t.Fatalf("AssertEnvoyConfigGolden needs an envoy config; is the Fake configured with EnvoyConfig: true?")
+	}
+
+	got, err := NormalizeEnvoyConfig(config)
+	if err != nil {
+		t.Fatalf("unable to normalize envoy config: %v", err)
+	}
+
+	AssertGolden(t, path, got)
+}
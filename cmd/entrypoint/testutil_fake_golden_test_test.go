@@ -0,0 +1,84 @@
+package entrypoint_test
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+)
+
+// recordingT lets a test check what AssertGolden complains about without failing itself.
+type recordingT struct {
+	*testing.T
+	errors []string
+}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func goldenTestConfig(tmpdir, hash string, names ...string) *v3bootstrap.Bootstrap {
+	config := &v3bootstrap.Bootstrap{StaticResources: &v3bootstrap.Bootstrap_StaticResources{}}
+	for _, name := range names {
+		config.StaticResources.Clusters = append(config.StaticResources.Clusters, &v3cluster.Cluster{
+			Name:        name,
+			AltStatName: fmt.Sprintf("%s/default/secrets-decoded/%s/%s.crt", tmpdir, name, hash),
+		})
+	}
+	return config
+}
+
+func TestNormalizeEnvoyConfig(t *testing.T) {
+	// The same config from two runs: different temporary directories and secret hashes, and
+	// the clusters in a different order.
+	first, err := entrypoint.NormalizeEnvoyConfig(goldenTestConfig("/tmp/tmpa1b2c3d4", "0123456789ABCDEF0123456789ABCDEF01234567", "cluster_b", "cluster_a"))
+	require.NoError(t, err)
+	second, err := entrypoint.NormalizeEnvoyConfig(goldenTestConfig("/tmp/tmpz9y8x7w6", "FEDCBA9876543210FEDCBA9876543210FEDCBA98", "cluster_a", "cluster_b"))
+	require.NoError(t, err)
+	assert.Equal(t, string(first), string(second))
+
+	assert.Contains(t, string(first), `"alt_stat_name": "<tmpdir>/default/secrets-decoded/cluster_a/<hash>.crt"`)
+	assert.Less(t, strings.Index(string(first), "cluster_a"), strings.Index(string(first), "cluster_b"))
+
+	// Normalizing doesn't touch the config it was handed.
+	config := goldenTestConfig("/tmp/tmpa1b2c3d4", "0123456789ABCDEF0123456789ABCDEF01234567", "cluster_b", "cluster_a")
+	_, err = entrypoint.NormalizeEnvoyConfig(config)
+	require.NoError(t, err)
+	assert.Equal(t, "cluster_b", config.StaticResources.Clusters[0].Name)
+}
+
+func TestAssertGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden", "config.json")
+
+	// Without a golden file, there's nothing to compare against.
+	r := &recordingT{T: t}
+	entrypoint.AssertGolden(r, path, []byte("one\n"))
+	require.Len(t, r.errors, 1)
+	assert.Contains(t, r.errors[0], "rerun with -update")
+
+	// -update writes it (and the directory it goes in)...
+	require.NoError(t, flag.Set("update", "true"))
+	r = &recordingT{T: t}
+	entrypoint.AssertGolden(r, path, []byte("one\n"))
+	require.NoError(t, flag.Set("update", "false"))
+	assert.Empty(t, r.errors)
+
+	// ...after which the same thing matches...
+	r = &recordingT{T: t}
+	entrypoint.AssertGolden(r, path, []byte("one\n"))
+	assert.Empty(t, r.errors)
+
+	// ...and anything else doesn't.
+	r = &recordingT{T: t}
+	entrypoint.AssertGolden(r, path, []byte("two\n"))
+	require.Len(t, r.errors, 1)
+	assert.Contains(t, r.errors[0], "doesn't match")
+}
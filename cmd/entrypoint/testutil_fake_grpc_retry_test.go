@@ -0,0 +1,73 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGRPCRetryOn(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: grpc-only
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /grpc.Only/
+  rewrite: /grpc.Only/
+  service: grpc-only.default
+  grpc: true
+  retry_policy:
+    grpc_retry_on:
+    - cancelled
+    - deadline-exceeded
+    - unavailable
+    num_retries: 3
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: grpc-mixed
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /grpc.Mixed/
+  rewrite: /grpc.Mixed/
+  service: grpc-mixed.default
+  grpc: true
+  retry_policy:
+    retry_on: connect-failure
+    grpc_retry_on:
+    - unavailable
+`, "grpc-mixed", "cluster_grpc_mixed_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	routeAction := findVirtualHostRoute(listener, func(r *route.RouteAction) bool {
+		return r.GetCluster() == "cluster_grpc_only_default_default"
+	})
+	require.NotNil(t, routeAction)
+	require.NotNil(t, routeAction.RetryPolicy)
+	assert.Equal(t, "cancelled,deadline-exceeded,unavailable", routeAction.RetryPolicy.RetryOn)
+	assert.Equal(t, uint32(3), routeAction.RetryPolicy.NumRetries.GetValue())
+
+	// HTTP conditions come first, then the gRPC codes.
+	routeAction = findVirtualHostRoute(listener, func(r *route.RouteAction) bool {
+		return r.GetCluster() == "cluster_grpc_mixed_default_default"
+	})
+	require.NotNil(t, routeAction)
+	require.NotNil(t, routeAction.RetryPolicy)
+	assert.Equal(t, "connect-failure,unavailable", routeAction.RetryPolicy.RetryOn)
+}
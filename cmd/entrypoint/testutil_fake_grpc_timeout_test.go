@@ -0,0 +1,71 @@
+package entrypoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGRPCTimeoutHeader(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: grpc-deadline
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /grpc.Deadline/
+  rewrite: /grpc.Deadline/
+  service: grpc-deadline.default
+  grpc: true
+  max_grpc_timeout_ms: 30000
+  grpc_timeout_offset_ms: 50
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: grpc-offset-only
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /grpc.OffsetOnly/
+  rewrite: /grpc.OffsetOnly/
+  service: grpc-offset-only.default
+  grpc: true
+  grpc_timeout_offset_ms: 50
+`, "grpc-offset-only", "cluster_grpc_offset_only_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// The grpc-timeout header replaces the route timeout, clamped to 30s and shortened by the
+	// offset; Envoy does the clamping and offsetting per request.
+	routeAction := findVirtualHostRoute(listener, func(r *route.RouteAction) bool {
+		return r.GetCluster() == "cluster_grpc_deadline_default_default"
+	})
+	require.NotNil(t, routeAction)
+	require.NotNil(t, routeAction.MaxGrpcTimeout)
+	assert.Equal(t, 30*time.Second, routeAction.MaxGrpcTimeout.AsDuration())
+	require.NotNil(t, routeAction.GrpcTimeoutOffset)
+	assert.Equal(t, 50*time.Millisecond, routeAction.GrpcTimeoutOffset.AsDuration())
+
+	// An offset on its own does nothing in Envoy, so it's dropped, and the header is ignored.
+	routeAction = findVirtualHostRoute(listener, func(r *route.RouteAction) bool {
+		return r.GetCluster() == "cluster_grpc_offset_only_default_default"
+	})
+	require.NotNil(t, routeAction)
+	assert.Nil(t, routeAction.MaxGrpcTimeout)
+	assert.Nil(t, routeAction.GrpcTimeoutOffset)
+	assert.Equal(t, 3*time.Second, routeAction.Timeout.AsDuration())
+}
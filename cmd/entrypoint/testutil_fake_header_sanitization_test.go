@@ -0,0 +1,68 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderSanitization(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    header_sanitization:
+      strip_hop_by_hop: true
+      sensitive_headers:
+      - Authorization
+      - X-Internal-Token
+`+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("internal")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: checks-credentials
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /api/
+  service: api.default
+  preserve_sensitive_headers:
+  - authorization
+`, "checks-credentials", "cluster_api_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// Sensitive headers (lowercased) and hop-by-hop headers are stripped going upstream, and
+	// hop-by-hop headers coming back. Envoy does connection, upgrade, and friends itself.
+	r := findRoute(listener, func(r *route.Route) bool {
+		return r.GetRoute().GetCluster() == "cluster_internal_default_default"
+	})
+	require.NotNil(t, r)
+	assert.Subset(t, r.RequestHeadersToRemove, []string{"authorization", "x-internal-token", "keep-alive", "proxy-connection", "proxy-authorization"})
+	assert.NotContains(t, r.RequestHeadersToRemove, "connection")
+	assert.NotContains(t, r.RequestHeadersToRemove, "te")
+	assert.Subset(t, r.ResponseHeadersToRemove, []string{"keep-alive", "proxy-connection", "proxy-authenticate"})
+
+	// A Mapping can keep what its service needs, and only that.
+	r = findRoute(listener, func(r *route.Route) bool {
+		return r.GetRoute().GetCluster() == "cluster_api_default_default"
+	})
+	require.NotNil(t, r)
+	assert.NotContains(t, r.RequestHeadersToRemove, "authorization")
+	assert.Subset(t, r.RequestHeadersToRemove, []string{"x-internal-token", "proxy-authorization"})
+}
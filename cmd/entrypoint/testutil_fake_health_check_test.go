@@ -0,0 +1,123 @@
+package entrypoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCheckJitter(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: jittered
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /jittered/
+  service: jittered.default
+  health_checks:
+  - interval: 10s
+    interval_jitter: 1s
+    interval_jitter_percent: 20
+    health_check:
+      http:
+        path: /healthz
+        expected_statuses:
+        - min: 200
+          max: 204
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: defaults
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /defaults/
+  service: defaults.default
+  health_checks:
+  - health_check:
+      http:
+        path: /healthz
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: capped
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /capped/
+  service: capped.default
+  health_checks:
+  - interval: 2s
+    interval_jitter: 5s
+    health_check:
+      http:
+        path: /healthz
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: grpc-checked
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /grpc.Checked/
+  rewrite: /grpc.Checked/
+  service: grpc-checked.default
+  grpc: true
+  health_checks:
+  - interval_jitter_percent: 10
+    health_check:
+      grpc:
+        service_name: grpc.Checked
+`, "grpc-checked", "cluster_grpc_checked_default_default")
+
+	cluster := FindCluster(config, ClusterNameContains("cluster_jittered_default_default"))
+	require.NotNil(t, cluster)
+	require.Len(t, cluster.HealthChecks, 1)
+	hc := cluster.HealthChecks[0]
+	assert.Equal(t, 10*time.Second, hc.Interval.AsDuration())
+	assert.Equal(t, time.Second, hc.IntervalJitter.AsDuration())
+	assert.Equal(t, uint32(20), hc.IntervalJitterPercent)
+	require.NotNil(t, hc.GetHttpHealthCheck())
+	assert.Equal(t, "/healthz", hc.GetHttpHealthCheck().Path)
+	require.Len(t, hc.GetHttpHealthCheck().ExpectedStatuses, 1)
+	assert.Equal(t, int64(200), hc.GetHttpHealthCheck().ExpectedStatuses[0].Start)
+	assert.Equal(t, int64(205), hc.GetHttpHealthCheck().ExpectedStatuses[0].End)
+
+	// With nothing but the check itself, we get Envoy's defaults and no jitter.
+	cluster = FindCluster(config, ClusterNameContains("cluster_defaults_default_default"))
+	require.NotNil(t, cluster)
+	require.Len(t, cluster.HealthChecks, 1)
+	hc = cluster.HealthChecks[0]
+	assert.Equal(t, 3*time.Second, hc.Timeout.AsDuration())
+	assert.Equal(t, 5*time.Second, hc.Interval.AsDuration())
+	assert.Nil(t, hc.IntervalJitter)
+	assert.Equal(t, uint32(0), hc.IntervalJitterPercent)
+	assert.Equal(t, uint32(2), hc.UnhealthyThreshold.GetValue())
+	assert.Equal(t, uint32(1), hc.HealthyThreshold.GetValue())
+
+	// A jitter longer than the interval is capped at the interval.
+	cluster = FindCluster(config, ClusterNameContains("cluster_capped_default_default"))
+	require.NotNil(t, cluster)
+	require.Len(t, cluster.HealthChecks, 1)
+	assert.Equal(t, 2*time.Second, cluster.HealthChecks[0].IntervalJitter.AsDuration())
+
+	cluster = FindCluster(config, ClusterNameContains("cluster_grpc_checked_default_default"))
+	require.NotNil(t, cluster)
+	require.Len(t, cluster.HealthChecks, 1)
+	hc = cluster.HealthChecks[0]
+	assert.Equal(t, uint32(10), hc.IntervalJitterPercent)
+	require.NotNil(t, hc.GetGrpcHealthCheck())
+	assert.Equal(t, "grpc.Checked", hc.GetGrpcHealthCheck().ServiceName)
+}
@@ -0,0 +1,105 @@
+package entrypoint_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkoutWeights returns the weights of the /checkout/ route's clusters, or nil if there's no
+// weighted /checkout/ route.
+func checkoutWeights(config *bootstrap.Bootstrap) map[string]uint32 {
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	if listener == nil {
+		return nil
+	}
+	vh := findVirtualHost(listener, "*")
+	if vh == nil {
+		return nil
+	}
+
+	for _, r := range vh.Routes {
+		wc := r.GetRoute().GetWeightedClusters()
+		if r.Match.GetPrefix() != "/checkout/" || wc == nil || wc.GetTotalWeight().GetValue() != 100 {
+			continue
+		}
+
+		weights := map[string]uint32{}
+		for _, c := range wc.Clusters {
+			weights[c.Name] = c.GetWeight().GetValue()
+		}
+		return weights
+	}
+
+	return nil
+}
+
+func TestHealthRebalance(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: checkout
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /checkout/
+  service: checkout.default
+  health_rebalance: true
+  health_rebalance_recovery_ms: 0
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: checkout-canary
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /checkout/
+  service: checkout-canary.default
+  weight: 20
+  health_rebalance: true
+  health_rebalance_recovery_ms: 0
+`, "checkout-canary", "cluster_checkout_canary_default_default")
+
+	// The group is a single weighted route, even without a runtime key prefix, so that the
+	// weights can be moved.
+	configured := map[string]uint32{
+		"cluster_checkout_default_default":        80,
+		"cluster_checkout_canary_default_default": 20,
+	}
+	assert.Equal(t, configured, checkoutWeights(config))
+
+	// Everyone healthy: nothing changes.
+	assert.False(t, f.SetClusterHealth(map[string]int{
+		"cluster_checkout_default_default":        3,
+		"cluster_checkout_canary_default_default": 1,
+	}))
+
+	// The canary loses its last healthy endpoint, and its traffic goes to the stable service.
+	require.True(t, f.SetClusterHealth(map[string]int{
+		"cluster_checkout_default_default": 3,
+	}))
+	_, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return reflect.DeepEqual(map[string]uint32{"cluster_checkout_default_default": 100}, checkoutWeights(config))
+	})
+	require.NoError(t, err)
+
+	// With the stable service down too, there's nowhere better to send anything, so the
+	// configured weights come back.
+	require.True(t, f.SetClusterHealth(map[string]int{}))
+	_, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return reflect.DeepEqual(configured, checkoutWeights(config))
+	})
+	require.NoError(t, err)
+}
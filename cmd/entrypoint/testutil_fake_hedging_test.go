@@ -0,0 +1,122 @@
+package entrypoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHedging(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: hedged
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /hedged/
+  service: hedged.default
+  circuit_breakers:
+  - max_requests: 500
+    max_retries: 50
+  retry_policy:
+    retry_on: 5xx
+    num_retries: 2
+    per_try_timeout: 250ms
+  hedging:
+    budget_percent: 20
+    min_concurrency: 1
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: zero
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /zero/
+  service: zero.default
+  retry_policy:
+    retry_on: 5xx
+    per_try_timeout: 250ms
+  hedging:
+    budget_percent: 0
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: untimed
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /untimed/
+  service: untimed.default
+  retry_policy:
+    retry_on: 5xx
+  hedging:
+    budget_percent: 10
+`, "untimed", "cluster_untimed_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// A try that runs past its per-try timeout gets hedged...
+	r := findRoute(listener, func(r *route.Route) bool {
+		return r.GetMatch().GetPrefix() == "/hedged/"
+	})
+	require.NotNil(t, r)
+	assert.True(t, r.GetRoute().GetHedgePolicy().GetHedgeOnPerTryTimeout())
+	assert.Equal(t, 250*time.Millisecond, r.GetRoute().GetRetryPolicy().GetPerTryTimeout().AsDuration())
+	assert.Equal(t, uint32(2), r.GetRoute().GetRetryPolicy().GetNumRetries().GetValue())
+
+	// ...within a budget on the cluster, which retries share, and which takes over from
+	// max_retries. The rest of the circuit breaker stays as it was.
+	cluster := FindCluster(config, func(c *v3cluster.Cluster) bool {
+		return c.Name == r.GetRoute().GetCluster()
+	})
+	require.NotNil(t, cluster)
+	thresholds := cluster.GetCircuitBreakers().GetThresholds()
+	require.Len(t, thresholds, 1)
+	assert.Equal(t, uint32(500), thresholds[0].GetMaxRequests().GetValue())
+	budget := thresholds[0].GetRetryBudget()
+	require.NotNil(t, budget)
+	assert.Equal(t, 20.0, budget.GetBudgetPercent().GetValue())
+	assert.Equal(t, uint32(1), budget.GetMinRetryConcurrency().GetValue())
+
+	// With no budget, or no per-try timeout to hedge on, there's no hedging, but the retries
+	// are still there.
+	for _, prefix := range []string{"/zero/", "/untimed/"} {
+		r := findRoute(listener, func(r *route.Route) bool {
+			return r.GetMatch().GetPrefix() == prefix
+		})
+		require.NotNil(t, r, prefix)
+		assert.Nil(t, r.GetRoute().GetHedgePolicy(), prefix)
+		assert.NotNil(t, r.GetRoute().GetRetryPolicy(), prefix)
+
+		cluster := FindCluster(config, func(c *v3cluster.Cluster) bool {
+			return c.Name == r.GetRoute().GetCluster()
+		})
+		require.NotNil(t, cluster, prefix)
+		assert.Nil(t, cluster.GetCircuitBreakers(), prefix)
+	}
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Mapping("untimed") != nil && len(diag.NoticesFor("untimed.default")) > 0
+	})
+	require.NoError(t, err)
+	assert.True(t, hasNotice(diag, "hedged.default", "max_retries is replaced by the hedging budget"))
+	assert.True(t, hasNotice(diag, "zero.default", "budget_percent 0 allows no hedged requests"))
+	assert.True(t, hasNotice(diag, "untimed.default", "hedging does nothing without a retry_policy per_try_timeout"))
+}
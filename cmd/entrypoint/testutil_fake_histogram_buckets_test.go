@@ -0,0 +1,84 @@
+package entrypoint_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    histogram_buckets: [10, 100, 1000, 10000]
+`+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: checkout
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /checkout/
+  service: checkout.default
+  histogram_buckets: [5, 25, 50, 100, 250, 500]
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: metrics-scraper
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /scrape/
+  service: scraper.default
+  disable_histograms: true
+`+entrypoint.FakeMappingYAML("catalog")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: backwards
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /backwards/
+  service: backwards.default
+  histogram_buckets: [100, 10]
+`, "backwards", "cluster_checkout_default_default")
+	require.NotNil(t, config.StatsConfig)
+
+	// The Mapping's own buckets go first, since the first match wins, and the Module's cover
+	// every other cluster, like catalog.
+	settings := config.StatsConfig.HistogramBucketSettings
+	require.Len(t, settings, 2)
+	assert.Equal(t, "cluster.checkout_default.", settings[0].GetMatch().GetPrefix())
+	assert.Equal(t, []float64{5, 25, 50, 100, 250, 500}, settings[0].Buckets)
+	assert.Equal(t, "cluster.", settings[1].GetMatch().GetPrefix())
+	assert.Equal(t, []float64{10, 100, 1000, 10000}, settings[1].Buckets)
+
+	// The scraper's histograms are gone, but nothing else of its, nor anyone else's.
+	patterns := config.StatsConfig.GetStatsMatcher().GetExclusionList().GetPatterns()
+	require.Len(t, patterns, 1)
+	excluded := regexp.MustCompile(patterns[0].GetSafeRegex().GetRegex())
+	assert.True(t, excluded.MatchString("cluster.scraper_default.upstream_rq_time"))
+	assert.True(t, excluded.MatchString("cluster.scraper_default.internal.upstream_rq_time"))
+	assert.True(t, excluded.MatchString("cluster.scraper_default.upstream_cx_connect_ms"))
+	assert.False(t, excluded.MatchString("cluster.scraper_default.upstream_rq_total"))
+	assert.False(t, excluded.MatchString("cluster.catalog_default.upstream_rq_time"))
+
+	// Buckets out of order don't make a histogram, so that Mapping is rejected.
+	assert.Nil(t, FindCluster(config, ClusterNameContains("cluster_backwards_default_default")))
+}
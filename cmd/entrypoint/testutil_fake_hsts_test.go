@@ -0,0 +1,190 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	http "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chainVirtualHost returns the virtual host for domain in the listener's TLS filter chains, or
+// in its cleartext ones if tls is false.
+func chainVirtualHost(listener *v3listener.Listener, tls bool, domain string) *route.VirtualHost {
+	for _, fc := range listener.FilterChains {
+		if (fc.TransportSocket != nil) != tls {
+			continue
+		}
+		hcm := entrypoint.ChainHCM(fc)
+		if hcm == nil {
+			continue
+		}
+		if rs, ok := hcm.RouteSpecifier.(*http.HttpConnectionManager_RouteConfig); ok {
+			for _, vh := range rs.RouteConfig.VirtualHosts {
+				for _, d := range vh.Domains {
+					if d == domain {
+						return vh
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func TestHSTS(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: example-cert
+  namespace: default
+type: kubernetes.io/tls
+data:
+  tls.crt: bm90LWEtcmVhbC1yc2EtY2VydA==
+  tls.key: ` + rsaKeyPEM + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    hsts:
+      maxAge: 600
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: ambassador-listener-8443
+  namespace: default
+spec:
+  port: 8443
+  protocol: HTTPS
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+` + entrypoint.FakeListenerYAML + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: secure
+  namespace: default
+spec:
+  hostname: secure.example.com
+  tlsSecret:
+    name: example-cert
+  requestPolicy:
+    insecure:
+      action: Route
+  hsts:
+    maxAge: 63072000
+    includeSubDomains: true
+    preload: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: defaulted
+  namespace: default
+spec:
+  hostname: defaulted.example.com
+  tlsSecret:
+    name: example-cert
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: unlisted
+  namespace: default
+spec:
+  hostname: unlisted.example.com
+  tlsSecret:
+    name: example-cert
+  hsts:
+    preload: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: app
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /app/
+  service: app.default
+  add_response_headers:
+    strict-transport-security: max-age=1
+`)
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "app"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	var tlsListener, cleartextListener *v3listener.Listener
+	_, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		tlsListener = findListener(config, func(l *v3listener.Listener) bool {
+			return l.Name == "ambassador-listener-8443"
+		})
+		cleartextListener = findListener(config, func(l *v3listener.Listener) bool {
+			return l.Name == "ambassador-listener-8080"
+		})
+		return tlsListener != nil && cleartextListener != nil &&
+			chainVirtualHost(tlsListener, true, "unlisted.example.com") != nil
+	})
+	require.NoError(t, err)
+
+	hsts := func(vh *route.VirtualHost) string {
+		for _, h := range vh.GetResponseHeadersToAdd() {
+			if h.GetHeader().GetKey() == "strict-transport-security" {
+				// It has to replace whatever the service sent.
+				assert.False(t, h.GetAppend().GetValue())
+				return h.GetHeader().GetValue()
+			}
+		}
+		return ""
+	}
+
+	// Over TLS, the Host's own hsts wins over the Module's, and the vhost's header goes on
+	// after the Mapping's, so it replaces that too.
+	vh := chainVirtualHost(tlsListener, true, "secure.example.com")
+	require.NotNil(t, vh)
+	assert.Equal(t, "max-age=63072000; includeSubDomains; preload", hsts(vh))
+	r := findRoute(tlsListener, func(r *route.Route) bool {
+		return r.GetMatch().GetPrefix() == "/app/"
+	})
+	require.NotNil(t, r)
+	assert.Equal(t, "max-age=1", headerValue(r.ResponseHeadersToAdd, "strict-transport-security"))
+
+	vh = chainVirtualHost(tlsListener, true, "defaulted.example.com")
+	require.NotNil(t, vh)
+	assert.Equal(t, "max-age=600", hsts(vh))
+
+	vh = chainVirtualHost(tlsListener, true, "unlisted.example.com")
+	require.NotNil(t, vh)
+	assert.Equal(t, "max-age=31536000; preload", hsts(vh))
+
+	// Cleartext never gets it, even for a Host that routes cleartext.
+	vh = chainVirtualHost(cleartextListener, false, "secure.example.com")
+	require.NotNil(t, vh)
+	assert.Empty(t, hsts(vh))
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return len(diag.NoticesFor("unlisted.default")) > 0
+	})
+	require.NoError(t, err)
+	assert.True(t, hasNotice(diag, "unlisted.default", "hsts preload needs includeSubDomains"))
+	assert.False(t, hasNotice(diag, "secure.default", "hsts"))
+}
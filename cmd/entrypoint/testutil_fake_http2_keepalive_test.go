@@ -0,0 +1,110 @@
+package entrypoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTP2KeepAlive(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    http2_keepalive:
+      interval_ms: 30000
+      timeout_ms: 5000
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: grpc-default
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /grpc.Default/
+  rewrite: /grpc.Default/
+  service: grpc-default.default
+  grpc: true
+  keepalive:
+    idle_time: 60
+    interval: 10
+    probes: 3
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: grpc-override
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /grpc.Override/
+  rewrite: /grpc.Override/
+  service: grpc-override.default
+  grpc: true
+  http2_keepalive:
+    interval_ms: 500
+    timeout_ms: 250
+    interval_jitter_percent: 0
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: http-plain
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /http/
+  service: http-plain.default
+  http2_keepalive:
+    interval_ms: 30000
+    timeout_ms: 5000
+`)
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "http-plain"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_http_plain_default")) != nil
+	})
+	require.NoError(t, err)
+
+	// The Module's keepalive applies to gRPC clusters, alongside TCP keepalive.
+	cluster := FindCluster(config, ClusterNameContains("cluster_grpc_default_default"))
+	require.NotNil(t, cluster)
+	keepalive := cluster.GetHttp2ProtocolOptions().GetConnectionKeepalive()
+	require.NotNil(t, keepalive)
+	assert.Equal(t, 30*time.Second, keepalive.Interval.AsDuration())
+	assert.Equal(t, 5*time.Second, keepalive.Timeout.AsDuration())
+	assert.Nil(t, keepalive.IntervalJitter)
+	assert.NotNil(t, cluster.GetUpstreamConnectionOptions().GetTcpKeepalive())
+
+	// A Mapping's keepalive wins, very short interval and all (that just gets a notice).
+	cluster = FindCluster(config, ClusterNameContains("cluster_grpc_override_default"))
+	require.NotNil(t, cluster)
+	keepalive = cluster.GetHttp2ProtocolOptions().GetConnectionKeepalive()
+	require.NotNil(t, keepalive)
+	assert.Equal(t, 500*time.Millisecond, keepalive.Interval.AsDuration())
+	assert.Equal(t, 250*time.Millisecond, keepalive.Timeout.AsDuration())
+	require.NotNil(t, keepalive.IntervalJitter)
+	assert.Equal(t, float64(0), keepalive.IntervalJitter.Value)
+
+	// HTTP/1.1 clusters don't PING at all.
+	cluster = FindCluster(config, ClusterNameContains("cluster_http_plain_default"))
+	require.NotNil(t, cluster)
+	assert.Nil(t, cluster.Http2ProtocolOptions)
+}
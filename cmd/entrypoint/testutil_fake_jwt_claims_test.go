@@ -0,0 +1,205 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	rbacconfig "github.com/datawire/ambassador/v2/pkg/api/envoy/config/rbac/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	jwtauthn "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/jwt_authn/v3"
+	rbac "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/rbac/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// claimPrincipal checks that principal allows a JWT from provider whose claim is value, or
+// is an array with value in it.
+func claimPrincipal(t *testing.T, principal *rbacconfig.Principal, provider, claim, value string) {
+	t.Helper()
+	ids := principal.GetOrIds().GetIds()
+	require.Len(t, ids, 2)
+	for _, id := range ids {
+		matcher := id.GetMetadata()
+		require.NotNil(t, matcher)
+		assert.Equal(t, "envoy.filters.http.jwt_authn", matcher.GetFilter())
+		require.Len(t, matcher.GetPath(), 2)
+		assert.Equal(t, provider, matcher.GetPath()[0].GetKey())
+		assert.Equal(t, claim, matcher.GetPath()[1].GetKey())
+	}
+	assert.Equal(t, value, ids[0].GetMetadata().GetValue().GetStringMatch().GetExact())
+	assert.Equal(t, value, ids[1].GetMetadata().GetValue().GetListMatch().GetOneOf().GetStringMatch().GetExact())
+}
+
+func TestJWTClaims(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    jwt_providers:
+      partners:
+        issuer: https://auth.example.com/
+        audiences: [ "api.example.com" ]
+        jwks_uri: https://auth.example.com/.well-known/jwks.json
+      internal:
+        jwks: '{"keys": []}'
+`+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: orders
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /orders/
+  service: orders.default
+  jwt:
+    provider: partners
+    claims:
+    - name: scope
+      values: [ "orders:read" ]
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: admin
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /admin/
+  service: admin.default
+  jwt:
+    provider: partners
+    claims:
+    - name: role
+      values: [ "admin" ]
+    - name: tenant
+      values: [ "acme" ]
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: status
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /status/
+  service: status.default
+  jwt:
+    provider: internal
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: unnamed
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /unnamed/
+  service: unnamed.default
+  jwt:
+    claims:
+    - name: role
+      values: [ "admin" ]
+`+entrypoint.FakeMappingYAML("plain"), "plain", "cluster_plain_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	hcm := entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+
+	// JWTs get checked right after CORS, and their claims right after that.
+	filterIdx := map[string]int{}
+	for i, filter := range hcm.HttpFilters {
+		filterIdx[filter.Name] = i
+	}
+	require.Contains(t, filterIdx, "envoy.filters.http.jwt_authn")
+	require.Contains(t, filterIdx, "ambassador.jwt_claims")
+	assert.Equal(t, filterIdx["envoy.filters.http.cors"]+1, filterIdx["envoy.filters.http.jwt_authn"])
+	assert.Equal(t, filterIdx["envoy.filters.http.jwt_authn"]+1, filterIdx["ambassador.jwt_claims"])
+
+	// Each provider's payload ends up in metadata under its own name. partners' keys come
+	// from a cluster of their own; internal's are right there.
+	authn := &jwtauthn.JwtAuthentication{}
+	require.NoError(t, ptypes.UnmarshalAny(hcm.HttpFilters[filterIdx["envoy.filters.http.jwt_authn"]].GetTypedConfig(), authn))
+	require.Contains(t, authn.Providers, "partners")
+	partners := authn.Providers["partners"]
+	assert.Equal(t, "partners", partners.GetPayloadInMetadata())
+	assert.Equal(t, "https://auth.example.com/", partners.GetIssuer())
+	assert.Equal(t, []string{"api.example.com"}, partners.GetAudiences())
+	assert.Equal(t, "https://auth.example.com/.well-known/jwks.json", partners.GetRemoteJwks().GetHttpUri().GetUri())
+	jwksCluster := FindCluster(config, ClusterNameContains("auth_example_com"))
+	require.NotNil(t, jwksCluster)
+	assert.Equal(t, jwksCluster.Name, partners.GetRemoteJwks().GetHttpUri().GetCluster())
+	require.Contains(t, authn.Providers, "internal")
+	assert.Equal(t, `{"keys": []}`, authn.Providers["internal"].GetLocalJwks().GetInlineString())
+	assert.Equal(t, "internal", authn.RequirementMap["internal"].GetProviderName())
+
+	perRoute := func(cluster string) (*jwtauthn.PerRouteConfig, *rbac.RBACPerRoute) {
+		r := findRoute(listener, func(r *route.Route) bool {
+			return r.GetRoute().GetCluster() == cluster
+		})
+		require.NotNil(t, r)
+
+		var requirement *jwtauthn.PerRouteConfig
+		if cfg, ok := r.TypedPerFilterConfig["envoy.filters.http.jwt_authn"]; ok {
+			requirement = &jwtauthn.PerRouteConfig{}
+			require.NoError(t, ptypes.UnmarshalAny(cfg, requirement))
+		}
+
+		var claims *rbac.RBACPerRoute
+		if cfg, ok := r.TypedPerFilterConfig["ambassador.jwt_claims"]; ok {
+			claims = &rbac.RBACPerRoute{}
+			require.NoError(t, ptypes.UnmarshalAny(cfg, claims))
+		}
+		return requirement, claims
+	}
+
+	// orders needs a partners JWT with orders:read in its scope...
+	requirement, claims := perRoute("cluster_orders_default_default")
+	require.NotNil(t, requirement)
+	assert.Equal(t, "partners", requirement.GetRequirementName())
+	require.NotNil(t, claims)
+	policy := claims.GetRbac().GetRules().GetPolicies()["ambassador-jwt-claims"]
+	require.NotNil(t, policy)
+	assert.Equal(t, rbacconfig.RBAC_ALLOW, claims.GetRbac().GetRules().GetAction())
+	require.Len(t, policy.GetPrincipals(), 1)
+	claimPrincipal(t, policy.GetPrincipals()[0], "partners", "scope", "orders:read")
+
+	// ...admin needs both of its claims...
+	requirement, claims = perRoute("cluster_admin_default_default")
+	require.NotNil(t, requirement)
+	require.NotNil(t, claims)
+	policy = claims.GetRbac().GetRules().GetPolicies()["ambassador-jwt-claims"]
+	require.Len(t, policy.GetPrincipals(), 1)
+	both := policy.GetPrincipals()[0].GetAndIds().GetIds()
+	require.Len(t, both, 2)
+	claimPrincipal(t, both[0], "partners", "role", "admin")
+	claimPrincipal(t, both[1], "partners", "tenant", "acme")
+
+	// ...status needs any valid JWT from internal, and nothing more...
+	requirement, claims = perRoute("cluster_status_default_default")
+	require.NotNil(t, requirement)
+	assert.Equal(t, "internal", requirement.GetRequirementName())
+	assert.Nil(t, claims)
+
+	// ...and plain needs nothing at all.
+	requirement, claims = perRoute("cluster_plain_default_default")
+	assert.Nil(t, requirement)
+	assert.Nil(t, claims)
+
+	// With two providers, a Mapping has to say which one it means.
+	assert.Nil(t, FindCluster(config, ClusterNameContains("cluster_unnamed_default_default")))
+}
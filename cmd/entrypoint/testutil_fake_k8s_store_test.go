@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"reflect"
 	"sort"
 	"strings"
 	"sync"
@@ -104,6 +105,56 @@ func (k *K8sStore) Delete(kind, namespace, name string) error {
 	return nil
 }
 
+// Resources returns a copy of every resource in the store.
+func (k *K8sStore) Resources() map[K8sKey]kates.Object {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	result := map[K8sKey]kates.Object{}
+	for key, obj := range k.resources {
+		result[key] = obj.DeepCopyObject().(kates.Object)
+	}
+	return result
+}
+
+// Restore makes the store hold exactly the supplied resources (as returned by Resources), with the
+// deltas that kubernetes would have produced getting there: resources that aren't in the supplied
+// set are deleted, new ones are added, and ones that differ are updated.
+func (k *K8sStore) Restore(resources map[K8sKey]kates.Object) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	for _, key := range sortedKeys(k.resources) {
+		if _, ok := resources[key]; ok {
+			continue
+		}
+		delta, err := kates.NewDeltaFromObject(kates.ObjectDelete, k.resources[key])
+		if err != nil {
+			return err
+		}
+		k.deltas = append(k.deltas, delta)
+		delete(k.resources, key)
+	}
+
+	for _, key := range sortedKeys(resources) {
+		obj := resources[key].DeepCopyObject().(kates.Object)
+		deltaType := kates.ObjectAdd
+		if old, ok := k.resources[key]; ok {
+			if reflect.DeepEqual(old, obj) {
+				continue
+			}
+			deltaType = kates.ObjectUpdate
+		}
+		delta, err := kates.NewDeltaFromObject(deltaType, obj)
+		if err != nil {
+			return err
+		}
+		k.deltas = append(k.deltas, delta)
+		k.resources[key] = obj
+	}
+	return nil
+}
+
 // UpsertFile will parse the yaml manifests in the referenced file and Upsert each resource from the
 // file.
 func (k *K8sStore) UpsertFile(filename string) error {
@@ -131,6 +182,23 @@ func (k *K8sStore) UpsertYAML(yaml string) error {
 	return nil
 }
 
+// UpsertUnstructuredYAML is UpsertYAML, but keeps the resources just as they were written, so
+// fields our types don't know about get through, as they would from a cluster whose CRDs are
+// newer than ours.
+func (k *K8sStore) UpsertUnstructuredYAML(yaml string) error {
+	objs, err := kates.ParseManifestsToUnstructured(yaml)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		if err := k.Upsert(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // A Cursor allows multiple views of the same stream of deltas. The cursors implement a bootstrap
 // semantic where they will generate synthetic Add deltas for every resource that currently exists,
 // and from that point on report the real deltas that actually occur on the store.
@@ -211,6 +279,8 @@ func canonGVK(rawString string) (canonKind string, canonGroupVersion string, err
 		return "Service", "v1", nil
 	case "endpoints":
 		return "Endpoints", "v1", nil
+	case "endpointslice", "endpointslices":
+		return "EndpointSlice", "discovery.k8s.io/v1", nil
 	case "secret", "secrets":
 		return "Secret", "v1", nil
 	case "ingress", "ingresses":
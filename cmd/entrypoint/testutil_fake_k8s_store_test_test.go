@@ -131,3 +131,63 @@ func TestNamespaceDefault(t *testing.T) {
 		assert.Equal(t, "default", r.GetNamespace())
 	}
 }
+
+func TestStoreRestore(t *testing.T) {
+	store := entrypoint.NewK8sStore()
+	assert.NoError(t, store.UpsertFile("testdata/TestStore.yaml"))
+	saved := store.Resources()
+
+	c := store.Cursor()
+	_, _, err := c.Get()
+	require.NoError(t, err)
+
+	// Change foo, delete bar, and add baz...
+	assert.NoError(t, store.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: foo
+  namespace: default
+spec:
+  prefix: /foo-changed
+  service: foo
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: baz
+  namespace: default
+spec:
+  prefix: /baz
+  service: baz
+`))
+	assert.NoError(t, store.Delete("Mapping", "default", "bar"))
+	_, _, err = c.Get()
+	require.NoError(t, err)
+
+	// ...and the copy we took doesn't notice.
+	assert.Len(t, saved, 2)
+
+	// Restoring undoes each change with the delta it takes.
+	require.NoError(t, store.Restore(saved))
+	resources, deltas, err := c.Get()
+	require.NoError(t, err)
+	assert.Len(t, resources, 2)
+	assert.Contains(t, resources, entrypoint.K8sKey{"Mapping", "default", "bar"})
+	assert.NotContains(t, resources, entrypoint.K8sKey{"Mapping", "default", "baz"})
+
+	require.Len(t, deltas, 3)
+	assert.Equal(t, kates.ObjectDelete, deltas[0].DeltaType)
+	assert.Equal(t, "baz", deltas[0].Name)
+	assert.Equal(t, kates.ObjectAdd, deltas[1].DeltaType)
+	assert.Equal(t, "bar", deltas[1].Name)
+	assert.Equal(t, kates.ObjectUpdate, deltas[2].DeltaType)
+	assert.Equal(t, "foo", deltas[2].Name)
+
+	// Restoring again changes nothing.
+	require.NoError(t, store.Restore(saved))
+	_, deltas, err = c.Get()
+	require.NoError(t, err)
+	assert.Empty(t, deltas)
+}
@@ -0,0 +1,96 @@
+package entrypoint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	amb "github.com/datawire/ambassador/v2/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/v2/pkg/snapshot/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// annotationMapping returns the Mapping with the given name that came from an annotation, or nil.
+func annotationMapping(snap *snapshot.Snapshot, namespace, name string) *amb.Mapping {
+	for _, a := range snap.Kubernetes.Annotations {
+		if m, ok := a.(*amb.Mapping); ok && m.GetNamespace() == namespace && m.GetName() == name {
+			return m
+		}
+	}
+	return nil
+}
+
+func TestLegacyAnnotations(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true, LegacyAnnotations: true}, nil)
+
+	// One annotation, several resources, in the oldest style there is.
+	legacy := makeService("default", "legacy")
+	legacy.ObjectMeta.Annotations = map[string]string{
+		"getambassador.io/config": `
+---
+apiVersion: ambassador/v1
+kind: Mapping
+name: legacy-mapping
+prefix: /legacy/
+service: legacy.default
+---
+apiVersion: ambassador/v1
+kind: Mapping
+name: legacy-admin-mapping
+prefix: /legacy-admin/
+service: legacy.default:8080
+---
+apiVersion: ambassador/v1
+kind: Module
+name: ambassador
+config:
+  diagnostics:
+    enabled: true
+`,
+	}
+	require.NoError(t, f.Upsert(legacy))
+
+	broken := makeService("default", "broken")
+	broken.ObjectMeta.Annotations = map[string]string{
+		"getambassador.io/config": "apiVersion: ambassador/v1\nkind: Mapping\n  name: [broken\n",
+	}
+	require.NoError(t, f.Upsert(broken))
+	f.Flush()
+
+	snap, err := f.GetSnapshot(func(snap *snapshot.Snapshot) bool {
+		return annotationMapping(snap, "default", "legacy-admin-mapping") != nil
+	})
+	require.NoError(t, err)
+
+	// Both Mappings come through, in the namespace of the Service they were on, and so does
+	// the Module. The broken annotation doesn't take the good one down with it.
+	mapping := annotationMapping(snap, "default", "legacy-mapping")
+	require.NotNil(t, mapping)
+	assert.Equal(t, "/legacy/", mapping.Spec.Prefix)
+	assert.Equal(t, "legacy.default", mapping.Spec.Service)
+	assert.Equal(t, "legacy.default:8080", annotationMapping(snap, "default", "legacy-admin-mapping").Spec.Service)
+
+	var module *amb.Module
+	for _, a := range snap.Kubernetes.Annotations {
+		if m, ok := a.(*amb.Module); ok {
+			module = m
+		}
+	}
+	require.NotNil(t, module)
+	assert.Equal(t, "ambassador", module.GetName())
+
+	// diagd turns them into clusters like any other Mapping...
+	_, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_legacy_default_8080_default")) != nil
+	})
+	require.NoError(t, err)
+
+	// ...and says what's wrong with the broken one.
+	_, err = f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return strings.Contains(diag.Error("broken.default"), "could not parse getambassador.io/config annotation")
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,75 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	http "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerCodecType(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: listener-h2c
+  namespace: default
+spec:
+  port: 8080
+  protocol: HTTP
+  securityModel: XFP
+  codecType: HTTP2
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: listener-auto
+  namespace: default
+spec:
+  port: 8081
+  protocol: HTTP
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: grpc-foo
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /grpc.Foo/
+  rewrite: /grpc.Foo/
+  service: grpc-foo.default
+  grpc: true
+`, "grpc-foo", "cluster_grpc_foo_default_default")
+
+	// The h2c listener only speaks HTTP/2, so HTTP/1.1 clients are refused...
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "listener-h2c"
+	})
+	require.NotNil(t, listener)
+	hcm := entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+	assert.Equal(t, http.HttpConnectionManager_HTTP2, hcm.CodecType)
+
+	// ...while the default stays AUTO.
+	listener = findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "listener-auto"
+	})
+	require.NotNil(t, listener)
+	hcm = entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+	assert.Equal(t, http.HttpConnectionManager_AUTO, hcm.CodecType)
+}
@@ -0,0 +1,145 @@
+package entrypoint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listenerError returns true if the diagnostics have an error for the named Listener that
+// contains want.
+func listenerError(diag *entrypoint.Diagnostics, name, want string) bool {
+	for _, e := range diag.Errors {
+		if len(e) == 2 && strings.HasPrefix(e[0], name+".default") && strings.Contains(e[1], want) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestListenerConflicts(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: cleartext
+  namespace: default
+spec:
+  port: 8080
+  protocol: HTTP
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: redirected
+  namespace: default
+spec:
+  port: 8080
+  destinationPort: 9090
+  protocol: HTTP
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: admin
+  namespace: default
+spec:
+  port: 8001
+  protocol: HTTP
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: foo
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /foo/
+  service: foo.default
+`)
+	require.NoError(t, err)
+	f.Flush()
+
+	snap, err := f.GetSnapshot(HasMapping("default", "foo"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	// Envoy's admin interface already has 8001, so that Listener can't have it.
+	_, err = f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return listenerError(diag, "admin", "Envoy admin port")
+	})
+	require.NoError(t, err)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return findListener(config, func(l *v3listener.Listener) bool {
+			return l.GetAddress().GetSocketAddress().GetPortValue() == 8080
+		}) != nil
+	})
+	require.NoError(t, err)
+	assert.Nil(t, findListener(config, func(l *v3listener.Listener) bool {
+		return l.GetAddress().GetSocketAddress().GetPortValue() == 8001
+	}))
+
+	// Sharing a port by destination port is fine: both Listeners end up in one Envoy
+	// listener, and neither is an error.
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool { return true })
+	require.NoError(t, err)
+	assert.False(t, listenerError(diag, "cleartext", "Duplicate listener"))
+	assert.False(t, listenerError(diag, "redirected", "Duplicate listener"))
+
+	// A second Listener for all of 8080 is a duplicate, though. Whichever of the two is seen
+	// second is rejected, and there's still only one Envoy listener on 8080.
+	err = f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: encrypted
+  namespace: default
+spec:
+  port: 8080
+  protocol: HTTPS
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+`)
+	require.NoError(t, err)
+	f.Flush()
+
+	_, err = f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return listenerError(diag, "encrypted", "Duplicate listener") ||
+			listenerError(diag, "cleartext", "Duplicate listener")
+	})
+	require.NoError(t, err)
+
+	config, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool { return true })
+	require.NoError(t, err)
+	count := 0
+	for _, l := range config.StaticResources.Listeners {
+		if l.GetAddress().GetSocketAddress().GetPortValue() == 8080 {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+}
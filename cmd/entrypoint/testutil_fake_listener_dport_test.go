@@ -0,0 +1,208 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	http "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chainDomains returns all the virtual host domains served by a filter chain.
+func chainDomains(fc *v3listener.FilterChain) []string {
+	domains := []string{}
+	if hcm := entrypoint.ChainHCM(fc); hcm != nil {
+		if rs, ok := hcm.RouteSpecifier.(*http.HttpConnectionManager_RouteConfig); ok {
+			for _, vh := range rs.RouteConfig.VirtualHosts {
+				domains = append(domains, vh.Domains...)
+			}
+		}
+	}
+	return domains
+}
+
+func TestListenerDestinationPort(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: listener-9090
+  namespace: default
+spec:
+  port: 8080
+  destinationPort: 9090
+  protocol: HTTP
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+    selector:
+      matchLabels:
+        listener: nine
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: listener-default
+  namespace: default
+spec:
+  port: 8080
+  protocol: HTTP
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+    selector:
+      matchLabels:
+        listener: default
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: host-a
+  namespace: default
+  labels:
+    listener: nine
+spec:
+  hostname: a.example.com
+  requestPolicy:
+    insecure:
+      action: Route
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: host-b
+  namespace: default
+  labels:
+    listener: default
+spec:
+  hostname: b.example.com
+  requestPolicy:
+    insecure:
+      action: Route
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: a
+  namespace: default
+spec:
+  hostname: a.example.com
+  prefix: /a/
+  service: a.default
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: b
+  namespace: default
+spec:
+  hostname: b.example.com
+  prefix: /b/
+  service: b.default
+`, "b", "cluster_b_default_default")
+
+	// Both Listeners end up in a single Envoy listener on the shared port.
+	var onPort []*v3listener.Listener
+	for _, l := range config.StaticResources.Listeners {
+		if l.Address.GetSocketAddress().GetPortValue() == 8080 {
+			onPort = append(onPort, l)
+		}
+	}
+	require.Len(t, onPort, 1)
+	listener := onPort[0]
+
+	filterNames := []string{}
+	for _, lf := range listener.ListenerFilters {
+		filterNames = append(filterNames, lf.Name)
+	}
+	assert.Contains(t, filterNames, "envoy.filters.listener.original_dst")
+
+	// Connections originally for port 9090 go to a.example.com; everything else falls through
+	// to the chain with no destination port, which serves b.example.com.
+	var portChain, defaultChain *v3listener.FilterChain
+	for _, fc := range listener.FilterChains {
+		if fc.FilterChainMatch.GetDestinationPort() == nil {
+			defaultChain = fc
+		} else if fc.FilterChainMatch.GetDestinationPort().GetValue() == 9090 {
+			portChain = fc
+		}
+	}
+	require.NotNil(t, portChain)
+	require.NotNil(t, defaultChain)
+
+	assert.Contains(t, chainDomains(portChain), "a.example.com")
+	assert.NotContains(t, chainDomains(portChain), "b.example.com")
+	assert.Contains(t, chainDomains(defaultChain), "b.example.com")
+	assert.NotContains(t, chainDomains(defaultChain), "a.example.com")
+}
+
+func TestListenerDestinationPortConflict(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: listener-9090
+  namespace: default
+spec:
+  port: 8080
+  destinationPort: 9090
+  protocol: HTTP
+  securityModel: XFP
+  keepAlive:
+    idle_time: 30
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: listener-default
+  namespace: default
+spec:
+  port: 8080
+  protocol: HTTP
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+`+entrypoint.FakeMappingYAML("b"), "b", "cluster_b_default_default")
+
+	// Socket options apply to the whole Envoy listener, so the two can't share it: whichever
+	// came second is left out, with an error saying why, instead of quietly taking the first
+	// one's keepalive.
+	var onPort []*v3listener.Listener
+	for _, l := range config.StaticResources.Listeners {
+		if l.Address.GetSocketAddress().GetPortValue() == 8080 {
+			onPort = append(onPort, l)
+		}
+	}
+	require.Len(t, onPort, 1)
+
+	var kept, dropped string
+	for _, fc := range onPort[0].FilterChains {
+		if fc.FilterChainMatch.GetDestinationPort() == nil {
+			kept, dropped = "listener-default", "listener-9090"
+		} else {
+			kept, dropped = "listener-9090", "listener-default"
+		}
+	}
+	require.NotEmpty(t, kept)
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Error(dropped+".default") != ""
+	})
+	require.NoError(t, err)
+	assert.Contains(t, diag.Error(dropped+".default"),
+		"shares port 8080 with "+kept+", but needs different keepAlive settings; ignoring it")
+	assert.Empty(t, diag.Error(kept+".default"))
+}
@@ -0,0 +1,116 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3core "github.com/datawire/ambassador/v2/pkg/api/envoy/config/core/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// findSocketOption returns the listener's socket option with the given level and name, if any.
+func findSocketOption(listener *v3listener.Listener, level, name int64) *v3core.SocketOption {
+	for _, opt := range listener.SocketOptions {
+		if opt.GetLevel() == level && opt.GetName() == name {
+			return opt
+		}
+	}
+	return nil
+}
+
+func TestListenerDownstreamKeepAlive(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	require.NoError(t, f.Upsert(makeService("default", "foo")))
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    downstream_keepalive:
+      idle_time: 600
+      probes: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: ambassador-listener-8080
+  namespace: default
+spec:
+  port: 8080
+  protocol: HTTP
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+  keepAlive:
+    idle_time: 30
+    interval: 10
+    probes: 3
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: ambassador-listener-8443
+  namespace: default
+spec:
+  port: 8443
+  protocol: HTTPS
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: foo
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /foo
+  service: foo.default
+`, "foo", "cluster_foo_default_default")
+
+	// The 8080 Listener sets its own keepalive, which wins over the Module.
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	opt := findSocketOption(listener, 1, 9) // SOL_SOCKET, SO_KEEPALIVE
+	require.NotNil(t, opt)
+	assert.Equal(t, int64(1), opt.GetIntValue())
+
+	opt = findSocketOption(listener, 6, 4) // IPPROTO_TCP, TCP_KEEPIDLE
+	require.NotNil(t, opt)
+	assert.Equal(t, int64(30), opt.GetIntValue())
+
+	opt = findSocketOption(listener, 6, 5) // IPPROTO_TCP, TCP_KEEPINTVL
+	require.NotNil(t, opt)
+	assert.Equal(t, int64(10), opt.GetIntValue())
+
+	opt = findSocketOption(listener, 6, 6) // IPPROTO_TCP, TCP_KEEPCNT
+	require.NotNil(t, opt)
+	assert.Equal(t, int64(3), opt.GetIntValue())
+
+	// The 8443 Listener falls back to the Module default, which only sets the idle time: YAML's
+	// true is no count of probes, even though Python would take it for 1.
+	listener = findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8443"
+	})
+	require.NotNil(t, listener)
+
+	assert.NotNil(t, findSocketOption(listener, 1, 9))
+	opt = findSocketOption(listener, 6, 4)
+	require.NotNil(t, opt)
+	assert.Equal(t, int64(600), opt.GetIntValue())
+	assert.Nil(t, findSocketOption(listener, 6, 5))
+	assert.Nil(t, findSocketOption(listener, 6, 6))
+}
@@ -0,0 +1,48 @@
+package entrypoint_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	"github.com/datawire/ambassador/v2/pkg/snapshot/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeLoadSnapshotJSON(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, &snapshot.AmbassadorMetaInfo{
+		AmbassadorVersion: "2.1.0",
+	})
+
+	// The captured snapshot comes from an older version, has a Mapping with an older apiVersion,
+	// and has resources the Fake can't store (Pods) or has never heard of (FutureResources). None
+	// of that should stop us from generating config from the rest of it.
+	data, err := ioutil.ReadFile("testdata/captured-snapshot.json")
+	require.NoError(t, err)
+	require.NoError(t, f.LoadSnapshotJSON(data))
+
+	snap, err := f.GetSnapshot(HasMapping("default", "quote"))
+	require.NoError(t, err)
+	require.Len(t, snap.Kubernetes.Services, 1)
+	assert.Empty(t, snap.Kubernetes.Pods)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_quote_default")) != nil
+	})
+	require.NoError(t, err)
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	routeAction := findVirtualHostRoute(listener, func(r *route.RouteAction) bool {
+		return strings.HasPrefix(r.GetCluster(), "cluster_quote_default")
+	})
+	assert.NotNil(t, routeAction)
+}
@@ -0,0 +1,185 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	accesslog "github.com/datawire/ambassador/v2/pkg/api/envoy/config/accesslog/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	lua "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/lua/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logRequest is as much of a finished request as the log_sampling access log filter looks at.
+// Roll is where the request ID lands in 0-99, which decides whether it's sampled.
+type logRequest struct {
+	Mark    string
+	Status  uint32
+	Flagged bool
+	Roll    uint32
+}
+
+// logged evaluates an access log filter the way Envoy would for req, for the filters that
+// log_sampling uses.
+func logged(t *testing.T, filter *accesslog.AccessLogFilter, req logRequest) bool {
+	t.Helper()
+	switch {
+	case filter.GetOrFilter() != nil:
+		for _, f := range filter.GetOrFilter().Filters {
+			if logged(t, f, req) {
+				return true
+			}
+		}
+		return false
+	case filter.GetAndFilter() != nil:
+		for _, f := range filter.GetAndFilter().Filters {
+			if !logged(t, f, req) {
+				return false
+			}
+		}
+		return true
+	case filter.GetMetadataFilter() != nil:
+		mf := filter.GetMetadataFilter()
+		require.Equal(t, "ambassador.log_sampling", mf.GetMatcher().GetFilter())
+		if req.Mark == "" {
+			return mf.GetMatchIfKeyNotFound().GetValue()
+		}
+		value := mf.GetMatcher().GetValue()
+		if value.GetNullMatch() != nil {
+			return false
+		}
+		return value.GetStringMatch().GetExact() == req.Mark
+	case filter.GetStatusCodeFilter() != nil:
+		comparison := filter.GetStatusCodeFilter().GetComparison()
+		require.Equal(t, accesslog.ComparisonFilter_GE, comparison.GetOp())
+		return req.Status >= comparison.GetValue().GetDefaultValue()
+	case filter.GetResponseFlagFilter() != nil:
+		require.Empty(t, filter.GetResponseFlagFilter().GetFlags())
+		return req.Flagged
+	case filter.GetRuntimeFilter() != nil:
+		return req.Roll < filter.GetRuntimeFilter().GetPercentSampled().GetNumerator()
+	}
+	t.Fatalf("unexpected access log filter %v", filter)
+	return false
+}
+
+func TestLogSampling(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: LogService
+metadata:
+  name: als
+  namespace: default
+spec:
+  service: als.default:9001
+  driver: http
+  driver_config: {}
+  grpc: true
+`+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: catalog
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /catalog/
+  service: catalog.default
+  log_sampling:
+    percent: 5
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: events
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /events/
+  service: events.default
+  log_sampling:
+    percent: 0
+    always_log_status: 500
+`+entrypoint.FakeMappingYAML("plain")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: broken
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /broken/
+  service: broken.default
+  log_sampling:
+    percent: 150
+`, "broken", "cluster_plain_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	hcm := entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+
+	// The mark goes on first, ahead of anything that might answer a request itself...
+	require.NotEmpty(t, hcm.HttpFilters)
+	assert.Equal(t, "ambassador.log_sampling", hcm.HttpFilters[0].Name)
+
+	mark := func(prefix string) string {
+		routes := prefixRoutes(listener, prefix)
+		require.Len(t, routes, 1, prefix)
+		cfg, ok := routes[0].TypedPerFilterConfig["ambassador.log_sampling"]
+		if !ok {
+			return ""
+		}
+		lpr := &lua.LuaPerRoute{}
+		require.NoError(t, ptypes.UnmarshalAny(cfg, lpr))
+		return lpr.GetSourceCode().GetInlineString()
+	}
+	assert.Contains(t, mark("/catalog/"), `dynamicMetadata():set("ambassador.log_sampling", "mapping", "catalog.default")`)
+	assert.Contains(t, mark("/events/"), `"events.default"`)
+	assert.Empty(t, mark("/plain/"))
+
+	// ...and the file log and the LogService both filter on it the same way.
+	require.Len(t, hcm.AccessLog, 2)
+	names := []string{}
+	for _, al := range hcm.AccessLog {
+		names = append(names, al.Name)
+		require.NotNil(t, al.Filter, al.Name)
+		assert.Equal(t, hcm.AccessLog[0].Filter.String(), al.Filter.String())
+	}
+	assert.ElementsMatch(t, []string{"envoy.access_loggers.http_grpc", "envoy.access_loggers.file"}, names)
+	filter := hcm.AccessLog[0].Filter
+
+	for _, tc := range []struct {
+		name   string
+		req    logRequest
+		logged bool
+	}{
+		{"unsampled Mapping", logRequest{Status: 200, Roll: 99}, true},
+		{"sampled success", logRequest{Mark: "catalog.default", Status: 200, Roll: 4}, true},
+		{"unsampled success", logRequest{Mark: "catalog.default", Status: 200, Roll: 5}, false},
+		{"redirect", logRequest{Mark: "catalog.default", Status: 302, Roll: 50}, true},
+		{"server error", logRequest{Mark: "catalog.default", Status: 503, Roll: 50}, true},
+		{"cut-off stream", logRequest{Mark: "catalog.default", Status: 200, Flagged: true, Roll: 50}, true},
+		{"client error below always_log_status", logRequest{Mark: "events.default", Status: 404, Roll: 0}, false},
+		{"server error at always_log_status", logRequest{Mark: "events.default", Status: 500, Roll: 99}, true},
+		{"reset stream with nothing sampled", logRequest{Mark: "events.default", Status: 200, Flagged: true, Roll: 0}, true},
+	} {
+		assert.Equal(t, tc.logged, logged(t, filter, tc.req), tc.name)
+	}
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Error("broken.default") != ""
+	})
+	require.NoError(t, err)
+	assert.Contains(t, diag.Error("broken.default"), "Invalid log_sampling: percent 150 must be between 0 and 100")
+}
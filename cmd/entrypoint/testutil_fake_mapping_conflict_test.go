@@ -0,0 +1,88 @@
+package entrypoint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// conflictNotice returns the notice about a Mapping matching the same requests as another, or ""
+// if there isn't one.
+func conflictNotice(diag *entrypoint.Diagnostics, key string) string {
+	for _, notice := range diag.NoticesFor(key) {
+		if strings.Contains(notice, "matches exactly the same requests") {
+			return notice
+		}
+	}
+	return ""
+}
+
+func TestMappingConflicts(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(entrypoint.FakeListenerYAML + entrypoint.FakeMappingYAML("orders") + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: orders-copy
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /orders/
+  service: orders-v2.default
+` + entrypoint.FakeMappingYAML("search") + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: search-canary
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /search/
+  service: search-canary.default
+  weight: 10
+` + entrypoint.FakeMappingYAML("billing") + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: billing-beta
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /billing/
+  service: billing-beta.default
+  headers:
+    x-beta: "true"
+`)
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "billing-beta"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	// orders-copy matches everything orders does, and neither says how to split the traffic,
+	// so that's almost certainly a mistake: the notice names them both.
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Mapping("billing-beta") != nil && conflictNotice(diag, "orders-copy.default") != ""
+	})
+	require.NoError(t, err)
+	notice := conflictNotice(diag, "orders-copy.default")
+	assert.Contains(t, notice, "orders-copy.default")
+	assert.Contains(t, notice, "Mapping orders.default")
+	assert.Contains(t, notice, "/orders/")
+	assert.Empty(t, conflictNotice(diag, "orders.default"))
+
+	// A canary with a weight overlaps on purpose, and a header match is a different route
+	// entirely.
+	assert.Empty(t, conflictNotice(diag, "search.default"))
+	assert.Empty(t, conflictNotice(diag, "search-canary.default"))
+	assert.Empty(t, conflictNotice(diag, "billing.default"))
+	assert.Empty(t, conflictNotice(diag, "billing-beta.default"))
+}
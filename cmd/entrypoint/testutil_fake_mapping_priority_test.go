@@ -0,0 +1,96 @@
+package entrypoint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3core "github.com/datawire/ambassador/v2/pkg/api/envoy/config/core/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappingPriority(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: critical
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /critical/
+  service: critical.default
+  priority: HIGH
+  circuit_breakers:
+  - max_requests: 100
+  - priority: high
+    max_requests: 1000
+`+entrypoint.FakeMappingYAML("batch")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: twice
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /twice/
+  service: twice.default
+  circuit_breakers:
+  - priority: high
+    max_requests: 10
+  - priority: HIGH
+    max_requests: 20
+`, "critical", "cluster_critical_default_default", "cluster_batch_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	routeFor := func(prefix string) *route.Route {
+		return findRoute(listener, func(r *route.Route) bool {
+			return r.GetMatch().GetPrefix() == prefix
+		})
+	}
+
+	// The high-priority Mapping's requests are routed at high priority, whatever case it's
+	// written in...
+	critical := routeFor("/critical/")
+	require.NotNil(t, critical)
+	assert.Equal(t, v3core.RoutingPriority_HIGH, critical.GetRoute().Priority)
+
+	// ...and count against the high-priority thresholds, which are separate from the default
+	// ones.
+	cluster := FindCluster(config, ClusterNameContains("cluster_critical_default_default"))
+	require.NotNil(t, cluster)
+	thresholds := cluster.GetCircuitBreakers().GetThresholds()
+	require.Len(t, thresholds, 2)
+	assert.Equal(t, v3core.RoutingPriority_DEFAULT, thresholds[0].Priority)
+	assert.Equal(t, uint32(100), thresholds[0].GetMaxRequests().GetValue())
+	assert.Equal(t, v3core.RoutingPriority_HIGH, thresholds[1].Priority)
+	assert.Equal(t, uint32(1000), thresholds[1].GetMaxRequests().GetValue())
+
+	// Without a priority, a Mapping gets the default.
+	batch := routeFor("/batch/")
+	require.NotNil(t, batch)
+	assert.Equal(t, v3core.RoutingPriority_DEFAULT, batch.GetRoute().Priority)
+
+	// Two sets of thresholds for the same priority can't both apply, so that Mapping is
+	// rejected rather than having one of them quietly ignored.
+	assert.Nil(t, routeFor("/twice/"))
+	_, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		for _, e := range diag.Errors {
+			if len(e) == 2 && strings.HasPrefix(e[0], "twice.default") && strings.Contains(e[1], "Invalid circuit_breakers") {
+				return true
+			}
+		}
+		return false
+	})
+	require.NoError(t, err)
+}
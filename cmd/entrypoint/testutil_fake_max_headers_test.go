@@ -0,0 +1,42 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	lua "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/lua/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleMaxHeadersCount(t *testing.T) {
+	_, hcm := entrypoint.RunListenerFake(t, `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    max_request_headers_kb: 32
+    max_headers_count: 50
+    max_header_value_bytes: 4096
+`+entrypoint.FakeListenersYAML+entrypoint.FakeMappingYAML("foo"), "foo")
+
+	// The total header size limit and the header count limit are separate knobs.
+	assert.Equal(t, uint32(32), hcm.GetMaxRequestHeadersKb().GetValue())
+	require.NotNil(t, hcm.CommonHttpProtocolOptions)
+	assert.Equal(t, uint32(50), hcm.CommonHttpProtocolOptions.GetMaxHeadersCount().GetValue())
+
+	// Envoy can't limit a single header value, so that's a Lua filter ahead of everything else.
+	require.NotEmpty(t, hcm.HttpFilters)
+	assert.Equal(t, "ambassador.max_header_value_bytes", hcm.HttpFilters[0].Name)
+	script := &lua.Lua{}
+	require.NoError(t, ptypes.UnmarshalAny(hcm.HttpFilters[0].GetTypedConfig(), script))
+	assert.Contains(t, script.InlineCode, "string.len(value) > 4096")
+	assert.Contains(t, script.InlineCode, `[":status"] = "431"`)
+}
@@ -0,0 +1,68 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxRequestsPerConnection(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: session
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /session/
+  service: session.default
+  max_requests_per_connection: 1
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: batch
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /batch/
+  service: batch.default
+  max_requests_per_connection: 100
+`+entrypoint.FakeMappingYAML("plain")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: none
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /none/
+  service: none.default
+  max_requests_per_connection: 0
+`, "none", "cluster_plain_default_default")
+
+	// session's service keeps state per connection, so every request gets a new one...
+	cluster := FindCluster(config, ClusterNameContains("cluster_session_default_default"))
+	require.NotNil(t, cluster)
+	assert.Equal(t, uint32(1), cluster.GetMaxRequestsPerConnection().GetValue())
+
+	// ...batch just recycles its connections now and then...
+	cluster = FindCluster(config, ClusterNameContains("cluster_batch_default_default"))
+	require.NotNil(t, cluster)
+	assert.Equal(t, uint32(100), cluster.GetMaxRequestsPerConnection().GetValue())
+
+	// ...and plain reuses connections for as long as they last.
+	cluster = FindCluster(config, ClusterNameContains("cluster_plain_default_default"))
+	require.NotNil(t, cluster)
+	assert.Nil(t, cluster.MaxRequestsPerConnection)
+
+	// A connection has to be good for at least one request.
+	assert.Nil(t, FindCluster(config, ClusterNameContains("cluster_none_default_default")))
+}
@@ -0,0 +1,197 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	v3tls "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/datawire/ambassador/v2/pkg/kates"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func istioCertUpdate(cert string) entrypoint.IstioCertUpdate {
+	return entrypoint.IstioCertUpdate{
+		Op:        "update",
+		Name:      "istio-certs",
+		Namespace: "default",
+		Secret: &kates.Secret{
+			TypeMeta: kates.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "Secret",
+			},
+			ObjectMeta: kates.ObjectMeta{
+				Name:      "istio-certs",
+				Namespace: "default",
+			},
+			Type: kates.SecretTypeTLS,
+			Data: map[string][]byte{
+				"tls.key": []byte("not-a-real-key"),
+				"tls.crt": []byte(cert),
+			},
+		},
+	}
+}
+
+func TestMeshMTLS(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	f.SendIstioCertUpdate(istioCertUpdate("not-a-real-cert"))
+
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    mesh_mtls: istio
+`+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("in-mesh")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: outside
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /outside/
+  service: outside.default
+  mesh_mtls: none
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: linkerd-side
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /linkerd-side/
+  service: linkerd-side.default
+  mesh_mtls: linkerd
+`, "linkerd-side", "cluster_linkerd_side_default")
+
+	// The in-mesh Mapping picks up the Module default, and originates TLS with the Istio
+	// certs and ALPN.
+	inMesh := FindCluster(config, ClusterNameContains("cluster_in_mesh_default"))
+	require.NotNil(t, inMesh)
+	require.NotNil(t, inMesh.TransportSocket)
+	tlsCtx := &v3tls.UpstreamTlsContext{}
+	require.NoError(t, ptypes.UnmarshalAny(inMesh.TransportSocket.GetTypedConfig(), tlsCtx))
+	assert.Equal(t, []string{"istio-peer-exchange", "istio"}, tlsCtx.CommonTlsContext.AlpnProtocols)
+	require.Len(t, tlsCtx.CommonTlsContext.TlsCertificates, 1)
+	firstCertFile := tlsCtx.CommonTlsContext.TlsCertificates[0].CertificateChain.GetFilename()
+	assert.NotEmpty(t, firstCertFile)
+
+	// Backends outside the mesh opt out, and Linkerd's proxy does the mTLS itself, so neither
+	// of those originate TLS.
+	outside := FindCluster(config, ClusterNameContains("cluster_outside_default"))
+	require.NotNil(t, outside)
+	assert.Nil(t, outside.TransportSocket)
+
+	linkerd := FindCluster(config, ClusterNameContains("cluster_linkerd_side_default"))
+	require.NotNil(t, linkerd)
+	assert.Nil(t, linkerd.TransportSocket)
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	r := findRoute(listener, func(r *route.Route) bool {
+		return r.GetRoute().GetCluster() == linkerd.Name
+	})
+	require.NotNil(t, r)
+	var dstOverride string
+	for _, h := range r.RequestHeadersToAdd {
+		if h.Header.Key == "l5d-dst-override" {
+			dstOverride = h.Header.Value
+		}
+	}
+	assert.Equal(t, "linkerd-side.default:80", dstOverride)
+
+	// When Istio rotates its certs, the in-mesh cluster keeps its name, so Envoy just picks up
+	// the new certs.
+	f.SendIstioCertUpdate(istioCertUpdate("not-a-real-cert-either"))
+	f.Flush()
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		c := FindCluster(config, func(c *v3cluster.Cluster) bool { return c.Name == inMesh.Name })
+		if c == nil || c.TransportSocket == nil {
+			return false
+		}
+		rotated := &v3tls.UpstreamTlsContext{}
+		if err := ptypes.UnmarshalAny(c.TransportSocket.GetTypedConfig(), rotated); err != nil {
+			return false
+		}
+		certs := rotated.CommonTlsContext.TlsCertificates
+		return len(certs) == 1 && certs[0].CertificateChain.GetFilename() != firstCertFile
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, config)
+}
+
+func TestMeshMTLSGRPC(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	f.SendIstioCertUpdate(istioCertUpdate("not-a-real-cert"))
+
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    mesh_mtls: istio
+`+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("in-mesh")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: in-mesh-grpc
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /echo.EchoService/
+  rewrite: /echo.EchoService/
+  service: in-mesh-grpc.default
+  grpc: true
+`, "in-mesh-grpc", "cluster_in_mesh_grpc_default")
+
+	alpn := func(c *v3cluster.Cluster) []string {
+		require.NotNil(t, c)
+		require.NotNil(t, c.TransportSocket)
+		tlsCtx := &v3tls.UpstreamTlsContext{}
+		require.NoError(t, ptypes.UnmarshalAny(c.TransportSocket.GetTypedConfig(), tlsCtx))
+		return tlsCtx.CommonTlsContext.AlpnProtocols
+	}
+
+	// The gRPC service has to be able to negotiate HTTP/2, so it's offered h2 as well as the
+	// Istio protocols...
+	assert.Equal(t, []string{"istio-peer-exchange", "istio", "h2"},
+		alpn(FindCluster(config, ClusterNameContains("cluster_in_mesh_grpc_default"))))
+
+	// ...but the HTTP/1.1 service isn't, so that it can't pick a protocol its cluster doesn't
+	// speak.
+	assert.Equal(t, []string{"istio-peer-exchange", "istio"},
+		alpn(FindCluster(config, ClusterNameContains("cluster_in_mesh_default"))))
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Mapping("in-mesh-grpc") != nil
+	})
+	require.NoError(t, err)
+	for _, e := range diag.Errors {
+		for _, part := range e {
+			assert.NotContains(t, part, "does not include h2")
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappingMethods(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: reader
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /api/
+  service: reader.default
+  methods:
+  - get
+  - HEAD
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: writer
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /api/
+  service: writer.default
+  methods:
+  - POST
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: browser
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /browser/
+  service: browser.default
+  methods:
+  - GET
+  cors:
+    origins:
+    - https://app.example.com
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: everything-else
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /
+  service: everything-else.default
+`, "everything-else", "cluster_browser_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	vh := findVirtualHost(listener, "*")
+	require.NotNil(t, vh)
+
+	// Envoy takes the first route that matches, so this is where a request would go: a
+	// cluster, or a direct response status.
+	selected := func(prefix, method string) (string, uint32, *route.Route) {
+		for _, r := range vh.Routes {
+			if r.Match.GetPrefix() != prefix || !routeSelects(t, r, map[string]string{":method": method}) {
+				continue
+			}
+			return r.GetRoute().GetCluster(), r.GetDirectResponse().GetStatus(), r
+		}
+		return "", 0, nil
+	}
+
+	cluster, _, _ := selected("/api/", "GET")
+	assert.Equal(t, "cluster_reader_default_default", cluster)
+	cluster, _, _ = selected("/api/", "HEAD")
+	assert.Equal(t, "cluster_reader_default_default", cluster)
+	cluster, _, _ = selected("/api/", "POST")
+	assert.Equal(t, "cluster_writer_default_default", cluster)
+
+	// Anything else on /api/ is refused outright, rather than going to the / Mapping, and the
+	// 405 names what both Mappings allow.
+	cluster, status, r := selected("/api/", "DELETE")
+	assert.Equal(t, "", cluster)
+	assert.Equal(t, uint32(405), status)
+	require.NotNil(t, r)
+	require.Len(t, r.ResponseHeadersToAdd, 1)
+	assert.Equal(t, "allow", r.ResponseHeadersToAdd[0].Header.Key)
+	assert.Equal(t, "GET, HEAD, POST", r.ResponseHeadersToAdd[0].Header.Value)
+
+	// With CORS, preflights have to reach the Mapping's route for Envoy to answer them.
+	cluster, _, r = selected("/browser/", "OPTIONS")
+	assert.Equal(t, "cluster_browser_default_default", cluster)
+	require.NotNil(t, r)
+	assert.NotNil(t, r.GetRoute().GetCors())
+	_, status, r = selected("/browser/", "PUT")
+	assert.Equal(t, uint32(405), status)
+	require.NotNil(t, r)
+	assert.Equal(t, "GET, OPTIONS", r.ResponseHeadersToAdd[0].Header.Value)
+
+	// Paths nobody restricted are unaffected.
+	cluster, _, _ = selected("/", "DELETE")
+	assert.Equal(t, "cluster_everything_else_default_default", cluster)
+}
@@ -0,0 +1,144 @@
+package entrypoint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaceRoutePrefixes(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    namespace_route_prefixes: true
+` + entrypoint.FakeListenerYAML + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: api
+  namespace: team-a
+spec:
+  hostname: "*"
+  prefix: /api/
+  service: api.team-a
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: api
+  namespace: team-b
+spec:
+  hostname: "*"
+  prefix: /api/
+  service: api.team-b
+  rewrite: ""
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: docs
+  namespace: team-a
+spec:
+  hostname: "*"
+  prefix: /team-a/docs/
+  service: docs.team-a
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: everything
+  namespace: team-a
+spec:
+  hostname: "*"
+  prefix: /
+  service: everything.team-a
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: catch-all
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /
+  service: catch-all.default
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: sneaky
+  namespace: team-b
+spec:
+  hostname: "*"
+  prefix: "/.*"
+  prefix_regex: true
+  service: sneaky.team-b
+`)
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("team-b", "sneaky"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_catch_all_default")) != nil
+	})
+	require.NoError(t, err)
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// routeFor finds the route to a service's cluster.
+	routeFor := func(service string) *route.Route {
+		return findRoute(listener, func(r *route.Route) bool {
+			return strings.HasPrefix(r.GetRoute().GetCluster(), "cluster_"+service)
+		})
+	}
+
+	// Both teams asked for /api/, and each gets it under their own namespace.
+	r := routeFor("api_team_a")
+	require.NotNil(t, r)
+	assert.Equal(t, "/team-a/api/", r.Match.GetPrefix())
+	assert.Equal(t, "/", r.GetRoute().GetPrefixRewrite())
+
+	// team-b didn't rewrite, so their service still sees /api/... rather than /team-b/api/...
+	r = routeFor("api_team_b")
+	require.NotNil(t, r)
+	assert.Equal(t, "/team-b/api/", r.Match.GetPrefix())
+	assert.Equal(t, "/api/", r.GetRoute().GetPrefixRewrite())
+
+	// A prefix that's already in the namespace isn't prefixed twice.
+	r = routeFor("docs_team_a")
+	require.NotNil(t, r)
+	assert.Equal(t, "/team-a/docs/", r.Match.GetPrefix())
+
+	// A tenant's / only catches its own namespace; the real catch-all lives in Ambassador's
+	// namespace and stays where it is.
+	r = routeFor("everything_team_a")
+	require.NotNil(t, r)
+	assert.Equal(t, "/team-a/", r.Match.GetPrefix())
+	r = routeFor("catch_all_default")
+	require.NotNil(t, r)
+	assert.Equal(t, "/", r.Match.GetPrefix())
+
+	// A regex can't be fenced in, so it doesn't get a route at all.
+	assert.Nil(t, routeFor("sneaky"))
+}
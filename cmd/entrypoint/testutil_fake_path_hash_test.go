@@ -0,0 +1,113 @@
+package entrypoint_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathHashPolicy(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(serviceWithEndpoints("assets")+serviceWithEndpoints("blobs")+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: assets
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /assets/
+  service: assets
+  resolver: endpoint
+  load_balancer:
+    policy: maglev
+    path:
+      segments: 2
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: blobs
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /blobs/
+  service: blobs
+  resolver: endpoint
+  load_balancer:
+    policy: ring_hash
+    path: {}
+`, "blobs", "cluster_blobs_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// hashed returns what the route hashes for a request path, by applying its rewrite the
+	// way Envoy does.
+	hashed := func(r *route.Route, path string) string {
+		policies := r.GetRoute().GetHashPolicy()
+		require.Len(t, policies, 1)
+		header := policies[0].GetHeader()
+		require.NotNil(t, header)
+		assert.Equal(t, ":path", header.HeaderName)
+		rewrite := header.GetRegexRewrite()
+		require.NotNil(t, rewrite)
+		re := regexp.MustCompile(rewrite.GetPattern().GetRegex())
+		// RE2 substitutions say \1 where Go says ${1}.
+		return re.ReplaceAllString(path, strings.ReplaceAll(rewrite.Substitution, `\1`, "${1}"))
+	}
+
+	// The query string never counts, and with segments, neither does anything past them.
+	assets := findRoute(listener, func(r *route.Route) bool {
+		return r.GetMatch().GetPrefix() == "/assets/"
+	})
+	require.NotNil(t, assets)
+	assert.Equal(t, "/assets/v2", hashed(assets, "/assets/v2/app.js?cachebust=123"))
+	assert.Equal(t, "/assets/v2", hashed(assets, "/assets/v2/style.css"))
+	assert.Equal(t, "/assets/", hashed(assets, "/assets/"))
+
+	blobs := findRoute(listener, func(r *route.Route) bool {
+		return r.GetMatch().GetPrefix() == "/blobs/"
+	})
+	require.NotNil(t, blobs)
+	assert.Equal(t, "/blobs/sha256/abc", hashed(blobs, "/blobs/sha256/abc?download=1"))
+	assert.Equal(t, "/blobs/sha256/abc", hashed(blobs, "/blobs/sha256/abc"))
+
+	assetsCluster := FindCluster(config, func(c *v3cluster.Cluster) bool {
+		return c.Name == assets.GetRoute().GetCluster()
+	})
+	require.NotNil(t, assetsCluster)
+	assert.Equal(t, v3cluster.Cluster_MAGLEV, assetsCluster.LbPolicy)
+	require.NotNil(t, assetsCluster.EdsClusterConfig)
+
+	blobsCluster := FindCluster(config, func(c *v3cluster.Cluster) bool {
+		return c.Name == blobs.GetRoute().GetCluster()
+	})
+	require.NotNil(t, blobsCluster)
+	assert.Equal(t, v3cluster.Cluster_RING_HASH, blobsCluster.LbPolicy)
+
+	// Scaling up is only a new load assignment for the same cluster, so maglev keeps most
+	// paths on the endpoints they were already on.
+	serviceName := assetsCluster.EdsClusterConfig.ServiceName
+	_, err := f.GetLoadAssignments(hasLoadAssignmentIPs(serviceName, "10.42.0.15"))
+	require.NoError(t, err)
+	subset, err := makeSubset(8080, "10.42.0.15", "10.42.0.16")
+	require.NoError(t, err)
+	require.NoError(t, f.Upsert(makeEndpoints("default", "assets", subset)))
+	f.Flush()
+	_, err = f.GetLoadAssignments(hasLoadAssignmentIPs(serviceName, "10.42.0.15", "10.42.0.16"))
+	require.NoError(t, err)
+	f.AssertNoReconfigure(2 * time.Second)
+}
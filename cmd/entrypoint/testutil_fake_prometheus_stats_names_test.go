@@ -0,0 +1,137 @@
+package entrypoint_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusStatsNames(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    cluster_stats_names: prometheus
+`+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: catalog
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /catalog/
+  service: catalog.shop.svc.cluster.local:8080
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: users
+  namespace: accounts
+spec:
+  hostname: "*"
+  prefix: /users/
+  service: users
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: partner
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /partner/
+  service: https://api.partner.example.com
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: long
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /long/
+  service: recommendations-for-returning-customers.storefront-personalization
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: named
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /named/
+  service: named.default
+  stats_name: named_by_hand
+`, "named", "cluster_named_default")
+
+	statsName := func(name string) string {
+		cluster := FindCluster(config, ClusterNameContains(name))
+		require.NotNil(t, cluster, name)
+		return cluster.AltStatName
+	}
+
+	// The service, its namespace (the Mapping's, if it doesn't say), and its port, with
+	// nothing but underscores between them.
+	assert.Equal(t, "catalog_shop_8080", statsName("cluster_catalog_shop"))
+	assert.Equal(t, "users_accounts_80", statsName("cluster_users_accounts"))
+
+	// A host outside the cluster keeps its dots out of the way.
+	assert.Equal(t, "api-partner-example-com_default_443", statsName("api_partner_example_com"))
+
+	// A stats_name of its own still wins.
+	assert.Equal(t, "named_by_hand", statsName("cluster_named_default"))
+
+	// long's cluster name is too long for Envoy, so it gets cut down, but its stats don't.
+	var long *v3cluster.Cluster
+	for _, c := range config.StaticResources.Clusters {
+		if c.AltStatName == "recommendations-for-returning-customers_storefront-personalization_80" {
+			long = c
+		}
+	}
+	require.NotNil(t, long)
+	assert.LessOrEqual(t, len(long.Name), 60)
+
+	// The stats tags split every one of those names back up, and leave the others alone.
+	require.NotNil(t, config.StatsConfig)
+	tags := map[string]*regexp.Regexp{}
+	for _, tag := range config.StatsConfig.StatsTags {
+		tags[tag.TagName] = regexp.MustCompile(tag.GetRegex())
+	}
+	require.Len(t, tags, 3)
+
+	extract := func(stat string) map[string]string {
+		values := map[string]string{}
+		for name, re := range tags {
+			if m := re.FindStringSubmatch(stat); m != nil {
+				assert.Equal(t, "cluster."+m[1]+"upstream_rq_total", stat)
+				values[name] = m[2]
+			}
+		}
+		return values
+	}
+
+	assert.Equal(t, map[string]string{
+		"ambassador_service":   "catalog",
+		"ambassador_namespace": "shop",
+		"ambassador_port":      "8080",
+	}, extract("cluster.catalog_shop_8080.upstream_rq_total"))
+	assert.Equal(t, map[string]string{
+		"ambassador_service":   "recommendations-for-returning-customers",
+		"ambassador_namespace": "storefront-personalization",
+		"ambassador_port":      "80",
+	}, extract("cluster.recommendations-for-returning-customers_storefront-personalization_80.upstream_rq_total"))
+	assert.Empty(t, extract("cluster.named_by_hand.upstream_rq_total"))
+}
@@ -103,3 +103,30 @@ func (q *Queue) AssertEmpty(timeout time.Duration, msg string) {
 	defer q.cond.L.Unlock()
 	assert.Empty(q.T, q.entries, msg)
 }
+
+// AssertNoMore will check that, for the supplied duration, no entries that satisfy the supplied
+// predicate show up past the ones already returned by Get.
+func (q *Queue) AssertNoMore(timeout time.Duration, predicate func(interface{}) bool, msg string) {
+	q.T.Helper()
+	time.Sleep(timeout)
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	var matching []interface{}
+	for _, obj := range q.entries[q.offset:] {
+		if predicate(obj) {
+			matching = append(matching, obj)
+		}
+	}
+	assert.Empty(q.T, matching, msg)
+}
+
+// Latest returns the most recently added entry, or nil if the queue has never had one. It
+// doesn't affect what Get returns.
+func (q *Queue) Latest() interface{} {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	if len(q.entries) == 0 {
+		return nil
+	}
+	return q.entries[len(q.entries)-1]
+}
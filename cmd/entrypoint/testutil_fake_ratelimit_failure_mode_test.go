@@ -0,0 +1,73 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	ratelimit "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/ratelimit/v3"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rateLimitFilterConfig(t *testing.T, rateLimitService string) *ratelimit.RateLimit {
+	_, hcm := entrypoint.RunListenerFake(t, rateLimitService+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: limited
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /limited/
+  service: limited.default
+  labels:
+    ambassador:
+    - request_label_group:
+      - generic_key:
+          value: limited
+      - remote_address:
+          key: remote_address
+`, "limited")
+
+	rl := &ratelimit.RateLimit{}
+	entrypoint.HTTPFilterConfig(t, hcm, "envoy.filters.http.ratelimit", rl)
+	return rl
+}
+
+func TestRateLimitFailureModeDefault(t *testing.T) {
+	rl := rateLimitFilterConfig(t, `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: RateLimitService
+metadata:
+  name: ratelimit
+  namespace: default
+spec:
+  service: ratelimit.default:5000
+  protocol_version: v3
+`)
+
+	// Without being told otherwise, an unreachable RateLimitService lets requests through.
+	assert.False(t, rl.FailureModeDeny)
+}
+
+func TestRateLimitFailureModeDeny(t *testing.T) {
+	rl := rateLimitFilterConfig(t, `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: RateLimitService
+metadata:
+  name: ratelimit
+  namespace: default
+spec:
+  service: ratelimit.default:5000
+  protocol_version: v3
+  failure_mode_deny: true
+`)
+
+	// The Mapping has more than one descriptor, but they all go in the same call, so the
+	// failure mode covers every one of them.
+	assert.True(t, rl.FailureModeDeny)
+	assert.Equal(t, "ambassador", rl.Domain)
+}
@@ -0,0 +1,104 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readinessConfig(t *testing.T, module string) *entrypoint.Fake {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(module + entrypoint.FakeListenerYAML + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: checked-a
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /checked-a/
+  service: checked-a.default
+  health_checks:
+  - health_check:
+      http:
+        path: /healthz
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: checked-b
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /checked-b/
+  service: checked-b.default
+  health_checks:
+  - health_check:
+      http:
+        path: /healthz
+` + entrypoint.FakeMappingYAML("unchecked"))
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "unchecked"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	_, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_unchecked_default_default")) != nil
+	})
+	require.NoError(t, err)
+
+	return f
+}
+
+func TestReadinessUpstreamHealth(t *testing.T) {
+	f := readinessConfig(t, `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    readiness_healthy_cluster_fraction: 0.9
+`)
+
+	// With nothing healthy, only "unchecked" counts, since it isn't health checked. (Nor are
+	// Ambassador's own clusters, like diagd's, but they don't count either way.) So we're not
+	// ready...
+	ready, none := f.UpstreamReadiness(nil)
+	assert.False(t, ready)
+	assert.InDelta(t, 1.0/3.0, none, 1e-9)
+
+	// ...nor with one of the health checked services up...
+	ready, one := f.UpstreamReadiness(map[string]int{
+		"cluster_checked_a_default_default": 2,
+	})
+	assert.False(t, ready)
+	assert.Greater(t, one, none)
+
+	// ...but once both are, everything is healthy.
+	ready, both := f.UpstreamReadiness(map[string]int{
+		"cluster_checked_a_default_default": 2,
+		"cluster_checked_b_default_default": 1,
+	})
+	assert.True(t, ready)
+	assert.Equal(t, 1.0, both)
+}
+
+func TestReadinessDefault(t *testing.T) {
+	f := readinessConfig(t, "")
+
+	// Without the Module asking for it, readiness is just about the config, so it doesn't
+	// matter that nothing is healthy.
+	ready, fraction := f.UpstreamReadiness(nil)
+	assert.True(t, ready)
+	assert.Less(t, fraction, 1.0)
+}
@@ -0,0 +1,127 @@
+package entrypoint_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rewritePath returns what a route's regex_rewrite does to path, the way Envoy does it: every
+// match of the pattern in the path (but not the query string) is replaced. Go's regexp is RE2
+// too, so only the substitution needs translating, from \1 to ${1}.
+func rewritePath(t *testing.T, r *route.Route, path string) string {
+	t.Helper()
+	rewrite := r.GetRoute().GetRegexRewrite()
+	require.NotNil(t, rewrite)
+	re, err := regexp.Compile(rewrite.GetPattern().GetRegex())
+	require.NoError(t, err)
+
+	var template strings.Builder
+	substitution := rewrite.GetSubstitution()
+	for i := 0; i < len(substitution); i++ {
+		switch c := substitution[i]; {
+		case c == '\\' && i+1 < len(substitution):
+			i++
+			if substitution[i] == '\\' {
+				template.WriteByte('\\')
+			} else {
+				template.WriteString("${" + string(substitution[i]) + "}")
+			}
+		case c == '$':
+			template.WriteString("$$")
+		default:
+			template.WriteByte(c)
+		}
+	}
+
+	query := ""
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path, query = path[:i], path[i:]
+	}
+	return re.ReplaceAllString(path, template.String()) + query
+}
+
+func TestRegexRewriteSegments(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: orders
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /api/
+  service: orders.default
+  regex_rewrite:
+    pattern: '^/api/(v(\d+))/orders/([^/]+)/(.*)$'
+    substitution: '/orders-v\2/\3/\4'
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: legacy
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /legacy/
+  service: legacy.default
+  regex_rewrite:
+    pattern: '/v\d+/'
+    substitution: '/'
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: broken
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /broken/
+  service: broken.default
+  regex_rewrite:
+    pattern: '^/broken/(v\d+)/(.*)$'
+    substitution: '/\3'
+`, "broken", "cluster_legacy_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	routeFor := func(cluster string) *route.Route {
+		r := findRoute(listener, func(r *route.Route) bool {
+			return r.GetRoute().GetCluster() == cluster
+		})
+		require.NotNil(t, r)
+		return r
+	}
+
+	// Groups are numbered by their opening parenthesis, so the version's digits, nested in
+	// the version segment, are \2, and the segments after it are \3 and \4.
+	orders := routeFor("cluster_orders_default_default")
+	assert.Equal(t, "/orders-v2/1234/items/5", rewritePath(t, orders, "/api/v2/orders/1234/items/5"))
+
+	// A path the pattern doesn't match goes upstream as it is.
+	assert.Equal(t, "/api/orders/1234", rewritePath(t, orders, "/api/orders/1234"))
+
+	// An unanchored pattern takes out every segment it matches, and leaves the query alone.
+	legacy := routeFor("cluster_legacy_default_default")
+	assert.Equal(t, "/legacy/users/7?since=/v2/", rewritePath(t, legacy, "/legacy/v1/users/v2/7?since=/v2/"))
+
+	// broken's substitution wants a group its pattern doesn't have.
+	assert.Nil(t, FindCluster(config, ClusterNameContains("cluster_broken_default_default")))
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Error("broken.default") != ""
+	})
+	require.NoError(t, err)
+	assert.Contains(t, diag.Error("broken.default"), "only has 2 capture groups")
+}
@@ -0,0 +1,125 @@
+package entrypoint
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReloadStats is what diagd's metrics endpoint says about reconfigurations. A failed
+// reconfiguration leaves Envoy running the last good config, so it shows up in Failures but
+// not in Generation.
+type ReloadStats struct {
+	Successes  int // Reconfigurations that changed the config Envoy runs.
+	Failures   int // Reconfigurations that left Envoy running the last good config.
+	Generation int // Snapshot generation of the config Envoy runs.
+
+	// Durations is how many reconfigurations took at most each bucket's upper bound, in
+	// seconds. Like a Prometheus histogram, the counts are cumulative, and the +Inf bucket
+	// counts everything.
+	Durations map[float64]int
+}
+
+// Quantile estimates the q-quantile (0 <= q <= 1) of the reconfiguration durations, in
+// seconds, the same way Prometheus' histogram_quantile does: by interpolating within the
+// bucket it falls in. It returns NaN when there have been no reconfigurations.
+func (s *ReloadStats) Quantile(q float64) float64 {
+	bounds := make([]float64, 0, len(s.Durations))
+	for bound := range s.Durations {
+		bounds = append(bounds, bound)
+	}
+	sort.Float64s(bounds)
+
+	if len(bounds) == 0 || s.Durations[bounds[len(bounds)-1]] == 0 {
+		return math.NaN()
+	}
+
+	rank := q * float64(s.Durations[bounds[len(bounds)-1]])
+	lower, below := 0.0, 0
+	for _, bound := range bounds {
+		count := s.Durations[bound]
+		if float64(count) >= rank {
+			if math.IsInf(bound, 1) {
+				// Nothing to interpolate toward, so the best we can say is the last
+				// finite bound.
+				return lower
+			}
+			if count == below {
+				return bound
+			}
+			return lower + (bound-lower)*(rank-float64(below))/float64(count-below)
+		}
+		lower, below = bound, count
+	}
+	return lower
+}
+
+// parseReloadStats picks the reconfiguration metrics out of Prometheus text exposition.
+func parseReloadStats(text string) (*ReloadStats, error) {
+	stats := &ReloadStats{Durations: map[float64]int{}}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		space := strings.LastIndex(line, " ")
+		if space < 0 {
+			continue
+		}
+		series, rawValue := line[:space], line[space+1:]
+		value, err := strconv.ParseFloat(rawValue, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad value in metric %q: %w", line, err)
+		}
+
+		switch {
+		case series == `ambassador_reconfigurations_total{outcome="success"}`:
+			stats.Successes = int(value)
+		case series == `ambassador_reconfigurations_total{outcome="failure"}`:
+			stats.Failures = int(value)
+		case series == "ambassador_config_generation":
+			stats.Generation = int(value)
+		case strings.HasPrefix(series, `ambassador_reconfiguration_duration_seconds_bucket{le="`):
+			le := strings.TrimSuffix(strings.TrimPrefix(series, `ambassador_reconfiguration_duration_seconds_bucket{le="`), `"}`)
+			bound, err := strconv.ParseFloat(le, 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad bucket in metric %q: %w", line, err)
+			}
+			stats.Durations[bound] = int(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Stats returns the reconfiguration metrics from diagd's real metrics endpoint, as of now.
+func (f *Fake) Stats() (*ReloadStats, error) {
+	f.T.Helper()
+	resp, err := http.Get(GetEventHost() + "/metrics")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metrics endpoint returned %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseReloadStats(string(body))
+}
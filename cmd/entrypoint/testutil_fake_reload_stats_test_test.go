@@ -0,0 +1,56 @@
+package entrypoint_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+)
+
+// TestFakeReloadStats checks that every reconfiguration is counted, and that the generation
+// only moves when Envoy actually gets the new config.
+func TestFakeReloadStats(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	require.NoError(t, f.UpsertYAML(entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("orders")))
+	f.Flush()
+
+	snap, err := f.GetSnapshot(HasMapping("default", "orders"))
+	require.NoError(t, err)
+	_, err = f.GetEnvoyConfig(func(config *v3bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_orders_default_default")) != nil
+	})
+	require.NoError(t, err)
+
+	stats, err := f.Stats()
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, stats.Successes, 1)
+	assert.Equal(t, 0, stats.Failures)
+	assert.Equal(t, snap.Generation, stats.Generation)
+
+	// Every reconfiguration lands in the +Inf bucket, and its duration is somewhere sensible.
+	assert.Equal(t, stats.Successes+stats.Failures, stats.Durations[math.Inf(1)])
+	p50, p99 := stats.Quantile(0.5), stats.Quantile(0.99)
+	assert.False(t, math.IsNaN(p50))
+	assert.True(t, p50 >= 0 && p50 <= p99, "p50 %v, p99 %v", p50, p99)
+
+	// Another reconfiguration moves the generation along with the count.
+	require.NoError(t, f.UpsertYAML(entrypoint.FakeMappingYAML("billing")))
+	f.Flush()
+
+	snap, err = f.GetSnapshot(HasMapping("default", "billing"))
+	require.NoError(t, err)
+	_, err = f.GetEnvoyConfig(func(config *v3bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_billing_default_default")) != nil
+	})
+	require.NoError(t, err)
+
+	next, err := f.Stats()
+	require.NoError(t, err)
+	assert.Greater(t, next.Successes, stats.Successes)
+	assert.Equal(t, snap.Generation, next.Generation)
+	assert.Equal(t, next.Successes+next.Failures, next.Durations[math.Inf(1)])
+}
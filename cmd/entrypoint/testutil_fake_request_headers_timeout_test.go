@@ -0,0 +1,80 @@
+package entrypoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestHeadersTimeout(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    request_headers_timeout_ms: 10000
+`+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: uploads-listener-8081
+  namespace: default
+spec:
+  port: 8081
+  protocol: HTTP
+  securityModel: XFP
+  requestHeadersTimeoutMs: 30000
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: internal-listener-8082
+  namespace: default
+spec:
+  port: 8082
+  protocol: HTTP
+  securityModel: XFP
+  requestHeadersTimeoutMs: 0
+  hostBinding:
+    namespace:
+      from: ALL
+`+entrypoint.FakeMappingYAML("backend"), "backend", "cluster_backend_default_default")
+
+	// headersTimeout is the request headers timeout on a Listener's HCM, or 0 if there isn't one.
+	headersTimeout := func(name string) time.Duration {
+		listener := findListener(config, func(l *v3listener.Listener) bool {
+			return l.Name == name
+		})
+		require.NotNil(t, listener)
+		hcm := entrypoint.ListenerHCM(listener)
+		require.NotNil(t, hcm)
+		if hcm.RequestHeadersTimeout == nil {
+			return 0
+		}
+		timeout, err := ptypes.Duration(hcm.RequestHeadersTimeout)
+		require.NoError(t, err)
+		return timeout
+	}
+
+	// Listeners pick up the Module's timeout unless they say otherwise, and saying 0 turns
+	// it back off for that Listener.
+	assert.Equal(t, 10*time.Second, headersTimeout("ambassador-listener-8080"))
+	assert.Equal(t, 30*time.Second, headersTimeout("uploads-listener-8081"))
+	assert.Equal(t, time.Duration(0), headersTimeout("internal-listener-8082"))
+}
@@ -0,0 +1,102 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	filelog "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/access_loggers/file/v3"
+	hcm "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func requestIDConfig(t *testing.T, extra string) (*hcm.HttpConnectionManager, *filelog.FileAccessLog) {
+	_, httpConnectionManager := entrypoint.RunListenerFake(t,
+		extra+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("backend"), "backend")
+
+	require.NotEmpty(t, httpConnectionManager.AccessLog)
+	accessLog := &filelog.FileAccessLog{}
+	require.NoError(t, ptypes.UnmarshalAny(httpConnectionManager.AccessLog[0].GetTypedConfig(), accessLog))
+
+	return httpConnectionManager, accessLog
+}
+
+func TestRequestIDCorrelationText(t *testing.T) {
+	httpConnectionManager, accessLog := requestIDConfig(t, `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    correlate_request_id: true
+    preserve_external_request_id: true
+    envoy_log_format: "%REQ(:METHOD)% %REQ(X-ENVOY-ORIGINAL-PATH?:PATH)% %RESPONSE_CODE%"
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TracingService
+metadata:
+  name: tracing
+  namespace: default
+spec:
+  driver: zipkin
+  service: zipkin.default:9411
+`)
+
+	// An ID the client sent is kept, and a request without one gets one, so there's always
+	// an ID to log...
+	assert.True(t, httpConnectionManager.PreserveExternalRequestId)
+	assert.True(t, httpConnectionManager.GetGenerateRequestId().GetValue())
+
+	// ...the custom log format, which left it out, gets it added...
+	format := accessLog.GetLogFormat().GetTextFormatSource().GetInlineString()
+	assert.Equal(t, "%REQ(:METHOD)% %REQ(X-ENVOY-ORIGINAL-PATH?:PATH)% %RESPONSE_CODE% \"%REQ(X-REQUEST-ID)%\"\n", format)
+
+	// ...and every span is tagged with the same header.
+	require.NotNil(t, httpConnectionManager.Tracing)
+	var found bool
+	for _, tag := range httpConnectionManager.Tracing.CustomTags {
+		if tag.GetTag() == "request_id" {
+			found = true
+			assert.Equal(t, "x-request-id", tag.GetRequestHeader().GetName())
+			assert.Equal(t, "-", tag.GetRequestHeader().GetDefaultValue())
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestRequestIDCorrelationJSON(t *testing.T) {
+	httpConnectionManager, accessLog := requestIDConfig(t, `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    correlate_request_id: true
+    envoy_log_type: json
+    envoy_log_format:
+      status: "%RESPONSE_CODE%"
+      path: "%REQ(:PATH)%"
+`)
+
+	// Without preserve_external_request_id, Envoy replaces whatever the client sent...
+	assert.False(t, httpConnectionManager.PreserveExternalRequestId)
+	assert.True(t, httpConnectionManager.GetGenerateRequestId().GetValue())
+
+	// ...and the JSON log gets the same request_id field as the default format has.
+	fields := accessLog.GetJsonFormat().GetFields()
+	require.Contains(t, fields, "request_id")
+	assert.Equal(t, "%REQ(X-REQUEST-ID)%", fields["request_id"].GetStringValue())
+	assert.Len(t, fields, 3)
+
+	// There's no TracingService, so there are no spans to tag.
+	assert.Nil(t, httpConnectionManager.Tracing)
+}
@@ -0,0 +1,116 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestSmugglingProtection(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    allow_chunked_length: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: strict-listener
+  namespace: default
+spec:
+  port: 8080
+  protocol: HTTP
+  securityModel: XFP
+  requestSmugglingProtection: strict
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: legacy-listener
+  namespace: default
+spec:
+  port: 8081
+  protocol: HTTP
+  securityModel: XFP
+  requestSmugglingProtection: legacy
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: module-listener
+  namespace: default
+spec:
+  port: 8082
+  protocol: HTTP
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: foo
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /foo
+  service: foo.default
+`, "foo", "cluster_foo_default_default")
+
+	settings := func(name string) (allowChunkedLength, keepInvalidOpen, streamError bool) {
+		listener := findListener(config, func(l *v3listener.Listener) bool {
+			return l.Name == name
+		})
+		require.NotNil(t, listener)
+		hcm := entrypoint.ListenerHCM(listener)
+		require.NotNil(t, hcm)
+		// Strict is Envoy's own default, so only legacy spells anything out.
+		return hcm.GetHttpProtocolOptions().GetAllowChunkedLength(),
+			hcm.GetHttpProtocolOptions().GetOverrideStreamErrorOnInvalidHttpMessage().GetValue(),
+			hcm.GetStreamErrorOnInvalidHttpMessage().GetValue()
+	}
+
+	// A strict Listener rejects Content-Length with Transfer-Encoding, and closes the
+	// connection on anything it can't parse, whatever the Module's allow_chunked_length says.
+	allowChunkedLength, keepInvalidOpen, streamError := settings("strict-listener")
+	assert.False(t, allowChunkedLength)
+	assert.False(t, keepInvalidOpen)
+	assert.False(t, streamError)
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "strict-listener"
+	})
+	hcm := entrypoint.ListenerHCM(listener)
+	assert.Nil(t, hcm.GetHttpProtocolOptions().GetOverrideStreamErrorOnInvalidHttpMessage())
+	assert.Nil(t, hcm.GetStreamErrorOnInvalidHttpMessage())
+
+	// The legacy Listener lets all of that through, for clients strict parsing breaks.
+	allowChunkedLength, keepInvalidOpen, streamError = settings("legacy-listener")
+	assert.True(t, allowChunkedLength)
+	assert.True(t, keepInvalidOpen)
+	assert.True(t, streamError)
+
+	// A Listener that doesn't say gets the Module's default, which is strict, apart from the
+	// Module's own allow_chunked_length.
+	allowChunkedLength, keepInvalidOpen, streamError = settings("module-listener")
+	assert.True(t, allowChunkedLength)
+	assert.False(t, keepInvalidOpen)
+	assert.False(t, streamError)
+}
@@ -0,0 +1,102 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	lua "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/lua/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestTimeoutHeader(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    request_timeout_header:
+      honor: false
+`+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("public")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: reports
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /reports/
+  service: reports.default
+  timeout_ms: 3000
+  request_timeout_header:
+    max_ms: 60000
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: batch
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /batch/
+  service: batch.default
+  request_timeout_header:
+    honor: true
+`, "batch", "cluster_batch_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	hcm := entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+
+	// The filter has to get to the header before the router does.
+	filterIdx := map[string]int{}
+	for i, filter := range hcm.HttpFilters {
+		filterIdx[filter.Name] = i
+	}
+	require.Contains(t, filterIdx, "ambassador.request_timeout_header")
+	assert.Equal(t, filterIdx["envoy.filters.http.router"]-1, filterIdx["ambassador.request_timeout_header"])
+
+	perRoute := func(cluster string) (*route.Route, *lua.LuaPerRoute) {
+		r := findRoute(listener, func(r *route.Route) bool {
+			return r.GetRoute().GetCluster() == cluster
+		})
+		require.NotNil(t, r)
+		cfg, ok := r.TypedPerFilterConfig["ambassador.request_timeout_header"]
+		if !ok {
+			return r, nil
+		}
+		lpr := &lua.LuaPerRoute{}
+		require.NoError(t, ptypes.UnmarshalAny(cfg, lpr))
+		return r, lpr
+	}
+
+	// The Module doesn't trust anyone with the header...
+	_, lpr := perRoute("cluster_public_default_default")
+	require.NotNil(t, lpr)
+	assert.Contains(t, lpr.GetSourceCode().GetInlineString(), `remove("x-envoy-upstream-rq-timeout-ms")`)
+
+	// ...but reports lets it go as high as a minute (its own timeout still being the default)...
+	r, lpr := perRoute("cluster_reports_default_default")
+	require.NotNil(t, lpr)
+	assert.Contains(t, lpr.GetSourceCode().GetInlineString(), `ms > 60000`)
+	assert.Contains(t, lpr.GetSourceCode().GetInlineString(), `replace("x-envoy-upstream-rq-timeout-ms", "60000")`)
+	assert.Equal(t, int64(3), r.GetRoute().GetTimeout().GetSeconds())
+
+	// ...and batch leaves it entirely to Envoy.
+	_, lpr = perRoute("cluster_batch_default_default")
+	assert.Nil(t, lpr)
+}
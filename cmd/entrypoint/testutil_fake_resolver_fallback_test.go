@@ -0,0 +1,155 @@
+package entrypoint_test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+	v3endpoint "github.com/datawire/ambassador/v2/pkg/api/envoy/config/endpoint/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	v3aggregate "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/clusters/aggregate/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// aggregateClusters returns the clusters an aggregate cluster is made of, in the order envoy
+// tries them, or nil if it isn't an aggregate.
+func aggregateClusters(t *testing.T, cluster *v3cluster.Cluster) []string {
+	t.Helper()
+	if cluster.GetClusterType().GetName() != "envoy.clusters.aggregate" {
+		return nil
+	}
+	aggregate := &v3aggregate.ClusterConfig{}
+	require.NoError(t, anypb.UnmarshalTo(cluster.GetClusterType().GetTypedConfig(), aggregate, proto.UnmarshalOptions{}))
+	return aggregate.Clusters
+}
+
+func TestResolverFallback(t *testing.T) {
+	// This test will not pass in legacy mode because diagd will not emit EDS clusters in legacy mode.
+	if legacy, err := strconv.ParseBool(os.Getenv("AMBASSADOR_LEGACY_MODE")); err == nil && legacy {
+		return
+	}
+
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(serviceWithEndpoints("quote") + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    resolver_fallback:
+    - endpoint
+` + entrypoint.FakeListenerYAML + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: ConsulResolver
+metadata:
+  name: consul-dc1
+  namespace: default
+spec:
+  address: consul-server.default:8500
+  datacenter: dc1
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: quote
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /quote/
+  service: quote
+  resolver: consul-dc1
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: direct
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /direct/
+  service: quote
+  resolver: endpoint
+`)
+	require.NoError(t, err)
+	f.ConsulEndpoint("dc1", "quote", "1.2.3.4", 8080)
+	f.Flush()
+
+	_, err = f.GetSnapshot(HasMapping("default", "direct"))
+	require.NoError(t, err)
+
+	var fallback *v3cluster.Cluster
+	config, err := f.GetEnvoyConfig(func(config *v3bootstrap.Bootstrap) bool {
+		fallback = FindCluster(config, func(c *v3cluster.Cluster) bool {
+			return aggregateClusters(t, c) != nil
+		})
+		return fallback != nil
+	})
+	require.NoError(t, err)
+
+	// The Mapping with the Module's resolver_fallback goes to an aggregate of a consul cluster
+	// and a Kubernetes endpoint cluster for the same service, in that order. The Mapping that
+	// already uses the fallback resolver has nothing to fall back to.
+	members := aggregateClusters(t, fallback)
+	require.Len(t, members, 2)
+	consulCluster := FindCluster(config, func(c *v3cluster.Cluster) bool { return c.Name == members[0] })
+	k8sCluster := FindCluster(config, func(c *v3cluster.Cluster) bool { return c.Name == members[1] })
+	require.NotNil(t, consulCluster)
+	require.NotNil(t, k8sCluster)
+	assert.Equal(t, "consul/dc1/quote", consulCluster.GetEdsClusterConfig().GetServiceName())
+	assert.Contains(t, k8sCluster.GetEdsClusterConfig().GetServiceName(), "k8s/default/quote")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	routes := prefixRoutes(listener, "/quote/")
+	require.Len(t, routes, 1)
+	assert.Equal(t, fallback.Name, routes[0].GetRoute().GetCluster())
+	routes = prefixRoutes(listener, "/direct/")
+	require.Len(t, routes, 1)
+	assert.Equal(t, k8sCluster.Name, routes[0].GetRoute().GetCluster())
+
+	consulPath := consulCluster.GetEdsClusterConfig().GetServiceName()
+	k8sPath := k8sCluster.GetEdsClusterConfig().GetServiceName()
+
+	// While consul has endpoints, everything goes there.
+	assignments, err := f.GetLoadAssignments(hasLoadAssignmentIPs(consulPath, "1.2.3.4"))
+	require.NoError(t, err)
+	assert.Equal(t, consulCluster.Name, f.AggregateTarget(config, assignments, fallback.Name))
+
+	// When consul has none, the Kubernetes endpoints take over...
+	f.ClearConsulEndpoints("dc1", "quote")
+	f.Flush()
+	assignments, err = f.GetLoadAssignments(hasLoadAssignmentIPs(consulPath))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.42.0.15"}, loadAssignmentIPs(assignments[k8sPath]))
+	assert.Equal(t, k8sCluster.Name, f.AggregateTarget(config, assignments, fallback.Name))
+
+	// ...until they're gone too, when there's nowhere left to send anything...
+	require.NoError(t, f.Delete("Endpoints", "default", "quote"))
+	f.Flush()
+	assignments, err = f.GetLoadAssignments(func(assignments map[string]*v3endpoint.ClusterLoadAssignment) bool {
+		return len(loadAssignmentIPs(assignments[k8sPath])) == 0
+	})
+	require.NoError(t, err)
+	assert.Empty(t, loadAssignmentIPs(assignments[consulPath]))
+	assert.Equal(t, "", f.AggregateTarget(config, assignments, fallback.Name))
+
+	// ...and once consul is back, it gets everything again.
+	f.ConsulEndpoint("dc1", "quote", "1.2.3.5", 8080)
+	f.Flush()
+	assignments, err = f.GetLoadAssignments(hasLoadAssignmentIPs(consulPath, "1.2.3.5"))
+	require.NoError(t, err)
+	assert.Equal(t, consulCluster.Name, f.AggregateTarget(config, assignments, fallback.Name))
+}
@@ -0,0 +1,82 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseFlagStats(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    response_flag_stats_limit: 1
+`+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: checkout
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /checkout/
+  service: shop.default
+  response_flag_stats:
+  - UT
+  - URX
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: search
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /search/
+  service: shop.default
+  response_flag_stats:
+  - UT
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: browse
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /browse/
+  service: shop.default
+`, "browse", "cluster_shop_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	vh := findVirtualHost(listener, "*")
+	require.NotNil(t, vh)
+
+	// All three Mappings share a cluster, so the per-flag stats have to come from a virtual
+	// cluster of the Mapping's own. The limit is 1, so only checkout (first by name) gets one,
+	// and browse never asked.
+	vcs := map[string][]string{}
+	for _, vc := range vh.VirtualClusters {
+		for _, h := range vc.Headers {
+			if h.Name == ":path" {
+				vcs[vc.Name] = append(vcs[vc.Name], h.GetPrefixMatch())
+			}
+		}
+	}
+	assert.Equal(t, map[string][]string{"checkout_default": {"/checkout/"}}, vcs)
+}
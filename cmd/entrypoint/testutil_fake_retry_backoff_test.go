@@ -0,0 +1,91 @@
+package entrypoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryBackOff(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: tuned
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /tuned/
+  service: tuned.default
+  retry_policy:
+    retry_on: 5xx
+    num_retries: 4
+    retry_back_off:
+      base_interval_ms: 100
+      max_interval_ms: 1500
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: default-backoff
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /default-backoff/
+  service: default-backoff.default
+  retry_policy:
+    retry_on: 5xx
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: inverted
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /inverted/
+  service: inverted.default
+  retry_policy:
+    retry_on: connect-failure
+    retry_back_off:
+      base_interval_ms: 2000
+      max_interval_ms: 500
+`, "inverted", "cluster_inverted_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	retryPolicy := func(cluster string) *route.RetryPolicy {
+		routeAction := findVirtualHostRoute(listener, func(r *route.RouteAction) bool {
+			return r.GetCluster() == cluster
+		})
+		require.NotNil(t, routeAction)
+		require.NotNil(t, routeAction.RetryPolicy)
+		return routeAction.RetryPolicy
+	}
+
+	policy := retryPolicy("cluster_tuned_default_default")
+	require.NotNil(t, policy.RetryBackOff)
+	assert.Equal(t, 100*time.Millisecond, policy.RetryBackOff.BaseInterval.AsDuration())
+	assert.Equal(t, 1500*time.Millisecond, policy.RetryBackOff.MaxInterval.AsDuration())
+
+	// Without retry_back_off, Envoy's default exponential backoff applies.
+	policy = retryPolicy("cluster_default_backoff_default_default")
+	assert.Nil(t, policy.RetryBackOff)
+
+	// A base interval larger than the max is an error; we keep retrying, but with the
+	// default backoff.
+	policy = retryPolicy("cluster_inverted_default_default")
+	assert.Equal(t, "connect-failure", policy.RetryOn)
+	assert.Nil(t, policy.RetryBackOff)
+}
@@ -0,0 +1,72 @@
+package entrypoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryBufferLimit(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: uploads
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /uploads/
+  service: uploads.default
+  retry_buffer_limit_bytes: 65536
+  retry_policy:
+    retry_on: 5xx
+    num_retries: 2
+    per_try_timeout: 500ms
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: plain
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /plain/
+  service: plain.default
+  retry_policy:
+    retry_on: 5xx
+`, "plain", "cluster_plain_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// Envoy keeps up to the limit of each body around for retries; anything bigger gets one
+	// try, which the per-try timeout still bounds.
+	r := findRoute(listener, func(r *route.Route) bool {
+		return r.GetRoute().GetCluster() == "cluster_uploads_default_default"
+	})
+	require.NotNil(t, r)
+	require.NotNil(t, r.PerRequestBufferLimitBytes)
+	assert.Equal(t, uint32(65536), r.PerRequestBufferLimitBytes.Value)
+	retry := r.GetRoute().GetRetryPolicy()
+	require.NotNil(t, retry)
+	assert.Equal(t, uint32(2), retry.GetNumRetries().GetValue())
+	assert.Equal(t, 500*time.Millisecond, retry.PerTryTimeout.AsDuration())
+
+	// Without a limit, the listener's buffer limit applies.
+	r = findRoute(listener, func(r *route.Route) bool {
+		return r.GetRoute().GetCluster() == "cluster_plain_default_default"
+	})
+	require.NotNil(t, r)
+	assert.NotNil(t, r.GetRoute().GetRetryPolicy())
+	assert.Nil(t, r.PerRequestBufferLimitBytes)
+}
@@ -0,0 +1,94 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryOnReset(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: flaky
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /flaky/
+  service: flaky.default
+  retry_policy:
+    retry_on: 5xx
+    num_retries: 2
+    retry_on_reset: true
+    retry_other_hosts: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: ingest
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /ingest/
+  service: ingest.default
+  retry_policy:
+    retry_on_reset: true
+    retry_non_idempotent: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: plain
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /plain/
+  service: plain.default
+  retry_policy:
+    retry_on: connect-failure
+    retry_non_idempotent: true
+`, "plain", "cluster_plain_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	retryPolicy := func(cluster string) *route.RetryPolicy {
+		routeAction := findVirtualHostRoute(listener, func(r *route.RouteAction) bool {
+			return r.GetCluster() == cluster
+		})
+		require.NotNil(t, routeAction)
+		require.NotNil(t, routeAction.RetryPolicy)
+		return routeAction.RetryPolicy
+	}
+
+	// flaky retries its 5xxs and its early resets, but only for requests that are safe to
+	// send twice, and still steers the retries to other hosts...
+	policy := retryPolicy("cluster_flaky_default_default")
+	assert.Equal(t, "5xx,reset,connect-failure,refused-stream", policy.RetryOn)
+	require.Len(t, policy.RetriableRequestHeaders, 1)
+	methods := policy.RetriableRequestHeaders[0]
+	assert.Equal(t, ":method", methods.Name)
+	assert.Equal(t, "GET|HEAD|OPTIONS|TRACE|PUT|DELETE", methods.GetSafeRegexMatch().GetRegex())
+	require.Len(t, policy.RetryHostPredicate, 1)
+	assert.Equal(t, "envoy.retry_host_predicates.previous_hosts", policy.RetryHostPredicate[0].Name)
+
+	// ...ingest knows its POSTs are safe to repeat...
+	policy = retryPolicy("cluster_ingest_default_default")
+	assert.Equal(t, "reset,connect-failure,refused-stream", policy.RetryOn)
+	assert.Empty(t, policy.RetriableRequestHeaders)
+
+	// ...and retry_non_idempotent doesn't change anything without retry_on_reset.
+	policy = retryPolicy("cluster_plain_default_default")
+	assert.Equal(t, "connect-failure", policy.RetryOn)
+	assert.Empty(t, policy.RetriableRequestHeaders)
+}
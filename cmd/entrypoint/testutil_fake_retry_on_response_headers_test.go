@@ -0,0 +1,100 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryOnResponseHeaders(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: shedding
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /shedding/
+  service: shedding.default
+  retry_policy:
+    num_retries: 2
+    retry_other_hosts: true
+    retry_on_response_headers:
+      X-Upstream-Overloaded: "true"
+      x-shed-load: ""
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: both
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /both/
+  service: both.default
+  retry_policy:
+    retry_on: 5xx
+    retry_on_response_headers:
+      x-retry-elsewhere: "yes"
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: plain
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /plain/
+  service: plain.default
+  retry_policy:
+    retry_on: 5xx
+`, "plain", "cluster_plain_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	retryPolicy := func(cluster string) *route.RetryPolicy {
+		routeAction := findVirtualHostRoute(listener, func(r *route.RouteAction) bool {
+			return r.GetCluster() == cluster
+		})
+		require.NotNil(t, routeAction)
+		require.NotNil(t, routeAction.RetryPolicy)
+		return routeAction.RetryPolicy
+	}
+
+	// shedding retries only on what the upstream says in its response headers -- a value
+	// for one, mere presence for the other, both in Envoy's lowercase -- and sends each retry
+	// to a host it hasn't tried yet.
+	policy := retryPolicy("cluster_shedding_default_default")
+	assert.Equal(t, "retriable-headers", policy.RetryOn)
+	assert.Equal(t, uint32(2), policy.GetNumRetries().GetValue())
+	require.Len(t, policy.RetriableHeaders, 2)
+	assert.Equal(t, "x-shed-load", policy.RetriableHeaders[0].Name)
+	assert.True(t, policy.RetriableHeaders[0].GetPresentMatch())
+	assert.Equal(t, "x-upstream-overloaded", policy.RetriableHeaders[1].Name)
+	assert.Equal(t, "true", policy.RetriableHeaders[1].GetExactMatch())
+	require.Len(t, policy.RetryHostPredicate, 1)
+	assert.Equal(t, "envoy.retry_host_predicates.previous_hosts", policy.RetryHostPredicate[0].Name)
+
+	// both adds the response headers to its other conditions...
+	policy = retryPolicy("cluster_both_default_default")
+	assert.Equal(t, "5xx,retriable-headers", policy.RetryOn)
+	require.Len(t, policy.RetriableHeaders, 1)
+	assert.Equal(t, "x-retry-elsewhere", policy.RetriableHeaders[0].Name)
+	assert.Equal(t, "yes", policy.RetriableHeaders[0].GetExactMatch())
+
+	// ...while plain doesn't look at response headers at all.
+	policy = retryPolicy("cluster_plain_default_default")
+	assert.Equal(t, "5xx", policy.RetryOn)
+	assert.Empty(t, policy.RetriableHeaders)
+}
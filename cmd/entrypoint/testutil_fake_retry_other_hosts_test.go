@@ -0,0 +1,70 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryOtherHosts(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: spread
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /spread/
+  service: spread.default
+  retry_policy:
+    retry_on: 5xx
+    num_retries: 3
+    retry_other_hosts: true
+    host_selection_retry_max_attempts: 5
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: same-host
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /same-host/
+  service: same-host.default
+  retry_policy:
+    retry_on: 5xx
+    host_selection_retry_max_attempts: 5
+`, "same-host", "cluster_same_host_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	retryPolicy := func(cluster string) *route.RetryPolicy {
+		routeAction := findVirtualHostRoute(listener, func(r *route.RouteAction) bool {
+			return r.GetCluster() == cluster
+		})
+		require.NotNil(t, routeAction)
+		require.NotNil(t, routeAction.RetryPolicy)
+		return routeAction.RetryPolicy
+	}
+
+	policy := retryPolicy("cluster_spread_default_default")
+	require.Len(t, policy.RetryHostPredicate, 1)
+	assert.Equal(t, "envoy.retry_host_predicates.previous_hosts", policy.RetryHostPredicate[0].Name)
+	assert.Equal(t, int64(5), policy.HostSelectionRetryMaxAttempts)
+
+	// Without retry_other_hosts there's no predicate, so the max attempts are dropped too.
+	policy = retryPolicy("cluster_same_host_default_default")
+	assert.Empty(t, policy.RetryHostPredicate)
+	assert.Equal(t, int64(0), policy.HostSelectionRetryMaxAttempts)
+}
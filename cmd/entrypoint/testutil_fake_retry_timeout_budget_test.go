@@ -0,0 +1,82 @@
+package entrypoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryTimeoutBudget(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: budget
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /budget/
+  service: budget.default
+  timeout_ms: 5000
+  retry_policy:
+    retry_on: 5xx
+    num_retries: 3
+    per_try_timeout: 2s
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: overbudget
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /overbudget/
+  service: overbudget.default
+  timeout_ms: 5000
+  retry_policy:
+    retry_on: 5xx
+    num_retries: 3
+    per_try_timeout: 10s
+`, "overbudget", "cluster_overbudget_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// The route's timeout covers every try, so with 2s tries, three retries only get as far as
+	// the 5s budget allows.
+	r := findRoute(listener, func(r *route.Route) bool {
+		return r.GetRoute().GetCluster() == "cluster_budget_default_default"
+	})
+	require.NotNil(t, r)
+	assert.Equal(t, 5*time.Second, r.GetRoute().GetTimeout().AsDuration())
+	retry := r.GetRoute().GetRetryPolicy()
+	require.NotNil(t, retry)
+	assert.Equal(t, uint32(3), retry.GetNumRetries().GetValue())
+	assert.Equal(t, 2*time.Second, retry.GetPerTryTimeout().AsDuration())
+
+	// A try that's allowed longer than the whole request still gets configured, but the route's
+	// timeout is what cuts it off.
+	r = findRoute(listener, func(r *route.Route) bool {
+		return r.GetRoute().GetCluster() == "cluster_overbudget_default_default"
+	})
+	require.NotNil(t, r)
+	assert.Equal(t, 5*time.Second, r.GetRoute().GetTimeout().AsDuration())
+	assert.Equal(t, 10*time.Second, r.GetRoute().GetRetryPolicy().GetPerTryTimeout().AsDuration())
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Mapping("overbudget") != nil && len(diag.NoticesFor("overbudget.default")) > 0
+	})
+	require.NoError(t, err)
+	assert.True(t, hasNotice(diag, "overbudget.default", "per_try_timeout of 10000ms is longer than timeout_ms 5000"))
+	assert.False(t, hasNotice(diag, "budget.default", "per_try_timeout"))
+}
@@ -0,0 +1,132 @@
+package entrypoint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// routeOrder returns the position of the first route to each cluster whose name starts with
+// prefix, in the order Envoy will check them.
+func routeOrder(listener *v3listener.Listener, prefix string) map[string]int {
+	order := map[string]int{}
+	idx := 0
+	findRoute(listener, func(r *route.Route) bool {
+		cluster := r.GetRoute().GetCluster()
+		if strings.HasPrefix(cluster, prefix) {
+			if _, seen := order[cluster]; !seen {
+				order[cluster] = idx
+			}
+		}
+		idx++
+		return false
+	})
+	return order
+}
+
+func TestRoutePrecedenceByPrefixLength(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: api
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /api/
+  service: prec-api
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: api-v1
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /api/v1/
+  service: prec-api-v1
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: plain
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /hdr/
+  service: prec-plain
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: canary
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /hdr/
+  service: prec-canary
+  headers:
+    x-canary: "true"
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: literal
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /re/abc/
+  service: prec-literal
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: regex
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: "/re/[a-z]+/"
+  prefix_regex: true
+  service: prec-regex
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: pinned
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /re/
+  service: prec-pinned
+  precedence: 10
+`, "pinned", "cluster_prec_pinned_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	order := routeOrder(listener, "cluster_prec_")
+	require.Len(t, order, 7)
+
+	// The longer prefix is checked first, with no precedence needed...
+	assert.Less(t, order["cluster_prec_api_v1_default"], order["cluster_prec_api_default"])
+
+	// ...equal prefixes go by how much else they match on...
+	assert.Less(t, order["cluster_prec_canary_default"], order["cluster_prec_plain_default"])
+
+	// ...a regex counts by the length of its pattern...
+	assert.Less(t, order["cluster_prec_regex_default"], order["cluster_prec_literal_default"])
+
+	// ...and explicit precedence beats all of it.
+	assert.Less(t, order["cluster_prec_pinned_default"], order["cluster_prec_regex_default"])
+	assert.Less(t, order["cluster_prec_pinned_default"], order["cluster_prec_api_v1_default"])
+}
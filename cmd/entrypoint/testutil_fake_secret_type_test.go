@@ -0,0 +1,231 @@
+package entrypoint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hasError returns whether any of diagd's errors for the resource with the given key contains
+// substr.
+func hasError(diag *entrypoint.Diagnostics, key, substr string) bool {
+	for _, e := range diag.Errors {
+		if len(e) == 2 && e[0] == key && strings.Contains(e[1], substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSecretTypeMismatch(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: example-cert
+  namespace: default
+type: kubernetes.io/tls
+data:
+  tls.crt: bm90LWEtcmVhbC1yc2EtY2VydA==
+  tls.key: ` + rsaKeyPEM + `
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: opaque-cert
+  namespace: default
+type: Opaque
+data:
+  tls.crt: bm90LWEtcmVhbC1yc2EtY2VydA==
+  tls.key: ` + rsaKeyPEM + `
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: registry-creds
+  namespace: default
+type: kubernetes.io/dockerconfigjson
+data:
+  .dockerconfigjson: e30=
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db-password
+  namespace: default
+type: Opaque
+data:
+  password: aHVudGVyMg==
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: half-cert
+  namespace: default
+type: kubernetes.io/tls
+data:
+  tls.crt: bm90LWEtcmVhbC1yc2EtY2VydA==
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: key-only
+  namespace: default
+type: kubernetes.io/tls
+data:
+  tls.key: ` + rsaKeyPEM + `
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: client-ca
+  namespace: default
+type: Opaque
+data:
+  ca.crt: bm90LWEtcmVhbC1yc2EtY2VydA==
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: ambassador-listener-8443
+  namespace: default
+spec:
+  port: 8443
+  protocol: HTTPS
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: secure
+  namespace: default
+spec:
+  hostname: secure.example.com
+  tlsSecret:
+    name: example-cert
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: opaque
+  namespace: default
+spec:
+  hostname: opaque.example.com
+  tlsSecret:
+    name: opaque-cert
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: registry
+  namespace: default
+spec:
+  hostname: registry.example.com
+  tlsSecret:
+    name: registry-creds
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: database
+  namespace: default
+spec:
+  hostname: database.example.com
+  tlsSecret:
+    name: db-password
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: pending
+  namespace: default
+spec:
+  hostname: pending.example.com
+  tlsSecret:
+    name: not-issued-yet
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: half
+  namespace: default
+spec:
+  hosts:
+  - half.example.com
+  secret: half-cert
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: keyonly
+  namespace: default
+spec:
+  hosts:
+  - keyonly.example.com
+  secret: key-only
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: mtls
+  namespace: default
+spec:
+  hosts:
+  - mtls.example.com
+  secret: example-cert
+  ca_secret: client-ca
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: app
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /app/
+  service: app.default
+`)
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "app"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Error("registry.default") != "" && diag.Error("mtls.default") != ""
+	})
+	require.NoError(t, err)
+
+	// Something that isn't a TLS secret at all says what it is and what it should be...
+	assert.True(t, hasError(diag, "registry.default",
+		"invalid TLS secret registry-creds: it has type kubernetes.io/dockerconfigjson, but a certificate needs type kubernetes.io/tls"))
+	// ...and so does an Opaque secret with nothing TLS about it...
+	assert.True(t, hasError(diag, "database.default",
+		"invalid TLS secret db-password: it has type Opaque, but a certificate needs type kubernetes.io/tls"))
+	// ...but an Opaque secret with a certificate and key in it is fine, as ever.
+	assert.Empty(t, diag.Error("opaque.default"))
+	assert.Empty(t, diag.Error("secure.default"))
+
+	// A secret that doesn't exist might just not be issued yet, so that's not a mismatch.
+	assert.Empty(t, diag.Error("pending.default"))
+
+	// A TLS secret missing either half says which.
+	assert.True(t, hasError(diag, "half.default",
+		"TLSContext half found no private key in secret half-cert in namespace default: it has type kubernetes.io/tls but no tls.key"))
+	assert.True(t, hasError(diag, "keyonly.default",
+		"TLSContext keyonly found no certificate in secret key-only in namespace default: it has type kubernetes.io/tls but no tls.crt, ignoring..."))
+
+	// A CA secret doesn't need a key, but it does need its certificate where we look for it.
+	assert.True(t, hasError(diag, "mtls.default",
+		"TLSContext mtls found no validation certificate in secret client-ca in namespace default: it has type Opaque but no tls.crt (the CA certificate goes in tls.crt, not ca.crt)"))
+}
@@ -0,0 +1,95 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShadowOnCircuitBreak(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: checkout
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /checkout/
+  service: checkout.default
+  circuit_breakers:
+  - max_requests: 1000
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: checkout-next
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /checkout/
+  service: checkout-next.default
+  shadow: true
+  shadow_on_circuit_break:
+    max_requests: 25
+`+entrypoint.FakeMappingYAML("cart")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: cart-next
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /cart/
+  service: cart-next.default
+  shadow: true
+  shadow_runtime_key: mirror.cart
+  shadow_on_circuit_break:
+    runtime_key: mirror.cart.tripped
+`, "cart-next", "cluster_shadow_checkout_next_default", "cluster_cart_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// checkout mirrors nothing until the runtime key, named for the shadow Mapping, says
+	// otherwise.
+	routeAction := findVirtualHostRoute(listener, func(r *route.RouteAction) bool {
+		return r.GetCluster() == "cluster_checkout_default_default"
+	})
+	require.NotNil(t, routeAction)
+	require.Len(t, routeAction.RequestMirrorPolicies, 1)
+	mirror := routeAction.RequestMirrorPolicies[0]
+	assert.Contains(t, mirror.Cluster, "cluster_shadow_checkout_next_default")
+	require.NotNil(t, mirror.RuntimeFraction)
+	assert.Equal(t, "shadow_on_circuit_break.checkout-next.default", mirror.RuntimeFraction.RuntimeKey)
+	assert.Equal(t, uint32(0), mirror.RuntimeFraction.DefaultValue.Numerator)
+
+	// Once it's on, everything the tripped primary can't take is still mirrored, so the
+	// shadow only takes so much of it at once.
+	shadow := FindCluster(config, ClusterNameContains("cluster_shadow_checkout_next_default"))
+	require.NotNil(t, shadow)
+	thresholds := shadow.GetCircuitBreakers().GetThresholds()
+	require.Len(t, thresholds, 1)
+	assert.Equal(t, uint32(25), thresholds[0].GetMaxRequests().GetValue())
+	assert.Equal(t, uint32(25), thresholds[0].GetMaxPendingRequests().GetValue())
+	assert.Equal(t, uint32(0), thresholds[0].GetMaxRetries().GetValue())
+
+	// Two runtime keys for one mirror can't both work, so cart-next is rejected and cart
+	// doesn't mirror at all.
+	assert.Nil(t, FindCluster(config, ClusterNameContains("cluster_shadow_cart_next_default")))
+	routeAction = findVirtualHostRoute(listener, func(r *route.RouteAction) bool {
+		return r.GetCluster() == "cluster_cart_default_default"
+	})
+	require.NotNil(t, routeAction)
+	assert.Empty(t, routeAction.RequestMirrorPolicies)
+}
@@ -0,0 +1,120 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShadowCloneCluster(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: reports
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /reports/
+  service: reports.default
+  tls: true
+  connect_timeout_ms: 7000
+  circuit_breakers:
+  - max_requests: 500
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: reports-next
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /reports/
+  service: reports-next.default
+  shadow: true
+  shadow_clone_cluster: true
+  connect_timeout_ms: 2000
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: search
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /search/
+  service: search.default
+  tls: true
+  weight: 50
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: search-canary
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /search/
+  service: search-canary.default
+  weight: 50
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: search-next
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /search/
+  service: search-next.default
+  shadow: true
+  shadow_clone_cluster: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: billing-next
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /billing/
+  service: billing-next.default
+  shadow: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: billing
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /billing/
+  service: billing.default
+  tls: true
+`, "billing", "cluster_shadow_reports_next_default", "cluster_shadow_search_next_default", "cluster_shadow_billing_next_default")
+
+	// reports-next points somewhere else entirely, but talks to it the way reports talks to
+	// its own service: TLS and the same circuit breakers, with the connect timeout it asked for.
+	shadow := FindCluster(config, ClusterNameContains("cluster_shadow_reports_next_default"))
+	require.NotNil(t, shadow)
+	assert.NotNil(t, shadow.TransportSocket)
+	assert.Equal(t, int64(2), shadow.ConnectTimeout.GetSeconds())
+	thresholds := shadow.GetCircuitBreakers().GetThresholds()
+	require.Len(t, thresholds, 1)
+	assert.Equal(t, uint32(500), thresholds[0].GetMaxRequests().GetValue())
+
+	// The search canaries disagree about TLS, so search-next doesn't get it from either.
+	shadow = FindCluster(config, ClusterNameContains("cluster_shadow_search_next_default"))
+	require.NotNil(t, shadow)
+	assert.Nil(t, shadow.TransportSocket)
+
+	// billing-next didn't ask, so it's the same plain cluster it always was.
+	shadow = FindCluster(config, ClusterNameContains("cluster_shadow_billing_next_default"))
+	require.NotNil(t, shadow)
+	assert.Nil(t, shadow.TransportSocket)
+}
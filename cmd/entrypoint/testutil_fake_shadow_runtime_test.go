@@ -0,0 +1,71 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShadowRuntimeKey(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("foo")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: foo-shadow
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /foo/
+  service: foo-shadow.default
+  shadow: true
+  weight: 10
+  shadow_runtime_key: mirror.foo
+`+entrypoint.FakeMappingYAML("bar")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: bar-shadow
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /bar/
+  service: bar-shadow.default
+  shadow: true
+  weight: 0
+`, "bar-shadow", "cluster_shadow_bar_shadow_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// foo mirrors 10% by default, but the runtime key can override that.
+	routeAction := findVirtualHostRoute(listener, func(r *route.RouteAction) bool {
+		return r.GetCluster() == "cluster_foo_default_default"
+	})
+	require.NotNil(t, routeAction)
+	require.Len(t, routeAction.RequestMirrorPolicies, 1)
+	fraction := routeAction.RequestMirrorPolicies[0].RuntimeFraction
+	require.NotNil(t, fraction)
+	assert.Equal(t, "mirror.foo", fraction.RuntimeKey)
+	assert.Equal(t, uint32(10), fraction.DefaultValue.Numerator)
+
+	// bar mirrors nothing, and has no runtime key.
+	routeAction = findVirtualHostRoute(listener, func(r *route.RouteAction) bool {
+		return r.GetCluster() == "cluster_bar_default_default"
+	})
+	require.NotNil(t, routeAction)
+	require.Len(t, routeAction.RequestMirrorPolicies, 1)
+	fraction = routeAction.RequestMirrorPolicies[0].RuntimeFraction
+	require.NotNil(t, fraction)
+	assert.Empty(t, fraction.RuntimeKey)
+	assert.Equal(t, uint32(0), fraction.DefaultValue.Numerator)
+}
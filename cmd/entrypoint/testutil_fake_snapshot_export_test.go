@@ -0,0 +1,249 @@
+package entrypoint_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	"github.com/datawire/ambassador/v2/pkg/snapshot/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readExport decodes the snapshot exported to path.
+func readExport(t *testing.T, path string) *snapshot.Snapshot {
+	t.Helper()
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var snap *snapshot.Snapshot
+	require.NoError(t, json.Unmarshal(contents, &snap))
+	return snap
+}
+
+func TestSnapshotExport(t *testing.T) {
+	// Exports go in the export directory in the snapshot dir, and only there.
+	dir := t.TempDir()
+	t.Setenv("snapshot_dir", dir)
+	path := filepath.Join(dir, "export", "snapshot.json")
+
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    snapshot_export:
+      path: snapshot.json
+      interval_s: 0
+      keep: 2
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: example-cert
+  namespace: default
+type: kubernetes.io/tls
+data:
+  tls.crt: bm90LWEtcmVhbC1jZXJ0
+  tls.key: bm90LWEtcmVhbC1rZXk=
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: example
+  namespace: default
+spec:
+  hostname: example.com
+  tlsSecret:
+    name: example-cert
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: quote-0
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /quote-0/
+  service: quote.default
+`)
+	require.NoError(t, err)
+
+	f.Flush()
+	_, err = f.GetSnapshot(HasMapping("default", "quote-0"))
+	require.NoError(t, err)
+
+	// The export has everything in the snapshot but what's in its secrets, which only keep
+	// their keys.
+	exported := readExport(t, path)
+	require.NotNil(t, exported.Kubernetes)
+	require.Len(t, exported.Kubernetes.Mappings, 1)
+	assert.Equal(t, "quote-0", exported.Kubernetes.Mappings[0].Name)
+	require.Len(t, exported.Kubernetes.Secrets, 1)
+	assert.Equal(t, "example-cert", exported.Kubernetes.Secrets[0].Name)
+	assert.Equal(t, map[string][]byte{
+		"tls.crt": []byte("<REDACTED>"),
+		"tls.key": []byte("<REDACTED>"),
+	}, exported.Kubernetes.Secrets[0].Data)
+
+	raw, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "bm90LWEtcmVhbC1rZXk=")
+	assert.NotContains(t, string(raw), "not-a-real-key")
+
+	// Every new snapshot moves the earlier exports along, and only two of them are kept.
+	for i := 1; i <= 3; i++ {
+		name := fmt.Sprintf("quote-%d", i)
+		require.NoError(t, f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: `+name+`
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /`+name+`/
+  service: quote.default
+`))
+		f.Flush()
+		_, err = f.GetSnapshot(HasMapping("default", name))
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, readExport(t, path).Kubernetes.Mappings, 4)
+	assert.Len(t, readExport(t, path+".1").Kubernetes.Mappings, 3)
+	assert.Len(t, readExport(t, path+".2").Kubernetes.Mappings, 2)
+	assert.NoFileExists(t, path+".3")
+	assert.NoFileExists(t, path+".tmp")
+
+	// Exporting on demand goes to the same place, and moves everything along again.
+	exportedTo, err := f.ExportSnapshot()
+	require.NoError(t, err)
+	assert.Equal(t, path, exportedTo)
+	assert.Len(t, readExport(t, path).Kubernetes.Mappings, 4)
+	assert.Len(t, readExport(t, path+".1").Kubernetes.Mappings, 4)
+	assert.Len(t, readExport(t, path+".2").Kubernetes.Mappings, 3)
+	assert.NoFileExists(t, path+".3")
+}
+
+func TestSnapshotExportNotConfigured(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{}, nil)
+	require.NoError(t, f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: quote
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /quote/
+  service: quote.default
+`))
+	f.Flush()
+	_, err := f.GetSnapshot(HasMapping("default", "quote"))
+	require.NoError(t, err)
+
+	// Without snapshot_export there's nowhere to export to.
+	_, err = f.ExportSnapshot()
+	assert.EqualError(t, err, "the ambassador Module has no snapshot_export path")
+}
+
+func TestSnapshotExportOutsideExportDir(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("snapshot_dir", filepath.Join(dir, "snapshots"))
+
+	for _, path := range []string{filepath.Join(dir, "elsewhere.json"), "../elsewhere.json", ".."} {
+		t.Run(path, func(t *testing.T) {
+			f := entrypoint.RunFake(t, entrypoint.FakeConfig{}, nil)
+			require.NoError(t, f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    snapshot_export:
+      path: "`+path+`"
+      interval_s: 0
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: quote
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /quote/
+  service: quote.default
+`))
+			f.Flush()
+			_, err := f.GetSnapshot(HasMapping("default", "quote"))
+			require.NoError(t, err)
+
+			// A path with a directory in it could write anywhere we can, so nothing gets
+			// written at all.
+			_, err = f.ExportSnapshot()
+			assert.EqualError(t, err, fmt.Sprintf("snapshot_export path %q must be a file name, with no directory", path))
+			assert.NoFileExists(t, filepath.Join(dir, "elsewhere.json"))
+			assert.NoDirExists(t, filepath.Join(dir, "snapshots", "export"))
+		})
+	}
+}
+
+func TestSnapshotExportDefaultInterval(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("snapshot_dir", dir)
+	path := filepath.Join(dir, "export", "snapshot.json")
+
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{}, nil)
+	mapping := func(name string) string {
+		return `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: ` + name + `
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /` + name + `/
+  service: quote.default
+`
+	}
+	require.NoError(t, f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    snapshot_export:
+      path: snapshot.json
+`+mapping("quote-0")))
+	f.Flush()
+	_, err := f.GetSnapshot(HasMapping("default", "quote-0"))
+	require.NoError(t, err)
+	assert.Len(t, readExport(t, path).Kubernetes.Mappings, 1)
+
+	// Without an interval_s, the next snapshot isn't exported until a minute has gone by.
+	require.NoError(t, f.UpsertYAML(mapping("quote-1")))
+	f.Flush()
+	_, err = f.GetSnapshot(HasMapping("default", "quote-1"))
+	require.NoError(t, err)
+	assert.Len(t, readExport(t, path).Kubernetes.Mappings, 1)
+	assert.NoFileExists(t, path+".1")
+}
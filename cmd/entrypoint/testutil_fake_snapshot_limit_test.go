@@ -0,0 +1,132 @@
+package entrypoint_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	snapshot "github.com/datawire/ambassador/v2/pkg/snapshot/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// limitModule is an ambassador Module with a small soft limit, shedding or not.
+func limitModule(shed bool) string {
+	return fmt.Sprintf(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    snapshot_soft_limit_bytes: 16384
+    snapshot_shed: %t
+`, shed)
+}
+
+// manyMappings is a config with enough Mappings to be over any small soft limit.
+func manyMappings(count int) string {
+	var yaml strings.Builder
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&yaml, `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: tenant-%d
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /tenant-%d/
+  service: tenant-%d.default
+`, i, i, i)
+	}
+	return yaml.String()
+}
+
+func TestSnapshotSoftLimitWarnOnly(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	require.NoError(t, f.UpsertYAML(limitModule(false)+manyMappings(50)))
+	f.Flush()
+
+	// Without snapshot_shed, being over the limit is only a warning: the snapshot
+	// goes out whole, deltas and all.
+	snap, err := f.GetSnapshot(HasMapping("default", "tenant-49"))
+	require.NoError(t, err)
+	assert.Len(t, snap.Kubernetes.Mappings, 50)
+	assert.NotEmpty(t, snap.Deltas)
+}
+
+func TestSnapshotSoftLimitShedding(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	require.NoError(t, f.UpsertYAML(limitModule(true)+manyMappings(50)+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: broken
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /broken/
+  service: broken.default
+  redirect_response_code: 399
+`))
+	f.Flush()
+
+	// No amount of shedding touches anything that routes: even though the snapshot is still
+	// over the limit, every Mapping is there...
+	snap, err := f.GetSnapshot(HasMapping("default", "tenant-49"))
+	require.NoError(t, err)
+	assert.Len(t, snap.Kubernetes.Mappings, 50)
+
+	// ...invalid resources are kept, so diagd can still report what's wrong with them...
+	require.Len(t, snap.Invalid, 1)
+	assert.Equal(t, "broken", snap.Invalid[0].GetName())
+
+	// ...and makes it all the way to Envoy.
+	_, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_tenant_49_default_default")) != nil &&
+			FindCluster(config, ClusterNameContains("cluster_tenant_0_default_default")) != nil
+	})
+	require.NoError(t, err)
+
+	// Deltas are never shed either, since they're what tells diagd which of its cached config
+	// is stale: a Mapping that's edited while the snapshot is over the limit still has its
+	// edit make it to Envoy.
+	require.NoError(t, f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: tenant-0
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /tenant-0/
+  service: tenant-zero.default
+`))
+	f.Flush()
+
+	snap, err = f.GetSnapshot(func(snap *snapshot.Snapshot) bool {
+		for _, m := range snap.Kubernetes.Mappings {
+			if m.Name == "tenant-0" && m.Spec.Service == "tenant-zero.default" {
+				return true
+			}
+		}
+		return false
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, snap.Deltas)
+
+	_, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_tenant_zero_default_default")) != nil &&
+			FindCluster(config, ClusterNameContains("cluster_tenant_0_default_default")) == nil
+	})
+	require.NoError(t, err)
+}
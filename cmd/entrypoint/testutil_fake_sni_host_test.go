@@ -0,0 +1,131 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	http "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// findRoute returns the first route in a listener that satisfies the predicate. Unlike
+// findVirtualHostRoute, it returns the whole Route, not just its RouteAction.
+func findRoute(listener *v3listener.Listener, predicate func(*route.Route) bool) *route.Route {
+	for _, fc := range listener.FilterChains {
+		hcm := entrypoint.ChainHCM(fc)
+		if hcm == nil {
+			continue
+		}
+		if rs, ok := hcm.RouteSpecifier.(*http.HttpConnectionManager_RouteConfig); ok {
+			for _, vh := range rs.RouteConfig.VirtualHosts {
+				for _, r := range vh.Routes {
+					if predicate(r) {
+						return r
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func TestHostRewriteFromSNI(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: tenant
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /tenant/
+  service: tenant.default
+  host_rewrite_from_sni: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: literal
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /literal/
+  service: literal.default
+  host_rewrite: backend.example.com
+  host_rewrite_from_sni: true
+`, "literal", "cluster_literal_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	clusterIs := func(name string) func(*route.Route) bool {
+		return func(r *route.Route) bool {
+			return r.GetRoute().GetCluster() == name
+		}
+	}
+
+	// The SNI is copied into a header, and the Host is rewritten from that header.
+	r := findRoute(listener, clusterIs("cluster_tenant_default_default"))
+	require.NotNil(t, r)
+	header := r.GetRoute().GetHostRewriteHeader()
+	require.NotEmpty(t, header)
+
+	var sniValue string
+	for _, h := range r.RequestHeadersToAdd {
+		if h.Header.Key == header {
+			sniValue = h.Header.Value
+		}
+	}
+	assert.Equal(t, "%REQUESTED_SERVER_NAME%", sniValue)
+
+	// An explicit host_rewrite wins.
+	r = findRoute(listener, clusterIs("cluster_literal_default_default"))
+	require.NotNil(t, r)
+	assert.Equal(t, "backend.example.com", r.GetRoute().GetHostRewriteLiteral())
+	assert.Empty(t, r.GetRoute().GetHostRewriteHeader())
+}
+
+func TestHostRewriteFromSNISpoofed(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenersYAML+entrypoint.FakeMappingYAML("tenant")+`
+  host_rewrite_from_sni: true
+  remove_request_headers:
+  - x-internal-only
+`, "tenant", "cluster_tenant_default_default")
+
+	// A client that sends x-ambassador-sni itself -- say over cleartext, where there's no SNI
+	// to overwrite it with -- mustn't get to pick the Host. On every listener, the route
+	// removes the header before setting it, which Envoy does in that order, and the Mapping's
+	// own headers to remove are still removed too.
+	for _, name := range []string{"ambassador-listener-8080", "ambassador-listener-8443"} {
+		listener := findListener(config, func(l *v3listener.Listener) bool {
+			return l.Name == name
+		})
+		require.NotNil(t, listener, name)
+
+		r := findRoute(listener, func(r *route.Route) bool {
+			return r.GetRoute().GetCluster() == "cluster_tenant_default_default"
+		})
+		require.NotNil(t, r, name)
+		require.Equal(t, "x-ambassador-sni", r.GetRoute().GetHostRewriteHeader(), name)
+		assert.ElementsMatch(t, []string{"x-internal-only", "x-ambassador-sni"}, r.RequestHeadersToRemove, name)
+
+		var set bool
+		for _, h := range r.RequestHeadersToAdd {
+			if h.Header.Key == "x-ambassador-sni" {
+				set = true
+				assert.Equal(t, "%REQUESTED_SERVER_NAME%", h.Header.Value, name)
+				assert.False(t, h.GetAppend().GetValue(), name)
+			}
+		}
+		assert.True(t, set, name)
+	}
+}
@@ -0,0 +1,89 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSPIFFEValidation(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: spire-bundle
+  namespace: default
+type: kubernetes.io/tls
+data:
+  tls.crt: bm90LWEtcmVhbC1jZXJ0
+  tls.key: bm90LWEtcmVhbC1rZXk=
+`+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: payments-peer
+  namespace: default
+spec:
+  ca_secret: spire-bundle
+  spiffe_trust_domain: example.org
+  spiffe_ids:
+  - spiffe://example.org/ns/payments/sa/api
+  - spiffe://example.org/ns/*/sa/payments-worker
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: mesh-peer
+  namespace: default
+spec:
+  ca_secret: spire-bundle
+  spiffe_trust_domain: example.org
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: payments
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /payments/
+  service: https://payments.default
+  tls: payments-peer
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: ledger
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /ledger/
+  service: https://ledger.default
+  tls: mesh-peer
+`, "ledger", "cluster_https___ledger_default_otls_mesh_peer")
+
+	// Named IDs match exactly, and a wildcard segment stands in for exactly one segment.
+	cluster := FindCluster(config, ClusterNameContains("cluster_https___payments_default_otls_payments_peer"))
+	require.NotNil(t, cluster)
+	validation := upstreamTLSContext(t, cluster).CommonTlsContext.GetValidationContext()
+	require.NotNil(t, validation)
+	require.NotNil(t, validation.TrustedCa)
+	matchers := validation.MatchSubjectAltNames
+	require.Len(t, matchers, 2)
+	assert.Equal(t, "spiffe://example.org/ns/payments/sa/api", matchers[0].GetExact())
+	assert.Equal(t, `^spiffe://example\.org/ns/[^/]+/sa/payments\-worker$`, matchers[1].GetSafeRegex().GetRegex())
+
+	// A trust domain on its own takes any workload in it.
+	cluster = FindCluster(config, ClusterNameContains("cluster_https___ledger_default_otls_mesh_peer"))
+	require.NotNil(t, cluster)
+	validation = upstreamTLSContext(t, cluster).CommonTlsContext.GetValidationContext()
+	require.NotNil(t, validation)
+	require.Len(t, validation.MatchSubjectAltNames, 1)
+	assert.Equal(t, "spiffe://example.org/", validation.MatchSubjectAltNames[0].GetPrefix())
+}
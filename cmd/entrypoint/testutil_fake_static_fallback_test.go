@@ -0,0 +1,142 @@
+package entrypoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	responsemap "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/response_map/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticFallback(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    error_response_overrides:
+    - on_status_code: 503
+      body:
+        json_format:
+          error: "unavailable"
+` + entrypoint.FakeListenerYAML + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: catalog
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /catalog/
+  service: catalog.default
+  resolver: endpoint
+  static_fallback:
+    status_code: 200
+    body: '{"items": [], "degraded": true}'
+    content_type: application/json
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: status
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /status/
+  service: status.default
+  bypass_error_response_overrides: true
+  static_fallback:
+    body: 'Down for maintenance'
+` + entrypoint.FakeMappingYAML("plain"))
+	require.NoError(t, err)
+	require.NoError(t, f.Upsert(makeService("default", "catalog")))
+	require.NoError(t, f.Upsert(makeEndpoints("default", "catalog")))
+	f.Flush()
+
+	snap, err := f.GetSnapshot(HasMapping("default", "plain"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_plain_default_default")) != nil
+	})
+	require.NoError(t, err)
+
+	// catalog's service has scaled to nothing, so its cluster has no endpoints at all.
+	cluster := FindCluster(config, ClusterNameContains("cluster_catalog_default_default"))
+	require.NotNil(t, cluster)
+	require.NotNil(t, cluster.EdsClusterConfig)
+	serviceName := cluster.EdsClusterConfig.ServiceName
+	assignments, err := f.GetLoadAssignments(hasLoadAssignmentIPs(serviceName))
+	require.NoError(t, err)
+	require.Contains(t, assignments, serviceName)
+	assert.Empty(t, loadAssignmentIPs(assignments[serviceName]))
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	perRoute := func(cluster string) *responsemap.ResponseMapPerRoute {
+		r := findRoute(listener, func(r *route.Route) bool {
+			return r.GetRoute().GetCluster() == cluster
+		})
+		require.NotNil(t, r)
+		cfg, ok := r.TypedPerFilterConfig["envoy.filters.http.response_map"]
+		if !ok {
+			return nil
+		}
+		rm := &responsemap.ResponseMapPerRoute{}
+		require.NoError(t, ptypes.UnmarshalAny(cfg, rm))
+		return rm
+	}
+
+	// Without a healthy endpoint, Envoy answers for catalog itself. The Module's override
+	// still comes after it, for catalog's own 503s...
+	rm := perRoute("cluster_catalog_default_default")
+	require.NotNil(t, rm)
+	mappers := rm.GetResponseMap().GetMappers()
+	require.Len(t, mappers, 2)
+	assert.Equal(t, []string{"UH"}, mappers[0].GetFilter().GetResponseFlagFilter().GetFlags())
+	assert.Equal(t, uint32(200), mappers[0].GetStatusCode().GetValue())
+	assert.Equal(t, `{"items": [], "degraded": true}`, mappers[0].GetBody().GetInlineString())
+	assert.Equal(t, "application/json", mappers[0].GetBodyFormatOverride().GetContentType())
+	assert.NotNil(t, mappers[1].GetFilter().GetStatusCodeFilter())
+
+	// ...while status bypasses the overrides, but not its own fallback.
+	rm = perRoute("cluster_status_default_default")
+	require.NotNil(t, rm)
+	assert.False(t, rm.GetDisabled())
+	mappers = rm.GetResponseMap().GetMappers()
+	require.Len(t, mappers, 1)
+	assert.Equal(t, uint32(503), mappers[0].GetStatusCode().GetValue())
+	assert.Equal(t, "Down for maintenance", mappers[0].GetBody().GetInlineString())
+	assert.Equal(t, "text/plain", mappers[0].GetBodyFormatOverride().GetContentType())
+
+	// plain has no fallback, and leaves the Module's overrides to the filter.
+	assert.Nil(t, perRoute("cluster_plain_default_default"))
+
+	// When catalog comes back, there's nothing to change but the endpoints: with a healthy
+	// one to send to, Envoy never gets to the fallback.
+	subset, err := makeSubset(8080, "10.0.0.1")
+	require.NoError(t, err)
+	require.NoError(t, f.Upsert(makeEndpoints("default", "catalog", subset)))
+	f.Flush()
+	_, err = f.GetLoadAssignments(hasLoadAssignmentIPs(serviceName, "10.0.0.1"))
+	require.NoError(t, err)
+	f.AssertNoReconfigure(2 * time.Second)
+}
@@ -0,0 +1,154 @@
+package entrypoint_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	http "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// orderedVirtualClusters returns the virtual clusters of a listener's first virtual host, in
+// the order Envoy checks them.
+func orderedVirtualClusters(l *v3listener.Listener) []*route.VirtualCluster {
+	for _, fc := range l.FilterChains {
+		hcm := entrypoint.ChainHCM(fc)
+		if hcm == nil {
+			continue
+		}
+		if rs, ok := hcm.RouteSpecifier.(*http.HttpConnectionManager_RouteConfig); ok {
+			for _, vh := range rs.RouteConfig.VirtualHosts {
+				return vh.VirtualClusters
+			}
+		}
+	}
+	return nil
+}
+
+func TestStatsDimensions(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    stats_dimensions_limit: 20
+`+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: api
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /api/
+  service: api.default
+  stats_dimensions:
+  - name: method
+    header: ":method"
+    values: [GET, POST]
+  - name: tenant
+    header: X-Tenant
+    values: [acme, globex-corp]
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: wide
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /wide/
+  service: wide.default
+  stats_dimensions:
+  - name: tenant
+    header: x-tenant
+    values: [a, b, c, d, e]
+  - name: region
+    header: x-region
+    values: [us, eu, ap, sa]
+`, "wide", "cluster_wide_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// Envoy counts each request against the first virtual cluster it matches, so each value
+	// comes before "other", which is everything else -- including requests without the header.
+	// wide would need 30 of them, over the limit of 20, so it doesn't get any.
+	vcs := orderedVirtualClusters(listener)
+	var names []string
+	for _, vc := range vcs {
+		names = append(names, vc.Name)
+	}
+	assert.Equal(t, []string{
+		"api_default-method-GET-tenant-acme",
+		"api_default-method-GET-tenant-globex_corp",
+		"api_default-method-GET-tenant-other",
+		"api_default-method-POST-tenant-acme",
+		"api_default-method-POST-tenant-globex_corp",
+		"api_default-method-POST-tenant-other",
+		"api_default-method-other-tenant-acme",
+		"api_default-method-other-tenant-globex_corp",
+		"api_default-method-other-tenant-other",
+	}, names)
+
+	headers := func(vc *route.VirtualCluster) map[string]string {
+		values := map[string]string{}
+		for _, h := range vc.Headers {
+			if h.Name == ":path" {
+				assert.Equal(t, "/api/", h.GetPrefixMatch())
+				continue
+			}
+			values[h.Name] = h.GetExactMatch()
+		}
+		return values
+	}
+	assert.Equal(t, map[string]string{":method": "GET", "x-tenant": "globex-corp"}, headers(vcs[1]))
+	assert.Equal(t, map[string]string{":method": "POST"}, headers(vcs[5]))
+	assert.Equal(t, map[string]string{"x-tenant": "acme"}, headers(vcs[6]))
+	assert.Empty(t, headers(vcs[8]))
+
+	// Every dimension is a stats tag, which picks its value out of the virtual cluster's stats.
+	require.NotNil(t, config.StatsConfig)
+	tags := map[string]*regexp.Regexp{}
+	for _, tag := range config.StatsConfig.StatsTags {
+		tags[tag.TagName] = regexp.MustCompile(tag.GetRegex())
+	}
+	require.Contains(t, tags, "method")
+	require.Contains(t, tags, "tenant")
+	assert.NotContains(t, tags, "region")
+
+	extract := func(stat string) map[string]string {
+		values := map[string]string{}
+		for name, re := range tags {
+			if m := re.FindStringSubmatch(stat); m != nil {
+				values[name] = m[2]
+			}
+		}
+		return values
+	}
+	assert.Equal(t, map[string]string{"method": "POST", "tenant": "globex_corp"},
+		extract("vhost.ambassador-listener-8080-_.vcluster.api_default-method-POST-tenant-globex_corp.upstream_rq_2xx"))
+	assert.Equal(t, map[string]string{"method": "other", "tenant": "other"},
+		extract("vhost.ambassador-listener-8080-_.vcluster.api_default-method-other-tenant-other.upstream_rq_total"))
+	assert.Empty(t, extract("cluster.cluster_api_default_default.upstream_rq_total"))
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Mapping("wide") != nil && diag.Error("wide.default") != ""
+	})
+	require.NoError(t, err)
+	assert.Contains(t, diag.Error("wide.default"), "stats_dimensions would need 30 virtual clusters, over stats_dimensions_limit (20)")
+	assert.Empty(t, diag.Error("api.default"))
+}
@@ -0,0 +1,61 @@
+package entrypoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamingTimeouts(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: events
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /events/
+  service: events.default
+  timeout_ms: 0
+  idle_timeout_ms: 300000
+`+entrypoint.FakeMappingYAML("api"), "api", "cluster_api_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// timeout_ms: 0 must come through as a disabled timeout, not as the default, and the
+	// idle timeout must stay on the route rather than being folded into it.
+	routeAction := findVirtualHostRoute(listener, func(r *route.RouteAction) bool {
+		return r.GetCluster() == "cluster_events_default_default"
+	})
+	require.NotNil(t, routeAction)
+	require.NotNil(t, routeAction.Timeout)
+	assert.Equal(t, time.Duration(0), routeAction.Timeout.AsDuration())
+	require.NotNil(t, routeAction.IdleTimeout)
+	assert.Equal(t, 300*time.Second, routeAction.IdleTimeout.AsDuration())
+
+	// A Mapping without either gets the default request timeout and no idle timeout.
+	routeAction = findVirtualHostRoute(listener, func(r *route.RouteAction) bool {
+		return r.GetCluster() == "cluster_api_default_default"
+	})
+	require.NotNil(t, routeAction)
+	assert.Equal(t, 3*time.Second, routeAction.Timeout.AsDuration())
+	assert.Nil(t, routeAction.IdleTimeout)
+
+	// Neither one leaks into the connection manager.
+	hcm := entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+	assert.Nil(t, hcm.StreamIdleTimeout)
+	assert.Nil(t, hcm.RequestTimeout)
+}
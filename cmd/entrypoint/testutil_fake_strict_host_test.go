@@ -0,0 +1,137 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// findVirtualHost returns the virtual host on the listener that lists the given domain.
+func findVirtualHost(listener *v3listener.Listener, domain string) *route.VirtualHost {
+	hcm := entrypoint.ListenerHCM(listener)
+	if hcm == nil {
+		return nil
+	}
+	for _, vh := range hcm.GetRouteConfig().GetVirtualHosts() {
+		for _, d := range vh.Domains {
+			if d == domain {
+				return vh
+			}
+		}
+	}
+	return nil
+}
+
+func TestStrictHostMatching(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: ambassador-listener-8080
+  namespace: default
+spec:
+  port: 8080
+  protocol: HTTP
+  securityModel: INSECURE
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: known
+  namespace: default
+spec:
+  hostname: known.example.com
+  requestPolicy:
+    insecure:
+      action: Route
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: catch-all
+  namespace: default
+spec:
+  hostname: "*"
+  requestPolicy:
+    insecure:
+      action: Route
+` + entrypoint.FakeMappingYAML("foo"))
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "foo"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	listenerFrom := func(config *bootstrap.Bootstrap) *v3listener.Listener {
+		return findListener(config, func(l *v3listener.Listener) bool {
+			return l.Name == "ambassador-listener-8080"
+		})
+	}
+
+	catchAllRejects := func(config *bootstrap.Bootstrap) bool {
+		listener := listenerFrom(config)
+		if listener == nil {
+			return false
+		}
+		vh := findVirtualHost(listener, "*")
+		return vh != nil && len(vh.Routes) == 1 && vh.Routes[0].GetDirectResponse() != nil
+	}
+
+	// By default, the "*" Host catches any hostname and routes it like any other Host.
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("cluster_foo_default_default")) != nil
+	})
+	require.NoError(t, err)
+	assert.False(t, catchAllRejects(config))
+
+	vh := findVirtualHost(listenerFrom(config), "*")
+	require.NotNil(t, vh)
+	assert.NotEmpty(t, vh.Routes)
+	assert.Equal(t, "cluster_foo_default_default", vh.Routes[0].GetRoute().GetCluster())
+
+	// With strict matching, the "*" Host only rejects what nothing else matched.
+	err = f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    strict_host_matching: true
+    strict_host_matching_status: 421
+`)
+	require.NoError(t, err)
+	f.Flush()
+
+	config, err = f.GetEnvoyConfig(catchAllRejects)
+	require.NoError(t, err)
+
+	listener := listenerFrom(config)
+	vh = findVirtualHost(listener, "*")
+	require.NotNil(t, vh)
+	assert.Equal(t, "/", vh.Routes[0].GetMatch().GetPrefix())
+	assert.Equal(t, uint32(421), vh.Routes[0].GetDirectResponse().Status)
+
+	// Known hostnames still route.
+	vh = findVirtualHost(listener, "known.example.com")
+	require.NotNil(t, vh)
+	var clusters []string
+	for _, r := range vh.Routes {
+		clusters = append(clusters, r.GetRoute().GetCluster())
+	}
+	assert.Contains(t, clusters, "cluster_foo_default_default")
+}
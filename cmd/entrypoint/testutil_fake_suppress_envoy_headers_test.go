@@ -0,0 +1,102 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	router "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/router/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var suppressEnvoyHeadersYAML = entrypoint.FakeListenerYAML + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: public
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /public/
+  service: public.default
+  suppress_envoy_headers: true
+` + entrypoint.FakeMappingYAML("debug")
+
+// routerSuppressesEnvoyHeaders returns whether the listener's router filter drops Envoy's
+// headers for every route.
+func routerSuppressesEnvoyHeaders(t *testing.T, listener *v3listener.Listener) bool {
+	hcm := entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+
+	for _, filter := range hcm.HttpFilters {
+		if filter.Name == "envoy.filters.http.router" {
+			if filter.GetTypedConfig() == nil {
+				return false
+			}
+
+			config := &router.Router{}
+			require.NoError(t, ptypes.UnmarshalAny(filter.GetTypedConfig(), config))
+			return config.SuppressEnvoyHeaders
+		}
+	}
+
+	require.Fail(t, "no router filter")
+	return false
+}
+
+func TestSuppressEnvoyHeaders(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(suppressEnvoyHeadersYAML, "debug", "cluster_debug_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// Without the Module setting, the router adds the headers, and only the public
+	// Mapping strips them.
+	assert.False(t, routerSuppressesEnvoyHeaders(t, listener))
+
+	r := findRoute(listener, func(r *route.Route) bool {
+		return r.GetRoute().GetCluster() == "cluster_public_default_default"
+	})
+	require.NotNil(t, r)
+	assert.Contains(t, r.ResponseHeadersToRemove, "x-envoy-upstream-service-time")
+	assert.Contains(t, r.ResponseHeadersToRemove, "x-envoy-overloaded")
+
+	r = findRoute(listener, func(r *route.Route) bool {
+		return r.GetRoute().GetCluster() == "cluster_debug_default_default"
+	})
+	require.NotNil(t, r)
+	assert.NotContains(t, r.ResponseHeadersToRemove, "x-envoy-upstream-service-time")
+
+	// Turning it on in the Module makes the router suppress them everywhere.
+	require.NoError(t, f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    suppress_envoy_headers: true
+`))
+	f.Flush()
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		l := findListener(config, func(l *v3listener.Listener) bool {
+			return l.Name == "ambassador-listener-8080"
+		})
+		return l != nil && routerSuppressesEnvoyHeaders(t, l)
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, config)
+}
@@ -0,0 +1,166 @@
+package entrypoint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	tcp "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/tcp_proxy/v3"
+	v3tls "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/transport_sockets/tls/v3"
+	snapshot "github.com/datawire/ambassador/v2/pkg/snapshot/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tcpChainFor returns the filter chain of the listener whose tcp_proxy sends connections to a
+// cluster with the given substring in its name.
+func tcpChainFor(t *testing.T, listener *v3listener.Listener, cluster string) *v3listener.FilterChain {
+	for _, fc := range listener.FilterChains {
+		for _, filter := range fc.Filters {
+			if filter.Name != "envoy.filters.network.tcp_proxy" {
+				continue
+			}
+			proxy := &tcp.TcpProxy{}
+			require.NoError(t, ptypes.UnmarshalAny(filter.GetTypedConfig(), proxy))
+			for _, c := range proxy.GetWeightedClusters().GetClusters() {
+				if strings.Contains(c.Name, cluster) {
+					return fc
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func TestTCPMappingALPN(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(exampleCert("bm90LWEtcmVhbC1jZXJ0") + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: ambassador-listener-8443
+  namespace: default
+spec:
+  port: 8443
+  protocol: TLS
+  securityModel: SECURE
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: example
+  namespace: default
+spec:
+  hostname: example.com
+  tlsSecret:
+    name: example-cert
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TCPMapping
+metadata:
+  name: grpc
+  namespace: default
+spec:
+  port: 8443
+  host: example.com
+  service: grpc.default:9000
+  alpn_protocols: [ "h2" ]
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TCPMapping
+metadata:
+  name: web
+  namespace: default
+spec:
+  port: 8443
+  host: example.com
+  service: web.default:8080
+  alpn_protocols: [ "http/1.1", "http/1.0" ]
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TCPMapping
+metadata:
+  name: anything
+  namespace: default
+spec:
+  port: 8443
+  host: example.com
+  service: anything.default:7000
+`)
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(func(snap *snapshot.Snapshot) bool {
+		return len(snap.Kubernetes.TCPMappings) == 3
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		listener := tlsListener(config)
+		return listener != nil && len(listener.FilterChains) == 3
+	})
+	require.NoError(t, err)
+	listener := tlsListener(config)
+
+	// Clients offering h2 go to grpc, and we agree to h2 when we terminate their TLS...
+	grpc := tcpChainFor(t, listener, "grpc_default")
+	require.NotNil(t, grpc)
+	assert.Equal(t, []string{"h2"}, grpc.FilterChainMatch.ApplicationProtocols)
+	assert.Equal(t, []string{"example.com"}, grpc.FilterChainMatch.ServerNames)
+	tlsCtx := &v3tls.DownstreamTlsContext{}
+	require.NoError(t, ptypes.UnmarshalAny(grpc.TransportSocket.GetTypedConfig(), tlsCtx))
+	assert.Equal(t, []string{"h2"}, tlsCtx.CommonTlsContext.AlpnProtocols)
+
+	// ...either flavor of HTTP/1 goes to web...
+	web := tcpChainFor(t, listener, "web_default")
+	require.NotNil(t, web)
+	assert.Equal(t, []string{"http/1.0", "http/1.1"}, web.FilterChainMatch.ApplicationProtocols)
+
+	// ...and anything else, including no ALPN at all, goes to the TCPMapping that didn't ask.
+	anything := tcpChainFor(t, listener, "anything_default")
+	require.NotNil(t, anything)
+	assert.Empty(t, anything.FilterChainMatch.ApplicationProtocols)
+	assert.Equal(t, []string{"example.com"}, anything.FilterChainMatch.ServerNames)
+
+	// A TCPMapping wanting a protocol that's already taken can't have a chain of its own.
+	err = f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TCPMapping
+metadata:
+  name: grpc-too
+  namespace: default
+spec:
+  port: 8443
+  host: example.com
+  service: grpc-too.default:9000
+  alpn_protocols: [ "h2", "h2c" ]
+`)
+	require.NoError(t, err)
+	f.Flush()
+
+	config, err = f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return FindCluster(config, ClusterNameContains("grpc_too_default")) != nil
+	})
+	require.NoError(t, err)
+	listener = tlsListener(config)
+	h2 := 0
+	for _, fc := range listener.FilterChains {
+		for _, proto := range fc.GetFilterChainMatch().GetApplicationProtocols() {
+			if proto == "h2" {
+				h2++
+			}
+		}
+	}
+	assert.Equal(t, 1, h2)
+}
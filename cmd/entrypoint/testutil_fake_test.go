@@ -4,21 +4,39 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os/exec"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/datawire/ambassador/v2/cmd/ambex"
+	"github.com/datawire/ambassador/v2/pkg/acp"
 	v3bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+	v3endpoint "github.com/datawire/ambassador/v2/pkg/api/envoy/config/endpoint/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	v3metrics "github.com/datawire/ambassador/v2/pkg/api/envoy/config/metrics/v3"
+	v3route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	v3aggregate "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/clusters/aggregate/v3"
+	v3httpman "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
 	amb "github.com/datawire/ambassador/v2/pkg/api/getambassador.io/v3alpha1"
 	"github.com/datawire/ambassador/v2/pkg/consulwatch"
+	ecp_cache_types "github.com/datawire/ambassador/v2/pkg/envoy-control-plane/cache/types"
+	ecp_resource "github.com/datawire/ambassador/v2/pkg/envoy-control-plane/resource/v3"
+	ecp_wellknown "github.com/datawire/ambassador/v2/pkg/envoy-control-plane/wellknown"
 	"github.com/datawire/ambassador/v2/pkg/kates"
 	"github.com/datawire/ambassador/v2/pkg/snapshot/v1"
 	"github.com/datawire/dlib/dgroup"
 	"github.com/datawire/dlib/dlog"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 // The Fake struct is a test harness for edgestack. Its goals are to help us fill out our test
@@ -68,10 +86,29 @@ type Fake struct {
 	// This holds the current snapshot.
 	currentSnapshot *atomic.Value
 
+	// This exports snapshots the way the watcher does, as the ambassador Module asks.
+	exporter *snapshotExporter
+
 	fastpath     *Queue // All fastpath snapshots that have been produced.
 	snapshots    *Queue // All snapshots that have been produced.
 	envoyConfigs *Queue // All envoyConfigs that have been produced.
 
+	// This does to the envoy configs what ambex does to the snapshots it hands to envoy, so
+	// that clusters that have just gone away show up as draining.
+	clusterDrainer *ambex.ClusterDrainer
+
+	// Likewise this shifts the weights of routes that want to be rebalanced on cluster health,
+	// going by whatever SetClusterHealth last said. It needs the last envoy config as diagd
+	// made it, to apply again when health changes.
+	rebalancer      *ambex.WeightRebalancer
+	rebalanceMutex  sync.Mutex
+	lastEnvoyConfig *v3bootstrap.Bootstrap
+
+	// This is what health-based readiness needs to know about the most recent envoy config.
+	readinessMutex  sync.Mutex
+	requiredHealthy float64
+	healthChecked   map[string]bool
+
 	// This is used to make Teardown idempotent.
 	teardownOnce sync.Once
 
@@ -83,6 +120,18 @@ type FakeConfig struct {
 	EnvoyConfig bool          // If true then the Fake will produce envoy configs in addition to Snapshots.
 	DiagdDebug  bool          // If true then diagd will have debugging enabled
 	Timeout     time.Duration // How long to wait for snapshots and/or envoy configs to become available.
+
+	// If IstioCertDir is set, the Fake watches that directory for Istio certs the same way
+	// AMBASSADOR_ISTIO_SECRET_DIR does in production, so tests write cert files there instead
+	// of calling SendIstioCertUpdate.
+	IstioCertDir string
+
+	// If LegacyAnnotations is set, the Fake translates the getambassador.io/config annotations
+	// on the Services and Ingresses in each snapshot the same way the watcher does, and puts the
+	// Mappings, Modules, and so on that they turn into in the snapshot's Kubernetes.Annotations.
+	// They don't survive the trip through JSON otherwise. Malformed annotations are left out,
+	// and show up in diagd's errors.
+	LegacyAnnotations bool
 }
 
 func (fc *FakeConfig) fillDefaults() {
@@ -111,15 +160,19 @@ func NewFake(t *testing.T, config FakeConfig) *Fake {
 		consulNotifier: NewNotifier(),
 
 		currentSnapshot: &atomic.Value{},
+		exporter:        &snapshotExporter{},
 
 		fastpath:     NewQueue(t, config.Timeout),
 		snapshots:    NewQueue(t, config.Timeout),
 		envoyConfigs: NewQueue(t, config.Timeout),
+
+		clusterDrainer: ambex.NewClusterDrainer(),
+		rebalancer:     ambex.NewWeightRebalancer(),
 	}
 
 	fake.k8sSource = &fakeK8sSource{fake: fake, store: k8sStore}
 	fake.watcher = &fakeWatcher{fake: fake, store: consulStore}
-	fake.istioCertSource = &fakeIstioCertSource{}
+	fake.istioCertSource = &fakeIstioCertSource{dir: config.IstioCertDir}
 
 	return fake
 }
@@ -145,7 +198,7 @@ func (f *Fake) Setup() {
 		}
 
 		f.group.Go("snapshot_server", func(ctx context.Context) error {
-			return snapshotServer(ctx, f.currentSnapshot)
+			return snapshotServer(ctx, f.currentSnapshot, f.exporter)
 		})
 
 		f.group.Go("diagd", func(ctx context.Context) error {
@@ -229,30 +282,143 @@ func (f *Fake) AssertEndpointsEmpty(timeout time.Duration) {
 	f.fastpath.AssertEmpty(timeout, "endpoints queue not empty")
 }
 
+// GetLoadAssignments will return the ClusterLoadAssignments (keyed by EDS service name) that ambex
+// would send to envoy for the next endpoint update that satisfies the supplied predicate. Like
+// ambex, this joins the endpoints against the EDS clusters of the most recent envoy config, so
+// every EDS cluster gets an assignment, even if it's empty.
+func (f *Fake) GetLoadAssignments(predicate func(map[string]*v3endpoint.ClusterLoadAssignment) bool) (map[string]*v3endpoint.ClusterLoadAssignment, error) {
+	f.T.Helper()
+	if !f.config.EnvoyConfig {
+		f.T.Fatalf("GetLoadAssignments needs the Fake to be configured with EnvoyConfig: true")
+	}
+	ctx := dlog.NewTestContext(f.T, false)
+	var result map[string]*v3endpoint.ClusterLoadAssignment
+	_, err := f.fastpath.Get(func(obj interface{}) bool {
+		config, _ := f.envoyConfigs.Latest().(*v3bootstrap.Bootstrap)
+		if config == nil {
+			return false
+		}
+		clusters := []ecp_cache_types.Resource{}
+		for _, cluster := range config.StaticResources.Clusters {
+			clusters = append(clusters, cluster)
+		}
+		assignments := map[string]*v3endpoint.ClusterLoadAssignment{}
+		eds := obj.(*ambex.FastpathSnapshot).Endpoints.ToMap_v3()
+		for _, res := range ambex.JoinEdsClustersV3(ctx, clusters, eds) {
+			cla := res.(*v3endpoint.ClusterLoadAssignment)
+			assignments[cla.ClusterName] = cla
+		}
+		if predicate(assignments) {
+			result = assignments
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AggregateTarget returns the cluster that envoy would send requests for the supplied aggregate
+// cluster to, given the supplied load assignments (see GetLoadAssignments): the first of its
+// clusters that has any endpoints, or "" if none of them do. (The Fake has no health checks, so
+// every endpoint counts as healthy.)
+func (f *Fake) AggregateTarget(config *v3bootstrap.Bootstrap, assignments map[string]*v3endpoint.ClusterLoadAssignment, name string) string {
+	f.T.Helper()
+	clusters := map[string]*v3cluster.Cluster{}
+	for _, cluster := range config.GetStaticResources().GetClusters() {
+		clusters[cluster.Name] = cluster
+	}
+
+	aggregate, ok := clusters[name]
+	if !ok || aggregate.GetClusterType() == nil {
+		f.T.Fatalf("no aggregate cluster %s", name)
+	}
+	aggregateConfig := &v3aggregate.ClusterConfig{}
+	if err := anypb.UnmarshalTo(aggregate.GetClusterType().GetTypedConfig(), aggregateConfig, proto.UnmarshalOptions{}); err != nil {
+		f.T.Fatalf("error decoding aggregate cluster %s: %+v", name, err)
+	}
+
+	for _, member := range aggregateConfig.Clusters {
+		cluster, ok := clusters[member]
+		if !ok {
+			continue
+		}
+		loadAssignment := cluster.GetLoadAssignment()
+		if cluster.GetEdsClusterConfig() != nil {
+			ref := cluster.GetEdsClusterConfig().GetServiceName()
+			if ref == "" {
+				ref = cluster.Name
+			}
+			loadAssignment = assignments[ref]
+		}
+		for _, locality := range loadAssignment.GetEndpoints() {
+			if len(locality.GetLbEndpoints()) > 0 {
+				return member
+			}
+		}
+	}
+	return ""
+}
+
+// AssertNoReconfigure checks that, for the supplied duration, nothing is sent to diagd past the
+// snapshots and envoy configs already returned by GetSnapshot and GetEnvoyConfig. That is, the
+// clusters and listeners (CDS and LDS) are left alone, which is what should happen when only
+// endpoints change.
+func (f *Fake) AssertNoReconfigure(timeout time.Duration) {
+	f.T.Helper()
+	f.snapshots.AssertNoMore(timeout, func(obj interface{}) bool {
+		return obj.(SnapshotEntry).Disposition == SnapshotReady
+	}, "snapshot sent to diagd")
+	if f.config.EnvoyConfig {
+		f.envoyConfigs.AssertNoMore(0, func(interface{}) bool { return true }, "envoy config regenerated")
+	}
+}
+
 type SnapshotEntry struct {
 	Disposition SnapshotDisposition
 	Snapshot    *snapshot.Snapshot
 }
 
 // We pass this into the watcher loop to get notified when a snapshot is produced.
-func (f *Fake) notifySnapshot(ctx context.Context, disp SnapshotDisposition, snapJSON []byte) error {
+func (f *Fake) notifySnapshot(ctx context.Context, disp SnapshotDisposition, snapJSON []byte, module *amb.Module) error {
+	var snap *snapshot.Snapshot
+	err := json.Unmarshal(snapJSON, &snap)
+	if err != nil {
+		f.T.Fatalf("error decoding snapshot: %+v", err)
+	}
+
+	if f.config.LegacyAnnotations && snap.Kubernetes != nil {
+		parseAnnotations(ctx, snap.Kubernetes)
+	}
+
 	if disp == SnapshotReady && f.config.EnvoyConfig {
 		if err := notifyReconfigWebhooksFunc(ctx, &noopNotable{}, false); err != nil {
 			return err
 		}
-		f.appendEnvoyConfig(ctx)
+		f.appendEnvoyConfig(ctx, module)
 	}
 
-	var snap *snapshot.Snapshot
-	err := json.Unmarshal(snapJSON, &snap)
-	if err != nil {
-		f.T.Fatalf("error decoding snapshot: %+v", err)
+	if disp == SnapshotReady {
+		f.exporter.Notify(ctx, snapJSON, module)
 	}
 
 	f.snapshots.Add(SnapshotEntry{disp, snap})
 	return nil
 }
 
+// ExportSnapshot exports the current snapshot right away, the way POSTing to /snapshot-export
+// does, and returns the path it went to.
+func (f *Fake) ExportSnapshot() (string, error) {
+	f.T.Helper()
+	snapJSON, _ := f.currentSnapshot.Load().([]byte)
+	if snapJSON == nil {
+		return "", fmt.Errorf("no snapshot yet")
+	}
+	return f.exporter.Export(dlog.NewTestContext(f.T, false), snapJSON)
+}
+
 // GetSnapshotEntry will return the next SnapshotEntry that satisfies the supplied predicate.
 func (f *Fake) GetSnapshotEntry(predicate func(SnapshotEntry) bool) (SnapshotEntry, error) {
 	f.T.Helper()
@@ -278,15 +444,148 @@ func (f *Fake) GetSnapshot(predicate func(*snapshot.Snapshot) bool) (*snapshot.S
 	return entry.Snapshot, nil
 }
 
-func (f *Fake) appendEnvoyConfig(ctx context.Context) {
+// DependencyGraph will return the dependency graph of the next snapshot that satisfies the
+// supplied predicate. See entrypoint.DependencyGraph for what the edges are.
+func (f *Fake) DependencyGraph(predicate func(*snapshot.Snapshot) bool) ([]Dependency, error) {
+	f.T.Helper()
+	snap, err := f.GetSnapshot(predicate)
+	if err != nil {
+		return nil, err
+	}
+	return DependencyGraph(dlog.NewTestContext(f.T, false), snap.Kubernetes), nil
+}
+
+func (f *Fake) appendEnvoyConfig(ctx context.Context, module *amb.Module) {
 	msg, err := ambex.Decode(ctx, "/tmp/envoy.json")
 	if err != nil {
 		f.T.Fatalf("error decoding envoy.json after sending snapshot to python: %+v", err)
 	}
 	bs := msg.(*v3bootstrap.Bootstrap)
+	bs.StaticResources.Clusters = f.clusterDrainer.Apply(bs.StaticResources.Clusters)
+	bs.StatsConfig = f.bootstrapStatsConfig()
+
+	f.rebalanceMutex.Lock()
+	f.lastEnvoyConfig = bs
+	bs = f.rebalanceRoutes(bs)
+	f.rebalanceMutex.Unlock()
+
+	required := requiredHealthyFraction(ctx, module)
+
+	f.readinessMutex.Lock()
+	f.requiredHealthy = required
+	f.healthChecked = healthCheckedClusters(bs)
+	f.readinessMutex.Unlock()
+
 	f.envoyConfigs.Add(bs)
 }
 
+// bootstrapStatsConfig returns the stats_config from the bootstrap diagd wrote alongside
+// envoy.json. Envoy only reads the bootstrap when it starts, so this is what a restarted Envoy
+// would get, rather than anything ambex sends.
+func (f *Fake) bootstrapStatsConfig() *v3metrics.StatsConfig {
+	contents, err := ioutil.ReadFile("/tmp/bootstrap-ads.json")
+	if err != nil {
+		f.T.Fatalf("error reading bootstrap-ads.json after sending snapshot to python: %+v", err)
+	}
+	var bootstrap struct {
+		StatsConfig json.RawMessage `json:"stats_config"`
+	}
+	if err := json.Unmarshal(contents, &bootstrap); err != nil {
+		f.T.Fatalf("error decoding bootstrap-ads.json: %+v", err)
+	}
+	if bootstrap.StatsConfig == nil {
+		return nil
+	}
+	statsConfig := &v3metrics.StatsConfig{}
+	if err := protojson.Unmarshal(bootstrap.StatsConfig, statsConfig); err != nil {
+		f.T.Fatalf("error decoding stats_config in bootstrap-ads.json: %+v", err)
+	}
+	return statsConfig
+}
+
+// UpstreamReadiness returns whether Ambassador would be ready, going by the most recent envoy
+// config, if each cluster had the given number of healthy endpoints, along with the fraction
+// of clusters that would be healthy. Clusters that aren't health checked are always healthy
+// (Ambassador's own aren't counted at all), and without the ambassador Module's
+// readiness_healthy_cluster_fraction, upstream health never matters. This only works if the
+// Fake was started with EnvoyConfig.
+func (f *Fake) UpstreamReadiness(healthyHosts map[string]int) (bool, float64) {
+	f.readinessMutex.Lock()
+	defer f.readinessMutex.Unlock()
+
+	return acp.UpstreamsReady(f.requiredHealthy, f.healthChecked, healthyHosts),
+		acp.HealthyFraction(f.healthChecked, healthyHosts)
+}
+
+// DrainingClusters returns the clusters that have gone away but are being kept in the envoy
+// config until their `cluster_drain_time_ms` is up, and when each will be done. This only works
+// if the Fake was started with EnvoyConfig.
+func (f *Fake) DrainingClusters() map[string]time.Time {
+	return f.clusterDrainer.Draining()
+}
+
+// SetClusterHealth tells the Fake how many healthy endpoints each cluster has (clusters that
+// aren't mentioned have none), the way ambex hears it from Envoy. If that changes the weights
+// of any route with `health_rebalance`, the envoy config is produced again with the new
+// weights, and SetClusterHealth returns true. This only works if the Fake was started with
+// EnvoyConfig.
+func (f *Fake) SetClusterHealth(healthyHosts map[string]int) bool {
+	f.rebalanceMutex.Lock()
+	defer f.rebalanceMutex.Unlock()
+
+	if !f.rebalancer.SetHealth(healthyHosts) || f.lastEnvoyConfig == nil {
+		return false
+	}
+
+	f.envoyConfigs.Add(f.rebalanceRoutes(f.lastEnvoyConfig))
+	return true
+}
+
+// rebalanceRoutes does to an envoy config what ambex does to the route configurations it hands
+// to envoy. Ambex only sees those once it's moved them out of the listeners into RDS; here
+// they're still inline. The caller must hold rebalanceMutex.
+func (f *Fake) rebalanceRoutes(bs *v3bootstrap.Bootstrap) *v3bootstrap.Bootstrap {
+	bs = proto.Clone(bs).(*v3bootstrap.Bootstrap)
+
+	var filters []*v3listener.Filter
+	var hcms []*v3httpman.HttpConnectionManager
+	var routeConfigs []*v3route.RouteConfiguration
+
+	for _, l := range bs.GetStaticResources().GetListeners() {
+		for _, chain := range l.GetFilterChains() {
+			for _, filter := range chain.GetFilters() {
+				if filter.Name != ecp_wellknown.HTTPConnectionManager {
+					continue
+				}
+				hcm := &v3httpman.HttpConnectionManager{}
+				if err := anypb.UnmarshalTo(filter.GetTypedConfig(), hcm, proto.UnmarshalOptions{}); err != nil {
+					f.T.Fatalf("error decoding http connection manager: %+v", err)
+				}
+				if hcm.GetRouteConfig() == nil {
+					continue
+				}
+				filters = append(filters, filter)
+				hcms = append(hcms, hcm)
+				routeConfigs = append(routeConfigs, hcm.GetRouteConfig())
+			}
+		}
+	}
+
+	for i, rc := range f.rebalancer.Apply(routeConfigs) {
+		if rc == routeConfigs[i] {
+			continue
+		}
+		hcms[i].RouteSpecifier = &v3httpman.HttpConnectionManager_RouteConfig{RouteConfig: rc}
+		typedConfig, err := anypb.New(hcms[i])
+		if err != nil {
+			f.T.Fatalf("error encoding http connection manager: %+v", err)
+		}
+		filters[i].ConfigType = &v3listener.Filter_TypedConfig{TypedConfig: typedConfig}
+	}
+
+	return bs
+}
+
 // GetEnvoyConfig will return the next envoy config that satisfies the supplied predicate.
 func (f *Fake) GetEnvoyConfig(predicate func(*v3bootstrap.Bootstrap) bool) (*v3bootstrap.Bootstrap, error) {
 	f.T.Helper()
@@ -299,6 +598,158 @@ func (f *Fake) GetEnvoyConfig(predicate func(*v3bootstrap.Bootstrap) bool) (*v3b
 	return untyped.(*v3bootstrap.Bootstrap), nil
 }
 
+// FakeListenerYAML is the Listener most tests start from: cleartext on 8080, taking Hosts from
+// every namespace.
+const FakeListenerYAML = `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: ambassador-listener-8080
+  namespace: default
+spec:
+  port: 8080
+  protocol: HTTP
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+`
+
+// FakeListenersYAML is FakeListenerYAML plus its TLS counterpart on 8443.
+const FakeListenersYAML = FakeListenerYAML + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: ambassador-listener-8443
+  namespace: default
+spec:
+  port: 8443
+  protocol: HTTPS
+  securityModel: XFP
+  hostBinding:
+    namespace:
+      from: ALL
+`
+
+// FakeMappingYAML returns a Mapping called name, in the default namespace, that sends /name/ on
+// every hostname to the service name.default. Its spec comes last, so more spec keys can be
+// appended to it.
+func FakeMappingYAML(name string) string {
+	return fmt.Sprintf(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: %[1]s
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /%[1]s/
+  service: %[1]s.default
+`, name)
+}
+
+// RunListenerFake runs a Fake on yaml, which has to include the Mapping called mapping in the
+// default namespace, and returns the first envoy config with that Mapping's cluster in it,
+// along with the HttpConnectionManager of its 8080 Listener.
+func RunListenerFake(t *testing.T, yaml, mapping string) (*v3bootstrap.Bootstrap, *v3httpman.HttpConnectionManager) {
+	t.Helper()
+	f := RunFake(t, FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(yaml, mapping, fmt.Sprintf("cluster_%s_default_default", mapping))
+
+	for _, l := range config.GetStaticResources().GetListeners() {
+		if l.Name == "ambassador-listener-8080" {
+			if hcm := ListenerHCM(l); hcm != nil {
+				return config, hcm
+			}
+		}
+	}
+	t.Fatalf("no HttpConnectionManager on ambassador-listener-8080")
+	return nil, nil
+}
+
+// UpsertYAMLAndGetEnvoyConfig upserts yaml and flushes, waits for a snapshot with the Mapping
+// called mapping in the default namespace, and returns the first envoy config after that with,
+// for each of clusters, a cluster whose name contains it. Any error fails the test.
+func (f *Fake) UpsertYAMLAndGetEnvoyConfig(yaml, mapping string, clusters ...string) *v3bootstrap.Bootstrap {
+	f.T.Helper()
+	if err := f.UpsertYAML(yaml); err != nil {
+		f.T.Fatalf("error upserting yaml: %+v", err)
+	}
+	f.Flush()
+
+	_, err := f.GetSnapshot(func(snap *snapshot.Snapshot) bool {
+		for _, m := range snap.Kubernetes.Mappings {
+			if m.Namespace == "default" && m.Name == mapping {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		f.T.Fatalf("error getting snapshot with Mapping %s: %+v", mapping, err)
+	}
+
+	config, err := f.GetEnvoyConfig(func(config *v3bootstrap.Bootstrap) bool {
+		for _, cluster := range clusters {
+			found := false
+			for _, c := range config.GetStaticResources().GetClusters() {
+				if strings.Contains(c.Name, cluster) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		f.T.Fatalf("error getting envoy config with %s: %+v", strings.Join(clusters, ", "), err)
+	}
+	return config
+}
+
+// ListenerHCM returns the HttpConnectionManager of the first of the listener's filter chains
+// that has one, or nil if none does.
+func ListenerHCM(listener *v3listener.Listener) *v3httpman.HttpConnectionManager {
+	for _, fc := range listener.GetFilterChains() {
+		if hcm := ChainHCM(fc); hcm != nil {
+			return hcm
+		}
+	}
+	return nil
+}
+
+// ChainHCM returns the filter chain's HttpConnectionManager, or nil if it doesn't have one.
+func ChainHCM(fc *v3listener.FilterChain) *v3httpman.HttpConnectionManager {
+	for _, filter := range fc.GetFilters() {
+		if filter.Name == ecp_wellknown.HTTPConnectionManager {
+			return ecp_resource.GetHTTPConnectionManager(filter)
+		}
+	}
+	return nil
+}
+
+// HTTPFilterConfig decodes the typed_config of the HttpConnectionManager's first HTTP filter
+// called name into config, failing the test if there's no such filter.
+func HTTPFilterConfig(t *testing.T, hcm *v3httpman.HttpConnectionManager, name string, config proto.Message) {
+	t.Helper()
+	for _, filter := range hcm.GetHttpFilters() {
+		if filter.Name != name {
+			continue
+		}
+		if err := anypb.UnmarshalTo(filter.GetTypedConfig(), config, proto.UnmarshalOptions{}); err != nil {
+			t.Fatalf("error decoding %s: %+v", name, err)
+		}
+		return
+	}
+	t.Fatalf("no %s filter", name)
+}
+
 // AutoFlush will cause a flush whenever any inputs are modified.
 func (f *Fake) AutoFlush(enabled bool) {
 	f.k8sNotifier.AutoNotify(enabled)
@@ -336,6 +787,16 @@ func (f *Fake) UpsertYAML(yaml string) error {
 	return nil
 }
 
+// UpsertUnstructuredYAML is UpsertYAML for resources with fields our types don't know about,
+// which it keeps just as they were written.
+func (f *Fake) UpsertUnstructuredYAML(yaml string) error {
+	if err := f.k8sStore.UpsertUnstructuredYAML(yaml); err != nil {
+		return err
+	}
+	f.k8sNotifier.Changed()
+	return nil
+}
+
 // Upsert will update (or if necessary create) the supplied resource in the fake k8s datastore.
 func (f *Fake) Upsert(resource kates.Object) error {
 	if err := f.k8sStore.Upsert(resource); err != nil {
@@ -354,14 +815,217 @@ func (f *Fake) Delete(kind, namespace, name string) error {
 	return nil
 }
 
+// LoadSnapshotJSON will decode a captured snapshot (e.g. the output of the snapshot server, or a
+// snapshot attached to a bug report) and load it with LoadSnapshot. Keys in the JSON that this
+// version of the snapshot types doesn't know about are logged and otherwise ignored.
+func (f *Fake) LoadSnapshotJSON(data []byte) error {
+	f.T.Helper()
+	var raw struct {
+		Kubernetes json.RawMessage
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for _, key := range unknownJSONKeys(data, reflect.TypeOf(snapshot.Snapshot{})) {
+		f.T.Logf("LoadSnapshotJSON: ignoring unknown snapshot key %q", key)
+	}
+	if len(raw.Kubernetes) > 0 {
+		for _, key := range unknownJSONKeys(raw.Kubernetes, reflect.TypeOf(snapshot.KubernetesSnapshot{})) {
+			f.T.Logf("LoadSnapshotJSON: ignoring unknown Kubernetes resource type %q", key)
+		}
+	}
+
+	var snap *snapshot.Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+	return f.LoadSnapshot(snap)
+}
+
+// LoadSnapshot will feed every input from a captured snapshot into the control plane, exactly as
+// if the resources had been supplied with Upsert and ConsulEndpoint, and then Flush. This makes it
+// possible to reproduce the config produced for a snapshot from the field without first turning
+// it back into YAML.
+//
+// Resources that the Fake doesn't know how to store are logged and skipped rather than failing the
+// load, as are differences between the snapshot's Ambassador version and the Fake's: a snapshot
+// captured with a different version may well still be useful, but anything that goes wrong when
+// replaying it should be read with that in mind.
+func (f *Fake) LoadSnapshot(snap *snapshot.Snapshot) error {
+	f.T.Helper()
+	if snap.AmbassadorMeta != nil && f.ambassadorMeta != nil &&
+		snap.AmbassadorMeta.AmbassadorVersion != f.ambassadorMeta.AmbassadorVersion {
+		f.T.Logf("LoadSnapshot: snapshot was captured from Ambassador %q, but the Fake is running as %q",
+			snap.AmbassadorMeta.AmbassadorVersion, f.ambassadorMeta.AmbassadorVersion)
+	}
+
+	if snap.Kubernetes != nil {
+		// Walk every list of resources in the snapshot, so that we pick up new resource types
+		// without having to remember to update this. Fields that aren't serialized (the
+		// annotations, and the secrets before they're merged) are derived from the others by
+		// the watcher, so we skip them. Snapshots captured before KubernetesSnapshot.UnmarshalJSON
+		// stopped doubling the legacy fields can still have the same resource in them twice, so
+		// we only load each one once.
+		loaded := map[string]bool{}
+		v := reflect.ValueOf(snap.Kubernetes).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.Tag.Get("json") == "-" || field.Type.Kind() != reflect.Slice {
+				continue
+			}
+			for j := 0; j < v.Field(i).Len(); j++ {
+				obj, ok := v.Field(i).Index(j).Interface().(kates.Object)
+				if !ok || reflect.ValueOf(obj).IsNil() {
+					continue
+				}
+				key := fmt.Sprintf("%s:%s:%s", field.Name, obj.GetNamespace(), obj.GetName())
+				if loaded[key] {
+					continue
+				}
+				loaded[key] = true
+				// Not everything that ends up in a snapshot has its TypeMeta filled in, but
+				// the name of the list it's in will do.
+				listKind := strings.Split(field.Tag.Get("json"), ",")[0]
+				if listKind == "" {
+					listKind = field.Name
+				}
+				if err := f.loadSnapshotObject(obj, listKind); err != nil {
+					f.T.Logf("LoadSnapshot: skipping %s %s/%s: %v", field.Name, obj.GetNamespace(), obj.GetName(), err)
+				}
+			}
+		}
+		f.k8sNotifier.Changed()
+	}
+
+	// Resources that failed validation when the snapshot was captured get another go, so that
+	// we see whatever the current code makes of them.
+	for _, un := range snap.Invalid {
+		if err := f.loadSnapshotObject(un, un.GetKind()); err != nil {
+			f.T.Logf("LoadSnapshot: skipping invalid %s %s/%s: %v", un.GetKind(), un.GetNamespace(), un.GetName(), err)
+		}
+	}
+
+	if snap.Consul != nil {
+		// Captured consul endpoints don't record their datacenter, so make them available in
+		// every datacenter that a resolver in the snapshot asks for.
+		datacenters := map[string]bool{}
+		if snap.Kubernetes != nil {
+			for _, resolver := range snap.Kubernetes.ConsulResolvers {
+				datacenters[resolver.Spec.Datacenter] = true
+			}
+		}
+		for service, endpoints := range snap.Consul.Endpoints {
+			if endpoints.Id != "" {
+				f.consulStore.Set(endpoints.Id, service, endpoints)
+				continue
+			}
+			for datacenter := range datacenters {
+				f.consulStore.Set(datacenter, service, endpoints)
+			}
+		}
+		f.consulNotifier.Changed()
+	}
+
+	f.Flush()
+	return nil
+}
+
+func (f *Fake) loadSnapshotObject(obj kates.Object, defaultKind string) error {
+	f.T.Helper()
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Kind == "" {
+		gvk.Kind = defaultKind
+	}
+	kind, apiVersion, err := canonGVK(gvk.Kind)
+	if err != nil {
+		return err
+	}
+	if original := gvk.GroupVersion().String(); original != "" && original != apiVersion {
+		f.T.Logf("LoadSnapshot: loading %s %s/%s from %s as %s", kind, obj.GetNamespace(), obj.GetName(), original, apiVersion)
+	}
+	gvk.Kind = kind
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	return f.k8sStore.Upsert(obj)
+}
+
+// unknownJSONKeys returns the keys of the JSON object in data that don't correspond to any field of
+// the supplied struct type.
+func unknownJSONKeys(data []byte, typ reflect.Type) []string {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil
+	}
+	known := map[string]bool{}
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = typ.Field(i).Name
+		}
+		known[strings.ToLower(name)] = true
+	}
+	var unknown []string
+	for key := range obj {
+		if !known[strings.ToLower(key)] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// A Checkpoint is everything that had been fed into a Fake at some point: see Fake.Checkpoint.
+type Checkpoint struct {
+	resources map[K8sKey]kates.Object
+	consul    map[ConsulKey]consulwatch.Endpoints
+}
+
+// Checkpoint captures the kubernetes resources and consul endpoints that have been fed into the
+// Fake so far (whether or not they've been flushed), for Restore to go back to later. Istio cert
+// updates aren't captured: they're sent once rather than stored, so there's nothing to go back
+// to. Neither is anything the Fake only does to envoy configs, like SetClusterHealth.
+func (f *Fake) Checkpoint() Checkpoint {
+	return Checkpoint{
+		resources: f.k8sStore.Resources(),
+		consul:    f.consulStore.Endpoints(),
+	}
+}
+
+// Restore puts the kubernetes resources and consul endpoints back the way they were when the
+// Checkpoint was taken -- re-adding anything deleted since, and deleting anything added since --
+// and then flushes, just as if each change had been made by hand. The control plane sees the
+// changes as ordinary deltas, so the config it regenerates is what a cluster going through the
+// same edits would get.
+func (f *Fake) Restore(c Checkpoint) {
+	f.T.Helper()
+	if err := f.k8sStore.Restore(c.resources); err != nil {
+		f.T.Fatalf("error restoring checkpoint: %+v", err)
+	}
+	f.consulStore.Restore(c.consul)
+	f.k8sNotifier.Changed()
+	f.consulNotifier.Changed()
+	f.Flush()
+}
+
 // ConsulEndpoint stores the supplied consul endpoint data.
 func (f *Fake) ConsulEndpoint(datacenter, service, address string, port int, tags ...string) {
 	f.consulStore.ConsulEndpoint(datacenter, service, address, port, tags...)
 	f.consulNotifier.Changed()
 }
 
-// SendIstioCertUpdate sends the supplied Istio certificate update.
+// ClearConsulEndpoints takes away all the consul endpoint data for a service, as if every instance
+// of it had gone away.
+func (f *Fake) ClearConsulEndpoints(datacenter, service string) {
+	f.consulStore.Clear(datacenter, service)
+	f.consulNotifier.Changed()
+}
+
+// SendIstioCertUpdate sends the supplied Istio certificate update. It can't be used with
+// FakeConfig.IstioCertDir, since then the updates come from the files in that directory.
 func (f *Fake) SendIstioCertUpdate(update IstioCertUpdate) {
+	f.T.Helper()
+	if f.config.IstioCertDir != "" {
+		f.T.Fatal("SendIstioCertUpdate can't be used with IstioCertDir: write the cert files instead")
+	}
 	f.istioCertSource.updateChannel <- update
 }
 
@@ -474,10 +1138,16 @@ func (f *fakeStopper) Stop() {
 }
 
 type fakeIstioCertSource struct {
+	dir           string
 	updateChannel chan IstioCertUpdate
 }
 
 func (src *fakeIstioCertSource) Watch(ctx context.Context) (IstioCertWatcher, error) {
+	if src.dir != "" {
+		// Use the real thing, just pointed somewhere else.
+		return (&istioCertSource{secretDir: src.dir}).Watch(ctx)
+	}
+
 	src.updateChannel = make(chan IstioCertUpdate)
 
 	return &istioCertWatcher{
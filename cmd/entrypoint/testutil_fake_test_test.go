@@ -2,6 +2,9 @@ package entrypoint_test
 
 import (
 	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -196,3 +199,67 @@ func TestFakeIstioCert(t *testing.T) {
 		t.Errorf("needed 2 secrets, got %d", len(k.Secrets))
 	}
 }
+
+// writeFileAtomically writes a file the way Istio's agent does: write a temporary file
+// in the same directory, then rename it into place, so a watcher never sees half a cert.
+func writeFileAtomically(t *testing.T, dir, name, contents string) {
+	t.Helper()
+	tmp := filepath.Join(dir, "."+name+".tmp")
+	require.NoError(t, ioutil.WriteFile(tmp, []byte(contents), 0600))
+	require.NoError(t, os.Rename(tmp, filepath.Join(dir, name)))
+}
+
+func istioSecretIs(key, chain string) func(*snapshot.Snapshot) bool {
+	return func(snap *snapshot.Snapshot) bool {
+		for _, secret := range snap.Kubernetes.Secrets {
+			if secret.Name == "istio-certs" && secret.Namespace == "default" {
+				return string(secret.Data["tls.key"]) == key && string(secret.Data["tls.crt"]) == chain
+			}
+		}
+		return key == "" && chain == ""
+	}
+}
+
+func TestFakeIstioCertDir(t *testing.T) {
+	dir := t.TempDir()
+
+	// Istio also writes root-cert.pem (and someone might leave other files around), but
+	// only key.pem and cert-chain.pem make up the secret.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "root-cert.pem"), []byte("root"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README"), []byte("ignore me"), 0600))
+
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: false, IstioCertDir: dir}, nil)
+	f.AutoFlush(true)
+
+	// The Istio cert only lands in the snapshot when something refers to it.
+	assert.NoError(t, f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: istio-upstream
+  namespace: default
+spec:
+  secret: istio-certs
+  alpn_protocols: istio
+`))
+
+	writeFileAtomically(t, dir, "key.pem", "key-1")
+	writeFileAtomically(t, dir, "cert-chain.pem", "chain-1")
+
+	_, err := f.GetSnapshot(istioSecretIs("key-1", "chain-1"))
+	require.NoError(t, err)
+
+	// Rotation replaces both files; the snapshot follows.
+	writeFileAtomically(t, dir, "cert-chain.pem", "chain-2")
+	writeFileAtomically(t, dir, "key.pem", "key-2")
+
+	_, err = f.GetSnapshot(istioSecretIs("key-2", "chain-2"))
+	require.NoError(t, err)
+
+	// Without the key there's no usable cert, so the secret goes away.
+	require.NoError(t, os.Remove(filepath.Join(dir, "key.pem")))
+
+	_, err = f.GetSnapshot(istioSecretIs("", ""))
+	require.NoError(t, err)
+}
@@ -0,0 +1,162 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chainsByTransport sorts a listener's filter chains by the transport_protocol they match
+// ("" for chains that match either).
+func chainsByTransport(listener *v3listener.Listener) map[string][]*v3listener.FilterChain {
+	chains := map[string][]*v3listener.FilterChain{}
+	for _, fc := range listener.FilterChains {
+		proto := fc.GetFilterChainMatch().GetTransportProtocol()
+		chains[proto] = append(chains[proto], fc)
+	}
+	return chains
+}
+
+func TestListenerTLSDetection(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+
+	// hostsem-basic.yaml has TLS Listeners on 8080 and 8443, and two Hosts with TLS that
+	// also take cleartext.
+	require.NoError(t, f.UpsertFile("testdata/hostsem-basic.yaml"))
+	err := f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: listener-split
+  namespace: default
+spec:
+  port: 8100
+  protocol: HTTPS
+  securityModel: XFP
+  tlsDetection: Split
+  hostBinding:
+    namespace:
+      from: ALL
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: listener-tls-only
+  namespace: default
+spec:
+  port: 8101
+  protocol: HTTPS
+  securityModel: XFP
+  tlsDetection: Split
+  hostBinding:
+    selector:
+      matchLabels:
+        tls-only: "true"
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Host
+metadata:
+  name: h3-host
+  namespace: default
+  labels:
+    tls-only: "true"
+spec:
+  hostname: h3.example.com
+  tlsSecret:
+    name: h1-secret
+  requestPolicy:
+    insecure:
+      action: Reject
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: h3-mapping
+  namespace: default
+spec:
+  hostname: h3.example.com
+  prefix: /h3/
+  service: h3.default
+`)
+	require.NoError(t, err)
+
+	f.Flush()
+	snap, err := f.GetSnapshot(HasMapping("default", "h3-mapping"))
+	require.NoError(t, err)
+	assert.NotNil(t, snap)
+
+	config, err := f.GetEnvoyConfig(func(config *bootstrap.Bootstrap) bool {
+		return findListener(config, func(l *v3listener.Listener) bool {
+			return l.Name == "listener-tls-only"
+		}) != nil
+	})
+	require.NoError(t, err)
+
+	listener := func(name string) *v3listener.Listener {
+		l := findListener(config, func(l *v3listener.Listener) bool {
+			return l.Name == name
+		})
+		require.NotNil(t, l, name)
+
+		var inspector bool
+		for _, lf := range l.ListenerFilters {
+			if lf.Name == "envoy.filters.listener.tls_inspector" {
+				inspector = true
+			}
+		}
+		assert.True(t, inspector, name)
+		return l
+	}
+
+	// By default, the cleartext chain matches anything, so it also gets TLS connections
+	// whose SNI matches no Host.
+	chains := chainsByTransport(listener("ambassador-listener-8443"))
+	assert.Len(t, chains[""], 1)
+	assert.Empty(t, chains["raw_buffer"])
+	assert.NotEmpty(t, chains["tls"])
+
+	// With Split, the TLS inspector sends cleartext and TLS to their own chains, and nothing
+	// matches either.
+	chains = chainsByTransport(listener("listener-split"))
+	assert.Empty(t, chains[""])
+	require.Len(t, chains["raw_buffer"], 1)
+	for _, fc := range chains["tls"] {
+		assert.NotNil(t, fc.TransportSocket)
+		assert.NotEmpty(t, fc.GetFilterChainMatch().GetServerNames())
+	}
+	assert.Len(t, chains["tls"], 2)
+
+	cleartext := entrypoint.ChainHCM(chains["raw_buffer"][0])
+	require.NotNil(t, cleartext)
+	var domains []string
+	for _, vh := range cleartext.GetRouteConfig().GetVirtualHosts() {
+		domains = append(domains, vh.Domains...)
+	}
+	assert.ElementsMatch(t, []string{"h1.example.com", "h2.example.com"}, domains)
+
+	// A Split Listener whose Hosts all need TLS tells cleartext clients so, rather than
+	// routing them anywhere.
+	chains = chainsByTransport(listener("listener-tls-only"))
+	assert.Empty(t, chains[""])
+	require.Len(t, chains["tls"], 1)
+	assert.Equal(t, []string{"h3.example.com"}, chains["tls"][0].GetFilterChainMatch().GetServerNames())
+
+	require.Len(t, chains["raw_buffer"], 1)
+	cleartext = entrypoint.ChainHCM(chains["raw_buffer"][0])
+	require.NotNil(t, cleartext)
+	vhosts := cleartext.GetRouteConfig().GetVirtualHosts()
+	require.Len(t, vhosts, 1)
+	assert.Equal(t, []string{"*"}, vhosts[0].Domains)
+	require.Len(t, vhosts[0].Routes, 1)
+	direct, ok := vhosts[0].Routes[0].Action.(*route.Route_DirectResponse)
+	require.True(t, ok)
+	assert.Equal(t, uint32(400), direct.DirectResponse.Status)
+	assert.Contains(t, direct.DirectResponse.GetBody().GetInlineString(), "requires TLS")
+}
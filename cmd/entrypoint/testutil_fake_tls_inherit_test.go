@@ -0,0 +1,182 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	tls "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/transport_sockets/tls/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSContextInheritance(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: base
+  namespace: default
+spec:
+  min_tls_version: v1.2
+  max_tls_version: v1.3
+  cipher_suites:
+  - ECDHE-RSA-AES128-GCM-SHA256
+  alpn_protocols: h2
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: middle
+  namespace: default
+spec:
+  inherit_from: base
+  max_tls_version: v1.2
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: child
+  namespace: default
+spec:
+  inherit_from: middle
+  alpn_protocols: http/1.1
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: loop-a
+  namespace: default
+spec:
+  inherit_from: loop-b
+  min_tls_version: v1.3
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: loop-b
+  namespace: default
+spec:
+  inherit_from: loop-a
+  cipher_suites:
+  - ECDHE-RSA-AES256-GCM-SHA384
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: inherits
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /inherits/
+  service: inherits.default
+  tls: child
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: loops
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /loops/
+  service: loops.default
+  tls: loop-a
+`, "loops", "cluster_loops_default_otls_loop_a_default")
+
+	// child gets its cipher suites and minimum version from base, its maximum version from
+	// middle (which overrides base), and keeps its own ALPN.
+	cluster := FindCluster(config, ClusterNameContains("cluster_inherits_default_otls_child_default"))
+	require.NotNil(t, cluster)
+	common := upstreamTLSContext(t, cluster).CommonTlsContext
+	assert.Equal(t, []string{"http/1.1"}, common.AlpnProtocols)
+	require.NotNil(t, common.TlsParams)
+	assert.Equal(t, tls.TlsParameters_TLSv1_2, common.TlsParams.TlsMinimumProtocolVersion)
+	assert.Equal(t, tls.TlsParameters_TLSv1_2, common.TlsParams.TlsMaximumProtocolVersion)
+	assert.Equal(t, []string{"ECDHE-RSA-AES128-GCM-SHA256"}, common.TlsParams.CipherSuites)
+
+	// A loop is an error, but both contexts still work: loop-a picks up loop-b's settings
+	// before the walk notices it's back where it started.
+	cluster = FindCluster(config, ClusterNameContains("cluster_loops_default_otls_loop_a_default"))
+	require.NotNil(t, cluster)
+	common = upstreamTLSContext(t, cluster).CommonTlsContext
+	require.NotNil(t, common.TlsParams)
+	assert.Equal(t, tls.TlsParameters_TLSv1_3, common.TlsParams.TlsMinimumProtocolVersion)
+	assert.Equal(t, []string{"ECDHE-RSA-AES256-GCM-SHA384"}, common.TlsParams.CipherSuites)
+}
+
+func TestTLSContextInheritanceNamespaces(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenersYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: base
+  namespace: default
+spec:
+  min_tls_version: v1.2
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: base
+  namespace: other
+spec:
+  min_tls_version: v1.3
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: default-child
+  namespace: default
+spec:
+  inherit_from: base
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: other-child
+  namespace: other
+spec:
+  inherit_from: base
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: in-default
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /in-default/
+  service: in-default.default
+  tls: default-child
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: in-other
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /in-other/
+  service: in-other.default
+  tls: other-child
+`, "in-other", "otls_other_child")
+
+	// Both namespaces have a TLSContext called base, and each child inherits from the one in
+	// its own namespace.
+	cluster := FindCluster(config, ClusterNameContains("otls_default_child"))
+	require.NotNil(t, cluster)
+	common := upstreamTLSContext(t, cluster).CommonTlsContext
+	require.NotNil(t, common.TlsParams)
+	assert.Equal(t, tls.TlsParameters_TLSv1_2, common.TlsParams.TlsMinimumProtocolVersion)
+
+	cluster = FindCluster(config, ClusterNameContains("otls_other_child"))
+	require.NotNil(t, cluster)
+	common = upstreamTLSContext(t, cluster).CommonTlsContext
+	require.NotNil(t, common.TlsParams)
+	assert.Equal(t, tls.TlsParameters_TLSv1_3, common.TlsParams.TlsMinimumProtocolVersion)
+}
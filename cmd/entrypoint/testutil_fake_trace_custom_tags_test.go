@@ -0,0 +1,69 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceCustomTags(t *testing.T) {
+	_, httpConnectionManager := tracePropagationConfig(t, `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TracingService
+metadata:
+  name: tracing
+  namespace: default
+spec:
+  driver: zipkin
+  service: zipkin.default:9411
+  tag_headers:
+  - x-tenant-id
+  - user-agent
+  custom_tags:
+  - tag: env
+    literal:
+      value: prod
+  - tag: tenant
+    request_header:
+      name: x-tenant-id
+  - tag: region
+    request_header:
+      name: x-region
+      default_value: unknown
+  - tag: user-agent
+    metadata:
+      kind: route
+      key: envoy.lb
+      path: [canary]
+`)
+
+	tags := httpConnectionManager.Tracing.CustomTags
+	names := []string{}
+	for _, tag := range tags {
+		names = append(names, tag.Tag)
+	}
+
+	// tag_headers come first, except for user-agent, which the custom tag of the same name
+	// replaces rather than duplicates.
+	require.Equal(t, []string{"x-tenant-id", "env", "tenant", "region", "user-agent"}, names)
+	assert.Equal(t, "x-tenant-id", tags[0].GetRequestHeader().GetName())
+
+	// A literal tag is the same on every span.
+	assert.Equal(t, "prod", tags[1].GetLiteral().GetValue())
+
+	// A header without a default leaves the tag off spans for requests that don't have the
+	// header, where one with a default always tags the span.
+	assert.Equal(t, "x-tenant-id", tags[2].GetRequestHeader().GetName())
+	assert.Equal(t, "", tags[2].GetRequestHeader().GetDefaultValue())
+	assert.Equal(t, "x-region", tags[3].GetRequestHeader().GetName())
+	assert.Equal(t, "unknown", tags[3].GetRequestHeader().GetDefaultValue())
+
+	metadata := tags[4].GetMetadata()
+	require.NotNil(t, metadata)
+	assert.NotNil(t, metadata.GetKind().GetRoute())
+	assert.Equal(t, "envoy.lb", metadata.GetMetadataKey().GetKey())
+	require.Len(t, metadata.GetMetadataKey().GetPath(), 1)
+	assert.Equal(t, "canary", metadata.GetMetadataKey().GetPath()[0].GetKey())
+}
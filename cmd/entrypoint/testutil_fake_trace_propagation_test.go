@@ -0,0 +1,85 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3trace "github.com/datawire/ambassador/v2/pkg/api/envoy/config/trace/v3"
+	hcm "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tracePropagationConfig(t *testing.T, tracingService string) (*bootstrap.Bootstrap, *hcm.HttpConnectionManager) {
+	config, httpConnectionManager := entrypoint.RunListenerFake(t,
+		tracingService+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("backend"), "backend")
+	require.NotNil(t, httpConnectionManager.Tracing)
+
+	return config, httpConnectionManager
+}
+
+func TestTracePropagationZipkin(t *testing.T) {
+	config, httpConnectionManager := tracePropagationConfig(t, `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TracingService
+metadata:
+  name: tracing
+  namespace: default
+spec:
+  driver: zipkin
+  service: zipkin.default:9411
+  propagation: b3
+`)
+
+	// The native zipkin tracer is what writes the b3 headers...
+	require.NotNil(t, config.Tracing)
+	assert.Equal(t, "envoy.zipkin", config.Tracing.Http.Name)
+	zipkin := &v3trace.ZipkinConfig{}
+	require.NoError(t, ptypes.UnmarshalAny(config.Tracing.Http.GetTypedConfig(), zipkin))
+	assert.True(t, zipkin.TraceId_128Bit)
+
+	// ...and everyone's trusted, so nothing gets stripped.
+	assert.Empty(t, httpConnectionManager.GetRouteConfig().GetRequestHeadersToRemove())
+}
+
+func TestTracePropagationOpenCensus(t *testing.T) {
+	config, httpConnectionManager := tracePropagationConfig(t, `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TracingService
+metadata:
+  name: tracing
+  namespace: default
+spec:
+  driver: opencensus
+  service: ocagent.default:55678
+  trust_incoming_trace_context: false
+`)
+
+	require.NotNil(t, config.Tracing)
+	assert.Equal(t, "envoy.tracers.opencensus", config.Tracing.Http.Name)
+	opencensus := &v3trace.OpenCensusConfig{}
+	require.NoError(t, ptypes.UnmarshalAny(config.Tracing.Http.GetTypedConfig(), opencensus))
+	assert.True(t, opencensus.OcagentExporterEnabled)
+	assert.Equal(t, "ocagent.default:55678", opencensus.OcagentAddress)
+
+	// Upstreams get both traceparent and b3, so a backend that only understands b3 can still
+	// join the trace...
+	assert.Equal(t, []v3trace.OpenCensusConfig_TraceContext{
+		v3trace.OpenCensusConfig_TRACE_CONTEXT,
+		v3trace.OpenCensusConfig_B3,
+	}, opencensus.OutgoingTraceContext)
+
+	// ...but clients aren't trusted, so their trace context is ignored...
+	assert.Empty(t, opencensus.IncomingTraceContext)
+
+	// ...and whatever of it the tracer doesn't overwrite on the way upstream is stripped.
+	assert.ElementsMatch(t, []string{
+		"b3", "x-b3-parentspanid", "x-b3-flags", "tracestate",
+	}, httpConnectionManager.GetRouteConfig().GetRequestHeadersToRemove())
+}
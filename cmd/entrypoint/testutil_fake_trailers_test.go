@@ -0,0 +1,61 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrailersAndResponseHeaderLimits(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    enable_trailers: true
+    max_response_headers_count: 200
+`+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("foo")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: grpc-foo
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /grpc.Foo/
+  rewrite: /grpc.Foo/
+  service: grpc-foo.default
+  grpc: true
+`, "grpc-foo", "cluster_grpc_foo_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	hcm := entrypoint.ListenerHCM(listener)
+	require.NotNil(t, hcm)
+	assert.True(t, hcm.GetHttpProtocolOptions().GetEnableTrailers())
+
+	// HTTP/1.1 upstreams need trailers turned on explicitly...
+	cluster := FindCluster(config, ClusterNameContains("cluster_foo_default_default"))
+	require.NotNil(t, cluster)
+	assert.True(t, cluster.GetHttpProtocolOptions().GetEnableTrailers())
+	assert.Equal(t, uint32(200), cluster.GetCommonHttpProtocolOptions().GetMaxHeadersCount().GetValue())
+
+	// ...but gRPC runs over HTTP/2, which always forwards trailers.
+	cluster = FindCluster(config, ClusterNameContains("cluster_grpc_foo_default_default"))
+	require.NotNil(t, cluster)
+	assert.Nil(t, cluster.GetHttpProtocolOptions())
+	assert.NotNil(t, cluster.GetHttp2ProtocolOptions())
+	assert.Equal(t, uint32(200), cluster.GetCommonHttpProtocolOptions().GetMaxHeadersCount().GetValue())
+}
@@ -0,0 +1,101 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrailingSlash(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    trailing_slash: redirect
+`+entrypoint.FakeListenerYAML+entrypoint.FakeMappingYAML("docs")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: api
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /api/
+  rewrite: /v1/
+  service: api.default
+  trailing_slash: match
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: legacy
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /legacy/
+  service: legacy.default
+  trailing_slash: strict
+`+entrypoint.FakeMappingYAML("files")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: files-index
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /files
+  prefix_exact: true
+  service: files-index.default
+`, "files-index", "cluster_files_index_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+	pathRoute := func(path string, predicate func(*route.Route) bool) *route.Route {
+		return findRoute(listener, func(r *route.Route) bool {
+			return r.GetMatch().GetPath() == path && predicate(r)
+		})
+	}
+	anything := func(*route.Route) bool { return true }
+
+	// The Module says to redirect /docs to /docs/, keeping the method...
+	docs := pathRoute("/docs", func(r *route.Route) bool {
+		return r.GetRedirect().GetPathRedirect() != ""
+	})
+	require.NotNil(t, docs)
+	assert.Equal(t, "/docs/", docs.GetRedirect().GetPathRedirect())
+	assert.Equal(t, route.RedirectAction_PERMANENT_REDIRECT, docs.GetRedirect().GetResponseCode())
+
+	// ...but api would rather just route /api, rewritten the same way as /api/ is...
+	api := pathRoute("/api", func(r *route.Route) bool {
+		return r.GetRoute() != nil
+	})
+	require.NotNil(t, api)
+	assert.Equal(t, "cluster_api_default_default", api.GetRoute().GetCluster())
+	assert.Equal(t, "/v1/", api.GetRoute().GetPrefixRewrite())
+
+	// ...legacy wants /legacy left alone...
+	assert.Nil(t, pathRoute("/legacy", anything))
+
+	// ...and /files belongs to a Mapping of its own.
+	assert.NotNil(t, pathRoute("/files", func(r *route.Route) bool {
+		return r.GetRoute().GetCluster() == "cluster_files_index_default_default"
+	}))
+	assert.Nil(t, pathRoute("/files", func(r *route.Route) bool {
+		return r.GetRedirect().GetPathRedirect() != "" || r.GetRoute().GetCluster() == "cluster_files_default_default"
+	}))
+}
@@ -0,0 +1,91 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	extauthz "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/ext_authz/v3"
+	lua "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/lua/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappingTypedPerFilterConfig(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: AuthService
+metadata:
+  name: auth
+  namespace: default
+spec:
+  auth_service: auth.default:3000
+  proto: grpc
+  protocol_version: v3
+`+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: custom
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /custom/
+  service: custom.default
+  auth_context_extensions:
+    team: payments
+  typed_per_filter_config:
+    envoy.filters.http.ext_authz:
+      "@type": type.googleapis.com/envoy.extensions.filters.http.ext_authz.v3.ExtAuthzPerRoute
+      check_settings:
+        context_extensions:
+          tier: gold
+    envoy.filters.http.lua:
+      "@type": type.googleapis.com/envoy.extensions.filters.http.lua.v3.LuaPerRoute
+      disabled: true
+`+entrypoint.FakeMappingYAML("plain"), "plain", "cluster_plain_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	r := findRoute(listener, func(r *route.Route) bool {
+		return r.GetRoute().GetCluster() == "cluster_custom_default_default"
+	})
+	require.NotNil(t, r)
+
+	// The Mapping's ext_authz config is the same @type as the one Ambassador makes for
+	// auth_context_extensions, so the two are merged...
+	cfg, ok := r.TypedPerFilterConfig["envoy.filters.http.ext_authz"]
+	require.True(t, ok)
+	perRoute := &extauthz.ExtAuthzPerRoute{}
+	require.NoError(t, ptypes.UnmarshalAny(cfg, perRoute))
+	assert.Equal(t, map[string]string{
+		"team": "payments",
+		"tier": "gold",
+	}, perRoute.GetCheckSettings().GetContextExtensions())
+
+	// ...and config for a filter Ambassador doesn't know about goes in as it is (with a
+	// notice, since there's no Lua filter in the chain for it to configure).
+	cfg, ok = r.TypedPerFilterConfig["envoy.filters.http.lua"]
+	require.True(t, ok)
+	luaPerRoute := &lua.LuaPerRoute{}
+	require.NoError(t, ptypes.UnmarshalAny(cfg, luaPerRoute))
+	assert.True(t, luaPerRoute.GetDisabled())
+
+	// Other Mappings get nothing extra.
+	r = findRoute(listener, func(r *route.Route) bool {
+		return r.GetRoute().GetCluster() == "cluster_plain_default_default"
+	})
+	require.NotNil(t, r)
+	assert.NotContains(t, r.TypedPerFilterConfig, "envoy.filters.http.lua")
+	assert.NotContains(t, r.TypedPerFilterConfig, "envoy.filters.http.ext_authz")
+}
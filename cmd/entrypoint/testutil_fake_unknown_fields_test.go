@@ -0,0 +1,103 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	"github.com/datawire/ambassador/v2/pkg/kates"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unknownFieldsMappings has a Mapping with fields from some future CRD, one with a typo, and one
+// with nothing wrong at all, which goes last so that HasMapping can wait for it.
+const unknownFieldsMappings = `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: future
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /future/
+  service: future.default
+  futureField: 3
+  retry_policy:
+    num_retries: 2
+    somethingNew: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: typo
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /typo/
+  service: typo.default
+  retry_policy:
+    retry_onn: 5xx
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: plain
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /plain/
+  service: plain.default
+`
+
+// invalidErrors returns the validation errors for each invalid resource in the snapshot, by name.
+func invalidErrors(invalid []*kates.Unstructured) map[string]string {
+	errors := map[string]string{}
+	for _, un := range invalid {
+		msg, _ := un.Object["errors"].(string)
+		errors[un.GetName()] = msg
+	}
+	return errors
+}
+
+func TestUnknownFieldsIgnored(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{}, nil)
+	require.NoError(t, f.UpsertUnstructuredYAML(unknownFieldsMappings))
+	f.Flush()
+
+	snap, err := f.GetSnapshot(HasMapping("default", "plain"))
+	require.NoError(t, err)
+
+	// Fields we don't know about don't cost a resource anything: it's just as if they weren't
+	// there, typo or not.
+	assert.Empty(t, snap.Invalid)
+	require.Len(t, snap.Kubernetes.Mappings, 3)
+	for _, mapping := range snap.Kubernetes.Mappings {
+		if mapping.Name == "future" {
+			require.NotNil(t, mapping.Spec.RetryPolicy)
+			assert.Equal(t, 2, *mapping.Spec.RetryPolicy.NumRetries)
+		}
+	}
+}
+
+func TestUnknownFieldsStrict(t *testing.T) {
+	t.Setenv("AMBASSADOR_STRICT_SCHEMA_VALIDATION", "true")
+
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{}, nil)
+	require.NoError(t, f.UpsertUnstructuredYAML(unknownFieldsMappings))
+	f.Flush()
+
+	snap, err := f.GetSnapshot(HasMapping("default", "plain"))
+	require.NoError(t, err)
+
+	// In strict mode, unknown fields make a resource invalid, and the typo says what it's
+	// likely a typo of.
+	require.Len(t, snap.Kubernetes.Mappings, 1)
+	assert.Equal(t, "plain", snap.Kubernetes.Mappings[0].Name)
+
+	errors := invalidErrors(snap.Invalid)
+	require.Len(t, errors, 2)
+	assert.Equal(t, "unknown field spec.futureField\nunknown field spec.retry_policy.somethingNew\n", errors["future"])
+	assert.Equal(t, "unknown field spec.retry_policy.retry_onn (did you mean retry_on?)\n", errors["typo"])
+}
@@ -0,0 +1,106 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	extauthz "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/ext_authz/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadBuffering(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    buffer_limit_bytes: 65536
+    buffer:
+      max_request_bytes: 16384
+---
+apiVersion: getambassador.io/v3alpha1
+kind: AuthService
+metadata:
+  name: auth
+  namespace: default
+spec:
+  auth_service: auth.default:3000
+  proto: http
+  include_body:
+    max_bytes: 131072
+    allow_partial: false
+`+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Listener
+metadata:
+  name: uploads
+  namespace: default
+spec:
+  port: 8081
+  protocol: HTTP
+  securityModel: XFP
+  uploadBuffering:
+    connectionBufferBytes: 4194304
+    stream: true
+  hostBinding:
+    namespace:
+      from: ALL
+`+entrypoint.FakeMappingYAML("files"), "files", "cluster_files_default_default")
+
+	// filters returns a Listener's HTTP filters by name, along with its ext_authz config.
+	filters := func(name string) (*v3listener.Listener, map[string]bool, *extauthz.ExtAuthz) {
+		listener := findListener(config, func(l *v3listener.Listener) bool {
+			return l.Name == name
+		})
+		require.NotNil(t, listener)
+		hcm := entrypoint.ListenerHCM(listener)
+		require.NotNil(t, hcm)
+
+		names := map[string]bool{}
+		var authz *extauthz.ExtAuthz
+		for _, filter := range hcm.HttpFilters {
+			names[filter.Name] = true
+			if filter.Name == "envoy.filters.http.ext_authz" {
+				authz = &extauthz.ExtAuthz{}
+				require.NoError(t, ptypes.UnmarshalAny(filter.GetTypedConfig(), authz))
+			}
+		}
+		require.NotNil(t, authz)
+		return listener, names, authz
+	}
+
+	// The ordinary Listener keeps the Module's buffers, and waits for the AuthService's
+	// whole max_bytes -- which is more than a connection can hold.
+	listener, names, authz := filters("ambassador-listener-8080")
+	assert.Equal(t, uint32(65536), listener.GetPerConnectionBufferLimitBytes().GetValue())
+	assert.True(t, names["envoy.filters.http.buffer"])
+	assert.Equal(t, uint32(131072), authz.GetWithRequestBody().GetMaxRequestBytes())
+	assert.False(t, authz.GetWithRequestBody().GetAllowPartialMessage())
+
+	// The uploads Listener holds more per connection, streams bodies through without the
+	// Module's buffer, and sends the AuthService only the start of each one.
+	listener, names, authz = filters("uploads")
+	assert.Equal(t, uint32(4194304), listener.GetPerConnectionBufferLimitBytes().GetValue())
+	assert.False(t, names["envoy.filters.http.buffer"])
+	assert.Equal(t, uint32(131072), authz.GetWithRequestBody().GetMaxRequestBytes())
+	assert.True(t, authz.GetWithRequestBody().GetAllowPartialMessage())
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Mapping("files") != nil && len(diag.NoticesFor("uploads.default")) > 0
+	})
+	require.NoError(t, err)
+	assert.True(t, hasNotice(diag, "uploads.default", "the Module's buffer does nothing here"))
+	assert.True(t, hasNotice(diag, "uploads.default", "sends only the first 131072 bytes"))
+	assert.True(t, hasNotice(diag, "ambassador-listener-8080.default", "so bigger bodies get a 413"))
+}
@@ -0,0 +1,88 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+	tls "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/transport_sockets/tls/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func upstreamTLSContext(t *testing.T, cluster *v3cluster.Cluster) *tls.UpstreamTlsContext {
+	t.Helper()
+	require.NotNil(t, cluster.TransportSocket)
+	ctx := &tls.UpstreamTlsContext{}
+	require.NoError(t, ptypes.UnmarshalAny(cluster.TransportSocket.GetTypedConfig(), ctx))
+	return ctx
+}
+
+func TestUpstreamALPN(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: TLSContext
+metadata:
+  name: http1-only
+  namespace: default
+spec:
+  alpn_protocols: http/1.1
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: grpc-tls
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /grpc.Tls/
+  rewrite: /grpc.Tls/
+  service: https://grpc-tls.default
+  grpc: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: grpc-pinned
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /grpc.Pinned/
+  rewrite: /grpc.Pinned/
+  service: grpc-pinned.default
+  tls: http1-only
+  grpc: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: http-tls
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /http-tls/
+  service: https://http-tls.default
+`, "http-tls", "cluster_https___http_tls_default")
+
+	// gRPC over TLS offers h2, even with no TLSContext at all.
+	cluster := FindCluster(config, ClusterNameContains("cluster_https___grpc_tls_default"))
+	require.NotNil(t, cluster)
+	require.NotNil(t, cluster.Http2ProtocolOptions)
+	assert.Equal(t, []string{"h2"}, upstreamTLSContext(t, cluster).CommonTlsContext.AlpnProtocols)
+
+	// An explicit alpn_protocols is left alone (diagd flags the mismatch instead).
+	cluster = FindCluster(config, ClusterNameContains("cluster_grpc_pinned_default_otls_http1_only"))
+	require.NotNil(t, cluster)
+	assert.Equal(t, []string{"http/1.1"}, upstreamTLSContext(t, cluster).CommonTlsContext.AlpnProtocols)
+
+	// HTTP/1.1 upstreams don't get ALPN they didn't ask for.
+	cluster = FindCluster(config, ClusterNameContains("cluster_https___http_tls_default"))
+	require.NotNil(t, cluster)
+	assert.Nil(t, cluster.Http2ProtocolOptions)
+	assert.Empty(t, upstreamTLSContext(t, cluster).CommonTlsContext.GetAlpnProtocols())
+}
@@ -0,0 +1,85 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpstreamBindAddress(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	require.NoError(t, f.Upsert(makeService("default", "foo")))
+	require.NoError(t, f.Upsert(makeService("default", "bar")))
+	require.NoError(t, f.Upsert(makeService("default", "baz")))
+	config := f.UpsertYAMLAndGetEnvoyConfig(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    upstream_bind_address: 10.0.0.5
+`+entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: foo
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /foo
+  service: foo.default
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: bar
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /bar
+  service: bar.default
+  upstream_bind_address: "2001:db8::1"
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: baz
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /baz
+  service: baz.default
+  upstream_bind_address: not-an-address
+`, "bar", "cluster_bar_default_default", "cluster_baz_default_default")
+
+	// foo has no override, so it gets the Module's address.
+	cluster := FindCluster(config, ClusterNameContains("cluster_foo_default_default"))
+	require.NotNil(t, cluster)
+	require.NotNil(t, cluster.UpstreamBindConfig)
+	addr := cluster.UpstreamBindConfig.SourceAddress
+	assert.Equal(t, "10.0.0.5", addr.GetAddress())
+	assert.Equal(t, uint32(0), addr.GetPortValue())
+
+	// bar overrides it with an IPv6 address, which gets its own cluster.
+	cluster = FindCluster(config, ClusterNameContains("cluster_bar_default_default_sa_"))
+	require.NotNil(t, cluster)
+	require.NotNil(t, cluster.UpstreamBindConfig)
+	addr = cluster.UpstreamBindConfig.SourceAddress
+	assert.Equal(t, "2001:db8::1", addr.GetAddress())
+	assert.Equal(t, uint32(0), addr.GetPortValue())
+
+	// baz's isn't an address at all, so it's as if baz had none: no cluster of its own, and
+	// the Module's address.
+	cluster = FindCluster(config, ClusterNameContains("cluster_baz_default_default"))
+	require.NotNil(t, cluster)
+	assert.NotContains(t, cluster.Name, "_sa_")
+	require.NotNil(t, cluster.UpstreamBindConfig)
+	assert.Equal(t, "10.0.0.5", cluster.UpstreamBindConfig.SourceAddress.GetAddress())
+}
@@ -0,0 +1,165 @@
+package entrypoint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+	http "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// virtualClusters returns all the virtual clusters in a listener, by name.
+func virtualClusters(l *v3listener.Listener) map[string]*route.VirtualCluster {
+	vcs := map[string]*route.VirtualCluster{}
+	for _, fc := range l.FilterChains {
+		hcm := entrypoint.ChainHCM(fc)
+		if hcm == nil {
+			continue
+		}
+		if rs, ok := hcm.RouteSpecifier.(*http.HttpConnectionManager_RouteConfig); ok {
+			for _, vh := range rs.RouteConfig.VirtualHosts {
+				for _, vc := range vh.VirtualClusters {
+					vcs[vc.Name] = vc
+				}
+			}
+		}
+	}
+	return vcs
+}
+
+func TestMappingVirtualCluster(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: users
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /users/
+  service: api.default
+  virtual_cluster: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: orders
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /orders/
+  method: POST
+  service: api.default
+  stats_name: orders-post
+  virtual_cluster: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: misc
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /misc/
+  service: api.default
+`, "misc", "cluster_api_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// Only the Mappings that asked for virtual clusters get them, even though all three
+	// share one cluster.
+	vcs := virtualClusters(listener)
+	require.Len(t, vcs, 2)
+
+	users := vcs["users_default"]
+	require.NotNil(t, users)
+	require.Len(t, users.Headers, 1)
+	assert.Equal(t, ":path", users.Headers[0].Name)
+	assert.Equal(t, "/users/", users.Headers[0].GetPrefixMatch())
+
+	// The stats_name wins for naming, and the method match comes along.
+	orders := vcs["orders_post"]
+	require.NotNil(t, orders)
+	headers := map[string]*route.HeaderMatcher{}
+	for _, h := range orders.Headers {
+		headers[h.Name] = h
+	}
+	require.Contains(t, headers, ":path")
+	assert.Equal(t, "/orders/", headers[":path"].GetPrefixMatch())
+	require.Contains(t, headers, ":method")
+	assert.Equal(t, "POST", headers[":method"].GetExactMatch())
+}
+
+func TestMappingVirtualClusterNameCollision(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: users
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /users/
+  service: api.default
+  stats_name: api
+  virtual_cluster: true
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: orders
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /orders/
+  service: api.default
+  stats_name: api
+  virtual_cluster: true
+`, "orders", "cluster_api_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	// Both Mappings asked for the "api" virtual cluster. One of them gets it, and the other
+	// gets one with its own name in it, rather than the two sharing one set of stats.
+	vcs := virtualClusters(listener)
+	require.Len(t, vcs, 2)
+	require.Contains(t, vcs, "api")
+
+	renamed := ""
+	for name := range vcs {
+		if name != "api" {
+			renamed = name
+		}
+	}
+	require.Contains(t, []string{"api_users_default", "api_orders_default"}, renamed)
+
+	paths := []string{}
+	for _, vc := range vcs {
+		require.NotEmpty(t, vc.Headers)
+		paths = append(paths, vc.Headers[0].GetPrefixMatch())
+	}
+	assert.ElementsMatch(t, []string{"/users/", "/orders/"}, paths)
+
+	key := strings.TrimPrefix(strings.TrimSuffix(renamed, "_default"), "api_") + ".default"
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return len(diag.NoticesFor(key)) > 0
+	})
+	require.NoError(t, err)
+	assert.True(t, hasNotice(diag, key, "virtual cluster api is already"))
+	assert.True(t, hasNotice(diag, key, "so this Mapping's is "+renamed))
+}
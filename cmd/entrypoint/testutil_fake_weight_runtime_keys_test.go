@@ -0,0 +1,164 @@
+package entrypoint_test
+
+import (
+	"testing"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	route "github.com/datawire/ambassador/v2/pkg/api/envoy/config/route/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeightRuntimeKeys(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: checkout
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /checkout/
+  service: checkout.default
+  weight_runtime_key_prefix: routing.canary.checkout
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: checkout-canary
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /checkout/
+  service: checkout-canary.default
+  weight: 10
+  weight_runtime_key_prefix: routing.canary.checkout
+`+entrypoint.FakeMappingYAML("search")+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: search-canary
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /search/
+  service: search-canary.default
+  weight: 10
+`, "search-canary", "cluster_search_canary_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	routesFor := func(prefix string) []*route.Route {
+		var routes []*route.Route
+		vh := findVirtualHost(listener, "*")
+		require.NotNil(t, vh)
+		for _, r := range vh.Routes {
+			if r.Match.GetPrefix() == prefix {
+				routes = append(routes, r)
+			}
+		}
+		return routes
+	}
+
+	// The runtime-keyed group is a single route, so one runtime update moves both weights
+	// together: there's no window where the canary's fraction and the rest disagree.
+	checkout := routesFor("/checkout/")
+	require.Len(t, checkout, 1)
+	assert.Nil(t, checkout[0].Match.GetRuntimeFraction())
+
+	wc := checkout[0].GetRoute().GetWeightedClusters()
+	require.NotNil(t, wc)
+	assert.Equal(t, "routing.canary.checkout", wc.RuntimeKeyPrefix)
+	assert.Equal(t, uint32(100), wc.GetTotalWeight().GetValue())
+
+	// Until routing.canary.checkout.<cluster> is set in the runtime, each cluster gets the
+	// weight its Mapping asked for.
+	weights := map[string]uint32{}
+	for _, c := range wc.Clusters {
+		weights[c.Name] = c.GetWeight().GetValue()
+	}
+	assert.Equal(t, map[string]uint32{
+		"cluster_checkout_canary_default_default": 10,
+		"cluster_checkout_default_default":        90,
+	}, weights)
+
+	// Groups without the prefix still fall through their runtime_fractions.
+	search := routesFor("/search/")
+	require.Len(t, search, 2)
+	assert.Equal(t, "cluster_search_canary_default_default", search[0].GetRoute().GetCluster())
+	assert.Equal(t, "routing.traffic_shift.cluster_search_canary_default_default", search[0].Match.GetRuntimeFraction().GetRuntimeKey())
+}
+
+func TestWeightRuntimeKeysDifferentRouteSettings(t *testing.T) {
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	config := f.UpsertYAMLAndGetEnvoyConfig(entrypoint.FakeListenerYAML+`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: orders
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /orders/
+  service: orders.default
+  weight_runtime_key_prefix: routing.canary.orders
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: orders-canary
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /orders/
+  rewrite: /v2/orders/
+  timeout_ms: 1000
+  service: orders-canary.default
+  weight: 10
+  weight_runtime_key_prefix: routing.canary.orders
+`, "orders-canary", "cluster_orders_canary_default_default")
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	vh := findVirtualHost(listener, "*")
+	require.NotNil(t, vh)
+	routes := map[string]*route.Route{}
+	for _, r := range vh.Routes {
+		if r.Match.GetPrefix() == "/orders/" {
+			routes[r.GetRoute().GetCluster()] = r
+		}
+	}
+
+	// One weighted route would have to pick one Mapping's rewrite and timeout for both of
+	// them, so each Mapping keeps its own route (and settings) instead.
+	require.Len(t, routes, 2)
+
+	canary := routes["cluster_orders_canary_default_default"]
+	require.NotNil(t, canary)
+	assert.Equal(t, "/v2/orders/", canary.GetRoute().PrefixRewrite)
+	assert.Equal(t, int64(1), canary.GetRoute().GetTimeout().GetSeconds())
+	assert.Equal(t, "routing.traffic_shift.cluster_orders_canary_default_default", canary.Match.GetRuntimeFraction().GetRuntimeKey())
+
+	orders := routes["cluster_orders_default_default"]
+	require.NotNil(t, orders)
+	assert.Equal(t, "/", orders.GetRoute().PrefixRewrite)
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Error("orders-canary.default") != ""
+	})
+	require.NoError(t, err)
+	assert.Contains(t, diag.Error("orders-canary.default"), "set rewrite, timeout_ms differently")
+	assert.Contains(t, diag.Error("orders.default"), "set rewrite, timeout_ms differently")
+}
@@ -0,0 +1,268 @@
+package entrypoint_test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/datawire/ambassador/v2/cmd/entrypoint"
+	v3bootstrap "github.com/datawire/ambassador/v2/pkg/api/envoy/config/bootstrap/v3"
+	v3cluster "github.com/datawire/ambassador/v2/pkg/api/envoy/config/cluster/v3"
+	v3listener "github.com/datawire/ambassador/v2/pkg/api/envoy/config/listener/v3"
+	responsemap "github.com/datawire/ambassador/v2/pkg/api/envoy/extensions/filters/http/response_map/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroReplicas(t *testing.T) {
+	// This test will not pass in legacy mode because diagd will not emit EDS clusters in legacy mode.
+	if legacy, err := strconv.ParseBool(os.Getenv("AMBASSADOR_LEGACY_MODE")); err == nil && legacy {
+		return
+	}
+
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(serviceWithEndpoints("scaler") + serviceWithEndpoints("interceptor") + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    zero_replicas:
+      respond:
+        body: 'Starting up, try again shortly'
+` + entrypoint.FakeListenerYAML + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: scaler
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /scaler/
+  service: scaler
+  resolver: endpoint
+  zero_replicas:
+    hold:
+      service: interceptor
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: reports
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /reports/
+  service: reports
+  resolver: endpoint
+` + entrypoint.FakeMappingYAML("plain") + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: unwatched
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /unwatched/
+  service: unwatched.default
+  zero_replicas:
+    respond:
+      body: 'Unavailable'
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: both
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /both/
+  service: both
+  resolver: endpoint
+  static_fallback:
+    body: 'Down'
+  zero_replicas:
+    respond:
+      body: 'Also down'
+`)
+	require.NoError(t, err)
+	require.NoError(t, f.Upsert(makeService("default", "reports")))
+	require.NoError(t, f.Upsert(makeEndpoints("default", "reports")))
+	f.Flush()
+
+	_, err = f.GetSnapshot(HasMapping("default", "both"))
+	require.NoError(t, err)
+
+	var hold *v3cluster.Cluster
+	config, err := f.GetEnvoyConfig(func(config *v3bootstrap.Bootstrap) bool {
+		hold = FindCluster(config, func(c *v3cluster.Cluster) bool {
+			return aggregateClusters(t, c) != nil
+		})
+		return hold != nil && FindCluster(config, ClusterNameContains("cluster_unwatched_default")) != nil
+	})
+	require.NoError(t, err)
+
+	listener := findListener(config, func(l *v3listener.Listener) bool {
+		return l.Name == "ambassador-listener-8080"
+	})
+	require.NotNil(t, listener)
+
+	perRoute := func(prefix string) *responsemap.ResponseMapPerRoute {
+		routes := prefixRoutes(listener, prefix)
+		require.Len(t, routes, 1, prefix)
+		cfg, ok := routes[0].TypedPerFilterConfig["envoy.filters.http.response_map"]
+		if !ok {
+			return nil
+		}
+		rm := &responsemap.ResponseMapPerRoute{}
+		require.NoError(t, ptypes.UnmarshalAny(cfg, rm))
+		return rm
+	}
+
+	// reports has scaled to nothing, so it gets the Module's response instead of a bare 503.
+	rm := perRoute("/reports/")
+	require.NotNil(t, rm)
+	mappers := rm.GetResponseMap().GetMappers()
+	require.Len(t, mappers, 1)
+	assert.Equal(t, []string{"UH"}, mappers[0].GetFilter().GetResponseFlagFilter().GetFlags())
+	assert.Equal(t, uint32(503), mappers[0].GetStatusCode().GetValue())
+	assert.Equal(t, "Starting up, try again shortly", mappers[0].GetBody().GetInlineString())
+
+	// Through a Kubernetes Service, there's no telling, so plain doesn't get the Module's and
+	// unwatched's own is ignored.
+	assert.Nil(t, perRoute("/plain/"))
+	assert.Nil(t, perRoute("/unwatched/"))
+
+	// scaler's own zero_replicas holds instead of answering: its route goes to an aggregate of
+	// its own cluster and then the interceptor's.
+	assert.Nil(t, perRoute("/scaler/"))
+	routes := prefixRoutes(listener, "/scaler/")
+	require.Len(t, routes, 1)
+	assert.Equal(t, hold.Name, routes[0].GetRoute().GetCluster())
+
+	members := aggregateClusters(t, hold)
+	require.Len(t, members, 2)
+	scalerCluster := FindCluster(config, func(c *v3cluster.Cluster) bool { return c.Name == members[0] })
+	interceptorCluster := FindCluster(config, func(c *v3cluster.Cluster) bool { return c.Name == members[1] })
+	require.NotNil(t, scalerCluster)
+	require.NotNil(t, interceptorCluster)
+	scalerPath := scalerCluster.GetEdsClusterConfig().GetServiceName()
+	interceptorPath := interceptorCluster.GetEdsClusterConfig().GetServiceName()
+	assert.Contains(t, scalerPath, "k8s/default/scaler")
+	assert.Contains(t, interceptorPath, "k8s/default/interceptor")
+
+	// While scaler has endpoints, it gets everything...
+	assignments, err := f.GetLoadAssignments(hasLoadAssignmentIPs(interceptorPath, "10.42.0.15"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.42.0.15"}, loadAssignmentIPs(assignments[scalerPath]))
+	assert.Equal(t, scalerCluster.Name, f.AggregateTarget(config, assignments, hold.Name))
+
+	// ...when it scales to zero, the interceptor holds its requests...
+	require.NoError(t, f.Upsert(makeEndpoints("default", "scaler")))
+	f.Flush()
+	assignments, err = f.GetLoadAssignments(hasLoadAssignmentIPs(scalerPath))
+	require.NoError(t, err)
+	assert.Equal(t, interceptorCluster.Name, f.AggregateTarget(config, assignments, hold.Name))
+
+	// ...and once it's back, it gets them again. None of that needs new routes or clusters.
+	subset, err := makeSubset(8080, "10.42.0.16")
+	require.NoError(t, err)
+	require.NoError(t, f.Upsert(makeEndpoints("default", "scaler", subset)))
+	f.Flush()
+	assignments, err = f.GetLoadAssignments(hasLoadAssignmentIPs(scalerPath, "10.42.0.16"))
+	require.NoError(t, err)
+	assert.Equal(t, scalerCluster.Name, f.AggregateTarget(config, assignments, hold.Name))
+	f.AssertNoReconfigure(2 * time.Second)
+
+	diag, err := f.GetDiagnostics(func(diag *entrypoint.Diagnostics) bool {
+		return diag.Error("both.default") != ""
+	})
+	require.NoError(t, err)
+	assert.Contains(t, diag.Error("both.default"), "Invalid zero_replicas: respond and static_fallback can't both be set")
+	assert.True(t, hasNotice(diag, "unwatched.default", "resolver kubernetes-service doesn't watch endpoints, ignoring zero_replicas"))
+	assert.False(t, hasNotice(diag, "plain.default", "zero_replicas"))
+}
+
+func TestZeroReplicasConsulModuleHold(t *testing.T) {
+	// This test will not pass in legacy mode because diagd will not emit EDS clusters in legacy mode.
+	if legacy, err := strconv.ParseBool(os.Getenv("AMBASSADOR_LEGACY_MODE")); err == nil && legacy {
+		return
+	}
+
+	f := entrypoint.RunFake(t, entrypoint.FakeConfig{EnvoyConfig: true}, nil)
+	err := f.UpsertYAML(`
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    zero_replicas:
+      hold:
+        service: interceptor
+` + entrypoint.FakeListenerYAML + `
+---
+apiVersion: getambassador.io/v3alpha1
+kind: ConsulResolver
+metadata:
+  name: consul-dc1
+  namespace: default
+spec:
+  address: consul-server.default:8500
+  datacenter: dc1
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Mapping
+metadata:
+  name: quote
+  namespace: default
+spec:
+  hostname: "*"
+  prefix: /quote/
+  service: quote
+  resolver: consul-dc1
+`)
+	require.NoError(t, err)
+	f.ConsulEndpoint("dc1", "quote", "1.2.3.4", 8080)
+	f.ConsulEndpoint("dc1", "interceptor", "1.2.3.5", 8080)
+	f.Flush()
+
+	_, err = f.GetSnapshot(HasMapping("default", "quote"))
+	require.NoError(t, err)
+
+	var hold *v3cluster.Cluster
+	config, err := f.GetEnvoyConfig(func(config *v3bootstrap.Bootstrap) bool {
+		hold = FindCluster(config, func(c *v3cluster.Cluster) bool {
+			return aggregateClusters(t, c) != nil
+		})
+		return hold != nil
+	})
+	require.NoError(t, err)
+
+	// The Mapping has no zero_replicas of its own, so it holds with the Module's, and the
+	// interceptor is found with the Mapping's consul resolver...
+	members := aggregateClusters(t, hold)
+	require.Len(t, members, 2)
+	quoteCluster := FindCluster(config, func(c *v3cluster.Cluster) bool { return c.Name == members[0] })
+	interceptorCluster := FindCluster(config, func(c *v3cluster.Cluster) bool { return c.Name == members[1] })
+	require.NotNil(t, quoteCluster)
+	require.NotNil(t, interceptorCluster)
+	assert.Equal(t, "consul/dc1/quote", quoteCluster.GetEdsClusterConfig().GetServiceName())
+	assert.Equal(t, "consul/dc1/interceptor", interceptorCluster.GetEdsClusterConfig().GetServiceName())
+
+	// ...which means consul has to be watching it, or there'd be nothing to hold with.
+	assignments, err := f.GetLoadAssignments(hasLoadAssignmentIPs("consul/dc1/interceptor", "1.2.3.5"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.2.3.4"}, loadAssignmentIPs(assignments["consul/dc1/quote"]))
+}
@@ -2,7 +2,6 @@ package entrypoint
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"sync"
@@ -12,6 +11,7 @@ import (
 
 	"github.com/datawire/ambassador/v2/cmd/ambex"
 	"github.com/datawire/ambassador/v2/pkg/acp"
+	amb "github.com/datawire/ambassador/v2/pkg/api/getambassador.io/v3alpha1"
 	"github.com/datawire/ambassador/v2/pkg/debug"
 	ecp_v2_cache "github.com/datawire/ambassador/v2/pkg/envoy-control-plane/cache/v2"
 	"github.com/datawire/ambassador/v2/pkg/gateway"
@@ -25,6 +25,7 @@ func watcher(
 	ctx context.Context,
 	ambwatch *acp.AmbassadorWatcher,
 	encoded *atomic.Value,
+	exporter *snapshotExporter,
 	fastpathCh chan<- *ambex.FastpathSnapshot,
 	clusterID string,
 	version string,
@@ -48,9 +49,13 @@ func watcher(
 
 	// **** SETUP DONE for the Kubernetes Watcher
 
-	notify := func(ctx context.Context, disposition SnapshotDisposition, _ []byte) error {
+	notify := func(ctx context.Context, disposition SnapshotDisposition, snapJSON []byte, module *amb.Module) error {
 		if disposition == SnapshotReady {
-			return notifyReconfigWebhooks(ctx, ambwatch)
+			if err := notifyReconfigWebhooks(ctx, ambwatch); err != nil {
+				return err
+			}
+			updateUpstreamReadiness(ctx, ambwatch, module)
+			exporter.Notify(ctx, snapJSON, module)
 		}
 		return nil
 	}
@@ -89,7 +94,10 @@ func getAmbassadorMeta(ambassadorID string, clusterID string, version string, cl
 	return ambMeta
 }
 
-type SnapshotProcessor func(context.Context, SnapshotDisposition, []byte) error
+// A SnapshotProcessor gets each snapshot as JSON, along with a copy of the ambassador Module
+// it has (nil if there isn't one), so that anything that only cares about the Module's settings
+// can find them without decoding the whole snapshot.
+type SnapshotProcessor func(ctx context.Context, disposition SnapshotDisposition, snapJSON []byte, module *amb.Module) error
 type SnapshotDisposition int
 
 const (
@@ -435,6 +443,12 @@ func (sh *SnapshotHolder) K8sUpdate(
 				if sh.endpointRoutingInfo.endpointWatches[key] || sh.dispatcher.IsWatched(delta.Namespace, delta.Name) {
 					endpointsChanged = true
 				}
+			} else if delta.Kind == "EndpointSlice" {
+				// The delta doesn't say which Service the slice is for, so any slice
+				// changing might change the locality of an endpoint we care about.
+				if len(sh.endpointRoutingInfo.endpointWatches) > 0 {
+					endpointsChanged = true
+				}
 			} else {
 				endpointsOnly = false
 			}
@@ -546,6 +560,7 @@ func (sh *SnapshotHolder) Notify(
 
 	// If the change is solely endpoints we don't bother making a snapshot.
 	var snapshotJSON []byte
+	var module *amb.Module
 	var bootstrapped bool
 	changed := true
 
@@ -562,12 +577,20 @@ func (sh *SnapshotHolder) Notify(
 			Kubernetes:     sh.k8sSnapshot,
 			Consul:         sh.consulSnapshot,
 			Invalid:        sh.validator.getInvalid(),
+			Certificates:   certificateStatuses(sh.k8sSnapshot.Secrets),
 			Deltas:         sh.unsentDeltas,
 			AmbassadorMeta: sh.ambassadorMeta,
+			Generation:     sh.snapshotChangeCount,
+		}
+
+		// The watcher goes on changing the snapshot once we let go of the mutex, so the
+		// processors get their own copy of the Module.
+		if m := findAmbassadorModule(ctx, sh.k8sSnapshot); m != nil {
+			module = m.DeepCopy()
 		}
 
 		var err error
-		snapshotJSON, err = json.MarshalIndent(sn, "", "  ")
+		snapshotJSON, err = limitSnapshot(ctx, sn, module)
 		if err != nil {
 			return err
 		}
@@ -598,13 +621,13 @@ func (sh *SnapshotHolder) Notify(
 		// know about the new configuration.
 		var err error
 		notifyWebhooksTimer.Time(func() {
-			err = snapshotProcessor(ctx, SnapshotReady, snapshotJSON)
+			err = snapshotProcessor(ctx, SnapshotReady, snapshotJSON, module)
 		})
 		if err != nil {
 			return err
 		}
 	}
-	return snapshotProcessor(ctx, SnapshotIncomplete, snapshotJSON)
+	return snapshotProcessor(ctx, SnapshotIncomplete, snapshotJSON, module)
 }
 
 // The kates aka "real" version of our injected dependencies.
@@ -107,6 +107,15 @@ func (w *AmbassadorWatcher) FetchEnvoyReady(ctx context.Context) {
 	w.ew.FetchEnvoyReady(ctx)
 }
 
+// SetUpstreamReadiness sets what fraction of upstream clusters must be healthy for
+// Ambassador to be ready, and which clusters are health checked. See upstream.go.
+func (w *AmbassadorWatcher) SetUpstreamReadiness(required float64, healthChecked map[string]bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.ew.SetUpstreamReadiness(required, healthChecked)
+}
+
 // NoteSnapshotSent will note that a snapshot has been sent.
 func (w *AmbassadorWatcher) NoteSnapshotSent() {
 	w.mutex.Lock()
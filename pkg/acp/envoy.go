@@ -6,15 +6,18 @@
 // Envoy - and just Envoy, all other Ambassador elements are ignored - and tell you
 // whether it's alive and ready, or not.
 //
-// At the moment, "alive" and "ready" mean the same thing for an EnvoyWatcher. Both
-// IsAlive() and IsReady() methods exist, though, for a future in which we monitor
-// them separately.
+// By default, "alive" and "ready" mean the same thing for an EnvoyWatcher. With
+// health-based readiness on (see upstream.go), being ready also requires enough of
+// Envoy's upstream clusters to be healthy.
 //
 // TESTING HOOKS:
 // Since we try to check Envoy readiness to see how Envoy is doing, you can use
 // EnvoyWatcher.SetReadyCheck to change the function that EnvoyWatcher uses to
 // check readiness. The default is EnvoyWatcher.defaultFetcher, which tries to pull
-// readiness from http://localhost:8001/ready.
+// readiness from http://localhost:8001/ready. Likewise EnvoyWatcher.SetUpstreamCheck
+// changes how upstream cluster health is fetched when health-based readiness is on
+// (see upstream.go); the default pulls http://localhost:8001/clusters?format=json, at most
+// once every DefaultUpstreamCheckInterval (SetUpstreamCheckInterval changes that).
 //
 // This hook is NOT meant for you to change the fetcher on the fly in a running
 // EnvoyWatcher. Set it at instantiation, then leave it alone. See envoy_test.go
@@ -33,6 +36,11 @@ import (
 	"github.com/datawire/dlib/dlog"
 )
 
+// DefaultUpstreamCheckInterval is the least time between fetches of upstream cluster health.
+// Every readiness probe checks on Envoy, but /clusters is a lot bigger than /ready, and a
+// cluster's health checks don't run that often anyway.
+const DefaultUpstreamCheckInterval = 5 * time.Second
+
 // EnvoyWatcher encapsulates state and methods for keeping an eye on a running
 // Envoy, and deciding if it's healthy.
 type EnvoyWatcher struct {
@@ -45,12 +53,28 @@ type EnvoyWatcher struct {
 
 	// Did the last ready check succeed?
 	LastSucceeded bool
+
+	// How shall we fetch upstream cluster health, and how often?
+	upstreamCheck    envoyFetcher
+	upstreamInterval time.Duration
+
+	// What fraction of upstream clusters must be healthy for Envoy to be ready (zero means
+	// we don't care), and which clusters are health checked?
+	requiredHealthy float64
+	healthChecked   map[string]bool
+
+	// How many healthy endpoints did each cluster have at the last check, and when was that?
+	// (Zero if there hasn't been one since the configuration changed.)
+	healthyHosts      map[string]int
+	upstreamCheckedAt time.Time
 }
 
 // NewEnvoyWatcher creates a new EnvoyWatcher, given a fetcher.
 func NewEnvoyWatcher() *EnvoyWatcher {
 	w := &EnvoyWatcher{}
 	w.SetReadyCheck(w.defaultFetcher)
+	w.SetUpstreamCheck(w.defaultUpstreamFetcher)
+	w.SetUpstreamCheckInterval(DefaultUpstreamCheckInterval)
 
 	return w
 }
@@ -58,6 +82,17 @@ func NewEnvoyWatcher() *EnvoyWatcher {
 // This the default Fetcher for the EnvoyWatcher -- it actually connects to Envoy
 // and checks for ready.
 func (w *EnvoyWatcher) defaultFetcher(ctx context.Context) (*EnvoyFetcherResponse, error) {
+	return w.fetch(ctx, "/ready")
+}
+
+// This is the default upstream Fetcher for the EnvoyWatcher -- it asks Envoy for the
+// health of every endpoint of every cluster.
+func (w *EnvoyWatcher) defaultUpstreamFetcher(ctx context.Context) (*EnvoyFetcherResponse, error) {
+	return w.fetch(ctx, "/clusters?format=json")
+}
+
+// fetch GETs a path from Envoy's admin interface.
+func (w *EnvoyWatcher) fetch(ctx context.Context, path string) (*EnvoyFetcherResponse, error) {
 	// Set up a context with a deliberate 2-second timeout. Envoy shouldn't ever take more
 	// than 100ms to answer the ready check, and if we don't pick a short timeout here,
 	// this call can hang for way longer than we would like it to.
@@ -65,7 +100,7 @@ func (w *EnvoyWatcher) defaultFetcher(ctx context.Context) (*EnvoyFetcherRespons
 	defer tcancel()
 
 	// Build a request...
-	req, err := http.NewRequestWithContext(tctx, http.MethodGet, "http://localhost:8001/ready", nil)
+	req, err := http.NewRequestWithContext(tctx, http.MethodGet, "http://localhost:8001"+path, nil)
 
 	if err != nil {
 		// ...which should never fail. WTFO?
@@ -78,7 +113,7 @@ func (w *EnvoyWatcher) defaultFetcher(ctx context.Context) (*EnvoyFetcherRespons
 	if err != nil {
 		// Unlike the last error case, this one isn't a weird situation at
 		// all -- e.g. if Envoy isn't running yet, we'll land here.
-		return nil, fmt.Errorf("error fetching /ready: %v", err)
+		return nil, fmt.Errorf("error fetching %s: %v", path, err)
 	}
 
 	// Don't forget to close the body once done.
@@ -106,6 +141,32 @@ func (w *EnvoyWatcher) SetReadyCheck(readyCheck envoyFetcher) {
 	w.readyCheck = readyCheck
 }
 
+// SetUpstreamCheck will change the function we use to fetch upstream cluster health. Like
+// SetReadyCheck, this is here for testing.
+func (w *EnvoyWatcher) SetUpstreamCheck(upstreamCheck envoyFetcher) {
+	w.upstreamCheck = upstreamCheck
+}
+
+// SetUpstreamCheckInterval will change the least time between fetches of upstream cluster
+// health. Like SetReadyCheck, this is here for testing.
+func (w *EnvoyWatcher) SetUpstreamCheckInterval(interval time.Duration) {
+	w.upstreamInterval = interval
+}
+
+// SetUpstreamReadiness sets what fraction of upstream clusters must be healthy for Envoy
+// to be ready, and which clusters are health checked. This changes whenever the
+// configuration does; a required fraction of zero turns health-based readiness off.
+func (w *EnvoyWatcher) SetUpstreamReadiness(required float64, healthChecked map[string]bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.requiredHealthy = required
+	w.healthChecked = healthChecked
+
+	// Take a fresh look at the next check, since the clusters have likely changed.
+	w.upstreamCheckedAt = time.Time{}
+}
+
 // FetchEnvoyReady will check whether Envoy's ready endpoint is fetchable.
 func (w *EnvoyWatcher) FetchEnvoyReady(ctx context.Context) {
 	succeeded := false
@@ -125,8 +186,42 @@ func (w *EnvoyWatcher) FetchEnvoyReady(ctx context.Context) {
 	}
 
 	w.mutex.Lock()
-	defer w.mutex.Unlock()
+	required := w.requiredHealthy
 	w.LastSucceeded = succeeded
+	checkedAt := w.upstreamCheckedAt
+	w.mutex.Unlock()
+
+	// If readiness depends on upstream health, go find out how the upstreams are doing too,
+	// unless we did that recently enough. Not being able to tell counts as nothing being
+	// healthy.
+	var healthyHosts map[string]int
+
+	if succeeded && required > 0 {
+		if !checkedAt.IsZero() && time.Since(checkedAt) < w.upstreamInterval {
+			return
+		}
+
+		clustersResponse, err := w.upstreamCheck(ctx)
+
+		if err == nil && clustersResponse.StatusCode == 200 {
+			healthyHosts, err = ParseClusterHealth(clustersResponse.Text)
+		} else if err == nil {
+			err = fmt.Errorf("status %d", clustersResponse.StatusCode)
+		}
+
+		if err != nil {
+			dlog.Debugf(ctx, "could not fetch Envoy cluster health: %v", err)
+		}
+
+		checkedAt = time.Now()
+	} else {
+		checkedAt = time.Time{}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.healthyHosts = healthyHosts
+	w.upstreamCheckedAt = checkedAt
 }
 
 // IsAlive returns true IFF Envoy should be considered alive.
@@ -139,8 +234,16 @@ func (w *EnvoyWatcher) IsAlive() bool {
 	return w.LastSucceeded
 }
 
-// IsReady returns true IFF Envoy should be considered ready. Currently Envoy is
-// considered ready whenever it's alive; this method is here for future-proofing.
+// IsReady returns true IFF Envoy should be considered ready. By default Envoy is
+// considered ready whenever it's alive; with health-based readiness on, enough of its
+// upstream clusters must be healthy as well.
 func (w *EnvoyWatcher) IsReady() bool {
-	return w.IsAlive()
+	if !w.IsAlive() {
+		return false
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return UpstreamsReady(w.requiredHealthy, w.healthChecked, w.healthyHosts)
 }
@@ -0,0 +1,84 @@
+// Copyright 2021 Datawire. All rights reserved.
+//
+// This is the upstream half of health-based readiness: if the ambassador Module sets
+// readiness_healthy_cluster_fraction, Ambassador isn't ready until at least that fraction of
+// its upstream clusters has a healthy endpoint, as far as Envoy's own health checking can tell.
+//
+// Envoy only knows whether an endpoint is healthy for clusters that are actively health
+// checked, so clusters without health checks are always counted as healthy: we'd rather be
+// ready than wait on something nobody is checking.
+
+package acp
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+
+	v3admin "github.com/datawire/ambassador/v2/pkg/api/envoy/admin/v3"
+	v3core "github.com/datawire/ambassador/v2/pkg/api/envoy/config/core/v3"
+)
+
+// HealthyFraction works out what fraction of clusters is healthy. healthChecked says which
+// clusters exist, and whether each is health checked; healthyHosts says how many healthy
+// endpoints each cluster has. A cluster that isn't health checked is always healthy, and if
+// there are no clusters at all, everything is healthy.
+func HealthyFraction(healthChecked map[string]bool, healthyHosts map[string]int) float64 {
+	if len(healthChecked) == 0 {
+		return 1
+	}
+
+	healthy := 0
+
+	for name, checked := range healthChecked {
+		if !checked || healthyHosts[name] > 0 {
+			healthy++
+		}
+	}
+
+	return float64(healthy) / float64(len(healthChecked))
+}
+
+// UpstreamsReady returns true IFF at least the required fraction of clusters is healthy. A
+// required fraction of zero (the default) means that readiness doesn't depend on upstream
+// health at all.
+func UpstreamsReady(required float64, healthChecked map[string]bool, healthyHosts map[string]int) bool {
+	if required <= 0 {
+		return true
+	}
+
+	return HealthyFraction(healthChecked, healthyHosts) >= required
+}
+
+// ParseClusterHealth counts the healthy endpoints of each cluster in the output of Envoy's
+// /clusters?format=json admin endpoint.
+func ParseClusterHealth(text []byte) (map[string]int, error) {
+	var clusters v3admin.Clusters
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(text, &clusters); err != nil {
+		return nil, err
+	}
+
+	healthyHosts := map[string]int{}
+
+	for _, cluster := range clusters.GetClusterStatuses() {
+		for _, host := range cluster.GetHostStatuses() {
+			if hostHealthy(host.GetHealthStatus()) {
+				healthyHosts[cluster.GetName()]++
+			}
+		}
+	}
+
+	return healthyHosts, nil
+}
+
+// hostHealthy decides whether Envoy would send traffic to an endpoint.
+func hostHealthy(status *v3admin.HostHealthStatus) bool {
+	if status.GetFailedActiveHealthCheck() || status.GetPendingActiveHc() {
+		return false
+	}
+
+	switch status.GetEdsHealthStatus() {
+	case v3core.HealthStatus_UNHEALTHY, v3core.HealthStatus_DRAINING, v3core.HealthStatus_TIMEOUT:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,180 @@
+package acp_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/datawire/ambassador/v2/pkg/acp"
+	"github.com/datawire/dlib/dlog"
+)
+
+// This is what Envoy's /clusters?format=json looks like, trimmed down: "checked" has one
+// endpoint failing its health check and one fine, "failing" has its only endpoint failing,
+// "pending" hasn't been checked yet, and "unchecked" isn't health checked at all.
+const clustersJSON = `{
+  "cluster_statuses": [
+    {
+      "name": "checked",
+      "added_via_api": true,
+      "host_statuses": [
+        {"health_status": {"failed_active_health_check": true, "eds_health_status": "HEALTHY"}},
+        {"health_status": {"eds_health_status": "HEALTHY"}, "weight": 1}
+      ]
+    },
+    {
+      "name": "failing",
+      "added_via_api": true,
+      "host_statuses": [
+        {"health_status": {"failed_active_health_check": true, "eds_health_status": "HEALTHY"}}
+      ]
+    },
+    {
+      "name": "pending",
+      "added_via_api": true,
+      "host_statuses": [
+        {"health_status": {"pending_active_hc": true, "eds_health_status": "HEALTHY"}}
+      ]
+    },
+    {
+      "name": "unchecked",
+      "added_via_api": true,
+      "host_statuses": [
+        {"health_status": {"eds_health_status": "UNHEALTHY"}}
+      ]
+    }
+  ]
+}`
+
+func TestParseClusterHealth(t *testing.T) {
+	healthyHosts, err := acp.ParseClusterHealth([]byte(clustersJSON))
+	if err != nil {
+		t.Fatalf("ParseClusterHealth: %v", err)
+	}
+
+	expected := map[string]int{"checked": 1, "failing": 0, "pending": 0, "unchecked": 0}
+
+	for name, count := range expected {
+		if healthyHosts[name] != count {
+			t.Errorf("cluster %s: %d healthy hosts, wanted %d", name, healthyHosts[name], count)
+		}
+	}
+}
+
+func TestHealthyFraction(t *testing.T) {
+	healthyHosts, err := acp.ParseClusterHealth([]byte(clustersJSON))
+	if err != nil {
+		t.Fatalf("ParseClusterHealth: %v", err)
+	}
+
+	healthChecked := map[string]bool{
+		"checked":   true,
+		"failing":   true,
+		"pending":   true,
+		"unchecked": false,
+	}
+
+	// "unchecked" counts as healthy even though EDS says otherwise, since we only go by
+	// health checks.
+	if f := acp.HealthyFraction(healthChecked, healthyHosts); f != 0.5 {
+		t.Errorf("HealthyFraction %f, wanted 0.5", f)
+	}
+
+	if !acp.UpstreamsReady(0.5, healthChecked, healthyHosts) {
+		t.Errorf("UpstreamsReady(0.5) false, wanted true")
+	}
+
+	if acp.UpstreamsReady(0.75, healthChecked, healthyHosts) {
+		t.Errorf("UpstreamsReady(0.75) true, wanted false")
+	}
+
+	// With no required fraction, upstream health doesn't matter...
+	if !acp.UpstreamsReady(0, healthChecked, nil) {
+		t.Errorf("UpstreamsReady(0) false, wanted true")
+	}
+
+	// ...and no clusters at all is healthy.
+	if f := acp.HealthyFraction(nil, nil); f != 1 {
+		t.Errorf("HealthyFraction with no clusters %f, wanted 1", f)
+	}
+}
+
+func TestEnvoyUpstreamReadiness(t *testing.T) {
+	m := newEnvoyMetadata(t, Happy)
+
+	clusters := clustersJSON
+	m.ew.SetUpstreamCheck(func(ctx context.Context) (*acp.EnvoyFetcherResponse, error) {
+		return &acp.EnvoyFetcherResponse{StatusCode: 200, Text: []byte(clusters)}, nil
+	})
+	// Look at the upstreams every time, so that we see them change right away.
+	m.ew.SetUpstreamCheckInterval(0)
+
+	// Without health-based readiness, upstream health is ignored.
+	m.ew.FetchEnvoyReady(dlog.NewTestContext(t, false))
+	m.check(1, true)
+
+	// Turn it on: half the clusters are healthy, which isn't enough for 0.75...
+	m.ew.SetUpstreamReadiness(0.75, map[string]bool{
+		"checked":   true,
+		"failing":   true,
+		"pending":   true,
+		"unchecked": false,
+	})
+	m.ew.FetchEnvoyReady(dlog.NewTestContext(t, false))
+
+	if !m.ew.IsAlive() {
+		t.Errorf("EnvoyWatcher.IsAlive false, wanted true")
+	}
+
+	if m.ew.IsReady() {
+		t.Errorf("EnvoyWatcher.IsReady true with half the clusters healthy, wanted false")
+	}
+
+	// ...until the pending health check passes.
+	clusters = `{"cluster_statuses": [
+		{"name": "checked", "host_statuses": [{"health_status": {}}]},
+		{"name": "pending", "host_statuses": [{"health_status": {}}]}
+	]}`
+	m.ew.FetchEnvoyReady(dlog.NewTestContext(t, false))
+
+	if !m.ew.IsReady() {
+		t.Errorf("EnvoyWatcher.IsReady false with three quarters of the clusters healthy, wanted true")
+	}
+}
+
+func TestEnvoyUpstreamCheckInterval(t *testing.T) {
+	m := newEnvoyMetadata(t, Happy)
+
+	fetches := 0
+	m.ew.SetUpstreamCheck(func(ctx context.Context) (*acp.EnvoyFetcherResponse, error) {
+		fetches++
+		return &acp.EnvoyFetcherResponse{StatusCode: 200, Text: []byte(clustersJSON)}, nil
+	})
+	m.ew.SetUpstreamCheckInterval(time.Hour)
+
+	// Without health-based readiness, there's nothing to fetch...
+	m.ew.FetchEnvoyReady(dlog.NewTestContext(t, false))
+	if fetches != 0 {
+		t.Errorf("%d upstream fetches with health-based readiness off, wanted 0", fetches)
+	}
+
+	// ...and with it, fetching once an interval is enough...
+	m.ew.SetUpstreamReadiness(0.5, map[string]bool{"checked": true, "unchecked": false})
+	m.ew.FetchEnvoyReady(dlog.NewTestContext(t, false))
+	m.ew.FetchEnvoyReady(dlog.NewTestContext(t, false))
+	if fetches != 1 {
+		t.Errorf("%d upstream fetches within an interval, wanted 1", fetches)
+	}
+
+	// ...while still going by what that fetch said...
+	if !m.ew.IsReady() {
+		t.Errorf("EnvoyWatcher.IsReady false between upstream fetches, wanted true")
+	}
+
+	// ...until the configuration changes.
+	m.ew.SetUpstreamReadiness(0.5, map[string]bool{"checked": true, "failing": true})
+	m.ew.FetchEnvoyReady(dlog.NewTestContext(t, false))
+	if fetches != 2 {
+		t.Errorf("%d upstream fetches after a configuration change, wanted 2", fetches)
+	}
+}
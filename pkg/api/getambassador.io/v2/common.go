@@ -97,11 +97,16 @@ import (
 
 type CircuitBreaker struct {
 	// +kubebuilder:validation:Enum={"default", "high"}
-	Priority           string `json:"priority,omitempty"`
-	MaxConnections     *int   `json:"max_connections,omitempty"`
-	MaxPendingRequests *int   `json:"max_pending_requests,omitempty"`
-	MaxRequests        *int   `json:"max_requests,omitempty"`
-	MaxRetries         *int   `json:"max_retries,omitempty"`
+	Priority string `json:"priority,omitempty"`
+	// MaxConnections limits the connections to the whole cluster. There's no limit per
+	// upstream host: the Envoy we ship has no per_host_thresholds.
+	MaxConnections     *int `json:"max_connections,omitempty"`
+	MaxPendingRequests *int `json:"max_pending_requests,omitempty"`
+	MaxRequests        *int `json:"max_requests,omitempty"`
+	MaxRetries         *int `json:"max_retries,omitempty"`
+	// MaxConnectionPools limits how many connection pools the cluster may have open at
+	// once.
+	MaxConnectionPools *int `json:"max_connection_pools,omitempty"`
 }
 
 // ErrorResponseTextFormatSource specifies a source for an error response body
@@ -80,7 +80,9 @@ type MappingSpec struct {
 	ClusterIdleTimeout           *MillisecondDuration `json:"cluster_idle_timeout_ms,omitempty"`
 	ClusterMaxConnectionLifetime *MillisecondDuration `json:"cluster_max_connection_lifetime_ms,omitempty"`
 	// The timeout for requests that use this Mapping. Overrides `cluster_request_timeout_ms` set on the Ambassador Module, if it exists.
-	Timeout     *MillisecondDuration `json:"timeout_ms,omitempty"`
+	// 0 disables it, which streaming endpoints (e.g. server-sent events) usually want together with an IdleTimeout.
+	Timeout *MillisecondDuration `json:"timeout_ms,omitempty"`
+	// The timeout for requests that use this Mapping to go without any activity upstream or downstream. It's separate from Timeout.
 	IdleTimeout *MillisecondDuration `json:"idle_timeout_ms,omitempty"`
 	TLS         *BoolOrString        `json:"tls,omitempty"`
 
@@ -136,6 +138,175 @@ type MappingSpec struct {
 
 	// +k8s:conversion-gen:rename=StatsName
 	V3StatsName string `json:"v3StatsName,omitempty"`
+	// +k8s:conversion-gen:rename=UpstreamBindAddress
+	V3UpstreamBindAddress string `json:"v3UpstreamBindAddress,omitempty"`
+	// +k8s:conversion-gen:rename=ShadowRuntimeKey
+	V3ShadowRuntimeKey string `json:"v3ShadowRuntimeKey,omitempty"`
+	// +k8s:conversion-gen:rename=ShadowOnCircuitBreak
+	V3ShadowOnCircuitBreak *ShadowOnCircuitBreak `json:"v3ShadowOnCircuitBreak,omitempty"`
+	// +k8s:conversion-gen:rename=ShadowCloneCluster
+	V3ShadowCloneCluster *bool `json:"v3ShadowCloneCluster,omitempty"`
+	// +k8s:conversion-gen:rename=TrailingSlash
+	V3TrailingSlash string `json:"v3TrailingSlash,omitempty"`
+	// +k8s:conversion-gen:rename=EchoRequestID
+	V3EchoRequestID *bool `json:"v3EchoRequestID,omitempty"`
+	// +k8s:conversion-gen:rename=AutoSNI
+	V3AutoSNI *bool `json:"v3AutoSNI,omitempty"`
+	// +k8s:conversion-gen:rename=AutoHTTPProtocol
+	V3AutoHTTPProtocol *bool `json:"v3AutoHTTPProtocol,omitempty"`
+	// +k8s:conversion-gen:rename=JWT
+	V3JWT *JWTRequirement `json:"v3JWT,omitempty"`
+	// +k8s:conversion-gen:rename=StaticFallback
+	V3StaticFallback *StaticFallback `json:"v3StaticFallback,omitempty"`
+	// +k8s:conversion-gen:rename=VirtualCluster
+	V3VirtualCluster *bool `json:"v3VirtualCluster,omitempty"`
+	// +k8s:conversion-gen:rename=HostRewriteFromSNI
+	V3HostRewriteFromSNI *bool `json:"v3HostRewriteFromSNI,omitempty"`
+	// +k8s:conversion-gen:rename=DNSFailureRefreshRate
+	V3DNSFailureRefreshRate *MillisecondDuration `json:"v3DNSFailureRefreshRate,omitempty"`
+	// +k8s:conversion-gen:rename=InitialFetchTimeout
+	V3InitialFetchTimeout *MillisecondDuration `json:"v3InitialFetchTimeout,omitempty"`
+	// +k8s:conversion-gen:rename=HealthChecks
+	V3HealthChecks []HealthCheck `json:"v3HealthChecks,omitempty"`
+	// +k8s:conversion-gen:rename=MeshMTLS
+	V3MeshMTLS string `json:"v3MeshMTLS,omitempty"`
+	// +k8s:conversion-gen:rename=MaxGRPCTimeout
+	V3MaxGRPCTimeout *MillisecondDuration `json:"v3MaxGRPCTimeout,omitempty"`
+	// +k8s:conversion-gen:rename=GRPCTimeoutOffset
+	V3GRPCTimeoutOffset *MillisecondDuration `json:"v3GRPCTimeoutOffset,omitempty"`
+	// +k8s:conversion-gen:rename=DynamicForwardProxy
+	V3DynamicForwardProxy *DynamicForwardProxy `json:"v3DynamicForwardProxy,omitempty"`
+	// +k8s:conversion-gen:rename=SuppressEnvoyHeaders
+	V3SuppressEnvoyHeaders *bool `json:"v3SuppressEnvoyHeaders,omitempty"`
+	// +k8s:conversion-gen:rename=DecompressorMaxRequestBytes
+	V3DecompressorMaxRequestBytes *int `json:"v3DecompressorMaxRequestBytes,omitempty"`
+	// +k8s:conversion-gen:rename=ClusterProtocolOptions
+	V3ClusterProtocolOptions *UntypedDict `json:"v3ClusterProtocolOptions,omitempty"`
+	// +k8s:conversion-gen:rename=ClusterDrainTime
+	V3ClusterDrainTime *MillisecondDuration `json:"v3ClusterDrainTime,omitempty"`
+	// +k8s:conversion-gen:rename=HTTP2KeepAlive
+	V3HTTP2KeepAlive *HTTP2KeepAlive `json:"v3HTTP2KeepAlive,omitempty"`
+	// +k8s:conversion-gen:rename=RetryBufferLimitBytes
+	V3RetryBufferLimitBytes *int `json:"v3RetryBufferLimitBytes,omitempty"`
+	// +k8s:conversion-gen:rename=PreserveSensitiveHeaders
+	V3PreserveSensitiveHeaders []string `json:"v3PreserveSensitiveHeaders,omitempty"`
+	// +k8s:conversion-gen:rename=ContentTypes
+	V3ContentTypes []string `json:"v3ContentTypes,omitempty"`
+	// +k8s:conversion-gen:rename=ResponseFlagStats
+	V3ResponseFlagStats []string `json:"v3ResponseFlagStats,omitempty"`
+	// +k8s:conversion-gen:rename=Methods
+	V3Methods []string `json:"v3Methods,omitempty"`
+	// +k8s:conversion-gen:rename=BypassCompression
+	V3BypassCompression *bool `json:"v3BypassCompression,omitempty"`
+	// +k8s:conversion-gen:rename=RequestTimeoutHeader
+	V3RequestTimeoutHeader *RequestTimeoutHeader `json:"v3RequestTimeoutHeader,omitempty"`
+	// +k8s:conversion-gen:rename=CookieAttributes
+	V3CookieAttributes *CookieAttributes `json:"v3CookieAttributes,omitempty"`
+	// +k8s:conversion-gen:rename=WeightRuntimeKeyPrefix
+	V3WeightRuntimeKeyPrefix string `json:"v3WeightRuntimeKeyPrefix,omitempty"`
+	// +k8s:conversion-gen:rename=BodyTransform
+	V3BodyTransform *BodyTransform `json:"v3BodyTransform,omitempty"`
+	// +k8s:conversion-gen:rename=TypedPerFilterConfig
+	V3TypedPerFilterConfig *UntypedDict `json:"v3TypedPerFilterConfig,omitempty"`
+	// +k8s:conversion-gen:rename=HealthRebalance
+	V3HealthRebalance *bool `json:"v3HealthRebalance,omitempty"`
+	// +k8s:conversion-gen:rename=HealthRebalanceRecoveryMs
+	V3HealthRebalanceRecoveryMs *int `json:"v3HealthRebalanceRecoveryMs,omitempty"`
+	// +k8s:conversion-gen:rename=HistogramBuckets
+	V3HistogramBuckets []int `json:"v3HistogramBuckets,omitempty"`
+	// +k8s:conversion-gen:rename=DisableHistograms
+	V3DisableHistograms *bool `json:"v3DisableHistograms,omitempty"`
+	// +k8s:conversion-gen:rename=CaseInsensitiveHeaders
+	V3CaseInsensitiveHeaders []string `json:"v3CaseInsensitiveHeaders,omitempty"`
+	// +k8s:conversion-gen:rename=MaxRequestsPerConnection
+	V3MaxRequestsPerConnection *int `json:"v3MaxRequestsPerConnection,omitempty"`
+	// +k8s:conversion-gen:rename=ABHeader
+	V3ABHeader *ABHeader `json:"v3ABHeader,omitempty"`
+	// +k8s:conversion-gen:rename=ResolverFallback
+	V3ResolverFallback []string `json:"v3ResolverFallback,omitempty"`
+	// +k8s:conversion-gen:rename=StatsDimensions
+	V3StatsDimensions []StatsDimension `json:"v3StatsDimensions,omitempty"`
+	// +k8s:conversion-gen:rename=Hedging
+	V3Hedging *Hedging `json:"v3Hedging,omitempty"`
+	// +k8s:conversion-gen:rename=ZeroReplicas
+	V3ZeroReplicas *ZeroReplicas `json:"v3ZeroReplicas,omitempty"`
+	// +k8s:conversion-gen:rename=LogSampling
+	V3LogSampling *LogSampling `json:"v3LogSampling,omitempty"`
+}
+
+// DynamicForwardProxy configures a Mapping that forwards to the host each request names.
+type DynamicForwardProxy struct {
+	// AllowedHosts are the hosts that requests may be forwarded to, as exact names or
+	// "*.example.com" for any subdomain; "*" allows every host. Requests for any other host
+	// don't match the Mapping. The list becomes a regex, so a long one may need the
+	// Ambassador Module's `regex_max_size` raised.
+	//
+	// +kubebuilder:validation:MinItems=1
+	AllowedHosts []string `json:"allowed_hosts"`
+}
+
+// HealthCheck is an active health check, which Envoy sends to every endpoint of a Mapping's
+// service on its own schedule, whether or not any traffic is being routed there.
+type HealthCheck struct {
+	// Timeout for each health check. Defaults to 3 seconds.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// Interval between health checks. Defaults to 5 seconds.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// IntervalJitter adds a random delay of up to this long to every interval, so that
+	// replicas that started together don't keep checking the same endpoints together.
+	IntervalJitter *metav1.Duration `json:"interval_jitter,omitempty"`
+	// IntervalJitterPercent adds a random delay of up to this percentage of the interval.
+	// It can be combined with IntervalJitter, in which case both are added.
+	//
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	IntervalJitterPercent int `json:"interval_jitter_percent,omitempty"`
+	// Number of failed checks before an endpoint is considered unhealthy. Defaults to 2.
+	UnhealthyThreshold int `json:"unhealthy_threshold,omitempty"`
+	// Number of successful checks before an endpoint is considered healthy again.
+	// Defaults to 1.
+	HealthyThreshold int `json:"healthy_threshold,omitempty"`
+
+	// What to check.
+	HealthCheckSpecifier HealthCheckSpecifier `json:"health_check"`
+}
+
+// HealthCheckSpecifier says what to check: exactly one of http or grpc must be given.
+//
+// +kubebuilder:validation:MaxProperties=1
+// +kubebuilder:validation:MinProperties=1
+type HealthCheckSpecifier struct {
+	HTTPHealthCheck *HTTPHealthCheck `json:"http,omitempty"`
+	GRPCHealthCheck *GRPCHealthCheck `json:"grpc,omitempty"`
+}
+
+// HTTPHealthCheck checks an endpoint with an HTTP GET.
+type HTTPHealthCheck struct {
+	// +kubebuilder:validation:Required
+	Path string `json:"path"`
+	// Hostname is the Host header for the check. Defaults to the name of the cluster.
+	Hostname string `json:"hostname,omitempty"`
+	// ExpectedStatuses are the status codes that count as healthy. Defaults to 200.
+	ExpectedStatuses []HealthCheckStatusRange `json:"expected_statuses,omitempty"`
+}
+
+// HealthCheckStatusRange is a range of HTTP status codes, including both ends.
+type HealthCheckStatusRange struct {
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=599
+	Min int `json:"min"`
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=599
+	Max int `json:"max"`
+}
+
+// GRPCHealthCheck checks an endpoint with the standard gRPC health checking protocol
+// (grpc.health.v1.Health/Check).
+type GRPCHealthCheck struct {
+	// ServiceName is the service to ask about. Defaults to the server as a whole.
+	ServiceName string `json:"service_name,omitempty"`
+	// Authority is the :authority header for the check. Defaults to the name of the cluster.
+	Authority string `json:"authority,omitempty"`
 }
 
 type RegexMap struct {
@@ -339,6 +510,209 @@ type KeepAlive struct {
 	Interval *int `json:"interval,omitempty"`
 }
 
+// HTTP2KeepAlive has Envoy send HTTP/2 PING frames on idle upstream connections, so that
+// connections that a load balancer in the way has silently reaped get noticed and closed.
+type HTTP2KeepAlive struct {
+	// Interval between PINGs. Required.
+	Interval *MillisecondDuration `json:"interval_ms,omitempty"`
+	// Timeout is how long to wait for a PING to be answered before closing the connection.
+	// Required.
+	Timeout *MillisecondDuration `json:"timeout_ms,omitempty"`
+	// IntervalJitterPercent adds a random delay of up to this percentage of the interval.
+	// Envoy defaults to 15.
+	//
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	IntervalJitterPercent *int `json:"interval_jitter_percent,omitempty"`
+}
+
+// RequestTimeoutHeader says what to do with the `x-envoy-upstream-rq-timeout-ms` header, which
+// lets a request set its own upstream timeout. Envoy already drops it from requests that don't
+// come from an internal address (as long as `use_remote_address` is on), so it's only trusted
+// clients that can set it in the first place.
+type RequestTimeoutHeader struct {
+	// Honor the header. Defaults to true; false drops it from every request.
+	Honor *bool `json:"honor,omitempty"`
+	// Max is the longest timeout the header can ask for; anything longer (or 0, which would
+	// mean no timeout at all) gets this instead. Only valid if the header is honored.
+	Max *MillisecondDuration `json:"max_ms,omitempty"`
+}
+
+// ABHeader adds a marker header to a share of a Mapping's requests, for A/B testing without
+// a second backend. The requests that get it and the ones that don't go to the same service.
+type ABHeader struct {
+	// Name is the header to add.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// Value is what the header is set to.
+	// +kubebuilder:validation:Required
+	Value string `json:"value"`
+	// Percent is how many requests out of every 100 get the header. 0 adds it to none and
+	// 100 to all of them.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Percent int `json:"percent"`
+	// Cookie, if set, is a cookie that keeps each client on the side it was first put on:
+	// the first response sets it (for the rest of the browser session), and requests that
+	// already have it skip the draw.
+	Cookie string `json:"cookie,omitempty"`
+}
+
+// StatsDimension splits a Mapping's request stats by the value of a request header, as a
+// tag on its virtual cluster's stats. Envoy already tags those with the response code and
+// its class (envoy_response_code, envoy_response_code_class).
+type StatsDimension struct {
+	// Name is the tag the value shows up as. It must be lowercase letters, digits, and
+	// underscores, starting with a letter.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern="^[a-z][a-z0-9_]*$"
+	Name string `json:"name"`
+	// Header is the request header to take the value from; use `:method` for the method.
+	// +kubebuilder:validation:Required
+	Header string `json:"header"`
+	// Values are the values that get a tag value of their own. Requests without the
+	// header, or with any other value, are tagged `other`: Envoy can only count requests
+	// against values it knows about up front, which also keeps the number of time series
+	// bounded.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	Values []string `json:"values"`
+}
+
+// Hedging sends a try that runs out of its retry_policy per_try_timeout to another endpoint
+// as well, without cancelling it, and takes whichever answers first.
+type Hedging struct {
+	// BudgetPercent caps the extra requests -- hedged ones and retries alike, since Envoy
+	// counts hedges as retries -- at this percentage of the requests active on the
+	// Mapping's cluster. 0 means none at all, which turns hedging off.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	BudgetPercent int `json:"budget_percent"`
+	// MinConcurrency is how many extra requests are allowed regardless of the budget, so
+	// that a quiet cluster can still hedge. Defaults to 3.
+	// +kubebuilder:validation:Minimum=0
+	MinConcurrency *int `json:"min_concurrency,omitempty"`
+}
+
+// ZeroReplicas is what to do with a Mapping's requests while its service has no endpoints,
+// as when it has scaled to zero. Exactly one of Respond and Hold must be set.
+type ZeroReplicas struct {
+	// Respond answers the request with this response, as with `static_fallback`.
+	Respond *StaticFallback `json:"respond,omitempty"`
+	// Hold sends the request to another service that holds on to it until the Mapping's
+	// service is back, then passes it on.
+	Hold *ZeroReplicasHold `json:"hold,omitempty"`
+}
+
+// ZeroReplicasHold names the service that holds requests while a Mapping's service scales up
+// from zero: something like Knative's activator or the KEDA HTTP add-on's interceptor, which
+// starts the scale-up itself. It's found with the Mapping's resolver, and how long it holds is
+// up to it, bounded by the Mapping's timeout_ms.
+type ZeroReplicasHold struct {
+	// +kubebuilder:validation:Required
+	Service string `json:"service"`
+}
+
+// LogSampling logs only Percent of a Mapping's requests, except that a response with at least
+// AlwaysLogStatus, or one Envoy flagged (say, a long response that was cut off partway), is
+// always logged.
+type LogSampling struct {
+	// Percent is the percentage of the rest that are logged. They're sampled on the request
+	// ID, as traces are. The Envoy runtime key `log_sampling.<name>.<namespace>.percent`
+	// overrides it.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Percent int `json:"percent"`
+	// AlwaysLogStatus is the lowest status that's always logged. Defaults to 300, so that
+	// only 1xx and 2xx responses are sampled.
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=599
+	AlwaysLogStatus *int `json:"always_log_status,omitempty"`
+}
+
+// ShadowOnCircuitBreak makes a `shadow` Mapping mirror nothing until its runtime key says
+// otherwise. Envoy can't see a circuit breaker tripping from inside a route, so whatever
+// watches the primary cluster's circuit_breakers stats (an alert, an operator) sets the key
+// to the percentage to mirror while it's tripped, and back to 0 after.
+type ShadowOnCircuitBreak struct {
+	// RuntimeKey is the Envoy runtime key that turns mirroring on. Defaults to
+	// `shadow_on_circuit_break.<name>.<namespace>` for the shadow Mapping.
+	RuntimeKey string `json:"runtime_key,omitempty"`
+	// MaxRequests caps how many mirrored requests can be in flight to the shadow at once,
+	// so that mirroring during an outage can't pile onto it. Defaults to 10. Ignored if
+	// the shadow Mapping has circuit_breakers of its own.
+	//
+	// +kubebuilder:validation:Minimum=1
+	MaxRequests *int `json:"max_requests,omitempty"`
+}
+
+// CookieAttributes are attributes to add to every Set-Cookie header in a response that
+// doesn't already have them. A cookie that already says its SameSite keeps it.
+type CookieAttributes struct {
+	// Secure adds the Secure attribute.
+	Secure *bool `json:"secure,omitempty"`
+	// SameSite adds SameSite with this value. None is only allowed along with Secure,
+	// since browsers reject SameSite=None cookies that aren't Secure.
+	//
+	// +kubebuilder:validation:Enum={"Strict","Lax","None"}
+	SameSite string `json:"same_site,omitempty"`
+}
+
+// BodyTransform is a pair of templates for request and response bodies. In a template,
+// `{{ body }}` stands for the original body, exactly as it was (so wrapping a JSON body is
+// `{"data": {{ body }}}`), and everything else is literal text. Only bodies with a
+// Content-Length of at most MaxBytes and one of ContentTypes get transformed: streamed or
+// compressed bodies, bodies of other types, and empty bodies pass through untouched.
+type BodyTransform struct {
+	// Request is the template for the body sent upstream.
+	Request string `json:"request,omitempty"`
+	// Response is the template for the body sent back to the client.
+	Response string `json:"response,omitempty"`
+	// ContentTypes are the media types to transform; parameters like charset don't count.
+	// Defaults to application/json.
+	ContentTypes []string `json:"content_types,omitempty"`
+	// MaxBytes is the largest body to transform, since transforming a body means buffering
+	// all of it. Defaults to 65536, and can't be more than the Ambassador Module's
+	// `buffer_limit_bytes` (1MiB if that isn't set).
+	MaxBytes *int `json:"max_bytes,omitempty"`
+}
+
+// StaticFallback is a response for Envoy to send itself when a Mapping's cluster has no
+// healthy endpoints, instead of its usual 503. It takes over from `error_response_overrides`
+// for those requests, and applies even with `bypass_error_response_overrides`.
+type StaticFallback struct {
+	// StatusCode is the status of the response. Defaults to 503.
+	//
+	// +kubebuilder:validation:Minimum=200
+	// +kubebuilder:validation:Maximum=599
+	StatusCode *int `json:"status_code,omitempty"`
+	// Body is the body of the response, as is.
+	Body string `json:"body"`
+	// ContentType is the Content-Type of the response. Defaults to text/plain.
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// JWTRequirement is the JWT a Mapping's requests must carry: one from Provider, with every
+// one of Claims. A request without a valid JWT gets a 401, and one whose JWT doesn't have the
+// claims gets a 403.
+type JWTRequirement struct {
+	// Provider is the name of one of the Ambassador Module's `jwt_providers`. It can be left
+	// out if there's only one.
+	Provider string `json:"provider,omitempty"`
+	// Claims must all match for a request to get through.
+	Claims []JWTClaim `json:"claims,omitempty"`
+}
+
+// JWTClaim matches a JWT claim that is one of Values or, for an array claim, that has one of
+// Values in it. A JWT without the claim doesn't match.
+type JWTClaim struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
 type CORS struct {
 	Origins        *OriginList        `json:"origins,omitempty"`
 	Methods        StringOrStringList `json:"methods,omitempty"`
@@ -396,6 +770,53 @@ type RetryPolicy struct {
 	RetryOn       string `json:"retry_on,omitempty"`
 	NumRetries    *int   `json:"num_retries,omitempty"`
 	PerTryTimeout string `json:"per_try_timeout,omitempty"`
+
+	// GRPCRetryOn lists the gRPC status codes that should be retried, e.g. "unavailable".
+	// These are combined with RetryOn. Note that Envoy doesn't know whether a gRPC method
+	// is idempotent, so only list codes that are safe to retry for every method behind
+	// this Mapping.
+	GRPCRetryOn []string `json:"grpc_retry_on,omitempty"`
+
+	// RetryBackOff overrides Envoy's default exponential backoff between retries (a
+	// 25ms base interval, capped at 10 times the base).
+	RetryBackOff *RetryBackOff `json:"retry_back_off,omitempty"`
+
+	// RetryOtherHosts makes each retry avoid the upstream hosts already tried for this
+	// request, when there are any others to pick. With a single host it changes nothing.
+	RetryOtherHosts *bool `json:"retry_other_hosts,omitempty"`
+
+	// HostSelectionRetryMaxAttempts is how many times Envoy will pick again when it lands on
+	// a host it has already tried, before settling for it anyway (Envoy's default is 1). It
+	// only means anything with RetryOtherHosts.
+	HostSelectionRetryMaxAttempts *int `json:"host_selection_retry_max_attempts,omitempty"`
+
+	// RetryOnReset also retries requests that the upstream resets, or never accepts a
+	// connection or stream for, before sending any response. (It can be used with or instead
+	// of RetryOn.) A reset can come after the upstream has already seen the request, so
+	// with RetryOnReset the policy only ever retries idempotent requests (GET, HEAD,
+	// OPTIONS, TRACE, PUT and DELETE), unless RetryNonIdempotent is set too.
+	RetryOnReset *bool `json:"retry_on_reset,omitempty"`
+
+	// RetryNonIdempotent lets RetryOnReset retry requests of any method, for upstreams
+	// where repeating a POST or PATCH is known to be harmless.
+	RetryNonIdempotent *bool `json:"retry_non_idempotent,omitempty"`
+
+	// RetryOnResponseHeaders retries a request when the upstream's response has any of these
+	// headers, with the given value, or with any value at all when the value is "". (It can
+	// be used with or instead of RetryOn.) Envoy picks the cluster before it sends the
+	// request, so the retry can only go to another host of the same cluster -- which it
+	// will, given RetryOtherHosts -- never to a different one. Only the response headers
+	// count, not its body or trailers, and a request body Envoy couldn't buffer whole can't
+	// be retried at all.
+	RetryOnResponseHeaders map[string]string `json:"retry_on_response_headers,omitempty"`
+}
+
+// RetryBackOff is the exponential backoff between retries. Envoy picks each delay at
+// random between zero and the current interval, which starts at the base interval and
+// doubles with every retry until it hits the max interval.
+type RetryBackOff struct {
+	BaseInterval *MillisecondDuration `json:"base_interval_ms,omitempty"`
+	MaxInterval  *MillisecondDuration `json:"max_interval_ms,omitempty"`
 }
 
 type LoadBalancer struct {
@@ -405,6 +826,19 @@ type LoadBalancer struct {
 	Cookie   *LoadBalancerCookie `json:"cookie,omitempty"`
 	Header   string              `json:"header,omitempty"`
 	SourceIp *bool               `json:"source_ip,omitempty"`
+
+	// ChoiceCount is how many random hosts policy least_request compares for each request,
+	// picking the one with the fewest active requests; Envoy's default is 2. More choices
+	// get closer to the least loaded host in a large pool, but look at more hosts for every
+	// request.
+	// +kubebuilder:validation:Minimum=2
+	ChoiceCount *int `json:"choice_count,omitempty"`
+
+	// Path has policy ring_hash or maglev hash on the request's path, so that each path (or
+	// path prefix) keeps going to the same endpoint; `path: {}` hashes on the whole path. The
+	// query string is never part of the hash. With maglev, an endpoint coming or going moves
+	// as few paths as it can to other endpoints.
+	Path *LoadBalancerPath `json:"path,omitempty"`
 }
 
 type LoadBalancerCookie struct {
@@ -414,6 +848,13 @@ type LoadBalancerCookie struct {
 	Ttl  string `json:"ttl,omitempty"`
 }
 
+type LoadBalancerPath struct {
+	// Segments, if set, hashes on only this many leading segments of the path, so that
+	// `segments: 1` sends everything under `/assets/` (say) to the same endpoint.
+	// +kubebuilder:validation:Minimum=1
+	Segments *int `json:"segments,omitempty"`
+}
+
 // MappingStatus defines the observed state of Mapping
 type MappingStatus struct {
 	// +kubebuilder:validation:Enum={"","Inactive","Running"}
@@ -38,6 +38,8 @@ type RateLimitServiceSpec struct {
 
 	// +k8s:conversion-gen:rename=StatsName
 	V3StatsName string `json:"v3StatsName,omitempty"`
+	// +k8s:conversion-gen:rename=FailureModeDeny
+	V3FailureModeDeny *bool `json:"v3FailureModeDeny,omitempty"`
 }
 
 // RateLimitService is the Schema for the ratelimitservices API
@@ -48,6 +48,8 @@ type TCPMappingSpec struct {
 
 	// +k8s:conversion-gen:rename=StatsName
 	V3StatsName string `json:"v3StatsName,omitempty"`
+	// +k8s:conversion-gen:rename=ALPNProtocols
+	V3ALPNProtocols []string `json:"v3ALPNProtocols,omitempty"`
 }
 
 // TCPMapping is the Schema for the tcpmappings API
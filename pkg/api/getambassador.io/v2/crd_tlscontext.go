@@ -44,6 +44,17 @@ type TLSContextSpec struct {
 	SecretNamespacing     *bool    `json:"secret_namespacing,omitempty"`
 	RedirectCleartextFrom *int     `json:"redirect_cleartext_from,omitempty"`
 	SNI                   string   `json:"sni,omitempty"`
+
+	// +k8s:conversion-gen:rename=InheritFrom
+	V3InheritFrom string `json:"v3InheritFrom,omitempty"`
+	// +k8s:conversion-gen:rename=SPIFFEIDs
+	V3SPIFFEIDs []string `json:"v3SPIFFEIDs,omitempty"`
+	// +k8s:conversion-gen:rename=SPIFFETrustDomain
+	V3SPIFFETrustDomain string `json:"v3SPIFFETrustDomain,omitempty"`
+	// +k8s:conversion-gen:rename=AdditionalSecrets
+	V3AdditionalSecrets []string `json:"v3AdditionalSecrets,omitempty"`
+	// +k8s:conversion-gen:rename=OCSPStaplePolicy
+	V3OCSPStaplePolicy string `json:"v3OCSPStaplePolicy,omitempty"`
 }
 
 // TLSContext is the Schema for the tlscontexts API
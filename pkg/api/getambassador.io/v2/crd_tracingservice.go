@@ -41,11 +41,51 @@ type TraceConfig struct {
 	ServiceName              string `json:"service_name,omitempty"`
 }
 
+// TraceCustomTag is a tag to put on every span, with its value from exactly one of Literal,
+// RequestHeader, Environment, or Metadata.
+type TraceCustomTag struct {
+	// +kubebuilder:validation:Required
+	Tag           string              `json:"tag,omitempty"`
+	Literal       *TraceLiteralTag    `json:"literal,omitempty"`
+	RequestHeader *TraceValueFromName `json:"request_header,omitempty"`
+	Environment   *TraceValueFromName `json:"environment,omitempty"`
+	Metadata      *TraceMetadataTag   `json:"metadata,omitempty"`
+}
+
+type TraceLiteralTag struct {
+	// +kubebuilder:validation:Required
+	Value string `json:"value,omitempty"`
+}
+
+// TraceValueFromName takes a tag's value from the request header, or the environment variable,
+// with the given name. Without a DefaultValue, a span whose request doesn't have the header
+// (or an Ambassador without the variable) doesn't get the tag at all.
+type TraceValueFromName struct {
+	// +kubebuilder:validation:Required
+	Name         string `json:"name,omitempty"`
+	DefaultValue string `json:"default_value,omitempty"`
+}
+
+// TraceMetadataTag takes a tag's value from the metadata of the request itself, or of the
+// route, cluster, or endpoint that it went to: Key is the metadata namespace (like
+// "envoy.lb"), and Path the keys to follow within it, of which there must be at least one.
+// Without a DefaultValue, a span without that metadata doesn't get the tag.
+type TraceMetadataTag struct {
+	// +kubebuilder:validation:Enum={"request","route","cluster","host"}
+	// +kubebuilder:validation:Required
+	Kind string `json:"kind,omitempty"`
+	// +kubebuilder:validation:Required
+	Key string `json:"key,omitempty"`
+	// +kubebuilder:validation:MinItems=1
+	Path         []string `json:"path,omitempty"`
+	DefaultValue string   `json:"default_value,omitempty"`
+}
+
 // TracingServiceSpec defines the desired state of TracingService
 type TracingServiceSpec struct {
 	AmbassadorID AmbassadorID `json:"ambassador_id,omitempty"`
 
-	// +kubebuilder:validation:Enum={"lightstep","zipkin","datadog"}
+	// +kubebuilder:validation:Enum={"lightstep","zipkin","datadog","opencensus"}
 	// +kubebuilder:validation:Required
 	Driver string `json:"driver,omitempty"`
 	// +kubebuilder:validation:Required
@@ -56,6 +96,12 @@ type TracingServiceSpec struct {
 
 	// +k8s:conversion-gen:rename=StatsName
 	V3StatsName string `json:"v3StatsName,omitempty"`
+	// +k8s:conversion-gen:rename=Propagation
+	V3Propagation string `json:"v3Propagation,omitempty"`
+	// +k8s:conversion-gen:rename=TrustIncomingTraceContext
+	V3TrustIncomingTraceContext *bool `json:"v3TrustIncomingTraceContext,omitempty"`
+	// +k8s:conversion-gen:rename=CustomTags
+	V3CustomTags []TraceCustomTag `json:"v3CustomTags,omitempty"`
 }
 
 // TracingService is the Schema for the tracingservices API
@@ -32,6 +32,16 @@ func init() {
 // RegisterConversions adds conversion functions to the given scheme.
 // Public to allow building arbitrary schemes.
 func RegisterConversions(s *runtime.Scheme) error {
+	if err := s.AddGeneratedConversionFunc((*ABHeader)(nil), (*v3alpha1.ABHeader)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_ABHeader_To_v3alpha1_ABHeader(a.(*ABHeader), b.(*v3alpha1.ABHeader), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.ABHeader)(nil), (*ABHeader)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_ABHeader_To_v2_ABHeader(a.(*v3alpha1.ABHeader), b.(*ABHeader), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*ACMEProviderSpec)(nil), (*v3alpha1.ACMEProviderSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v2_ACMEProviderSpec_To_v3alpha1_ACMEProviderSpec(a.(*ACMEProviderSpec), b.(*v3alpha1.ACMEProviderSpec), scope)
 	}); err != nil {
@@ -92,6 +102,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*BodyTransform)(nil), (*v3alpha1.BodyTransform)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_BodyTransform_To_v3alpha1_BodyTransform(a.(*BodyTransform), b.(*v3alpha1.BodyTransform), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.BodyTransform)(nil), (*BodyTransform)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_BodyTransform_To_v2_BodyTransform(a.(*v3alpha1.BodyTransform), b.(*BodyTransform), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*CircuitBreaker)(nil), (*v3alpha1.CircuitBreaker)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v2_CircuitBreaker_To_v3alpha1_CircuitBreaker(a.(*CircuitBreaker), b.(*v3alpha1.CircuitBreaker), scope)
 	}); err != nil {
@@ -132,6 +152,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*CookieAttributes)(nil), (*v3alpha1.CookieAttributes)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_CookieAttributes_To_v3alpha1_CookieAttributes(a.(*CookieAttributes), b.(*v3alpha1.CookieAttributes), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.CookieAttributes)(nil), (*CookieAttributes)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_CookieAttributes_To_v2_CookieAttributes(a.(*v3alpha1.CookieAttributes), b.(*CookieAttributes), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*DevPortal)(nil), (*v3alpha1.DevPortal)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v2_DevPortal_To_v3alpha1_DevPortal(a.(*DevPortal), b.(*v3alpha1.DevPortal), scope)
 	}); err != nil {
@@ -222,6 +252,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*DynamicForwardProxy)(nil), (*v3alpha1.DynamicForwardProxy)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_DynamicForwardProxy_To_v3alpha1_DynamicForwardProxy(a.(*DynamicForwardProxy), b.(*v3alpha1.DynamicForwardProxy), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.DynamicForwardProxy)(nil), (*DynamicForwardProxy)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_DynamicForwardProxy_To_v2_DynamicForwardProxy(a.(*v3alpha1.DynamicForwardProxy), b.(*DynamicForwardProxy), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*ErrorResponseOverride)(nil), (*v3alpha1.ErrorResponseOverride)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v2_ErrorResponseOverride_To_v3alpha1_ErrorResponseOverride(a.(*ErrorResponseOverride), b.(*v3alpha1.ErrorResponseOverride), scope)
 	}); err != nil {
@@ -252,6 +292,86 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*GRPCHealthCheck)(nil), (*v3alpha1.GRPCHealthCheck)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_GRPCHealthCheck_To_v3alpha1_GRPCHealthCheck(a.(*GRPCHealthCheck), b.(*v3alpha1.GRPCHealthCheck), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.GRPCHealthCheck)(nil), (*GRPCHealthCheck)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_GRPCHealthCheck_To_v2_GRPCHealthCheck(a.(*v3alpha1.GRPCHealthCheck), b.(*GRPCHealthCheck), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*HSTSConfig)(nil), (*v3alpha1.HSTSConfig)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_HSTSConfig_To_v3alpha1_HSTSConfig(a.(*HSTSConfig), b.(*v3alpha1.HSTSConfig), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.HSTSConfig)(nil), (*HSTSConfig)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_HSTSConfig_To_v2_HSTSConfig(a.(*v3alpha1.HSTSConfig), b.(*HSTSConfig), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*HTTP2KeepAlive)(nil), (*v3alpha1.HTTP2KeepAlive)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_HTTP2KeepAlive_To_v3alpha1_HTTP2KeepAlive(a.(*HTTP2KeepAlive), b.(*v3alpha1.HTTP2KeepAlive), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.HTTP2KeepAlive)(nil), (*HTTP2KeepAlive)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_HTTP2KeepAlive_To_v2_HTTP2KeepAlive(a.(*v3alpha1.HTTP2KeepAlive), b.(*HTTP2KeepAlive), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*HTTPHealthCheck)(nil), (*v3alpha1.HTTPHealthCheck)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_HTTPHealthCheck_To_v3alpha1_HTTPHealthCheck(a.(*HTTPHealthCheck), b.(*v3alpha1.HTTPHealthCheck), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.HTTPHealthCheck)(nil), (*HTTPHealthCheck)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_HTTPHealthCheck_To_v2_HTTPHealthCheck(a.(*v3alpha1.HTTPHealthCheck), b.(*HTTPHealthCheck), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*HealthCheck)(nil), (*v3alpha1.HealthCheck)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_HealthCheck_To_v3alpha1_HealthCheck(a.(*HealthCheck), b.(*v3alpha1.HealthCheck), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.HealthCheck)(nil), (*HealthCheck)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_HealthCheck_To_v2_HealthCheck(a.(*v3alpha1.HealthCheck), b.(*HealthCheck), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*HealthCheckSpecifier)(nil), (*v3alpha1.HealthCheckSpecifier)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_HealthCheckSpecifier_To_v3alpha1_HealthCheckSpecifier(a.(*HealthCheckSpecifier), b.(*v3alpha1.HealthCheckSpecifier), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.HealthCheckSpecifier)(nil), (*HealthCheckSpecifier)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_HealthCheckSpecifier_To_v2_HealthCheckSpecifier(a.(*v3alpha1.HealthCheckSpecifier), b.(*HealthCheckSpecifier), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*HealthCheckStatusRange)(nil), (*v3alpha1.HealthCheckStatusRange)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_HealthCheckStatusRange_To_v3alpha1_HealthCheckStatusRange(a.(*HealthCheckStatusRange), b.(*v3alpha1.HealthCheckStatusRange), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.HealthCheckStatusRange)(nil), (*HealthCheckStatusRange)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_HealthCheckStatusRange_To_v2_HealthCheckStatusRange(a.(*v3alpha1.HealthCheckStatusRange), b.(*HealthCheckStatusRange), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*Hedging)(nil), (*v3alpha1.Hedging)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_Hedging_To_v3alpha1_Hedging(a.(*Hedging), b.(*v3alpha1.Hedging), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.Hedging)(nil), (*Hedging)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_Hedging_To_v2_Hedging(a.(*v3alpha1.Hedging), b.(*Hedging), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*Host)(nil), (*v3alpha1.Host)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v2_Host_To_v3alpha1_Host(a.(*Host), b.(*v3alpha1.Host), scope)
 	}); err != nil {
@@ -292,6 +412,26 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*JWTClaim)(nil), (*v3alpha1.JWTClaim)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_JWTClaim_To_v3alpha1_JWTClaim(a.(*JWTClaim), b.(*v3alpha1.JWTClaim), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.JWTClaim)(nil), (*JWTClaim)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_JWTClaim_To_v2_JWTClaim(a.(*v3alpha1.JWTClaim), b.(*JWTClaim), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*JWTRequirement)(nil), (*v3alpha1.JWTRequirement)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_JWTRequirement_To_v3alpha1_JWTRequirement(a.(*JWTRequirement), b.(*v3alpha1.JWTRequirement), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.JWTRequirement)(nil), (*JWTRequirement)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_JWTRequirement_To_v2_JWTRequirement(a.(*v3alpha1.JWTRequirement), b.(*JWTRequirement), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*KeepAlive)(nil), (*v3alpha1.KeepAlive)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v2_KeepAlive_To_v3alpha1_KeepAlive(a.(*KeepAlive), b.(*v3alpha1.KeepAlive), scope)
 	}); err != nil {
@@ -382,6 +522,26 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*LoadBalancerPath)(nil), (*v3alpha1.LoadBalancerPath)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_LoadBalancerPath_To_v3alpha1_LoadBalancerPath(a.(*LoadBalancerPath), b.(*v3alpha1.LoadBalancerPath), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.LoadBalancerPath)(nil), (*LoadBalancerPath)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_LoadBalancerPath_To_v2_LoadBalancerPath(a.(*v3alpha1.LoadBalancerPath), b.(*LoadBalancerPath), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*LogSampling)(nil), (*v3alpha1.LogSampling)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_LogSampling_To_v3alpha1_LogSampling(a.(*LogSampling), b.(*v3alpha1.LogSampling), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.LogSampling)(nil), (*LogSampling)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_LogSampling_To_v2_LogSampling(a.(*v3alpha1.LogSampling), b.(*LogSampling), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*LogService)(nil), (*v3alpha1.LogService)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v2_LogService_To_v3alpha1_LogService(a.(*LogService), b.(*v3alpha1.LogService), scope)
 	}); err != nil {
@@ -532,6 +692,26 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*RequestTimeoutHeader)(nil), (*v3alpha1.RequestTimeoutHeader)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_RequestTimeoutHeader_To_v3alpha1_RequestTimeoutHeader(a.(*RequestTimeoutHeader), b.(*v3alpha1.RequestTimeoutHeader), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.RequestTimeoutHeader)(nil), (*RequestTimeoutHeader)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_RequestTimeoutHeader_To_v2_RequestTimeoutHeader(a.(*v3alpha1.RequestTimeoutHeader), b.(*RequestTimeoutHeader), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*RetryBackOff)(nil), (*v3alpha1.RetryBackOff)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_RetryBackOff_To_v3alpha1_RetryBackOff(a.(*RetryBackOff), b.(*v3alpha1.RetryBackOff), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.RetryBackOff)(nil), (*RetryBackOff)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_RetryBackOff_To_v2_RetryBackOff(a.(*v3alpha1.RetryBackOff), b.(*RetryBackOff), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*RetryPolicy)(nil), (*v3alpha1.RetryPolicy)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v2_RetryPolicy_To_v3alpha1_RetryPolicy(a.(*RetryPolicy), b.(*v3alpha1.RetryPolicy), scope)
 	}); err != nil {
@@ -542,6 +722,36 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*ShadowOnCircuitBreak)(nil), (*v3alpha1.ShadowOnCircuitBreak)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_ShadowOnCircuitBreak_To_v3alpha1_ShadowOnCircuitBreak(a.(*ShadowOnCircuitBreak), b.(*v3alpha1.ShadowOnCircuitBreak), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.ShadowOnCircuitBreak)(nil), (*ShadowOnCircuitBreak)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_ShadowOnCircuitBreak_To_v2_ShadowOnCircuitBreak(a.(*v3alpha1.ShadowOnCircuitBreak), b.(*ShadowOnCircuitBreak), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*StaticFallback)(nil), (*v3alpha1.StaticFallback)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_StaticFallback_To_v3alpha1_StaticFallback(a.(*StaticFallback), b.(*v3alpha1.StaticFallback), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.StaticFallback)(nil), (*StaticFallback)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_StaticFallback_To_v2_StaticFallback(a.(*v3alpha1.StaticFallback), b.(*StaticFallback), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*StatsDimension)(nil), (*v3alpha1.StatsDimension)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_StatsDimension_To_v3alpha1_StatsDimension(a.(*StatsDimension), b.(*v3alpha1.StatsDimension), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.StatsDimension)(nil), (*StatsDimension)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_StatsDimension_To_v2_StatsDimension(a.(*v3alpha1.StatsDimension), b.(*StatsDimension), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*TCPMapping)(nil), (*v3alpha1.TCPMapping)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v2_TCPMapping_To_v3alpha1_TCPMapping(a.(*TCPMapping), b.(*v3alpha1.TCPMapping), scope)
 	}); err != nil {
@@ -612,6 +822,36 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*TraceCustomTag)(nil), (*v3alpha1.TraceCustomTag)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_TraceCustomTag_To_v3alpha1_TraceCustomTag(a.(*TraceCustomTag), b.(*v3alpha1.TraceCustomTag), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.TraceCustomTag)(nil), (*TraceCustomTag)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_TraceCustomTag_To_v2_TraceCustomTag(a.(*v3alpha1.TraceCustomTag), b.(*TraceCustomTag), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*TraceLiteralTag)(nil), (*v3alpha1.TraceLiteralTag)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_TraceLiteralTag_To_v3alpha1_TraceLiteralTag(a.(*TraceLiteralTag), b.(*v3alpha1.TraceLiteralTag), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.TraceLiteralTag)(nil), (*TraceLiteralTag)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_TraceLiteralTag_To_v2_TraceLiteralTag(a.(*v3alpha1.TraceLiteralTag), b.(*TraceLiteralTag), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*TraceMetadataTag)(nil), (*v3alpha1.TraceMetadataTag)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_TraceMetadataTag_To_v3alpha1_TraceMetadataTag(a.(*TraceMetadataTag), b.(*v3alpha1.TraceMetadataTag), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.TraceMetadataTag)(nil), (*TraceMetadataTag)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_TraceMetadataTag_To_v2_TraceMetadataTag(a.(*v3alpha1.TraceMetadataTag), b.(*TraceMetadataTag), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*TraceSampling)(nil), (*v3alpha1.TraceSampling)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v2_TraceSampling_To_v3alpha1_TraceSampling(a.(*TraceSampling), b.(*v3alpha1.TraceSampling), scope)
 	}); err != nil {
@@ -622,6 +862,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*TraceValueFromName)(nil), (*v3alpha1.TraceValueFromName)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_TraceValueFromName_To_v3alpha1_TraceValueFromName(a.(*TraceValueFromName), b.(*v3alpha1.TraceValueFromName), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.TraceValueFromName)(nil), (*TraceValueFromName)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_TraceValueFromName_To_v2_TraceValueFromName(a.(*v3alpha1.TraceValueFromName), b.(*TraceValueFromName), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*TracingService)(nil), (*v3alpha1.TracingService)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v2_TracingService_To_v3alpha1_TracingService(a.(*TracingService), b.(*v3alpha1.TracingService), scope)
 	}); err != nil {
@@ -662,6 +912,26 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*ZeroReplicas)(nil), (*v3alpha1.ZeroReplicas)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_ZeroReplicas_To_v3alpha1_ZeroReplicas(a.(*ZeroReplicas), b.(*v3alpha1.ZeroReplicas), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.ZeroReplicas)(nil), (*ZeroReplicas)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_ZeroReplicas_To_v2_ZeroReplicas(a.(*v3alpha1.ZeroReplicas), b.(*ZeroReplicas), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*ZeroReplicasHold)(nil), (*v3alpha1.ZeroReplicasHold)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_ZeroReplicasHold_To_v3alpha1_ZeroReplicasHold(a.(*ZeroReplicasHold), b.(*v3alpha1.ZeroReplicasHold), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v3alpha1.ZeroReplicasHold)(nil), (*ZeroReplicasHold)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v3alpha1_ZeroReplicasHold_To_v2_ZeroReplicasHold(a.(*v3alpha1.ZeroReplicasHold), b.(*ZeroReplicasHold), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddConversionFunc((*string)(nil), (**BoolOrString)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_string_To_Pointer_v2_BoolOrString(a.(*string), b.(**BoolOrString), scope)
 	}); err != nil {
@@ -775,6 +1045,32 @@ func RegisterConversions(s *runtime.Scheme) error {
 	return nil
 }
 
+func autoConvert_v2_ABHeader_To_v3alpha1_ABHeader(in *ABHeader, out *v3alpha1.ABHeader, s conversion.Scope) error {
+	out.Name = in.Name
+	out.Value = in.Value
+	out.Percent = in.Percent
+	out.Cookie = in.Cookie
+	return nil
+}
+
+// Convert_v2_ABHeader_To_v3alpha1_ABHeader is an autogenerated conversion function.
+func Convert_v2_ABHeader_To_v3alpha1_ABHeader(in *ABHeader, out *v3alpha1.ABHeader, s conversion.Scope) error {
+	return autoConvert_v2_ABHeader_To_v3alpha1_ABHeader(in, out, s)
+}
+
+func autoConvert_v3alpha1_ABHeader_To_v2_ABHeader(in *v3alpha1.ABHeader, out *ABHeader, s conversion.Scope) error {
+	out.Name = in.Name
+	out.Value = in.Value
+	out.Percent = in.Percent
+	out.Cookie = in.Cookie
+	return nil
+}
+
+// Convert_v3alpha1_ABHeader_To_v2_ABHeader is an autogenerated conversion function.
+func Convert_v3alpha1_ABHeader_To_v2_ABHeader(in *v3alpha1.ABHeader, out *ABHeader, s conversion.Scope) error {
+	return autoConvert_v3alpha1_ABHeader_To_v2_ABHeader(in, out, s)
+}
+
 func autoConvert_v2_ACMEProviderSpec_To_v3alpha1_ACMEProviderSpec(in *ACMEProviderSpec, out *v3alpha1.ACMEProviderSpec, s conversion.Scope) error {
 	out.Authority = in.Authority
 	out.Email = in.Email
@@ -1063,6 +1359,32 @@ func Convert_v3alpha1_AuthServiceStatusOnError_To_v2_AuthServiceStatusOnError(in
 	return autoConvert_v3alpha1_AuthServiceStatusOnError_To_v2_AuthServiceStatusOnError(in, out, s)
 }
 
+func autoConvert_v2_BodyTransform_To_v3alpha1_BodyTransform(in *BodyTransform, out *v3alpha1.BodyTransform, s conversion.Scope) error {
+	out.Request = in.Request
+	out.Response = in.Response
+	out.ContentTypes = in.ContentTypes
+	out.MaxBytes = in.MaxBytes
+	return nil
+}
+
+// Convert_v2_BodyTransform_To_v3alpha1_BodyTransform is an autogenerated conversion function.
+func Convert_v2_BodyTransform_To_v3alpha1_BodyTransform(in *BodyTransform, out *v3alpha1.BodyTransform, s conversion.Scope) error {
+	return autoConvert_v2_BodyTransform_To_v3alpha1_BodyTransform(in, out, s)
+}
+
+func autoConvert_v3alpha1_BodyTransform_To_v2_BodyTransform(in *v3alpha1.BodyTransform, out *BodyTransform, s conversion.Scope) error {
+	out.Request = in.Request
+	out.Response = in.Response
+	out.ContentTypes = in.ContentTypes
+	out.MaxBytes = in.MaxBytes
+	return nil
+}
+
+// Convert_v3alpha1_BodyTransform_To_v2_BodyTransform is an autogenerated conversion function.
+func Convert_v3alpha1_BodyTransform_To_v2_BodyTransform(in *v3alpha1.BodyTransform, out *BodyTransform, s conversion.Scope) error {
+	return autoConvert_v3alpha1_BodyTransform_To_v2_BodyTransform(in, out, s)
+}
+
 func autoConvert_v2_CORS_To_v3alpha1_CORS(in *CORS, out *v3alpha1.CORS, s conversion.Scope) error {
 	// WARNING: in.Origins requires manual conversion: inconvertible types (*./pkg/api/getambassador.io/v2.OriginList vs []string)
 	out.Methods = []string(in.Methods)
@@ -1090,6 +1412,7 @@ func autoConvert_v2_CircuitBreaker_To_v3alpha1_CircuitBreaker(in *CircuitBreaker
 	out.MaxPendingRequests = in.MaxPendingRequests
 	out.MaxRequests = in.MaxRequests
 	out.MaxRetries = in.MaxRetries
+	out.MaxConnectionPools = in.MaxConnectionPools
 	return nil
 }
 
@@ -1104,6 +1427,7 @@ func autoConvert_v3alpha1_CircuitBreaker_To_v2_CircuitBreaker(in *v3alpha1.Circu
 	out.MaxPendingRequests = in.MaxPendingRequests
 	out.MaxRequests = in.MaxRequests
 	out.MaxRetries = in.MaxRetries
+	out.MaxConnectionPools = in.MaxConnectionPools
 	return nil
 }
 
@@ -1208,6 +1532,28 @@ func Convert_v3alpha1_ConsulResolverSpec_To_v2_ConsulResolverSpec(in *v3alpha1.C
 	return autoConvert_v3alpha1_ConsulResolverSpec_To_v2_ConsulResolverSpec(in, out, s)
 }
 
+func autoConvert_v2_CookieAttributes_To_v3alpha1_CookieAttributes(in *CookieAttributes, out *v3alpha1.CookieAttributes, s conversion.Scope) error {
+	out.Secure = in.Secure
+	out.SameSite = in.SameSite
+	return nil
+}
+
+// Convert_v2_CookieAttributes_To_v3alpha1_CookieAttributes is an autogenerated conversion function.
+func Convert_v2_CookieAttributes_To_v3alpha1_CookieAttributes(in *CookieAttributes, out *v3alpha1.CookieAttributes, s conversion.Scope) error {
+	return autoConvert_v2_CookieAttributes_To_v3alpha1_CookieAttributes(in, out, s)
+}
+
+func autoConvert_v3alpha1_CookieAttributes_To_v2_CookieAttributes(in *v3alpha1.CookieAttributes, out *CookieAttributes, s conversion.Scope) error {
+	out.Secure = in.Secure
+	out.SameSite = in.SameSite
+	return nil
+}
+
+// Convert_v3alpha1_CookieAttributes_To_v2_CookieAttributes is an autogenerated conversion function.
+func Convert_v3alpha1_CookieAttributes_To_v2_CookieAttributes(in *v3alpha1.CookieAttributes, out *CookieAttributes, s conversion.Scope) error {
+	return autoConvert_v3alpha1_CookieAttributes_To_v2_CookieAttributes(in, out, s)
+}
+
 func autoConvert_v2_DevPortal_To_v3alpha1_DevPortal(in *DevPortal, out *v3alpha1.DevPortal, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v2_DevPortalSpec_To_v3alpha1_DevPortalSpec(&in.Spec, &out.Spec, s); err != nil {
@@ -1574,6 +1920,26 @@ func Convert_v3alpha1_DriverConfig_To_v2_DriverConfig(in *v3alpha1.DriverConfig,
 	return autoConvert_v3alpha1_DriverConfig_To_v2_DriverConfig(in, out, s)
 }
 
+func autoConvert_v2_DynamicForwardProxy_To_v3alpha1_DynamicForwardProxy(in *DynamicForwardProxy, out *v3alpha1.DynamicForwardProxy, s conversion.Scope) error {
+	out.AllowedHosts = in.AllowedHosts
+	return nil
+}
+
+// Convert_v2_DynamicForwardProxy_To_v3alpha1_DynamicForwardProxy is an autogenerated conversion function.
+func Convert_v2_DynamicForwardProxy_To_v3alpha1_DynamicForwardProxy(in *DynamicForwardProxy, out *v3alpha1.DynamicForwardProxy, s conversion.Scope) error {
+	return autoConvert_v2_DynamicForwardProxy_To_v3alpha1_DynamicForwardProxy(in, out, s)
+}
+
+func autoConvert_v3alpha1_DynamicForwardProxy_To_v2_DynamicForwardProxy(in *v3alpha1.DynamicForwardProxy, out *DynamicForwardProxy, s conversion.Scope) error {
+	out.AllowedHosts = in.AllowedHosts
+	return nil
+}
+
+// Convert_v3alpha1_DynamicForwardProxy_To_v2_DynamicForwardProxy is an autogenerated conversion function.
+func Convert_v3alpha1_DynamicForwardProxy_To_v2_DynamicForwardProxy(in *v3alpha1.DynamicForwardProxy, out *DynamicForwardProxy, s conversion.Scope) error {
+	return autoConvert_v3alpha1_DynamicForwardProxy_To_v2_DynamicForwardProxy(in, out, s)
+}
+
 func autoConvert_v2_ErrorResponseOverride_To_v3alpha1_ErrorResponseOverride(in *ErrorResponseOverride, out *v3alpha1.ErrorResponseOverride, s conversion.Scope) error {
 	out.OnStatusCode = in.OnStatusCode
 	if err := Convert_v2_ErrorResponseOverrideBody_To_v3alpha1_ErrorResponseOverrideBody(&in.Body, &out.Body, s); err != nil {
@@ -1658,62 +2024,326 @@ func Convert_v3alpha1_ErrorResponseTextFormatSource_To_v2_ErrorResponseTextForma
 	return autoConvert_v3alpha1_ErrorResponseTextFormatSource_To_v2_ErrorResponseTextFormatSource(in, out, s)
 }
 
-func autoConvert_v2_Host_To_v3alpha1_Host(in *Host, out *v3alpha1.Host, s conversion.Scope) error {
-	out.ObjectMeta = in.ObjectMeta
-	if in.Spec != nil {
-		in, out := &in.Spec, &out.Spec
-		*out = new(v3alpha1.HostSpec)
-		if err := Convert_v2_HostSpec_To_v3alpha1_HostSpec(*in, *out, s); err != nil {
-			return err
-		}
-	} else {
-		out.Spec = nil
-	}
-	if err := Convert_v2_HostStatus_To_v3alpha1_HostStatus(&in.Status, &out.Status, s); err != nil {
-		return err
-	}
+func autoConvert_v2_GRPCHealthCheck_To_v3alpha1_GRPCHealthCheck(in *GRPCHealthCheck, out *v3alpha1.GRPCHealthCheck, s conversion.Scope) error {
+	out.ServiceName = in.ServiceName
+	out.Authority = in.Authority
 	return nil
 }
 
-// Convert_v2_Host_To_v3alpha1_Host is an autogenerated conversion function.
-func Convert_v2_Host_To_v3alpha1_Host(in *Host, out *v3alpha1.Host, s conversion.Scope) error {
-	return autoConvert_v2_Host_To_v3alpha1_Host(in, out, s)
+// Convert_v2_GRPCHealthCheck_To_v3alpha1_GRPCHealthCheck is an autogenerated conversion function.
+func Convert_v2_GRPCHealthCheck_To_v3alpha1_GRPCHealthCheck(in *GRPCHealthCheck, out *v3alpha1.GRPCHealthCheck, s conversion.Scope) error {
+	return autoConvert_v2_GRPCHealthCheck_To_v3alpha1_GRPCHealthCheck(in, out, s)
 }
 
-func autoConvert_v3alpha1_Host_To_v2_Host(in *v3alpha1.Host, out *Host, s conversion.Scope) error {
-	out.ObjectMeta = in.ObjectMeta
-	if in.Spec != nil {
-		in, out := &in.Spec, &out.Spec
-		*out = new(HostSpec)
-		if err := Convert_v3alpha1_HostSpec_To_v2_HostSpec(*in, *out, s); err != nil {
-			return err
-		}
-	} else {
-		out.Spec = nil
-	}
-	if err := Convert_v3alpha1_HostStatus_To_v2_HostStatus(&in.Status, &out.Status, s); err != nil {
-		return err
-	}
+func autoConvert_v3alpha1_GRPCHealthCheck_To_v2_GRPCHealthCheck(in *v3alpha1.GRPCHealthCheck, out *GRPCHealthCheck, s conversion.Scope) error {
+	out.ServiceName = in.ServiceName
+	out.Authority = in.Authority
 	return nil
 }
 
-// Convert_v3alpha1_Host_To_v2_Host is an autogenerated conversion function.
-func Convert_v3alpha1_Host_To_v2_Host(in *v3alpha1.Host, out *Host, s conversion.Scope) error {
-	return autoConvert_v3alpha1_Host_To_v2_Host(in, out, s)
+// Convert_v3alpha1_GRPCHealthCheck_To_v2_GRPCHealthCheck is an autogenerated conversion function.
+func Convert_v3alpha1_GRPCHealthCheck_To_v2_GRPCHealthCheck(in *v3alpha1.GRPCHealthCheck, out *GRPCHealthCheck, s conversion.Scope) error {
+	return autoConvert_v3alpha1_GRPCHealthCheck_To_v2_GRPCHealthCheck(in, out, s)
 }
 
-func autoConvert_v2_HostList_To_v3alpha1_HostList(in *HostList, out *v3alpha1.HostList, s conversion.Scope) error {
-	out.ListMeta = in.ListMeta
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]v3alpha1.Host, len(*in))
-		for i := range *in {
-			if err := Convert_v2_Host_To_v3alpha1_Host(&(*in)[i], &(*out)[i], s); err != nil {
-				return err
-			}
-		}
-	} else {
-		out.Items = nil
+func autoConvert_v2_HSTSConfig_To_v3alpha1_HSTSConfig(in *HSTSConfig, out *v3alpha1.HSTSConfig, s conversion.Scope) error {
+	out.MaxAge = in.MaxAge
+	out.IncludeSubDomains = in.IncludeSubDomains
+	out.Preload = in.Preload
+	return nil
+}
+
+// Convert_v2_HSTSConfig_To_v3alpha1_HSTSConfig is an autogenerated conversion function.
+func Convert_v2_HSTSConfig_To_v3alpha1_HSTSConfig(in *HSTSConfig, out *v3alpha1.HSTSConfig, s conversion.Scope) error {
+	return autoConvert_v2_HSTSConfig_To_v3alpha1_HSTSConfig(in, out, s)
+}
+
+func autoConvert_v3alpha1_HSTSConfig_To_v2_HSTSConfig(in *v3alpha1.HSTSConfig, out *HSTSConfig, s conversion.Scope) error {
+	out.MaxAge = in.MaxAge
+	out.IncludeSubDomains = in.IncludeSubDomains
+	out.Preload = in.Preload
+	return nil
+}
+
+// Convert_v3alpha1_HSTSConfig_To_v2_HSTSConfig is an autogenerated conversion function.
+func Convert_v3alpha1_HSTSConfig_To_v2_HSTSConfig(in *v3alpha1.HSTSConfig, out *HSTSConfig, s conversion.Scope) error {
+	return autoConvert_v3alpha1_HSTSConfig_To_v2_HSTSConfig(in, out, s)
+}
+
+func autoConvert_v2_HTTP2KeepAlive_To_v3alpha1_HTTP2KeepAlive(in *HTTP2KeepAlive, out *v3alpha1.HTTP2KeepAlive, s conversion.Scope) error {
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v3alpha1.MillisecondDuration)
+		**out = v3alpha1.MillisecondDuration(**in)
+	} else {
+		out.Interval = nil
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v3alpha1.MillisecondDuration)
+		**out = v3alpha1.MillisecondDuration(**in)
+	} else {
+		out.Timeout = nil
+	}
+	out.IntervalJitterPercent = in.IntervalJitterPercent
+	return nil
+}
+
+// Convert_v2_HTTP2KeepAlive_To_v3alpha1_HTTP2KeepAlive is an autogenerated conversion function.
+func Convert_v2_HTTP2KeepAlive_To_v3alpha1_HTTP2KeepAlive(in *HTTP2KeepAlive, out *v3alpha1.HTTP2KeepAlive, s conversion.Scope) error {
+	return autoConvert_v2_HTTP2KeepAlive_To_v3alpha1_HTTP2KeepAlive(in, out, s)
+}
+
+func autoConvert_v3alpha1_HTTP2KeepAlive_To_v2_HTTP2KeepAlive(in *v3alpha1.HTTP2KeepAlive, out *HTTP2KeepAlive, s conversion.Scope) error {
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(MillisecondDuration)
+		**out = MillisecondDuration(**in)
+	} else {
+		out.Interval = nil
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(MillisecondDuration)
+		**out = MillisecondDuration(**in)
+	} else {
+		out.Timeout = nil
+	}
+	out.IntervalJitterPercent = in.IntervalJitterPercent
+	return nil
+}
+
+// Convert_v3alpha1_HTTP2KeepAlive_To_v2_HTTP2KeepAlive is an autogenerated conversion function.
+func Convert_v3alpha1_HTTP2KeepAlive_To_v2_HTTP2KeepAlive(in *v3alpha1.HTTP2KeepAlive, out *HTTP2KeepAlive, s conversion.Scope) error {
+	return autoConvert_v3alpha1_HTTP2KeepAlive_To_v2_HTTP2KeepAlive(in, out, s)
+}
+
+func autoConvert_v2_HTTPHealthCheck_To_v3alpha1_HTTPHealthCheck(in *HTTPHealthCheck, out *v3alpha1.HTTPHealthCheck, s conversion.Scope) error {
+	out.Path = in.Path
+	out.Hostname = in.Hostname
+	if in.ExpectedStatuses != nil {
+		in, out := &in.ExpectedStatuses, &out.ExpectedStatuses
+		*out = make([]v3alpha1.HealthCheckStatusRange, len(*in))
+		for i := range *in {
+			(*out)[i] = v3alpha1.HealthCheckStatusRange((*in)[i])
+		}
+	} else {
+		out.ExpectedStatuses = nil
+	}
+	return nil
+}
+
+// Convert_v2_HTTPHealthCheck_To_v3alpha1_HTTPHealthCheck is an autogenerated conversion function.
+func Convert_v2_HTTPHealthCheck_To_v3alpha1_HTTPHealthCheck(in *HTTPHealthCheck, out *v3alpha1.HTTPHealthCheck, s conversion.Scope) error {
+	return autoConvert_v2_HTTPHealthCheck_To_v3alpha1_HTTPHealthCheck(in, out, s)
+}
+
+func autoConvert_v3alpha1_HTTPHealthCheck_To_v2_HTTPHealthCheck(in *v3alpha1.HTTPHealthCheck, out *HTTPHealthCheck, s conversion.Scope) error {
+	out.Path = in.Path
+	out.Hostname = in.Hostname
+	if in.ExpectedStatuses != nil {
+		in, out := &in.ExpectedStatuses, &out.ExpectedStatuses
+		*out = make([]HealthCheckStatusRange, len(*in))
+		for i := range *in {
+			(*out)[i] = HealthCheckStatusRange((*in)[i])
+		}
+	} else {
+		out.ExpectedStatuses = nil
+	}
+	return nil
+}
+
+// Convert_v3alpha1_HTTPHealthCheck_To_v2_HTTPHealthCheck is an autogenerated conversion function.
+func Convert_v3alpha1_HTTPHealthCheck_To_v2_HTTPHealthCheck(in *v3alpha1.HTTPHealthCheck, out *HTTPHealthCheck, s conversion.Scope) error {
+	return autoConvert_v3alpha1_HTTPHealthCheck_To_v2_HTTPHealthCheck(in, out, s)
+}
+
+func autoConvert_v2_HealthCheck_To_v3alpha1_HealthCheck(in *HealthCheck, out *v3alpha1.HealthCheck, s conversion.Scope) error {
+	out.Timeout = in.Timeout
+	out.Interval = in.Interval
+	out.IntervalJitter = in.IntervalJitter
+	out.IntervalJitterPercent = in.IntervalJitterPercent
+	out.UnhealthyThreshold = in.UnhealthyThreshold
+	out.HealthyThreshold = in.HealthyThreshold
+	if err := Convert_v2_HealthCheckSpecifier_To_v3alpha1_HealthCheckSpecifier(&in.HealthCheckSpecifier, &out.HealthCheckSpecifier, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_v2_HealthCheck_To_v3alpha1_HealthCheck is an autogenerated conversion function.
+func Convert_v2_HealthCheck_To_v3alpha1_HealthCheck(in *HealthCheck, out *v3alpha1.HealthCheck, s conversion.Scope) error {
+	return autoConvert_v2_HealthCheck_To_v3alpha1_HealthCheck(in, out, s)
+}
+
+func autoConvert_v3alpha1_HealthCheck_To_v2_HealthCheck(in *v3alpha1.HealthCheck, out *HealthCheck, s conversion.Scope) error {
+	out.Timeout = in.Timeout
+	out.Interval = in.Interval
+	out.IntervalJitter = in.IntervalJitter
+	out.IntervalJitterPercent = in.IntervalJitterPercent
+	out.UnhealthyThreshold = in.UnhealthyThreshold
+	out.HealthyThreshold = in.HealthyThreshold
+	if err := Convert_v3alpha1_HealthCheckSpecifier_To_v2_HealthCheckSpecifier(&in.HealthCheckSpecifier, &out.HealthCheckSpecifier, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_v3alpha1_HealthCheck_To_v2_HealthCheck is an autogenerated conversion function.
+func Convert_v3alpha1_HealthCheck_To_v2_HealthCheck(in *v3alpha1.HealthCheck, out *HealthCheck, s conversion.Scope) error {
+	return autoConvert_v3alpha1_HealthCheck_To_v2_HealthCheck(in, out, s)
+}
+
+func autoConvert_v2_HealthCheckSpecifier_To_v3alpha1_HealthCheckSpecifier(in *HealthCheckSpecifier, out *v3alpha1.HealthCheckSpecifier, s conversion.Scope) error {
+	if in.HTTPHealthCheck != nil {
+		in, out := &in.HTTPHealthCheck, &out.HTTPHealthCheck
+		*out = new(v3alpha1.HTTPHealthCheck)
+		if err := Convert_v2_HTTPHealthCheck_To_v3alpha1_HTTPHealthCheck(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.HTTPHealthCheck = nil
+	}
+	if in.GRPCHealthCheck != nil {
+		in, out := &in.GRPCHealthCheck, &out.GRPCHealthCheck
+		*out = new(v3alpha1.GRPCHealthCheck)
+		**out = v3alpha1.GRPCHealthCheck(**in)
+	} else {
+		out.GRPCHealthCheck = nil
+	}
+	return nil
+}
+
+// Convert_v2_HealthCheckSpecifier_To_v3alpha1_HealthCheckSpecifier is an autogenerated conversion function.
+func Convert_v2_HealthCheckSpecifier_To_v3alpha1_HealthCheckSpecifier(in *HealthCheckSpecifier, out *v3alpha1.HealthCheckSpecifier, s conversion.Scope) error {
+	return autoConvert_v2_HealthCheckSpecifier_To_v3alpha1_HealthCheckSpecifier(in, out, s)
+}
+
+func autoConvert_v3alpha1_HealthCheckSpecifier_To_v2_HealthCheckSpecifier(in *v3alpha1.HealthCheckSpecifier, out *HealthCheckSpecifier, s conversion.Scope) error {
+	if in.HTTPHealthCheck != nil {
+		in, out := &in.HTTPHealthCheck, &out.HTTPHealthCheck
+		*out = new(HTTPHealthCheck)
+		if err := Convert_v3alpha1_HTTPHealthCheck_To_v2_HTTPHealthCheck(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.HTTPHealthCheck = nil
+	}
+	if in.GRPCHealthCheck != nil {
+		in, out := &in.GRPCHealthCheck, &out.GRPCHealthCheck
+		*out = new(GRPCHealthCheck)
+		**out = GRPCHealthCheck(**in)
+	} else {
+		out.GRPCHealthCheck = nil
+	}
+	return nil
+}
+
+// Convert_v3alpha1_HealthCheckSpecifier_To_v2_HealthCheckSpecifier is an autogenerated conversion function.
+func Convert_v3alpha1_HealthCheckSpecifier_To_v2_HealthCheckSpecifier(in *v3alpha1.HealthCheckSpecifier, out *HealthCheckSpecifier, s conversion.Scope) error {
+	return autoConvert_v3alpha1_HealthCheckSpecifier_To_v2_HealthCheckSpecifier(in, out, s)
+}
+
+func autoConvert_v2_HealthCheckStatusRange_To_v3alpha1_HealthCheckStatusRange(in *HealthCheckStatusRange, out *v3alpha1.HealthCheckStatusRange, s conversion.Scope) error {
+	out.Min = in.Min
+	out.Max = in.Max
+	return nil
+}
+
+// Convert_v2_HealthCheckStatusRange_To_v3alpha1_HealthCheckStatusRange is an autogenerated conversion function.
+func Convert_v2_HealthCheckStatusRange_To_v3alpha1_HealthCheckStatusRange(in *HealthCheckStatusRange, out *v3alpha1.HealthCheckStatusRange, s conversion.Scope) error {
+	return autoConvert_v2_HealthCheckStatusRange_To_v3alpha1_HealthCheckStatusRange(in, out, s)
+}
+
+func autoConvert_v3alpha1_HealthCheckStatusRange_To_v2_HealthCheckStatusRange(in *v3alpha1.HealthCheckStatusRange, out *HealthCheckStatusRange, s conversion.Scope) error {
+	out.Min = in.Min
+	out.Max = in.Max
+	return nil
+}
+
+// Convert_v3alpha1_HealthCheckStatusRange_To_v2_HealthCheckStatusRange is an autogenerated conversion function.
+func Convert_v3alpha1_HealthCheckStatusRange_To_v2_HealthCheckStatusRange(in *v3alpha1.HealthCheckStatusRange, out *HealthCheckStatusRange, s conversion.Scope) error {
+	return autoConvert_v3alpha1_HealthCheckStatusRange_To_v2_HealthCheckStatusRange(in, out, s)
+}
+
+func autoConvert_v2_Hedging_To_v3alpha1_Hedging(in *Hedging, out *v3alpha1.Hedging, s conversion.Scope) error {
+	out.BudgetPercent = in.BudgetPercent
+	out.MinConcurrency = in.MinConcurrency
+	return nil
+}
+
+// Convert_v2_Hedging_To_v3alpha1_Hedging is an autogenerated conversion function.
+func Convert_v2_Hedging_To_v3alpha1_Hedging(in *Hedging, out *v3alpha1.Hedging, s conversion.Scope) error {
+	return autoConvert_v2_Hedging_To_v3alpha1_Hedging(in, out, s)
+}
+
+func autoConvert_v3alpha1_Hedging_To_v2_Hedging(in *v3alpha1.Hedging, out *Hedging, s conversion.Scope) error {
+	out.BudgetPercent = in.BudgetPercent
+	out.MinConcurrency = in.MinConcurrency
+	return nil
+}
+
+// Convert_v3alpha1_Hedging_To_v2_Hedging is an autogenerated conversion function.
+func Convert_v3alpha1_Hedging_To_v2_Hedging(in *v3alpha1.Hedging, out *Hedging, s conversion.Scope) error {
+	return autoConvert_v3alpha1_Hedging_To_v2_Hedging(in, out, s)
+}
+
+func autoConvert_v2_Host_To_v3alpha1_Host(in *Host, out *v3alpha1.Host, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	if in.Spec != nil {
+		in, out := &in.Spec, &out.Spec
+		*out = new(v3alpha1.HostSpec)
+		if err := Convert_v2_HostSpec_To_v3alpha1_HostSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Spec = nil
+	}
+	if err := Convert_v2_HostStatus_To_v3alpha1_HostStatus(&in.Status, &out.Status, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_v2_Host_To_v3alpha1_Host is an autogenerated conversion function.
+func Convert_v2_Host_To_v3alpha1_Host(in *Host, out *v3alpha1.Host, s conversion.Scope) error {
+	return autoConvert_v2_Host_To_v3alpha1_Host(in, out, s)
+}
+
+func autoConvert_v3alpha1_Host_To_v2_Host(in *v3alpha1.Host, out *Host, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	if in.Spec != nil {
+		in, out := &in.Spec, &out.Spec
+		*out = new(HostSpec)
+		if err := Convert_v3alpha1_HostSpec_To_v2_HostSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Spec = nil
+	}
+	if err := Convert_v3alpha1_HostStatus_To_v2_HostStatus(&in.Status, &out.Status, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_v3alpha1_Host_To_v2_Host is an autogenerated conversion function.
+func Convert_v3alpha1_Host_To_v2_Host(in *v3alpha1.Host, out *Host, s conversion.Scope) error {
+	return autoConvert_v3alpha1_Host_To_v2_Host(in, out, s)
+}
+
+func autoConvert_v2_HostList_To_v3alpha1_HostList(in *HostList, out *v3alpha1.HostList, s conversion.Scope) error {
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]v3alpha1.Host, len(*in))
+		for i := range *in {
+			if err := Convert_v2_Host_To_v3alpha1_Host(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
 	}
 	return nil
 }
@@ -1785,6 +2415,13 @@ func autoConvert_v2_HostSpec_To_v3alpha1_HostSpec(in *HostSpec, out *v3alpha1.Ho
 	} else {
 		out.TLS = nil
 	}
+	if in.V3HSTS != nil {
+		in, out := &in.V3HSTS, &out.HSTS
+		*out = new(v3alpha1.HSTSConfig)
+		**out = v3alpha1.HSTSConfig(**in)
+	} else {
+		out.HSTS = nil
+	}
 	return nil
 }
 
@@ -1829,6 +2466,13 @@ func autoConvert_v3alpha1_HostSpec_To_v2_HostSpec(in *v3alpha1.HostSpec, out *Ho
 	} else {
 		out.TLS = nil
 	}
+	if in.HSTS != nil {
+		in, out := &in.HSTS, &out.V3HSTS
+		*out = new(HSTSConfig)
+		**out = HSTSConfig(**in)
+	} else {
+		out.V3HSTS = nil
+	}
 	return nil
 }
 
@@ -1886,6 +2530,66 @@ func Convert_v3alpha1_InsecureRequestPolicy_To_v2_InsecureRequestPolicy(in *v3al
 	return autoConvert_v3alpha1_InsecureRequestPolicy_To_v2_InsecureRequestPolicy(in, out, s)
 }
 
+func autoConvert_v2_JWTClaim_To_v3alpha1_JWTClaim(in *JWTClaim, out *v3alpha1.JWTClaim, s conversion.Scope) error {
+	out.Name = in.Name
+	out.Values = in.Values
+	return nil
+}
+
+// Convert_v2_JWTClaim_To_v3alpha1_JWTClaim is an autogenerated conversion function.
+func Convert_v2_JWTClaim_To_v3alpha1_JWTClaim(in *JWTClaim, out *v3alpha1.JWTClaim, s conversion.Scope) error {
+	return autoConvert_v2_JWTClaim_To_v3alpha1_JWTClaim(in, out, s)
+}
+
+func autoConvert_v3alpha1_JWTClaim_To_v2_JWTClaim(in *v3alpha1.JWTClaim, out *JWTClaim, s conversion.Scope) error {
+	out.Name = in.Name
+	out.Values = in.Values
+	return nil
+}
+
+// Convert_v3alpha1_JWTClaim_To_v2_JWTClaim is an autogenerated conversion function.
+func Convert_v3alpha1_JWTClaim_To_v2_JWTClaim(in *v3alpha1.JWTClaim, out *JWTClaim, s conversion.Scope) error {
+	return autoConvert_v3alpha1_JWTClaim_To_v2_JWTClaim(in, out, s)
+}
+
+func autoConvert_v2_JWTRequirement_To_v3alpha1_JWTRequirement(in *JWTRequirement, out *v3alpha1.JWTRequirement, s conversion.Scope) error {
+	out.Provider = in.Provider
+	if in.Claims != nil {
+		in, out := &in.Claims, &out.Claims
+		*out = make([]v3alpha1.JWTClaim, len(*in))
+		for i := range *in {
+			(*out)[i] = v3alpha1.JWTClaim((*in)[i])
+		}
+	} else {
+		out.Claims = nil
+	}
+	return nil
+}
+
+// Convert_v2_JWTRequirement_To_v3alpha1_JWTRequirement is an autogenerated conversion function.
+func Convert_v2_JWTRequirement_To_v3alpha1_JWTRequirement(in *JWTRequirement, out *v3alpha1.JWTRequirement, s conversion.Scope) error {
+	return autoConvert_v2_JWTRequirement_To_v3alpha1_JWTRequirement(in, out, s)
+}
+
+func autoConvert_v3alpha1_JWTRequirement_To_v2_JWTRequirement(in *v3alpha1.JWTRequirement, out *JWTRequirement, s conversion.Scope) error {
+	out.Provider = in.Provider
+	if in.Claims != nil {
+		in, out := &in.Claims, &out.Claims
+		*out = make([]JWTClaim, len(*in))
+		for i := range *in {
+			(*out)[i] = JWTClaim((*in)[i])
+		}
+	} else {
+		out.Claims = nil
+	}
+	return nil
+}
+
+// Convert_v3alpha1_JWTRequirement_To_v2_JWTRequirement is an autogenerated conversion function.
+func Convert_v3alpha1_JWTRequirement_To_v2_JWTRequirement(in *v3alpha1.JWTRequirement, out *JWTRequirement, s conversion.Scope) error {
+	return autoConvert_v3alpha1_JWTRequirement_To_v2_JWTRequirement(in, out, s)
+}
+
 func autoConvert_v2_KeepAlive_To_v3alpha1_KeepAlive(in *KeepAlive, out *v3alpha1.KeepAlive, s conversion.Scope) error {
 	out.Probes = in.Probes
 	out.IdleTime = in.IdleTime
@@ -2105,6 +2809,14 @@ func autoConvert_v2_LoadBalancer_To_v3alpha1_LoadBalancer(in *LoadBalancer, out
 	}
 	out.Header = in.Header
 	out.SourceIp = in.SourceIp
+	out.ChoiceCount = in.ChoiceCount
+	if in.Path != nil {
+		in, out := &in.Path, &out.Path
+		*out = new(v3alpha1.LoadBalancerPath)
+		**out = v3alpha1.LoadBalancerPath(**in)
+	} else {
+		out.Path = nil
+	}
 	return nil
 }
 
@@ -2124,6 +2836,14 @@ func autoConvert_v3alpha1_LoadBalancer_To_v2_LoadBalancer(in *v3alpha1.LoadBalan
 	}
 	out.Header = in.Header
 	out.SourceIp = in.SourceIp
+	out.ChoiceCount = in.ChoiceCount
+	if in.Path != nil {
+		in, out := &in.Path, &out.Path
+		*out = new(LoadBalancerPath)
+		**out = LoadBalancerPath(**in)
+	} else {
+		out.Path = nil
+	}
 	return nil
 }
 
@@ -2156,6 +2876,48 @@ func Convert_v3alpha1_LoadBalancerCookie_To_v2_LoadBalancerCookie(in *v3alpha1.L
 	return autoConvert_v3alpha1_LoadBalancerCookie_To_v2_LoadBalancerCookie(in, out, s)
 }
 
+func autoConvert_v2_LoadBalancerPath_To_v3alpha1_LoadBalancerPath(in *LoadBalancerPath, out *v3alpha1.LoadBalancerPath, s conversion.Scope) error {
+	out.Segments = in.Segments
+	return nil
+}
+
+// Convert_v2_LoadBalancerPath_To_v3alpha1_LoadBalancerPath is an autogenerated conversion function.
+func Convert_v2_LoadBalancerPath_To_v3alpha1_LoadBalancerPath(in *LoadBalancerPath, out *v3alpha1.LoadBalancerPath, s conversion.Scope) error {
+	return autoConvert_v2_LoadBalancerPath_To_v3alpha1_LoadBalancerPath(in, out, s)
+}
+
+func autoConvert_v3alpha1_LoadBalancerPath_To_v2_LoadBalancerPath(in *v3alpha1.LoadBalancerPath, out *LoadBalancerPath, s conversion.Scope) error {
+	out.Segments = in.Segments
+	return nil
+}
+
+// Convert_v3alpha1_LoadBalancerPath_To_v2_LoadBalancerPath is an autogenerated conversion function.
+func Convert_v3alpha1_LoadBalancerPath_To_v2_LoadBalancerPath(in *v3alpha1.LoadBalancerPath, out *LoadBalancerPath, s conversion.Scope) error {
+	return autoConvert_v3alpha1_LoadBalancerPath_To_v2_LoadBalancerPath(in, out, s)
+}
+
+func autoConvert_v2_LogSampling_To_v3alpha1_LogSampling(in *LogSampling, out *v3alpha1.LogSampling, s conversion.Scope) error {
+	out.Percent = in.Percent
+	out.AlwaysLogStatus = in.AlwaysLogStatus
+	return nil
+}
+
+// Convert_v2_LogSampling_To_v3alpha1_LogSampling is an autogenerated conversion function.
+func Convert_v2_LogSampling_To_v3alpha1_LogSampling(in *LogSampling, out *v3alpha1.LogSampling, s conversion.Scope) error {
+	return autoConvert_v2_LogSampling_To_v3alpha1_LogSampling(in, out, s)
+}
+
+func autoConvert_v3alpha1_LogSampling_To_v2_LogSampling(in *v3alpha1.LogSampling, out *LogSampling, s conversion.Scope) error {
+	out.Percent = in.Percent
+	out.AlwaysLogStatus = in.AlwaysLogStatus
+	return nil
+}
+
+// Convert_v3alpha1_LogSampling_To_v2_LogSampling is an autogenerated conversion function.
+func Convert_v3alpha1_LogSampling_To_v2_LogSampling(in *v3alpha1.LogSampling, out *LogSampling, s conversion.Scope) error {
+	return autoConvert_v3alpha1_LogSampling_To_v2_LogSampling(in, out, s)
+}
+
 func autoConvert_v2_LogService_To_v3alpha1_LogService(in *LogService, out *v3alpha1.LogService, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v2_LogServiceSpec_To_v3alpha1_LogServiceSpec(&in.Spec, &out.Spec, s); err != nil {
@@ -2459,7 +3221,9 @@ func autoConvert_v2_MappingSpec_To_v3alpha1_MappingSpec(in *MappingSpec, out *v3
 	if in.RetryPolicy != nil {
 		in, out := &in.RetryPolicy, &out.RetryPolicy
 		*out = new(v3alpha1.RetryPolicy)
-		**out = v3alpha1.RetryPolicy(**in)
+		if err := Convert_v2_RetryPolicy_To_v3alpha1_RetryPolicy(*in, *out, s); err != nil {
+			return err
+		}
 	} else {
 		out.RetryPolicy = nil
 	}
@@ -2593,17 +3357,204 @@ func autoConvert_v2_MappingSpec_To_v3alpha1_MappingSpec(in *MappingSpec, out *v3
 	// INFO: in.QueryParameters opted out of conversion generation
 	out.RegexQueryParameters = in.RegexQueryParameters
 	out.StatsName = in.V3StatsName
-	return nil
-}
-
-func autoConvert_v3alpha1_MappingSpec_To_v2_MappingSpec(in *v3alpha1.MappingSpec, out *MappingSpec, s conversion.Scope) error {
-	if err := Convert_v3alpha1_AmbassadorID_To_v2_AmbassadorID(&in.AmbassadorID, &out.AmbassadorID, s); err != nil {
-		return err
+	out.UpstreamBindAddress = in.V3UpstreamBindAddress
+	out.ShadowRuntimeKey = in.V3ShadowRuntimeKey
+	if in.V3ShadowOnCircuitBreak != nil {
+		in, out := &in.V3ShadowOnCircuitBreak, &out.ShadowOnCircuitBreak
+		*out = new(v3alpha1.ShadowOnCircuitBreak)
+		**out = v3alpha1.ShadowOnCircuitBreak(**in)
+	} else {
+		out.ShadowOnCircuitBreak = nil
+	}
+	out.ShadowCloneCluster = in.V3ShadowCloneCluster
+	out.TrailingSlash = in.V3TrailingSlash
+	out.EchoRequestID = in.V3EchoRequestID
+	out.AutoSNI = in.V3AutoSNI
+	out.AutoHTTPProtocol = in.V3AutoHTTPProtocol
+	if in.V3JWT != nil {
+		in, out := &in.V3JWT, &out.JWT
+		*out = new(v3alpha1.JWTRequirement)
+		if err := Convert_v2_JWTRequirement_To_v3alpha1_JWTRequirement(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.JWT = nil
 	}
-	out.Prefix = in.Prefix
-	out.PrefixRegex = in.PrefixRegex
-	out.PrefixExact = in.PrefixExact
-	out.Service = in.Service
+	if in.V3StaticFallback != nil {
+		in, out := &in.V3StaticFallback, &out.StaticFallback
+		*out = new(v3alpha1.StaticFallback)
+		**out = v3alpha1.StaticFallback(**in)
+	} else {
+		out.StaticFallback = nil
+	}
+	out.VirtualCluster = in.V3VirtualCluster
+	out.HostRewriteFromSNI = in.V3HostRewriteFromSNI
+	if in.V3DNSFailureRefreshRate != nil {
+		in, out := &in.V3DNSFailureRefreshRate, &out.DNSFailureRefreshRate
+		*out = new(v3alpha1.MillisecondDuration)
+		**out = v3alpha1.MillisecondDuration(**in)
+	} else {
+		out.DNSFailureRefreshRate = nil
+	}
+	if in.V3InitialFetchTimeout != nil {
+		in, out := &in.V3InitialFetchTimeout, &out.InitialFetchTimeout
+		*out = new(v3alpha1.MillisecondDuration)
+		**out = v3alpha1.MillisecondDuration(**in)
+	} else {
+		out.InitialFetchTimeout = nil
+	}
+	if in.V3HealthChecks != nil {
+		in, out := &in.V3HealthChecks, &out.HealthChecks
+		*out = make([]v3alpha1.HealthCheck, len(*in))
+		for i := range *in {
+			if err := Convert_v2_HealthCheck_To_v3alpha1_HealthCheck(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.HealthChecks = nil
+	}
+	out.MeshMTLS = in.V3MeshMTLS
+	if in.V3MaxGRPCTimeout != nil {
+		in, out := &in.V3MaxGRPCTimeout, &out.MaxGRPCTimeout
+		*out = new(v3alpha1.MillisecondDuration)
+		**out = v3alpha1.MillisecondDuration(**in)
+	} else {
+		out.MaxGRPCTimeout = nil
+	}
+	if in.V3GRPCTimeoutOffset != nil {
+		in, out := &in.V3GRPCTimeoutOffset, &out.GRPCTimeoutOffset
+		*out = new(v3alpha1.MillisecondDuration)
+		**out = v3alpha1.MillisecondDuration(**in)
+	} else {
+		out.GRPCTimeoutOffset = nil
+	}
+	if in.V3DynamicForwardProxy != nil {
+		in, out := &in.V3DynamicForwardProxy, &out.DynamicForwardProxy
+		*out = new(v3alpha1.DynamicForwardProxy)
+		**out = v3alpha1.DynamicForwardProxy(**in)
+	} else {
+		out.DynamicForwardProxy = nil
+	}
+	out.SuppressEnvoyHeaders = in.V3SuppressEnvoyHeaders
+	out.DecompressorMaxRequestBytes = in.V3DecompressorMaxRequestBytes
+	if in.V3ClusterProtocolOptions != nil {
+		in, out := &in.V3ClusterProtocolOptions, &out.ClusterProtocolOptions
+		*out = new(v3alpha1.UntypedDict)
+		**out = v3alpha1.UntypedDict(**in)
+	} else {
+		out.ClusterProtocolOptions = nil
+	}
+	if in.V3ClusterDrainTime != nil {
+		in, out := &in.V3ClusterDrainTime, &out.ClusterDrainTime
+		*out = new(v3alpha1.MillisecondDuration)
+		**out = v3alpha1.MillisecondDuration(**in)
+	} else {
+		out.ClusterDrainTime = nil
+	}
+	if in.V3HTTP2KeepAlive != nil {
+		in, out := &in.V3HTTP2KeepAlive, &out.HTTP2KeepAlive
+		*out = new(v3alpha1.HTTP2KeepAlive)
+		if err := Convert_v2_HTTP2KeepAlive_To_v3alpha1_HTTP2KeepAlive(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.HTTP2KeepAlive = nil
+	}
+	out.RetryBufferLimitBytes = in.V3RetryBufferLimitBytes
+	out.PreserveSensitiveHeaders = in.V3PreserveSensitiveHeaders
+	out.ContentTypes = in.V3ContentTypes
+	out.ResponseFlagStats = in.V3ResponseFlagStats
+	out.Methods = in.V3Methods
+	out.BypassCompression = in.V3BypassCompression
+	if in.V3RequestTimeoutHeader != nil {
+		in, out := &in.V3RequestTimeoutHeader, &out.RequestTimeoutHeader
+		*out = new(v3alpha1.RequestTimeoutHeader)
+		if err := Convert_v2_RequestTimeoutHeader_To_v3alpha1_RequestTimeoutHeader(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.RequestTimeoutHeader = nil
+	}
+	if in.V3CookieAttributes != nil {
+		in, out := &in.V3CookieAttributes, &out.CookieAttributes
+		*out = new(v3alpha1.CookieAttributes)
+		**out = v3alpha1.CookieAttributes(**in)
+	} else {
+		out.CookieAttributes = nil
+	}
+	out.WeightRuntimeKeyPrefix = in.V3WeightRuntimeKeyPrefix
+	if in.V3BodyTransform != nil {
+		in, out := &in.V3BodyTransform, &out.BodyTransform
+		*out = new(v3alpha1.BodyTransform)
+		**out = v3alpha1.BodyTransform(**in)
+	} else {
+		out.BodyTransform = nil
+	}
+	if in.V3TypedPerFilterConfig != nil {
+		in, out := &in.V3TypedPerFilterConfig, &out.TypedPerFilterConfig
+		*out = new(v3alpha1.UntypedDict)
+		**out = v3alpha1.UntypedDict(**in)
+	} else {
+		out.TypedPerFilterConfig = nil
+	}
+	out.HealthRebalance = in.V3HealthRebalance
+	out.HealthRebalanceRecoveryMs = in.V3HealthRebalanceRecoveryMs
+	out.HistogramBuckets = in.V3HistogramBuckets
+	out.DisableHistograms = in.V3DisableHistograms
+	out.CaseInsensitiveHeaders = in.V3CaseInsensitiveHeaders
+	out.MaxRequestsPerConnection = in.V3MaxRequestsPerConnection
+	if in.V3ABHeader != nil {
+		in, out := &in.V3ABHeader, &out.ABHeader
+		*out = new(v3alpha1.ABHeader)
+		**out = v3alpha1.ABHeader(**in)
+	} else {
+		out.ABHeader = nil
+	}
+	out.ResolverFallback = in.V3ResolverFallback
+	if in.V3StatsDimensions != nil {
+		in, out := &in.V3StatsDimensions, &out.StatsDimensions
+		*out = make([]v3alpha1.StatsDimension, len(*in))
+		for i := range *in {
+			(*out)[i] = v3alpha1.StatsDimension((*in)[i])
+		}
+	} else {
+		out.StatsDimensions = nil
+	}
+	if in.V3Hedging != nil {
+		in, out := &in.V3Hedging, &out.Hedging
+		*out = new(v3alpha1.Hedging)
+		**out = v3alpha1.Hedging(**in)
+	} else {
+		out.Hedging = nil
+	}
+	if in.V3ZeroReplicas != nil {
+		in, out := &in.V3ZeroReplicas, &out.ZeroReplicas
+		*out = new(v3alpha1.ZeroReplicas)
+		if err := Convert_v2_ZeroReplicas_To_v3alpha1_ZeroReplicas(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.ZeroReplicas = nil
+	}
+	if in.V3LogSampling != nil {
+		in, out := &in.V3LogSampling, &out.LogSampling
+		*out = new(v3alpha1.LogSampling)
+		**out = v3alpha1.LogSampling(**in)
+	} else {
+		out.LogSampling = nil
+	}
+	return nil
+}
+
+func autoConvert_v3alpha1_MappingSpec_To_v2_MappingSpec(in *v3alpha1.MappingSpec, out *MappingSpec, s conversion.Scope) error {
+	if err := Convert_v3alpha1_AmbassadorID_To_v2_AmbassadorID(&in.AmbassadorID, &out.AmbassadorID, s); err != nil {
+		return err
+	}
+	out.Prefix = in.Prefix
+	out.PrefixRegex = in.PrefixRegex
+	out.PrefixExact = in.PrefixExact
+	out.Service = in.Service
 	if in.AddRequestHeaders != nil {
 		in, out := &in.AddRequestHeaders, &out.AddRequestHeaders
 		*out = make(map[string]AddedHeader, len(*in))
@@ -2679,7 +3630,9 @@ func autoConvert_v3alpha1_MappingSpec_To_v2_MappingSpec(in *v3alpha1.MappingSpec
 	if in.RetryPolicy != nil {
 		in, out := &in.RetryPolicy, &out.RetryPolicy
 		*out = new(RetryPolicy)
-		**out = RetryPolicy(**in)
+		if err := Convert_v3alpha1_RetryPolicy_To_v2_RetryPolicy(*in, *out, s); err != nil {
+			return err
+		}
 	} else {
 		out.RetryPolicy = nil
 	}
@@ -2840,6 +3793,193 @@ func autoConvert_v3alpha1_MappingSpec_To_v2_MappingSpec(in *v3alpha1.MappingSpec
 	}
 	out.RegexQueryParameters = in.RegexQueryParameters
 	out.V3StatsName = in.StatsName
+	out.V3UpstreamBindAddress = in.UpstreamBindAddress
+	out.V3ShadowRuntimeKey = in.ShadowRuntimeKey
+	if in.ShadowOnCircuitBreak != nil {
+		in, out := &in.ShadowOnCircuitBreak, &out.V3ShadowOnCircuitBreak
+		*out = new(ShadowOnCircuitBreak)
+		**out = ShadowOnCircuitBreak(**in)
+	} else {
+		out.V3ShadowOnCircuitBreak = nil
+	}
+	out.V3ShadowCloneCluster = in.ShadowCloneCluster
+	out.V3TrailingSlash = in.TrailingSlash
+	out.V3EchoRequestID = in.EchoRequestID
+	out.V3AutoSNI = in.AutoSNI
+	out.V3AutoHTTPProtocol = in.AutoHTTPProtocol
+	if in.JWT != nil {
+		in, out := &in.JWT, &out.V3JWT
+		*out = new(JWTRequirement)
+		if err := Convert_v3alpha1_JWTRequirement_To_v2_JWTRequirement(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.V3JWT = nil
+	}
+	if in.StaticFallback != nil {
+		in, out := &in.StaticFallback, &out.V3StaticFallback
+		*out = new(StaticFallback)
+		**out = StaticFallback(**in)
+	} else {
+		out.V3StaticFallback = nil
+	}
+	out.V3VirtualCluster = in.VirtualCluster
+	out.V3HostRewriteFromSNI = in.HostRewriteFromSNI
+	if in.DNSFailureRefreshRate != nil {
+		in, out := &in.DNSFailureRefreshRate, &out.V3DNSFailureRefreshRate
+		*out = new(MillisecondDuration)
+		**out = MillisecondDuration(**in)
+	} else {
+		out.V3DNSFailureRefreshRate = nil
+	}
+	if in.InitialFetchTimeout != nil {
+		in, out := &in.InitialFetchTimeout, &out.V3InitialFetchTimeout
+		*out = new(MillisecondDuration)
+		**out = MillisecondDuration(**in)
+	} else {
+		out.V3InitialFetchTimeout = nil
+	}
+	if in.HealthChecks != nil {
+		in, out := &in.HealthChecks, &out.V3HealthChecks
+		*out = make([]HealthCheck, len(*in))
+		for i := range *in {
+			if err := Convert_v3alpha1_HealthCheck_To_v2_HealthCheck(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.V3HealthChecks = nil
+	}
+	out.V3MeshMTLS = in.MeshMTLS
+	if in.MaxGRPCTimeout != nil {
+		in, out := &in.MaxGRPCTimeout, &out.V3MaxGRPCTimeout
+		*out = new(MillisecondDuration)
+		**out = MillisecondDuration(**in)
+	} else {
+		out.V3MaxGRPCTimeout = nil
+	}
+	if in.GRPCTimeoutOffset != nil {
+		in, out := &in.GRPCTimeoutOffset, &out.V3GRPCTimeoutOffset
+		*out = new(MillisecondDuration)
+		**out = MillisecondDuration(**in)
+	} else {
+		out.V3GRPCTimeoutOffset = nil
+	}
+	if in.DynamicForwardProxy != nil {
+		in, out := &in.DynamicForwardProxy, &out.V3DynamicForwardProxy
+		*out = new(DynamicForwardProxy)
+		**out = DynamicForwardProxy(**in)
+	} else {
+		out.V3DynamicForwardProxy = nil
+	}
+	out.V3SuppressEnvoyHeaders = in.SuppressEnvoyHeaders
+	out.V3DecompressorMaxRequestBytes = in.DecompressorMaxRequestBytes
+	if in.ClusterProtocolOptions != nil {
+		in, out := &in.ClusterProtocolOptions, &out.V3ClusterProtocolOptions
+		*out = new(UntypedDict)
+		**out = UntypedDict(**in)
+	} else {
+		out.V3ClusterProtocolOptions = nil
+	}
+	if in.ClusterDrainTime != nil {
+		in, out := &in.ClusterDrainTime, &out.V3ClusterDrainTime
+		*out = new(MillisecondDuration)
+		**out = MillisecondDuration(**in)
+	} else {
+		out.V3ClusterDrainTime = nil
+	}
+	if in.HTTP2KeepAlive != nil {
+		in, out := &in.HTTP2KeepAlive, &out.V3HTTP2KeepAlive
+		*out = new(HTTP2KeepAlive)
+		if err := Convert_v3alpha1_HTTP2KeepAlive_To_v2_HTTP2KeepAlive(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.V3HTTP2KeepAlive = nil
+	}
+	out.V3RetryBufferLimitBytes = in.RetryBufferLimitBytes
+	out.V3PreserveSensitiveHeaders = in.PreserveSensitiveHeaders
+	out.V3ContentTypes = in.ContentTypes
+	out.V3ResponseFlagStats = in.ResponseFlagStats
+	out.V3Methods = in.Methods
+	out.V3BypassCompression = in.BypassCompression
+	if in.RequestTimeoutHeader != nil {
+		in, out := &in.RequestTimeoutHeader, &out.V3RequestTimeoutHeader
+		*out = new(RequestTimeoutHeader)
+		if err := Convert_v3alpha1_RequestTimeoutHeader_To_v2_RequestTimeoutHeader(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.V3RequestTimeoutHeader = nil
+	}
+	if in.CookieAttributes != nil {
+		in, out := &in.CookieAttributes, &out.V3CookieAttributes
+		*out = new(CookieAttributes)
+		**out = CookieAttributes(**in)
+	} else {
+		out.V3CookieAttributes = nil
+	}
+	out.V3WeightRuntimeKeyPrefix = in.WeightRuntimeKeyPrefix
+	if in.BodyTransform != nil {
+		in, out := &in.BodyTransform, &out.V3BodyTransform
+		*out = new(BodyTransform)
+		**out = BodyTransform(**in)
+	} else {
+		out.V3BodyTransform = nil
+	}
+	if in.TypedPerFilterConfig != nil {
+		in, out := &in.TypedPerFilterConfig, &out.V3TypedPerFilterConfig
+		*out = new(UntypedDict)
+		**out = UntypedDict(**in)
+	} else {
+		out.V3TypedPerFilterConfig = nil
+	}
+	out.V3HealthRebalance = in.HealthRebalance
+	out.V3HealthRebalanceRecoveryMs = in.HealthRebalanceRecoveryMs
+	out.V3HistogramBuckets = in.HistogramBuckets
+	out.V3DisableHistograms = in.DisableHistograms
+	out.V3CaseInsensitiveHeaders = in.CaseInsensitiveHeaders
+	out.V3MaxRequestsPerConnection = in.MaxRequestsPerConnection
+	if in.ABHeader != nil {
+		in, out := &in.ABHeader, &out.V3ABHeader
+		*out = new(ABHeader)
+		**out = ABHeader(**in)
+	} else {
+		out.V3ABHeader = nil
+	}
+	out.V3ResolverFallback = in.ResolverFallback
+	if in.StatsDimensions != nil {
+		in, out := &in.StatsDimensions, &out.V3StatsDimensions
+		*out = make([]StatsDimension, len(*in))
+		for i := range *in {
+			(*out)[i] = StatsDimension((*in)[i])
+		}
+	} else {
+		out.V3StatsDimensions = nil
+	}
+	if in.Hedging != nil {
+		in, out := &in.Hedging, &out.V3Hedging
+		*out = new(Hedging)
+		**out = Hedging(**in)
+	} else {
+		out.V3Hedging = nil
+	}
+	if in.ZeroReplicas != nil {
+		in, out := &in.ZeroReplicas, &out.V3ZeroReplicas
+		*out = new(ZeroReplicas)
+		if err := Convert_v3alpha1_ZeroReplicas_To_v2_ZeroReplicas(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.V3ZeroReplicas = nil
+	}
+	if in.LogSampling != nil {
+		in, out := &in.LogSampling, &out.V3LogSampling
+		*out = new(LogSampling)
+		**out = LogSampling(**in)
+	} else {
+		out.V3LogSampling = nil
+	}
 	// WARNING: in.V2ExplicitTLS requires manual conversion: does not exist in peer-type
 	// WARNING: in.V2BoolHeaders requires manual conversion: does not exist in peer-type
 	// WARNING: in.V2BoolQueryParameters requires manual conversion: does not exist in peer-type
@@ -3088,6 +4228,7 @@ func autoConvert_v2_RateLimitServiceSpec_To_v3alpha1_RateLimitServiceSpec(in *Ra
 	// WARNING: in.TLS requires manual conversion: inconvertible types (*./pkg/api/getambassador.io/v2.BoolOrString vs string)
 	out.ProtocolVersion = in.ProtocolVersion
 	out.StatsName = in.V3StatsName
+	out.FailureModeDeny = in.V3FailureModeDeny
 	return nil
 }
 
@@ -3109,6 +4250,7 @@ func autoConvert_v3alpha1_RateLimitServiceSpec_To_v2_RateLimitServiceSpec(in *v3
 	}
 	out.ProtocolVersion = in.ProtocolVersion
 	out.V3StatsName = in.StatsName
+	out.V3FailureModeDeny = in.FailureModeDeny
 	// WARNING: in.V2ExplicitTLS requires manual conversion: does not exist in peer-type
 	return nil
 }
@@ -3155,10 +4297,105 @@ func Convert_v3alpha1_RequestPolicy_To_v2_RequestPolicy(in *v3alpha1.RequestPoli
 	return autoConvert_v3alpha1_RequestPolicy_To_v2_RequestPolicy(in, out, s)
 }
 
+func autoConvert_v2_RequestTimeoutHeader_To_v3alpha1_RequestTimeoutHeader(in *RequestTimeoutHeader, out *v3alpha1.RequestTimeoutHeader, s conversion.Scope) error {
+	out.Honor = in.Honor
+	if in.Max != nil {
+		in, out := &in.Max, &out.Max
+		*out = new(v3alpha1.MillisecondDuration)
+		**out = v3alpha1.MillisecondDuration(**in)
+	} else {
+		out.Max = nil
+	}
+	return nil
+}
+
+// Convert_v2_RequestTimeoutHeader_To_v3alpha1_RequestTimeoutHeader is an autogenerated conversion function.
+func Convert_v2_RequestTimeoutHeader_To_v3alpha1_RequestTimeoutHeader(in *RequestTimeoutHeader, out *v3alpha1.RequestTimeoutHeader, s conversion.Scope) error {
+	return autoConvert_v2_RequestTimeoutHeader_To_v3alpha1_RequestTimeoutHeader(in, out, s)
+}
+
+func autoConvert_v3alpha1_RequestTimeoutHeader_To_v2_RequestTimeoutHeader(in *v3alpha1.RequestTimeoutHeader, out *RequestTimeoutHeader, s conversion.Scope) error {
+	out.Honor = in.Honor
+	if in.Max != nil {
+		in, out := &in.Max, &out.Max
+		*out = new(MillisecondDuration)
+		**out = MillisecondDuration(**in)
+	} else {
+		out.Max = nil
+	}
+	return nil
+}
+
+// Convert_v3alpha1_RequestTimeoutHeader_To_v2_RequestTimeoutHeader is an autogenerated conversion function.
+func Convert_v3alpha1_RequestTimeoutHeader_To_v2_RequestTimeoutHeader(in *v3alpha1.RequestTimeoutHeader, out *RequestTimeoutHeader, s conversion.Scope) error {
+	return autoConvert_v3alpha1_RequestTimeoutHeader_To_v2_RequestTimeoutHeader(in, out, s)
+}
+
+func autoConvert_v2_RetryBackOff_To_v3alpha1_RetryBackOff(in *RetryBackOff, out *v3alpha1.RetryBackOff, s conversion.Scope) error {
+	if in.BaseInterval != nil {
+		in, out := &in.BaseInterval, &out.BaseInterval
+		*out = new(v3alpha1.MillisecondDuration)
+		**out = v3alpha1.MillisecondDuration(**in)
+	} else {
+		out.BaseInterval = nil
+	}
+	if in.MaxInterval != nil {
+		in, out := &in.MaxInterval, &out.MaxInterval
+		*out = new(v3alpha1.MillisecondDuration)
+		**out = v3alpha1.MillisecondDuration(**in)
+	} else {
+		out.MaxInterval = nil
+	}
+	return nil
+}
+
+// Convert_v2_RetryBackOff_To_v3alpha1_RetryBackOff is an autogenerated conversion function.
+func Convert_v2_RetryBackOff_To_v3alpha1_RetryBackOff(in *RetryBackOff, out *v3alpha1.RetryBackOff, s conversion.Scope) error {
+	return autoConvert_v2_RetryBackOff_To_v3alpha1_RetryBackOff(in, out, s)
+}
+
+func autoConvert_v3alpha1_RetryBackOff_To_v2_RetryBackOff(in *v3alpha1.RetryBackOff, out *RetryBackOff, s conversion.Scope) error {
+	if in.BaseInterval != nil {
+		in, out := &in.BaseInterval, &out.BaseInterval
+		*out = new(MillisecondDuration)
+		**out = MillisecondDuration(**in)
+	} else {
+		out.BaseInterval = nil
+	}
+	if in.MaxInterval != nil {
+		in, out := &in.MaxInterval, &out.MaxInterval
+		*out = new(MillisecondDuration)
+		**out = MillisecondDuration(**in)
+	} else {
+		out.MaxInterval = nil
+	}
+	return nil
+}
+
+// Convert_v3alpha1_RetryBackOff_To_v2_RetryBackOff is an autogenerated conversion function.
+func Convert_v3alpha1_RetryBackOff_To_v2_RetryBackOff(in *v3alpha1.RetryBackOff, out *RetryBackOff, s conversion.Scope) error {
+	return autoConvert_v3alpha1_RetryBackOff_To_v2_RetryBackOff(in, out, s)
+}
+
 func autoConvert_v2_RetryPolicy_To_v3alpha1_RetryPolicy(in *RetryPolicy, out *v3alpha1.RetryPolicy, s conversion.Scope) error {
 	out.RetryOn = in.RetryOn
 	out.NumRetries = in.NumRetries
 	out.PerTryTimeout = in.PerTryTimeout
+	out.GRPCRetryOn = in.GRPCRetryOn
+	if in.RetryBackOff != nil {
+		in, out := &in.RetryBackOff, &out.RetryBackOff
+		*out = new(v3alpha1.RetryBackOff)
+		if err := Convert_v2_RetryBackOff_To_v3alpha1_RetryBackOff(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.RetryBackOff = nil
+	}
+	out.RetryOtherHosts = in.RetryOtherHosts
+	out.HostSelectionRetryMaxAttempts = in.HostSelectionRetryMaxAttempts
+	out.RetryOnReset = in.RetryOnReset
+	out.RetryNonIdempotent = in.RetryNonIdempotent
+	out.RetryOnResponseHeaders = in.RetryOnResponseHeaders
 	return nil
 }
 
@@ -3171,6 +4408,21 @@ func autoConvert_v3alpha1_RetryPolicy_To_v2_RetryPolicy(in *v3alpha1.RetryPolicy
 	out.RetryOn = in.RetryOn
 	out.NumRetries = in.NumRetries
 	out.PerTryTimeout = in.PerTryTimeout
+	out.GRPCRetryOn = in.GRPCRetryOn
+	if in.RetryBackOff != nil {
+		in, out := &in.RetryBackOff, &out.RetryBackOff
+		*out = new(RetryBackOff)
+		if err := Convert_v3alpha1_RetryBackOff_To_v2_RetryBackOff(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.RetryBackOff = nil
+	}
+	out.RetryOtherHosts = in.RetryOtherHosts
+	out.HostSelectionRetryMaxAttempts = in.HostSelectionRetryMaxAttempts
+	out.RetryOnReset = in.RetryOnReset
+	out.RetryNonIdempotent = in.RetryNonIdempotent
+	out.RetryOnResponseHeaders = in.RetryOnResponseHeaders
 	return nil
 }
 
@@ -3179,6 +4431,76 @@ func Convert_v3alpha1_RetryPolicy_To_v2_RetryPolicy(in *v3alpha1.RetryPolicy, ou
 	return autoConvert_v3alpha1_RetryPolicy_To_v2_RetryPolicy(in, out, s)
 }
 
+func autoConvert_v2_ShadowOnCircuitBreak_To_v3alpha1_ShadowOnCircuitBreak(in *ShadowOnCircuitBreak, out *v3alpha1.ShadowOnCircuitBreak, s conversion.Scope) error {
+	out.RuntimeKey = in.RuntimeKey
+	out.MaxRequests = in.MaxRequests
+	return nil
+}
+
+// Convert_v2_ShadowOnCircuitBreak_To_v3alpha1_ShadowOnCircuitBreak is an autogenerated conversion function.
+func Convert_v2_ShadowOnCircuitBreak_To_v3alpha1_ShadowOnCircuitBreak(in *ShadowOnCircuitBreak, out *v3alpha1.ShadowOnCircuitBreak, s conversion.Scope) error {
+	return autoConvert_v2_ShadowOnCircuitBreak_To_v3alpha1_ShadowOnCircuitBreak(in, out, s)
+}
+
+func autoConvert_v3alpha1_ShadowOnCircuitBreak_To_v2_ShadowOnCircuitBreak(in *v3alpha1.ShadowOnCircuitBreak, out *ShadowOnCircuitBreak, s conversion.Scope) error {
+	out.RuntimeKey = in.RuntimeKey
+	out.MaxRequests = in.MaxRequests
+	return nil
+}
+
+// Convert_v3alpha1_ShadowOnCircuitBreak_To_v2_ShadowOnCircuitBreak is an autogenerated conversion function.
+func Convert_v3alpha1_ShadowOnCircuitBreak_To_v2_ShadowOnCircuitBreak(in *v3alpha1.ShadowOnCircuitBreak, out *ShadowOnCircuitBreak, s conversion.Scope) error {
+	return autoConvert_v3alpha1_ShadowOnCircuitBreak_To_v2_ShadowOnCircuitBreak(in, out, s)
+}
+
+func autoConvert_v2_StaticFallback_To_v3alpha1_StaticFallback(in *StaticFallback, out *v3alpha1.StaticFallback, s conversion.Scope) error {
+	out.StatusCode = in.StatusCode
+	out.Body = in.Body
+	out.ContentType = in.ContentType
+	return nil
+}
+
+// Convert_v2_StaticFallback_To_v3alpha1_StaticFallback is an autogenerated conversion function.
+func Convert_v2_StaticFallback_To_v3alpha1_StaticFallback(in *StaticFallback, out *v3alpha1.StaticFallback, s conversion.Scope) error {
+	return autoConvert_v2_StaticFallback_To_v3alpha1_StaticFallback(in, out, s)
+}
+
+func autoConvert_v3alpha1_StaticFallback_To_v2_StaticFallback(in *v3alpha1.StaticFallback, out *StaticFallback, s conversion.Scope) error {
+	out.StatusCode = in.StatusCode
+	out.Body = in.Body
+	out.ContentType = in.ContentType
+	return nil
+}
+
+// Convert_v3alpha1_StaticFallback_To_v2_StaticFallback is an autogenerated conversion function.
+func Convert_v3alpha1_StaticFallback_To_v2_StaticFallback(in *v3alpha1.StaticFallback, out *StaticFallback, s conversion.Scope) error {
+	return autoConvert_v3alpha1_StaticFallback_To_v2_StaticFallback(in, out, s)
+}
+
+func autoConvert_v2_StatsDimension_To_v3alpha1_StatsDimension(in *StatsDimension, out *v3alpha1.StatsDimension, s conversion.Scope) error {
+	out.Name = in.Name
+	out.Header = in.Header
+	out.Values = in.Values
+	return nil
+}
+
+// Convert_v2_StatsDimension_To_v3alpha1_StatsDimension is an autogenerated conversion function.
+func Convert_v2_StatsDimension_To_v3alpha1_StatsDimension(in *StatsDimension, out *v3alpha1.StatsDimension, s conversion.Scope) error {
+	return autoConvert_v2_StatsDimension_To_v3alpha1_StatsDimension(in, out, s)
+}
+
+func autoConvert_v3alpha1_StatsDimension_To_v2_StatsDimension(in *v3alpha1.StatsDimension, out *StatsDimension, s conversion.Scope) error {
+	out.Name = in.Name
+	out.Header = in.Header
+	out.Values = in.Values
+	return nil
+}
+
+// Convert_v3alpha1_StatsDimension_To_v2_StatsDimension is an autogenerated conversion function.
+func Convert_v3alpha1_StatsDimension_To_v2_StatsDimension(in *v3alpha1.StatsDimension, out *StatsDimension, s conversion.Scope) error {
+	return autoConvert_v3alpha1_StatsDimension_To_v2_StatsDimension(in, out, s)
+}
+
 func autoConvert_v2_TCPMapping_To_v3alpha1_TCPMapping(in *TCPMapping, out *v3alpha1.TCPMapping, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v2_TCPMappingSpec_To_v3alpha1_TCPMappingSpec(&in.Spec, &out.Spec, s); err != nil {
@@ -3272,6 +4594,7 @@ func autoConvert_v2_TCPMappingSpec_To_v3alpha1_TCPMappingSpec(in *TCPMappingSpec
 	out.Weight = in.Weight
 	out.ClusterTag = in.ClusterTag
 	out.StatsName = in.V3StatsName
+	out.ALPNProtocols = in.V3ALPNProtocols
 	return nil
 }
 
@@ -3302,6 +4625,7 @@ func autoConvert_v3alpha1_TCPMappingSpec_To_v2_TCPMappingSpec(in *v3alpha1.TCPMa
 	out.Weight = in.Weight
 	out.ClusterTag = in.ClusterTag
 	out.V3StatsName = in.StatsName
+	out.V3ALPNProtocols = in.ALPNProtocols
 	// WARNING: in.V2ExplicitTLS requires manual conversion: does not exist in peer-type
 	return nil
 }
@@ -3435,6 +4759,11 @@ func autoConvert_v2_TLSContextSpec_To_v3alpha1_TLSContextSpec(in *TLSContextSpec
 	out.SecretNamespacing = in.SecretNamespacing
 	out.RedirectCleartextFrom = in.RedirectCleartextFrom
 	out.SNI = in.SNI
+	out.InheritFrom = in.V3InheritFrom
+	out.SPIFFEIDs = in.V3SPIFFEIDs
+	out.SPIFFETrustDomain = in.V3SPIFFETrustDomain
+	out.AdditionalSecrets = in.V3AdditionalSecrets
+	out.OCSPStaplePolicy = in.V3OCSPStaplePolicy
 	return nil
 }
 
@@ -3462,6 +4791,11 @@ func autoConvert_v3alpha1_TLSContextSpec_To_v2_TLSContextSpec(in *v3alpha1.TLSCo
 	out.SecretNamespacing = in.SecretNamespacing
 	out.RedirectCleartextFrom = in.RedirectCleartextFrom
 	out.SNI = in.SNI
+	out.V3InheritFrom = in.InheritFrom
+	out.V3SPIFFEIDs = in.SPIFFEIDs
+	out.V3SPIFFETrustDomain = in.SPIFFETrustDomain
+	out.V3AdditionalSecrets = in.AdditionalSecrets
+	out.V3OCSPStaplePolicy = in.OCSPStaplePolicy
 	return nil
 }
 
@@ -3504,6 +4838,128 @@ func Convert_v3alpha1_TraceConfig_To_v2_TraceConfig(in *v3alpha1.TraceConfig, ou
 	return autoConvert_v3alpha1_TraceConfig_To_v2_TraceConfig(in, out, s)
 }
 
+func autoConvert_v2_TraceCustomTag_To_v3alpha1_TraceCustomTag(in *TraceCustomTag, out *v3alpha1.TraceCustomTag, s conversion.Scope) error {
+	out.Tag = in.Tag
+	if in.Literal != nil {
+		in, out := &in.Literal, &out.Literal
+		*out = new(v3alpha1.TraceLiteralTag)
+		**out = v3alpha1.TraceLiteralTag(**in)
+	} else {
+		out.Literal = nil
+	}
+	if in.RequestHeader != nil {
+		in, out := &in.RequestHeader, &out.RequestHeader
+		*out = new(v3alpha1.TraceValueFromName)
+		**out = v3alpha1.TraceValueFromName(**in)
+	} else {
+		out.RequestHeader = nil
+	}
+	if in.Environment != nil {
+		in, out := &in.Environment, &out.Environment
+		*out = new(v3alpha1.TraceValueFromName)
+		**out = v3alpha1.TraceValueFromName(**in)
+	} else {
+		out.Environment = nil
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(v3alpha1.TraceMetadataTag)
+		**out = v3alpha1.TraceMetadataTag(**in)
+	} else {
+		out.Metadata = nil
+	}
+	return nil
+}
+
+// Convert_v2_TraceCustomTag_To_v3alpha1_TraceCustomTag is an autogenerated conversion function.
+func Convert_v2_TraceCustomTag_To_v3alpha1_TraceCustomTag(in *TraceCustomTag, out *v3alpha1.TraceCustomTag, s conversion.Scope) error {
+	return autoConvert_v2_TraceCustomTag_To_v3alpha1_TraceCustomTag(in, out, s)
+}
+
+func autoConvert_v3alpha1_TraceCustomTag_To_v2_TraceCustomTag(in *v3alpha1.TraceCustomTag, out *TraceCustomTag, s conversion.Scope) error {
+	out.Tag = in.Tag
+	if in.Literal != nil {
+		in, out := &in.Literal, &out.Literal
+		*out = new(TraceLiteralTag)
+		**out = TraceLiteralTag(**in)
+	} else {
+		out.Literal = nil
+	}
+	if in.RequestHeader != nil {
+		in, out := &in.RequestHeader, &out.RequestHeader
+		*out = new(TraceValueFromName)
+		**out = TraceValueFromName(**in)
+	} else {
+		out.RequestHeader = nil
+	}
+	if in.Environment != nil {
+		in, out := &in.Environment, &out.Environment
+		*out = new(TraceValueFromName)
+		**out = TraceValueFromName(**in)
+	} else {
+		out.Environment = nil
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(TraceMetadataTag)
+		**out = TraceMetadataTag(**in)
+	} else {
+		out.Metadata = nil
+	}
+	return nil
+}
+
+// Convert_v3alpha1_TraceCustomTag_To_v2_TraceCustomTag is an autogenerated conversion function.
+func Convert_v3alpha1_TraceCustomTag_To_v2_TraceCustomTag(in *v3alpha1.TraceCustomTag, out *TraceCustomTag, s conversion.Scope) error {
+	return autoConvert_v3alpha1_TraceCustomTag_To_v2_TraceCustomTag(in, out, s)
+}
+
+func autoConvert_v2_TraceLiteralTag_To_v3alpha1_TraceLiteralTag(in *TraceLiteralTag, out *v3alpha1.TraceLiteralTag, s conversion.Scope) error {
+	out.Value = in.Value
+	return nil
+}
+
+// Convert_v2_TraceLiteralTag_To_v3alpha1_TraceLiteralTag is an autogenerated conversion function.
+func Convert_v2_TraceLiteralTag_To_v3alpha1_TraceLiteralTag(in *TraceLiteralTag, out *v3alpha1.TraceLiteralTag, s conversion.Scope) error {
+	return autoConvert_v2_TraceLiteralTag_To_v3alpha1_TraceLiteralTag(in, out, s)
+}
+
+func autoConvert_v3alpha1_TraceLiteralTag_To_v2_TraceLiteralTag(in *v3alpha1.TraceLiteralTag, out *TraceLiteralTag, s conversion.Scope) error {
+	out.Value = in.Value
+	return nil
+}
+
+// Convert_v3alpha1_TraceLiteralTag_To_v2_TraceLiteralTag is an autogenerated conversion function.
+func Convert_v3alpha1_TraceLiteralTag_To_v2_TraceLiteralTag(in *v3alpha1.TraceLiteralTag, out *TraceLiteralTag, s conversion.Scope) error {
+	return autoConvert_v3alpha1_TraceLiteralTag_To_v2_TraceLiteralTag(in, out, s)
+}
+
+func autoConvert_v2_TraceMetadataTag_To_v3alpha1_TraceMetadataTag(in *TraceMetadataTag, out *v3alpha1.TraceMetadataTag, s conversion.Scope) error {
+	out.Kind = in.Kind
+	out.Key = in.Key
+	out.Path = in.Path
+	out.DefaultValue = in.DefaultValue
+	return nil
+}
+
+// Convert_v2_TraceMetadataTag_To_v3alpha1_TraceMetadataTag is an autogenerated conversion function.
+func Convert_v2_TraceMetadataTag_To_v3alpha1_TraceMetadataTag(in *TraceMetadataTag, out *v3alpha1.TraceMetadataTag, s conversion.Scope) error {
+	return autoConvert_v2_TraceMetadataTag_To_v3alpha1_TraceMetadataTag(in, out, s)
+}
+
+func autoConvert_v3alpha1_TraceMetadataTag_To_v2_TraceMetadataTag(in *v3alpha1.TraceMetadataTag, out *TraceMetadataTag, s conversion.Scope) error {
+	out.Kind = in.Kind
+	out.Key = in.Key
+	out.Path = in.Path
+	out.DefaultValue = in.DefaultValue
+	return nil
+}
+
+// Convert_v3alpha1_TraceMetadataTag_To_v2_TraceMetadataTag is an autogenerated conversion function.
+func Convert_v3alpha1_TraceMetadataTag_To_v2_TraceMetadataTag(in *v3alpha1.TraceMetadataTag, out *TraceMetadataTag, s conversion.Scope) error {
+	return autoConvert_v3alpha1_TraceMetadataTag_To_v2_TraceMetadataTag(in, out, s)
+}
+
 func autoConvert_v2_TraceSampling_To_v3alpha1_TraceSampling(in *TraceSampling, out *v3alpha1.TraceSampling, s conversion.Scope) error {
 	out.Client = in.Client
 	out.Random = in.Random
@@ -3528,6 +4984,28 @@ func Convert_v3alpha1_TraceSampling_To_v2_TraceSampling(in *v3alpha1.TraceSampli
 	return autoConvert_v3alpha1_TraceSampling_To_v2_TraceSampling(in, out, s)
 }
 
+func autoConvert_v2_TraceValueFromName_To_v3alpha1_TraceValueFromName(in *TraceValueFromName, out *v3alpha1.TraceValueFromName, s conversion.Scope) error {
+	out.Name = in.Name
+	out.DefaultValue = in.DefaultValue
+	return nil
+}
+
+// Convert_v2_TraceValueFromName_To_v3alpha1_TraceValueFromName is an autogenerated conversion function.
+func Convert_v2_TraceValueFromName_To_v3alpha1_TraceValueFromName(in *TraceValueFromName, out *v3alpha1.TraceValueFromName, s conversion.Scope) error {
+	return autoConvert_v2_TraceValueFromName_To_v3alpha1_TraceValueFromName(in, out, s)
+}
+
+func autoConvert_v3alpha1_TraceValueFromName_To_v2_TraceValueFromName(in *v3alpha1.TraceValueFromName, out *TraceValueFromName, s conversion.Scope) error {
+	out.Name = in.Name
+	out.DefaultValue = in.DefaultValue
+	return nil
+}
+
+// Convert_v3alpha1_TraceValueFromName_To_v2_TraceValueFromName is an autogenerated conversion function.
+func Convert_v3alpha1_TraceValueFromName_To_v2_TraceValueFromName(in *v3alpha1.TraceValueFromName, out *TraceValueFromName, s conversion.Scope) error {
+	return autoConvert_v3alpha1_TraceValueFromName_To_v2_TraceValueFromName(in, out, s)
+}
+
 func autoConvert_v2_TracingService_To_v3alpha1_TracingService(in *TracingService, out *v3alpha1.TracingService, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v2_TracingServiceSpec_To_v3alpha1_TracingServiceSpec(&in.Spec, &out.Spec, s); err != nil {
@@ -3618,6 +5096,19 @@ func autoConvert_v2_TracingServiceSpec_To_v3alpha1_TracingServiceSpec(in *Tracin
 		out.Config = nil
 	}
 	out.StatsName = in.V3StatsName
+	out.Propagation = in.V3Propagation
+	out.TrustIncomingTraceContext = in.V3TrustIncomingTraceContext
+	if in.V3CustomTags != nil {
+		in, out := &in.V3CustomTags, &out.CustomTags
+		*out = make([]v3alpha1.TraceCustomTag, len(*in))
+		for i := range *in {
+			if err := Convert_v2_TraceCustomTag_To_v3alpha1_TraceCustomTag(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.CustomTags = nil
+	}
 	return nil
 }
 
@@ -3648,6 +5139,19 @@ func autoConvert_v3alpha1_TracingServiceSpec_To_v2_TracingServiceSpec(in *v3alph
 		out.Config = nil
 	}
 	out.V3StatsName = in.StatsName
+	out.V3Propagation = in.Propagation
+	out.V3TrustIncomingTraceContext = in.TrustIncomingTraceContext
+	if in.CustomTags != nil {
+		in, out := &in.CustomTags, &out.V3CustomTags
+		*out = make([]TraceCustomTag, len(*in))
+		for i := range *in {
+			if err := Convert_v3alpha1_TraceCustomTag_To_v2_TraceCustomTag(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.V3CustomTags = nil
+	}
 	return nil
 }
 
@@ -3675,3 +5179,69 @@ func autoConvert_v3alpha1_UntypedDict_To_v2_UntypedDict(in *v3alpha1.UntypedDict
 func Convert_v3alpha1_UntypedDict_To_v2_UntypedDict(in *v3alpha1.UntypedDict, out *UntypedDict, s conversion.Scope) error {
 	return autoConvert_v3alpha1_UntypedDict_To_v2_UntypedDict(in, out, s)
 }
+
+func autoConvert_v2_ZeroReplicas_To_v3alpha1_ZeroReplicas(in *ZeroReplicas, out *v3alpha1.ZeroReplicas, s conversion.Scope) error {
+	if in.Respond != nil {
+		in, out := &in.Respond, &out.Respond
+		*out = new(v3alpha1.StaticFallback)
+		**out = v3alpha1.StaticFallback(**in)
+	} else {
+		out.Respond = nil
+	}
+	if in.Hold != nil {
+		in, out := &in.Hold, &out.Hold
+		*out = new(v3alpha1.ZeroReplicasHold)
+		**out = v3alpha1.ZeroReplicasHold(**in)
+	} else {
+		out.Hold = nil
+	}
+	return nil
+}
+
+// Convert_v2_ZeroReplicas_To_v3alpha1_ZeroReplicas is an autogenerated conversion function.
+func Convert_v2_ZeroReplicas_To_v3alpha1_ZeroReplicas(in *ZeroReplicas, out *v3alpha1.ZeroReplicas, s conversion.Scope) error {
+	return autoConvert_v2_ZeroReplicas_To_v3alpha1_ZeroReplicas(in, out, s)
+}
+
+func autoConvert_v3alpha1_ZeroReplicas_To_v2_ZeroReplicas(in *v3alpha1.ZeroReplicas, out *ZeroReplicas, s conversion.Scope) error {
+	if in.Respond != nil {
+		in, out := &in.Respond, &out.Respond
+		*out = new(StaticFallback)
+		**out = StaticFallback(**in)
+	} else {
+		out.Respond = nil
+	}
+	if in.Hold != nil {
+		in, out := &in.Hold, &out.Hold
+		*out = new(ZeroReplicasHold)
+		**out = ZeroReplicasHold(**in)
+	} else {
+		out.Hold = nil
+	}
+	return nil
+}
+
+// Convert_v3alpha1_ZeroReplicas_To_v2_ZeroReplicas is an autogenerated conversion function.
+func Convert_v3alpha1_ZeroReplicas_To_v2_ZeroReplicas(in *v3alpha1.ZeroReplicas, out *ZeroReplicas, s conversion.Scope) error {
+	return autoConvert_v3alpha1_ZeroReplicas_To_v2_ZeroReplicas(in, out, s)
+}
+
+func autoConvert_v2_ZeroReplicasHold_To_v3alpha1_ZeroReplicasHold(in *ZeroReplicasHold, out *v3alpha1.ZeroReplicasHold, s conversion.Scope) error {
+	out.Service = in.Service
+	return nil
+}
+
+// Convert_v2_ZeroReplicasHold_To_v3alpha1_ZeroReplicasHold is an autogenerated conversion function.
+func Convert_v2_ZeroReplicasHold_To_v3alpha1_ZeroReplicasHold(in *ZeroReplicasHold, out *v3alpha1.ZeroReplicasHold, s conversion.Scope) error {
+	return autoConvert_v2_ZeroReplicasHold_To_v3alpha1_ZeroReplicasHold(in, out, s)
+}
+
+func autoConvert_v3alpha1_ZeroReplicasHold_To_v2_ZeroReplicasHold(in *v3alpha1.ZeroReplicasHold, out *ZeroReplicasHold, s conversion.Scope) error {
+	out.Service = in.Service
+	return nil
+}
+
+// Convert_v3alpha1_ZeroReplicasHold_To_v2_ZeroReplicasHold is an autogenerated conversion function.
+func Convert_v3alpha1_ZeroReplicasHold_To_v2_ZeroReplicasHold(in *v3alpha1.ZeroReplicasHold, out *ZeroReplicasHold, s conversion.Scope) error {
+	return autoConvert_v3alpha1_ZeroReplicasHold_To_v2_ZeroReplicasHold(in, out, s)
+}
@@ -26,6 +26,21 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ABHeader) DeepCopyInto(out *ABHeader) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ABHeader.
+func (in *ABHeader) DeepCopy() *ABHeader {
+	if in == nil {
+		return nil
+	}
+	out := new(ABHeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ACMEProviderSpec) DeepCopyInto(out *ACMEProviderSpec) {
 	*out = *in
@@ -311,6 +326,31 @@ func (in *AuthServiceStatusOnError) DeepCopy() *AuthServiceStatusOnError {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BodyTransform) DeepCopyInto(out *BodyTransform) {
+	*out = *in
+	if in.ContentTypes != nil {
+		in, out := &in.ContentTypes, &out.ContentTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxBytes != nil {
+		in, out := &in.MaxBytes, &out.MaxBytes
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BodyTransform.
+func (in *BodyTransform) DeepCopy() *BodyTransform {
+	if in == nil {
+		return nil
+	}
+	out := new(BodyTransform)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BoolOrString) DeepCopyInto(out *BoolOrString) {
 	*out = *in
@@ -399,6 +439,11 @@ func (in *CircuitBreaker) DeepCopyInto(out *CircuitBreaker) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.MaxConnectionPools != nil {
+		in, out := &in.MaxConnectionPools, &out.MaxConnectionPools
+		*out = new(int)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CircuitBreaker.
@@ -489,6 +534,26 @@ func (in *ConsulResolverSpec) DeepCopy() *ConsulResolverSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CookieAttributes) DeepCopyInto(out *CookieAttributes) {
+	*out = *in
+	if in.Secure != nil {
+		in, out := &in.Secure, &out.Secure
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CookieAttributes.
+func (in *CookieAttributes) DeepCopy() *CookieAttributes {
+	if in == nil {
+		return nil
+	}
+	out := new(CookieAttributes)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DevPortal) DeepCopyInto(out *DevPortal) {
 	*out = *in
@@ -798,6 +863,26 @@ func (in *DriverConfig) DeepCopy() *DriverConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DynamicForwardProxy) DeepCopyInto(out *DynamicForwardProxy) {
+	*out = *in
+	if in.AllowedHosts != nil {
+		in, out := &in.AllowedHosts, &out.AllowedHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DynamicForwardProxy.
+func (in *DynamicForwardProxy) DeepCopy() *DynamicForwardProxy {
+	if in == nil {
+		return nil
+	}
+	out := new(DynamicForwardProxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ErrorResponseOverride) DeepCopyInto(out *ErrorResponseOverride) {
 	*out = *in
@@ -856,6 +941,192 @@ func (in *ErrorResponseTextFormatSource) DeepCopy() *ErrorResponseTextFormatSour
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GRPCHealthCheck) DeepCopyInto(out *GRPCHealthCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPCHealthCheck.
+func (in *GRPCHealthCheck) DeepCopy() *GRPCHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(GRPCHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HSTSConfig) DeepCopyInto(out *HSTSConfig) {
+	*out = *in
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(int)
+		**out = **in
+	}
+	if in.IncludeSubDomains != nil {
+		in, out := &in.IncludeSubDomains, &out.IncludeSubDomains
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Preload != nil {
+		in, out := &in.Preload, &out.Preload
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HSTSConfig.
+func (in *HSTSConfig) DeepCopy() *HSTSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(HSTSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTP2KeepAlive) DeepCopyInto(out *HTTP2KeepAlive) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.IntervalJitterPercent != nil {
+		in, out := &in.IntervalJitterPercent, &out.IntervalJitterPercent
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTP2KeepAlive.
+func (in *HTTP2KeepAlive) DeepCopy() *HTTP2KeepAlive {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTP2KeepAlive)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPHealthCheck) DeepCopyInto(out *HTTPHealthCheck) {
+	*out = *in
+	if in.ExpectedStatuses != nil {
+		in, out := &in.ExpectedStatuses, &out.ExpectedStatuses
+		*out = make([]HealthCheckStatusRange, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPHealthCheck.
+func (in *HTTPHealthCheck) DeepCopy() *HTTPHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheck) DeepCopyInto(out *HealthCheck) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.IntervalJitter != nil {
+		in, out := &in.IntervalJitter, &out.IntervalJitter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	in.HealthCheckSpecifier.DeepCopyInto(&out.HealthCheckSpecifier)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheck.
+func (in *HealthCheck) DeepCopy() *HealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheckSpecifier) DeepCopyInto(out *HealthCheckSpecifier) {
+	*out = *in
+	if in.HTTPHealthCheck != nil {
+		in, out := &in.HTTPHealthCheck, &out.HTTPHealthCheck
+		*out = new(HTTPHealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GRPCHealthCheck != nil {
+		in, out := &in.GRPCHealthCheck, &out.GRPCHealthCheck
+		*out = new(GRPCHealthCheck)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheckSpecifier.
+func (in *HealthCheckSpecifier) DeepCopy() *HealthCheckSpecifier {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheckSpecifier)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheckStatusRange) DeepCopyInto(out *HealthCheckStatusRange) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheckStatusRange.
+func (in *HealthCheckStatusRange) DeepCopy() *HealthCheckStatusRange {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheckStatusRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Hedging) DeepCopyInto(out *Hedging) {
+	*out = *in
+	if in.MinConcurrency != nil {
+		in, out := &in.MinConcurrency, &out.MinConcurrency
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Hedging.
+func (in *Hedging) DeepCopy() *Hedging {
+	if in == nil {
+		return nil
+	}
+	out := new(Hedging)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Host) DeepCopyInto(out *Host) {
 	*out = *in
@@ -967,6 +1238,11 @@ func (in *HostSpec) DeepCopyInto(out *HostSpec) {
 		*out = new(TLSConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.V3HSTS != nil {
+		in, out := &in.V3HSTS, &out.V3HSTS
+		*out = new(HSTSConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostSpec.
@@ -1023,6 +1299,48 @@ func (in *InsecureRequestPolicy) DeepCopy() *InsecureRequestPolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JWTClaim) DeepCopyInto(out *JWTClaim) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JWTClaim.
+func (in *JWTClaim) DeepCopy() *JWTClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(JWTClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JWTRequirement) DeepCopyInto(out *JWTRequirement) {
+	*out = *in
+	if in.Claims != nil {
+		in, out := &in.Claims, &out.Claims
+		*out = make([]JWTClaim, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JWTRequirement.
+func (in *JWTRequirement) DeepCopy() *JWTRequirement {
+	if in == nil {
+		return nil
+	}
+	out := new(JWTRequirement)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KeepAlive) DeepCopyInto(out *KeepAlive) {
 	*out = *in
@@ -1222,6 +1540,16 @@ func (in *LoadBalancer) DeepCopyInto(out *LoadBalancer) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.ChoiceCount != nil {
+		in, out := &in.ChoiceCount, &out.ChoiceCount
+		*out = new(int)
+		**out = **in
+	}
+	if in.Path != nil {
+		in, out := &in.Path, &out.Path
+		*out = new(LoadBalancerPath)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancer.
@@ -1250,33 +1578,73 @@ func (in *LoadBalancerCookie) DeepCopy() *LoadBalancerCookie {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *LogService) DeepCopyInto(out *LogService) {
+func (in *LoadBalancerPath) DeepCopyInto(out *LoadBalancerPath) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
+	if in.Segments != nil {
+		in, out := &in.Segments, &out.Segments
+		*out = new(int)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogService.
-func (in *LogService) DeepCopy() *LogService {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerPath.
+func (in *LoadBalancerPath) DeepCopy() *LoadBalancerPath {
 	if in == nil {
 		return nil
 	}
-	out := new(LogService)
+	out := new(LoadBalancerPath)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *LogService) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogSampling) DeepCopyInto(out *LogSampling) {
+	*out = *in
+	if in.AlwaysLogStatus != nil {
+		in, out := &in.AlwaysLogStatus, &out.AlwaysLogStatus
+		*out = new(int)
+		**out = **in
 	}
-	return nil
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogSampling.
+func (in *LogSampling) DeepCopy() *LogSampling {
+	if in == nil {
+		return nil
+	}
+	out := new(LogSampling)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *LogServiceList) DeepCopyInto(out *LogServiceList) {
+func (in *LogService) DeepCopyInto(out *LogService) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogService.
+func (in *LogService) DeepCopy() *LogService {
+	if in == nil {
+		return nil
+	}
+	out := new(LogService)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LogService) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogServiceList) DeepCopyInto(out *LogServiceList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
@@ -1887,6 +2255,220 @@ func (in *MappingSpec) DeepCopyInto(out *MappingSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.V3ShadowOnCircuitBreak != nil {
+		in, out := &in.V3ShadowOnCircuitBreak, &out.V3ShadowOnCircuitBreak
+		*out = new(ShadowOnCircuitBreak)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.V3ShadowCloneCluster != nil {
+		in, out := &in.V3ShadowCloneCluster, &out.V3ShadowCloneCluster
+		*out = new(bool)
+		**out = **in
+	}
+	if in.V3EchoRequestID != nil {
+		in, out := &in.V3EchoRequestID, &out.V3EchoRequestID
+		*out = new(bool)
+		**out = **in
+	}
+	if in.V3AutoSNI != nil {
+		in, out := &in.V3AutoSNI, &out.V3AutoSNI
+		*out = new(bool)
+		**out = **in
+	}
+	if in.V3AutoHTTPProtocol != nil {
+		in, out := &in.V3AutoHTTPProtocol, &out.V3AutoHTTPProtocol
+		*out = new(bool)
+		**out = **in
+	}
+	if in.V3JWT != nil {
+		in, out := &in.V3JWT, &out.V3JWT
+		*out = new(JWTRequirement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.V3StaticFallback != nil {
+		in, out := &in.V3StaticFallback, &out.V3StaticFallback
+		*out = new(StaticFallback)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.V3VirtualCluster != nil {
+		in, out := &in.V3VirtualCluster, &out.V3VirtualCluster
+		*out = new(bool)
+		**out = **in
+	}
+	if in.V3HostRewriteFromSNI != nil {
+		in, out := &in.V3HostRewriteFromSNI, &out.V3HostRewriteFromSNI
+		*out = new(bool)
+		**out = **in
+	}
+	if in.V3DNSFailureRefreshRate != nil {
+		in, out := &in.V3DNSFailureRefreshRate, &out.V3DNSFailureRefreshRate
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.V3InitialFetchTimeout != nil {
+		in, out := &in.V3InitialFetchTimeout, &out.V3InitialFetchTimeout
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.V3HealthChecks != nil {
+		in, out := &in.V3HealthChecks, &out.V3HealthChecks
+		*out = make([]HealthCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.V3MaxGRPCTimeout != nil {
+		in, out := &in.V3MaxGRPCTimeout, &out.V3MaxGRPCTimeout
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.V3GRPCTimeoutOffset != nil {
+		in, out := &in.V3GRPCTimeoutOffset, &out.V3GRPCTimeoutOffset
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.V3DynamicForwardProxy != nil {
+		in, out := &in.V3DynamicForwardProxy, &out.V3DynamicForwardProxy
+		*out = new(DynamicForwardProxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.V3SuppressEnvoyHeaders != nil {
+		in, out := &in.V3SuppressEnvoyHeaders, &out.V3SuppressEnvoyHeaders
+		*out = new(bool)
+		**out = **in
+	}
+	if in.V3DecompressorMaxRequestBytes != nil {
+		in, out := &in.V3DecompressorMaxRequestBytes, &out.V3DecompressorMaxRequestBytes
+		*out = new(int)
+		**out = **in
+	}
+	if in.V3ClusterProtocolOptions != nil {
+		in, out := &in.V3ClusterProtocolOptions, &out.V3ClusterProtocolOptions
+		*out = new(UntypedDict)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.V3ClusterDrainTime != nil {
+		in, out := &in.V3ClusterDrainTime, &out.V3ClusterDrainTime
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.V3HTTP2KeepAlive != nil {
+		in, out := &in.V3HTTP2KeepAlive, &out.V3HTTP2KeepAlive
+		*out = new(HTTP2KeepAlive)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.V3RetryBufferLimitBytes != nil {
+		in, out := &in.V3RetryBufferLimitBytes, &out.V3RetryBufferLimitBytes
+		*out = new(int)
+		**out = **in
+	}
+	if in.V3PreserveSensitiveHeaders != nil {
+		in, out := &in.V3PreserveSensitiveHeaders, &out.V3PreserveSensitiveHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.V3ContentTypes != nil {
+		in, out := &in.V3ContentTypes, &out.V3ContentTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.V3ResponseFlagStats != nil {
+		in, out := &in.V3ResponseFlagStats, &out.V3ResponseFlagStats
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.V3Methods != nil {
+		in, out := &in.V3Methods, &out.V3Methods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.V3BypassCompression != nil {
+		in, out := &in.V3BypassCompression, &out.V3BypassCompression
+		*out = new(bool)
+		**out = **in
+	}
+	if in.V3RequestTimeoutHeader != nil {
+		in, out := &in.V3RequestTimeoutHeader, &out.V3RequestTimeoutHeader
+		*out = new(RequestTimeoutHeader)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.V3CookieAttributes != nil {
+		in, out := &in.V3CookieAttributes, &out.V3CookieAttributes
+		*out = new(CookieAttributes)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.V3BodyTransform != nil {
+		in, out := &in.V3BodyTransform, &out.V3BodyTransform
+		*out = new(BodyTransform)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.V3TypedPerFilterConfig != nil {
+		in, out := &in.V3TypedPerFilterConfig, &out.V3TypedPerFilterConfig
+		*out = new(UntypedDict)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.V3HealthRebalance != nil {
+		in, out := &in.V3HealthRebalance, &out.V3HealthRebalance
+		*out = new(bool)
+		**out = **in
+	}
+	if in.V3HealthRebalanceRecoveryMs != nil {
+		in, out := &in.V3HealthRebalanceRecoveryMs, &out.V3HealthRebalanceRecoveryMs
+		*out = new(int)
+		**out = **in
+	}
+	if in.V3HistogramBuckets != nil {
+		in, out := &in.V3HistogramBuckets, &out.V3HistogramBuckets
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.V3DisableHistograms != nil {
+		in, out := &in.V3DisableHistograms, &out.V3DisableHistograms
+		*out = new(bool)
+		**out = **in
+	}
+	if in.V3CaseInsensitiveHeaders != nil {
+		in, out := &in.V3CaseInsensitiveHeaders, &out.V3CaseInsensitiveHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.V3MaxRequestsPerConnection != nil {
+		in, out := &in.V3MaxRequestsPerConnection, &out.V3MaxRequestsPerConnection
+		*out = new(int)
+		**out = **in
+	}
+	if in.V3ABHeader != nil {
+		in, out := &in.V3ABHeader, &out.V3ABHeader
+		*out = new(ABHeader)
+		**out = **in
+	}
+	if in.V3ResolverFallback != nil {
+		in, out := &in.V3ResolverFallback, &out.V3ResolverFallback
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.V3StatsDimensions != nil {
+		in, out := &in.V3StatsDimensions, &out.V3StatsDimensions
+		*out = make([]StatsDimension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.V3Hedging != nil {
+		in, out := &in.V3Hedging, &out.V3Hedging
+		*out = new(Hedging)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.V3ZeroReplicas != nil {
+		in, out := &in.V3ZeroReplicas, &out.V3ZeroReplicas
+		*out = new(ZeroReplicas)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.V3LogSampling != nil {
+		in, out := &in.V3LogSampling, &out.V3LogSampling
+		*out = new(LogSampling)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MappingSpec.
@@ -2124,6 +2706,11 @@ func (in *RateLimitServiceSpec) DeepCopyInto(out *RateLimitServiceSpec) {
 		*out = new(BoolOrString)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.V3FailureModeDeny != nil {
+		in, out := &in.V3FailureModeDeny, &out.V3FailureModeDeny
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitServiceSpec.
@@ -2167,6 +2754,56 @@ func (in *RequestPolicy) DeepCopy() *RequestPolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RequestTimeoutHeader) DeepCopyInto(out *RequestTimeoutHeader) {
+	*out = *in
+	if in.Honor != nil {
+		in, out := &in.Honor, &out.Honor
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Max != nil {
+		in, out := &in.Max, &out.Max
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RequestTimeoutHeader.
+func (in *RequestTimeoutHeader) DeepCopy() *RequestTimeoutHeader {
+	if in == nil {
+		return nil
+	}
+	out := new(RequestTimeoutHeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryBackOff) DeepCopyInto(out *RetryBackOff) {
+	*out = *in
+	if in.BaseInterval != nil {
+		in, out := &in.BaseInterval, &out.BaseInterval
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.MaxInterval != nil {
+		in, out := &in.MaxInterval, &out.MaxInterval
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryBackOff.
+func (in *RetryBackOff) DeepCopy() *RetryBackOff {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryBackOff)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
 	*out = *in
@@ -2175,6 +2812,43 @@ func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.GRPCRetryOn != nil {
+		in, out := &in.GRPCRetryOn, &out.GRPCRetryOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RetryBackOff != nil {
+		in, out := &in.RetryBackOff, &out.RetryBackOff
+		*out = new(RetryBackOff)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RetryOtherHosts != nil {
+		in, out := &in.RetryOtherHosts, &out.RetryOtherHosts
+		*out = new(bool)
+		**out = **in
+	}
+	if in.HostSelectionRetryMaxAttempts != nil {
+		in, out := &in.HostSelectionRetryMaxAttempts, &out.HostSelectionRetryMaxAttempts
+		*out = new(int)
+		**out = **in
+	}
+	if in.RetryOnReset != nil {
+		in, out := &in.RetryOnReset, &out.RetryOnReset
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RetryNonIdempotent != nil {
+		in, out := &in.RetryNonIdempotent, &out.RetryNonIdempotent
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RetryOnResponseHeaders != nil {
+		in, out := &in.RetryOnResponseHeaders, &out.RetryOnResponseHeaders
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
@@ -2187,6 +2861,66 @@ func (in *RetryPolicy) DeepCopy() *RetryPolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShadowOnCircuitBreak) DeepCopyInto(out *ShadowOnCircuitBreak) {
+	*out = *in
+	if in.MaxRequests != nil {
+		in, out := &in.MaxRequests, &out.MaxRequests
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShadowOnCircuitBreak.
+func (in *ShadowOnCircuitBreak) DeepCopy() *ShadowOnCircuitBreak {
+	if in == nil {
+		return nil
+	}
+	out := new(ShadowOnCircuitBreak)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticFallback) DeepCopyInto(out *StaticFallback) {
+	*out = *in
+	if in.StatusCode != nil {
+		in, out := &in.StatusCode, &out.StatusCode
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticFallback.
+func (in *StaticFallback) DeepCopy() *StaticFallback {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticFallback)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StatsDimension) DeepCopyInto(out *StatsDimension) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatsDimension.
+func (in *StatsDimension) DeepCopy() *StatsDimension {
+	if in == nil {
+		return nil
+	}
+	out := new(StatsDimension)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in StringOrStringList) DeepCopyInto(out *StringOrStringList) {
 	{
@@ -2299,6 +3033,11 @@ func (in *TCPMappingSpec) DeepCopyInto(out *TCPMappingSpec) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.V3ALPNProtocols != nil {
+		in, out := &in.V3ALPNProtocols, &out.V3ALPNProtocols
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPMappingSpec.
@@ -2442,6 +3181,16 @@ func (in *TLSContextSpec) DeepCopyInto(out *TLSContextSpec) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.V3SPIFFEIDs != nil {
+		in, out := &in.V3SPIFFEIDs, &out.V3SPIFFEIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.V3AdditionalSecrets != nil {
+		in, out := &in.V3AdditionalSecrets, &out.V3AdditionalSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSContextSpec.
@@ -2479,6 +3228,76 @@ func (in *TraceConfig) DeepCopy() *TraceConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TraceCustomTag) DeepCopyInto(out *TraceCustomTag) {
+	*out = *in
+	if in.Literal != nil {
+		in, out := &in.Literal, &out.Literal
+		*out = new(TraceLiteralTag)
+		**out = **in
+	}
+	if in.RequestHeader != nil {
+		in, out := &in.RequestHeader, &out.RequestHeader
+		*out = new(TraceValueFromName)
+		**out = **in
+	}
+	if in.Environment != nil {
+		in, out := &in.Environment, &out.Environment
+		*out = new(TraceValueFromName)
+		**out = **in
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(TraceMetadataTag)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TraceCustomTag.
+func (in *TraceCustomTag) DeepCopy() *TraceCustomTag {
+	if in == nil {
+		return nil
+	}
+	out := new(TraceCustomTag)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TraceLiteralTag) DeepCopyInto(out *TraceLiteralTag) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TraceLiteralTag.
+func (in *TraceLiteralTag) DeepCopy() *TraceLiteralTag {
+	if in == nil {
+		return nil
+	}
+	out := new(TraceLiteralTag)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TraceMetadataTag) DeepCopyInto(out *TraceMetadataTag) {
+	*out = *in
+	if in.Path != nil {
+		in, out := &in.Path, &out.Path
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TraceMetadataTag.
+func (in *TraceMetadataTag) DeepCopy() *TraceMetadataTag {
+	if in == nil {
+		return nil
+	}
+	out := new(TraceMetadataTag)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TraceSampling) DeepCopyInto(out *TraceSampling) {
 	*out = *in
@@ -2509,6 +3328,21 @@ func (in *TraceSampling) DeepCopy() *TraceSampling {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TraceValueFromName) DeepCopyInto(out *TraceValueFromName) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TraceValueFromName.
+func (in *TraceValueFromName) DeepCopy() *TraceValueFromName {
+	if in == nil {
+		return nil
+	}
+	out := new(TraceValueFromName)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TracingService) DeepCopyInto(out *TracingService) {
 	*out = *in
@@ -2590,6 +3424,18 @@ func (in *TracingServiceSpec) DeepCopyInto(out *TracingServiceSpec) {
 		*out = new(TraceConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.V3TrustIncomingTraceContext != nil {
+		in, out := &in.V3TrustIncomingTraceContext, &out.V3TrustIncomingTraceContext
+		*out = new(bool)
+		**out = **in
+	}
+	if in.V3CustomTags != nil {
+		in, out := &in.V3CustomTags, &out.V3CustomTags
+		*out = make([]TraceCustomTag, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracingServiceSpec.
@@ -2631,3 +3477,43 @@ func (in *UntypedDict) DeepCopy() *UntypedDict {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeroReplicas) DeepCopyInto(out *ZeroReplicas) {
+	*out = *in
+	if in.Respond != nil {
+		in, out := &in.Respond, &out.Respond
+		*out = new(StaticFallback)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hold != nil {
+		in, out := &in.Hold, &out.Hold
+		*out = new(ZeroReplicasHold)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeroReplicas.
+func (in *ZeroReplicas) DeepCopy() *ZeroReplicas {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeroReplicas)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeroReplicasHold) DeepCopyInto(out *ZeroReplicasHold) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeroReplicasHold.
+func (in *ZeroReplicasHold) DeepCopy() *ZeroReplicasHold {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeroReplicasHold)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -134,6 +134,24 @@ type HostSpec struct {
 	// TLS configuration.  It is not valid to specify both
 	// `tlsContext` and `tls`.
 	TLS *TLSConfig `json:"tls,omitempty"`
+
+	// HSTS adds a Strict-Transport-Security header to every response to a request that
+	// came in over TLS for this Host, replacing any the service sent. Responses over
+	// cleartext never get one. Without it, the ambassador Module's hsts applies.
+	HSTS *HSTSConfig `json:"hsts,omitempty"`
+}
+
+// HSTSConfig says how long browsers should insist on HTTPS for a Host, and for what.
+type HSTSConfig struct {
+	// MaxAge is how long, in seconds, browsers remember to use only HTTPS. Defaults to a
+	// year (31536000); 0 tells them to forget.
+	// +kubebuilder:validation:Minimum=0
+	MaxAge *int `json:"maxAge,omitempty"`
+	// IncludeSubDomains extends the policy to every subdomain of the hostname.
+	IncludeSubDomains *bool `json:"includeSubDomains,omitempty"`
+	// Preload says the policy is fit for browsers' built-in HSTS preload lists, which also
+	// need includeSubDomains and a maxAge of at least a year.
+	Preload *bool `json:"preload,omitempty"`
 }
 
 type TLSConfig struct {
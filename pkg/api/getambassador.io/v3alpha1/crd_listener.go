@@ -95,6 +95,23 @@ const (
 	INSECURESecurityModelType SecurityModelType = "INSECURE"
 )
 
+// CodecType defines which HTTP versions a Listener will accept from clients.
+// +kubebuilder:validation:Enum=AUTO;HTTP1;HTTP2
+type CodecType string
+
+const (
+	// AUTOCodecType accepts HTTP/1.1 or HTTP/2. With TLS, ALPN decides; in cleartext,
+	// clients that start with the HTTP/2 preface (h2c with prior knowledge) get HTTP/2.
+	AUTOCodecType CodecType = "AUTO"
+
+	// HTTP1CodecType accepts only HTTP/1.1.
+	HTTP1CodecType CodecType = "HTTP1"
+
+	// HTTP2CodecType accepts only HTTP/2, so cleartext clients must use h2c with prior
+	// knowledge. (The HTTP/1.1 Upgrade to h2c is not supported.)
+	HTTP2CodecType CodecType = "HTTP2"
+)
+
 // NamespaceFromType defines how we evaluate a NamespaceBindingType.
 // +kubebuilder:validation:Enum=SELF;ALL;SELECTOR
 type NamespaceFromType string
@@ -136,7 +153,8 @@ type HostBindingType struct {
 type ListenerSpec struct {
 	AmbassadorID AmbassadorID `json:"ambassador_id,omitempty"`
 
-	// Port is the network port. Only one Listener can use a given port.
+	// Port is the network port. Only one Listener can use a given port, unless they
+	// have different DestinationPorts.
 	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:validation:Maximum=65535
 	// +kubebuilder:validation:Required
@@ -164,11 +182,109 @@ type ListenerSpec struct {
 	// the network.
 	L7Depth int32 `json:"l7Depth,omitempty"`
 
+	// KeepAlive enables TCP keepalive on downstream (client) connections accepted
+	// by this Listener. It overrides the Module's downstream_keepalive setting.
+	KeepAlive *KeepAlive `json:"keepAlive,omitempty"`
+
+	// DestinationPort restricts this Listener to connections whose original destination
+	// port (e.g. before an iptables REDIRECT) is this port. Several Listeners may share a
+	// port if they have different DestinationPorts; a Listener on that port without a
+	// DestinationPort handles any connections the others don't.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	DestinationPort int32 `json:"destinationPort,omitempty"`
+
+	// CodecType forces the HTTP version accepted by this Listener. The default is AUTO.
+	// It only applies to Listeners whose protocol stack includes HTTP. With TLS, make
+	// sure it agrees with the ALPN protocols offered by the TLSContext.
+	CodecType CodecType `json:"codecType,omitempty"`
+
+	// ForwardedProto, if set, overwrites the X-Forwarded-Proto header sent to upstream
+	// services. Without it, Envoy sets X-Forwarded-Proto from whether the client used TLS
+	// to reach this Listener, unless l7Depth says the header came from a trusted load
+	// balancer. Set it to "https" for a cleartext Listener behind a load balancer that
+	// terminates TLS without saying so. Routing (and securityModel XFP in particular) still
+	// sees the header as it arrived.
+	// +kubebuilder:validation:Enum=http;https
+	ForwardedProto string `json:"forwardedProto,omitempty"`
+
+	// RequestHeadersTimeout limits how long a client has to finish sending a request's
+	// headers, so that a slowloris client can't hold connections open by trickling them
+	// in. It overrides the Module's request_headers_timeout_ms; 0 turns the timeout off,
+	// which is Envoy's default. Keep it generous enough for clients on slow networks.
+	// It only applies to Listeners whose protocol stack includes HTTP.
+	RequestHeadersTimeout *MillisecondDuration `json:"requestHeadersTimeoutMs,omitempty"`
+
+	// RequestSmugglingProtection says how strictly this Listener parses HTTP/1 requests. With
+	// "strict" (the default), a request with both Content-Length and Transfer-Encoding is
+	// rejected, and a request that can't be parsed closes its connection. With "legacy", the
+	// Content-Length is dropped and the connection is kept open where possible, which some old
+	// clients need but which can allow request smuggling. It overrides the Module's
+	// request_smuggling_protection and allow_chunked_length. It only applies to Listeners
+	// whose protocol stack includes HTTP.
+	// +kubebuilder:validation:Enum=strict;legacy
+	RequestSmugglingProtection string `json:"requestSmugglingProtection,omitempty"`
+
+	// TLSDetection says how the TLS inspector picks filter chains when one port serves
+	// both TLS and cleartext. With "SNI" (the default), TLS connections go to the chain for
+	// their SNI, and the cleartext chain takes everything else -- including TLS connections
+	// whose SNI matches no Host, which then fail inside the HTTP codec. With "Split", the
+	// cleartext chain only takes cleartext connections, and TLS ones only ever go to TLS
+	// chains (so one whose SNI matches no Host, with no "*" Host to fall back on, is closed
+	// during the handshake). If no Host on a Split Listener takes cleartext requests, they
+	// get a 400 saying the port needs TLS, rather than a closed connection. It only applies
+	// to Listeners whose protocol stack includes both TLS and HTTP.
+	// +kubebuilder:validation:Enum=SNI;Split
+	TLSDetection string `json:"tlsDetection,omitempty"`
+
+	// UploadBuffering tunes this Listener for clients sending large request bodies. It
+	// only applies to Listeners whose protocol stack includes HTTP.
+	UploadBuffering *UploadBuffering `json:"uploadBuffering,omitempty"`
+
+	// ConnectionRateLimit caps how fast this Listener accepts new connections, to blunt
+	// connection floods. It's separate from any limit on how many connections can be open
+	// at once.
+	ConnectionRateLimit *ConnectionRateLimit `json:"connectionRateLimit,omitempty"`
+
 	// HostBinding allows restricting which Hosts will be used for this Listener.
 	// +kubebuilder:validation:Required
 	HostBinding HostBindingType `json:"hostBinding"`
 }
 
+// UploadBuffering says how much of a request Envoy holds on to for each connection.
+type UploadBuffering struct {
+	// ConnectionBufferBytes overrides the Module's buffer_limit_bytes on this Listener.
+	// Every connection can use this much memory, so it's the number to watch with many
+	// concurrent uploads.
+	// +kubebuilder:validation:Minimum=1
+	ConnectionBufferBytes *int32 `json:"connectionBufferBytes,omitempty"`
+
+	// Stream, if true, passes request bodies upstream as they arrive, rather than waiting
+	// for all of them: the Module's buffer isn't used on this Listener, and an AuthService
+	// with include_body only sees the first max_bytes of each body.
+	Stream *bool `json:"stream,omitempty"`
+}
+
+// ConnectionRateLimit is a token bucket for new connections: it holds burst connections,
+// and refills connectionsPerSecond of them every second. A connection that finds it empty is
+// closed as soon as it's accepted. Every client shares the bucket -- it doesn't single out
+// any one source IP -- and each Ambassador pod has its own. Envoy can only put a bucket on
+// each of the Listener's filter chains (with TLS, one per Host, plus one for cleartext), so
+// the rate and burst are split evenly between them, and all of them together stay within the
+// limit.
+type ConnectionRateLimit struct {
+	// ConnectionsPerSecond is how many new connections are accepted each second, once any
+	// burst is used up.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Required
+	ConnectionsPerSecond int32 `json:"connectionsPerSecond"`
+
+	// Burst is how many new connections can be accepted at once after a lull. It defaults
+	// to connectionsPerSecond, and can't be any less.
+	// +kubebuilder:validation:Minimum=1
+	Burst *int32 `json:"burst,omitempty"`
+}
+
 // Listener is the Schema for the hosts API
 //
 // +kubebuilder:object:root=true
@@ -62,8 +62,16 @@ type MappingSpec struct {
 	// The response code to use when generating an HTTP redirect. Defaults to 301. Used with
 	// `host_redirect`.
 	// +kubebuilder:validation:Enum={301,302,303,307,308}
-	RedirectResponseCode         *int                 `json:"redirect_response_code,omitempty"`
-	Priority                     string               `json:"priority,omitempty"`
+	RedirectResponseCode *int `json:"redirect_response_code,omitempty"`
+	// Priority is the routing priority of this Mapping's requests, "default" or "high".
+	// Requests at each priority count against the circuit_breakers thresholds for that
+	// priority, so high-priority traffic can keep flowing while default traffic is being
+	// shed. Defaults to "default".
+	Priority string `json:"priority,omitempty"`
+	// Precedence orders this Mapping's route against the others: higher goes first.
+	// Without it (or on a tie), longer prefixes go first, then Mappings with more header
+	// and query parameter matchers. A regex prefix counts by the length of its pattern, so
+	// regex Mappings that overlap literal ones usually want an explicit precedence.
 	Precedence                   *int                 `json:"precedence,omitempty"`
 	ClusterTag                   string               `json:"cluster_tag,omitempty"`
 	RemoveRequestHeaders         []string             `json:"remove_request_headers,omitempty"`
@@ -76,7 +84,9 @@ type MappingSpec struct {
 	ClusterIdleTimeout           *MillisecondDuration `json:"cluster_idle_timeout_ms,omitempty"`
 	ClusterMaxConnectionLifetime *MillisecondDuration `json:"cluster_max_connection_lifetime_ms,omitempty"`
 	// The timeout for requests that use this Mapping. Overrides `cluster_request_timeout_ms` set on the Ambassador Module, if it exists.
-	Timeout     *MillisecondDuration `json:"timeout_ms,omitempty"`
+	// 0 disables it, which streaming endpoints (e.g. server-sent events) usually want together with an IdleTimeout.
+	Timeout *MillisecondDuration `json:"timeout_ms,omitempty"`
+	// The timeout for requests that use this Mapping to go without any activity upstream or downstream. It's separate from Timeout.
 	IdleTimeout *MillisecondDuration `json:"idle_timeout_ms,omitempty"`
 	TLS         string               `json:"tls,omitempty"`
 
@@ -158,11 +168,372 @@ type MappingSpec struct {
 	RegexQueryParameters map[string]string `json:"regex_query_parameters,omitempty"`
 	StatsName            string            `json:"stats_name,omitempty"`
 
+	// UpstreamBindAddress is the source IP address (IPv4 or IPv6) to bind to for
+	// connections to the upstream service. Overrides `upstream_bind_address` set on the
+	// Ambassador Module, if it exists.
+	UpstreamBindAddress string `json:"upstream_bind_address,omitempty"`
+
+	// ShadowRuntimeKey is the Envoy runtime key that controls what percentage of requests
+	// get mirrored to a `shadow` Mapping. If the key isn't set in the runtime, `weight` (or
+	// 100) is used.
+	ShadowRuntimeKey string `json:"shadow_runtime_key,omitempty"`
+
+	// ShadowOnCircuitBreak keeps a `shadow` Mapping from mirroring anything until a runtime
+	// key turns it on, for comparing against a new version while the primary's circuit
+	// breaker is tripped. Can't be combined with ShadowRuntimeKey.
+	ShadowOnCircuitBreak *ShadowOnCircuitBreak `json:"shadow_on_circuit_break,omitempty"`
+
+	// ShadowCloneCluster gives a `shadow` Mapping's cluster the connection settings (TLS,
+	// circuit breakers, timeouts, load balancing and the like) of the Mapping it mirrors,
+	// except for any it sets itself. Defaults to the Module's shadow_clone_cluster.
+	ShadowCloneCluster *bool `json:"shadow_clone_cluster,omitempty"`
+
+	// TrailingSlash says what a prefix ending in a slash, like `/foo/`, does with a request
+	// for `/foo`: `strict` doesn't match it, `redirect` sends the client to `/foo/`, and
+	// `match` routes it as if it had the slash. Overrides `trailing_slash` on the Ambassador
+	// Module; the default is `strict`. Either way, nothing is taken from a Mapping whose
+	// prefix is `/foo` itself.
+	//
+	// +kubebuilder:validation:Enum={"strict","redirect","match"}
+	TrailingSlash string `json:"trailing_slash,omitempty"`
+
+	// EchoRequestID sends the request's `x-request-id` back in an `x-request-id` response
+	// header: the one Envoy generated, or the client's own if `preserve_external_request_id`
+	// kept it. Overrides `echo_request_id` on the Ambassador Module.
+	EchoRequestID *bool `json:"echo_request_id,omitempty"`
+
+	// AutoSNI sends each request's Host, after any HostRewrite, as the SNI when originating
+	// TLS, for upstreams that route on it. A request without a usable Host gets the
+	// TLSContext's `sni`, or failing that the host of the service. It does nothing unless
+	// the Mapping originates TLS.
+	AutoSNI *bool `json:"auto_sni,omitempty"`
+
+	// AutoHTTPProtocol offers the upstream both HTTP/2 and HTTP/1.1 with ALPN, and falls back
+	// to HTTP/1.1 when it doesn't pick h2. It does nothing unless the Mapping originates TLS,
+	// and is ignored for gRPC, which needs HTTP/2.
+	AutoHTTPProtocol *bool `json:"auto_http_protocol,omitempty"`
+
+	// JWT requires a JWT from one of the Ambassador Module's `jwt_providers` on this
+	// Mapping's requests, optionally with particular claims.
+	JWT *JWTRequirement `json:"jwt,omitempty"`
+
+	// StaticFallback is what to answer with when this Mapping's cluster has no healthy
+	// endpoints, whether it has none at all or none that pass health checks. Requests are
+	// routed normally again as soon as an endpoint is healthy.
+	StaticFallback *StaticFallback `json:"static_fallback,omitempty"`
+
+	// VirtualCluster gives this Mapping an Envoy virtual cluster of its own, so that it
+	// gets request stats even when it shares an upstream cluster with other Mappings. The
+	// virtual cluster is named after StatsName, or the Mapping's name and namespace; if
+	// another Mapping's already has that name, this one's gets the Mapping's name and
+	// namespace added, with a notice. Each virtual cluster is checked against every
+	// request for its host, so don't turn this on for thousands of Mappings.
+	VirtualCluster *bool `json:"virtual_cluster,omitempty"`
+
+	// HostRewriteFromSNI sets the upstream Host to the SNI that the client sent, for
+	// Mappings reached over TLS. Requests without SNI keep their Host. This can't be
+	// combined with HostRewrite or AutoHostRewrite.
+	HostRewriteFromSNI *bool `json:"host_rewrite_from_sni,omitempty"`
+
+	// DNSFailureRefreshRate is where Envoy starts backing off (with jitter) after a DNS
+	// lookup for this Mapping's service fails. Overrides `dns_failure_refresh_rate_ms` set
+	// on the Ambassador Module, if it exists. Only applies to DNS-resolved services.
+	DNSFailureRefreshRate *MillisecondDuration `json:"dns_failure_refresh_rate_ms,omitempty"`
+
+	// InitialFetchTimeout is how long Envoy waits for the first endpoints of this Mapping's
+	// service; 0 waits forever. Overrides `initial_fetch_timeout_ms` set on the Ambassador
+	// Module, if it exists. Only applies to services resolved using endpoints.
+	InitialFetchTimeout *MillisecondDuration `json:"initial_fetch_timeout_ms,omitempty"`
+
+	// HealthChecks are active health checks for this Mapping's service. Endpoints that fail
+	// them stop getting traffic until they pass again.
+	HealthChecks []HealthCheck `json:"health_checks,omitempty"`
+
+	// MeshMTLS originates mTLS to this Mapping's service using the certs of the given
+	// service mesh, unless the Mapping already originates TLS with `tls` or an explicit
+	// scheme. Overrides `mesh_mtls` set on the Ambassador Module, if it exists; use "none"
+	// for services outside the mesh.
+	//
+	// +kubebuilder:validation:Enum={"istio","consul","linkerd","none"}
+	MeshMTLS string `json:"mesh_mtls,omitempty"`
+
+	// MaxGRPCTimeout makes gRPC requests use the deadline from their `grpc-timeout` header
+	// instead of `timeout_ms`, clamped to this value; 0 means no clamp. gRPC requests
+	// without the header get this value as their timeout.
+	MaxGRPCTimeout *MillisecondDuration `json:"max_grpc_timeout_ms,omitempty"`
+
+	// GRPCTimeoutOffset is subtracted from the `grpc-timeout` header (when the header is
+	// longer than it), so that Envoy times the request out before the client gives up on it.
+	// It needs `max_grpc_timeout_ms`.
+	GRPCTimeoutOffset *MillisecondDuration `json:"grpc_timeout_offset_ms,omitempty"`
+
+	// DynamicForwardProxy sends requests to whatever host their Host header (or SNI) names,
+	// resolving it with the DNS cache configured by `dynamic_forward_proxy` on the Ambassador
+	// Module, instead of to a fixed service. The service must be "*" (or "https://*" to
+	// originate TLS to every host).
+	DynamicForwardProxy *DynamicForwardProxy `json:"dynamic_forward_proxy,omitempty"`
+
+	// SuppressEnvoyHeaders strips the debugging headers Envoy adds to responses (like
+	// `x-envoy-upstream-service-time`) for this Mapping. `suppress_envoy_headers` on the
+	// Ambassador Module suppresses them everywhere, and a Mapping can't turn them back on.
+	SuppressEnvoyHeaders *bool `json:"suppress_envoy_headers,omitempty"`
+
+	// DecompressorMaxRequestBytes overrides the Ambassador Module's
+	// `decompressor.max_request_bytes` for this Mapping: a request body that decompresses to
+	// more than this gets a 413. 0 turns the limit off for this Mapping. It needs the limit
+	// set on the Module.
+	// +kubebuilder:validation:Minimum=0
+	DecompressorMaxRequestBytes *int `json:"decompressor_max_request_bytes,omitempty"`
+
+	// ClusterProtocolOptions is added to the upstream cluster's Envoy
+	// `typed_extension_protocol_options`: each key is an extension name, and each value is
+	// that extension's config, with its `@type`. Options for
+	// `envoy.extensions.upstreams.http.v3.HttpProtocolOptions` are deep-merged over the
+	// HTTP options Ambassador generates, and win where they disagree. This is an escape
+	// hatch: Ambassador checks the shape, but Envoy checks the contents.
+	ClusterProtocolOptions *UntypedDict `json:"cluster_protocol_options,omitempty"`
+
+	// ClusterDrainTime is how long the upstream cluster stays in Envoy, with nothing routed
+	// to it, after the last Mapping using it goes away, so that requests already sent to it
+	// can finish. 0 removes it at once. Overrides `cluster_drain_time_ms` set on the
+	// Ambassador Module, if it exists.
+	ClusterDrainTime *MillisecondDuration `json:"cluster_drain_time_ms,omitempty"`
+
+	// HTTP2KeepAlive sends HTTP/2 PINGs on idle connections to this Mapping's service. It
+	// only applies to HTTP/2 (`grpc`) services, and has nothing to do with `keepalive`, which
+	// is TCP keepalive; both can be set. Overrides `http2_keepalive` set on the Ambassador
+	// Module, if it exists.
+	HTTP2KeepAlive *HTTP2KeepAlive `json:"http2_keepalive,omitempty"`
+
+	// RetryBufferLimitBytes is how much of each request body Envoy keeps so that it can
+	// resend it on a retry (or to a shadow). A request whose body outgrows it isn't
+	// retried: it gets one try, still bounded by `per_try_timeout`. Defaults to the
+	// listener's buffer limit (see `buffer_limit_bytes` on the Ambassador Module).
+	//
+	// +kubebuilder:validation:Minimum=0
+	RetryBufferLimitBytes *int `json:"retry_buffer_limit_bytes,omitempty"`
+
+	// PreserveSensitiveHeaders lists headers that `header_sanitization` on the Ambassador
+	// Module would strip, but that this Mapping's service needs (like `authorization` for
+	// a service that checks credentials itself).
+	PreserveSensitiveHeaders []string `json:"preserve_sensitive_headers,omitempty"`
+
+	// ContentTypes limits this Mapping to requests whose Content-Type is one of these
+	// (ignoring parameters like charset), so that Mappings on the same prefix can split
+	// traffic by what it carries. Each is `type/subtype`, `type/*`, or `*/*`, and a subtype
+	// may end in `*`: `application/grpc-web*` matches every grpc-web variant. The route is
+	// picked on the Content-Type the client sent, before `enable_grpc_web` rewrites it.
+	ContentTypes []string `json:"content_types,omitempty"`
+
+	// ResponseFlagStats gives this Mapping a virtual cluster (as with VirtualCluster) to
+	// count requests that end with these Envoy response flags. Envoy only counts `UT`
+	// (upstream_rq_timeout) and `URX` (upstream_rq_retry_limit_exceeded) per route; the
+	// rest are in the cluster's stats. `response_flag_stats_limit` on the Ambassador Module
+	// (default 100) caps how many Mappings can do this.
+	ResponseFlagStats []string `json:"response_flag_stats,omitempty"`
+
+	// Methods limits this Mapping to these HTTP methods; any other method on the same
+	// host, prefix, and headers gets a 405 with an Allow header, rather than falling
+	// through to a less specific Mapping. If the Mapping has a CORS policy (its own or the
+	// Ambassador Module's), OPTIONS is allowed too, so that preflights still get answered.
+	// Can't be combined with `method`.
+	Methods []string `json:"methods,omitempty"`
+
+	// BypassCompression keeps the Ambassador Module's gzip off this Mapping's responses,
+	// for content that's already compressed or streams (like server-sent events) that
+	// mustn't sit in the compressor's buffer. Envoy can't turn the compressor off per
+	// route, so this adds `no-transform` to the response's Cache-Control, which the
+	// compressor honors.
+	BypassCompression *bool `json:"bypass_compression,omitempty"`
+
+	// RequestTimeoutHeader says whether a request's `x-envoy-upstream-rq-timeout-ms` header
+	// can set its timeout on this Mapping, and how long it can ask for. Overrides
+	// `request_timeout_header` set on the Ambassador Module, if it exists.
+	RequestTimeoutHeader *RequestTimeoutHeader `json:"request_timeout_header,omitempty"`
+
+	// CookieAttributes has Envoy add Secure and/or SameSite to the Set-Cookie headers this
+	// Mapping's service sends back, for services that don't set them themselves. Overrides
+	// `cookie_attributes` set on the Ambassador Module, if it exists.
+	CookieAttributes *CookieAttributes `json:"cookie_attributes,omitempty"`
+
+	// WeightRuntimeKeyPrefix sends the traffic for this Mapping's group (every Mapping with
+	// the same prefix, headers, etc.) through a single route with weighted clusters, whose
+	// weights Envoy reads from the runtime keys `<prefix>.<cluster name>`. A key that isn't
+	// set takes the Mapping's `weight`, and one runtime update shifts every weight at once.
+	// The runtime weights must still add up to the group's total (100, unless the weights
+	// given add up to less). All the Mappings in the group have to use the same prefix, and
+	// agree on their other route settings (rewrites, timeouts, jwt, and so on); if they
+	// don't, it's an error, and each Mapping gets its own route as if this weren't set.
+	WeightRuntimeKeyPrefix string `json:"weight_runtime_key_prefix,omitempty"`
+
+	// BodyTransform rewrites the bodies of this Mapping's requests and/or responses from a
+	// template.
+	BodyTransform *BodyTransform `json:"body_transform,omitempty"`
+
+	// TypedPerFilterConfig is added to the Envoy `typed_per_filter_config` of this Mapping's
+	// routes: each key is an HTTP filter name, and each value is that filter's per-route
+	// config, with its `@type`. Where Ambassador already generates per-route config for the
+	// same filter and `@type`, this is deep-merged over it and wins where they disagree; with
+	// a different `@type`, it replaces Ambassador's. A filter name that isn't in the HTTP
+	// filter chain gets a notice. This is an escape hatch: Ambassador checks the shape, but
+	// Envoy checks the contents.
+	TypedPerFilterConfig *UntypedDict `json:"typed_per_filter_config,omitempty"`
+
+	// HealthRebalance has Ambassador shift a weighted group's traffic away from any of its
+	// clusters that has no healthy endpoints, sharing that cluster's weight out among the
+	// others in proportion to their own weights. If every cluster in the group is unhealthy,
+	// the weights stay as configured. All the Mappings in the group have to agree on it.
+	HealthRebalance *bool `json:"health_rebalance,omitempty"`
+
+	// HealthRebalanceRecoveryMs is how long a cluster that HealthRebalance has taken out of a
+	// group must stay healthy before it gets its weight back, so that a flapping cluster
+	// doesn't flap the traffic with it. The default is 30000 (30 seconds).
+	HealthRebalanceRecoveryMs *int `json:"health_rebalance_recovery_ms,omitempty"`
+
+	// HistogramBuckets sets the bucket upper bounds, in milliseconds and in increasing order,
+	// for the request and connection time histograms of this Mapping's cluster, in place of
+	// the ambassador Module's histogram_buckets (or Envoy's defaults). Every bucket is another
+	// time series for every histogram, so keep them few; there can be at most 64. Envoy reads
+	// these when it starts, so changes take effect on the next restart.
+	HistogramBuckets []int `json:"histogram_buckets,omitempty"`
+
+	// DisableHistograms turns off the request and connection time histograms of this
+	// Mapping's cluster altogether, for clusters whose latency nobody needs to see.
+	DisableHistograms *bool `json:"disable_histograms,omitempty"`
+
+	// CaseInsensitiveHeaders lists headers from `headers` and `regex_headers` whose values
+	// match regardless of case, for values where case means nothing (like an `x-tenant`
+	// that clients spell however they like). Header names always match regardless of
+	// case. Every other value is still matched exactly as written, which is what a
+	// case-sensitive value (like a token) needs.
+	CaseInsensitiveHeaders []string `json:"case_insensitive_headers,omitempty"`
+
+	// MaxRequestsPerConnection is how many requests Envoy sends over one connection to this
+	// Mapping's service before closing it; by default connections are reused without
+	// limit. 1 turns reuse off, for services that keep state per connection, at the cost
+	// of a new connection (and, with TLS, a new handshake) for every request. For gRPC
+	// services it counts streams on the HTTP/2 connection.
+	// +kubebuilder:validation:Minimum=1
+	MaxRequestsPerConnection *int `json:"max_requests_per_connection,omitempty"`
+
+	// ABHeader adds a header to a percentage of this Mapping's requests, so that the
+	// service can tell which side of an A/B test each one is on.
+	ABHeader *ABHeader `json:"ab_header,omitempty"`
+
+	// ResolverFallback names resolvers to try, in order, when the Mapping's own resolver has
+	// no healthy endpoints for its service (say, when Consul is down). Traffic goes back to
+	// the Mapping's resolver as soon as it has endpoints again. Without it, the ambassador
+	// Module's resolver_fallback applies.
+	ResolverFallback []string `json:"resolver_fallback,omitempty"`
+
+	// StatsDimensions give this Mapping a virtual cluster (as with VirtualCluster) for
+	// every combination of their values, so that its request stats can be broken down by
+	// method, tenant, and the like. That's one more set of stats for every combination, so
+	// `stats_dimensions_limit` on the Ambassador Module (default 50) caps how many
+	// combinations a Mapping can have.
+	StatsDimensions []StatsDimension `json:"stats_dimensions,omitempty"`
+
+	// Hedging races a slow try against another one, within a budget that keeps it from
+	// piling onto a struggling service. It needs a retry_policy with a per_try_timeout, and
+	// each hedged request counts against its num_retries. The budget replaces the
+	// circuit_breakers max_retries for the Mapping's cluster.
+	Hedging *Hedging `json:"hedging,omitempty"`
+
+	// ZeroReplicas is what to do instead of a plain 503 while the Mapping's service has no
+	// endpoints at all. It needs a resolver that watches endpoints (a KubernetesEndpointResolver
+	// or a ConsulResolver); through a Kubernetes Service, Envoy can't tell that there's nothing
+	// behind it. Without it, the ambassador Module's zero_replicas applies.
+	ZeroReplicas *ZeroReplicas `json:"zero_replicas,omitempty"`
+
+	// LogSampling thins out the access log lines for this Mapping's successful requests,
+	// keeping every error. It applies to every access log: the ambassador Module's, and each
+	// LogService's.
+	LogSampling *LogSampling `json:"log_sampling,omitempty"`
+
 	V2ExplicitTLS         *V2ExplicitTLS `json:"v2ExplicitTLS,omitempty"`
 	V2BoolHeaders         []string       `json:"v2BoolHeaders,omitempty"`
 	V2BoolQueryParameters []string       `json:"v2BoolQueryParameters,omitempty"`
 }
 
+// DynamicForwardProxy configures a Mapping that forwards to the host each request names.
+type DynamicForwardProxy struct {
+	// AllowedHosts are the hosts that requests may be forwarded to, as exact names or
+	// "*.example.com" for any subdomain; "*" allows every host. Requests for any other host
+	// don't match the Mapping. The list becomes a regex, so a long one may need the
+	// Ambassador Module's `regex_max_size` raised.
+	//
+	// +kubebuilder:validation:MinItems=1
+	AllowedHosts []string `json:"allowed_hosts"`
+}
+
+// HealthCheck is an active health check, which Envoy sends to every endpoint of a Mapping's
+// service on its own schedule, whether or not any traffic is being routed there.
+type HealthCheck struct {
+	// Timeout for each health check. Defaults to 3 seconds.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// Interval between health checks. Defaults to 5 seconds.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// IntervalJitter adds a random delay of up to this long to every interval, so that
+	// replicas that started together don't keep checking the same endpoints together.
+	IntervalJitter *metav1.Duration `json:"interval_jitter,omitempty"`
+	// IntervalJitterPercent adds a random delay of up to this percentage of the interval.
+	// It can be combined with IntervalJitter, in which case both are added.
+	//
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	IntervalJitterPercent int `json:"interval_jitter_percent,omitempty"`
+	// Number of failed checks before an endpoint is considered unhealthy. Defaults to 2.
+	UnhealthyThreshold int `json:"unhealthy_threshold,omitempty"`
+	// Number of successful checks before an endpoint is considered healthy again.
+	// Defaults to 1.
+	HealthyThreshold int `json:"healthy_threshold,omitempty"`
+
+	// What to check.
+	HealthCheckSpecifier HealthCheckSpecifier `json:"health_check"`
+}
+
+// HealthCheckSpecifier says what to check: exactly one of http or grpc must be given.
+//
+// +kubebuilder:validation:MaxProperties=1
+// +kubebuilder:validation:MinProperties=1
+type HealthCheckSpecifier struct {
+	HTTPHealthCheck *HTTPHealthCheck `json:"http,omitempty"`
+	GRPCHealthCheck *GRPCHealthCheck `json:"grpc,omitempty"`
+}
+
+// HTTPHealthCheck checks an endpoint with an HTTP GET.
+type HTTPHealthCheck struct {
+	// +kubebuilder:validation:Required
+	Path string `json:"path"`
+	// Hostname is the Host header for the check. Defaults to the name of the cluster.
+	Hostname string `json:"hostname,omitempty"`
+	// ExpectedStatuses are the status codes that count as healthy. Defaults to 200.
+	ExpectedStatuses []HealthCheckStatusRange `json:"expected_statuses,omitempty"`
+}
+
+// HealthCheckStatusRange is a range of HTTP status codes, including both ends.
+type HealthCheckStatusRange struct {
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=599
+	Min int `json:"min"`
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=599
+	Max int `json:"max"`
+}
+
+// GRPCHealthCheck checks an endpoint with the standard gRPC health checking protocol
+// (grpc.health.v1.Health/Check).
+type GRPCHealthCheck struct {
+	// ServiceName is the service to ask about. Defaults to the server as a whole.
+	ServiceName string `json:"service_name,omitempty"`
+	// Authority is the :authority header for the check. Defaults to the name of the cluster.
+	Authority string `json:"authority,omitempty"`
+}
+
+// RegexMap rewrites a path (for regex_rewrite and regex_redirect): every match of Pattern is
+// replaced with Substitution, where \1 through \9 are Pattern's capture groups (numbered by
+// where their opening parenthesis is) and \0 is the whole match. A path that doesn't match
+// is left alone.
 type RegexMap struct {
 	Pattern      string `json:"pattern,omitempty"`
 	Substitution string `json:"substitution,omitempty"`
@@ -279,6 +650,209 @@ type KeepAlive struct {
 	Interval *int `json:"interval,omitempty"`
 }
 
+// HTTP2KeepAlive has Envoy send HTTP/2 PING frames on idle upstream connections, so that
+// connections that a load balancer in the way has silently reaped get noticed and closed.
+type HTTP2KeepAlive struct {
+	// Interval between PINGs. Required.
+	Interval *MillisecondDuration `json:"interval_ms,omitempty"`
+	// Timeout is how long to wait for a PING to be answered before closing the connection.
+	// Required.
+	Timeout *MillisecondDuration `json:"timeout_ms,omitempty"`
+	// IntervalJitterPercent adds a random delay of up to this percentage of the interval.
+	// Envoy defaults to 15.
+	//
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	IntervalJitterPercent *int `json:"interval_jitter_percent,omitempty"`
+}
+
+// RequestTimeoutHeader says what to do with the `x-envoy-upstream-rq-timeout-ms` header, which
+// lets a request set its own upstream timeout. Envoy already drops it from requests that don't
+// come from an internal address (as long as `use_remote_address` is on), so it's only trusted
+// clients that can set it in the first place.
+type RequestTimeoutHeader struct {
+	// Honor the header. Defaults to true; false drops it from every request.
+	Honor *bool `json:"honor,omitempty"`
+	// Max is the longest timeout the header can ask for; anything longer (or 0, which would
+	// mean no timeout at all) gets this instead. Only valid if the header is honored.
+	Max *MillisecondDuration `json:"max_ms,omitempty"`
+}
+
+// ABHeader adds a marker header to a share of a Mapping's requests, for A/B testing without
+// a second backend. The requests that get it and the ones that don't go to the same service.
+type ABHeader struct {
+	// Name is the header to add.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// Value is what the header is set to.
+	// +kubebuilder:validation:Required
+	Value string `json:"value"`
+	// Percent is how many requests out of every 100 get the header. 0 adds it to none and
+	// 100 to all of them.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Percent int `json:"percent"`
+	// Cookie, if set, is a cookie that keeps each client on the side it was first put on:
+	// the first response sets it (for the rest of the browser session), and requests that
+	// already have it skip the draw.
+	Cookie string `json:"cookie,omitempty"`
+}
+
+// StatsDimension splits a Mapping's request stats by the value of a request header, as a
+// tag on its virtual cluster's stats. Envoy already tags those with the response code and
+// its class (envoy_response_code, envoy_response_code_class).
+type StatsDimension struct {
+	// Name is the tag the value shows up as. It must be lowercase letters, digits, and
+	// underscores, starting with a letter.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern="^[a-z][a-z0-9_]*$"
+	Name string `json:"name"`
+	// Header is the request header to take the value from; use `:method` for the method.
+	// +kubebuilder:validation:Required
+	Header string `json:"header"`
+	// Values are the values that get a tag value of their own. Requests without the
+	// header, or with any other value, are tagged `other`: Envoy can only count requests
+	// against values it knows about up front, which also keeps the number of time series
+	// bounded.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	Values []string `json:"values"`
+}
+
+// Hedging sends a try that runs out of its retry_policy per_try_timeout to another endpoint
+// as well, without cancelling it, and takes whichever answers first.
+type Hedging struct {
+	// BudgetPercent caps the extra requests -- hedged ones and retries alike, since Envoy
+	// counts hedges as retries -- at this percentage of the requests active on the
+	// Mapping's cluster. 0 means none at all, which turns hedging off.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	BudgetPercent int `json:"budget_percent"`
+	// MinConcurrency is how many extra requests are allowed regardless of the budget, so
+	// that a quiet cluster can still hedge. Defaults to 3.
+	// +kubebuilder:validation:Minimum=0
+	MinConcurrency *int `json:"min_concurrency,omitempty"`
+}
+
+// ZeroReplicas is what to do with a Mapping's requests while its service has no endpoints,
+// as when it has scaled to zero. Exactly one of Respond and Hold must be set.
+type ZeroReplicas struct {
+	// Respond answers the request with this response, as with `static_fallback`.
+	Respond *StaticFallback `json:"respond,omitempty"`
+	// Hold sends the request to another service that holds on to it until the Mapping's
+	// service is back, then passes it on.
+	Hold *ZeroReplicasHold `json:"hold,omitempty"`
+}
+
+// ZeroReplicasHold names the service that holds requests while a Mapping's service scales up
+// from zero: something like Knative's activator or the KEDA HTTP add-on's interceptor, which
+// starts the scale-up itself. It's found with the Mapping's resolver, and how long it holds is
+// up to it, bounded by the Mapping's timeout_ms.
+type ZeroReplicasHold struct {
+	// +kubebuilder:validation:Required
+	Service string `json:"service"`
+}
+
+// LogSampling logs only Percent of a Mapping's requests, except that a response with at least
+// AlwaysLogStatus, or one Envoy flagged (say, a long response that was cut off partway), is
+// always logged.
+type LogSampling struct {
+	// Percent is the percentage of the rest that are logged. They're sampled on the request
+	// ID, as traces are. The Envoy runtime key `log_sampling.<name>.<namespace>.percent`
+	// overrides it.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Percent int `json:"percent"`
+	// AlwaysLogStatus is the lowest status that's always logged. Defaults to 300, so that
+	// only 1xx and 2xx responses are sampled.
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=599
+	AlwaysLogStatus *int `json:"always_log_status,omitempty"`
+}
+
+// ShadowOnCircuitBreak makes a `shadow` Mapping mirror nothing until its runtime key says
+// otherwise. Envoy can't see a circuit breaker tripping from inside a route, so whatever
+// watches the primary cluster's circuit_breakers stats (an alert, an operator) sets the key
+// to the percentage to mirror while it's tripped, and back to 0 after.
+type ShadowOnCircuitBreak struct {
+	// RuntimeKey is the Envoy runtime key that turns mirroring on. Defaults to
+	// `shadow_on_circuit_break.<name>.<namespace>` for the shadow Mapping.
+	RuntimeKey string `json:"runtime_key,omitempty"`
+	// MaxRequests caps how many mirrored requests can be in flight to the shadow at once,
+	// so that mirroring during an outage can't pile onto it. Defaults to 10. Ignored if
+	// the shadow Mapping has circuit_breakers of its own.
+	//
+	// +kubebuilder:validation:Minimum=1
+	MaxRequests *int `json:"max_requests,omitempty"`
+}
+
+// CookieAttributes are attributes to add to every Set-Cookie header in a response that
+// doesn't already have them. A cookie that already says its SameSite keeps it.
+type CookieAttributes struct {
+	// Secure adds the Secure attribute.
+	Secure *bool `json:"secure,omitempty"`
+	// SameSite adds SameSite with this value. None is only allowed along with Secure,
+	// since browsers reject SameSite=None cookies that aren't Secure.
+	//
+	// +kubebuilder:validation:Enum={"Strict","Lax","None"}
+	SameSite string `json:"same_site,omitempty"`
+}
+
+// BodyTransform is a pair of templates for request and response bodies. In a template,
+// `{{ body }}` stands for the original body, exactly as it was (so wrapping a JSON body is
+// `{"data": {{ body }}}`), and everything else is literal text. Only bodies with a
+// Content-Length of at most MaxBytes and one of ContentTypes get transformed: streamed or
+// compressed bodies, bodies of other types, and empty bodies pass through untouched.
+type BodyTransform struct {
+	// Request is the template for the body sent upstream.
+	Request string `json:"request,omitempty"`
+	// Response is the template for the body sent back to the client.
+	Response string `json:"response,omitempty"`
+	// ContentTypes are the media types to transform; parameters like charset don't count.
+	// Defaults to application/json.
+	ContentTypes []string `json:"content_types,omitempty"`
+	// MaxBytes is the largest body to transform, since transforming a body means buffering
+	// all of it. Defaults to 65536, and can't be more than the Ambassador Module's
+	// `buffer_limit_bytes` (1MiB if that isn't set).
+	MaxBytes *int `json:"max_bytes,omitempty"`
+}
+
+// StaticFallback is a response for Envoy to send itself when a Mapping's cluster has no
+// healthy endpoints, instead of its usual 503. It takes over from `error_response_overrides`
+// for those requests, and applies even with `bypass_error_response_overrides`.
+type StaticFallback struct {
+	// StatusCode is the status of the response. Defaults to 503.
+	//
+	// +kubebuilder:validation:Minimum=200
+	// +kubebuilder:validation:Maximum=599
+	StatusCode *int `json:"status_code,omitempty"`
+	// Body is the body of the response, as is.
+	Body string `json:"body"`
+	// ContentType is the Content-Type of the response. Defaults to text/plain.
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// JWTRequirement is the JWT a Mapping's requests must carry: one from Provider, with every
+// one of Claims. A request without a valid JWT gets a 401, and one whose JWT doesn't have the
+// claims gets a 403.
+type JWTRequirement struct {
+	// Provider is the name of one of the Ambassador Module's `jwt_providers`. It can be left
+	// out if there's only one.
+	Provider string `json:"provider,omitempty"`
+	// Claims must all match for a request to get through.
+	Claims []JWTClaim `json:"claims,omitempty"`
+}
+
+// JWTClaim matches a JWT claim that is one of Values or, for an array claim, that has one of
+// Values in it. A JWT without the claim doesn't match.
+type JWTClaim struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
 type CORS struct {
 	Origins        []string `json:"origins,omitempty"`
 	Methods        []string `json:"methods,omitempty"`
@@ -295,6 +869,53 @@ type RetryPolicy struct {
 	RetryOn       string `json:"retry_on,omitempty"`
 	NumRetries    *int   `json:"num_retries,omitempty"`
 	PerTryTimeout string `json:"per_try_timeout,omitempty"`
+
+	// GRPCRetryOn lists the gRPC status codes that should be retried, e.g. "unavailable".
+	// These are combined with RetryOn. Note that Envoy doesn't know whether a gRPC method
+	// is idempotent, so only list codes that are safe to retry for every method behind
+	// this Mapping.
+	GRPCRetryOn []string `json:"grpc_retry_on,omitempty"`
+
+	// RetryBackOff overrides Envoy's default exponential backoff between retries (a
+	// 25ms base interval, capped at 10 times the base).
+	RetryBackOff *RetryBackOff `json:"retry_back_off,omitempty"`
+
+	// RetryOtherHosts makes each retry avoid the upstream hosts already tried for this
+	// request, when there are any others to pick. With a single host it changes nothing.
+	RetryOtherHosts *bool `json:"retry_other_hosts,omitempty"`
+
+	// HostSelectionRetryMaxAttempts is how many times Envoy will pick again when it lands on
+	// a host it has already tried, before settling for it anyway (Envoy's default is 1). It
+	// only means anything with RetryOtherHosts.
+	HostSelectionRetryMaxAttempts *int `json:"host_selection_retry_max_attempts,omitempty"`
+
+	// RetryOnReset also retries requests that the upstream resets, or never accepts a
+	// connection or stream for, before sending any response. (It can be used with or instead
+	// of RetryOn.) A reset can come after the upstream has already seen the request, so
+	// with RetryOnReset the policy only ever retries idempotent requests (GET, HEAD,
+	// OPTIONS, TRACE, PUT and DELETE), unless RetryNonIdempotent is set too.
+	RetryOnReset *bool `json:"retry_on_reset,omitempty"`
+
+	// RetryNonIdempotent lets RetryOnReset retry requests of any method, for upstreams
+	// where repeating a POST or PATCH is known to be harmless.
+	RetryNonIdempotent *bool `json:"retry_non_idempotent,omitempty"`
+
+	// RetryOnResponseHeaders retries a request when the upstream's response has any of these
+	// headers, with the given value, or with any value at all when the value is "". (It can
+	// be used with or instead of RetryOn.) Envoy picks the cluster before it sends the
+	// request, so the retry can only go to another host of the same cluster -- which it
+	// will, given RetryOtherHosts -- never to a different one. Only the response headers
+	// count, not its body or trailers, and a request body Envoy couldn't buffer whole can't
+	// be retried at all.
+	RetryOnResponseHeaders map[string]string `json:"retry_on_response_headers,omitempty"`
+}
+
+// RetryBackOff is the exponential backoff between retries. Envoy picks each delay at
+// random between zero and the current interval, which starts at the base interval and
+// doubles with every retry until it hits the max interval.
+type RetryBackOff struct {
+	BaseInterval *MillisecondDuration `json:"base_interval_ms,omitempty"`
+	MaxInterval  *MillisecondDuration `json:"max_interval_ms,omitempty"`
 }
 
 type LoadBalancer struct {
@@ -304,6 +925,19 @@ type LoadBalancer struct {
 	Cookie   *LoadBalancerCookie `json:"cookie,omitempty"`
 	Header   string              `json:"header,omitempty"`
 	SourceIp *bool               `json:"source_ip,omitempty"`
+
+	// ChoiceCount is how many random hosts policy least_request compares for each request,
+	// picking the one with the fewest active requests; Envoy's default is 2. More choices
+	// get closer to the least loaded host in a large pool, but look at more hosts for every
+	// request.
+	// +kubebuilder:validation:Minimum=2
+	ChoiceCount *int `json:"choice_count,omitempty"`
+
+	// Path has policy ring_hash or maglev hash on the request's path, so that each path (or
+	// path prefix) keeps going to the same endpoint; `path: {}` hashes on the whole path. The
+	// query string is never part of the hash. With maglev, an endpoint coming or going moves
+	// as few paths as it can to other endpoints.
+	Path *LoadBalancerPath `json:"path,omitempty"`
 }
 
 type LoadBalancerCookie struct {
@@ -313,6 +947,13 @@ type LoadBalancerCookie struct {
 	Ttl  string `json:"ttl,omitempty"`
 }
 
+type LoadBalancerPath struct {
+	// Segments, if set, hashes on only this many leading segments of the path, so that
+	// `segments: 1` sends everything under `/assets/` (say) to the same endpoint.
+	// +kubebuilder:validation:Minimum=1
+	Segments *int `json:"segments,omitempty"`
+}
+
 // MappingStatus defines the observed state of Mapping
 type MappingStatus struct {
 	// +kubebuilder:validation:Enum={"","Inactive","Running"}
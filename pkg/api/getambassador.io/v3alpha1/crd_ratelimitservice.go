@@ -37,6 +37,12 @@ type RateLimitServiceSpec struct {
 	ProtocolVersion string `json:"protocol_version,omitempty"`
 	StatsName       string `json:"stats_name,omitempty"`
 
+	// FailureModeDeny makes Envoy refuse requests (with a 500) when it can't get an answer
+	// from the RateLimitService, rather than letting them through. Envoy asks about all of a
+	// request's descriptors in a single call, so there's no partial failure: either the whole
+	// call succeeds, or this decides the request. Defaults to false (fail open).
+	FailureModeDeny *bool `json:"failure_mode_deny,omitempty"`
+
 	V2ExplicitTLS *V2ExplicitTLS `json:"v2ExplicitTLS,omitempty"`
 }
 
@@ -47,6 +47,12 @@ type TCPMappingSpec struct {
 	ClusterTag string `json:"cluster_tag,omitempty"`
 	StatsName  string `json:"stats_name,omitempty"`
 
+	// ALPNProtocols limits this TCPMapping to TLS connections whose client offers one of
+	// these protocols (e.g. "h2" or "http/1.1"), so that TCPMappings for the same host can
+	// send each protocol to a different service. A TCPMapping without it gets connections
+	// that offer none of the protocols the others ask for, or no ALPN at all.
+	ALPNProtocols []string `json:"alpn_protocols,omitempty"`
+
 	V2ExplicitTLS *V2ExplicitTLS `json:"v2ExplicitTLS,omitempty"`
 }
 
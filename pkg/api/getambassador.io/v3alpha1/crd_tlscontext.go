@@ -44,6 +44,38 @@ type TLSContextSpec struct {
 	SecretNamespacing     *bool    `json:"secret_namespacing,omitempty"`
 	RedirectCleartextFrom *int     `json:"redirect_cleartext_from,omitempty"`
 	SNI                   string   `json:"sni,omitempty"`
+
+	// InheritFrom names another TLSContext, in the same namespace, whose settings this one
+	// starts from. Anything set here overrides what's inherited; hosts, certificates, and
+	// redirect_cleartext_from are never inherited.
+	InheritFrom string `json:"inherit_from,omitempty"`
+
+	// SPIFFEIDs are the SPIFFE IDs (spiffe://trust-domain/path) a peer's certificate may
+	// carry as a URI SAN. A peer whose certificate has none of them is refused. A path
+	// segment of `*` matches any one segment, so spiffe://example.org/ns/*/sa/web takes
+	// that service account from any namespace. This needs ca_secret or cacert_chain_file,
+	// since a SAN means nothing from a certificate nobody checked.
+	SPIFFEIDs []string `json:"spiffe_ids,omitempty"`
+
+	// SPIFFETrustDomain limits peers to SPIFFE IDs in this trust domain (example.org, not
+	// spiffe://example.org). On its own, it accepts any ID in the trust domain; with
+	// SPIFFEIDs, they all have to be in it.
+	SPIFFETrustDomain string `json:"spiffe_trust_domain,omitempty"`
+
+	// AdditionalSecrets are more kubernetes.io/tls Secrets whose certificates are served
+	// alongside Secret's, so that one listener can offer (say) both an ECDSA and an RSA
+	// certificate, and each client's handshake picks one it can use. Envoy takes at most one
+	// certificate of each key type. Any of these Secrets, or Secret itself, may carry a
+	// DER-encoded OCSP response in `tls.ocsp-staple` to staple to its certificate.
+	AdditionalSecrets []string `json:"additional_secrets,omitempty"`
+
+	// OCSPStaplePolicy says what to do with a certificate whose OCSP response is missing or
+	// expired: lenient_stapling (the default) serves it without a staple, strict_stapling
+	// refuses it once its response has expired, and must_staple refuses it without a valid
+	// response.
+	//
+	// +kubebuilder:validation:Enum={"lenient_stapling","strict_stapling","must_staple"}
+	OCSPStaplePolicy string `json:"ocsp_staple_policy,omitempty"`
 }
 
 // TLSContext is the Schema for the tlscontexts API
@@ -41,11 +41,51 @@ type TraceConfig struct {
 	ServiceName              string `json:"service_name,omitempty"`
 }
 
+// TraceCustomTag is a tag to put on every span, with its value from exactly one of Literal,
+// RequestHeader, Environment, or Metadata.
+type TraceCustomTag struct {
+	// +kubebuilder:validation:Required
+	Tag           string              `json:"tag,omitempty"`
+	Literal       *TraceLiteralTag    `json:"literal,omitempty"`
+	RequestHeader *TraceValueFromName `json:"request_header,omitempty"`
+	Environment   *TraceValueFromName `json:"environment,omitempty"`
+	Metadata      *TraceMetadataTag   `json:"metadata,omitempty"`
+}
+
+type TraceLiteralTag struct {
+	// +kubebuilder:validation:Required
+	Value string `json:"value,omitempty"`
+}
+
+// TraceValueFromName takes a tag's value from the request header, or the environment variable,
+// with the given name. Without a DefaultValue, a span whose request doesn't have the header
+// (or an Ambassador without the variable) doesn't get the tag at all.
+type TraceValueFromName struct {
+	// +kubebuilder:validation:Required
+	Name         string `json:"name,omitempty"`
+	DefaultValue string `json:"default_value,omitempty"`
+}
+
+// TraceMetadataTag takes a tag's value from the metadata of the request itself, or of the
+// route, cluster, or endpoint that it went to: Key is the metadata namespace (like
+// "envoy.lb"), and Path the keys to follow within it, of which there must be at least one.
+// Without a DefaultValue, a span without that metadata doesn't get the tag.
+type TraceMetadataTag struct {
+	// +kubebuilder:validation:Enum={"request","route","cluster","host"}
+	// +kubebuilder:validation:Required
+	Kind string `json:"kind,omitempty"`
+	// +kubebuilder:validation:Required
+	Key string `json:"key,omitempty"`
+	// +kubebuilder:validation:MinItems=1
+	Path         []string `json:"path,omitempty"`
+	DefaultValue string   `json:"default_value,omitempty"`
+}
+
 // TracingServiceSpec defines the desired state of TracingService
 type TracingServiceSpec struct {
 	AmbassadorID AmbassadorID `json:"ambassador_id,omitempty"`
 
-	// +kubebuilder:validation:Enum={"lightstep","zipkin","datadog"}
+	// +kubebuilder:validation:Enum={"lightstep","zipkin","datadog","opencensus"}
 	// +kubebuilder:validation:Required
 	Driver string `json:"driver,omitempty"`
 	// +kubebuilder:validation:Required
@@ -54,6 +94,24 @@ type TracingServiceSpec struct {
 	TagHeaders []string       `json:"tag_headers,omitempty"`
 	Config     *TraceConfig   `json:"config,omitempty"`
 	StatsName  string         `json:"stats_name,omitempty"`
+
+	// Propagation is which trace context headers are accepted from clients and sent on to
+	// upstreams: "b3" (x-b3-* and b3), "w3c" (traceparent), or "both". The zipkin driver only
+	// speaks b3; the opencensus driver speaks either, and defaults to both, so that a backend
+	// that only understands b3 still gets a context it can join. The lightstep and datadog
+	// drivers always use their own headers, and don't take this.
+	// +kubebuilder:validation:Enum={"b3","w3c","both"}
+	Propagation string `json:"propagation,omitempty"`
+
+	// TrustIncomingTraceContext, if false, makes the opencensus driver start a new trace for
+	// every request instead of joining whatever trace the client claims to be in, and strips
+	// any trace headers the tracer won't be overwriting before the request goes upstream.
+	// Defaults to true.
+	TrustIncomingTraceContext *bool `json:"trust_incoming_trace_context,omitempty"`
+
+	// CustomTags are tags to put on every span, alongside the ones TagHeaders makes. A
+	// custom tag with the same name as one of the TagHeaders replaces it.
+	CustomTags []TraceCustomTag `json:"custom_tags,omitempty"`
 }
 
 // TracingService is the Schema for the tracingservices API
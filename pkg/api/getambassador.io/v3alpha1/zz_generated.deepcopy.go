@@ -26,6 +26,21 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ABHeader) DeepCopyInto(out *ABHeader) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ABHeader.
+func (in *ABHeader) DeepCopy() *ABHeader {
+	if in == nil {
+		return nil
+	}
+	out := new(ABHeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ACMEProviderSpec) DeepCopyInto(out *ACMEProviderSpec) {
 	*out = *in
@@ -373,6 +388,31 @@ func (in *AuthServiceStatusOnError) DeepCopy() *AuthServiceStatusOnError {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BodyTransform) DeepCopyInto(out *BodyTransform) {
+	*out = *in
+	if in.ContentTypes != nil {
+		in, out := &in.ContentTypes, &out.ContentTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxBytes != nil {
+		in, out := &in.MaxBytes, &out.MaxBytes
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BodyTransform.
+func (in *BodyTransform) DeepCopy() *BodyTransform {
+	if in == nil {
+		return nil
+	}
+	out := new(BodyTransform)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CORS) DeepCopyInto(out *CORS) {
 	*out = *in
@@ -436,6 +476,11 @@ func (in *CircuitBreaker) DeepCopyInto(out *CircuitBreaker) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.MaxConnectionPools != nil {
+		in, out := &in.MaxConnectionPools, &out.MaxConnectionPools
+		*out = new(int)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CircuitBreaker.
@@ -448,6 +493,26 @@ func (in *CircuitBreaker) DeepCopy() *CircuitBreaker {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectionRateLimit) DeepCopyInto(out *ConnectionRateLimit) {
+	*out = *in
+	if in.Burst != nil {
+		in, out := &in.Burst, &out.Burst
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectionRateLimit.
+func (in *ConnectionRateLimit) DeepCopy() *ConnectionRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectionRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConsulResolver) DeepCopyInto(out *ConsulResolver) {
 	*out = *in
@@ -526,6 +591,26 @@ func (in *ConsulResolverSpec) DeepCopy() *ConsulResolverSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CookieAttributes) DeepCopyInto(out *CookieAttributes) {
+	*out = *in
+	if in.Secure != nil {
+		in, out := &in.Secure, &out.Secure
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CookieAttributes.
+func (in *CookieAttributes) DeepCopy() *CookieAttributes {
+	if in == nil {
+		return nil
+	}
+	out := new(CookieAttributes)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DevPortal) DeepCopyInto(out *DevPortal) {
 	*out = *in
@@ -820,6 +905,26 @@ func (in *DriverConfig) DeepCopy() *DriverConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DynamicForwardProxy) DeepCopyInto(out *DynamicForwardProxy) {
+	*out = *in
+	if in.AllowedHosts != nil {
+		in, out := &in.AllowedHosts, &out.AllowedHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DynamicForwardProxy.
+func (in *DynamicForwardProxy) DeepCopy() *DynamicForwardProxy {
+	if in == nil {
+		return nil
+	}
+	out := new(DynamicForwardProxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ErrorResponseOverride) DeepCopyInto(out *ErrorResponseOverride) {
 	*out = *in
@@ -893,6 +998,192 @@ func (in *Features) DeepCopy() *Features {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GRPCHealthCheck) DeepCopyInto(out *GRPCHealthCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPCHealthCheck.
+func (in *GRPCHealthCheck) DeepCopy() *GRPCHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(GRPCHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HSTSConfig) DeepCopyInto(out *HSTSConfig) {
+	*out = *in
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(int)
+		**out = **in
+	}
+	if in.IncludeSubDomains != nil {
+		in, out := &in.IncludeSubDomains, &out.IncludeSubDomains
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Preload != nil {
+		in, out := &in.Preload, &out.Preload
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HSTSConfig.
+func (in *HSTSConfig) DeepCopy() *HSTSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(HSTSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTP2KeepAlive) DeepCopyInto(out *HTTP2KeepAlive) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.IntervalJitterPercent != nil {
+		in, out := &in.IntervalJitterPercent, &out.IntervalJitterPercent
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTP2KeepAlive.
+func (in *HTTP2KeepAlive) DeepCopy() *HTTP2KeepAlive {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTP2KeepAlive)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPHealthCheck) DeepCopyInto(out *HTTPHealthCheck) {
+	*out = *in
+	if in.ExpectedStatuses != nil {
+		in, out := &in.ExpectedStatuses, &out.ExpectedStatuses
+		*out = make([]HealthCheckStatusRange, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPHealthCheck.
+func (in *HTTPHealthCheck) DeepCopy() *HTTPHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheck) DeepCopyInto(out *HealthCheck) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.IntervalJitter != nil {
+		in, out := &in.IntervalJitter, &out.IntervalJitter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	in.HealthCheckSpecifier.DeepCopyInto(&out.HealthCheckSpecifier)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheck.
+func (in *HealthCheck) DeepCopy() *HealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheckSpecifier) DeepCopyInto(out *HealthCheckSpecifier) {
+	*out = *in
+	if in.HTTPHealthCheck != nil {
+		in, out := &in.HTTPHealthCheck, &out.HTTPHealthCheck
+		*out = new(HTTPHealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GRPCHealthCheck != nil {
+		in, out := &in.GRPCHealthCheck, &out.GRPCHealthCheck
+		*out = new(GRPCHealthCheck)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheckSpecifier.
+func (in *HealthCheckSpecifier) DeepCopy() *HealthCheckSpecifier {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheckSpecifier)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheckStatusRange) DeepCopyInto(out *HealthCheckStatusRange) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheckStatusRange.
+func (in *HealthCheckStatusRange) DeepCopy() *HealthCheckStatusRange {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheckStatusRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Hedging) DeepCopyInto(out *Hedging) {
+	*out = *in
+	if in.MinConcurrency != nil {
+		in, out := &in.MinConcurrency, &out.MinConcurrency
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Hedging.
+func (in *Hedging) DeepCopy() *Hedging {
+	if in == nil {
+		return nil
+	}
+	out := new(Hedging)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Host) DeepCopyInto(out *Host) {
 	*out = *in
@@ -1025,6 +1316,11 @@ func (in *HostSpec) DeepCopyInto(out *HostSpec) {
 		*out = new(TLSConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.HSTS != nil {
+		in, out := &in.HSTS, &out.HSTS
+		*out = new(HSTSConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostSpec.
@@ -1081,6 +1377,48 @@ func (in *InsecureRequestPolicy) DeepCopy() *InsecureRequestPolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JWTClaim) DeepCopyInto(out *JWTClaim) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JWTClaim.
+func (in *JWTClaim) DeepCopy() *JWTClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(JWTClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JWTRequirement) DeepCopyInto(out *JWTRequirement) {
+	*out = *in
+	if in.Claims != nil {
+		in, out := &in.Claims, &out.Claims
+		*out = make([]JWTClaim, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JWTRequirement.
+func (in *JWTRequirement) DeepCopy() *JWTRequirement {
+	if in == nil {
+		return nil
+	}
+	out := new(JWTRequirement)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KeepAlive) DeepCopyInto(out *KeepAlive) {
 	*out = *in
@@ -1342,6 +1680,26 @@ func (in *ListenerSpec) DeepCopyInto(out *ListenerSpec) {
 		*out = make([]ProtocolStackElement, len(*in))
 		copy(*out, *in)
 	}
+	if in.KeepAlive != nil {
+		in, out := &in.KeepAlive, &out.KeepAlive
+		*out = new(KeepAlive)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RequestHeadersTimeout != nil {
+		in, out := &in.RequestHeadersTimeout, &out.RequestHeadersTimeout
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.UploadBuffering != nil {
+		in, out := &in.UploadBuffering, &out.UploadBuffering
+		*out = new(UploadBuffering)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConnectionRateLimit != nil {
+		in, out := &in.ConnectionRateLimit, &out.ConnectionRateLimit
+		*out = new(ConnectionRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
 	in.HostBinding.DeepCopyInto(&out.HostBinding)
 }
 
@@ -1368,6 +1726,16 @@ func (in *LoadBalancer) DeepCopyInto(out *LoadBalancer) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.ChoiceCount != nil {
+		in, out := &in.ChoiceCount, &out.ChoiceCount
+		*out = new(int)
+		**out = **in
+	}
+	if in.Path != nil {
+		in, out := &in.Path, &out.Path
+		*out = new(LoadBalancerPath)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancer.
@@ -1396,9 +1764,49 @@ func (in *LoadBalancerCookie) DeepCopy() *LoadBalancerCookie {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *LogService) DeepCopyInto(out *LogService) {
+func (in *LoadBalancerPath) DeepCopyInto(out *LoadBalancerPath) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
+	if in.Segments != nil {
+		in, out := &in.Segments, &out.Segments
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerPath.
+func (in *LoadBalancerPath) DeepCopy() *LoadBalancerPath {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadBalancerPath)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogSampling) DeepCopyInto(out *LogSampling) {
+	*out = *in
+	if in.AlwaysLogStatus != nil {
+		in, out := &in.AlwaysLogStatus, &out.AlwaysLogStatus
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogSampling.
+func (in *LogSampling) DeepCopy() *LogSampling {
+	if in == nil {
+		return nil
+	}
+	out := new(LogSampling)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogService) DeepCopyInto(out *LogService) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
 }
@@ -2060,6 +2468,220 @@ func (in *MappingSpec) DeepCopyInto(out *MappingSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.ShadowOnCircuitBreak != nil {
+		in, out := &in.ShadowOnCircuitBreak, &out.ShadowOnCircuitBreak
+		*out = new(ShadowOnCircuitBreak)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ShadowCloneCluster != nil {
+		in, out := &in.ShadowCloneCluster, &out.ShadowCloneCluster
+		*out = new(bool)
+		**out = **in
+	}
+	if in.EchoRequestID != nil {
+		in, out := &in.EchoRequestID, &out.EchoRequestID
+		*out = new(bool)
+		**out = **in
+	}
+	if in.AutoSNI != nil {
+		in, out := &in.AutoSNI, &out.AutoSNI
+		*out = new(bool)
+		**out = **in
+	}
+	if in.AutoHTTPProtocol != nil {
+		in, out := &in.AutoHTTPProtocol, &out.AutoHTTPProtocol
+		*out = new(bool)
+		**out = **in
+	}
+	if in.JWT != nil {
+		in, out := &in.JWT, &out.JWT
+		*out = new(JWTRequirement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StaticFallback != nil {
+		in, out := &in.StaticFallback, &out.StaticFallback
+		*out = new(StaticFallback)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VirtualCluster != nil {
+		in, out := &in.VirtualCluster, &out.VirtualCluster
+		*out = new(bool)
+		**out = **in
+	}
+	if in.HostRewriteFromSNI != nil {
+		in, out := &in.HostRewriteFromSNI, &out.HostRewriteFromSNI
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DNSFailureRefreshRate != nil {
+		in, out := &in.DNSFailureRefreshRate, &out.DNSFailureRefreshRate
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.InitialFetchTimeout != nil {
+		in, out := &in.InitialFetchTimeout, &out.InitialFetchTimeout
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.HealthChecks != nil {
+		in, out := &in.HealthChecks, &out.HealthChecks
+		*out = make([]HealthCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MaxGRPCTimeout != nil {
+		in, out := &in.MaxGRPCTimeout, &out.MaxGRPCTimeout
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.GRPCTimeoutOffset != nil {
+		in, out := &in.GRPCTimeoutOffset, &out.GRPCTimeoutOffset
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.DynamicForwardProxy != nil {
+		in, out := &in.DynamicForwardProxy, &out.DynamicForwardProxy
+		*out = new(DynamicForwardProxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SuppressEnvoyHeaders != nil {
+		in, out := &in.SuppressEnvoyHeaders, &out.SuppressEnvoyHeaders
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DecompressorMaxRequestBytes != nil {
+		in, out := &in.DecompressorMaxRequestBytes, &out.DecompressorMaxRequestBytes
+		*out = new(int)
+		**out = **in
+	}
+	if in.ClusterProtocolOptions != nil {
+		in, out := &in.ClusterProtocolOptions, &out.ClusterProtocolOptions
+		*out = new(UntypedDict)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterDrainTime != nil {
+		in, out := &in.ClusterDrainTime, &out.ClusterDrainTime
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.HTTP2KeepAlive != nil {
+		in, out := &in.HTTP2KeepAlive, &out.HTTP2KeepAlive
+		*out = new(HTTP2KeepAlive)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RetryBufferLimitBytes != nil {
+		in, out := &in.RetryBufferLimitBytes, &out.RetryBufferLimitBytes
+		*out = new(int)
+		**out = **in
+	}
+	if in.PreserveSensitiveHeaders != nil {
+		in, out := &in.PreserveSensitiveHeaders, &out.PreserveSensitiveHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ContentTypes != nil {
+		in, out := &in.ContentTypes, &out.ContentTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ResponseFlagStats != nil {
+		in, out := &in.ResponseFlagStats, &out.ResponseFlagStats
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Methods != nil {
+		in, out := &in.Methods, &out.Methods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.BypassCompression != nil {
+		in, out := &in.BypassCompression, &out.BypassCompression
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RequestTimeoutHeader != nil {
+		in, out := &in.RequestTimeoutHeader, &out.RequestTimeoutHeader
+		*out = new(RequestTimeoutHeader)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CookieAttributes != nil {
+		in, out := &in.CookieAttributes, &out.CookieAttributes
+		*out = new(CookieAttributes)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BodyTransform != nil {
+		in, out := &in.BodyTransform, &out.BodyTransform
+		*out = new(BodyTransform)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TypedPerFilterConfig != nil {
+		in, out := &in.TypedPerFilterConfig, &out.TypedPerFilterConfig
+		*out = new(UntypedDict)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HealthRebalance != nil {
+		in, out := &in.HealthRebalance, &out.HealthRebalance
+		*out = new(bool)
+		**out = **in
+	}
+	if in.HealthRebalanceRecoveryMs != nil {
+		in, out := &in.HealthRebalanceRecoveryMs, &out.HealthRebalanceRecoveryMs
+		*out = new(int)
+		**out = **in
+	}
+	if in.HistogramBuckets != nil {
+		in, out := &in.HistogramBuckets, &out.HistogramBuckets
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.DisableHistograms != nil {
+		in, out := &in.DisableHistograms, &out.DisableHistograms
+		*out = new(bool)
+		**out = **in
+	}
+	if in.CaseInsensitiveHeaders != nil {
+		in, out := &in.CaseInsensitiveHeaders, &out.CaseInsensitiveHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxRequestsPerConnection != nil {
+		in, out := &in.MaxRequestsPerConnection, &out.MaxRequestsPerConnection
+		*out = new(int)
+		**out = **in
+	}
+	if in.ABHeader != nil {
+		in, out := &in.ABHeader, &out.ABHeader
+		*out = new(ABHeader)
+		**out = **in
+	}
+	if in.ResolverFallback != nil {
+		in, out := &in.ResolverFallback, &out.ResolverFallback
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StatsDimensions != nil {
+		in, out := &in.StatsDimensions, &out.StatsDimensions
+		*out = make([]StatsDimension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Hedging != nil {
+		in, out := &in.Hedging, &out.Hedging
+		*out = new(Hedging)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ZeroReplicas != nil {
+		in, out := &in.ZeroReplicas, &out.ZeroReplicas
+		*out = new(ZeroReplicas)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LogSampling != nil {
+		in, out := &in.LogSampling, &out.LogSampling
+		*out = new(LogSampling)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.V2ExplicitTLS != nil {
 		in, out := &in.V2ExplicitTLS, &out.V2ExplicitTLS
 		*out = new(V2ExplicitTLS)
@@ -2302,6 +2924,11 @@ func (in *RateLimitServiceSpec) DeepCopyInto(out *RateLimitServiceSpec) {
 		*out = new(MillisecondDuration)
 		**out = **in
 	}
+	if in.FailureModeDeny != nil {
+		in, out := &in.FailureModeDeny, &out.FailureModeDeny
+		*out = new(bool)
+		**out = **in
+	}
 	if in.V2ExplicitTLS != nil {
 		in, out := &in.V2ExplicitTLS, &out.V2ExplicitTLS
 		*out = new(V2ExplicitTLS)
@@ -2350,6 +2977,56 @@ func (in *RequestPolicy) DeepCopy() *RequestPolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RequestTimeoutHeader) DeepCopyInto(out *RequestTimeoutHeader) {
+	*out = *in
+	if in.Honor != nil {
+		in, out := &in.Honor, &out.Honor
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Max != nil {
+		in, out := &in.Max, &out.Max
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RequestTimeoutHeader.
+func (in *RequestTimeoutHeader) DeepCopy() *RequestTimeoutHeader {
+	if in == nil {
+		return nil
+	}
+	out := new(RequestTimeoutHeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryBackOff) DeepCopyInto(out *RetryBackOff) {
+	*out = *in
+	if in.BaseInterval != nil {
+		in, out := &in.BaseInterval, &out.BaseInterval
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+	if in.MaxInterval != nil {
+		in, out := &in.MaxInterval, &out.MaxInterval
+		*out = new(MillisecondDuration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryBackOff.
+func (in *RetryBackOff) DeepCopy() *RetryBackOff {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryBackOff)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
 	*out = *in
@@ -2358,6 +3035,43 @@ func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.GRPCRetryOn != nil {
+		in, out := &in.GRPCRetryOn, &out.GRPCRetryOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RetryBackOff != nil {
+		in, out := &in.RetryBackOff, &out.RetryBackOff
+		*out = new(RetryBackOff)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RetryOtherHosts != nil {
+		in, out := &in.RetryOtherHosts, &out.RetryOtherHosts
+		*out = new(bool)
+		**out = **in
+	}
+	if in.HostSelectionRetryMaxAttempts != nil {
+		in, out := &in.HostSelectionRetryMaxAttempts, &out.HostSelectionRetryMaxAttempts
+		*out = new(int)
+		**out = **in
+	}
+	if in.RetryOnReset != nil {
+		in, out := &in.RetryOnReset, &out.RetryOnReset
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RetryNonIdempotent != nil {
+		in, out := &in.RetryNonIdempotent, &out.RetryNonIdempotent
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RetryOnResponseHeaders != nil {
+		in, out := &in.RetryOnResponseHeaders, &out.RetryOnResponseHeaders
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
@@ -2370,6 +3084,66 @@ func (in *RetryPolicy) DeepCopy() *RetryPolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShadowOnCircuitBreak) DeepCopyInto(out *ShadowOnCircuitBreak) {
+	*out = *in
+	if in.MaxRequests != nil {
+		in, out := &in.MaxRequests, &out.MaxRequests
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShadowOnCircuitBreak.
+func (in *ShadowOnCircuitBreak) DeepCopy() *ShadowOnCircuitBreak {
+	if in == nil {
+		return nil
+	}
+	out := new(ShadowOnCircuitBreak)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticFallback) DeepCopyInto(out *StaticFallback) {
+	*out = *in
+	if in.StatusCode != nil {
+		in, out := &in.StatusCode, &out.StatusCode
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticFallback.
+func (in *StaticFallback) DeepCopy() *StaticFallback {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticFallback)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StatsDimension) DeepCopyInto(out *StatsDimension) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatsDimension.
+func (in *StatsDimension) DeepCopy() *StatsDimension {
+	if in == nil {
+		return nil
+	}
+	out := new(StatsDimension)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TCPMapping) DeepCopyInto(out *TCPMapping) {
 	*out = *in
@@ -2458,6 +3232,11 @@ func (in *TCPMappingSpec) DeepCopyInto(out *TCPMappingSpec) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.ALPNProtocols != nil {
+		in, out := &in.ALPNProtocols, &out.ALPNProtocols
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.V2ExplicitTLS != nil {
 		in, out := &in.V2ExplicitTLS, &out.V2ExplicitTLS
 		*out = new(V2ExplicitTLS)
@@ -2606,6 +3385,16 @@ func (in *TLSContextSpec) DeepCopyInto(out *TLSContextSpec) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.SPIFFEIDs != nil {
+		in, out := &in.SPIFFEIDs, &out.SPIFFEIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AdditionalSecrets != nil {
+		in, out := &in.AdditionalSecrets, &out.AdditionalSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSContextSpec.
@@ -2643,6 +3432,76 @@ func (in *TraceConfig) DeepCopy() *TraceConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TraceCustomTag) DeepCopyInto(out *TraceCustomTag) {
+	*out = *in
+	if in.Literal != nil {
+		in, out := &in.Literal, &out.Literal
+		*out = new(TraceLiteralTag)
+		**out = **in
+	}
+	if in.RequestHeader != nil {
+		in, out := &in.RequestHeader, &out.RequestHeader
+		*out = new(TraceValueFromName)
+		**out = **in
+	}
+	if in.Environment != nil {
+		in, out := &in.Environment, &out.Environment
+		*out = new(TraceValueFromName)
+		**out = **in
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(TraceMetadataTag)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TraceCustomTag.
+func (in *TraceCustomTag) DeepCopy() *TraceCustomTag {
+	if in == nil {
+		return nil
+	}
+	out := new(TraceCustomTag)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TraceLiteralTag) DeepCopyInto(out *TraceLiteralTag) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TraceLiteralTag.
+func (in *TraceLiteralTag) DeepCopy() *TraceLiteralTag {
+	if in == nil {
+		return nil
+	}
+	out := new(TraceLiteralTag)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TraceMetadataTag) DeepCopyInto(out *TraceMetadataTag) {
+	*out = *in
+	if in.Path != nil {
+		in, out := &in.Path, &out.Path
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TraceMetadataTag.
+func (in *TraceMetadataTag) DeepCopy() *TraceMetadataTag {
+	if in == nil {
+		return nil
+	}
+	out := new(TraceMetadataTag)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TraceSampling) DeepCopyInto(out *TraceSampling) {
 	*out = *in
@@ -2673,6 +3532,21 @@ func (in *TraceSampling) DeepCopy() *TraceSampling {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TraceValueFromName) DeepCopyInto(out *TraceValueFromName) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TraceValueFromName.
+func (in *TraceValueFromName) DeepCopy() *TraceValueFromName {
+	if in == nil {
+		return nil
+	}
+	out := new(TraceValueFromName)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TracingService) DeepCopyInto(out *TracingService) {
 	*out = *in
@@ -2754,6 +3628,18 @@ func (in *TracingServiceSpec) DeepCopyInto(out *TracingServiceSpec) {
 		*out = new(TraceConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.TrustIncomingTraceContext != nil {
+		in, out := &in.TrustIncomingTraceContext, &out.TrustIncomingTraceContext
+		*out = new(bool)
+		**out = **in
+	}
+	if in.CustomTags != nil {
+		in, out := &in.CustomTags, &out.CustomTags
+		*out = make([]TraceCustomTag, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracingServiceSpec.
@@ -2796,6 +3682,31 @@ func (in *UntypedDict) DeepCopy() *UntypedDict {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UploadBuffering) DeepCopyInto(out *UploadBuffering) {
+	*out = *in
+	if in.ConnectionBufferBytes != nil {
+		in, out := &in.ConnectionBufferBytes, &out.ConnectionBufferBytes
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Stream != nil {
+		in, out := &in.Stream, &out.Stream
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UploadBuffering.
+func (in *UploadBuffering) DeepCopy() *UploadBuffering {
+	if in == nil {
+		return nil
+	}
+	out := new(UploadBuffering)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *V2ExplicitTLS) DeepCopyInto(out *V2ExplicitTLS) {
 	*out = *in
@@ -2815,3 +3726,43 @@ func (in *V2ExplicitTLS) DeepCopy() *V2ExplicitTLS {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeroReplicas) DeepCopyInto(out *ZeroReplicas) {
+	*out = *in
+	if in.Respond != nil {
+		in, out := &in.Respond, &out.Respond
+		*out = new(StaticFallback)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hold != nil {
+		in, out := &in.Hold, &out.Hold
+		*out = new(ZeroReplicasHold)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeroReplicas.
+func (in *ZeroReplicas) DeepCopy() *ZeroReplicas {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeroReplicas)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeroReplicasHold) DeepCopyInto(out *ZeroReplicasHold) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeroReplicasHold.
+func (in *ZeroReplicasHold) DeepCopy() *ZeroReplicasHold {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeroReplicasHold)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -2,7 +2,9 @@ package kates
 
 import (
 	"context"
+	"fmt"
 	"path"
+	"sort"
 	"strings"
 	"sync"
 
@@ -22,6 +24,7 @@ type Validator struct {
 	static map[TypeMeta]*apiextVInternal.CustomResourceDefinition
 
 	mutex      sync.Mutex
+	schemas    map[TypeMeta]*apiextVInternal.CustomResourceValidation
 	validators map[TypeMeta]*validate.SchemaValidator
 }
 
@@ -71,6 +74,7 @@ func NewValidator(client *Client, staticCRDs []Object) (*Validator, error) {
 		client: client,
 		static: static,
 
+		schemas:    make(map[TypeMeta]*apiextVInternal.CustomResourceValidation),
 		validators: make(map[TypeMeta]*validate.SchemaValidator),
 	}, nil
 }
@@ -113,11 +117,10 @@ func (v *Validator) getCRD(ctx context.Context, tm TypeMeta) (*apiextVInternal.C
 	return nil, nil
 }
 
-func (v *Validator) getValidator(ctx context.Context, tm TypeMeta) (*validate.SchemaValidator, error) {
-	v.mutex.Lock()
-	defer v.mutex.Unlock()
-
-	validator, ok := v.validators[tm]
+// getSchema returns the schema for the given type, or nil if it isn't a CRD or its CRD doesn't
+// have one. The caller must hold v.mutex.
+func (v *Validator) getSchema(ctx context.Context, tm TypeMeta) (*apiextVInternal.CustomResourceValidation, error) {
+	schema, ok := v.schemas[tm]
 	if !ok {
 		crd, err := v.getCRD(ctx, tm)
 		if err != nil {
@@ -126,24 +129,41 @@ func (v *Validator) getValidator(ctx context.Context, tm TypeMeta) (*validate.Sc
 
 		if crd != nil {
 			if crd.Spec.Validation != nil {
-				validator, _, err = validation.NewSchemaValidator(crd.Spec.Validation)
-				if err != nil {
-					return nil, err
-				}
+				schema = crd.Spec.Validation
 			} else {
 				tmVersion := path.Base(tm.APIVersion)
 				for _, version := range crd.Spec.Versions {
 					if version.Name == tmVersion {
-						validator, _, err = validation.NewSchemaValidator(version.Schema)
-						if err != nil {
-							return nil, err
-						}
+						schema = version.Schema
 						break
 					}
 				}
 			}
 		}
 
+		v.schemas[tm] = schema // even if schema is nil; cache negative responses
+	}
+	return schema, nil
+}
+
+func (v *Validator) getValidator(ctx context.Context, tm TypeMeta) (*validate.SchemaValidator, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	validator, ok := v.validators[tm]
+	if !ok {
+		schema, err := v.getSchema(ctx, tm)
+		if err != nil {
+			return nil, err
+		}
+
+		if schema != nil {
+			validator, _, err = validation.NewSchemaValidator(schema)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		v.validators[tm] = validator // even if validator is nil; cache negative responses
 	}
 	return validator, nil
@@ -197,3 +217,106 @@ func (v *Validator) Validate(ctx context.Context, resource interface{}) error {
 
 	return nil
 }
+
+// An UnknownField is a field that the schema of a CRD doesn't know about.
+type UnknownField struct {
+	// Path is where the field is, e.g. "spec.retry_policy.retry_onn".
+	Path string
+	// Name is the field's own name, e.g. "retry_onn".
+	Name string
+	// Known is the fields that the schema does know about alongside it, sorted.
+	Known []string
+}
+
+// The UnknownFields method returns the fields in the supplied jsonish
+// object that the schema of its CRD doesn't know about. Validate
+// accepts these: Kubernetes just prunes them when it stores the
+// resource, and that's what we get when the CRD in the cluster is
+// newer than the one we were built with. They can also be typos,
+// though, so this is how to find them.
+//
+// Objects that aren't CRD instances have no unknown fields, and
+// neither do the parts of a schema that take fields it doesn't list
+// (x-kubernetes-preserve-unknown-fields, or an object with no
+// properties).
+func (v *Validator) UnknownFields(ctx context.Context, resource interface{}) ([]UnknownField, error) {
+	var tm TypeMeta
+	err := convert(resource, &tm)
+	if err != nil {
+		return nil, err
+	}
+
+	v.mutex.Lock()
+	schema, err := v.getSchema(ctx, tm)
+	v.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if schema == nil || schema.OpenAPIV3Schema == nil {
+		return nil, nil
+	}
+
+	var obj map[string]interface{}
+	if err := convert(resource, &obj); err != nil {
+		return nil, err
+	}
+
+	var unknown []UnknownField
+	findUnknownFields("", obj, schema.OpenAPIV3Schema, &unknown)
+	return unknown, nil
+}
+
+func findUnknownFields(prefix string, value interface{}, schema *apiextVInternal.JSONSchemaProps, unknown *[]UnknownField) {
+	if schema == nil || (schema.XPreserveUnknownFields != nil && *schema.XPreserveUnknownFields) {
+		return
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		if len(schema.Properties) == 0 {
+			if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+				for _, name := range names {
+					findUnknownFields(prefix+name+".", value[name], schema.AdditionalProperties.Schema, unknown)
+				}
+			}
+			return
+		}
+
+		var known []string
+		for _, name := range names {
+			property, ok := schema.Properties[name]
+			if ok {
+				findUnknownFields(prefix+name+".", value[name], &property, unknown)
+				continue
+			}
+			if schema.AdditionalProperties != nil && (schema.AdditionalProperties.Allows || schema.AdditionalProperties.Schema != nil) {
+				findUnknownFields(prefix+name+".", value[name], schema.AdditionalProperties.Schema, unknown)
+				continue
+			}
+
+			if known == nil {
+				for name := range schema.Properties {
+					known = append(known, name)
+				}
+				sort.Strings(known)
+			}
+			*unknown = append(*unknown, UnknownField{
+				Path:  prefix + name,
+				Name:  name,
+				Known: known,
+			})
+		}
+	case []interface{}:
+		if schema.Items != nil && schema.Items.Schema != nil {
+			for i, item := range value {
+				findUnknownFields(fmt.Sprintf("%s[%d].", strings.TrimSuffix(prefix, "."), i), item, schema.Items.Schema, unknown)
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package kates
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -72,6 +73,54 @@ func TestValidation(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestUnknownFields(t *testing.T) {
+	objs, err := ParseManifests(CRD)
+	require.NoError(t, err)
+
+	// This one only needs the static CRD, not a cluster.
+	validator, err := NewValidator(nil, objs)
+	require.NoError(t, err)
+
+	unknown, err := validator.UnknownFields(context.Background(), map[string]interface{}{
+		"apiVersion": "test.io/v1",
+		"kind":       "TestValidation",
+		"metadata": map[string]interface{}{
+			"name": "unknown",
+		},
+		"spec": map[string]interface{}{
+			"prefix": "/foo/",
+			"foo":    "bar",
+			"circuit_breakers": []interface{}{map[string]interface{}{
+				"priorty": "high",
+			}},
+			"headers": map[string]interface{}{
+				"x-anything": "goes",
+			},
+		},
+		"foo": "bar",
+	})
+	require.NoError(t, err)
+
+	paths := []string{}
+	for _, field := range unknown {
+		paths = append(paths, field.Path)
+	}
+	assert.Equal(t, []string{"foo", "spec.circuit_breakers[0].priorty", "spec.foo"}, paths)
+	assert.Equal(t, "priorty", unknown[1].Name)
+	assert.Contains(t, unknown[1].Known, "priority")
+
+	// Things that aren't CRDs have no unknown fields.
+	unknown, err = validator.UnknownFields(context.Background(), map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"spec": map[string]interface{}{
+			"foo": "bar",
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, unknown)
+}
+
 var CRD = `
 apiVersion: apiextensions.k8s.io/v1
 kind: CustomResourceDefinition
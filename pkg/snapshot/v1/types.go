@@ -42,7 +42,26 @@ type Snapshot struct {
 	// The Invalid field contains any kubernetes resources that have failed
 	// validation.
 	Invalid []*kates.Unstructured
-	Raw     json.RawMessage `json:"-"`
+	// The Certificates field says when the certificates in the TLS secrets that are
+	// actually in use expire, so that diagd can warn before they do.
+	Certificates []*CertificateStatus `json:"Certificates,omitempty"`
+	// The Generation field counts the changes that made it into a snapshot so far, so that
+	// every configuration that comes of a snapshot can say which one it came from.
+	Generation int             `json:"Generation,omitempty"`
+	Raw        json.RawMessage `json:"-"`
+}
+
+// CertificateStatus is what the entrypoint makes of the tls.crt of a secret that's in use.
+type CertificateStatus struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// NotAfter is when the first certificate in the chain to expire does so, in seconds since
+	// the epoch. That's usually the leaf, but not always.
+	NotAfter int64 `json:"notAfter,omitempty"`
+	// Subject is the common name of the certificate that expires at NotAfter.
+	Subject string `json:"subject,omitempty"`
+	// Error says why tls.crt couldn't be parsed, if it couldn't.
+	Error string `json:"error,omitempty"`
 }
 
 type AmbassadorMetaInfo struct {
@@ -60,6 +79,11 @@ type KubernetesSnapshot struct {
 	Services       []*kates.Service   `json:"service"`
 	Endpoints      []*kates.Endpoints `json:"Endpoints"`
 
+	// EndpointSlices are only here for the topology of their endpoints, which we give to envoy as
+	// their locality. Where that topology lives differs between discovery.k8s.io/v1beta1 and v1,
+	// so like the KNative types these stay unstructured. Diagd doesn't need them.
+	EndpointSlices []*kates.Unstructured `json:"-"`
+
 	// ambassador resources
 	Listeners   []*amb.Listener   `json:"Listener"`
 	Hosts       []*amb.Host       `json:"Host"`
@@ -146,10 +170,21 @@ func (a *KubernetesSnapshot) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, (*k8ssnap2)(a)); err != nil {
 		return err
 	}
-	a.Listeners = append(a.Listeners, legacyK8sTranslator.LegacyModeListeners...)
-	a.Hosts = append(a.Hosts, legacyK8sTranslator.LegacyModeHosts...)
-	a.Mappings = append(a.Mappings, legacyK8sTranslator.LegacyModeMappings...)
-	a.TCPMappings = append(a.TCPMappings, legacyK8sTranslator.LegacyModeTCPMappings...)
+	// The fields have the same names as the legacy ones, so the snapshot may well have them
+	// already. Appending those again would double them every time a snapshot goes through
+	// JSON.
+	if len(a.Listeners) == 0 {
+		a.Listeners = legacyK8sTranslator.LegacyModeListeners
+	}
+	if len(a.Hosts) == 0 {
+		a.Hosts = legacyK8sTranslator.LegacyModeHosts
+	}
+	if len(a.Mappings) == 0 {
+		a.Mappings = legacyK8sTranslator.LegacyModeMappings
+	}
+	if len(a.TCPMappings) == 0 {
+		a.TCPMappings = legacyK8sTranslator.LegacyModeTCPMappings
+	}
 	return nil
 }
 
@@ -30,3 +30,42 @@ func TestUnmarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestUnmarshalLegacyFields(t *testing.T) {
+	t.Parallel()
+	snapshot := &snapshotTypes.KubernetesSnapshot{}
+	data := []byte(`{
+		"Listener": [{"metadata": {"name": "listener", "namespace": "default"}}],
+		"Host": [{"metadata": {"name": "host", "namespace": "default"}}],
+		"Mapping": [{"metadata": {"name": "mapping", "namespace": "default"}}],
+		"TCPMapping": [{"metadata": {"name": "tcpmapping", "namespace": "default"}}]
+	}`)
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	// Going through JSON again mustn't change anything: the legacy field names are the same as
+	// the current ones, so each resource must only land once.
+	for round := 0; round < 2; round++ {
+		counts := map[string]int{
+			"Listener":   len(snapshot.Listeners),
+			"Host":       len(snapshot.Hosts),
+			"Mapping":    len(snapshot.Mappings),
+			"TCPMapping": len(snapshot.TCPMappings),
+		}
+		for field, count := range counts {
+			if count != 1 {
+				t.Errorf("round %d: %s has %d resources, not 1", round, field, count)
+			}
+		}
+
+		encoded, err := json.Marshal(snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshot = &snapshotTypes.KubernetesSnapshot{}
+		if err := json.Unmarshal(encoded, snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -172,6 +172,9 @@ class Config:
         self.logger.debug("SCHEMA DIR    %s" % os.path.abspath(self.schema_dir_path))
         self.k8s_status_updates: Dict[str, Tuple[str, str, Optional[Dict[str, Any]]]] = {}  # Tuple is (name, namespace, status_json)
         self.pod_labels: Dict[str, str] = {}
+        self.snapshot_generation: Optional[int] = None  # from the watt snapshot, if it has one
+        self.certificates: List[Dict[str, Any]] = []    # from the watt snapshot: when the certs in use expire
+        self.secret_types: Dict[str, Dict[str, Any]] = {}   # from the K8s secrets we fetched: their types and keys
         self._reset()
 
     def _reset(self) -> None:
@@ -568,7 +571,12 @@ class Config:
             if watt_errors:
                 # entrypoint.go reported errors. Did we find errors or not?
 
-                if rc:
+                if rc and all(error.startswith('unknown field ') for error in errors if error):
+                    # Unknown fields are only errors when entrypoint.go is running with
+                    # AMBASSADOR_STRICT_SCHEMA_VALIDATION, and our schemas don't look for
+                    # them at all, so there's nothing for us to disagree with.
+                    rc = RichStatus.fromError(watt_errors)
+                elif rc:
                     # We did not. Post this into fast_validation_disagreements
                     fvd = self.fast_validation_disagreements.setdefault(resource.rkey, [])
                     fvd.append(watt_errors)
@@ -653,6 +661,7 @@ class Config:
         name = rdict.pop('name', None)
         namespace = rdict.pop('namespace', None)
         metadata_labels = rdict.pop('metadata_labels', None)
+        metadata_annotations = rdict.pop('metadata_annotations', None)
         generation = rdict.pop('generation', None)
 
         serialized = dump_json(rdict)
@@ -21,6 +21,7 @@ import re
 
 from ..ir import IR
 from ..ir.irbasemappinggroup import IRBaseMappingGroup
+from ..ir.irhost import IRHost
 from ..ir.irhttpmappinggroup import IRHTTPMappingGroup
 from ..envoy import EnvoyConfig
 from .envoy_stats import EnvoyStats
@@ -294,6 +295,8 @@ class DiagResult:
         if diag_class:
             route_info['diag_class'] = diag_class
 
+        route_info.update(self.diag.selected_metadata(group))
+
         self.routes.append(route_info)
         self.include_referenced_elements(group)
 
@@ -377,6 +380,11 @@ class Diagnostics:
         self.ambassador_services: List[dict] = []
         self.ambassador_resolvers: List[dict] = []
 
+        # The ambassador Module can ask for some labels and annotations of Mappings and
+        # Hosts to be shown too, so that e.g. it's easy to tell which team owns what.
+        self.label_keys: List[str] = self.ir.ambassador_module.get('diagnostics_labels', None) or []
+        self.annotation_keys: List[str] = self.ir.ambassador_module.get('diagnostics_annotations', None) or []
+
         # Warn people about upcoming deprecations.
 
         warn_auth = False
@@ -590,6 +598,7 @@ class Diagnostics:
             'notices': self.notices,
             'fast_validation_disagreements': self.fast_validation_disagreements,
             'groups': { key: self.flattened(value) for key, value in self.groups.items() },
+            'hosts': [ self.host_info(host) for host in self.ir.get_hosts() ],
             # 'clusters': { key: value.as_dict() for key, value in self.clusters.items() },
             'tlscontexts': [ x.as_dict() for x in self.ir.tls_contexts.values() ]
         }
@@ -622,12 +631,51 @@ class Diagnostics:
             if host:
                 fm['host'] = host
 
+            fm.update(self.selected_metadata(m))
+
             flattened_mappings.append(fm)
 
         flattened['mappings'] = flattened_mappings
 
         return flattened
 
+    def host_info(self, host: IRHost) -> dict:
+        info = {
+            "_rkey": host.rkey,
+            "location": host.location,
+            "name": host.name,
+            "namespace": host.namespace,
+            "hostname": host.hostname,
+        }
+
+        info.update(self.selected_metadata(host))
+
+        return info
+
+    def selected_metadata(self, resource: dict) -> Dict[str, Dict[str, str]]:
+        """
+        Pick out the labels and annotations of a resource that were asked for with
+        diagnostics_labels and diagnostics_annotations. Keys that the resource doesn't
+        have are just left out.
+
+        :param resource: the IR resource (Mapping, Group, or Host) to look at
+        :return: a dict with 'labels' and/or 'annotations', if anything was found
+        """
+
+        selected: Dict[str, Dict[str, str]] = {}
+
+        for name, keys, metadata_key in [
+            ( 'labels', self.label_keys, 'metadata_labels' ),
+            ( 'annotations', self.annotation_keys, 'metadata_annotations' ),
+        ]:
+            metadata = resource.get(metadata_key) or {}
+            found = { key: metadata[key] for key in keys if key in metadata }
+
+            if found:
+                selected[name] = found
+
+        return selected
+
     def _remember_source(self, src_key: str, dest_key: str) -> None:
         """
         Link keys of active sources together. The source map lets us answer questions
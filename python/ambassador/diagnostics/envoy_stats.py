@@ -15,6 +15,7 @@
 from typing import Any, Callable, Dict, List, Optional, Union
 
 import logging
+import os
 import requests
 import threading
 import time
@@ -163,6 +164,20 @@ class EnvoyStatsMgr:
         self.fetch_log_levels = fetch_log_levels or self._fetch_log_levels
         self.fetch_envoy_stats = fetch_envoy_stats or self._fetch_envoy_stats
 
+        # The log levels the Ambassador module wants, and the ones we last managed to push
+        # to Envoy. We can't just push them when we reconfigure, since Envoy may not be
+        # running yet, so update() pushes them whenever the two differ.
+        self.log_levels: List[str] = []
+        self.pushed_log_levels: List[str] = []
+
+        # The level Envoy starts with (see GetEnvoyFlags in cmd/entrypoint), which is what
+        # we go back to when the module stops setting one.
+        self.default_log_level = 'trace' if 'envoy' in os.environ.get('AMBASSADOR_DEBUG', '') else 'error'
+
+        # Envoy's uptime as of the last stats update. If it goes down, Envoy restarted
+        # and forgot whatever levels we pushed.
+        self.envoy_uptime: Optional[int] = None
+
         self.stats = EnvoyStats(
             created=time.time(),
             max_live_age=max_live_age,
@@ -174,7 +189,13 @@ class EnvoyStatsMgr:
             url = "http://127.0.0.1:8001/logging"
 
             if level:
-                url += "?level=%s" % level
+                # "component:level" sets the level for just one component; anything
+                # else sets the level for everything.
+                if ':' in level:
+                    component, level = level.split(':', 1)
+                    url += "?%s=%s" % (component, level)
+                else:
+                    url += "?level=%s" % level
 
             r = requests.post(url)
 
@@ -263,6 +284,52 @@ class EnvoyStatsMgr:
             # self.logger.info("loginfo: %s" % self.loginfo)
             return True
 
+    def set_log_levels(self, levels: List[str]) -> None:
+        """
+        Set the log levels that Envoy should have; they're pushed to Envoy on the next
+        update if they differ from what was last pushed. Each entry is either a level,
+        which applies to every component, or a "component:level" pair; they're applied
+        in order. An empty list puts Envoy back to its default level.
+
+        You MUST NOT hold the access_lock when calling this method.
+        """
+
+        with self.access_lock:
+            self.log_levels = levels
+
+    def push_log_levels(self, last_attempt: float) -> None:
+        """
+        Push the log levels to Envoy if they've changed since we last pushed them, or
+        Envoy has restarted since. If that fails, we'll try again on the next update.
+
+        You MUST hold the update lock when calling this method.
+        You MUST NOT hold the access lock when calling this method.
+        """
+
+        with self.access_lock:
+            levels = self.log_levels
+            pushed = self.pushed_log_levels
+
+        if levels == pushed:
+            return
+
+        # Start from the default level unless the first entry sets every component, so
+        # that components we set before but don't now go back to the default too.
+        to_push = levels
+
+        if (not levels) or (':' in levels[0]):
+            to_push = [ self.default_log_level ] + levels
+
+        self.logger.info("EnvoyStats.push_log_levels: setting %s" % ", ".join(to_push))
+
+        for level in to_push:
+            if not self.update_log_levels(last_attempt, level=level):
+                self.logger.warning("EnvoyStats.push_log_levels: could not set %s, will retry" % level)
+                return
+
+        with self.access_lock:
+            self.pushed_log_levels = levels
+
     def get_stats(self) -> EnvoyStats:
         """
         Get the current Envoy stats object, safely.
@@ -350,6 +417,16 @@ class EnvoyStatsMgr:
             except:
                 continue
 
+        uptime = envoy_stats.get('server', {}).get('uptime', None)
+
+        if isinstance(uptime, int):
+            with self.access_lock:
+                if (self.envoy_uptime is not None) and (uptime < self.envoy_uptime):
+                    self.logger.info("EnvoyStats.update_envoy_stats: Envoy restarted, will push log levels again")
+                    self.pushed_log_levels = []
+
+                self.envoy_uptime = uptime
+
         # Now dig into clusters a bit more.
 
         requests_info = {}
@@ -482,6 +559,7 @@ class EnvoyStatsMgr:
             # Remember when we started.
             last_attempt = time.time()
 
+            self.push_log_levels(last_attempt)
             self.update_log_levels(last_attempt)
             self.update_envoy_stats(last_attempt)
         except Exception as e:
@@ -482,8 +482,11 @@ class V2Route(Cacheable):
         # Take the default `timeout_ms` value from the Ambassador module using `cluster_request_timeout_ms`.
         # If that isn't set, use 3000ms. The mapping below will override this if its own `timeout_ms` is set.
         default_timeout_ms = config.ir.ambassador_module.get('cluster_request_timeout_ms', 3000)
+        # IRHTTPMapping has already checked that priority is default or high.
+        priority = group.get('priority', None)
+
         route = {
-            'priority': group.get('priority'),
+            'priority': priority.upper() if priority else None,
             'timeout': "%0.3fs" % (mapping.get('timeout_ms', default_timeout_ms) / 1000.0),
             'cluster': mapping.cluster.envoy_name
         }
@@ -27,6 +27,9 @@ class V3StaticResources(dict):
             'clusters': config.clusters,
         })
 
+        if config.secrets:
+            self['secrets'] = [ config.secrets[name] for name in sorted(config.secrets.keys()) ]
+
     @classmethod
     def generate(cls, config: 'V3Config') -> None:
         # We needn't use config.save_element here -- this is just a wrapper element.
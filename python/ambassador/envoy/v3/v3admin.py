@@ -12,7 +12,11 @@
 # See the License for the specific language governing permissions and
 # limitations under the License
 
-from typing import TYPE_CHECKING
+from typing import List, TYPE_CHECKING
+
+import math
+
+from .v3listener import V3Listener
 
 if TYPE_CHECKING:
     from . import V3Config # pragma: no cover
@@ -34,6 +38,35 @@ class V3Admin(dict):
             }
         })
 
+        # Envoy's admin server has no idle timeout of its own, and it's the scrapers that
+        # go away without closing their connections that pile up. So admin_idle_timeout_ms
+        # turns on TCP keepalive for admin connections, probing once a connection has been
+        # idle that long and closing it if the other end is gone. Live connections (like the
+        # ones diagd and the readiness checks use) answer the probes and stay open. Zero
+        # means the same as not setting it.
+        admin_idle_timeout_ms = config.ir.ambassador_module.get('admin_idle_timeout_ms', None)
+
+        if admin_idle_timeout_ms:
+            self['socket_options'] = V3Admin.keepalive_socket_options(admin_idle_timeout_ms)
+
+    @staticmethod
+    def keepalive_socket_options(idle_timeout_ms: int) -> List[dict]:
+        # TCP_KEEPIDLE is in whole seconds, and 0 isn't allowed.
+        idle_seconds = max(1, math.ceil(idle_timeout_ms / 1000.0))
+
+        return [
+            {
+                'level': level,
+                'name': name,
+                'int_value': value,
+                'state': 'STATE_LISTENING'
+            }
+            for level, name, value in [
+                ( V3Listener.SOL_SOCKET, V3Listener.SO_KEEPALIVE, 1 ),
+                ( V3Listener.IPPROTO_TCP, V3Listener.TCP_KEEPIDLE, idle_seconds ),
+            ]
+        ]
+
     @classmethod
     def generate(cls, config: 'V3Config') -> None:
         config.admin = config.save_element('admin', config.ir.ambassador_module, V3Admin(config))
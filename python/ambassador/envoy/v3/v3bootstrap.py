@@ -1,7 +1,8 @@
-from typing import TYPE_CHECKING
+from typing import Any, Dict, List, TYPE_CHECKING
 from typing import cast as typecast
 
 import os
+import re
 
 from ...ir.ircluster import IRCluster
 from ...ir.irlogservice import IRLogService
@@ -142,8 +143,111 @@ class V3Bootstrap(dict):
                 'seconds': config.ir.statsd['interval']
             }
 
+        self.histogram_settings(config)
+
+        if config.ir.ambassador_module.get('cluster_stats_names', None) == 'prometheus':
+            stats_tags = self.setdefault('stats_config', {}).setdefault('stats_tags', [])
+            stats_tags.extend(dict(tag) for tag in V3Bootstrap.PrometheusStatsTags)
+
+        # One tag for each stats_dimensions name, however many Mappings use it.
+        dimensions = sorted({ dimension['name']
+                              for group in config.ir.groups.values() for mapping in group.mappings
+                              for dimension in (mapping.get('stats_dimensions', None) or []) })
+
+        if dimensions:
+            stats_tags = self.setdefault('stats_config', {}).setdefault('stats_tags', [])
+            stats_tags.extend(V3Bootstrap.stats_dimension_tag(name) for name in dimensions)
+
         self['static_resources']['clusters'] = clusters
 
+    @staticmethod
+    def stats_dimension_tag(name: str) -> Dict[str, str]:
+        # stats_dimensions virtual clusters are named <name>-<dimension>-<value>-... (see
+        # V3Route.generate_virtual_clusters), and this picks out one dimension's value. Envoy's
+        # own envoy.virtual_cluster tag still has the whole virtual cluster name.
+        return {
+            'tag_name': name,
+            'regex': r'\.vcluster\.[0-9A-Za-z_]+(?:-[a-z0-9_]+-[0-9A-Za-z_]+)*?(-' + name + r'-([0-9A-Za-z_]+))(?:-[a-z0-9_]+-[0-9A-Za-z_]+)*\.'
+        }
+
+    # With cluster_stats_names "prometheus", a cluster's stats are named for its service,
+    # namespace, and port (see IRCluster.prometheus_stats_name), and these tags split them
+    # out. Each removes the same part of the name as Envoy's own envoy.cluster_name tag, which
+    # still has the whole stats name, so metrics and dashboards using that keep working.
+    PrometheusStatsTags: List[Dict[str, str]] = [
+        {
+            'tag_name': 'ambassador_service',
+            'regex': r'^cluster\.(([a-z0-9-]+)_[a-z0-9-]+_[0-9]+\.)'
+        },
+        {
+            'tag_name': 'ambassador_namespace',
+            'regex': r'^cluster\.([a-z0-9-]+_([a-z0-9-]+)_[0-9]+\.)'
+        },
+        {
+            'tag_name': 'ambassador_port',
+            'regex': r'^cluster\.([a-z0-9-]+_[a-z0-9-]+_([0-9]+)\.)'
+        },
+    ]
+
+    # The histograms Envoy keeps for every cluster. (There are other upstream_rq_time
+    # histograms, like internal.upstream_rq_time, but they're all upstream_rq_time.)
+    ClusterHistograms = [ 'upstream_cx_connect_ms', 'upstream_cx_length_ms', 'upstream_rq_time' ]
+
+    def histogram_settings(self, config: 'V3Config') -> None:
+        # Envoy sets histogram buckets by stats name, and only when it starts, so these
+        # take effect on the next restart. The first setting that matches a histogram wins,
+        # so each cluster's own buckets go before the Ambassador Module's.
+        bucket_settings: List[Dict[str, Any]] = []
+        exclusions: List[Dict[str, Any]] = []
+        seen: Dict[str, Any] = {}
+
+        for cluster in sorted(config.ir.clusters.values(), key=lambda c: c.name):
+            buckets = cluster.get('histogram_buckets', None)
+            stats_name = cluster.get('stats_name', None)
+
+            if (buckets is None) or not stats_name:
+                continue
+
+            if stats_name in seen:
+                if seen[stats_name] != buckets:
+                    config.ir.aconf.post_notice(f"histogram_buckets for cluster {cluster.name} is ignored: another cluster with stats_name {stats_name} already has histogram_buckets {seen[stats_name]}", resource=cluster)
+
+                continue
+
+            seen[stats_name] = buckets
+
+            if buckets:
+                bucket_settings.append({
+                    'match': { 'prefix': f"cluster.{stats_name}." },
+                    'buckets': buckets
+                })
+            else:
+                # No buckets means no histograms at all for this cluster.
+                histograms = '|'.join(V3Bootstrap.ClusterHistograms)
+
+                exclusions.append({
+                    'safe_regex': {
+                        'google_re2': {},
+                        'regex': f"^cluster\\.{re.escape(stats_name)}\\.(.+\\.)?({histograms})$"
+                    }
+                })
+
+        default_buckets = config.ir.ambassador_module.get('histogram_buckets', None)
+
+        if default_buckets:
+            bucket_settings.append({
+                'match': { 'prefix': 'cluster.' },
+                'buckets': default_buckets
+            })
+
+        if bucket_settings:
+            self.setdefault('stats_config', {})['histogram_bucket_settings'] = bucket_settings
+
+        if exclusions:
+            self.setdefault('stats_config', {})['stats_matcher'] = {
+                'exclusion_list': { 'patterns': exclusions }
+            }
+
     @classmethod
     def generate(cls, config: 'V3Config') -> None:
         config.bootstrap = V3Bootstrap(config)
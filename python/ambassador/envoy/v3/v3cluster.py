@@ -13,7 +13,7 @@
 # limitations under the License
 
 import urllib
-from typing import Dict, List, Union, TYPE_CHECKING
+from typing import Any, Dict, List, TYPE_CHECKING
 
 from ...cache import Cacheable
 from ...ir.ircluster import IRCluster
@@ -23,13 +23,28 @@ from ...config import Config
 from .v3tls import V3TLSContext
 
 if TYPE_CHECKING:
+    from ...ir import IR # pragma: no cover
     from . import V3Config # pragma: no cover
 
 
 class V3Cluster(Cacheable):
+    HttpProtocolOptionsName = 'envoy.extensions.upstreams.http.v3.HttpProtocolOptions'
+
+    # The oneof in HttpProtocolOptions that picks the upstream protocol.
+    UpstreamProtocolConfigs = ( 'explicit_http_config', 'use_downstream_protocol_config', 'auto_config' )
+
+    # Envoy ignores filter_metadata it doesn't know about, so this is where we tell ambex
+    # things about a cluster (or, on a route, about that route). This has to agree with
+    # cmd/ambex/drain.go and cmd/ambex/rebalance.go.
+    AmbexMetadataKey = 'getambassador.io'
+
     def __init__(self, config: 'V3Config', cluster: IRCluster) -> None:
         super().__init__()
 
+        # The SDS secrets our TLS context refers to (see V3TLSContext.discover_secrets). This
+        # lives here rather than only in the config so that a cached V3Cluster still has them.
+        self.secrets: Dict[str, Dict[str, Any]] = {}
+
         dns_lookup_family = 'V4_ONLY'
 
         if cluster.enable_ipv6:
@@ -48,6 +63,26 @@ class V3Cluster(Cacheable):
         assert(cluster.envoy_name)
         assert(len(cluster.envoy_name) <= 60)
 
+        # An aggregate cluster has no hosts or connections of its own: Envoy hands each request
+        # to the first of its clusters that has healthy hosts, and that cluster does the rest.
+        aggregate_clusters = cluster.get('aggregate_clusters', None)
+
+        if aggregate_clusters:
+            self.update({
+                'name': cluster.envoy_name,
+                'connect_timeout': "%0.3fs" % (float(cluster.connect_timeout_ms) / 1000.0),
+                'lb_policy': 'CLUSTER_PROVIDED',
+                'cluster_type': {
+                    'name': 'envoy.clusters.aggregate',
+                    'typed_config': {
+                        '@type': 'type.googleapis.com/envoy.extensions.clusters.aggregate.v3.ClusterConfig',
+                        'clusters': [ cluster.ir.clusters[name].envoy_name for name in aggregate_clusters ]
+                    }
+                }
+            })
+
+            return
+
         cmap_entry = cluster.clustermap_entry()
         if Config.legacy_mode or (cmap_entry['kind'] == 'KubernetesServiceResolver'):
             ctype = cluster.type.upper()
@@ -70,10 +105,46 @@ class V3Cluster(Cacheable):
         if cluster.get('stats_name', ''):
             fields['alt_stat_name'] = cluster.stats_name
 
+        choice_count = (cluster.get('load_balancer', None) or {}).get('choice_count', None)
+
+        if (cluster.lb_type == 'least_request') and choice_count:
+            fields['least_request_lb_config'] = {
+                'choice_count': choice_count
+            }
+
         if cluster.respect_dns_ttl:
             fields['respect_dns_ttl'] = cluster.respect_dns_ttl
 
-        if ctype == 'EDS':
+        # Port 0 lets the kernel pick the source port, which is what we want: we only
+        # care about the source address.
+        if cluster.get('upstream_bind_address', None):
+            fields['upstream_bind_config'] = {
+                'source_address': {
+                    'address': cluster.upstream_bind_address,
+                    'port_value': 0
+                }
+            }
+
+        if cluster.get('dynamic_forward_proxy', False):
+            # Envoy resolves the host from each request with the DNS cache it shares with the
+            # dynamic forward proxy filter, so there's nothing to discover here. auto_sni makes
+            # it send that host as the SNI (and check it against the upstream's cert), too.
+            del fields['type']
+            del fields['dns_lookup_family']
+
+            fields['lb_policy'] = 'CLUSTER_PROVIDED'
+            fields['cluster_type'] = {
+                'name': 'envoy.clusters.dynamic_forward_proxy',
+                'typed_config': {
+                    '@type': 'type.googleapis.com/envoy.extensions.clusters.dynamic_forward_proxy.v3.ClusterConfig',
+                    'dns_cache_config': V3Cluster.dns_cache_config(cluster.ir)
+                }
+            }
+            fields['upstream_http_protocol_options'] = {
+                'auto_sni': True,
+                'auto_san_validation': True
+            }
+        elif ctype == 'EDS':
             fields['eds_cluster_config'] = {
                 'eds_config': {
                     'ads': {},
@@ -82,7 +153,34 @@ class V3Cluster(Cacheable):
                 },
                 'service_name': cmap_entry['endpoint_path']
             }
+
+            # How long Envoy waits for this cluster's first endpoints before giving up and
+            # warming without them. 0 means wait forever -- unless there's a warming timeout,
+            # which nothing gets to wait past.
+            initial_fetch_timeout_ms = cluster.get('initial_fetch_timeout_ms', None)
+            if initial_fetch_timeout_ms is None:
+                initial_fetch_timeout_ms = cluster.ir.ambassador_module.get('initial_fetch_timeout_ms', None)
+            cluster_warming_timeout_ms = cluster.ir.ambassador_module.get('cluster_warming_timeout_ms', None)
+            if cluster_warming_timeout_ms and \
+               ((not initial_fetch_timeout_ms) or (initial_fetch_timeout_ms > cluster_warming_timeout_ms)):
+                initial_fetch_timeout_ms = cluster_warming_timeout_ms
+            if initial_fetch_timeout_ms is not None:
+                fields['eds_cluster_config']['eds_config']['initial_fetch_timeout'] = "%0.3fs" % (float(initial_fetch_timeout_ms) / 1000.0)
         else:
+            # (A DNS cluster is warm after its first resolution, whether it worked or not, so
+            # a name that won't resolve doesn't hold anything up: it just has no endpoints
+            # until a retry works.)
+            #
+            # After a DNS failure, Envoy backs off from this interval with jitter, so that
+            # lots of clusters failing together don't all retry together.
+            dns_failure_refresh_rate_ms = cluster.get('dns_failure_refresh_rate_ms', None)
+            if dns_failure_refresh_rate_ms is None:
+                dns_failure_refresh_rate_ms = cluster.ir.ambassador_module.get('dns_failure_refresh_rate_ms', None)
+            if dns_failure_refresh_rate_ms:
+                fields['dns_failure_refresh_rate'] = {
+                    'base_interval': "%0.3fs" % (float(dns_failure_refresh_rate_ms) / 1000.0)
+                }
+
             fields['load_assignment'] = {
                 'cluster_name': cluster.envoy_name,
                 'endpoints': [
@@ -108,17 +206,64 @@ class V3Cluster(Cacheable):
             common_http_options = self.setdefault("common_http_protocol_options", {})
             common_http_options['max_connection_duration'] = "%0.3fs" % (float(cluster_max_connection_lifetime_ms) / 1000.0)
 
+        # Envoy reuses upstream connections without limit unless told otherwise; 1 gives
+        # every request a new connection (or, for HTTP/2, every stream). Our Envoy's
+        # CommonHttpProtocolOptions doesn't have this yet, so it's still on the cluster.
+        max_requests_per_connection = cluster.get('max_requests_per_connection', None)
+        if max_requests_per_connection:
+            fields['max_requests_per_connection'] = max_requests_per_connection
+
+        # This limits the number of headers (and trailers) we'll accept in a response from
+        # the upstream; a response over the limit is turned into a 503.
+        max_response_headers_count = cluster.ir.ambassador_module.get('max_response_headers_count', None)
+        if max_response_headers_count:
+            common_http_options = self.setdefault("common_http_protocol_options", {})
+            common_http_options['max_headers_count'] = max_response_headers_count
+
         circuit_breakers = self.get_circuit_breakers(cluster)
         if circuit_breakers is not None:
             fields['circuit_breakers'] = circuit_breakers
 
+        # When this cluster goes away, ambex keeps it around for this long with nothing
+        # routed to it, so that requests already on their way to it can finish.
+        cluster_drain_time_ms = cluster.get('cluster_drain_time_ms', None)
+        if cluster_drain_time_ms is None:
+            cluster_drain_time_ms = cluster.ir.ambassador_module.get('cluster_drain_time_ms', None)
+        if cluster_drain_time_ms:
+            # Add to whatever metadata the cluster already has, rather than replacing it.
+            filter_metadata = fields.setdefault('metadata', {}).setdefault('filter_metadata', {})
+            filter_metadata.setdefault(V3Cluster.AmbexMetadataKey, {})['drain_time_ms'] = cluster_drain_time_ms
+
+        health_checks = self.get_health_checks(cluster)
+        if health_checks:
+            fields['health_checks'] = health_checks
+
         # If this cluster is using http2 for grpc, set http2_protocol_options
         # Otherwise, check for http1-specific configuration.
         if cluster.get('grpc', False):
             self["http2_protocol_options"] = {}
+
+            # HTTP/2 PINGs keep idle connections from being reaped by load balancers in the
+            # way, and notice when they have been. This is separate from TCP keepalive below.
+            http2_keepalive = cluster.get('http2_keepalive', None)
+            if http2_keepalive is None:
+                http2_keepalive = cluster.ir.ambassador_module.get('http2_keepalive', None)
+            if http2_keepalive:
+                connection_keepalive = {
+                    'interval': "%0.3fs" % (float(http2_keepalive['interval_ms']) / 1000.0),
+                    'timeout': "%0.3fs" % (float(http2_keepalive['timeout_ms']) / 1000.0)
+                }
+                if http2_keepalive.get('interval_jitter_percent', None) is not None:
+                    connection_keepalive['interval_jitter'] = { 'value': float(http2_keepalive['interval_jitter_percent']) }
+                self["http2_protocol_options"]['connection_keepalive'] = connection_keepalive
         else:
             proper_case: bool = cluster.ir.ambassador_module['proper_case']
 
+            # The downstream side of trailer handling lives in v3listener.py.
+            if 'enable_trailers' in cluster.ir.ambassador_module:
+                http_options = self.setdefault("http_protocol_options", {})
+                http_options['enable_trailers'] = bool(cluster.ir.ambassador_module.enable_trailers)
+
             # Get the list of upstream headers whose casing should be overriden
             # from the Ambassador module. We configure the downstream side of this
             # in v3listener.py
@@ -160,6 +305,29 @@ class V3Cluster(Cacheable):
                 }
             else:
                 envoy_ctx = V3TLSContext(ctx=ctx, host_rewrite=cluster.get('host_rewrite', None))
+                envoy_ctx.as_upstream()
+                envoy_ctx.discover_secrets(cluster.ir, self.secrets)
+
+            # An HTTP/2 upstream has to be offered h2 in the handshake. An explicit
+            # alpn_protocols wins (IRCluster complains if it leaves out h2).
+            if cluster.get('grpc', False):
+                common = envoy_ctx.setdefault('common_tls_context', {})
+                common.setdefault('alpn_protocols', [ 'h2' ])
+
+            # auto_http_protocol offers both, and uses HTTP/1.1 unless the upstream picks h2
+            # (including when it doesn't do ALPN at all).
+            if cluster.get('auto_http_protocol', False):
+                common = envoy_ctx.setdefault('common_tls_context', {})
+                common.setdefault('alpn_protocols', [ 'h2', 'http/1.1' ])
+
+            # auto_sni takes the SNI from each request's Host; the TLS context's sni is only
+            # what we send when there's no usable Host.
+            if cluster.get('auto_sni', False):
+                upstream_options = fields.setdefault('upstream_http_protocol_options', {})
+                upstream_options['auto_sni'] = True
+
+                if cluster.get('auto_sni_fallback', None) and not envoy_ctx.get('sni', None):
+                    envoy_ctx['sni'] = cluster.auto_sni_fallback
 
             if envoy_ctx:
                 fields['transport_socket'] = {
@@ -194,6 +362,91 @@ class V3Cluster(Cacheable):
 
         self.update(fields)
 
+        cluster_protocol_options = cluster.get('cluster_protocol_options', None)
+
+        # auto_config only exists in HttpProtocolOptions, so auto_http_protocol always needs them.
+        if cluster.get('auto_http_protocol', False):
+            cluster_protocol_options = V3Cluster.deep_merge({
+                V3Cluster.HttpProtocolOptionsName: {
+                    '@type': f'type.googleapis.com/{V3Cluster.HttpProtocolOptionsName}'
+                }
+            }, cluster_protocol_options or {})
+
+        if cluster_protocol_options:
+            self.apply_protocol_options(cluster_protocol_options, auto=cluster.get('auto_http_protocol', False))
+
+    def apply_protocol_options(self, protocol_options: Dict[str, Dict[str, Any]], auto: bool=False) -> None:
+        options = { name: dict(config) for name, config in protocol_options.items() }
+        http_options = options.get(V3Cluster.HttpProtocolOptionsName, None)
+
+        if http_options is not None:
+            # Envoy won't take HttpProtocolOptions alongside the older per-cluster fields, so
+            # move what we generated into it first.
+            generated: Dict[str, Any] = {}
+            explicit: Dict[str, Any] = {}
+
+            for key in ( 'common_http_protocol_options', 'upstream_http_protocol_options' ):
+                if key in self:
+                    generated[key] = self.pop(key)
+
+            for key in ( 'http_protocol_options', 'http2_protocol_options' ):
+                if key in self:
+                    explicit[key] = self.pop(key)
+
+            # Which upstream protocol to use is a oneof that Envoy requires, and merging doesn't
+            # make sense there: if the Mapping picks one, it replaces ours outright.
+            if not any(key in http_options for key in V3Cluster.UpstreamProtocolConfigs):
+                if auto:
+                    generated['auto_config'] = {
+                        'http_protocol_options': explicit.get('http_protocol_options', {}),
+                        'http2_protocol_options': explicit.get('http2_protocol_options', {})
+                    }
+                else:
+                    generated['explicit_http_config'] = explicit or { 'http_protocol_options': {} }
+
+            options[V3Cluster.HttpProtocolOptionsName] = V3Cluster.deep_merge(generated, http_options)
+
+        self['typed_extension_protocol_options'] = options
+
+    @staticmethod
+    def deep_merge(base: Dict[str, Any], override: Dict[str, Any]) -> Dict[str, Any]:
+        merged = dict(base)
+
+        for key, value in override.items():
+            if isinstance(value, dict) and isinstance(merged.get(key, None), dict):
+                merged[key] = V3Cluster.deep_merge(merged[key], value)
+            else:
+                merged[key] = value
+
+        return merged
+
+    @staticmethod
+    def dns_cache_config(ir: 'IR') -> Dict[str, Any]:
+        # The filter and every dynamic forward proxy cluster must agree on this exactly, or
+        # Envoy will refuse the config.
+        dns_cache = ir.ambassador_module.dynamic_forward_proxy.config_dict() or {}
+
+        dns_lookup_family = 'V4_ONLY'
+
+        if ir.ambassador_module.enable_ipv6:
+            dns_lookup_family = 'AUTO' if ir.ambassador_module.enable_ipv4 else 'V6_ONLY'
+
+        config: Dict[str, Any] = {
+            'name': 'dynamic_forward_proxy_cache',
+            'dns_lookup_family': dns_lookup_family
+        }
+
+        if 'dns_refresh_rate_ms' in dns_cache:
+            config['dns_refresh_rate'] = "%0.3fs" % (float(dns_cache['dns_refresh_rate_ms']) / 1000.0)
+
+        if 'host_ttl_ms' in dns_cache:
+            config['host_ttl'] = "%0.3fs" % (float(dns_cache['host_ttl_ms']) / 1000.0)
+
+        if 'max_hosts' in dns_cache:
+            config['max_hosts'] = dns_cache['max_hosts']
+
+        return config
+
     def get_endpoints(self, cluster: IRCluster):
         result = []
 
@@ -219,12 +472,64 @@ class V3Cluster(Cacheable):
                 result.append({'endpoint': {'address': {'socket_address': address}}})
         return result
 
+    def get_health_checks(self, cluster: IRCluster) -> List[dict]:
+        health_checks = []
+
+        # The IR has already validated these and filled in the defaults.
+        for health_check in cluster.get('health_checks', None) or []:
+            envoy_health_check = {
+                'timeout': "%0.3fs" % (float(health_check['timeout_ms']) / 1000.0),
+                'interval': "%0.3fs" % (float(health_check['interval_ms']) / 1000.0),
+                'unhealthy_threshold': health_check['unhealthy_threshold'],
+                'healthy_threshold': health_check['healthy_threshold'],
+            }
+
+            if health_check['interval_jitter_ms']:
+                envoy_health_check['interval_jitter'] = "%0.3fs" % (float(health_check['interval_jitter_ms']) / 1000.0)
+
+            if health_check['interval_jitter_percent']:
+                envoy_health_check['interval_jitter_percent'] = health_check['interval_jitter_percent']
+
+            if 'http' in health_check:
+                http = health_check['http']
+                http_health_check: Dict[str, Any] = {
+                    'path': http['path']
+                }
+
+                if http.get('hostname', None):
+                    http_health_check['host'] = http['hostname']
+
+                # Envoy's ranges don't include their end.
+                expected_statuses = http.get('expected_statuses', None)
+                if expected_statuses:
+                    http_health_check['expected_statuses'] = [
+                        { 'start': status_range['min'], 'end': status_range['max'] + 1 }
+                        for status_range in expected_statuses
+                    ]
+
+                envoy_health_check['http_health_check'] = http_health_check
+            else:
+                grpc = health_check['grpc']
+                grpc_health_check: Dict[str, Any] = {}
+
+                if grpc.get('service_name', None):
+                    grpc_health_check['service_name'] = grpc['service_name']
+
+                if grpc.get('authority', None):
+                    grpc_health_check['authority'] = grpc['authority']
+
+                envoy_health_check['grpc_health_check'] = grpc_health_check
+
+            health_checks.append(envoy_health_check)
+
+        return health_checks
+
     def get_circuit_breakers(self, cluster: IRCluster):
         cluster_circuit_breakers = cluster.get('circuit_breakers', None)
         if cluster_circuit_breakers is None:
             return None
 
-        circuit_breakers: Dict[str, List[Dict[str, Union[str, int]]]] = {
+        circuit_breakers: Dict[str, List[Dict[str, Any]]] = {
             'thresholds': []
         }
 
@@ -235,11 +540,23 @@ class V3Cluster(Cacheable):
             else:
                 threshold['priority'] = 'DEFAULT'
 
-            digit_fields = ['max_connections', 'max_pending_requests', 'max_requests', 'max_retries']
+            # Note that with HTTP/2, a single connection carries many requests, so for gRPC
+            # and other HTTP/2 upstreams max_requests matters much more than max_connections.
+            digit_fields = ['max_connections', 'max_pending_requests', 'max_requests', 'max_retries',
+                            'max_connection_pools']
             for field in digit_fields:
                 if field in circuit_breaker:
                     threshold[field] = int(circuit_breaker.get(field))
 
+            # When there's a budget, Envoy ignores max_retries.
+            retry_budget = circuit_breaker.get('retry_budget', None)
+
+            if retry_budget:
+                threshold['retry_budget'] = {
+                    'budget_percent': { 'value': float(retry_budget['budget_percent']) },
+                    'min_retry_concurrency': retry_budget['min_retry_concurrency'],
+                }
+
             if len(threshold) > 0:
                 circuit_breakers['thresholds'].append(threshold)
 
@@ -258,6 +575,12 @@ class V3Cluster(Cacheable):
             cache_key = f"V3-{ircluster.cache_key}"
             cached_cluster = config.cache[cache_key]
 
+            # A cluster's envoy_name can change without its name changing (it can start
+            # or stop having a contested name, say), and then the cached one is stale.
+            if (cached_cluster is not None) and (cached_cluster.get('name', None) != ircluster.envoy_name):
+                config.cache.invalidate(cache_key)
+                cached_cluster = None
+
             if cached_cluster is None:
                 # Cache miss.
                 cluster = config.save_element('cluster', ircluster, V3Cluster(config, ircluster))
@@ -282,3 +605,4 @@ class V3Cluster(Cacheable):
 
             config.clusters.append(cluster)
             config.clustermap[ircluster.envoy_name] = ircluster.clustermap_entry()
+            config.secrets.update(cluster.secrets)
@@ -12,7 +12,7 @@
 # See the License for the specific language governing permissions and
 # limitations under the License
 
-from typing import Any, Dict, List, Optional, Tuple, Union, TYPE_CHECKING
+from typing import Any, Dict, List, Optional, Set, Tuple, Union, TYPE_CHECKING
 
 import json
 
@@ -48,6 +48,7 @@ class V3Config (EnvoyConfig):
     clusters: List[V3Cluster]
     static_resources: V3StaticResources
     clustermap: Dict[str, Any]
+    secrets: Dict[str, Dict[str, Any]]
 
     def __init__(self, ir: 'IR', cache: Optional[Cache]=None) -> None:
         ir.logger.info("EnvoyConfig: Generating V3")
@@ -58,6 +59,13 @@ class V3Config (EnvoyConfig):
         # ...then make sure we have a cache (which might be a NullCache).
         self.cache = cache or NullCache(self.ir.logger)
 
+        # The SDS secrets that listeners and clusters refer to, by name.
+        self.secrets = {}
+
+        # The notices V3Listener has posted about virtual clusters with the same name, so that
+        # each only gets posted once, however many listeners and hosts it comes up in.
+        self.virtual_cluster_notices: Set[Tuple[str, str]] = set()
+
         V3Admin.generate(self)
         V3Tracing.generate(self)
 
@@ -15,17 +15,21 @@ from typing import Any, Dict, List, Optional, Tuple, Union, TYPE_CHECKING
 from typing import cast as typecast
 
 import logging
+import re
 
 from multi import multi
 from ...ir.irauth import IRAuth
 from ...ir.irerrorresponse import IRErrorResponse
 from ...ir.irbuffer import IRBuffer
 from ...ir.irgzip import IRGzip
+from ...ir.irdecompressor import IRDecompressor
 from ...ir.irfilter import IRFilter
 from ...ir.irratelimit import IRRateLimit
 from ...ir.ircors import IRCORS
 from ...ir.ircluster import IRCluster
 
+from .v3cluster import V3Cluster
+
 from ...utils import parse_bool
 from ...utils import ParsedService as Service
 
@@ -139,6 +143,505 @@ def V3HTTPFilter_gzip(gzip: IRGzip, v3config: 'V3Config'):
         }
     }
 
+@V3HTTPFilter.when("IRDecompressor")
+def V3HTTPFilter_decompressor(decompressor: IRDecompressor, v3config: 'V3Config'):
+    del v3config  # silence unused-variable warning
+
+    request_direction_config: Dict[str, Any] = {}
+
+    if not decompressor.decompress_requests:
+        # The request direction is on unless told otherwise, and a RuntimeFeatureFlag
+        # has to have a runtime key.
+        request_direction_config['common_config'] = {
+            'enabled': {
+                'default_value': False,
+                'runtime_key': 'decompressor.request.enabled'
+            }
+        }
+
+    # Leave out whatever isn't set, so that Envoy uses its own defaults.
+    library_config: Dict[str, Any] = {
+        "@type": "type.googleapis.com/envoy.extensions.compression.gzip.decompressor.v3.Gzip",
+    }
+
+    for key in ('window_bits', 'chunk_size'):
+        if decompressor.get(key, None) is not None:
+            library_config[key] = decompressor[key]
+
+    return {
+        'name': 'envoy.filters.http.decompressor',
+        'typed_config': {
+            '@type': 'type.googleapis.com/envoy.extensions.filters.http.decompressor.v3.Decompressor',
+            'decompressor_library': {
+                "name": "envoy.compression.gzip.decompressor",
+                "typed_config": library_config
+            },
+            'request_direction_config': request_direction_config,
+        }
+    }
+
+MaxHeaderValueBytesFilterName = 'ambassador.max_header_value_bytes'
+
+@V3HTTPFilter.when("ir.max_header_value_bytes")
+def V3HTTPFilter_max_header_value_bytes(irfilter: IRFilter, v3config: 'V3Config'):
+    del v3config  # silence unused-variable warning
+
+    # Pseudo-headers are left to Envoy's own limits: :path in particular is often long.
+    inline_code = f"""
+function envoy_on_request(request_handle)
+  for key, value in pairs(request_handle:headers()) do
+    if string.sub(key, 1, 1) ~= ":" and string.len(value) > {irfilter.config['max_bytes']} then
+      request_handle:respond({{[":status"] = "431"}}, "Request Header Fields Too Large")
+      return
+    end
+  end
+end
+"""
+
+    return {
+        'name': MaxHeaderValueBytesFilterName,
+        'typed_config': {
+            '@type': 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua',
+            'inline_code': inline_code
+        }
+    }
+
+# The decompressor limit is a second buffer filter, so it needs a name of its own for routes to
+# configure it by, separate from the Module's buffer.
+DecompressorLimitFilterName = 'ambassador.decompressor_limit'
+
+@V3HTTPFilter.when("ir.decompressor_limit")
+def V3HTTPFilter_decompressor_limit(irfilter: IRFilter, v3config: 'V3Config'):
+    del v3config  # silence unused-variable warning
+
+    return {
+        'name': DecompressorLimitFilterName,
+        'typed_config': {
+            '@type': 'type.googleapis.com/envoy.extensions.filters.http.buffer.v3.Buffer',
+            'max_request_bytes': irfilter.config['max_request_bytes']
+        }
+    }
+
+# Mappings with bypass_compression need gzip to leave their responses alone, and the only
+# way to tell Envoy's compressor that on a single route is Cache-Control: no-transform. This
+# Lua filter sits right after gzip, doing nothing except on those routes, where their
+# LuaPerRoute runs CompressionExclusionLua instead.
+CompressionExclusionFilterName = 'ambassador.compression_exclusion'
+
+CompressionExclusionLua = """
+function envoy_on_response(response_handle)
+  local headers = response_handle:headers()
+  local cache_control = headers:get("cache-control")
+
+  if cache_control == nil then
+    headers:add("cache-control", "no-transform")
+  elseif not string.find(string.lower(cache_control), "no-transform", 1, true) then
+    headers:replace("cache-control", cache_control .. ", no-transform")
+  end
+end
+"""
+
+@V3HTTPFilter.when("ir.compression_exclusion")
+def V3HTTPFilter_compression_exclusion(irfilter: IRFilter, v3config: 'V3Config'):
+    del irfilter  # silence unused-variable warning
+    del v3config  # silence unused-variable warning
+
+    return {
+        'name': CompressionExclusionFilterName,
+        'typed_config': {
+            '@type': 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua',
+            'inline_code': 'function envoy_on_response(response_handle) end'
+        }
+    }
+
+# Mappings whose request_timeout_header drops or clamps x-envoy-upstream-rq-timeout-ms get
+# their LuaPerRoute from request_timeout_header_lua; the filter right before the router
+# does nothing anywhere else.
+RequestTimeoutHeaderFilterName = 'ambassador.request_timeout_header'
+
+def request_timeout_header_lua(policy: Dict[str, Any]) -> str:
+    if not policy.get('honor', True):
+        return """
+function envoy_on_request(request_handle)
+  request_handle:headers():remove("x-envoy-upstream-rq-timeout-ms")
+end
+"""
+
+    # Envoy ignores a timeout it can't parse, and takes 0 to mean no timeout at all.
+    return """
+function envoy_on_request(request_handle)
+  local headers = request_handle:headers()
+  local timeout = headers:get("x-envoy-upstream-rq-timeout-ms")
+
+  if timeout ~= nil then
+    local ms = tonumber(timeout)
+
+    if ms == nil then
+      headers:remove("x-envoy-upstream-rq-timeout-ms")
+    elseif ms == 0 or ms > %d then
+      headers:replace("x-envoy-upstream-rq-timeout-ms", "%d")
+    end
+  end
+end
+""" % (policy['max_ms'], policy['max_ms'])
+
+@V3HTTPFilter.when("ir.request_timeout_header")
+def V3HTTPFilter_request_timeout_header(irfilter: IRFilter, v3config: 'V3Config'):
+    del irfilter  # silence unused-variable warning
+    del v3config  # silence unused-variable warning
+
+    return {
+        'name': RequestTimeoutHeaderFilterName,
+        'typed_config': {
+            '@type': 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua',
+            'inline_code': 'function envoy_on_request(request_handle) end'
+        }
+    }
+
+# Mappings with log_sampling get their LuaPerRoute from log_sampling_lua, which marks each of
+# their requests in its dynamic metadata. That's all an access log filter can see of which
+# route a request took, so log_sampling_access_log_filter picks out each Mapping's that way.
+LogSamplingFilterName = 'ambassador.log_sampling'
+
+def log_sampling_lua(policy: Dict[str, Any]) -> str:
+    return """
+function envoy_on_request(request_handle)
+  request_handle:streamInfo():dynamicMetadata():set("%s", "mapping", "%s")
+end
+""" % (LogSamplingFilterName, policy['id'])
+
+def log_sampling_access_log_filter(policies: List[Dict[str, Any]]) -> Dict[str, Any]:
+    def marked(value: Dict[str, Any]) -> Dict[str, Any]:
+        return {
+            'filter': LogSamplingFilterName,
+            'path': [ { 'key': 'mapping' } ],
+            'value': value,
+        }
+
+    # A request that isn't marked -- one for a Mapping without log_sampling, or one that
+    # never got as far as a route -- is always logged: the null_match never matches the
+    # string a mark is, and without a mark it's up to match_if_key_not_found.
+    filters: List[Dict[str, Any]] = [
+        {
+            'metadata_filter': {
+                'matcher': marked({ 'null_match': {} }),
+                'match_if_key_not_found': True,
+            }
+        }
+    ]
+
+    # A marked one is logged if it didn't succeed, if Envoy flagged it, or if it's sampled.
+    # The flags catch a streamed response (or anything else long-running) that was cut off
+    # after its 200 went out. Access logs are written when the request ends, so that's also
+    # when a long one is sampled. Sampling goes by the request ID, as tracing does.
+    for policy in policies:
+        filters.append({
+            'and_filter': {
+                'filters': [
+                    {
+                        'metadata_filter': {
+                            'matcher': marked({ 'string_match': { 'exact': policy['id'] } }),
+                            'match_if_key_not_found': False,
+                        }
+                    },
+                    {
+                        'or_filter': {
+                            'filters': [
+                                {
+                                    'status_code_filter': {
+                                        'comparison': {
+                                            'op': 'GE',
+                                            'value': {
+                                                'default_value': policy['always_log_status'],
+                                                'runtime_key': f"log_sampling.{policy['id']}.always_log_status",
+                                            }
+                                        }
+                                    }
+                                },
+                                { 'response_flag_filter': {} },
+                                {
+                                    'runtime_filter': {
+                                        'runtime_key': f"log_sampling.{policy['id']}.percent",
+                                        'percent_sampled': {
+                                            'numerator': policy['percent'],
+                                            'denominator': 'HUNDRED',
+                                        },
+                                    }
+                                },
+                            ]
+                        }
+                    },
+                ]
+            }
+        })
+
+    return { 'or_filter': { 'filters': filters } }
+
+@V3HTTPFilter.when("ir.log_sampling")
+def V3HTTPFilter_log_sampling(irfilter: IRFilter, v3config: 'V3Config'):
+    del irfilter  # silence unused-variable warning
+    del v3config  # silence unused-variable warning
+
+    return {
+        'name': LogSamplingFilterName,
+        'typed_config': {
+            '@type': 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua',
+            'inline_code': 'function envoy_on_request(request_handle) end'
+        }
+    }
+
+# Mappings with cookie_attributes get their LuaPerRoute from cookie_attributes_lua, which
+# rewrites every Set-Cookie header in the response. Lua's headers:get() only returns the first
+# one, so it walks them all, then puts them back with whatever attributes were missing.
+CookieAttributesFilterName = 'ambassador.cookie_attributes'
+
+def cookie_attributes_lua(policy: Dict[str, Any]) -> str:
+    secure = 'true' if policy.get('secure', False) else 'false'
+    same_site = policy.get('same_site', None)
+    same_site_lua = f'"{same_site}"' if same_site else 'nil'
+
+    return """
+local secure = %s
+local same_site = %s
+
+function envoy_on_response(response_handle)
+  local headers = response_handle:headers()
+  local cookies = {}
+
+  for key, value in pairs(headers) do
+    if key == "set-cookie" then
+      table.insert(cookies, value)
+    end
+  end
+
+  if #cookies == 0 then
+    return
+  end
+
+  headers:remove("set-cookie")
+
+  for _, cookie in ipairs(cookies) do
+    -- Only look at the attributes: the cookie's value could say anything.
+    local attributes = string.lower(string.match(cookie, ";.*$") or "") .. ";"
+
+    if secure and not string.find(attributes, ";%%s*secure%%s*;") then
+      cookie = cookie .. "; Secure"
+    end
+
+    if same_site and not string.find(attributes, ";%%s*samesite%%s*=") then
+      cookie = cookie .. "; SameSite=" .. same_site
+    end
+
+    headers:add("set-cookie", cookie)
+  end
+end
+""" % (secure, same_site_lua)
+
+@V3HTTPFilter.when("ir.cookie_attributes")
+def V3HTTPFilter_cookie_attributes(irfilter: IRFilter, v3config: 'V3Config'):
+    del irfilter  # silence unused-variable warning
+    del v3config  # silence unused-variable warning
+
+    return {
+        'name': CookieAttributesFilterName,
+        'typed_config': {
+            '@type': 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua',
+            'inline_code': 'function envoy_on_response(response_handle) end'
+        }
+    }
+
+# Mappings with body_transform get their LuaPerRoute from body_transform_lua. The template
+# is split around its {{ body }}s ahead of time, so that the Lua only has to table.concat the
+# pieces back together with the original body in between.
+BodyTransformFilterName = 'ambassador.body_transform'
+
+BodyTransformPlaceholder = re.compile(r'\{\{\s*body\s*\}\}')
+
+def lua_string(value: str) -> str:
+    """
+    Quote a string for Lua 5.1, which doesn't have \\u escapes: anything that isn't printable
+    ASCII goes in as three-digit decimal byte escapes, so that a digit after one can't be
+    mistaken for part of it.
+    """
+    out = []
+
+    for byte in value.encode('utf-8'):
+        char = chr(byte)
+
+        if char in '"\\':
+            out.append('\\' + char)
+        elif 0x20 <= byte < 0x7f:
+            out.append(char)
+        else:
+            out.append('\\%03d' % byte)
+
+    return '"' + ''.join(out) + '"'
+
+def body_transform_lua(policy: Dict[str, Any]) -> str:
+    def lua_parts(template: Optional[str]) -> str:
+        if template is None:
+            return 'nil'
+
+        return '{ ' + ', '.join([ lua_string(part) for part in BodyTransformPlaceholder.split(template) ]) + ' }'
+
+    content_types = ', '.join([ '[%s] = true' % lua_string(ct) for ct in policy['content_types'] ])
+
+    return """
+local request_parts = %s
+local response_parts = %s
+local content_types = { %s }
+local max_bytes = %d
+
+local function transformable(headers)
+  if headers:get("content-encoding") ~= nil then
+    return false
+  end
+
+  local content_type = headers:get("content-type")
+
+  if content_type == nil then
+    return false
+  end
+
+  local media_type = string.lower(string.match(content_type, "^%%s*([^;%%s]+)") or "")
+  local length = tonumber(headers:get("content-length"))
+
+  return content_types[media_type] and length ~= nil and length > 0 and length <= max_bytes
+end
+
+local function transform(handle, parts)
+  local headers = handle:headers()
+
+  if parts == nil or not transformable(headers) then
+    return
+  end
+
+  local body = handle:body()
+
+  if body == nil then
+    return
+  end
+
+  local transformed = table.concat(parts, body:getBytes(0, body:length()))
+
+  body:setBytes(transformed)
+  headers:replace("content-length", tostring(#transformed))
+end
+
+function envoy_on_request(request_handle)
+  transform(request_handle, request_parts)
+end
+
+function envoy_on_response(response_handle)
+  transform(response_handle, response_parts)
+end
+""" % (lua_parts(policy['request']), lua_parts(policy['response']), content_types, policy['max_bytes'])
+
+@V3HTTPFilter.when("ir.body_transform")
+def V3HTTPFilter_body_transform(irfilter: IRFilter, v3config: 'V3Config'):
+    del irfilter  # silence unused-variable warning
+    del v3config  # silence unused-variable warning
+
+    return {
+        'name': BodyTransformFilterName,
+        'typed_config': {
+            '@type': 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua',
+            'inline_code': 'function envoy_on_request(request_handle) end'
+        }
+    }
+
+# Each JWT provider's payload goes into this filter's metadata under the provider's name,
+# which is where JWTClaimsFilterName's RBAC policies look for claims.
+JWTAuthnFilterName = 'envoy.filters.http.jwt_authn'
+
+@V3HTTPFilter.when("ir.jwt_authn")
+def V3HTTPFilter_jwt_authn(irfilter: IRFilter, v3config: 'V3Config'):
+    del irfilter  # silence unused-variable warning
+
+    providers: Dict[str, Any] = {}
+
+    for name, provider in v3config.ir.ambassador_module.jwt_providers.items():
+        config: Dict[str, Any] = {
+            'payload_in_metadata': name,
+            'forward': provider.get('forward', False),
+        }
+
+        if provider.get('issuer', None):
+            config['issuer'] = provider['issuer']
+
+        if provider.get('audiences', None):
+            config['audiences'] = provider['audiences']
+
+        if provider.get('jwks_uri', None):
+            config['remote_jwks'] = {
+                'http_uri': {
+                    'uri': provider['jwks_uri'],
+                    'cluster': v3config.ir.clusters[provider['cluster']].envoy_name,
+                    'timeout': '5s',
+                },
+            }
+        else:
+            config['local_jwks'] = { 'inline_string': provider['jwks'] }
+
+        providers[name] = config
+
+    # With no rules, the filter only checks JWTs on routes that name one of these.
+    return {
+        'name': JWTAuthnFilterName,
+        'typed_config': {
+            '@type': 'type.googleapis.com/envoy.extensions.filters.http.jwt_authn.v3.JwtAuthentication',
+            'providers': providers,
+            'requirement_map': { name: { 'provider_name': name } for name in providers.keys() },
+        }
+    }
+
+# Mappings with jwt claims get an RBACPerRoute from jwt_claims_rbac. This is a second RBAC
+# filter, separate from ip_allow and ip_deny, and with no rules it allows everything
+# everywhere else.
+JWTClaimsFilterName = 'ambassador.jwt_claims'
+
+def jwt_claims_rbac(policy: Dict[str, Any]) -> Dict[str, Any]:
+    """
+    Return the RBAC rules that allow only requests whose JWT has every one of policy's
+    claims. A claim matches if it's one of its values, or, if the claim is an array, if any
+    element is; a missing claim never matches.
+    """
+    principals: List[Dict[str, Any]] = []
+
+    for claim in policy['claims']:
+        path = [ { 'key': policy['provider'] }, { 'key': claim['name'] } ]
+        ids: List[Dict[str, Any]] = []
+
+        for value in claim['values']:
+            string_match = { 'string_match': { 'exact': value } }
+
+            ids.append({ 'metadata': { 'filter': JWTAuthnFilterName, 'path': path, 'value': string_match } })
+            ids.append({ 'metadata': { 'filter': JWTAuthnFilterName, 'path': path, 'value': { 'list_match': { 'one_of': string_match } } } })
+
+        principals.append({ 'or_ids': { 'ids': ids } })
+
+    return {
+        'action': 'ALLOW',
+        'policies': {
+            'ambassador-jwt-claims': {
+                'permissions': [ { 'any': True } ],
+                'principals': [ principals[0] if len(principals) == 1 else { 'and_ids': { 'ids': principals } } ],
+            }
+        }
+    }
+
+@V3HTTPFilter.when("ir.jwt_claims")
+def V3HTTPFilter_jwt_claims(irfilter: IRFilter, v3config: 'V3Config'):
+    del irfilter  # silence unused-variable warning
+    del v3config  # silence unused-variable warning
+
+    return {
+        'name': JWTClaimsFilterName,
+        'typed_config': {
+            '@type': 'type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBAC',
+        }
+    }
+
 @V3HTTPFilter.when("ir.grpc_http1_bridge")
 def V3HTTPFilter_grpc_http1_bridge(irfilter: IRFilter, v3config: 'V3Config'):
     del irfilter  # silence unused-variable warning
@@ -170,6 +673,57 @@ def V3HTTPFilter_grpc_stats(irfilter: IRFilter, v3config: 'V3Config'):
         }
     }
 
+@V3HTTPFilter.when("ir.concurrency_limit")
+def V3HTTPFilter_concurrency_limit(irfilter: IRFilter, v3config: 'V3Config'):
+    del v3config  # silence unused-variable warning
+
+    config = irfilter.config
+
+    def percent(key: str) -> Dict[str, float]:
+        return { 'value': float(config[key]) }
+
+    def seconds(key: str) -> str:
+        return "%0.3fs" % (config[key] / 1000.0)
+
+    return {
+        'name': 'envoy.filters.http.adaptive_concurrency',
+        'typed_config': {
+            '@type': 'type.googleapis.com/envoy.extensions.filters.http.adaptive_concurrency.v3.AdaptiveConcurrency',
+            'gradient_controller_config': {
+                'sample_aggregate_percentile': percent('sample_percentile'),
+                'concurrency_limit_params': {
+                    'max_concurrency_limit': config['max_concurrency'],
+                    'concurrency_update_interval': seconds('update_interval_ms')
+                },
+                'min_rtt_calc_params': {
+                    'interval': seconds('min_rtt_interval_ms'),
+                    'request_count': config['min_rtt_request_count'],
+                    'jitter': percent('min_rtt_jitter_percent'),
+                    'min_concurrency': config['min_concurrency'],
+                    'buffer': percent('min_rtt_buffer_percent')
+                }
+            },
+            # So that it can be turned off in a hurry, without a new configuration.
+            'enabled': {
+                'default_value': True,
+                'runtime_key': 'adaptive_concurrency.enabled'
+            }
+        }
+    }
+
+@V3HTTPFilter.when("ir.dynamic_forward_proxy")
+def V3HTTPFilter_dynamic_forward_proxy(irfilter: IRFilter, v3config: 'V3Config'):
+    del v3config  # silence unused-variable warning
+
+    # This only does anything for routes to a dynamic forward proxy cluster.
+    return {
+        'name': 'envoy.filters.http.dynamic_forward_proxy',
+        'typed_config': {
+            '@type': 'type.googleapis.com/envoy.extensions.filters.http.dynamic_forward_proxy.v3.FilterConfig',
+            'dns_cache_config': V3Cluster.dns_cache_config(irfilter.ir)
+        }
+    }
+
 def auth_cluster_uri(auth: IRAuth, cluster: IRCluster) -> str:
     cluster_context = cluster.get('tls_context')
     scheme = 'https' if cluster_context else 'http'
@@ -11,21 +11,24 @@
 # WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 # See the License for the specific language governing permissions and
 # limitations under the License
-from typing import Any, Dict, List, Optional, Tuple, TYPE_CHECKING
+from typing import Any, Dict, List, Optional, Set, Tuple, TYPE_CHECKING
 from typing import cast as typecast
 
 from os import environ
 
 import logging
+import re
 import sys
 
 from ...ir.irhost import IRHost
+from ...ir.irhttpmapping import IRHTTPMapping
 from ...ir.irlistener import IRListener
 from ...ir.irtcpmappinggroup import IRTCPMappingGroup
 
 from ...utils import dump_json, parse_bool
+from ...VERSION import Version
 
-from .v3httpfilter import V3HTTPFilter
+from .v3httpfilter import V3HTTPFilter, log_sampling_access_log_filter
 from .v3route import V3Route, DictifiedV3Route, V3RouteVariants, v3prettyroute, hostglob_matches
 from .v3tls import V3TLSContext
 
@@ -34,6 +37,9 @@ if TYPE_CHECKING:
     from ...ir.irtlscontext import IRTLSContext # pragma: no cover
     from . import V3Config                      # pragma: no cover
 
+# The access log command for the request ID. (Envoy's format commands are upper case.)
+RequestIDLogOperator = '%REQ(X-REQUEST-ID)%'
+
 
 # Model an Envoy filter chain.
 #
@@ -136,10 +142,12 @@ class V3Listener(dict):
         self.listener_filters: List[dict] = []
         self.traffic_direction: str = "UNSPECIFIED"
         self.per_connection_buffer_limit_bytes: Optional[int] = None
+        self.socket_options: List[dict] = []
         self._irlistener = irlistener   # We cache the IRListener to use its match method later
         self._stats_prefix = irlistener.statsPrefix
         self._security_model: str = irlistener.securityModel
         self._l7_depth: int = irlistener.get('l7Depth', 0)
+        self._destination_port: Optional[int] = irlistener.get('destinationPort', None)
         self._insecure_only: bool = False
         self._filter_chains: List[dict] = []
         self._base_http_config: Optional[Dict[str, Any]] = None
@@ -160,6 +168,22 @@ class V3Listener(dict):
         if buffer_limit_bytes:
             self.per_connection_buffer_limit_bytes = buffer_limit_bytes
 
+        # A Listener taking large uploads can have a buffer of its own.
+        upload_buffering = irlistener.get('uploadBuffering', None) or {}
+
+        if upload_buffering.get('connectionBufferBytes', None):
+            self.per_connection_buffer_limit_bytes = upload_buffering['connectionBufferBytes']
+
+        # Downstream keepalive can be set on the Listener itself, falling back to the
+        # Module's downstream_keepalive.
+        keepalive = irlistener.get('keepAlive', None)
+
+        if keepalive is None:
+            keepalive = self.config.ir.ambassador_module.get('downstream_keepalive', None)
+
+        if keepalive is not None:
+            self.socket_options = self.keepalive_socket_options(keepalive)
+
         # Build out our listener filters, and figure out if we're an HTTP listener
         # in the process.
         for proto in irlistener.protocolStack:
@@ -199,6 +223,68 @@ class V3Listener(dict):
 
                     self.add_tcp_group(irgroup)
 
+    # Linux values for the socket options we use to configure downstream keepalive. Envoy
+    # passes these straight to setsockopt(), so they're only correct on Linux -- which is
+    # the only platform we ship Envoy for.
+    SOL_SOCKET = 1
+    SO_KEEPALIVE = 9
+    IPPROTO_TCP = 6
+    TCP_KEEPIDLE = 4
+    TCP_KEEPINTVL = 5
+    TCP_KEEPCNT = 6
+
+    @staticmethod
+    def envoy_custom_tag(custom_tag: Dict[str, Any]) -> Dict[str, Any]:
+        # IRTracing has already made sure there's exactly one source.
+        if 'metadata' in custom_tag:
+            metadata = custom_tag['metadata']
+            envoy_metadata: Dict[str, Any] = {
+                'kind': { metadata['kind']: {} },
+                'metadata_key': {
+                    'key': metadata['key'],
+                    'path': [ { 'key': key } for key in metadata['path'] ]
+                }
+            }
+
+            if metadata.get('default_value', None):
+                envoy_metadata['default_value'] = metadata['default_value']
+
+            return { 'tag': custom_tag['tag'], 'metadata': envoy_metadata }
+
+        source = [ key for key in custom_tag.keys() if key != 'tag' ][0]
+
+        return { 'tag': custom_tag['tag'], source: dict(custom_tag[source]) }
+
+    def keepalive_socket_options(self, keepalive: dict) -> List[dict]:
+        # Note that keepalive probes don't count as activity as far as Envoy's idle timeouts
+        # (listener_idle_timeout_ms) are concerned: the idle timeout will still close a
+        # connection with no requests on it, however often we probe it.
+        def sockopt(level: int, name: int, value: int) -> dict:
+            return {
+                'level': level,
+                'name': name,
+                'int_value': value,
+                'state': 'STATE_LISTENING'
+            }
+
+        options = [ sockopt(self.SOL_SOCKET, self.SO_KEEPALIVE, 1) ]
+
+        for key, optname in [ ('idle_time', self.TCP_KEEPIDLE),
+                              ('interval', self.TCP_KEEPINTVL),
+                              ('probes', self.TCP_KEEPCNT) ]:
+            value = keepalive.get(key, None)
+
+            if value is None:
+                continue
+
+            if isinstance(value, bool) or not isinstance(value, int) or (value <= 0):
+                self.config.ir.post_error(f"Listener {self.name}: keepalive {key} must be a positive integer, ignoring")
+                continue
+
+            options.append(sockopt(self.IPPROTO_TCP, optname, value))
+
+        return options
+
     def add_chain(self, chain_type: str, host: Optional[IRHost]) -> V3Chain:
         # Add a chain for a specific Host to this listener, while dealing with the fundamental
         # asymmetry that filter_chain_match can - and should - use SNI whenever the chain has
@@ -309,6 +395,10 @@ class V3Listener(dict):
                     "typed_config": access_log_obj
                 })
 
+        # With correlate_request_id, a custom log format that leaves out the request ID gets it
+        # tacked on the end, so that every line can be matched up with its trace.
+        correlate_request_id = self.config.ir.ambassador_module.get('correlate_request_id', False)
+
         # Use sane access log spec in JSON
         if self.config.ir.ambassador_module.envoy_log_type.lower() == "json":
             log_format = self.config.ir.ambassador_module.get('envoy_log_format', None)
@@ -342,6 +432,10 @@ class V3Listener(dict):
                 if tracing_config and tracing_config.driver == 'envoy.tracers.datadog':
                     log_format['dd.trace_id'] = '%REQ(X-DATADOG-TRACE-ID)%'
                     log_format['dd.span_id'] = '%REQ(X-DATADOG-PARENT-ID)%'
+            elif correlate_request_id:
+                if not any([ RequestIDLogOperator in str(v).upper() for v in log_format.values() ]):
+                    log_format = dict(log_format)
+                    log_format['request_id'] = RequestIDLogOperator
 
             access_log.append({
                 'name': 'envoy.access_loggers.file',
@@ -357,6 +451,8 @@ class V3Listener(dict):
 
             if not log_format:
                 log_format = 'ACCESS [%START_TIME%] \"%REQ(:METHOD)% %REQ(X-ENVOY-ORIGINAL-PATH?:PATH)% %PROTOCOL%\" %RESPONSE_CODE% %RESPONSE_FLAGS% %BYTES_RECEIVED% %BYTES_SENT% %DURATION% %RESP(X-ENVOY-UPSTREAM-SERVICE-TIME)% \"%REQ(X-FORWARDED-FOR)%\" \"%REQ(USER-AGENT)%\" \"%REQ(X-REQUEST-ID)%\" \"%REQ(:AUTHORITY)%\" \"%UPSTREAM_HOST%\"'
+            elif correlate_request_id and (RequestIDLogOperator not in log_format.upper()):
+                log_format += f' \"{RequestIDLogOperator}\"'
 
             if self._log_debug:
                 self.config.ir.logger.debug("V3Listener: Using log_format '%s'" % log_format)
@@ -373,8 +469,50 @@ class V3Listener(dict):
                 }
             })
 
+        # Mappings with log_sampling share the access logs with everything else, the
+        # LogServices' too, so every one of them gets the same filter.
+        log_sampling = [ policy for policy in [ mapping.log_sampling_policy() for group in self.config.ir.groups.values()
+                                                for mapping in group.mappings if isinstance(mapping, IRHTTPMapping) ]
+                         if policy ]
+
+        if log_sampling:
+            log_filter = log_sampling_access_log_filter(sorted(log_sampling, key=lambda policy: policy['id']))
+
+            for al in access_log:
+                al['filter'] = log_filter
+
         return access_log
 
+    # tune_upload_buffering adjusts this V3Listener's HTTP filters for its uploadBuffering.
+    # Envoy holds on to a request body in two places: the Module's buffer filter, which
+    # waits for all of it, and ext_authz with include_body, which waits for the first
+    # max_bytes of it. Either one turns away a body too big for the connection's buffer with
+    # a 413, so a streaming Listener drops the first and only lets the second have part of
+    # the body.
+    def tune_upload_buffering(self, http_filters: List[Dict[str, Any]]) -> None:
+        upload_buffering = self._irlistener.get('uploadBuffering', None) or {}
+        irlistener = self._irlistener
+
+        if upload_buffering.get('stream', False):
+            for v3hf in list(http_filters):
+                if v3hf['name'] == 'envoy.filters.http.buffer':
+                    http_filters.remove(v3hf)
+                    self.config.ir.aconf.post_notice(f"Listener {irlistener.name}: uploadBuffering stream doesn't buffer whole requests, so the Module's buffer does nothing here", resource=irlistener)
+
+                body = v3hf.get('typed_config', {}).get('with_request_body', None)
+
+                if (v3hf['name'] == 'envoy.filters.http.ext_authz') and body and not body.get('allow_partial_message', False):
+                    body['allow_partial_message'] = True
+                    self.config.ir.aconf.post_notice(f"Listener {irlistener.name}: uploadBuffering stream sends only the first {body.get('max_request_bytes', 0)} bytes of each body to the AuthService", resource=irlistener)
+        elif self.per_connection_buffer_limit_bytes:
+            for v3hf in http_filters:
+                body = v3hf.get('typed_config', {}).get('with_request_body', None)
+
+                if (v3hf['name'] == 'envoy.filters.http.ext_authz') and body and \
+                   not body.get('allow_partial_message', False) and \
+                   (body.get('max_request_bytes', 0) > self.per_connection_buffer_limit_bytes):
+                    self.config.ir.aconf.post_notice(f"Listener {irlistener.name}: the AuthService's include_body max_bytes {body['max_request_bytes']} is more than the connection buffer of {self.per_connection_buffer_limit_bytes} bytes, so bigger bodies get a 413", resource=irlistener)
+
     # base_http_config constructs the starting configuration for this
     # V3Listener's http_connection_manager filter.
     def base_http_config(self) -> Dict[str, Any]:
@@ -397,6 +535,14 @@ class V3Listener(dict):
             if v3hf:
                 base_http_config['http_filters'].append(v3hf)
 
+        self.tune_upload_buffering(base_http_config['http_filters'])
+
+        # Envoy's codec_type enum uses the same names as the Listener's codecType.
+        codec_type = self._irlistener.get('codecType', None)
+
+        if codec_type:
+            base_http_config["codec_type"] = codec_type
+
         if 'use_remote_address' in self.config.ir.ambassador_module:
             base_http_config["use_remote_address"] = self.config.ir.ambassador_module.use_remote_address
 
@@ -419,15 +565,60 @@ class V3Listener(dict):
             else:
                 base_http_config["common_http_protocol_options"] = { 'headers_with_underscores_action': self.config.ir.ambassador_module.headers_with_underscores_action }
 
+        # The request headers timeout is what stops a slowloris client from holding a
+        # connection open by trickling its headers in. The Listener's own setting wins over
+        # the Module's; 0 (or neither) leaves it off, as Envoy does by default.
+        request_headers_timeout_ms = self._irlistener.get('requestHeadersTimeoutMs', None)
+
+        if request_headers_timeout_ms is None:
+            request_headers_timeout_ms = self.config.ir.ambassador_module.get('request_headers_timeout_ms', None)
+
+        if request_headers_timeout_ms:
+            base_http_config["request_headers_timeout"] = "%0.3fs" % (float(request_headers_timeout_ms) / 1000.0)
+
         max_request_headers_kb = self.config.ir.ambassador_module.get('max_request_headers_kb', None)
         if max_request_headers_kb:
             base_http_config["max_request_headers_kb"] = max_request_headers_kb
 
+        # max_request_headers_kb limits the total size of all the request headers; max_headers_count
+        # limits how many there can be. Envoy has no separate limit on a single header value, so
+        # max_header_value_bytes is a filter of its own (see IRAmbassador). Any of them answers a
+        # request over the limit with a 431.
+        max_headers_count = self.config.ir.ambassador_module.get('max_headers_count', None)
+        if max_headers_count:
+            http_options = base_http_config.setdefault("common_http_protocol_options", {})
+            http_options['max_headers_count'] = max_headers_count
+
         if 'enable_http10' in self.config.ir.ambassador_module:
             http_options = base_http_config.setdefault("http_protocol_options", {})
             http_options['accept_http_10'] = self.config.ir.ambassador_module.enable_http10
 
-        if 'allow_chunked_length' in self.config.ir.ambassador_module:
+        # HTTP/1.1 trailers are dropped unless we ask for them. (HTTP/2 trailers, which is
+        # where gRPC puts its status, are always forwarded.)
+        if 'enable_trailers' in self.config.ir.ambassador_module:
+            http_options = base_http_config.setdefault("http_protocol_options", {})
+            http_options['enable_trailers'] = bool(self.config.ir.ambassador_module.enable_trailers)
+
+        # Request smuggling depends on two proxies disagreeing about where one request ends and
+        # the next begins, so "strict" (the default) turns away a request with both
+        # Content-Length and Transfer-Encoding, and closes the connection after any request
+        # that can't be parsed rather than carrying on with whatever follows it. "legacy" strips
+        # the Content-Length and keeps the connection open where it can, for clients that need
+        # it. The Listener's setting wins over the Module's, including its allow_chunked_length.
+        # Strict is what Envoy does anyway, so only legacy needs saying.
+        request_smuggling_protection = self._irlistener.get('requestSmugglingProtection', None)
+        listener_smuggling_protection = request_smuggling_protection is not None
+
+        if not listener_smuggling_protection:
+            request_smuggling_protection = self.config.ir.ambassador_module.get('request_smuggling_protection', None) or 'strict'
+
+        if request_smuggling_protection == 'legacy':
+            http_options = base_http_config.setdefault("http_protocol_options", {})
+            http_options['allow_chunked_length'] = True
+            http_options['override_stream_error_on_invalid_http_message'] = True
+            base_http_config["stream_error_on_invalid_http_message"] = True
+
+        if ('allow_chunked_length' in self.config.ir.ambassador_module) and not listener_smuggling_protection:
             if self.config.ir.ambassador_module.allow_chunked_length != None:
                 http_options = base_http_config.setdefault("http_protocol_options", {})
                 http_options['allow_chunked_length'] = self.config.ir.ambassador_module.allow_chunked_length
@@ -441,6 +632,13 @@ class V3Listener(dict):
         if 'set_current_client_cert_details' in self.config.ir.ambassador_module:
             base_http_config["set_current_client_cert_details"] = self.config.ir.ambassador_module.set_current_client_cert_details
 
+        correlate_request_id = self.config.ir.ambassador_module.get('correlate_request_id', False)
+
+        if correlate_request_id:
+            # A request that shows up without an ID (or whose ID we don't keep) gets one, so
+            # there's always something to correlate on.
+            base_http_config["generate_request_id"] = True
+
         if self.config.ir.tracing:
             base_http_config["generate_request_id"] = True
 
@@ -448,6 +646,11 @@ class V3Listener(dict):
             self.traffic_direction = "OUTBOUND"
 
             req_hdrs = self.config.ir.tracing.get('tag_headers', [])
+            custom_tags = self.config.ir.tracing.get('custom_tags', [])
+            custom_tag_names = [ custom_tag['tag'] for custom_tag in custom_tags ]
+
+            # A custom tag replaces a tag_headers tag of the same name.
+            req_hdrs = [ hdr for hdr in req_hdrs if hdr not in custom_tag_names ]
 
             if req_hdrs:
                 base_http_config["tracing"]["custom_tags"] = []
@@ -460,6 +663,23 @@ class V3Listener(dict):
                     }
                     base_http_config["tracing"]["custom_tags"].append(custom_tag)
 
+            # Envoy leaves a tag off any span it has no value for (a missing header, say),
+            # unless there's a default_value.
+            for custom_tag in custom_tags:
+                base_http_config["tracing"].setdefault("custom_tags", []).append(V3Listener.envoy_custom_tag(custom_tag))
+
+            # Tag every span with the same request ID the access log shows, under the same name
+            # as the JSON access log uses, unless tag_headers already has it covered.
+            if correlate_request_id and ('x-request-id' not in [ hdr.lower() for hdr in req_hdrs ]) and \
+               ('request_id' not in custom_tag_names):
+                base_http_config["tracing"].setdefault("custom_tags", []).append({
+                    "request_header": {
+                        "name": "x-request-id",
+                        "default_value": "-"
+                    },
+                    "tag": "request_id"
+                })
+
 
             sampling = self.config.ir.tracing.get('sampling', {})
             if sampling:
@@ -564,6 +784,119 @@ class V3Listener(dict):
             # TCP is a lot simpler.
             self.finalize_tcp()
 
+        # If we're only handling one destination port, every chain has to match on it. The
+        # original_dst listener filter makes sure that we match the port the client actually
+        # connected to rather than the one it was redirected to.
+        if self._destination_port:
+            for filter_chain in self._filter_chains:
+                filter_chain['filter_chain_match']['destination_port'] = self._destination_port
+
+            self.listener_filters.insert(0, {
+                'name': 'envoy.filters.listener.original_dst'
+            })
+
+        # A connectionRateLimit goes ahead of everything else in every chain, so a connection
+        # over the limit is closed before it costs us a TLS handshake or an HTTP codec. Envoy
+        # gives each filter chain a token bucket of its own, though, with nothing in this API
+        # to make chains share one, so the Listener's rate and burst are split between its
+        # chains: however the connections are spread across its Hosts, together they never get
+        # more than the one limit.
+        connection_rate_limit = self._irlistener.get('connectionRateLimit', None)
+
+        if connection_rate_limit and self._filter_chains:
+            chains = len(self._filter_chains)
+            connections_per_second = connection_rate_limit['connectionsPerSecond']
+            burst = connection_rate_limit.get('burst', connections_per_second)
+
+            if connections_per_second < chains:
+                self.config.ir.aconf.post_notice(f"Listener {self._irlistener.name}: connectionRateLimit connectionsPerSecond {connections_per_second} is less than its {chains} filter chains, so each gets 1 connection per second", resource=self._irlistener)
+
+            for index, filter_chain in enumerate(self._filter_chains):
+                filter_chain['filters'].insert(0, self.connection_rate_limit_filter(
+                    V3Listener.share_of(connections_per_second, chains, index),
+                    V3Listener.share_of(burst, chains, index)))
+
+    # share_of is how much of total the index'th of count filter chains gets: an even split,
+    # with the remainder going one apiece to the first chains, so the shares add up to total.
+    # Envoy won't take an empty token bucket, so every chain gets at least one.
+    @staticmethod
+    def share_of(total: int, count: int, index: int) -> int:
+        share = total // count
+
+        if index < (total % count):
+            share += 1
+
+        return max(share, 1)
+
+    # connection_rate_limit_filter builds the network filter for one chain's share of this
+    # V3Listener's connectionRateLimit. Envoy's token bucket can refill at most every 50ms,
+    # and only with whole tokens, so refilling a second's worth once a second is what keeps
+    # it exact.
+    def connection_rate_limit_filter(self, connections_per_second: int, burst: int) -> Dict[str, Any]:
+        return {
+            'name': 'envoy.filters.network.local_ratelimit',
+            'typed_config': {
+                '@type': 'type.googleapis.com/envoy.extensions.filters.network.local_ratelimit.v3.LocalRateLimit',
+                'stat_prefix': self._stats_prefix,
+                'token_bucket': {
+                    'max_tokens': burst,
+                    'tokens_per_fill': connections_per_second,
+                    'fill_interval': '1s'
+                }
+            }
+        }
+
+    # unique_virtual_cluster returns vc, unless virtual_clusters already has one by that name
+    # for a different Mapping (they have the same stats_name, say). Envoy would count the one
+    # Mapping's requests in the other's, so then it's a copy of vc with the Mapping's name and
+    # namespace in its name too, ahead of any stats_dimensions.
+    def unique_virtual_cluster(self, vc: Dict[str, Any], virtual_clusters: Dict[str, dict]) -> Dict[str, Any]:
+        extant = virtual_clusters.get(vc['name'], None)
+
+        if (extant is None) or (extant['_mapping'].rkey == vc['_mapping'].rkey):
+            return vc
+
+        mapping = vc['_mapping']
+        base = vc['_base']
+        dimensions = vc['name'][len(base):]
+        unique = re.sub(r'[^0-9A-Za-z_]', '_', f"{base}_{mapping.name}_{mapping.namespace}")
+
+        notice = f"virtual cluster {base} is already {extant['_mapping'].name}.{extant['_mapping'].namespace}'s, so this Mapping's is {unique}"
+
+        if (mapping.rkey, notice) not in self.config.virtual_cluster_notices:
+            self.config.virtual_cluster_notices.add((mapping.rkey, notice))
+            self.config.ir.aconf.post_notice(notice, resource=mapping)
+
+        return { **vc, 'name': f"{unique}{dimensions}", '_base': unique }
+
+    def merge(self, other: 'V3Listener') -> bool:
+        # Fold another V3Listener for the same address and port into this one. This only
+        # happens for Listeners with different destination ports, so their filter chains
+        # can't collide -- but their listener filters, socket options, and buffer limit need
+        # to agree, since Envoy applies those to every connection before picking a filter
+        # chain. If they don't, post an error on the other Listener and return False.
+        ours = [ f['name'] for f in self.listener_filters if f['name'] != 'envoy.filters.listener.original_dst' ]
+        theirs = [ f['name'] for f in other.listener_filters if f['name'] != 'envoy.filters.listener.original_dst' ]
+
+        conflict = None
+
+        if ours != theirs:
+            conflict = "a different protocol stack"
+        elif self.socket_options != other.socket_options:
+            conflict = "different keepAlive settings"
+        elif self.per_connection_buffer_limit_bytes != other.per_connection_buffer_limit_bytes:
+            conflict = "a different connection buffer size"
+
+        if conflict:
+            other._irlistener.post_error(f"Listener {other._irlistener.name} shares port {self.port} with {self._irlistener.name}, but needs {conflict}; ignoring it")
+            return False
+
+        if not any(f['name'] == 'envoy.filters.listener.original_dst' for f in self.listener_filters):
+            self.listener_filters = other.listener_filters
+
+        self._filter_chains.extend(other._filter_chains)
+        return True
+
     def finalize_tcp(self) -> None:
         # Finalize a TCP listener, which amounts to walking all our TCP chains and
         # setting up Envoy configuration structures for them.
@@ -576,7 +909,34 @@ class V3Listener(dict):
             if self._log_debug:
                 logger.debug("BUILD CHAIN %s - %s", chain_key, chain)
 
+            # Envoy won't take two chains that match the same ALPN protocol for the same
+            # hosts, so keep track of which group got each protocol first.
+            alpn_owners: Dict[str, IRTCPMappingGroup] = {}
+
             for irgroup in chain.tcpmappings:
+                alpn_protocols: List[str] = irgroup.get('alpn_protocols', None) or []
+
+                if alpn_protocols:
+                    # Only the TLS inspector can see what the client offers.
+                    error = None
+
+                    if not self._tls_ok:
+                        error = f"alpn_protocols needs TLS in the protocolStack of Listener {self.name}"
+                    else:
+                        taken = [ proto for proto in alpn_protocols if proto in alpn_owners ]
+
+                        if taken:
+                            owner = alpn_owners[taken[0]]
+                            error = f"alpn_protocols {', '.join(taken)} already sent to {', '.join(sorted(m.name for m in owner.mappings))}"
+
+                    if error:
+                        for mapping in irgroup.mappings:
+                            mapping.post_error(f"{error}; ignoring this TCPMapping")
+                        continue
+
+                    for proto in alpn_protocols:
+                        alpn_owners[proto] = irgroup
+
                 # First up, which clusters do we need to talk to?
                 clusters = [{
                     'name': mapping.cluster.envoy_name,
@@ -616,6 +976,13 @@ class V3Listener(dict):
                     # filter_chain_match.
                     envoy_ctx = V3TLSContext(chain.context)
 
+                    # We're terminating TLS, so we have to agree to the protocol the chain
+                    # was picked for, unless the TLSContext already says what to agree to.
+                    if alpn_protocols and not envoy_ctx.get_common().get('alpn_protocols', None):
+                        envoy_ctx.get_common()['alpn_protocols'] = alpn_protocols
+
+                    envoy_ctx.discover_secrets(self.config.ir, self.config.secrets)
+
                     filter_chain['transport_socket'] = {
                         'name': 'envoy.transport_sockets.tls',
                         'typed_config': {
@@ -624,6 +991,12 @@ class V3Listener(dict):
                         }
                     }
 
+                if alpn_protocols:
+                    # Without a context, this is TLS we pass straight through, but the TLS
+                    # inspector can still see the ALPN in its ClientHello.
+                    filter_chain_match["transport_protocol"] = "tls"
+                    filter_chain_match["application_protocols"] = alpn_protocols
+
                 # We do server-name matching whether or not we have TLS, just to help
                 # make sure that we don't have two chains with an empty filter_match
                 # criterion (since Envoy will reject such a configuration).
@@ -765,6 +1138,12 @@ class V3Listener(dict):
                             extra_info = " (force Route for ACME challenge)"
                             action = "Route"
                             found_acme = True
+                        elif (self._irlistener.get('default_response', None) and
+                                rv.route["match"].get("prefix", "").startswith("/.well-known/acme-challenge/")):
+                            # The default listener has no Host asking for anything but a redirect
+                            # to HTTPS, which no ACME solver can answer yet.
+                            extra_info = " (force Route for ACME challenge on the default listener)"
+                            action = "Route"
                         elif (self.config.ir.edge_stack_allowed and
                                 (route_precedence == -1000000) and
                                 (rv.route["match"].get("safe_regex", {}).get("regex", None) == "^/$")):
@@ -893,6 +1272,7 @@ class V3Listener(dict):
                     # Note that we're modifying the filter_chain itself here, not
                     # filter_chain_match.
                     envoy_ctx = V3TLSContext(chain.context)
+                    envoy_ctx.discover_secrets(self.config.ir, self.config.secrets)
 
                     filter_chain['transport_socket'] = {
                         'name': 'envoy.transport_sockets.tls',
@@ -917,10 +1297,19 @@ class V3Listener(dict):
                 # Make certain that no internal keys from the route make it into the Envoy
                 # configuration.
                 routes = []
+                virtual_clusters: Dict[str, dict] = {}
 
                 for r in chain.routes:
                     routes.append({ k: v for k, v in r.items() if k[0] != '_' })
 
+                    # Route variants share their Mapping's virtual clusters, so only keep one
+                    # of each.
+                    for vc in r.get('_virtual_clusters', None) or []:
+                        vc = self.unique_virtual_cluster(vc, virtual_clusters)
+
+                        if vc['name'] not in virtual_clusters:
+                            virtual_clusters[vc['name']] = vc
+
                 # Do we - somehow - already have a vhost for this hostname? (This should
                 # be "impossible".)
 
@@ -935,8 +1324,92 @@ class V3Listener(dict):
 
                     filter_chain["_vhosts"][host.hostname] = vhost
 
+                # Browsers ignore Strict-Transport-Security over cleartext, so it only goes on
+                # the TLS chains, where it replaces whatever the service sent. (The vhost's
+                # response headers go on after every route's.)
+                if chain.type == "https":
+                    hsts = host.get('hsts', None) or self.config.ir.ambassador_module.get('hsts', None)
+
+                    if hsts:
+                        vhost["response_headers_to_add"] = [ {
+                            "header": { "key": "strict-transport-security", "value": IRHost.hsts_value(hsts) },
+                            "append": False
+                        } ]
+
+                # With strict_host_matching, a Host of "*" doesn't route anything: it's only
+                # there to reject requests whose Host header matches nothing more specific.
+                # (Envoy picks exact and suffix domains before "*".)
+                if (host.hostname == "*") and self.config.ir.ambassador_module.get('strict_host_matching', False):
+                    routes = [ {
+                        "match": { "prefix": "/" },
+                        "direct_response": {
+                            "status": self.config.ir.ambassador_module.get('strict_host_matching_status', None) or 404
+                        }
+                    } ]
+                    virtual_clusters = {}
+
+                # The default listener answers everything itself, except ACME challenges, which
+                # still need to get to whatever's solving them.
+                default_response = self._irlistener.get('default_response', None)
+
+                if default_response:
+                    routes = [ r for r in routes
+                               if r.get('match', {}).get('prefix', '').startswith('/.well-known/acme-challenge/') ]
+
+                    direct_response: Dict[str, Any] = { "status": default_response['status'] }
+
+                    if default_response.get('body', None):
+                        direct_response["body"] = { "inline_string": default_response['body'] }
+
+                    routes.append({
+                        "match": { "prefix": "/" },
+                        "direct_response": direct_response
+                    })
+                    virtual_clusters = {}
+
                 vhost["routes"] += routes
 
+                if virtual_clusters:
+                    vhost.setdefault("virtual_clusters", []).extend(
+                        { k: v for k, v in vc.items() if k[0] != '_' } for vc in virtual_clusters.values()
+                    )
+
+        # With tlsDetection Split, the TLS inspector decides between the TLS chains and the
+        # cleartext one, so that the cleartext chain never ends up with a TLS connection whose
+        # SNI didn't match anything. If no Host takes cleartext at all, cleartext requests get
+        # told they need TLS, instead of having their connection closed without a word.
+        if (self._irlistener.get('tlsDetection', None) == "Split") and filter_chains:
+            cleartext_chain = filter_chains.get("http", None)
+            takes_cleartext = (self._security_model == "SECURE")
+
+            for chain in self._chains.values():
+                if chain.type == "http":
+                    for host in chain.hosts.values():
+                        if (host.insecure_action or "Reject") != "Reject":
+                            takes_cleartext = True
+
+            if (not cleartext_chain) or (not takes_cleartext):
+                cleartext_chain = {
+                    "filter_chain_match": {},
+                    "_vhosts": {
+                        "*": {
+                            "name": f"{self.name}-tls-required",
+                            "domains": [ "*" ],
+                            "routes": [ {
+                                "match": { "prefix": "/" },
+                                "direct_response": {
+                                    "status": 400,
+                                    "body": { "inline_string": "This port requires TLS.\n" }
+                                }
+                            } ]
+                        }
+                    }
+                }
+
+                filter_chains["http"] = cleartext_chain
+
+            cleartext_chain["filter_chain_match"]["transport_protocol"] = "raw_buffer"
+
         # Once that's all done, walk the filter_chains dict...
         for fc_key, filter_chain in filter_chains.items():
             # ...set up our HTTP config...
@@ -947,6 +1420,37 @@ class V3Listener(dict):
                 "virtual_hosts": list(filter_chain["_vhosts"].values())
             }
 
+            # The route_config's request headers go on after every route's, so this wins
+            # over anything a Mapping adds.
+            forwarded_proto = self._irlistener.get('forwardedProto', None)
+
+            if forwarded_proto:
+                http_config["route_config"]["request_headers_to_add"] = [ {
+                    "header": { "key": "x-forwarded-proto", "value": forwarded_proto },
+                    "append": False
+                } ]
+
+            # Say which build, and which snapshot, this config came from, as "<version>/<generation>"
+            # (or just the version, for config that didn't come from a snapshot).
+            config_version_header = self.config.ir.ambassador_module.get('config_version_header', None)
+
+            if config_version_header:
+                generation = self.config.ir.aconf.snapshot_generation
+                config_version = f"{Version}/{generation}" if generation is not None else Version
+
+                http_config["route_config"]["response_headers_to_add"] = [ {
+                    "header": { "key": config_version_header, "value": config_version },
+                    "append": False
+                } ]
+
+            # Trace headers from clients we don't trust go, except the ones the tracer is about
+            # to overwrite anyway.
+            if self.config.ir.tracing:
+                untrusted_trace_headers = self.config.ir.tracing.untrusted_trace_headers()
+
+                if untrusted_trace_headers:
+                    http_config["route_config"]["request_headers_to_remove"] = untrusted_trace_headers
+
             # Now that we've saved our vhosts as a list, drop the dict version.
             del(filter_chain["_vhosts"])
 
@@ -989,6 +1493,9 @@ class V3Listener(dict):
         if self.listener_filters:
             listener["listener_filters"] = self.listener_filters
 
+        if self.socket_options:
+            listener["socket_options"] = self.socket_options
+
         return listener
 
     def pretty(self) -> dict:
@@ -1009,6 +1516,7 @@ class V3Listener(dict):
     def generate(cls, config: 'V3Config') -> None:
         config.listeners = []
         logger = config.ir.logger
+        bound: Dict[str, V3Listener] = {}
 
         for key in config.ir.listeners.keys():
             irlistener = config.ir.listeners[key]
@@ -1026,7 +1534,46 @@ class V3Listener(dict):
                         config.ir.logger.debug("      %s", v3prettyroute(r))
 
             # Does this listener have any filter chains?
-            if v3listener._filter_chains:
-                config.listeners.append(v3listener)
-            else:
+            if not v3listener._filter_chains:
                 irlistener.post_error("No matching Hosts found, disabling!")
+                continue
+
+            # Listeners that differ only by destination port share a single Envoy listener.
+            extant = bound.get(v3listener.bind_to, None)
+
+            if extant:
+                if not extant.merge(v3listener):
+                    # merge has posted the error. The port is taken, so this Listener gets
+                    # nothing.
+                    continue
+            else:
+                bound[v3listener.bind_to] = v3listener
+                config.listeners.append(v3listener)
+
+        cls.check_typed_per_filter_config(config)
+
+    @classmethod
+    def check_typed_per_filter_config(cls, config: 'V3Config') -> None:
+        # A Mapping's typed_per_filter_config can name any filter at all, so once we know
+        # which filters are really there, point out the ones that aren't, and the ones that
+        # threw out per-filter config we generated.
+        filter_names: Set[str] = set()
+
+        for v3listener in config.listeners:
+            base_http_config = getattr(v3listener, '_base_http_config', None) or {}
+
+            for http_filter in base_http_config.get('http_filters', []):
+                filter_names.add(http_filter['name'])
+
+        for route in config.routes:
+            mapping = getattr(route, '_mapping', None)
+
+            if not mapping or not mapping.get('typed_per_filter_config', None):
+                continue
+
+            for filter_name in mapping.typed_per_filter_config.keys():
+                if filter_name not in filter_names:
+                    config.ir.aconf.post_notice(f"typed_per_filter_config {filter_name}: no such HTTP filter, so it does nothing", resource=mapping)
+
+            for filter_name in route._replaced_per_filter_config:
+                config.ir.aconf.post_notice(f"typed_per_filter_config {filter_name}: the @type differs from Ambassador's, so it replaces Ambassador's per-route config", resource=mapping)
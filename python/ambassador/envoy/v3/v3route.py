@@ -12,6 +12,9 @@
 # See the License for the specific language governing permissions and
 # limitations under the License
 
+import copy
+import re
+
 from typing import Any, Dict, List, Optional, Set, Tuple, Union, TYPE_CHECKING
 from typing import cast as typecast
 
@@ -19,8 +22,17 @@ from ..common import EnvoyRoute
 from ...cache import Cacheable
 from ...ir.irhttpmappinggroup import IRHTTPMappingGroup
 from ...ir.irbasemapping import IRBaseMapping
+from ...ir.irhttpmapping import IRHTTPMapping
+from ...ir.irerrorresponse import IRErrorResponse
 from ...ir.irutils import hostglob_matches
 
+from .v3httpfilter import CompressionExclusionFilterName, CompressionExclusionLua, DecompressorLimitFilterName
+from .v3httpfilter import CookieAttributesFilterName, cookie_attributes_lua
+from .v3httpfilter import RequestTimeoutHeaderFilterName, request_timeout_header_lua
+from .v3httpfilter import BodyTransformFilterName, body_transform_lua
+from .v3httpfilter import JWTAuthnFilterName, JWTClaimsFilterName, jwt_claims_rbac
+from .v3httpfilter import LogSamplingFilterName, log_sampling_lua
+from .v3cluster import V3Cluster
 from .v3ratelimitaction import V3RateLimitAction
 
 if TYPE_CHECKING:
@@ -85,7 +97,10 @@ def v3prettyroute(route: DictifiedV3Route) -> str:
     target_str = "-none-"
 
     if route.get("route"):
-        target_str = f"ROUTE {route['route']['cluster']}"
+        if 'weighted_clusters' in route['route']:
+            target_str = "ROUTE " + ",".join(c['name'] for c in route['route']['weighted_clusters']['clusters'])
+        else:
+            target_str = f"ROUTE {route['route']['cluster']}"
     elif route.get("redirect"):
         target_str = f"REDIRECT"
 
@@ -94,6 +109,21 @@ def v3prettyroute(route: DictifiedV3Route) -> str:
     return f"<V3Route {hcstr}: {match_str} -> {target_str}>"
 
 
+# SNIHostHeader carries the downstream SNI to the route's host_rewrite_header, for
+# Mappings with host_rewrite_from_sni.
+SNIHostHeader = 'x-ambassador-sni'
+
+# EnvoyDebugResponseHeaders are the headers the router adds to responses for debugging,
+# which Mappings with suppress_envoy_headers strip.
+EnvoyDebugResponseHeaders = [ 'x-envoy-upstream-service-time', 'x-envoy-overloaded' ]
+
+# HopByHopRequestHeaders and HopByHopResponseHeaders are the hop-by-hop headers that
+# header_sanitization's strip_hop_by_hop removes. Envoy already handles connection, upgrade,
+# transfer-encoding, and te itself, and removing them here would break upgrades and gRPC.
+HopByHopRequestHeaders = [ 'keep-alive', 'proxy-connection', 'proxy-authorization' ]
+HopByHopResponseHeaders = [ 'keep-alive', 'proxy-connection', 'proxy-authenticate' ]
+
+
 # regex_matcher generates Envoy configuration to do a regex match in a Route. It's complex
 # here because, even though we don't have to deal with safe and unsafe regexes, it's simpler
 # to keep the weird baroqueness of this stuff wrapped in a function.
@@ -272,9 +302,10 @@ class V3Route(Cacheable):
     def __init__(self, config: 'V3Config', group: IRHTTPMappingGroup, mapping: IRBaseMapping) -> None:
         super().__init__()
 
-        # Save the logger and the group.
+        # Save the logger, the group, and the Mapping.
         self.logger = group.logger
         self._group = group
+        self._mapping = mapping
 
         # Passing a list to set is _very important_ here, lest you get a set of
         # the individual characters in group.host!
@@ -305,11 +336,17 @@ class V3Route(Cacheable):
             else:
                 runtime_fraction['runtime_key'] = f'routing.traffic_shift.{mapping.cluster.envoy_name}'
 
-        match = {
+        # With runtime-keyed weights, the whole group is one route, and the split happens in
+        # its weighted_clusters rather than by falling through a run of runtime_fractions.
+        weighted_clusters = V3Route.weighted_clusters(group) if (len(mapping) > 0) and not self.get('_failed') else None
+
+        match: Dict[str, Any] = {
             'case_sensitive': case_sensitive,
-            'runtime_fraction': runtime_fraction
         }
 
+        if not weighted_clusters:
+            match['runtime_fraction'] = runtime_fraction
+
         if envoy_route == 'prefix':
             match['prefix'] = route_prefix
         elif envoy_route == 'path':
@@ -339,6 +376,14 @@ class V3Route(Cacheable):
 
         self['match'] = match
 
+        # If asked, give this Mapping a virtual cluster of its own, so that it gets its own
+        # request stats even when it shares an Envoy cluster with other Mappings. That's also
+        # where the stats for response_flag_stats come from, and stats_dimensions splits it
+        # into one for each combination of values.
+        if mapping.get('virtual_cluster', False) or mapping.get('response_flag_stats', None) or \
+           mapping.get('stats_dimensions', None):
+            self['_virtual_clusters'] = self.generate_virtual_clusters(mapping, match)
+
         # `typed_per_filter_config` is used to pass typed configuration to Envoy filters
         typed_per_filter_config = {}
 
@@ -378,6 +423,33 @@ class V3Route(Cacheable):
                         }
                     }
 
+        # static_fallback goes ahead of any error response overrides, so that it's what answers
+        # when there's no healthy upstream, and it still applies on routes that bypass them.
+        static_fallback = mapping.static_fallback_mapper() if isinstance(mapping, IRHTTPMapping) else None
+
+        if static_fallback:
+            mappers: List[Dict[str, Any]] = []
+            response_map = typed_per_filter_config.get('envoy.filters.http.response_map', None)
+
+            if response_map is None:
+                # The route's per-filter config replaces the Module's, so carry the Module's
+                # overrides along.
+                for irfilter in config.ir.filters:
+                    if irfilter.kind == 'IRErrorResponse':
+                        module_config = typecast(IRErrorResponse, irfilter).config()
+
+                        if module_config:
+                            mappers = list(module_config['mappers'])
+            elif not response_map.get('disabled', False):
+                mappers = list(response_map['response_map']['mappers'])
+
+            typed_per_filter_config['envoy.filters.http.response_map'] = {
+                '@type': 'type.googleapis.com/envoy.extensions.filters.http.response_map.v3.ResponseMapPerRoute',
+                'response_map': {
+                    'mappers': [ static_fallback ] + mappers
+                }
+            }
+
         if mapping.get('bypass_auth', False):
             typed_per_filter_config['envoy.filters.http.ext_authz'] = {
                 '@type': 'type.googleapis.com/envoy.extensions.filters.http.ext_authz.v3.ExtAuthzPerRoute',
@@ -392,6 +464,90 @@ class V3Route(Cacheable):
                     'check_settings': {'context_extensions': auth_context_extensions}
                 }
 
+        decompressor_max_request_bytes = mapping.get('decompressor_max_request_bytes', None)
+
+        if decompressor_max_request_bytes is not None:
+            decompressor_limit: Dict[str, Any] = {
+                '@type': 'type.googleapis.com/envoy.extensions.filters.http.buffer.v3.BufferPerRoute',
+            }
+
+            if decompressor_max_request_bytes == 0:
+                decompressor_limit['disabled'] = True
+            else:
+                decompressor_limit['buffer'] = { 'max_request_bytes': decompressor_max_request_bytes }
+
+            typed_per_filter_config[DecompressorLimitFilterName] = decompressor_limit
+
+        # MappingFactory only sets up the exclusion filter if gzip is on, so without gzip
+        # there's nothing to bypass.
+        if mapping.get('bypass_compression', False) and config.ir.ambassador_module.get('gzip', None):
+            typed_per_filter_config[CompressionExclusionFilterName] = {
+                '@type': 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.LuaPerRoute',
+                'source_code': { 'inline_string': CompressionExclusionLua },
+            }
+
+        request_timeout_header = mapping.request_timeout_header_policy() if isinstance(mapping, IRHTTPMapping) else None
+
+        if request_timeout_header:
+            typed_per_filter_config[RequestTimeoutHeaderFilterName] = {
+                '@type': 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.LuaPerRoute',
+                'source_code': { 'inline_string': request_timeout_header_lua(request_timeout_header) },
+            }
+
+        cookie_attributes = mapping.cookie_attributes_policy() if isinstance(mapping, IRHTTPMapping) else None
+
+        if cookie_attributes:
+            typed_per_filter_config[CookieAttributesFilterName] = {
+                '@type': 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.LuaPerRoute',
+                'source_code': { 'inline_string': cookie_attributes_lua(cookie_attributes) },
+            }
+
+        body_transform = mapping.body_transform_policy() if isinstance(mapping, IRHTTPMapping) else None
+
+        if body_transform:
+            typed_per_filter_config[BodyTransformFilterName] = {
+                '@type': 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.LuaPerRoute',
+                'source_code': { 'inline_string': body_transform_lua(body_transform) },
+            }
+
+        jwt = mapping.jwt_policy() if isinstance(mapping, IRHTTPMapping) else None
+
+        if jwt:
+            typed_per_filter_config[JWTAuthnFilterName] = {
+                '@type': 'type.googleapis.com/envoy.extensions.filters.http.jwt_authn.v3.PerRouteConfig',
+                'requirement_name': jwt['provider'],
+            }
+
+            if jwt['claims']:
+                typed_per_filter_config[JWTClaimsFilterName] = {
+                    '@type': 'type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBACPerRoute',
+                    'rbac': { 'rules': jwt_claims_rbac(jwt) },
+                }
+
+        log_sampling = mapping.log_sampling_policy() if isinstance(mapping, IRHTTPMapping) else None
+
+        if log_sampling:
+            typed_per_filter_config[LogSamplingFilterName] = {
+                '@type': 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.LuaPerRoute',
+                'source_code': { 'inline_string': log_sampling_lua(log_sampling) },
+            }
+
+        # Last comes the Mapping's own per-filter config. For a filter we've already set up,
+        # it's merged over ours if it's the same @type, and replaces ours if it isn't (which
+        # V3Listener will mention).
+        self._replaced_per_filter_config: List[str] = []
+
+        for filter_name, filter_config in (mapping.get('typed_per_filter_config', None) or {}).items():
+            generated = typed_per_filter_config.get(filter_name, None)
+
+            if generated and (generated.get('@type', None) == filter_config['@type']):
+                typed_per_filter_config[filter_name] = V3Cluster.deep_merge(generated, filter_config)
+            else:
+                if generated:
+                    self._replaced_per_filter_config.append(filter_name)
+
+                typed_per_filter_config[filter_name] = dict(filter_config)
+
         if len(typed_per_filter_config) > 0:
             self['typed_per_filter_config'] = typed_per_filter_config
 
@@ -403,6 +559,23 @@ class V3Route(Cacheable):
         if response_headers_to_add:
             self['response_headers_to_add'] = self.generate_headers_to_add(response_headers_to_add)
 
+        # The request ID is only a random UUID (unless the client sent its own and we kept it),
+        # so echoing it gives nothing away, and lets a client find its request in our logs.
+        # An x-request-id the Mapping adds itself wins, though.
+        echo_request_id = group.get('echo_request_id', None)
+
+        if echo_request_id is None:
+            echo_request_id = config.ir.ambassador_module.get('echo_request_id', False)
+
+        if echo_request_id and ('x-request-id' not in { k.lower() for k in (response_headers_to_add or {}) }):
+            self.setdefault('response_headers_to_add', []).append({
+                'header': {
+                    'key': 'x-request-id',
+                    'value': '%REQ(x-request-id)%'
+                },
+                'append': False
+            })
+
         request_headers_to_remove = group.get('remove_request_headers', None)
         if request_headers_to_remove:
             if type(request_headers_to_remove) != list:
@@ -415,6 +588,49 @@ class V3Route(Cacheable):
                 response_headers_to_remove = [ response_headers_to_remove ]
             self['response_headers_to_remove'] = response_headers_to_remove
 
+        # The router filter adds these before the route's own response headers are processed,
+        # so we can strip them here for just this route. (The Module's suppress_envoy_headers
+        # stops the router from adding them at all.)
+        if group.get('suppress_envoy_headers', False):
+            response_headers_to_remove = self.setdefault('response_headers_to_remove', [])
+
+            for hdr in EnvoyDebugResponseHeaders:
+                if hdr not in response_headers_to_remove:
+                    response_headers_to_remove.append(hdr)
+
+        # The Module's header_sanitization strips headers that upstreams (or clients) have no
+        # business seeing, except for the ones this Mapping's service needs. Envoy removes
+        # these before adding the Mapping's add_request_headers, so those still get through.
+        header_sanitization = config.ir.ambassador_module.get('header_sanitization', None)
+
+        if header_sanitization:
+            preserve = set(hdr.lower() for hdr in group.get('preserve_sensitive_headers', None) or [])
+
+            strip_request = list(header_sanitization['sensitive_headers'])
+            strip_response: List[str] = []
+
+            if header_sanitization['strip_hop_by_hop']:
+                strip_request += HopByHopRequestHeaders
+                strip_response += HopByHopResponseHeaders
+
+            for key, strip in (('request_headers_to_remove', strip_request),
+                               ('response_headers_to_remove', strip_response)):
+                for hdr in strip:
+                    if hdr in preserve:
+                        continue
+
+                    to_remove = self.setdefault(key, [])
+
+                    if hdr not in to_remove:
+                        to_remove.append(hdr)
+
+        # Envoy buffers up to this much of each request body so that it can resend it on a
+        # retry (or to a shadow). Without it, the listener's buffer limit applies.
+        retry_buffer_limit_bytes = group.get('retry_buffer_limit_bytes', None)
+
+        if retry_buffer_limit_bytes is not None:
+            self['per_request_buffer_limit_bytes'] = retry_buffer_limit_bytes
+
         host_redirect = group.get('host_redirect', None)
 
         if host_redirect:
@@ -470,17 +686,44 @@ class V3Route(Cacheable):
         # Take the default `timeout_ms` value from the Ambassador module using `cluster_request_timeout_ms`.
         # If that isn't set, use 3000ms. The mapping below will override this if its own `timeout_ms` is set.
         default_timeout_ms = config.ir.ambassador_module.get('cluster_request_timeout_ms', 3000)
+        # IRHTTPMapping has already checked that priority is default or high.
+        priority = group.get('priority', None)
+
         route = {
-            'priority': group.get('priority'),
+            'priority': priority.upper() if priority else None,
             'timeout': "%0.3fs" % (mapping.get('timeout_ms', default_timeout_ms) / 1000.0),
-            'cluster': mapping.cluster.envoy_name
         }
 
+        if weighted_clusters:
+            route['weighted_clusters'] = weighted_clusters
+        else:
+            route['cluster'] = mapping.cluster.envoy_name
+
+        ab_header = mapping.get('ab_header', None)
+
+        if ab_header and weighted_clusters:
+            config.ir.aconf.post_notice("ab_header can't be combined with weight_runtime_key_prefix or health_rebalance; ignoring it", resource=mapping)
+        elif ab_header:
+            self.add_ab_header(route, ab_header)
+
         idle_timeout_ms = mapping.get('idle_timeout_ms', None)
 
         if idle_timeout_ms is not None:
             route['idle_timeout'] = "%0.3fs" % (idle_timeout_ms / 1000.0)
 
+        # max_grpc_timeout is deprecated in favor of max_stream_duration, but it's the one that
+        # replaces the route timeout with the grpc-timeout header, rather than adding a
+        # stream limit on top of it.
+        max_grpc_timeout_ms = mapping.get('max_grpc_timeout_ms', None)
+
+        if max_grpc_timeout_ms is not None:
+            route['max_grpc_timeout'] = "%0.3fs" % (max_grpc_timeout_ms / 1000.0)
+
+            grpc_timeout_offset_ms = mapping.get('grpc_timeout_offset_ms', None)
+
+            if grpc_timeout_offset_ms is not None:
+                route['grpc_timeout_offset'] = "%0.3fs" % (grpc_timeout_offset_ms / 1000.0)
+
         regex_rewrite = self.generate_regex_rewrite(config, group)
         if len(regex_rewrite) > 0:
             route['regex_rewrite'] =  regex_rewrite
@@ -493,7 +736,31 @@ class V3Route(Cacheable):
         if 'auto_host_rewrite' in mapping:
             route['auto_host_rewrite'] = mapping['auto_host_rewrite']
 
-        hash_policy = self.generate_hash_policy(group)
+        if mapping.get('host_rewrite_from_sni', False):
+            # Envoy can't rewrite the Host from the SNI directly, but it can rewrite it from
+            # another header, so stash the SNI in a header first. A connection without SNI
+            # doesn't get the header at all, which leaves the Host alone.
+            #
+            # The client mustn't get to pick the Host by sending the header itself, so it's
+            # removed first. That has to happen here rather than on the route config: Envoy
+            # applies the route's header changes first, and removes before it adds.
+            # (The list we have may be the Mapping's own, so don't append to it.)
+            request_headers_to_remove = self.get('request_headers_to_remove', [])
+
+            if SNIHostHeader not in request_headers_to_remove:
+                self['request_headers_to_remove'] = request_headers_to_remove + [ SNIHostHeader ]
+
+            self.setdefault('request_headers_to_add', []).append({
+                'header': {
+                    'key': SNIHostHeader,
+                    'value': '%REQUESTED_SERVER_NAME%'
+                },
+                'append': False
+            })
+
+            route['host_rewrite_header'] = SNIHostHeader
+
+        hash_policy = self.generate_hash_policy(config, group)
         if len(hash_policy) > 0:
             route['hash_policy'] = [ hash_policy ]
 
@@ -521,6 +788,11 @@ class V3Route(Cacheable):
         if retry_policy:
             route['retry_policy'] = retry_policy
 
+            # The hedged request is another try, so this only means anything with retries. (The
+            # Mapping drops hedging if there's no per_try_timeout to hedge on.)
+            if group.get('hedging', None):
+                route['hedge_policy'] = { 'hedge_on_per_try_timeout': True }
+
         # Is shadowing enabled?
         shadow = group.get("shadows", None)
 
@@ -529,15 +801,31 @@ class V3Route(Cacheable):
 
             weight = shadow.get('weight', 100)
 
+            runtime_fraction: Dict[str, Any] = {
+                'default_value': {
+                    'numerator': weight,
+                    'denominator': 'HUNDRED'
+                }
+            }
+
+            # With a runtime key, the weight is just the default: operators can dial the
+            # mirrored percentage up or down through the Envoy runtime without a reconfigure.
+            shadow_runtime_key = shadow.get('shadow_runtime_key', None)
+
+            if shadow_runtime_key:
+                runtime_fraction['runtime_key'] = shadow_runtime_key
+
+            # With shadow_on_circuit_break, nothing's mirrored until the runtime key says so.
+            shadow_on_circuit_break = shadow.get('shadow_on_circuit_break', None)
+
+            if shadow_on_circuit_break:
+                runtime_fraction['default_value']['numerator'] = 0
+                runtime_fraction['runtime_key'] = shadow_on_circuit_break['runtime_key']
+
             route['request_mirror_policies'] = [
                 {
                     'cluster': shadow.cluster.envoy_name,
-                    'runtime_fraction': {
-                        'default_value': {
-                            'numerator': weight,
-                            'denominator': 'HUNDRED'
-                        }
-                    }
+                    'runtime_fraction': runtime_fraction
                 }
            ]
 
@@ -570,6 +858,22 @@ class V3Route(Cacheable):
 
         self['route'] = route
 
+        # ambex is what actually moves the weights around, since it's the one that hears
+        # about cluster health; this just tells it which routes it may touch.
+        if weighted_clusters and group.get('health_rebalance'):
+            rebalance: Dict[str, Any] = { 'health_rebalance': True }
+
+            recovery_ms = group.get('health_rebalance_recovery_ms', None)
+
+            if recovery_ms is not None:
+                rebalance['recovery_ms'] = recovery_ms
+
+            self['metadata'] = {
+                'filter_metadata': {
+                    V3Cluster.AmbexMetadataKey: rebalance
+                }
+            }
+
     # matches_domain and matches_domains are both still written assuming a _host_constraints
     # with more than element. Not changing that yet.
     def matches_domain(self, domain: str) -> bool:
@@ -628,9 +932,278 @@ class V3Route(Cacheable):
         # One way or another, we have a route now.
         return route
 
+    @staticmethod
+    def weighted_clusters(group: IRHTTPMappingGroup) -> Optional[dict]:
+        """
+        The weighted_clusters for a group with weight_runtime_key_prefix or health_rebalance,
+        or None if the group has neither. Each Mapping's _weight is cumulative, so its share
+        is the difference from the one before it.
+        """
+        runtime_key_prefix = group.get('weight_runtime_key_prefix', None)
+
+        if not runtime_key_prefix and not group.get('health_rebalance'):
+            return None
+
+        weights: Dict[str, int] = {}
+        previous = 0
+
+        for mapping in group.mappings:
+            if 'cluster' not in mapping:
+                return None
+
+            # Two Mappings for the same service share a cluster, and so a runtime key.
+            name = mapping.cluster.envoy_name
+            weights[name] = weights.get(name, 0) + (mapping._weight - previous)
+            previous = mapping._weight
+
+        if previous <= 0:
+            # Envoy needs a nonzero total, and there's no traffic to split anyway.
+            return None
+
+        weighted_clusters: Dict[str, Any] = {
+            'clusters': [ { 'name': name, 'weight': weight } for name, weight in weights.items() ],
+            'total_weight': previous,
+        }
+
+        if runtime_key_prefix:
+            weighted_clusters['runtime_key_prefix'] = runtime_key_prefix
+
+        return weighted_clusters
+
+    def add_ab_header(self, route: Dict[str, Any], ab_header: Dict[str, Any]) -> None:
+        """
+        Add an ab_header to some of this route's requests. Envoy can't add a header at random,
+        but it can pick a weighted cluster at random, and add headers for the one it picks: so
+        the route gets two weighted clusters that are both its cluster, and only one of them
+        adds the header. With a cookie, each of them also says which one it was, so that
+        ab_header_sticky_routes can send the client the same way next time.
+        """
+        header = {
+            'header': { 'key': ab_header['name'], 'value': ab_header['value'] },
+            'append': False
+        }
+
+        percent = ab_header['percent']
+
+        if percent <= 0:
+            return
+
+        if percent >= 100:
+            self.setdefault('request_headers_to_add', []).append(header)
+            return
+
+        cluster = route.pop('cluster')
+
+        with_header: Dict[str, Any] = { 'name': cluster, 'weight': percent, 'request_headers_to_add': [ header ] }
+        without_header: Dict[str, Any] = { 'name': cluster, 'weight': 100 - percent }
+
+        cookie = ab_header.get('cookie', None)
+
+        if cookie:
+            for assignment, weighted_cluster in ( ( 'a', without_header ), ( 'b', with_header ) ):
+                weighted_cluster['response_headers_to_add'] = [ {
+                    'header': { 'key': 'set-cookie', 'value': f'{cookie}={assignment}; Path=/' },
+                    'append': True
+                } ]
+
+            self['_ab_header'] = ab_header
+
+        route['weighted_clusters'] = {
+            'clusters': [ with_header, without_header ],
+            'total_weight': 100,
+        }
+
+    @staticmethod
+    def ab_header_sticky_routes(config: 'V3Config', route: 'V3Route') -> List['V3Route']:
+        """
+        Build the routes that keep a client with an ab_header cookie on the side it's already
+        on: a copy of the route for each side that only matches requests with that side's
+        cookie, and goes straight to the cluster, with the header or without it. They have to
+        come before the route itself, which would otherwise draw again.
+        """
+        ab_header = route.get('_ab_header', None)
+
+        if not ab_header:
+            return []
+
+        cookie = re.escape(ab_header['cookie'])
+        with_header, without_header = route['route']['weighted_clusters']['clusters']
+
+        extra: List[V3Route] = []
+
+        for assignment, weighted_cluster in ( ( 'b', with_header ), ( 'a', without_header ) ):
+            match = dict(route['match'])
+            match['headers'] = match.get('headers', []) + [ {
+                'name': 'cookie',
+                **regex_matcher(config, f'(.*;\\s*)?{cookie}={assignment}(;.*)?', key='regex_match')
+            } ]
+
+            sticky_route = { k: v for k, v in route['route'].items() if k != 'weighted_clusters' }
+            sticky_route['cluster'] = weighted_cluster['name']
+
+            # The virtual cluster already covers the route itself; a second one would have
+            # the same name.
+            sticky = copy.copy(route)
+            sticky.pop('_virtual_clusters', None)
+            sticky.pop('_ab_header', None)
+            sticky['match'] = match
+            sticky['route'] = sticky_route
+
+            request_headers_to_add = route.get('request_headers_to_add', []) + weighted_cluster.get('request_headers_to_add', [])
+
+            if request_headers_to_add:
+                sticky['request_headers_to_add'] = request_headers_to_add
+
+            extra.append(sticky)
+
+        return extra
+
+    @staticmethod
+    def method_not_allowed(route: 'V3Route', methods: List[str]) -> 'V3Route':
+        """
+        Build the 405 route for a route limited to some methods: the same match without the
+        :method header (or the canary's runtime_fraction), answered directly.
+        """
+        match = { k: v for k, v in route['match'].items() if k != 'runtime_fraction' }
+        headers = [ h for h in match.pop('headers', []) if h.get('name') != ':method' ]
+
+        if headers:
+            match['headers'] = headers
+
+        # Copying the route keeps its group, which the listener needs to find its Hosts.
+        fallback = copy.copy(route)
+        fallback.clear()
+        fallback.update({
+            '_host_constraints': set(route['_host_constraints']),
+            '_method_fallback': set(methods),
+            'match': match,
+            'direct_response': { 'status': 405 },
+        })
+
+        if '_precedence' in route:
+            fallback['_precedence'] = route['_precedence']
+
+        return fallback
+
+    @staticmethod
+    def method_fallback_shadows(fallback: 'V3Route', route: 'V3Route') -> bool:
+        """
+        Would the 405 route take requests from this route if it came first? That's any route
+        on the same path, for the same hosts, that matches at least the same headers and query
+        parameters.
+        """
+        fmatch = fallback['match']
+        rmatch = route['match']
+
+        for key in [ 'prefix', 'path', 'safe_regex', 'case_sensitive' ]:
+            if fmatch.get(key) != rmatch.get(key):
+                return False
+
+        if not all([ any([ hostglob_matches(fhost, rhost) for fhost in fallback['_host_constraints'] ])
+                     for rhost in route['_host_constraints'] ]):
+            return False
+
+        for key in [ 'headers', 'query_parameters' ]:
+            rvalues = rmatch.get(key, [])
+
+            if not all([ fvalue in rvalues for fvalue in fmatch.get(key, []) ]):
+                return False
+
+        return True
+
+    @staticmethod
+    def trailing_slash_routes(config: 'V3Config', irgroup: IRHTTPMappingGroup, routes: List['V3Route'],
+                              prefixes: Set[str]) -> List['V3Route']:
+        """
+        Build the routes that let a request for /foo reach a group with prefix /foo/, if
+        trailing_slash asks for them: one redirect to /foo/, or a copy of each of the group's
+        routes matching exactly /foo (which the prefix rewrite then turns into the rewrite,
+        as if the slash had been there).
+        """
+        mode = irgroup.get('trailing_slash', None) or config.ir.ambassador_module.get('trailing_slash', None) or 'strict'
+
+        if (mode == 'strict') or not routes:
+            return []
+
+        prefix = routes[0]['match'].get('prefix', None)
+
+        if (not prefix) or (len(prefix) < 2) or not prefix.endswith('/'):
+            return []
+
+        path = prefix[:-1]
+
+        # If something else is routing /foo, it gets to keep doing that.
+        if path in prefixes:
+            return []
+
+        if mode == 'redirect':
+            route = routes[0]
+            match = { k: v for k, v in route['match'].items() if k not in ( 'prefix', 'runtime_fraction' ) }
+            match['path'] = path
+
+            # A 308 rather than a 301, so that clients don't turn a POST into a GET. The query
+            # string comes along.
+            redirect = copy.copy(route)
+            redirect.clear()
+            redirect.update({
+                '_host_constraints': set(route['_host_constraints']),
+                'match': match,
+                'redirect': {
+                    'path_redirect': prefix,
+                    'response_code': 'PERMANENT_REDIRECT'
+                }
+            })
+
+            if '_precedence' in route:
+                redirect['_precedence'] = route['_precedence']
+
+            return [ redirect ]
+
+        extra: List[V3Route] = []
+
+        for route in routes:
+            match = { k: v for k, v in route['match'].items() if k != 'prefix' }
+            match['path'] = path
+
+            # The virtual clusters already cover the prefix; second ones would have the same names.
+            exact = copy.copy(route)
+            exact.pop('_virtual_clusters', None)
+            exact['match'] = match
+            extra.append(exact)
+
+        return extra
+
+    @classmethod
+    def add_method_fallback(cls, config: 'V3Config', irgroup: IRHTTPMappingGroup, fallback: 'V3Route') -> None:
+        # Mappings on the same match that allow different methods share one 405, which has to
+        # Allow all of them.
+        for route in config.routes:
+            if ('_method_fallback' in route) and (route['match'] == fallback['match']) and \
+               (route['_host_constraints'] == fallback['_host_constraints']):
+                route['_method_fallback'] |= fallback['_method_fallback']
+                fallback = route
+                break
+        else:
+            # Envoy takes the first route that matches, so the 405 has to come after every
+            # route it would otherwise shadow -- its own group's, at least.
+            index = max([ i for i, route in enumerate(config.routes) if cls.method_fallback_shadows(fallback, route) ])
+            config.routes.insert(index + 1, config.save_element('route', irgroup, fallback))
+
+        fallback['response_headers_to_add'] = [ {
+            'header': { 'key': 'allow', 'value': ', '.join(sorted(fallback['_method_fallback'])) },
+            'append': False
+        } ]
+
     @classmethod
     def generate(cls, config: 'V3Config') -> None:
         config.routes = []
+        method_fallbacks: List[Tuple[IRHTTPMappingGroup, V3Route]] = []
+
+        # Every path anything routes by, so that trailing_slash doesn't take requests from a
+        # Mapping that asked for them.
+        prefixes: Set[str] = set([ irgroup.get('prefix') for irgroup in config.ir.ordered_groups()
+                                   if isinstance(irgroup, IRHTTPMappingGroup) and
+                                      (EnvoyRoute(irgroup).envoy_route in ( 'prefix', 'path' )) ])
 
         for irgroup in config.ir.ordered_groups():
             if not isinstance(irgroup, IRHTTPMappingGroup):
@@ -652,13 +1225,42 @@ class V3Route(Cacheable):
                 config.routes.append(route)
 
             # Repeat for our real mappings.
-            for mapping in irgroup.mappings:
+            group_route: Optional[V3Route] = None
+
+            mappings = irgroup.mappings
+
+            if V3Route.weighted_clusters(irgroup):
+                # One route covers the whole group, built from the Mapping that takes whatever
+                # weight is left over. (IRHTTPMappingGroup.check_weighted_route_settings has
+                # already made sure the others' route settings are the same.)
+                mappings = mappings[-1:]
+
+            group_routes: List[V3Route] = []
+
+            for mapping in mappings:
                 key = f"Route-{irgroup.group_id}-{mapping.cache_key}"
 
                 route = cls.get_route(config, key, irgroup, mapping)
 
                 if not route.get('_failed', False):
+                    for sticky in cls.ab_header_sticky_routes(config, route):
+                        config.routes.append(config.save_element('route', irgroup, sticky))
+
                     config.routes.append(config.save_element('route', irgroup, route))
+                    group_route = route
+                    group_routes.append(route)
+
+            for route in cls.trailing_slash_routes(config, irgroup, group_routes, prefixes):
+                config.routes.append(config.save_element('route', irgroup, route))
+
+            methods = irgroup.get('methods', None)
+
+            if methods and group_route:
+                method_fallbacks.append((irgroup, cls.method_not_allowed(group_route, methods)))
+
+        # The 405s can only go in once we know where every route is.
+        for irgroup, fallback in method_fallbacks:
+            cls.add_method_fallback(config, irgroup, fallback)
 
         # Once that's done, go build the variants on each route.
         config.route_variants = []
@@ -681,7 +1283,14 @@ class V3Route(Cacheable):
 
             # Is this a regex?
             if group_header.get('regex'):
+                if group_header.get('ignore_case'):
+                    header_value = '(?i)' + header_value
+
                 header.update(regex_matcher(config, header_value, key='regex_match'))
+            elif group_header.get('ignore_case'):
+                # Envoy's exact_match can't ignore case, but RE2 can, on a regex that only
+                # matches the value itself.
+                header.update(regex_matcher(config, '(?i)' + re.escape(header_value), key='regex_match'))
             else:
                 if header_name == ':authority':
                     # The authority header is special, because its value is a glob.
@@ -742,8 +1351,51 @@ class V3Route(Cacheable):
         return query_parameters
 
     @staticmethod
-    def generate_hash_policy(mapping_group: IRHTTPMappingGroup) -> dict:
-        hash_policy = {}
+    def generate_virtual_clusters(mapping: IRBaseMapping, match: dict) -> List[dict]:
+        # Virtual clusters match on headers only, so turn the route's path match into a
+        # match on :path. The route's own header matches (including :method) come along.
+        if 'prefix' in match:
+            path_header = { 'name': ':path', 'prefix_match': match['prefix'] }
+        elif 'path' in match:
+            path_header = { 'name': ':path', 'exact_match': match['path'] }
+        else:
+            path_header = { 'name': ':path', 'safe_regex_match': match['safe_regex'] }
+
+        name = mapping.get('stats_name', None) or f"{mapping.name}_{mapping.namespace}"
+        base = re.sub(r'[^0-9A-Za-z_]', '_', name)
+
+        # _mapping and _base let V3Listener tell two Mappings' virtual clusters with the same
+        # name apart, and name them apart.
+        virtual_clusters = [ {
+            'name': base,
+            'headers': [ path_header ] + match.get('headers', []),
+            '_mapping': mapping,
+            '_base': base
+        } ]
+
+        # Each dimension adds "-<name>-<value>" to the name (sanitizing never leaves a "-",
+        # so V3Bootstrap.stats_dimension_tag can pick them back out), and a match on its
+        # header. Envoy counts each request against the first virtual cluster it matches,
+        # and "other" -- which doesn't look at the header at all, so it also gets requests
+        # without one -- comes after every value, so it gets what the values don't.
+        for dimension in mapping.get('stats_dimensions', None) or []:
+            values = [ (re.sub(r'[^0-9A-Za-z_]', '_', value), value) for value in dimension['values'] ]
+            values.append((IRHTTPMapping.StatsDimensionOther, None))
+
+            virtual_clusters = [
+                {
+                    **vc,
+                    'name': f"{vc['name']}-{dimension['name']}-{tag}",
+                    'headers': vc['headers'] + ([ { 'name': dimension['header'], 'exact_match': value } ] if value is not None else [])
+                }
+                for vc in virtual_clusters for tag, value in values
+            ]
+
+        return virtual_clusters
+
+    @staticmethod
+    def generate_hash_policy(config: 'V3Config', mapping_group: IRHTTPMappingGroup) -> dict:
+        hash_policy: Dict[str, Any] = {}
         load_balancer = mapping_group.get('load_balancer', None)
         if load_balancer is not None:
             lb_policy = load_balancer.get('policy')
@@ -751,6 +1403,7 @@ class V3Route(Cacheable):
                 cookie = load_balancer.get('cookie')
                 header = load_balancer.get('header')
                 source_ip = load_balancer.get('source_ip')
+                path = load_balancer.get('path')
 
                 if cookie is not None:
                     hash_policy['cookie'] = {
@@ -768,6 +1421,23 @@ class V3Route(Cacheable):
                     hash_policy['connection_properties'] = {
                         'source_ip': source_ip
                     }
+                elif path is not None:
+                    # :path has the query string too, which would scatter requests for the same
+                    # thing, so the hash only sees what's before it (and maybe not all of that).
+                    segments = path.get('segments', None)
+
+                    if segments:
+                        pattern, substitution = rf'^((?:/[^/?]*){{1,{segments}}})[^?]*(?:\?.*)?$', r'\1'
+                    else:
+                        pattern, substitution = r'\?.*$', ''
+
+                    hash_policy['header'] = {
+                        'header_name': ':path',
+                        'regex_rewrite': {
+                            **regex_matcher(config, pattern, key='pattern', safe_key='pattern'),
+                            'substitution': substitution
+                        }
+                    }
 
         return hash_policy
 
@@ -12,13 +12,17 @@
 # See the License for the specific language governing permissions and
 # limitations under the License
 
-from typing import Callable, Dict, List, Optional, Union, TYPE_CHECKING
+from typing import Any, Callable, Dict, List, Optional, Union, TYPE_CHECKING
 from typing import cast as typecast
 
 import os
+import re
 
 from ...ir.irtlscontext import IRTLSContext
 
+if TYPE_CHECKING:
+    from ...ir import IR # pragma: no cover
+
 # This stuff isn't really accurate, but it'll do for now.
 #
 # XXX It's also a sure sign that this crap needs to be a proper class
@@ -55,6 +59,12 @@ class V3TLSContext(Dict):
 
         self.is_fallback = False
 
+        # Which Secret each of our certs came from (None if it came straight from a file),
+        # in the same order as tls_certificates, and likewise for the CA. See
+        # discover_secrets.
+        self.cert_secret_ids: List[Optional[str]] = []
+        self.ca_secret_id: Optional[str] = None
+
         if ctx:
             self.add_context(ctx)
 
@@ -115,6 +125,27 @@ class V3TLSContext(Dict):
         src: EnvoyCoreSource = { 'filename': value }
         validation[key] = src
 
+    def update_spiffe(self, spiffe_ids: Optional[List[str]], trust_domain: Optional[str]) -> None:
+        # Envoy checks match_subject_alt_names against every SAN in the peer's certificate,
+        # but only URI SANs start with spiffe://, so these can't be satisfied by a DNS name.
+        matchers: List[Dict[str, Any]] = []
+
+        if spiffe_ids:
+            for spiffe_id in spiffe_ids:
+                if '*' not in spiffe_id:
+                    matchers.append({ 'exact': spiffe_id })
+                else:
+                    # IRTLSContext makes sure '*' is only ever a whole path segment.
+                    regex = '/'.join('[^/]+' if segment == '*' else re.escape(segment)
+                                     for segment in spiffe_id.split('/'))
+                    matchers.append({ 'safe_regex': { 'google_re2': {}, 'regex': f"^{regex}$" } })
+        elif trust_domain:
+            matchers.append({ 'prefix': f"spiffe://{trust_domain}/" })
+
+        empty_context: EnvoyValidationContext = {}
+        validation = typecast(Dict[str, Any], self.get_common().setdefault('validation_context', empty_context))
+        validation['match_subject_alt_names'] = matchers
+
     def add_context(self, ctx: IRTLSContext) -> None:
         if TYPE_CHECKING:
             # This is needed because otherwise self.__setitem__ confuses things.
@@ -126,11 +157,39 @@ class V3TLSContext(Dict):
         for secretinfokey, handler, hkey in [
             ( 'cert_chain_file', self.update_cert_zero, 'certificate_chain' ),
             ( 'private_key_file', self.update_cert_zero, 'private_key' ),
+            ( 'ocsp_staple_file', self.update_cert_zero, 'ocsp_staple' ),
             ( 'cacert_chain_file', self.update_validation, 'trusted_ca' ),
         ]:
             if secretinfokey in ctx['secret_info']:
                 handler(hkey, ctx['secret_info'][secretinfokey])
 
+        if self.get('common_tls_context', {}).get('tls_certificates', None):
+            self.cert_secret_ids.append(ctx['secret_info'].get('cert_secret_id', None))
+
+        if 'cacert_chain_file' in ctx['secret_info']:
+            self.ca_secret_id = ctx['secret_info'].get('cacert_secret_id', None)
+
+        # Any more certificates follow the first; the handshake picks whichever suits the
+        # client.
+        for cert in ctx['secret_info'].get('additional_certs', []):
+            envoy_cert: Dict[str, EnvoyCoreSource] = {}
+
+            for secretinfokey, hkey in [
+                ( 'cert_chain_file', 'certificate_chain' ),
+                ( 'private_key_file', 'private_key' ),
+                ( 'ocsp_staple_file', 'ocsp_staple' ),
+            ]:
+                if secretinfokey in cert:
+                    envoy_cert[hkey] = { 'filename': cert[secretinfokey] }
+
+            self.get_certs().append(envoy_cert)
+            self.cert_secret_ids.append(cert.get('cert_secret_id', None))
+
+        ocsp_staple_policy = ctx.get('ocsp_staple_policy', None)
+
+        if ocsp_staple_policy:
+            self['ocsp_staple_policy'] = ocsp_staple_policy.upper()
+
         for ctxkey, handler, hkey in [
             ( 'alpn_protocols', self.update_alpn, 'alpn_protocols' ),
             ( 'cert_required', self.__setitem__, 'require_client_certificate' ),
@@ -156,6 +215,88 @@ class V3TLSContext(Dict):
             if value is not None:
                 list_handler(hkey, value)
 
+        if ctx.get('spiffe_ids', None) or ctx.get('spiffe_trust_domain', None):
+            self.update_spiffe(ctx.get('spiffe_ids', None), ctx.get('spiffe_trust_domain', None))
+
+    def as_upstream(self) -> None:
+        """
+        Drop what only makes sense when terminating TLS: when originating it, Envoy presents
+        exactly one client certificate, and staples nothing.
+        """
+
+        self.pop('ocsp_staple_policy', None)
+
+        certs = self.get('common_tls_context', {}).get('tls_certificates', None)
+
+        if certs:
+            del certs[1:]
+            del self.cert_secret_ids[1:]
+
+            # Without its staple, this cert isn't the same as the Secret's, so it can't share
+            # its SDS secret.
+            if certs[0].pop('ocsp_staple', None):
+                self.cert_secret_ids[0] = None
+
+    def discover_secrets(self, ir: 'IR', secrets: Dict[str, Dict[str, Any]]) -> None:
+        """
+        If the Ambassador module turns on secret_discovery, move the certs (and the CA) that
+        came from Secrets out into SDS secrets, added to secrets, and refer to them by name.
+        They're named for the Secret rather than what's in it, so when a Secret changes, only
+        the SDS secret does: Envoy uses the new cert for new handshakes, and leaves the
+        listener (or cluster) that refers to it, and its connections, alone. Ambex sends
+        these to Envoy along with everything else.
+
+        Envoy won't mix certs it's given with certs it has to go find, so unless every cert
+        came from a Secret, the certs stay where they are.
+        """
+
+        if not ir.ambassador_module.get('secret_discovery', False):
+            return
+
+        common = self.get('common_tls_context', None)
+
+        if not common:
+            return
+
+        certs = common.get('tls_certificates', None)
+
+        if certs and (len(certs) == len(self.cert_secret_ids)) and all(self.cert_secret_ids):
+            configs: List[Dict[str, Any]] = []
+
+            for cert, secret_id in zip(certs, self.cert_secret_ids):
+                secrets.setdefault(secret_id, { 'name': secret_id, 'tls_certificate': cert })
+                configs.append(V3TLSContext.sds_secret_config(secret_id))
+
+            del common['tls_certificates']
+            common['tls_certificate_sds_secret_configs'] = configs
+
+        validation = common.get('validation_context', None)
+
+        if self.ca_secret_id and validation and ('trusted_ca' in validation):
+            secrets.setdefault(self.ca_secret_id, {
+                'name': self.ca_secret_id,
+                'validation_context': { 'trusted_ca': validation['trusted_ca'] }
+            })
+
+            # How the peer gets checked against the CA stays here: Envoy puts the two back
+            # together.
+            del common['validation_context']
+            common['combined_validation_context'] = {
+                'default_validation_context': { k: v for k, v in validation.items() if k != 'trusted_ca' },
+                'validation_context_sds_secret_config': V3TLSContext.sds_secret_config(self.ca_secret_id),
+            }
+
+    @staticmethod
+    def sds_secret_config(name: str) -> Dict[str, Any]:
+        # The secret comes over ADS, like everything else ambex sends.
+        return {
+            'name': name,
+            'sds_config': {
+                'ads': {},
+                'resource_api_version': 'V3',
+            }
+        }
+
     def pretty(self) -> str:
         common_ctx = self.get("common_tls_context", {})
         certs = common_ctx.get("tls_certificates", [])
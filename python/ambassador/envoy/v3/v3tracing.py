@@ -22,6 +22,11 @@ if TYPE_CHECKING:
 
 
 class V3Tracing(dict):
+    OpenCensusTraceContexts = {
+        'b3': 'B3',
+        'w3c': 'TRACE_CONTEXT',
+    }
+
     def __init__(self, config: 'V3Config') -> None:
         # We should never be instantiated unless there is, in fact, defined tracing stuff.
         assert config.ir.tracing
@@ -58,6 +63,21 @@ class V3Tracing(dict):
             driver_config['@type'] = 'type.googleapis.com/envoy.config.trace.v3.DatadogConfig'
             if not driver_config.get('service_name'):
                 driver_config['service_name'] = 'ambassador'
+        elif name.lower() == 'envoy.tracers.opencensus':
+            # OpenCensus doesn't send spans through an Envoy cluster, it dials the agent itself,
+            # so none of the zipkin-flavored driver_config applies here.
+            contexts = [ V3Tracing.OpenCensusTraceContexts[fmt] for fmt in tracing.propagation_formats() ]
+
+            driver_config = {
+                '@type': 'type.googleapis.com/envoy.config.trace.v3.OpenCensusConfig',
+                'ocagent_exporter_enabled': True,
+                'ocagent_address': tracing.service,
+                'outgoing_trace_context': contexts,
+            }
+
+            # With no incoming contexts, every request starts a new trace.
+            if tracing.trust_incoming_trace_context:
+                driver_config['incoming_trace_context'] = contexts
         elif name.lower() == 'envoy.lightstep':
             driver_config['@type'] = 'type.googleapis.com/envoy.config.trace.v3.LightstepConfig'
         else:
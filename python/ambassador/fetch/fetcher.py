@@ -257,6 +257,12 @@ spec:
             # Grab deltas if they're present...
             self.deltas = watt_dict.get('Deltas', [])
 
+            # ...and which snapshot this is, if it says...
+            self.aconf.snapshot_generation = watt_dict.get('Generation', None)
+
+            # ...and what the entrypoint made of the certificates in the secrets we use...
+            self.aconf.certificates = watt_dict.get('Certificates') or []
+
             # ...then it's off to deal with Kubernetes.
             watt_k8s = watt_dict.get('Kubernetes', {})
 
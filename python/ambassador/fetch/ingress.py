@@ -268,4 +268,4 @@ class IngressProcessor (ManagedKubernetesProcessor):
         self._update_status(obj)
 
         # Let's see if our Ingress resource has Ambassador annotations on it
-        self.manager.emit_annotated(NormalizedResource.from_kubernetes_object_annotation(obj))
+        self.manager.emit_annotations(obj)
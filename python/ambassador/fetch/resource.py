@@ -4,6 +4,7 @@ from typing import Any, ClassVar, Dict, List, Optional
 import dataclasses
 import logging
 import os
+import yaml
 
 from ..config import ACResource, Config
 from ..utils import dump_yaml, parse_yaml, parse_bool, dump_json
@@ -23,11 +24,23 @@ class NormalizedResource:
     rkey: Optional[str] = None
     log_resources: ClassVar[bool] = parse_bool(os.environ.get('AMBASSADOR_LOG_RESOURCES'))
 
+    # These annotations are big and are already reflected in the resource itself, so there's
+    # no point in carrying them along.
+    ignored_annotations: ClassVar[List[str]] = [
+        'kubectl.kubernetes.io/last-applied-configuration',
+        'getambassador.io/config',
+    ]
+
+    @classmethod
+    def annotations_of(cls, obj: KubernetesObject) -> Dict[str, str]:
+        return { k: v for k, v in obj.annotations.items() if k not in cls.ignored_annotations }
+
     @classmethod
     def from_data(cls, kind: str, name: str, namespace: Optional[str] = None,
                   generation: Optional[int] = None, version: str = 'v3alpha1',
                   api_group = 'getambassador.io',
                   labels: Optional[Dict[str, Any]] = None,
+                  annotations: Optional[Dict[str, str]] = None,
                   spec: Dict[str, Any] = None, errors: Optional[str] = None,
                   rkey: Optional[str] = None) -> NormalizedResource:
         if rkey is None:
@@ -49,6 +62,9 @@ class NormalizedResource:
 
         ir_obj['metadata_labels'] = labels or {}
 
+        if annotations:
+            ir_obj['metadata_annotations'] = annotations
+
         if errors:
             ir_obj['errors'] = errors
 
@@ -77,6 +93,7 @@ class NormalizedResource:
             version=obj.gvk.version,
             api_group=obj.gvk.api_group,
             labels=labels,
+            annotations=cls.annotations_of(obj),
             spec=obj.spec,
         )
 
@@ -87,11 +104,20 @@ class NormalizedResource:
             return []
 
         def clean_normalize(r: Dict[str, Any]) -> NormalizedResource:
+            # Something that isn't a resource at all gets reported when it's emitted.
+            if not isinstance(r, dict):
+                return NormalizedResource(r, rkey=f'{obj.name}.{obj.namespace}')
+
             # Annotations should have to pass manual object validation.
             r['_force_validation'] = True
 
             if r.get('metadata_labels') is None and obj.labels:
                 r['metadata_labels'] = obj.labels
+            if r.get('metadata_annotations') is None:
+                annotations = cls.annotations_of(obj)
+
+                if annotations:
+                    r['metadata_annotations'] = annotations
             if r.get('namespace') is None and obj.scope == KubernetesObjectScope.NAMESPACE:
                 r['namespace'] = obj.namespace
 
@@ -187,3 +213,18 @@ class ResourceManager:
         with self.locations.mark_annotated():
             for resource in resources:
                 self.emit(resource)
+
+    def emit_annotations(self, obj: KubernetesObject):
+        """
+        Emits whatever is in a Kubernetes object's getambassador.io/config
+        annotation. An annotation that isn't valid YAML is an error for that
+        object, not a reason to give up on the rest of the configuration.
+        """
+        try:
+            resources = NormalizedResource.from_kubernetes_object_annotation(obj)
+        except yaml.error.YAMLError as e:
+            self.aconf.post_error(f"{obj.kind} {obj.name}.{obj.namespace}: could not parse getambassador.io/config annotation: {e}",
+                                  rkey=f'{obj.name}.{obj.namespace}')
+            return
+
+        self.emit_annotated(resources)
@@ -22,6 +22,7 @@ class SecretProcessor (ManagedKubernetesProcessor):
     KNOWN_DATA_KEYS = [
         'tls.crt',         # type="kubernetes.io/tls"
         'tls.key',         # type="kubernetes.io/tls"
+        'tls.ocsp-staple', # type="kubernetes.io/tls", optional
         'user.key',        # type="Opaque", used for AES ACME
         'cert-chain.pem',  # type="istio.io/key-and-cert"
         'key.pem',         # type="istio.io/key-and-cert"
@@ -44,11 +45,20 @@ class SecretProcessor (ManagedKubernetesProcessor):
 
     def _process(self, obj: KubernetesObject) -> None:
         secret_type = obj.get('type')
+        data = obj.get('data')
+
+        # Remember what every secret looks like, including the ones we're about to ignore, so
+        # that whatever refers to one of those can say why it won't work. Just the key names,
+        # mind you, not the data.
+        self.manager.aconf.secret_types[f'{obj.name}.{obj.namespace}'] = {
+            'type': secret_type,
+            'keys': sorted((data or {}).keys()),
+        }
+
         if secret_type not in self.KNOWN_TYPES:
             self.logger.debug("ignoring K8s Secret with unknown type %s" % secret_type)
             return
 
-        data = obj.get('data')
         if not data:
             self.logger.debug("ignoring K8s Secret with no data")
             return
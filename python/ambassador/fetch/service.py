@@ -103,7 +103,7 @@ class InternalServiceProcessor (ManagedKubernetesProcessor):
 
         # Although we can't emit this resource immediately, we can handle
         # anything added as an annotation.
-        self.manager.emit_annotated(NormalizedResource.from_kubernetes_object_annotation(obj))
+        self.manager.emit_annotations(obj)
 
 
 class InternalEndpointsProcessor (ManagedKubernetesProcessor):
@@ -11,12 +11,13 @@
 # WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 # See the License for the specific language governing permissions and
 # limitations under the License
-from typing import Any, Callable, Dict, Iterable, List, Optional, Tuple, Union, ValuesView
+from typing import Any, Callable, Dict, Iterable, List, Optional, Set, Tuple, Union, ValuesView
 from typing import cast as typecast
 
 import json
 import logging
 import os
+import time
 
 from ipaddress import ip_address
 
@@ -26,6 +27,7 @@ from ..utils import RichStatus, SavedSecret, SecretHandler, SecretInfo, dump_jso
 from ..cache import Cache, NullCache
 from ..config import Config
 from ..fetch import ResourceFetcher
+from ..fetch.secret import SecretProcessor
 
 from .irresource import IRResource
 from .irambassador import IRAmbassador
@@ -80,6 +82,7 @@ class IR:
     aconf: Config
     cache: Cache
     clusters: Dict[str, IRCluster]
+    contested_cluster_names: Set[str]
     agent_active: bool
     agent_service: Optional[str]
     agent_origination_ctx: Optional[IRTLSContext]
@@ -263,6 +266,7 @@ class IR:
 
         self.breakers = {}
         self.clusters = {}
+        self.contested_cluster_names = set()
         self.filters = []
         self.groups = {}
         self.grpc_services = {}
@@ -278,6 +282,7 @@ class IR:
         self.saved_secrets = {}
         self.secret_info = {}
         self.services = {}
+        self.k8s_services: Set[Tuple[str, str]] = set()
         self.sidecar_cluster_name = None
         self.tls_contexts = {}
         self.tls_module = None
@@ -339,6 +344,9 @@ class IR:
             # Uhoh.
             self.ambassador_module.set_active(False)    # This can't be good.
 
+        # With the Ambassador module finalized, we know how early to warn about certificates.
+        self.check_certificates(aconf)
+
         _activity_str = 'watching' if watch_only else 'starting'
         _mode_str = 'OSS'
 
@@ -367,6 +375,10 @@ class IR:
         self.outliers = aconf.get_config("OutlierDetection") or {}
         self.services = aconf.get_config("service") or {}
 
+        # Mappings get checked against the Kubernetes Services (as opposed to the Consul ones),
+        # by name and namespace.
+        self.k8s_services = { (svc.name, svc.namespace) for key, svc in self.services.items() if key.startswith('k8s-') }
+
         # Save tracing, ratelimit, and logging settings.
         self.tracing = typecast(IRTracing, self.save_resource(IRTracing(self, aconf)))
         self.ratelimit = typecast(IRRateLimit, self.save_resource(IRRateLimit(self, aconf)))
@@ -375,12 +387,24 @@ class IR:
         # After the Ambassador and TLS modules are done, we need to set up the
         # filter chains. Note that order of the filters matters. Start with auth,
         # since it needs to be able to override everything...
+        #
+        # ...unless the Ambassador module's cors_before_auth is set. Then the
+        # non-configurable cors filter goes first, so that it answers CORS preflight
+        # requests itself: a preflight never carries credentials, so auth would just
+        # reject it.
+        cors_filter = IRFilter(ir=self, aconf=aconf,
+                               rkey="ir.cors", kind="ir.cors", name="cors",
+                               config={})
+        cors_before_auth = self.ambassador_module.get('cors_before_auth', False)
+
+        if cors_before_auth:
+            self.save_filter(cors_filter)
+
         self.save_filter(IRAuth(self, aconf))
 
         # ...then deal with the non-configurable cors filter...
-        self.save_filter(IRFilter(ir=self, aconf=aconf,
-                                  rkey="ir.cors", kind="ir.cors", name="cors",
-                                  config={}))
+        if not cors_before_auth:
+            self.save_filter(cors_filter)
 
         # ...then the ratelimit filter...
         if self.ratelimit:
@@ -429,19 +453,27 @@ class IR:
         collisions: Dict[str, List[str]] = {}
 
         for name in sorted(self.clusters.keys()):
-            if len(name) > 60:
+            cluster = self.clusters[name]
+            full_name = name
+
+            # A cluster that kept a contested name (see IRCluster) is named for its
+            # settings, like the clusters that couldn't have that name, so that which
+            # one got there first doesn't matter.
+            if name in self.contested_cluster_names:
+                full_name = f"{name}_{IRCluster.variant_digest(cluster, cluster._resolver)}"
+
+            if len(full_name) > 60:
                 # Too long. Gather this cluster by name prefix and normalize
                 # its name below.
-                short_name = name[0:40]
+                short_name = full_name[0:40]
 
-                cluster = self.clusters[name]
-                self.logger.debug(f"COLLISION: compress {name} to {short_name}")
+                self.logger.debug(f"COLLISION: compress {full_name} to {short_name}")
 
                 collision_list = collisions.setdefault(short_name, [])
                 collision_list.append(name)
             else:
                 # Short enough, set the envoy name to the cluster name.
-                self.clusters[name]['envoy_name'] = name
+                cluster['envoy_name'] = full_name
 
         for short_name in sorted(collisions.keys()):
             name_list = collisions[short_name]
@@ -732,6 +764,38 @@ class IR:
                 self.logger.debug('saving "%s.%s" (from %s) in secret_info', secret_name, secret_namespace, secret_key)
                 self.secret_info[f'{secret_name}.{secret_namespace}'] = secret_info
 
+    # Warn about the certificates, in the secrets we use, that expire soon or already have. The
+    # entrypoint does the parsing, since it has a real X.509 parser; all we do here is decide what
+    # to say about it.
+    def check_certificates(self, aconf: Config) -> None:
+        aconf_secrets = aconf.get_config("secrets") or {}
+        warning_days = self.ambassador_module.get('cert_expiry_warning_days', IRAmbassador.DefaultCertExpiryWarningDays)
+        now = time.time()
+
+        for cert in aconf.certificates:
+            secret_key = f"{cert.get('name')}.{cert.get('namespace')}"
+            resource = aconf_secrets.get(secret_key, None)
+
+            if not resource:
+                # Not a secret we made anything of, so nothing refers to it.
+                continue
+
+            error = cert.get('error', None)
+
+            if error:
+                self.post_error(f"Secret {secret_key}: {error}", resource=resource)
+                continue
+
+            not_after = cert.get('notAfter', 0)
+            expiry = time.strftime('%Y-%m-%dT%H:%M:%SZ', time.gmtime(not_after))
+            subject = cert.get('subject') or 'with no common name'
+
+            if not_after <= now:
+                self.post_error(f"Secret {secret_key}: certificate {subject} expired at {expiry}", resource=resource)
+            elif not_after - now < warning_days * 86400:
+                days = int((not_after - now) // 86400)
+                aconf.post_notice(f"certificate {subject} expires at {expiry}, in {days} days", resource=resource)
+
     def save_tls_context(self, ctx: IRTLSContext) -> None:
         extant_ctx = self.tls_contexts.get(ctx.name, None)
         is_valid = True
@@ -805,6 +869,45 @@ class IR:
             self.saved_secrets[secret_name] = ss
         return ss
 
+    # Work out why a secret that resolve_secret couldn't make a certificate out of won't work:
+    # usually it's the wrong type of secret, or a TLS secret with half of it missing. A CA
+    # certificate only needs tls.crt; anything else needs tls.crt and tls.key. This returns None
+    # if the secret looks fine, or if we've never seen it at all, since a secret that doesn't
+    # exist yet (say, one ACME or cert-manager is still issuing) isn't a mismatch.
+    def secret_mismatch(self, secret_name: str, namespace: str, ca: bool=False) -> Optional[str]:
+        seen = self.aconf.secret_types.get(f'{secret_name}.{namespace}', None)
+
+        if not seen:
+            return None
+
+        secret_type = seen['type']
+        keys = seen['keys']
+
+        if secret_type == 'istio.io/key-and-cert':
+            needed = [ 'cert-chain.pem' ] if ca else [ 'cert-chain.pem', 'key.pem' ]
+        else:
+            needed = [ 'tls.crt' ] if ca else [ 'tls.crt', 'tls.key' ]
+
+        missing = [ key for key in needed if key not in keys ]
+
+        # An Opaque secret is fine as long as it has the right keys, so one with none of them
+        # is as much the wrong type as anything we don't know about. CA secrets are Opaque as
+        # often as not, though, since they rarely have a key.
+        if ca and (secret_type not in SecretProcessor.KNOWN_TYPES):
+            mismatch = f"it has type {secret_type}, but a CA certificate needs type kubernetes.io/tls or Opaque"
+        elif (secret_type not in SecretProcessor.KNOWN_TYPES) or \
+             ((secret_type == 'Opaque') and not ca and (len(missing) == len(needed))):
+            mismatch = f"it has type {secret_type}, but a certificate needs type kubernetes.io/tls"
+        elif missing:
+            mismatch = f"it has type {secret_type} but no {' or '.join(missing)}"
+        else:
+            return None
+
+        if ca and ('tls.crt' in missing) and ('ca.crt' in keys):
+            mismatch += " (the CA certificate goes in tls.crt, not ca.crt)"
+
+        return mismatch
+
     def resolve_resolver(self, cluster: IRCluster, resolver_name: Optional[str]) -> IRServiceResolver:
         # Which resolver should we use?
         if not resolver_name:
@@ -863,18 +966,18 @@ class IR:
             getattr(res, method_name)(self, aconf)
 
     def save_listener(self, listener: IRListener) -> None:
-        listener_key = listener.bind_to()
-
-        extant_listener = self.listeners.get(listener_key, None)
-        is_valid = True
-
-        if extant_listener:
-            self.post_error("Duplicate listener %s on %s:%d; keeping definition from %s" %
-                            (listener.name, listener.bind_address, listener.port, extant_listener.location))
-            is_valid = False
+        listener_key = listener.listener_key()
+
+        # Two Listeners fighting over a port would keep Envoy from starting, so the one that
+        # got here first wins and the other one is an error.
+        for extant_listener in self.listeners.values():
+            if listener.conflicts_with(extant_listener):
+                self.post_error("Duplicate listener %s on %s:%d; keeping definition from %s" %
+                                (listener.name, listener.bind_address, listener.port, extant_listener.location),
+                                resource=listener)
+                return
 
-        if is_valid:
-            self.listeners[listener_key] = listener
+        self.listeners[listener_key] = listener
 
     def add_mapping(self, aconf: Config, mapping: IRBaseMapping) -> Optional[IRBaseMappingGroup]:
         mapping.check_status()
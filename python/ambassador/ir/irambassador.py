@@ -1,5 +1,8 @@
 from typing import Any, ClassVar, Dict, List, Optional, TYPE_CHECKING
 
+import re
+import urllib.parse
+
 from ..constants import Constants
 
 from ..config import Config
@@ -8,12 +11,15 @@ from .irresource import IRResource
 from .iripallowdeny import IRIPAllowDeny
 from .irbasemapping import IRBaseMapping
 from .irhttpmapping import IRHTTPMapping
+from .irhost import IRHost
+from .ircluster import IRCluster
 from .irtls import IRAmbassadorTLS
 from .irtlscontext import IRTLSContext
 from .ircors import IRCORS
 from .irretrypolicy import IRRetryPolicy
 from .irbuffer import IRBuffer
 from .irgzip import IRGzip
+from .irdecompressor import IRDecompressor
 from .irfilter import IRFilter
 
 if TYPE_CHECKING:
@@ -29,58 +35,123 @@ class IRAmbassador (IRResource):
 
     AModTransparentKeys: ClassVar = [
         'add_linkerd_headers',
+        'admin_idle_timeout_ms',
         'admin_port',
         'auth_enabled',
         'allow_chunked_length',
         'buffer_limit_bytes',
+        'cert_expiry_warning_days',
         'circuit_breakers',
+        'cluster_drain_time_ms',
         'cluster_idle_timeout_ms',
         'cluster_max_connection_lifetime_ms',
         'cluster_request_timeout_ms',
+        'cluster_stats_names',
+        'cluster_warming_timeout_ms',
+        'config_version_header',
+        'cookie_attributes',
+        'correlate_request_id',
+        'cors_before_auth',
+        'echo_request_id',
         'debug_mode',
         # Do not include defaults, that's handled manually in setup.
         'default_label_domain',
         'default_labels',
+        'default_listener',
         'diagnostics',
+        'diagnostics_annotations',
+        'diagnostics_labels',
+        'dns_failure_refresh_rate_ms',
+        'downstream_keepalive',
         'enable_http10',
         'enable_ipv4',
         'enable_ipv6',
+        'enable_trailers',
+        'envoy_component_log_levels',
         'envoy_log_format',
+        'envoy_log_level',
         'envoy_log_path',
         'envoy_log_type',
         'forward_client_cert_details',
         # Do not include envoy_validation_timeout; we let finalize() type-check it.
         # Do not include ip_allow or ip_deny; we let finalize() type-check them.
+        'header_sanitization',
         'headers_with_underscores_action',
+        'histogram_buckets',
+        'hsts',
+        'http2_keepalive',
+        'initial_fetch_timeout_ms',
         'keepalive',
         'listener_idle_timeout_ms',
         'liveness_probe',
         'load_balancer',
+        'max_header_value_bytes',
+        'max_headers_count',
         'max_request_headers_kb',
+        'max_response_headers_count',
         'merge_slashes',
+        'mesh_mtls',
+        'namespace_route_prefixes',
         'reject_requests_with_escaped_slashes',
         'preserve_external_request_id',
         'proper_case',
         'prune_unreachable_routes',
+        'readiness_healthy_cluster_fraction',
         'readiness_probe',
         'regex_max_size',
         'regex_type',
+        'request_headers_timeout_ms',
+        'request_smuggling_protection',
+        'request_timeout_header',
         'resolver',
+        'resolver_fallback',
+        'response_flag_stats_limit',
         'error_response_overrides',
         'header_case_overrides',
+        'secret_discovery',
         'server_name',
         'service_port',
         'set_current_client_cert_details',
+        'shadow_clone_cluster',
+        'snapshot_export',
+        'snapshot_shed',
+        'snapshot_soft_limit_bytes',
+        'stats_dimensions_limit',
         'statsd',
+        'strict_host_matching',
+        'strict_host_matching_status',
         'strip_matching_host_port',
         'suppress_envoy_headers',
+        'trailing_slash',
+        'upstream_bind_address',
         'use_ambassador_namespace_for_service_resolution',
         'use_proxy_proto',
         'use_remote_address',
+        'validate_service_references',
+        'warn_on_mapping_conflicts',
         'x_forwarded_proto_redirect',
         'xff_num_trusted_hops',
+        'zero_replicas',
     ]
 
+    # A request_headers_timeout_ms under this is allowed, but it's liable to cut off clients on
+    # slow networks that are doing nothing wrong.
+    ShortRequestHeadersTimeoutMs: ClassVar[int] = 1000
+
+    # request_smuggling_protection (and a Listener's requestSmugglingProtection) can be one of
+    # these. "strict" is what Envoy does anyway; "legacy" is for clients that can't cope with it.
+    RequestSmugglingProtections: ClassVar[List[str]] = [ 'strict', 'legacy' ]
+
+    # config_version_header: true uses this name.
+    DefaultConfigVersionHeader: ClassVar[str] = 'x-emissary-config-version'
+
+    # Without a cert_expiry_warning_days, a certificate gets a notice this long before it expires:
+    # time enough to renew it by hand if automatic renewal has quietly stopped working.
+    DefaultCertExpiryWarningDays: ClassVar[int] = 14
+
+    # These are the levels that Envoy's /logging admin endpoint accepts.
+    ValidEnvoyLogLevels: ClassVar = [ 'trace', 'debug', 'info', 'warning', 'warn', 'error', 'critical', 'off' ]
+
     service_port: int
     default_label_domain: str
 
@@ -236,6 +307,22 @@ class IRAmbassador (IRResource):
                 if not cur.get('service', None):
                     cur['service'] = diag_service
 
+        # Envoy can only limit the headers as a whole, so a cap on any one header value is a
+        # Lua filter, first in the chain so that nothing else sees an oversized header.
+        if amod and ('max_header_value_bytes' in amod):
+            max_bytes = amod.max_header_value_bytes
+
+            if isinstance(max_bytes, bool) or not isinstance(max_bytes, int) or (max_bytes < 1):
+                self.post_error(f"max_header_value_bytes {max_bytes} must be a positive integer")
+                return False
+
+            self.max_header_value_bytes = IRFilter(ir=ir, aconf=aconf,
+                                                   kind='ir.max_header_value_bytes',
+                                                   name='max_header_value_bytes',
+                                                   config={ 'max_bytes': max_bytes })
+            self.max_header_value_bytes.sourced_by(amod)
+            ir.save_filter(self.max_header_value_bytes)
+
         if amod and ('enable_grpc_http11_bridge' in amod):
             self.grpc_http11_bridge = IRFilter(ir=ir, aconf=aconf,
                                                kind='ir.grpc_http1_bridge',
@@ -301,6 +388,27 @@ class IRAmbassador (IRResource):
             else:
                 return False
 
+        # Decompressor. This has to come after gzip so that, on the way back to the client,
+        # upstream responses are decompressed before gzip gets a chance to compress them.
+        if amod and ('decompressor' in amod):
+            self.decompressor = IRDecompressor(ir=ir, aconf=aconf, location=self.location, **amod.decompressor)
+
+            if self.decompressor:
+                ir.save_filter(self.decompressor)
+            else:
+                return False
+
+            # Envoy's decompressor can't limit how much it inflates, so a buffer filter right
+            # after it holds decompressed request bodies to max_request_bytes, answering 413
+            # past that. Like any buffer filter, it buffers every request body on its routes.
+            if self.decompressor.get('max_request_bytes', None):
+                self.decompressor_limit = IRFilter(ir=ir, aconf=aconf,
+                                                   kind='ir.decompressor_limit',
+                                                   name='decompressor_limit',
+                                                   config={ 'max_request_bytes': self.decompressor.max_request_bytes })
+                self.decompressor_limit.sourced_by(amod)
+                ir.save_filter(self.decompressor_limit)
+
          # Buffer.
         if amod and ('buffer' in amod):
             self.buffer = IRBuffer(ir=ir, aconf=aconf, location=self.location, **amod.buffer)
@@ -310,6 +418,74 @@ class IRAmbassador (IRResource):
             else:
                 return False
 
+        # The dynamic forward proxy filter and clusters have to share one DNS cache, so its
+        # config lives here rather than on the Mappings that use it.
+        if amod and ('dynamic_forward_proxy' in amod):
+            dns_cache = amod.dynamic_forward_proxy or {}
+
+            if not isinstance(dns_cache, dict):
+                self.post_error("dynamic_forward_proxy must be a dictionary")
+                return False
+
+            for key in dns_cache.keys():
+                if key not in ('dns_refresh_rate_ms', 'host_ttl_ms', 'max_hosts'):
+                    self.post_error(f"dynamic_forward_proxy: unknown key {key}")
+                    return False
+
+                value = dns_cache[key]
+
+                if not isinstance(value, int) or (value < 1):
+                    self.post_error(f"dynamic_forward_proxy: {key} {value} must be a positive integer")
+                    return False
+
+            self.dynamic_forward_proxy = IRFilter(ir=ir, aconf=aconf,
+                                                  kind='ir.dynamic_forward_proxy',
+                                                  name='dynamic_forward_proxy',
+                                                  config=dict(dns_cache))
+            self.dynamic_forward_proxy.sourced_by(amod)
+            ir.save_filter(self.dynamic_forward_proxy)
+
+        # A global cap on requests in flight, past which Envoy answers 503. Envoy's only way
+        # to do that is its adaptive concurrency filter, so a fixed limit is that filter held
+        # as still as it goes: see IRAmbassador.concurrency_limit_config.
+        if amod and ('concurrency_limit' in amod):
+            error = IRAmbassador.check_concurrency_limit(amod.concurrency_limit)
+
+            if error:
+                self.post_error(f"Invalid concurrency_limit: {error}")
+                return False
+
+            self.concurrency_limit = IRFilter(ir=ir, aconf=aconf,
+                                              kind='ir.concurrency_limit',
+                                              name='concurrency_limit',
+                                              config=IRAmbassador.concurrency_limit_config(amod.concurrency_limit))
+            self.concurrency_limit.sourced_by(amod)
+            ir.save_filter(self.concurrency_limit)
+
+        # jwt_providers are the JWT issuers that Mappings can require a JWT from. Envoy fetches
+        # the keys for a provider with a jwks_uri itself, so each of those needs a cluster.
+        if amod and ('jwt_providers' in amod):
+            error = IRAmbassador.check_jwt_providers(amod.jwt_providers)
+
+            if error:
+                self.post_error(f"Invalid jwt_providers: {error}")
+                return False
+
+            self.jwt_providers: Dict[str, Dict[str, Any]] = {}
+
+            for provider_name, provider in amod.jwt_providers.items():
+                provider = dict(provider)
+                jwks_uri = provider.get('jwks_uri', None)
+
+                if jwks_uri:
+                    parsed = urllib.parse.urlparse(jwks_uri)
+                    cluster = IRCluster(ir=ir, aconf=aconf, parent_ir_resource=self, location=self.location,
+                                        service=f"{parsed.scheme}://{parsed.netloc}", marker='jwks')
+                    cluster.referenced_by(self)
+                    provider['cluster'] = ir.add_cluster(cluster).name
+
+                self.jwt_providers[provider_name] = provider
+
         if amod and ('keepalive' in amod):
             self.keepalive = amod['keepalive']
 
@@ -345,10 +521,350 @@ class IRAmbassador (IRResource):
                 self.ip_allow_deny = None
 
         if self.get('load_balancer', None) is not None:
+            lb_policy = self['load_balancer'].get('policy', None)
+
+            # The Module's load_balancer is the default for every Mapping, so there's no
+            # Mapping-specific hash policy to fall back on: call that out specifically.
+            if (lb_policy in [ 'ring_hash', 'maglev' ]) and \
+               not any(k in self['load_balancer'] for k in [ 'cookie', 'header', 'source_ip', 'path' ]):
+                self.post_error("Invalid load_balancer specified: policy {} requires a cookie, header, source_ip, or path to hash on".format(lb_policy))
+                return False
+
+            error = IRHTTPMapping.check_choice_count(self['load_balancer'])
+
+            if error:
+                self.post_error(f"Invalid load_balancer specified: {error}")
+                return False
+
             if not IRHTTPMapping.validate_load_balancer(self['load_balancer']):
                 self.post_error("Invalid load_balancer specified: {}".format(self['load_balancer']))
                 return False
 
+        # These are cluster defaults; IRCluster checks the per-Mapping overrides the same way.
+        for key, minimum in [ ('cluster_drain_time_ms', 0), ('dns_failure_refresh_rate_ms', 2), ('initial_fetch_timeout_ms', 0), ('cluster_warming_timeout_ms', 1) ]:
+            value = self.get(key, None)
+
+            if (value is not None) and ((not isinstance(value, int)) or (value < minimum)):
+                self.post_error(f"Invalid {key} specified: {value}. Must be an integer of at least {minimum}")
+                return False
+
+        # cluster_warming_timeout_ms caps how long any new cluster can keep Envoy waiting, so
+        # it wins over a longer (or endless) initial_fetch_timeout_ms. Note that Envoy's config
+        # validation never warms anything, so a cluster that can't warm still counts as good
+        # config, and it's up to this timeout -- not the last-known-good fallback -- to keep it
+        # from holding up everything else.
+        cluster_warming_timeout_ms = self.get('cluster_warming_timeout_ms', None)
+        initial_fetch_timeout_ms = self.get('initial_fetch_timeout_ms', None)
+
+        if cluster_warming_timeout_ms and (initial_fetch_timeout_ms is not None) and \
+           ((initial_fetch_timeout_ms == 0) or (initial_fetch_timeout_ms > cluster_warming_timeout_ms)):
+            aconf.post_notice(f"initial_fetch_timeout_ms {initial_fetch_timeout_ms} is capped at cluster_warming_timeout_ms {cluster_warming_timeout_ms}")
+
+        http2_keepalive = self.get('http2_keepalive', None)
+
+        if http2_keepalive is not None:
+            http2_keepalive_error = IRCluster.validate_http2_keepalive(http2_keepalive)
+
+            if http2_keepalive_error is not None:
+                self.post_error(f"Invalid http2_keepalive specified: {http2_keepalive_error}")
+                # Every gRPC cluster looks at this, so don't leave it lying around.
+                del self['http2_keepalive']
+                return False
+
+        admin_idle_timeout_ms = self.get('admin_idle_timeout_ms', None)
+
+        if (admin_idle_timeout_ms is not None) and ((not isinstance(admin_idle_timeout_ms, int)) or (admin_idle_timeout_ms < 0)):
+            self.post_error(f"Invalid admin_idle_timeout_ms specified: {admin_idle_timeout_ms}. Must be a non-negative integer")
+            return False
+
+        response_flag_stats_limit = self.get('response_flag_stats_limit', None)
+
+        if (response_flag_stats_limit is not None) and ((not isinstance(response_flag_stats_limit, int)) or (response_flag_stats_limit < 0)):
+            self.post_error(f"Invalid response_flag_stats_limit specified: {response_flag_stats_limit}. Must be a non-negative integer")
+            del self['response_flag_stats_limit']
+            return False
+
+        stats_dimensions_limit = self.get('stats_dimensions_limit', None)
+
+        if (stats_dimensions_limit is not None) and ((not isinstance(stats_dimensions_limit, int)) or (stats_dimensions_limit < 1)):
+            self.post_error(f"Invalid stats_dimensions_limit specified: {stats_dimensions_limit}. Must be a positive integer")
+            del self['stats_dimensions_limit']
+            return False
+
+        histogram_buckets = self.get('histogram_buckets', None)
+
+        if histogram_buckets is not None:
+            # Turning histograms off is done one Mapping at a time, with disable_histograms.
+            error = IRHTTPMapping.check_histogram_buckets(histogram_buckets)
+
+            if error:
+                self.post_error(f"Invalid histogram_buckets specified: {error}")
+                del self['histogram_buckets']
+                return False
+
+        config_version_header = self.get('config_version_header', None)
+
+        if config_version_header is not None:
+            # Plenty of people won't want this in production, so false is the same as not
+            # setting it.
+            if config_version_header is True:
+                self['config_version_header'] = IRAmbassador.DefaultConfigVersionHeader
+            elif config_version_header is False:
+                del self['config_version_header']
+            elif isinstance(config_version_header, str) and re.match(r"^[A-Za-z0-9!#$%&'*+.^_`|~-]+$", config_version_header):
+                self['config_version_header'] = config_version_header.lower()
+            else:
+                self.post_error(f"Invalid config_version_header {config_version_header}: must be true, false, or a header name")
+                del self['config_version_header']
+                return False
+
+        cert_expiry_warning_days = self.get('cert_expiry_warning_days', None)
+
+        if (cert_expiry_warning_days is not None) and \
+           ((isinstance(cert_expiry_warning_days, bool)) or
+            (not isinstance(cert_expiry_warning_days, (int, float))) or
+            (cert_expiry_warning_days < 0)):
+            self.post_error(f"Invalid cert_expiry_warning_days specified: {cert_expiry_warning_days}. Must be a number of days, 0 or more")
+            del self['cert_expiry_warning_days']
+            return False
+
+        cluster_stats_names = self.get('cluster_stats_names', None)
+
+        if (cluster_stats_names is not None) and (cluster_stats_names not in IRCluster.StatsNameSchemes):
+            self.post_error(f"Invalid cluster_stats_names {cluster_stats_names}: must be one of {', '.join(IRCluster.StatsNameSchemes)}")
+            del self['cluster_stats_names']
+            return False
+
+        trailing_slash = self.get('trailing_slash', None)
+
+        if (trailing_slash is not None) and (trailing_slash not in IRHTTPMapping.TrailingSlashModes):
+            self.post_error(f"Invalid trailing_slash {trailing_slash}: must be one of {', '.join(IRHTTPMapping.TrailingSlashModes)}")
+            del self['trailing_slash']
+            return False
+
+        shadow_clone_cluster = self.get('shadow_clone_cluster', None)
+
+        if (shadow_clone_cluster is not None) and not isinstance(shadow_clone_cluster, bool):
+            self.post_error(f"Invalid shadow_clone_cluster {shadow_clone_cluster}: must be true or false")
+            del self['shadow_clone_cluster']
+            return False
+
+        cors_before_auth = self.get('cors_before_auth', None)
+
+        if (cors_before_auth is not None) and not isinstance(cors_before_auth, bool):
+            self.post_error(f"Invalid cors_before_auth {cors_before_auth}: must be true or false")
+            del self['cors_before_auth']
+            return False
+
+        cookie_attributes = self.get('cookie_attributes', None)
+
+        if cookie_attributes is not None:
+            error = IRHTTPMapping.check_cookie_attributes(cookie_attributes)
+
+            if error:
+                self.post_error(f"Invalid cookie_attributes specified: {error}")
+                # Every Mapping without one of its own looks at this.
+                del self['cookie_attributes']
+                return False
+
+        # readiness_healthy_cluster_fraction is read by the entrypoint rather than by anything
+        # here, so this is just about telling people when it won't work.
+        readiness_healthy_cluster_fraction = self.get('readiness_healthy_cluster_fraction', None)
+
+        if (readiness_healthy_cluster_fraction is not None) and \
+           ((isinstance(readiness_healthy_cluster_fraction, bool)) or
+            (not isinstance(readiness_healthy_cluster_fraction, (int, float))) or
+            (readiness_healthy_cluster_fraction < 0) or (readiness_healthy_cluster_fraction > 1)):
+            self.post_error(f"Invalid readiness_healthy_cluster_fraction specified: {readiness_healthy_cluster_fraction}. Must be a number from 0 to 1")
+            del self['readiness_healthy_cluster_fraction']
+            return False
+
+        # snapshot_export is another one for the entrypoint.
+        snapshot_export = self.get('snapshot_export', None)
+
+        if snapshot_export is not None:
+            error = IRAmbassador.check_snapshot_export(snapshot_export)
+
+            if error:
+                self.post_error(f"Invalid snapshot_export specified: {error}")
+                del self['snapshot_export']
+                return False
+
+        # So are the snapshot soft limit settings.
+        snapshot_soft_limit_bytes = self.get('snapshot_soft_limit_bytes', None)
+
+        if (snapshot_soft_limit_bytes is not None) and \
+           ((isinstance(snapshot_soft_limit_bytes, bool)) or
+            (not isinstance(snapshot_soft_limit_bytes, int)) or (snapshot_soft_limit_bytes < 0)):
+            self.post_error(f"Invalid snapshot_soft_limit_bytes specified: {snapshot_soft_limit_bytes}. Must be a non-negative integer")
+            del self['snapshot_soft_limit_bytes']
+            return False
+
+        snapshot_shed = self.get('snapshot_shed', None)
+
+        if (snapshot_shed is not None) and not isinstance(snapshot_shed, bool):
+            self.post_error(f"Invalid snapshot_shed specified: {snapshot_shed}. Must be true or false")
+            del self['snapshot_shed']
+            return False
+
+        # secret_discovery moves certs out into SDS secrets (see V3TLSContext.discover_secrets).
+        secret_discovery = self.get('secret_discovery', None)
+
+        if (secret_discovery is not None) and not isinstance(secret_discovery, bool):
+            self.post_error(f"Invalid secret_discovery specified: {secret_discovery}. Must be true or false")
+            del self['secret_discovery']
+            return False
+
+        correlate_request_id = self.get('correlate_request_id', None)
+
+        if (correlate_request_id is not None) and not isinstance(correlate_request_id, bool):
+            self.post_error(f"Invalid correlate_request_id specified: {correlate_request_id}. Must be true or false")
+            del self['correlate_request_id']
+            return False
+
+        echo_request_id = self.get('echo_request_id', None)
+
+        if (echo_request_id is not None) and not isinstance(echo_request_id, bool):
+            self.post_error(f"Invalid echo_request_id specified: {echo_request_id}. Must be true or false")
+            del self['echo_request_id']
+            return False
+
+        warn_on_mapping_conflicts = self.get('warn_on_mapping_conflicts', None)
+
+        if (warn_on_mapping_conflicts is not None) and not isinstance(warn_on_mapping_conflicts, bool):
+            self.post_error(f"Invalid warn_on_mapping_conflicts specified: {warn_on_mapping_conflicts}. Must be true or false")
+            del self['warn_on_mapping_conflicts']
+            return False
+
+        namespace_route_prefixes = self.get('namespace_route_prefixes', None)
+
+        if (namespace_route_prefixes is not None) and not isinstance(namespace_route_prefixes, bool):
+            self.post_error(f"Invalid namespace_route_prefixes specified: {namespace_route_prefixes}. Must be true or false")
+            # Every Mapping looks at this, so don't leave it lying around.
+            del self['namespace_route_prefixes']
+            return False
+
+        validate_service_references = self.get('validate_service_references', None)
+
+        if (validate_service_references is not None) and not isinstance(validate_service_references, bool):
+            self.post_error(f"Invalid validate_service_references specified: {validate_service_references}. Must be true or false")
+            del self['validate_service_references']
+            return False
+
+        request_headers_timeout_ms = self.get('request_headers_timeout_ms', None)
+
+        if request_headers_timeout_ms is not None:
+            error = IRAmbassador.check_request_headers_timeout(request_headers_timeout_ms)
+
+            if error:
+                self.post_error(f"Invalid request_headers_timeout_ms specified: {error}")
+                # Every Listener without one of its own looks at this.
+                del self['request_headers_timeout_ms']
+                return False
+
+            if 0 < request_headers_timeout_ms < IRAmbassador.ShortRequestHeadersTimeoutMs:
+                self.ir.aconf.post_notice(f"request_headers_timeout_ms {request_headers_timeout_ms} is short enough to cut off slow clients", resource=self)
+
+        request_smuggling_protection = self.get('request_smuggling_protection', None)
+
+        if request_smuggling_protection is not None:
+            if request_smuggling_protection not in IRAmbassador.RequestSmugglingProtections:
+                self.post_error(f"Invalid request_smuggling_protection specified: {request_smuggling_protection}. Must be strict or legacy")
+                del self['request_smuggling_protection']
+                return False
+
+            if request_smuggling_protection == 'legacy':
+                self.ir.aconf.post_notice("request_smuggling_protection legacy lets requests with both Content-Length and Transfer-Encoding through, which can allow request smuggling", resource=self)
+
+        # Which resolvers these are can't be checked until the resolvers are loaded: each Mapping
+        # using them checks that.
+        resolver_fallback = self.get('resolver_fallback', None)
+
+        if resolver_fallback is not None:
+            error = IRHTTPMapping.check_resolver_fallback(resolver_fallback)
+
+            if error:
+                self.post_error(f"Invalid resolver_fallback specified: {error}")
+                del self['resolver_fallback']
+                return False
+
+        # Knative's services never look empty, even scaled to zero: its activator stands in
+        # for them until they're back, so this never kicks in for them.
+        zero_replicas = self.get('zero_replicas', None)
+
+        if zero_replicas is not None:
+            error = IRHTTPMapping.check_zero_replicas(zero_replicas)
+
+            if error:
+                self.post_error(f"Invalid zero_replicas specified: {error}")
+                del self['zero_replicas']
+                return False
+
+        request_timeout_header = self.get('request_timeout_header', None)
+
+        if request_timeout_header is not None:
+            error = IRHTTPMapping.check_request_timeout_header(request_timeout_header)
+
+            if error:
+                self.post_error(f"Invalid request_timeout_header specified: {error}")
+                # Every Mapping without one of its own looks at this.
+                del self['request_timeout_header']
+                return False
+
+        strict_host_matching_status = self.get('strict_host_matching_status', None)
+
+        if (strict_host_matching_status is not None) and (strict_host_matching_status not in ( 404, 421 )):
+            self.post_error(f"Invalid strict_host_matching_status specified: {strict_host_matching_status}. Must be 404 or 421")
+            return False
+
+        default_listener = self.get('default_listener', None)
+
+        if default_listener is not None:
+            error = IRAmbassador.check_default_listener(default_listener)
+
+            if error:
+                self.post_error(f"Invalid default_listener specified: {error}")
+                del self['default_listener']
+                return False
+
+        header_sanitization = self.get('header_sanitization', None)
+
+        if header_sanitization is not None:
+            error = IRAmbassador.check_header_sanitization(header_sanitization)
+
+            if error:
+                self.post_error(f"Invalid header_sanitization specified: {error}")
+                # Every route looks at this, so don't leave it lying around.
+                del self['header_sanitization']
+                return False
+
+            # Header names are case-insensitive, and Envoy wants them lowercase.
+            self['header_sanitization'] = {
+                'strip_hop_by_hop': bool(header_sanitization.get('strip_hop_by_hop', False)),
+                'sensitive_headers': [ hdr.lower() for hdr in header_sanitization.get('sensitive_headers', []) ]
+            }
+
+        hsts = self.get('hsts', None)
+
+        if hsts is not None:
+            error = IRHost.check_hsts(hsts)
+
+            if error:
+                self.post_error(f"Invalid hsts specified: {error}")
+                del self['hsts']
+                return False
+
+            notice = IRHost.hsts_preload_notice(hsts)
+
+            if notice:
+                self.ir.aconf.post_notice(notice, resource=self)
+
+        mesh_mtls = self.get('mesh_mtls', None)
+
+        if (mesh_mtls is not None) and (mesh_mtls not in IRTLSContext.MeshMTLSProviders):
+            self.post_error("Invalid mesh_mtls specified: {}. Supported: {}".format(mesh_mtls, ", ".join(sorted(IRTLSContext.MeshMTLSProviders))))
+            return False
+
         if self.get('circuit_breakers', None) is not None:
             if not IRBaseMapping.validate_circuit_breakers(self.ir, self['circuit_breakers']):
                 self.post_error("Invalid circuit_breakers specified: {}".format(self['circuit_breakers']))
@@ -372,6 +888,36 @@ class IRAmbassador (IRResource):
             self.post_error("Invalid log_type specified: {}. Supported: json, text".format(self.get('envoy_log_type')))
             return False
 
+        log_level = self.get('envoy_log_level', None)
+        if (log_level is not None) and (log_level not in IRAmbassador.ValidEnvoyLogLevels):
+            self.post_error("Invalid envoy_log_level specified: {}. Supported: {}".format(
+                log_level, ', '.join(IRAmbassador.ValidEnvoyLogLevels)))
+            return False
+
+        component_log_levels = self.get('envoy_component_log_levels', None)
+        if component_log_levels is not None:
+            if not isinstance(component_log_levels, dict):
+                self.post_error("envoy_component_log_levels must be a dictionary of component names to log levels: {}".format(
+                    component_log_levels))
+                return False
+
+            for component, level in component_log_levels.items():
+                if level not in IRAmbassador.ValidEnvoyLogLevels:
+                    self.post_error("Invalid envoy_component_log_levels level for {}: {}. Supported: {}".format(
+                        component, level, ', '.join(IRAmbassador.ValidEnvoyLogLevels)))
+                    return False
+
+        # diagnostics_labels and diagnostics_annotations list the label and annotation keys
+        # to show for Mappings and Hosts in the diagnostics. A bad list isn't worth refusing
+        # the Module over.
+        for key in [ 'diagnostics_labels', 'diagnostics_annotations' ]:
+            value = self.get(key, None)
+
+            if value is not None:
+                if not isinstance(value, list) or not all(isinstance(x, str) for x in value):
+                    self.ir.post_error(f"{key} must be a list of strings, ignoring: {value}", resource=self)
+                    del self[key]
+
         if self.get('forward_client_cert_details') is not None:
             # https://www.envoyproxy.io/docs/envoy/latest/api-v3/extensions/filters/network/http_connection_manager/v3/http_connection_manager.proto#envoy-v3-api-enum-extensions-filters-network-http-connection-manager-v3-httpconnectionmanager-forwardclientcertdetails
             valid_values = ('SANITIZE', 'FORWARD_ONLY', 'APPEND_FORWARD', 'SANITIZE_SET', 'ALWAYS_FORWARD_ONLY')
@@ -402,6 +948,273 @@ class IRAmbassador (IRResource):
 
         return True
 
+    def envoy_log_levels(self) -> List[str]:
+        """
+        Return the Envoy log levels requested by this module, in the order in which they
+        need to be applied: the overall level first, so that it doesn't clobber the
+        per-component levels.
+        """
+
+        levels: List[str] = []
+
+        if self.get('envoy_log_level', None):
+            levels.append(self.envoy_log_level)
+
+        for component, level in sorted((self.get('envoy_component_log_levels', None) or {}).items()):
+            levels.append(f"{component}:{level}")
+
+        return levels
+
+    @staticmethod
+    def check_snapshot_export(snapshot_export: Any) -> Optional[str]:
+        """
+        Return what's wrong with a snapshot_export, or None if nothing is.
+        """
+
+        if not isinstance(snapshot_export, dict):
+            return f"{snapshot_export} is not a dictionary"
+
+        path = snapshot_export.get('path', None)
+
+        # Exports only ever go in the entrypoint's export directory, so the path is just a
+        # file name there.
+        if not isinstance(path, str) or (path in [ '', '.', '..' ]) or ('/' in path) or ('\\' in path):
+            return f"path {path} must be a file name, with no directory"
+
+        for key in [ 'interval_s', 'keep' ]:
+            value = snapshot_export.get(key, None)
+
+            if (value is not None) and \
+               ((isinstance(value, bool)) or (not isinstance(value, (int, float))) or (value < 0)):
+                return f"{key} {value} must be a number, 0 or more"
+
+        keep = snapshot_export.get('keep', None)
+
+        if (keep is not None) and not isinstance(keep, int):
+            return f"keep {keep} must be a whole number of files"
+
+        return None
+
+    @staticmethod
+    def check_request_headers_timeout(request_headers_timeout_ms: Any) -> Optional[str]:
+        """
+        Return what's wrong with a request headers timeout, or None if nothing is.
+        """
+
+        # bool is an int, but true isn't a timeout.
+        if not isinstance(request_headers_timeout_ms, int) or isinstance(request_headers_timeout_ms, bool) or (request_headers_timeout_ms < 0):
+            return f"{request_headers_timeout_ms} must be a non-negative integer (0 turns the timeout off)"
+
+        return None
+
+    # The concurrency_limit keys for each mode, with their defaults. (Envoy requires the two
+    # intervals, so they always get a value.)
+    ConcurrencyLimitKeys: ClassVar[Dict[str, Dict[str, int]]] = {
+        'adaptive': {
+            'max_concurrency': 1000,
+            'sample_percentile': 50,
+            'update_interval_ms': 100,
+            'min_rtt_interval_ms': 60000,
+            'min_rtt_request_count': 50,
+            'min_rtt_jitter_percent': 10,
+            'min_rtt_buffer_percent': 25,
+            'min_concurrency': 3,
+        },
+        'fixed': {
+            'limit': 0,
+        },
+    }
+
+    # Keys that are percentages rather than counts or times.
+    ConcurrencyLimitPercentKeys: ClassVar[List[str]] = [
+        'sample_percentile', 'min_rtt_jitter_percent', 'min_rtt_buffer_percent'
+    ]
+
+    @staticmethod
+    def check_concurrency_limit(concurrency_limit: Any) -> Optional[str]:
+        """
+        Return what's wrong with a concurrency_limit, or None if nothing is.
+        """
+
+        if not isinstance(concurrency_limit, dict):
+            return f"{concurrency_limit} must be a dictionary"
+
+        mode = concurrency_limit.get('mode', 'adaptive')
+
+        if mode not in IRAmbassador.ConcurrencyLimitKeys:
+            return f"mode {mode} must be one of {', '.join(IRAmbassador.ConcurrencyLimitKeys.keys())}"
+
+        allowed = IRAmbassador.ConcurrencyLimitKeys[mode]
+        unknown = set(concurrency_limit.keys()) - set(allowed.keys()) - { 'mode' }
+
+        if unknown:
+            return f"unknown keys for mode {mode}: {', '.join(sorted(unknown))}"
+
+        if (mode == 'fixed') and ('limit' not in concurrency_limit):
+            return "mode fixed needs a limit"
+
+        for key in allowed.keys():
+            value = concurrency_limit.get(key, allowed[key])
+
+            if not isinstance(value, int) or isinstance(value, bool):
+                return f"{key} {value} must be an integer"
+
+            if key in IRAmbassador.ConcurrencyLimitPercentKeys:
+                if not (0 <= value <= 100):
+                    return f"{key} {value} must be between 0 and 100"
+            elif value < 1:
+                return f"{key} {value} must be positive"
+
+        if mode == 'adaptive':
+            min_concurrency = concurrency_limit.get('min_concurrency', allowed['min_concurrency'])
+            max_concurrency = concurrency_limit.get('max_concurrency', allowed['max_concurrency'])
+
+            if min_concurrency > max_concurrency:
+                return f"min_concurrency {min_concurrency} can't be more than max_concurrency {max_concurrency}"
+
+        return None
+
+    @staticmethod
+    def concurrency_limit_config(concurrency_limit: Dict[str, Any]) -> Dict[str, int]:
+        """
+        Return the gradient controller settings for a valid concurrency_limit.
+
+        A fixed limit starts out at, and can never go over, its limit. The controller still
+        measures latency, but with the largest buffer it allows, so it only backs off when
+        requests take more than twice as long as they do when Envoy is idle -- that is, when
+        the backends really are drowning.
+        """
+
+        mode = concurrency_limit.get('mode', 'adaptive')
+
+        if mode == 'fixed':
+            limit = concurrency_limit['limit']
+
+            return {
+                'max_concurrency': limit,
+                'sample_percentile': 50,
+                'update_interval_ms': 100,
+                'min_rtt_interval_ms': 300000,
+                'min_rtt_request_count': 50,
+                'min_rtt_jitter_percent': 0,
+                'min_rtt_buffer_percent': 100,
+                'min_concurrency': limit,
+            }
+
+        config = dict(IRAmbassador.ConcurrencyLimitKeys['adaptive'])
+        config.update({ k: v for k, v in concurrency_limit.items() if k != 'mode' })
+
+        return config
+
+    @staticmethod
+    def check_jwt_providers(jwt_providers: Any) -> Optional[str]:
+        """
+        Return what's wrong with a jwt_providers, or None if nothing is.
+        """
+
+        if not isinstance(jwt_providers, dict) or not jwt_providers:
+            return f"{jwt_providers} must be a dictionary of provider names to providers"
+
+        for provider_name, provider in jwt_providers.items():
+            if not isinstance(provider, dict):
+                return f"{provider_name}: {provider} must be a dictionary"
+
+            unknown = set(provider.keys()) - { 'issuer', 'audiences', 'jwks_uri', 'jwks', 'forward' }
+
+            if unknown:
+                return f"{provider_name}: unknown keys {', '.join(sorted(unknown))}"
+
+            issuer = provider.get('issuer', None)
+
+            if (issuer is not None) and (not isinstance(issuer, str) or not issuer):
+                return f"{provider_name}: issuer must be a string"
+
+            audiences = provider.get('audiences', None)
+
+            if (audiences is not None) and (not isinstance(audiences, list) or
+                                            not all(isinstance(aud, str) and aud for aud in audiences)):
+                return f"{provider_name}: audiences must be a list of strings"
+
+            forward = provider.get('forward', None)
+
+            if (forward is not None) and not isinstance(forward, bool):
+                return f"{provider_name}: forward must be true or false"
+
+            # Envoy needs the keys from exactly one place.
+            jwks_uri = provider.get('jwks_uri', None)
+            jwks = provider.get('jwks', None)
+
+            if (jwks_uri is None) == (jwks is None):
+                return f"{provider_name}: exactly one of jwks_uri or jwks is required"
+
+            if jwks_uri is not None:
+                parsed = urllib.parse.urlparse(jwks_uri) if isinstance(jwks_uri, str) else None
+
+                if not parsed or (parsed.scheme not in [ 'http', 'https' ]) or not parsed.hostname:
+                    return f"{provider_name}: jwks_uri {jwks_uri} must be an http or https URL"
+
+            if (jwks is not None) and (not isinstance(jwks, str) or not jwks):
+                return f"{provider_name}: jwks must be a JWKS, as a string"
+
+        return None
+
+    @staticmethod
+    def check_default_listener(default_listener: Any) -> Optional[str]:
+        """
+        Return what's wrong with a default_listener, or None if nothing is.
+        """
+
+        if not isinstance(default_listener, dict):
+            return f"{default_listener} must be a dictionary"
+
+        unknown = set(default_listener.keys()) - { 'port', 'status', 'body' }
+
+        if unknown:
+            return f"unknown keys {', '.join(sorted(unknown))}"
+
+        port = default_listener.get('port', 8080)
+
+        if not isinstance(port, int) or isinstance(port, bool) or not (1 <= port <= 65535):
+            return f"port {port} must be between 1 and 65535"
+
+        status = default_listener.get('status', 404)
+
+        if not isinstance(status, int) or isinstance(status, bool) or not (200 <= status <= 599):
+            return f"status {status} must be between 200 and 599"
+
+        body = default_listener.get('body', None)
+
+        if (body is not None) and not isinstance(body, str):
+            return "body must be a string"
+
+        return None
+
+    @staticmethod
+    def check_header_sanitization(header_sanitization: Any) -> Optional[str]:
+        """
+        Return what's wrong with a header_sanitization, or None if nothing is.
+        """
+
+        if not isinstance(header_sanitization, dict):
+            return f"{header_sanitization} must be a dictionary"
+
+        unknown = set(header_sanitization.keys()) - { 'strip_hop_by_hop', 'sensitive_headers' }
+
+        if unknown:
+            return f"unknown keys {', '.join(sorted(unknown))}"
+
+        sensitive_headers = header_sanitization.get('sensitive_headers', [])
+
+        if not isinstance(sensitive_headers, list) or not all(isinstance(hdr, str) and hdr for hdr in sensitive_headers):
+            return "sensitive_headers must be a list of header names"
+
+        # Envoy won't remove these, and the request makes no sense without them.
+        for hdr in sensitive_headers:
+            if hdr.startswith(':') or (hdr.lower() == 'host'):
+                return f"sensitive_headers can't include {hdr}"
+
+        return None
+
     def add_mappings(self, ir: 'IR', aconf: Config):
         for name, cur in [
             ( "liveness",    self.liveness_probe ),
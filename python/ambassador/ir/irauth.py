@@ -2,7 +2,7 @@ from typing import Optional, TYPE_CHECKING
 from typing import cast as typecast
 
 from ..config import Config
-from ..utils import RichStatus
+from ..utils import RichStatus, parse_bool
 from ..resource import Resource
 
 from .irfilter import IRFilter
@@ -16,6 +16,14 @@ if TYPE_CHECKING:
 class IRAuth (IRFilter):
     cluster: Optional[IRCluster]
 
+    # The 4xx and 5xx codes Envoy will take for status_on_error; it rejects the whole filter
+    # for anything it doesn't have a name for.
+    StatusOnErrorCodes = frozenset([
+        400, 401, 402, 403, 404, 405, 406, 407, 408, 409, 410, 411, 412, 413, 414, 415, 416, 417,
+        421, 422, 423, 424, 426, 428, 429, 431,
+        500, 501, 502, 503, 504, 505, 506, 507, 508, 510, 511,
+    ])
+
     def __init__(self, ir: 'IR', aconf: Config,
                  rkey: str="ir.auth",
                  kind: str="IRAuth",
@@ -149,13 +157,25 @@ class IRAuth (IRFilter):
         self.__to_header_list('allowed_request_headers', module)
         self.__to_header_list('allowed_authorization_headers', module)
 
+        # If the AuthService can't be reached, Envoy either lets the request through
+        # (failure_mode_allow) or fails it with status_on_error, which defaults to 403.
         status_on_error = module.get('status_on_error', None)
         if status_on_error:
-            self['status_on_error'] = status_on_error
+            code = status_on_error.get('code', None)
+
+            if isinstance(code, bool) or (code not in IRAuth.StatusOnErrorCodes):
+                self.ir.aconf.post_error("AuthService status_on_error.code %s is not a 4xx or 5xx status Envoy supports; using 403" % code,
+                                         resource=module)
+            else:
+                self['status_on_error'] = status_on_error
 
         failure_mode_allow = module.get('failure_mode_allow', None)
-        if failure_mode_allow:
-            self['failure_mode_allow'] = failure_mode_allow
+        if failure_mode_allow is not None:
+            self['failure_mode_allow'] = parse_bool(failure_mode_allow)
+
+            if self['failure_mode_allow'] and status_on_error:
+                self.ir.aconf.post_notice("AuthService status_on_error does nothing with failure_mode_allow, since requests are allowed when the AuthService fails",
+                                          resource=module)
 
         # Required fields check.
         if self["api_version"] == None:
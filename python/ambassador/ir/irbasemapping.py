@@ -1,6 +1,6 @@
 import json
 
-from typing import Any, ClassVar, Dict, List, Optional, Tuple, Union, TYPE_CHECKING
+from typing import Any, ClassVar, Dict, List, Optional, Set, Tuple, Union, TYPE_CHECKING
 
 from urllib.parse import scheme_chars, urlparse
 
@@ -165,6 +165,8 @@ class IRBaseMapping (IRResource):
         if not isinstance(circuit_breakers, (list, tuple)):
             return False
 
+        seen_priorities: Set[str] = set()
+
         for circuit_breaker in circuit_breakers:
             if '_name' in circuit_breaker:
                 # Already reconciled.
@@ -175,8 +177,16 @@ class IRBaseMapping (IRResource):
 
             name_fields = [ 'cb' ]
 
+            prio = str(circuit_breaker.get('priority', 'default')).lower()
+
+            # Envoy keeps one set of thresholds per priority, so a second one for the same
+            # priority would quietly replace the first.
+            if prio in seen_priorities:
+                return False
+
+            seen_priorities.add(prio)
+
             if 'priority' in circuit_breaker:
-                prio = circuit_breaker.get('priority').lower()
                 if prio not in ['default', 'high']:
                     return False
 
@@ -187,7 +197,8 @@ class IRBaseMapping (IRResource):
             digit_fields = [ ( 'max_connections', 'c' ),
                              ( 'max_pending_requests', 'p' ),
                              ( 'max_requests', 'r' ),
-                             ( 'max_retries', 't' ) ]
+                             ( 'max_retries', 't' ),
+                             ( 'max_connection_pools', 'l' ) ]
 
             for field, abbrev in digit_fields:
                 if field in circuit_breaker:
@@ -197,6 +208,12 @@ class IRBaseMapping (IRResource):
                     except ValueError:
                         return False
 
+            # A retry budget (see hedging) is only ever set by Ambassador itself.
+            retry_budget = circuit_breaker.get('retry_budget', None)
+
+            if retry_budget:
+                name_fields.append(f"b{retry_budget['budget_percent']}m{retry_budget['min_retry_concurrency']}")
+
             circuit_breaker['_name'] = ''.join(name_fields)
             ir.logger.debug(f'Breaker valid: {circuit_breaker["_name"]}')
 
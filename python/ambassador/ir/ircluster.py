@@ -15,6 +15,8 @@
 from typing import Any, ClassVar, Dict, List, Optional, Union, TYPE_CHECKING
 from typing import cast as typecast
 
+import hashlib
+import ipaddress
 import json
 import re
 import urllib.parse
@@ -39,6 +41,30 @@ if TYPE_CHECKING:
 
 
 class IRCluster (IRResource):
+    # An http2_keepalive interval below this gets a notice.
+    HTTP2KeepAliveShortIntervalMs = 1000
+
+    # The settings that are already part of a cluster's name. (host_rewrite is only a cluster
+    # setting when originating TLS, and then it's in the name; without TLS it goes on the route.)
+    NameKeys: ClassVar[List[str]] = [ 'host_rewrite', 'originate_tls' ]
+
+    # The settings that change what Envoy does with a cluster, but aren't already part of
+    # its name. Two clusters with the same name can only be the same cluster if they agree on
+    # all of these, and on the NameKeys.
+    VariantKeys: ClassVar[List[str]] = [
+        'type', 'lb_type', 'tls_context', 'grpc', 'connect_timeout_ms', 'cluster_idle_timeout_ms',
+        'cluster_max_connection_lifetime_ms', 'upstream_bind_address', 'dns_failure_refresh_rate_ms',
+        'initial_fetch_timeout_ms', 'cluster_drain_time_ms', 'http2_keepalive', 'health_checks',
+        'cluster_protocol_options', 'dynamic_forward_proxy', 'keepalive', 'respect_dns_ttl',
+        'enable_ipv4', 'enable_ipv6', 'stats_name', 'auto_sni', 'auto_http_protocol',
+        'max_requests_per_connection', 'aggregate_clusters',
+    ]
+
+    # How the Ambassador Module's cluster_stats_names can name stats for clusters without a
+    # stats_name: "legacy" turns everything odd in the service into underscores, and
+    # "prometheus" keeps the service, namespace, and port apart (see prometheus_stats_name).
+    StatsNameSchemes: ClassVar[List[str]] = [ 'legacy', 'prometheus' ]
+
     def __init__(self, ir: 'IR', aconf: Config, parent_ir_resource: 'IRResource',
                  location: str,  # REQUIRED
 
@@ -47,11 +73,14 @@ class IRCluster (IRResource):
                  connect_timeout_ms: Optional[int] = 3000,
                  cluster_idle_timeout_ms: Optional[int] = None,
                  cluster_max_connection_lifetime_ms: Optional[int] = None,
+                 max_requests_per_connection: Optional[int] = None,
                  marker: Optional[str] = None,  # extra marker for this context name
                  stats_name: Optional[str] = None, # Override the stats name for this cluster
 
                  ctx_name: Optional[Union[str, bool]]=None,
                  host_rewrite: Optional[str]=None,
+                 auto_sni: Optional[bool]=False,
+                 auto_http_protocol: Optional[bool]=False,
 
                  dns_type: Optional[str]="strict_dns",
                  enable_ipv4: Optional[bool]=None,
@@ -63,6 +92,16 @@ class IRCluster (IRResource):
                  keepalive: Optional[dict] = None,
                  circuit_breakers: Optional[list] = None,
                  respect_dns_ttl: Optional[bool] = False,
+                 upstream_bind_address: Optional[str] = None,
+                 dns_failure_refresh_rate_ms: Optional[int] = None,
+                 initial_fetch_timeout_ms: Optional[int] = None,
+                 cluster_drain_time_ms: Optional[int] = None,
+                 http2_keepalive: Optional[Dict[str, Any]] = None,
+                 health_checks: Optional[List[Dict[str, Any]]] = None,
+                 cluster_protocol_options: Optional[Dict[str, Dict[str, Any]]] = None,
+                 dynamic_forward_proxy: Optional[bool] = False,
+                 histogram_buckets: Optional[List[int]] = None,
+                 aggregate_clusters: Optional[List[str]] = None,
 
                  rkey: str="-override-",
                  kind: str="IRCluster",
@@ -203,6 +242,47 @@ class IRCluster (IRResource):
         # TLS. Kind of odd, but there we go.)
         url = "tcp://%s:%d" % (hostname, port)
 
+        # auto_sni sends each request's Host as the SNI, so it needs a cluster of its own. For
+        # a request with no usable Host (none at all, or an IP address, which Envoy won't use
+        # as an SNI), Envoy falls back to the TLSContext's sni -- and if that's not set either,
+        # we'd rather send the host we're connecting to (or the one every request is rewritten
+        # to) than nothing.
+        auto_sni_fallback: Optional[str] = None
+
+        if auto_sni:
+            if not originate_tls:
+                ir.aconf.post_notice(f"{service}: auto_sni only applies when originating TLS, ignoring", resource=parent_ir_resource)
+                auto_sni = False
+            else:
+                name_fields.append('asni')
+
+                if not (ctx and ctx.get('sni', None)):
+                    for candidate in [ host_rewrite, hostname ]:
+                        if not candidate:
+                            continue
+
+                        try:
+                            ipaddress.ip_address(candidate)
+                        except ValueError:
+                            auto_sni_fallback = candidate
+                            break
+
+        # Envoy picks between HTTP/2 and HTTP/1.1 with ALPN, so this needs TLS: in cleartext
+        # there's nothing to negotiate with, and Envoy would refuse the cluster.
+        if auto_http_protocol and not originate_tls:
+            ir.aconf.post_notice(f"{service}: auto_http_protocol only applies when originating TLS, ignoring", resource=parent_ir_resource)
+            auto_http_protocol = False
+
+        # A dynamic forward proxy cluster has no endpoints of its own, so it mustn't be
+        # merged with a cluster that does.
+        if dynamic_forward_proxy:
+            name_fields.append('dfp')
+
+        # Nor must an aggregate of clusters (see resolver_fallback and zero_replicas), which only
+        # picks one of them.
+        if aggregate_clusters:
+            name_fields.append('fallback')
+
         # Is there a circuit breaker involved here?
         if circuit_breakers:
             for breaker in circuit_breakers:
@@ -216,6 +296,61 @@ class IRCluster (IRResource):
                     name_fields.append(f'cbu{unknown_breakers}')
                     unknown_breakers += 1
 
+        # Binding to a specific source address needs its own cluster, since Envoy configures
+        # that per-cluster. The Ambassador module's upstream_bind_address applies to every
+        # cluster, so we only need to name the per-Mapping override -- once we know it's an
+        # address at all. One that isn't falls back to the Module's, like having none.
+        if upstream_bind_address and not IRCluster.valid_ip_address(upstream_bind_address):
+            errors.append(f"{service}: upstream_bind_address {upstream_bind_address} is not a valid IP address, ignoring")
+            upstream_bind_address = None
+
+        if upstream_bind_address:
+            name_fields.append(f"sa-{upstream_bind_address}")
+        else:
+            upstream_bind_address = ir.ambassador_module.get('upstream_bind_address', None)
+
+            if upstream_bind_address and not IRCluster.valid_ip_address(upstream_bind_address):
+                errors.append(f"{service}: the Ambassador module's upstream_bind_address {upstream_bind_address} is not a valid IP address, ignoring")
+                upstream_bind_address = None
+
+        # Envoy needs the DNS failure backoff to start above 1ms. An initial fetch timeout of 0
+        # is fine, though: it means wait forever for endpoints.
+        if (dns_failure_refresh_rate_ms is not None) and \
+           ((not isinstance(dns_failure_refresh_rate_ms, int)) or (dns_failure_refresh_rate_ms <= 1)):
+            errors.append(f"{service}: dns_failure_refresh_rate_ms {dns_failure_refresh_rate_ms} must be an integer greater than 1, ignoring")
+            dns_failure_refresh_rate_ms = None
+
+        if (initial_fetch_timeout_ms is not None) and \
+           ((not isinstance(initial_fetch_timeout_ms, int)) or (initial_fetch_timeout_ms < 0)):
+            errors.append(f"{service}: initial_fetch_timeout_ms {initial_fetch_timeout_ms} must be a non-negative integer, ignoring")
+            initial_fetch_timeout_ms = None
+
+        # A health-checked cluster isn't warm until every endpoint has had its first check,
+        # which can take as long as the check's timeout.
+        cluster_warming_timeout_ms = ir.ambassador_module.get('cluster_warming_timeout_ms', None)
+
+        if cluster_warming_timeout_ms and health_checks:
+            slowest_timeout_ms = max([ hc.get('timeout_ms', 0) for hc in health_checks ])
+
+            if slowest_timeout_ms > cluster_warming_timeout_ms:
+                ir.aconf.post_notice(f"{service}: health check timeout {slowest_timeout_ms}ms can keep this cluster warming past cluster_warming_timeout_ms {cluster_warming_timeout_ms}")
+
+        if (cluster_drain_time_ms is not None) and \
+           ((not isinstance(cluster_drain_time_ms, int)) or (cluster_drain_time_ms < 0)):
+            errors.append(f"{service}: cluster_drain_time_ms {cluster_drain_time_ms} must be a non-negative integer, ignoring")
+            cluster_drain_time_ms = None
+
+        # HTTP/2 PINGs only make sense on HTTP/2 connections.
+        if http2_keepalive is not None:
+            http2_keepalive_error = IRCluster.validate_http2_keepalive(http2_keepalive)
+
+            if (http2_keepalive_error is None) and not grpc:
+                http2_keepalive_error = "http2_keepalive only applies to grpc services"
+
+            if http2_keepalive_error is not None:
+                errors.append(f"{service}: {http2_keepalive_error}, ignoring")
+                http2_keepalive = None
+
         # The Ambassador module will always have a load_balancer (which may be None).
         global_load_balancer = ir.ambassador_module.load_balancer
 
@@ -254,6 +389,14 @@ class IRCluster (IRResource):
                     if 'source_ip' in load_balancer:
                         key_fields.append('srcip')
 
+                    if 'path' in load_balancer:
+                        key_fields.append('path')
+                        key_fields.append(str(load_balancer['path'].get('segments', 'all')))
+
+                    if 'choice_count' in load_balancer:
+                        key_fields.append('cc')
+                        key_fields.append(str(load_balancer['choice_count']))
+
                     name_fields.append("-".join(key_fields))
 
         # Finally we can construct the cluster name.
@@ -286,15 +429,50 @@ class IRCluster (IRResource):
             'connect_timeout_ms': connect_timeout_ms,
             'cluster_idle_timeout_ms': cluster_idle_timeout_ms,
             'cluster_max_connection_lifetime_ms': cluster_max_connection_lifetime_ms,
+            'max_requests_per_connection': max_requests_per_connection,
             'respect_dns_ttl': respect_dns_ttl,
         }
 
+        if upstream_bind_address:
+            new_args['upstream_bind_address'] = upstream_bind_address
+
+        if dns_failure_refresh_rate_ms is not None:
+            new_args['dns_failure_refresh_rate_ms'] = dns_failure_refresh_rate_ms
+
+        if initial_fetch_timeout_ms is not None:
+            new_args['initial_fetch_timeout_ms'] = initial_fetch_timeout_ms
+
+        if cluster_drain_time_ms is not None:
+            new_args['cluster_drain_time_ms'] = cluster_drain_time_ms
+
+        if http2_keepalive is not None:
+            new_args['http2_keepalive'] = http2_keepalive
+
+        if health_checks:
+            new_args['health_checks'] = health_checks
+
+        if cluster_protocol_options:
+            new_args['cluster_protocol_options'] = cluster_protocol_options
+
+        if dynamic_forward_proxy:
+            new_args['dynamic_forward_proxy'] = True
+
+        if aggregate_clusters:
+            new_args['aggregate_clusters'] = aggregate_clusters
+
+        # Histogram buckets aren't part of the cluster's config at all: Envoy applies them by
+        # stats name, in the bootstrap. (See V3Bootstrap.) No buckets means no histograms.
+        if histogram_buckets is not None:
+            new_args['histogram_buckets'] = histogram_buckets
+
         # If we have a stats_name, use it. If not, default it to the service to make life
         # easier for people trying to find stats later -- but translate unusual characters
         # to underscores, just in case.
 
         if stats_name:
             new_args['stats_name'] = stats_name
+        elif ir.ambassador_module.get('cluster_stats_names', 'legacy') == 'prometheus':
+            new_args['stats_name'] = IRCluster.prometheus_stats_name(hostname, namespace, port)
         else:
             new_args['stats_name'] = re.sub(r'[^0-9A-Za-z_]', '_', service)
 
@@ -304,12 +482,35 @@ class IRCluster (IRResource):
         if host_rewrite:
             new_args['host_rewrite'] = host_rewrite
 
+        if auto_http_protocol:
+            new_args['auto_http_protocol'] = True
+
+        if auto_sni:
+            new_args['auto_sni'] = True
+
+            if auto_sni_fallback:
+                new_args['auto_sni_fallback'] = auto_sni_fallback
+
         if originate_tls:
             if ctx:
                 new_args['tls_context'] = typecast(IRTLSContext, ctx)
             else:
                 new_args['tls_context'] = IRTLSContext.null_context(ir=ir)
 
+        # Mappings for the same service share a cluster, but only if they agree on everything
+        # that goes into it. One that doesn't agree with the cluster already there gets a
+        # cluster of its own, named for its settings, so that every other Mapping with those
+        # same settings shares that one. Which Mapping gets there first depends on the order
+        # they're loaded in, so the name is marked contested, and the IR names the cluster
+        # that kept it for its settings too (see IR.__init__): every variant's envoy_name is
+        # the same no matter who came first.
+        extant = ir.clusters.get(name, None)
+
+        if extant and (IRCluster.variant_settings(extant, extant._resolver) != IRCluster.variant_settings(new_args, resolver)):
+            ir.contested_cluster_names.add(name)
+            name = f"{name}_{IRCluster.variant_digest(new_args, resolver)}"
+            ir.logger.debug(f"IRCluster: {service} differs from extant cluster {extant.name}, using {name}")
+
         if rkey == '-override-':
             rkey = name
 
@@ -342,6 +543,39 @@ class IRCluster (IRResource):
         if self.ignore_cluster:
             return False
 
+        # HTTP/2 over TLS is negotiated with ALPN, and lots of gRPC servers refuse a TLS
+        # connection that didn't offer h2. We offer it by default (see V3Cluster), but if the
+        # TLSContext pins ALPN to something else, Envoy will do what it's told -- so say so.
+        ctx = self.get('tls_context', None)
+
+        if self.get('grpc', False) and ctx and ctx.get('alpn_protocols', None):
+            alpn = [ proto.strip() for proto in ctx.alpn_protocols.split(',') ]
+
+            if 'h2' not in alpn:
+                self.ir.post_error(f"TLSContext {ctx.name} sets alpn_protocols {ctx.alpn_protocols}, which does not include h2; HTTP/2 to {self._hostname} may fail to negotiate", resource=self)
+
+        # With auto_http_protocol, the ALPN offer is what decides the protocol: without h2 it's
+        # always HTTP/1.1, and without http/1.1 there's nothing to fall back to.
+        if self.get('auto_http_protocol', False) and ctx and ctx.get('alpn_protocols', None):
+            alpn = [ proto.strip() for proto in ctx.alpn_protocols.split(',') ]
+
+            for proto in [ 'h2', 'http/1.1' ]:
+                if proto not in alpn:
+                    self.ir.aconf.post_notice(f"TLSContext {ctx.name} sets alpn_protocols {ctx.alpn_protocols}, which does not include {proto}; auto_http_protocol to {self._hostname} cannot use it", resource=self)
+
+        # Lots of gRPC servers hang up on clients that PING more often than they allow.
+        if self.get('grpc', False):
+            http2_keepalive = self.get('http2_keepalive', None) or ir.ambassador_module.get('http2_keepalive', None)
+
+            if http2_keepalive and (http2_keepalive['interval_ms'] < IRCluster.HTTP2KeepAliveShortIntervalMs):
+                self.ir.aconf.post_notice(f"http2_keepalive interval_ms {http2_keepalive['interval_ms']} for {self._hostname} is very short; upstreams may close connections that PING this often", resource=self)
+
+        # Envoy looks up a dynamic forward proxy's hosts itself, one request at a time, and an
+        # aggregate's hosts are its clusters'.
+        if self.get('dynamic_forward_proxy', False) or self.get('aggregate_clusters', None):
+            self.targets = []
+            return True
+
         # Resolve our actual targets.
         targets = ir.resolve_targets(self, self._resolver, self._hostname, self._namespace, self._port)
 
@@ -362,9 +596,73 @@ class IRCluster (IRResource):
 
         return True
 
+    @staticmethod
+    def validate_http2_keepalive(http2_keepalive: Any) -> Optional[str]:
+        """
+        Returns what's wrong with an http2_keepalive from a Mapping or the Ambassador Module,
+        or None if nothing is.
+        """
+        if not isinstance(http2_keepalive, dict):
+            return f"http2_keepalive {http2_keepalive} must be a dictionary"
+
+        # Envoy requires both of these, and won't take anything under a millisecond.
+        for key in ( 'interval_ms', 'timeout_ms' ):
+            value = http2_keepalive.get(key, None)
+
+            if not isinstance(value, int) or (value < 1):
+                return f"http2_keepalive {key} {value} must be a positive integer"
+
+        jitter = http2_keepalive.get('interval_jitter_percent', None)
+
+        if (jitter is not None) and (not isinstance(jitter, int) or not (0 <= jitter <= 100)):
+            return f"http2_keepalive interval_jitter_percent {jitter} must be an integer from 0 to 100"
+
+        return None
+
+    @staticmethod
+    def valid_ip_address(address: Any) -> bool:
+        """
+        Returns whether address is an IPv4 or IPv6 address, as for upstream_bind_address.
+        """
+        try:
+            ipaddress.ip_address(address)
+        except ValueError:
+            return False
+
+        return True
+
     def is_edge_stack_sidecar(self) -> bool:
         return self.is_active() and self._is_sidecar
 
+    @staticmethod
+    def prometheus_stats_name(hostname: str, namespace: str, port: int) -> str:
+        """
+        Return the stats name for a cluster with cluster_stats_names "prometheus":
+        "<service>_<namespace>_<port>". Kubernetes names can't have underscores, so the
+        stats tags from V3Bootstrap can always split it back up. A service may leave out its
+        namespace (and then it's the Mapping's, like Kubernetes DNS would have it) or spell
+        out the cluster domain. Anything else with dots is outside the cluster: its dots
+        become dashes, and the namespace is the Mapping's. The name stays the same when the
+        cluster's own name is too long for Envoy and gets mangled.
+        """
+
+        labels = hostname.lower().split('.')
+
+        if labels[-3:] == [ 'svc', 'cluster', 'local' ]:
+            labels = labels[:-3]
+        elif labels[-1:] == [ 'svc' ]:
+            labels = labels[:-1]
+
+        if len(labels) == 2:
+            service, namespace = labels
+        else:
+            service = '-'.join(labels)
+
+        service = re.sub(r'[^a-z0-9-]', '-', service)
+        namespace = re.sub(r'[^a-z0-9-]', '-', namespace.lower())
+
+        return f"{service}_{namespace}_{port}"
+
     def endpoints_required(self, load_balancer) -> bool:
         required = False
 
@@ -382,13 +680,41 @@ class IRCluster (IRResource):
 
         return self.urls
 
+    @staticmethod
+    def variant_settings(args: Dict[str, Any], resolver: Optional[str]) -> Dict[str, Any]:
+        """
+        The VariantKeys settings (plus the resolver, which decides where the endpoints come
+        from) of a cluster, or of the args for one. TLSContexts count by name.
+        """
+        settings: Dict[str, Any] = { 'resolver': resolver }
+
+        for key in IRCluster.VariantKeys:
+            value = args.get(key, None)
+
+            if key == 'tls_context' and value is not None:
+                value = value.get('name', None)
+
+            settings[key] = value
+
+        return settings
+
+    @staticmethod
+    def variant_digest(args: Dict[str, Any], resolver: Optional[str]) -> str:
+        """
+        A short, stable digest of variant_settings, to tell apart clusters that would
+        otherwise have the same name.
+        """
+        settings = IRCluster.variant_settings(args, resolver)
+        encoded = json.dumps(settings, sort_keys=True, default=str).encode('utf-8')
+
+        return hashlib.sha1(encoded).hexdigest()[0:8]
+
     def merge(self, other: 'IRCluster') -> bool:
         # Is this mergeable?
 
         mismatches = []
 
-        for key in [ 'type', 'lb_type', 'host_rewrite',
-                     'tls_context', 'originate_tls', 'grpc', 'connect_timeout_ms', 'cluster_idle_timeout_ms', 'cluster_max_connection_lifetime_ms' ]:
+        for key in IRCluster.NameKeys + IRCluster.VariantKeys:
             if self.get(key, None) != other.get(key, None):
                 mismatches.append(key)
 
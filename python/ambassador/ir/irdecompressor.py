@@ -0,0 +1,45 @@
+from typing import TYPE_CHECKING
+
+from ..config import Config
+
+from .irfilter import IRFilter
+
+if TYPE_CHECKING:
+    from .ir import IR # pragma: no cover
+
+class IRDecompressor (IRFilter):
+
+    def __init__(self, ir: 'IR', aconf: Config,
+                 rkey: str="ir.decompressor",
+                 name: str="ir.decompressor",
+                 kind: str="IRDecompressor",
+                 **kwargs) -> None:
+
+        super().__init__(
+            ir=ir, aconf=aconf, rkey=rkey, kind=kind, name=name, **kwargs)
+
+    def setup(self, ir: 'IR', aconf: Config) -> bool:
+        # Envoy only decompresses bodies whose Content-Encoding matches the library, and
+        # gzip is the only library we have.
+        self["window_bits"] = self.pop('window_bits', None)
+        self["chunk_size"] = self.pop('chunk_size', None)
+
+        # Upstream responses are always decompressed. Requests from clients are passed
+        # through untouched unless asked.
+        self["decompress_requests"] = bool(self.pop('decompress_requests', False))
+
+        # max_request_bytes caps how big a request body may get once it's decompressed, so
+        # that a small zip bomb can't turn into an enormous body. IRAmbassador enforces it.
+        max_request_bytes = self.pop('max_request_bytes', None)
+
+        if max_request_bytes is not None:
+            if not isinstance(max_request_bytes, int) or (max_request_bytes < 1):
+                self.post_error(f"Invalid decompressor max_request_bytes {max_request_bytes}: must be a positive integer")
+                return False
+
+            if self.decompress_requests:
+                self["max_request_bytes"] = max_request_bytes
+            else:
+                self.ir.aconf.post_notice("decompressor max_request_bytes is ignored without decompress_requests", resource=self)
+
+        return True
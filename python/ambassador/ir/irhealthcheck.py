@@ -0,0 +1,132 @@
+from typing import Any, Dict, Optional, TYPE_CHECKING
+
+import durationpy
+
+from ..config import Config
+
+from .irresource import IRResource
+
+if TYPE_CHECKING:
+    from .ir import IR # pragma: no cover
+
+
+class IRHealthCheck (IRResource):
+    """
+    IRHealthCheck is a single active health check from a Mapping's health_checks. Once it's
+    set up, as_dict() is what we hand to the cluster: plain data (with every duration in
+    milliseconds), so that clusters from different Mappings can compare their health checks.
+    """
+
+    # Envoy requires all of these; these are its documented defaults for everything else.
+    DefaultTimeoutMs = 3000
+    DefaultIntervalMs = 5000
+    DefaultUnhealthyThreshold = 2
+    DefaultHealthyThreshold = 1
+
+    def __init__(self, ir: 'IR', aconf: Config,
+
+                 rkey: str="ir.healthcheck",
+                 kind: str="IRHealthCheck",
+                 name: str="ir.healthcheck",
+                 **kwargs) -> None:
+
+        super().__init__(
+            ir=ir, aconf=aconf, rkey=rkey, kind=kind, name=name,
+            **kwargs
+        )
+
+    def setup(self, ir: 'IR', aconf: Config) -> bool:
+        timeout_ms = self.duration_ms('timeout', IRHealthCheck.DefaultTimeoutMs)
+        interval_ms = self.duration_ms('interval', IRHealthCheck.DefaultIntervalMs)
+        interval_jitter_ms = self.duration_ms('interval_jitter', 0)
+
+        if (timeout_ms is None) or (interval_ms is None) or (interval_jitter_ms is None):
+            return False
+
+        for key, ms in (('timeout', timeout_ms), ('interval', interval_ms)):
+            if ms <= 0:
+                self.post_error(f"Invalid health check: {key} must be positive")
+                return False
+
+        # A jitter bigger than the interval is legal as far as Envoy is concerned, but it
+        # means checks could end up twice as far apart as asked for, which is almost certainly
+        # not what was meant. Cap it at the interval.
+        if interval_jitter_ms > interval_ms:
+            self.ir.aconf.post_notice(f"health check interval_jitter {self.interval_jitter} is longer than the interval {self.get('interval', '5s')}; using the interval instead", resource=self)
+            interval_jitter_ms = interval_ms
+
+        interval_jitter_percent = self.get('interval_jitter_percent', 0)
+
+        if not isinstance(interval_jitter_percent, int) or not (0 <= interval_jitter_percent <= 100):
+            self.post_error(f"Invalid health check: interval_jitter_percent {interval_jitter_percent} must be an integer from 0 to 100")
+            return False
+
+        thresholds: Dict[str, int] = {}
+
+        for key, default in (('unhealthy_threshold', IRHealthCheck.DefaultUnhealthyThreshold),
+                             ('healthy_threshold', IRHealthCheck.DefaultHealthyThreshold)):
+            value = self.get(key, default)
+
+            if not isinstance(value, int) or (value < 1):
+                self.post_error(f"Invalid health check: {key} {value} must be a positive integer")
+                return False
+
+            thresholds[key] = value
+
+        specifier = self.get('health_check', None) or {}
+        http = specifier.get('http', None)
+        grpc = specifier.get('grpc', None)
+
+        if (http is None) == (grpc is None):
+            self.post_error("Invalid health check: health_check must have exactly one of http or grpc")
+            return False
+
+        if http is not None:
+            if not http.get('path', None):
+                self.post_error("Invalid health check: http.path is required")
+                return False
+
+            for status_range in http.get('expected_statuses', []):
+                low = status_range.get('min', None)
+                high = status_range.get('max', None)
+
+                if not isinstance(low, int) or not isinstance(high, int) or not (100 <= low <= high <= 599):
+                    self.post_error(f"Invalid health check: expected_statuses range {status_range} must have 100 <= min <= max <= 599")
+                    return False
+
+        self._normalized = {
+            'timeout_ms': timeout_ms,
+            'interval_ms': interval_ms,
+            'interval_jitter_ms': interval_jitter_ms,
+            'interval_jitter_percent': interval_jitter_percent,
+            'unhealthy_threshold': thresholds['unhealthy_threshold'],
+            'healthy_threshold': thresholds['healthy_threshold'],
+        }
+
+        if http is not None:
+            self._normalized['http'] = dict(http)
+        else:
+            self._normalized['grpc'] = dict(grpc)
+
+        return True
+
+    def duration_ms(self, key: str, default: int) -> Optional[int]:
+        value = self.get(key, None)
+
+        if value is None:
+            return default
+
+        try:
+            ms = int(durationpy.from_str(value).total_seconds() * 1000)
+        except Exception:
+            self.post_error(f"Invalid health check: {key} {value} is not a duration")
+            return None
+
+        if ms < 0:
+            self.post_error(f"Invalid health check: {key} {value} must not be negative")
+            return None
+
+        return ms
+
+    def as_dict(self) -> Dict[str, Any]:
+        return dict(self._normalized)
@@ -1,5 +1,5 @@
 import copy
-from typing import Dict, List, Optional, TYPE_CHECKING
+from typing import Any, ClassVar, Dict, List, Optional, TYPE_CHECKING
 
 import os
 
@@ -18,7 +18,9 @@ class IRHost(IRResource):
     AllowedKeys = {
         'acmeProvider',
         'hostname',
+        'hsts',
         'mappingSelector',
+        'metadata_annotations',
         'metadata_labels',
         'requestPolicy',
         'selector',
@@ -27,6 +29,10 @@ class IRHost(IRResource):
         'tls',
     }
 
+    # Without maxAge, hsts asks browsers to stick to HTTPS for a year, which is also the
+    # least the HSTS preload lists will take.
+    DefaultHSTSMaxAge: ClassVar[int] = 31536000
+
     hostname: str
     secure_action: str
     insecure_action: str
@@ -73,6 +79,21 @@ class IRHost(IRResource):
         self.insecure_action = insecure_policy.get('action', 'Redirect')
         self.insecure_addl_port: Optional[int] = insecure_policy.get('additionalPort', None)
 
+        hsts = self.get('hsts', None)
+
+        if hsts is not None:
+            error = IRHost.check_hsts(hsts)
+
+            if error:
+                # Responses still go out without it, so keep the Host.
+                self.post_error(f"Invalid hsts: {error}; not adding Strict-Transport-Security")
+                del self['hsts']
+            else:
+                notice = IRHost.hsts_preload_notice(hsts)
+
+                if notice:
+                    self.ir.aconf.post_notice(notice, resource=self)
+
         # If we have no mappingSelector, check for selector.
         mapsel = self.get('mappingSelector', None)
 
@@ -238,6 +259,14 @@ class IRHost(IRResource):
                             ir.logger.error(f"Host {self.name}: new TLSContext {ctx_name} is not valid")
                 else:
                     ir.logger.error(f"Host {self.name}: invalid TLS secret {tls_name}, marking inactive")
+
+                    # A secret that isn't there yet is normal enough (ACME may still be
+                    # getting it), but one that's the wrong shape won't fix itself.
+                    mismatch = ir.secret_mismatch(tls_ss.secret_name, tls_ss.namespace)
+
+                    if mismatch:
+                        self.post_error(f"invalid TLS secret {tls_name}: {mismatch}, marking inactive")
+
                     return False
 
         if self.get('acmeProvider', None):
@@ -410,6 +439,66 @@ class IRHost(IRResource):
             insecure_action, insecure_addl_port
         )
 
+    @staticmethod
+    def check_hsts(hsts: Any) -> Optional[str]:
+        """
+        Return what's wrong with an hsts, or None if nothing is.
+        """
+
+        if not isinstance(hsts, dict):
+            return f"{hsts} must be a dictionary"
+
+        unknown = set(hsts.keys()) - { 'maxAge', 'includeSubDomains', 'preload' }
+
+        if unknown:
+            return f"unknown keys {', '.join(sorted(unknown))}"
+
+        max_age = hsts.get('maxAge', None)
+
+        if (max_age is not None) and \
+           (not isinstance(max_age, int) or isinstance(max_age, bool) or (max_age < 0)):
+            return f"maxAge {max_age} must be a non-negative number of seconds"
+
+        for key in [ 'includeSubDomains', 'preload' ]:
+            if not isinstance(hsts.get(key, False), bool):
+                return f"{key} must be true or false"
+
+        return None
+
+    @staticmethod
+    def hsts_preload_notice(hsts: Dict[str, Any]) -> Optional[str]:
+        """
+        Return why the preload lists would turn down a valid hsts that asks for preload, or
+        None if they wouldn't (or it doesn't ask).
+        """
+
+        if not hsts.get('preload', False):
+            return None
+
+        if not hsts.get('includeSubDomains', False):
+            return "hsts preload needs includeSubDomains, or the HSTS preload lists won't take the hostname"
+
+        if hsts.get('maxAge', IRHost.DefaultHSTSMaxAge) < IRHost.DefaultHSTSMaxAge:
+            return f"hsts preload needs a maxAge of at least {IRHost.DefaultHSTSMaxAge}, or the HSTS preload lists won't take the hostname"
+
+        return None
+
+    @staticmethod
+    def hsts_value(hsts: Dict[str, Any]) -> str:
+        """
+        Return the Strict-Transport-Security header for a valid hsts.
+        """
+
+        directives = [ f"max-age={hsts.get('maxAge', IRHost.DefaultHSTSMaxAge)}" ]
+
+        if hsts.get('includeSubDomains', False):
+            directives.append('includeSubDomains')
+
+        if hsts.get('preload', False):
+            directives.append('preload')
+
+        return '; '.join(directives)
+
     def resolve(self, ir: 'IR', secret_name: str) -> SavedSecret:
         # Try to use our namespace for secret resolution. If we somehow have no
         # namespace, fall back to the Ambassador's namespace.
@@ -468,6 +557,10 @@ class HostFactory:
             if found_termination_context:
                 ir.post_error("No Hosts defined, but TLSContexts exist that terminate TLS. The TLSContexts are being ignored.")
 
+            # ...and warn that strict host matching won't leave anything to route to.
+            if ir.ambassador_module.get('strict_host_matching', False):
+                ir.aconf.post_notice("strict_host_matching is on, but no Hosts are defined: the default Host matches any hostname, so every request will be rejected.")
+
             # If we don't have a fallback secret, don't try to use it.
             #
             # We use the Ambassador's namespace here because we'll be creating the
@@ -1,7 +1,7 @@
-from ambassador.utils import RichStatus
+from ambassador.utils import RichStatus, parse_bool
 from ambassador.utils import ParsedService as Service
 
-from typing import Any, ClassVar, Dict, List, Optional, Type, Union, TYPE_CHECKING
+from typing import Any, ClassVar, Dict, List, Optional, Set, Type, Union, TYPE_CHECKING
 
 from ..config import Config
 
@@ -11,8 +11,11 @@ from .irhttpmappinggroup import IRHTTPMappingGroup
 from .irerrorresponse import IRErrorResponse
 from .ircors import IRCORS
 from .irretrypolicy import IRRetryPolicy
+from .irhealthcheck import IRHealthCheck
+from .irtlscontext import IRTLSContext
 
 import hashlib
+import re
 
 if TYPE_CHECKING:
     from .ir import IR # pragma: no cover
@@ -20,11 +23,13 @@ if TYPE_CHECKING:
 
 # Kind of cheating here so that it's easy to json-serialize key-value pairs (including with regex)
 class KeyValueDecorator (dict):
-    def __init__(self, name: str, value: Optional[str]=None, regex: Optional[bool]=False) -> None:
+    def __init__(self, name: str, value: Optional[str]=None, regex: Optional[bool]=False,
+                 ignore_case: Optional[bool]=False) -> None:
         super().__init__()
         self.name = name
         self.value = value
         self.regex = regex
+        self.ignore_case = ignore_case
 
     def __getattr__(self, key: str) -> Any:
         return self[key]
@@ -39,7 +44,7 @@ class KeyValueDecorator (dict):
         return len(self.name) + len(self._get_value()) + (1 if self.regex else 0)
 
     def key(self) -> str:
-        return self.name + '-' + self._get_value()
+        return self.name + '-' + self._get_value() + ('-i' if self.ignore_case else '')
 
 
 class IRHTTPMapping (IRBaseMapping):
@@ -71,39 +76,117 @@ class IRHTTPMapping (IRBaseMapping):
     # processing (like 'service', which must be copied and used to wrangle
     # Linkerd headers) _do_ need to be included.
 
+    # response_flag_stats can only count the response flags that Envoy counts for a virtual
+    # cluster, since that's the only per-route stats scope Envoy has. These are its stats.
+    ResponseFlagStats: ClassVar[Dict[str, str]] = {
+        'UT': 'upstream_rq_timeout',
+        'URX': 'upstream_rq_retry_limit_exceeded',
+    }
+
+    # Without response_flag_stats_limit on the Ambassador Module, only this many Mappings
+    # get response_flag_stats.
+    DefaultResponseFlagStatsLimit: ClassVar[int] = 100
+
+    # Without stats_dimensions_limit on the Ambassador Module, a Mapping's stats_dimensions
+    # can have only this many combinations of values (each a virtual cluster of its own).
+    DefaultStatsDimensionsLimit: ClassVar[int] = 50
+
+    # What stats_dimensions tags requests with when their value isn't one of the listed ones.
+    StatsDimensionOther: ClassVar[str] = 'other'
+
+    # The SameSite values cookie_attributes can add.
+    CookieSameSiteValues: ClassVar[List[str]] = [ 'Strict', 'Lax', 'None' ]
+
+    # body_transform only buffers bodies up to this big, unless told otherwise...
+    DefaultBodyTransformMaxBytes: ClassVar[int] = 65536
+
+    # ...and can't go past the listener's buffer limit, or Envoy fails the request instead.
+    DefaultBufferLimitBytes: ClassVar[int] = 1048576
+
+    # Every histogram bucket is another time series for every histogram of every cluster it
+    # applies to, so don't let anyone ask for an unreasonable number of them.
+    MaxHistogramBuckets: ClassVar[int] = 64
+
+    # With shadow_on_circuit_break, this is how many mirrored requests can be in flight to
+    # the shadow at once, unless it has circuit_breakers of its own.
+    DefaultShadowMaxRequests: ClassVar[int] = 10
+
+    # Without min_concurrency, hedging allows this many extra requests whatever the budget
+    # (which is Envoy's default for a retry budget).
+    DefaultHedgingMinConcurrency: ClassVar[int] = 3
+
+    # Without always_log_status, log_sampling only samples 1xx and 2xx responses.
+    DefaultLogSamplingAlwaysLogStatus: ClassVar[int] = 300
+
+    # How a prefix ending in a slash treats a request for the same path without it: strict
+    # doesn't match it (as always), redirect sends the client to the slashed path, and match
+    # routes it as if it had the slash.
+    TrailingSlashModes: ClassVar[List[str]] = [ 'strict', 'redirect', 'match' ]
+
     AllowedKeys: ClassVar[Dict[str, bool]] = {
+        "ab_header": False,
         "add_linkerd_headers": False,
         # Do not include add_request_headers and add_response_headers
         "auto_host_rewrite": False,
+        "auto_http_protocol": False,
+        "auto_sni": False,
         "bypass_auth": False,
+        "bypass_compression": False,
         "auth_context_extensions": False,
         "bypass_error_response_overrides": False,
+        "body_transform": False,
+        "case_insensitive_headers": False,
         "case_sensitive": False,
         "circuit_breakers": False,
+        "cluster_drain_time_ms": False,
         "cluster_idle_timeout_ms": False,
         "cluster_max_connection_lifetime_ms": False,
+        "cluster_protocol_options": False,
         # Do not include cluster_tag
         "connect_timeout_ms": False,
+        "content_types": False,
+        "cookie_attributes": False,
         "cors": False,
         "docs": False,
+        "dns_failure_refresh_rate_ms": False,
         "dns_type": False,
+        "decompressor_max_request_bytes": False,
+        "disable_histograms": False,
+        "dynamic_forward_proxy": False,
+        "echo_request_id": False,
         "enable_ipv4": False,
         "enable_ipv6": False,
         "error_response_overrides": False,
         "grpc": False,
+        "grpc_timeout_offset_ms": False,
         # Do not include headers
+        "health_checks": False,
+        "health_rebalance": False,
+        "health_rebalance_recovery_ms": False,
+        "hedging": False,
+        "histogram_buckets": False,
         # Do not include host
         # Do not include hostname
         "host_redirect": False,
         "host_regex": False,
         "host_rewrite": False,
+        "host_rewrite_from_sni": False,
+        "http2_keepalive": False,
         "idle_timeout_ms": False,
+        "initial_fetch_timeout_ms": False,
+        "jwt": False,
         "keepalive": False,
         "labels": False,        # Not supported in v0; requires v1+; handled in setup
         "load_balancer": False,
+        "log_sampling": False,
+        "max_grpc_timeout_ms": False,
+        "max_requests_per_connection": False,
+        "mesh_mtls": False,
+        "metadata_annotations": False,
         "metadata_labels": False,
         # Do not include method
         "method_regex": False,
+        "methods": False,
         "path_redirect": False,
         "prefix_redirect": False,
         "regex_redirect": False,
@@ -117,18 +200,35 @@ class IRHTTPMapping (IRBaseMapping):
         # Do not include regex_headers
         "remove_request_headers": True,
         "remove_response_headers": True,
+        "request_timeout_header": False,
         "resolver": False,
+        "resolver_fallback": False,
         "respect_dns_ttl": False,
+        "preserve_sensitive_headers": False,
+        "response_flag_stats": False,
+        "retry_buffer_limit_bytes": False,
         "retry_policy": False,
         # Do not include rewrite
         "service": False,       # See notes above
         "shadow": False,
+        "shadow_clone_cluster": False,
+        "shadow_on_circuit_break": False,
+        "shadow_runtime_key": False,
+        "static_fallback": False,
+        "stats_dimensions": False,
         "stats_name": True,
+        "suppress_envoy_headers": False,
         "timeout_ms": False,
         "tls": False,
+        "trailing_slash": False,
+        "typed_per_filter_config": False,
+        "upstream_bind_address": False,
         "use_websocket": False,
+        "virtual_cluster": False,
         "allow_upgrade": False,
         "weight": False,
+        "weight_runtime_key_prefix": False,
+        "zero_replicas": False,
 
         # Include the serialization, too.
         "serialization": False,
@@ -185,6 +285,29 @@ class IRHTTPMapping (IRBaseMapping):
             if add_linkerd_headers != None:
                 new_args["add_linkerd_headers"] = add_linkerd_headers
 
+        # Automatic mesh mTLS. The Module's mesh_mtls is the default, and a Mapping can pick a
+        # different mesh or opt out with "none". Either way, it only kicks in if the Mapping
+        # isn't already saying how to talk to its service (we check that in setup).
+        mesh_mtls = new_args.get('mesh_mtls', None)
+
+        if mesh_mtls is None:
+            mesh_mtls = ir.ambassador_module.get('mesh_mtls', None)
+
+        service_scheme = service.lower().split('://', 1)[0] if '://' in service else None
+
+        if (mesh_mtls in IRTLSContext.MeshMTLSProviders) and ('tls' not in new_args) and not service_scheme:
+            if mesh_mtls == 'linkerd':
+                # Linkerd's proxy originates mTLS itself, so long as it knows where the request
+                # is really going.
+                new_args.setdefault('add_linkerd_headers', True)
+            elif mesh_mtls in IRTLSContext.MeshCertSources:
+                # If the mesh certs aren't available, we still name the context, so that the
+                # cluster posts an error about falling back to cleartext. gRPC services get the
+                # context that offers h2.
+                h2 = bool(new_args.get('grpc', False))
+                IRTLSContext.mesh_context(ir, mesh_mtls, h2)
+                new_args['tls'] = IRTLSContext.mesh_context_name(mesh_mtls, h2)
+
         # OK. On to set up the headers (since we need them to compute our group ID).
         hdrs = []
         query_parameters = []
@@ -197,6 +320,10 @@ class IRHTTPMapping (IRBaseMapping):
         # Also start self.host as unspecified.
         self.host = None
 
+        # Header names never care about case, but values do unless case_insensitive_headers
+        # says otherwise. setup() checks it properly; here we just need the names.
+        ignore_case = IRHTTPMapping.case_insensitive_names(kwargs.get('case_insensitive_headers', None))
+
         # OK. Start by looking for a :authority header match.
         if 'headers' in kwargs:
             for name, value in kwargs.get('headers', {}).items():
@@ -223,13 +350,38 @@ class IRHTTPMapping (IRBaseMapping):
                             # for hostname, too.
                     else:
                         # It's not an :authority match, so we're good.
-                        hdrs.append(KeyValueDecorator(name, value))
+                        hdrs.append(KeyValueDecorator(name, value, ignore_case=(name.lower() in ignore_case)))
 
         if 'regex_headers' in kwargs:
             # DON'T do anything special with a regex :authority match: we can't
             # do host-based filtering within the IR for it anyway.
             for name, value in kwargs.get('regex_headers', {}).items():
-                hdrs.append(KeyValueDecorator(name, value, regex=True))
+                hdrs.append(KeyValueDecorator(name, value, regex=True, ignore_case=(name.lower() in ignore_case)))
+
+        # A dynamic forward proxy will go anywhere the request says, so only match requests
+        # for the allowed hosts. Everything else falls through to the other Mappings (or a 404).
+        if 'dynamic_forward_proxy' in kwargs:
+            allowed_hosts = (kwargs['dynamic_forward_proxy'] or {}).get('allowed_hosts', None) or []
+            allowed_regex = IRHTTPMapping.allowed_hosts_regex(allowed_hosts)
+
+            if not allowed_hosts:
+                new_args["_deferred_error"] = "dynamic_forward_proxy needs at least one allowed_hosts entry; use '*' to allow every host"
+            elif allowed_regex is None:
+                new_args["_deferred_error"] = f"dynamic_forward_proxy allowed_hosts {allowed_hosts} may only use '*' as a whole entry or a leading '*.'"
+            elif allowed_regex:
+                hdrs.append(KeyValueDecorator(":authority", allowed_regex, regex=True))
+
+        # Content types become a regex match on the Content-Type header, so Mappings on the same
+        # prefix can split traffic by what it's carrying. "*/*" matches everything, so it's no
+        # match at all.
+        if 'content_types' in kwargs:
+            content_types = kwargs['content_types']
+            content_types_regex = IRHTTPMapping.content_types_regex(content_types) if isinstance(content_types, list) else None
+
+            if content_types_regex is None:
+                new_args["_deferred_error"] = f"content_types {content_types} must be a list of type/subtype, type/*, or */*"
+            elif content_types_regex:
+                hdrs.append(KeyValueDecorator("content-type", content_types_regex, regex=True))
 
         if 'host' in kwargs:
             # It's deliberate that we'll allow kwargs['host'] to silently override an exact :authority
@@ -272,6 +424,24 @@ class IRHTTPMapping (IRBaseMapping):
         if 'method' in kwargs:
             hdrs.append(KeyValueDecorator(":method", kwargs['method'], kwargs.get('method_regex', False)))
 
+        # methods becomes a regex match on :method, and V3Route adds a 405 for everything else.
+        # Envoy only answers a CORS preflight on a route with a CORS policy, so a Mapping that
+        # has one (directly or from the Module) lets OPTIONS through as well.
+        if 'methods' in kwargs:
+            methods = IRHTTPMapping.normalize_methods(kwargs['methods'])
+
+            if methods is None:
+                new_args["_deferred_error"] = f"methods {kwargs['methods']} must be a non-empty list of HTTP methods"
+            elif 'method' in kwargs:
+                new_args["_deferred_error"] = "method and methods can't both be set"
+            else:
+                if (('cors' in kwargs) or ir.ambassador_module.get('cors', None)) and ('OPTIONS' not in methods):
+                    methods.append('OPTIONS')
+                    methods.sort()
+
+                new_args['methods'] = methods
+                hdrs.append(KeyValueDecorator(":method", "|".join(methods), regex=True))
+
         if 'use_websocket' in new_args:
             allow_upgrade = new_args.setdefault('allow_upgrade', [])
             if 'websocket' not in allow_upgrade:
@@ -313,7 +483,9 @@ class IRHTTPMapping (IRBaseMapping):
             # qualification.
             resolver_kind = 'KubernetesBogusResolver'
 
-        service = normalize_service_name(ir, service, namespace, resolver_kind, rkey=rkey)
+        # The "*" service of a dynamic forward proxy isn't a real host, so don't qualify it.
+        if 'dynamic_forward_proxy' not in kwargs:
+            service = normalize_service_name(ir, service, namespace, resolver_kind, rkey=rkey)
         self.ir.logger.debug(f"Mapping {name} service qualified to {repr(service)}")
 
         svc = Service(ir.logger, service)
@@ -365,6 +537,55 @@ class IRHTTPMapping (IRBaseMapping):
     def group_class() -> Type[IRBaseMappingGroup]:
         return IRHTTPMappingGroup
 
+    @staticmethod
+    def case_insensitive_names(case_insensitive_headers: Any) -> Set[str]:
+        """
+        Return the lowercased header names from case_insensitive_headers, or nothing at all
+        if it isn't a list of names (which setup() will complain about).
+        """
+
+        if not isinstance(case_insensitive_headers, list) or not all(isinstance(hdr, str) for hdr in case_insensitive_headers):
+            return set()
+
+        return { hdr.lower() for hdr in case_insensitive_headers }
+
+    def apply_namespace_route_prefix(self) -> bool:
+        """
+        Put this Mapping's prefix under its namespace, for namespace_route_prefixes on the
+        Module: /api/ in team-a becomes /team-a/api/, so it can't collide with team-b's /api/.
+        Mappings in Ambassador's own namespace are left alone, so that's where the catch-all
+        lives; so are prefixes that already start with the namespace.
+        """
+
+        # Only user Mappings: the probes, diagnostics, and such stay where they are.
+        if (self.kind != 'Mapping') or (self.namespace == self.ir.ambassador_namespace):
+            return True
+
+        prefix = self.get('prefix', None)
+
+        if not prefix:
+            return True
+
+        if self.get('prefix_regex', False):
+            # A regex could still match anyone's paths, which is what this is here to stop.
+            self.post_error(f"prefix_regex can't be put under namespace {self.namespace} by namespace_route_prefixes; use a plain prefix")
+            return False
+
+        namespace_prefix = f"/{self.namespace}"
+
+        if (prefix == namespace_prefix) or prefix.startswith(namespace_prefix + "/"):
+            return True
+
+        self.prefix = namespace_prefix + (prefix if prefix.startswith("/") else "/" + prefix)
+
+        # A Mapping that didn't rewrite still sends its service the path it asked for, not
+        # the one with the namespace in front. (With the default rewrite of "/", the whole
+        # prefix gets replaced anyway.)
+        if not self.get('rewrite') and not self.get('regex_rewrite'):
+            self.rewrite = prefix
+
+        return True
+
     def _enforce_mutual_exclusion(self, preferred, other):
         if preferred in self and other in self:
             self.ir.aconf.post_error(f"Cannot specify both {preferred} and {other}. Using {preferred} and ignoring {other}.", resource=self)
@@ -377,6 +598,70 @@ class IRHTTPMapping (IRBaseMapping):
             self.post_error(_deferred_error)
             return False
 
+        # The group ID comes from the prefix, so it has to be final before we set up the base.
+        if ir.ambassador_module.get('namespace_route_prefixes', False):
+            if not self.apply_namespace_route_prefix():
+                return False
+
+        # This has to happen before the base setup, which would otherwise give the shadow the
+        # Ambassador Module's circuit_breakers.
+        shadow_on_circuit_break = self.get('shadow_on_circuit_break', None)
+
+        if shadow_on_circuit_break is not None:
+            error = IRHTTPMapping.check_shadow_on_circuit_break(shadow_on_circuit_break)
+
+            if (not error) and not self.get('shadow', False):
+                error = "only a shadow Mapping can have it"
+
+            if (not error) and self.get('shadow_runtime_key', None):
+                error = "it can't be combined with shadow_runtime_key"
+
+            if error:
+                self.post_error(f"Invalid shadow_on_circuit_break: {error}")
+                return False
+
+            # Mirroring is off until the runtime key turns it on, so a weight never applies.
+            if 'weight' in self:
+                self.ir.aconf.post_notice("weight is ignored with shadow_on_circuit_break: the runtime key sets how much to mirror", resource=self)
+
+            self['shadow_on_circuit_break'] = {
+                'runtime_key': shadow_on_circuit_break.get('runtime_key', None) or
+                               f"shadow_on_circuit_break.{self.name}.{self.namespace}",
+            }
+
+            # In an outage, everything the primary can't handle is still mirrored, so cap how
+            # much of it can pile onto the shadow.
+            if self.get('circuit_breakers', None) is None:
+                max_requests = shadow_on_circuit_break.get('max_requests', None) or IRHTTPMapping.DefaultShadowMaxRequests
+
+                self['circuit_breakers'] = [ {
+                    'max_requests': max_requests,
+                    'max_pending_requests': max_requests,
+                    'max_retries': 0,
+                } ]
+
+        # Likewise, a shadow that clones the cluster settings of the Mapping it mirrors has to
+        # know which settings are its own before the base setup fills in the Module's.
+        shadow_clone_cluster = self.get('shadow_clone_cluster', None)
+
+        if (shadow_clone_cluster is not None) and not isinstance(shadow_clone_cluster, bool):
+            self.post_error(f"Invalid shadow_clone_cluster {shadow_clone_cluster}: must be true or false")
+            return False
+
+        if shadow_clone_cluster and not self.get('shadow', False):
+            self.ir.aconf.post_notice("shadow_clone_cluster is ignored: only a shadow Mapping can clone cluster settings", resource=self)
+            del self['shadow_clone_cluster']
+        elif self.get('shadow', False):
+            if shadow_clone_cluster is None:
+                shadow_clone_cluster = ir.ambassador_module.get('shadow_clone_cluster', False)
+
+            if shadow_clone_cluster:
+                self['shadow_clone_cluster'] = True
+                self['shadow_own_settings'] = [ k for k in IRHTTPMappingGroup.ShadowCloneKeys
+                                                if self.get(k, None) is not None ]
+            else:
+                self.pop('shadow_clone_cluster', None)
+
         if not super().setup(ir, aconf):
             return False
 
@@ -389,6 +674,13 @@ class IRHTTPMapping (IRBaseMapping):
             else:
                 return False
 
+            # Envoy only answers a preflight if the OPTIONS request matches a route with a
+            # CORS policy, which a Mapping restricted to some other method never will.
+            method = self.get('method', None)
+
+            if method and (method.upper() != 'OPTIONS') and not self.get('method_regex', False):
+                self.ir.aconf.post_notice(f"CORS preflight (OPTIONS) requests can't match this Mapping, because it only matches method {method}", resource=self)
+
         # If we have RETRY_POLICY stuff, normalize it.
         if 'retry_policy' in self:
             self.retry_policy = IRRetryPolicy(ir=ir, aconf=aconf, location=self.location, **self.retry_policy)
@@ -398,6 +690,431 @@ class IRHTTPMapping (IRBaseMapping):
             else:
                 return False
 
+        hedging = self.get('hedging', None)
+
+        if hedging is not None:
+            error = IRHTTPMapping.check_hedging(hedging)
+
+            if error:
+                self.post_error(f"Invalid hedging: {error}")
+                return False
+
+            # Envoy only hedges a try that runs out of time.
+            retry_policy = self.get('retry_policy', None) or self.ir.ambassador_module.get('retry_policy', None)
+
+            if hedging['budget_percent'] == 0:
+                self.ir.aconf.post_notice("hedging budget_percent 0 allows no hedged requests, so hedging is off", resource=self)
+                del self['hedging']
+            elif not (retry_policy and retry_policy.get('per_try_timeout_ms', None)):
+                self.ir.aconf.post_notice("hedging does nothing without a retry_policy per_try_timeout, so hedging is off", resource=self)
+                del self['hedging']
+            else:
+                self.apply_hedging_budget(hedging)
+
+        # Only complain about mesh_mtls set on the Mapping itself: a Module default quietly
+        # doesn't apply to Mappings that originate TLS some other way.
+        mesh_mtls = self.get('mesh_mtls', None)
+
+        if mesh_mtls is not None:
+            if mesh_mtls not in IRTLSContext.MeshMTLSProviders:
+                self.post_error("Invalid mesh_mtls specified: {}. Supported: {}".format(mesh_mtls, ", ".join(sorted(IRTLSContext.MeshMTLSProviders))))
+                return False
+
+            mesh_context_name = IRTLSContext.mesh_context_name(mesh_mtls, bool(self.get('grpc', False)))
+
+            if (mesh_mtls in IRTLSContext.MeshCertSources) and (self.get('tls', None) != mesh_context_name):
+                self.ir.aconf.post_notice(f"mesh_mtls {mesh_mtls} is ignored because this Mapping already sets how to originate TLS", resource=self)
+
+        if 'dynamic_forward_proxy' in self:
+            if not self.ir.ambassador_module.get('dynamic_forward_proxy', None):
+                self.post_error("dynamic_forward_proxy needs dynamic_forward_proxy on the Ambassador Module, to set up the DNS cache")
+                return False
+
+            service_host = self.service.split('://', 1)[-1]
+
+            if service_host != '*':
+                self.post_error(f"dynamic_forward_proxy needs service '*' (optionally with a scheme), not {self.service}; the host comes from each request")
+                return False
+
+        bypass_compression = self.get('bypass_compression', None)
+
+        if bypass_compression is not None:
+            if not isinstance(bypass_compression, bool):
+                self.post_error(f"Invalid bypass_compression {bypass_compression}: must be true or false")
+                return False
+
+            if bypass_compression and not self.ir.ambassador_module.get('gzip', None):
+                self.ir.aconf.post_notice("bypass_compression does nothing unless gzip is set on the Ambassador Module", resource=self)
+
+        decompressor_max_request_bytes = self.get('decompressor_max_request_bytes', None)
+
+        if decompressor_max_request_bytes is not None:
+            if not isinstance(decompressor_max_request_bytes, int) or (decompressor_max_request_bytes < 0):
+                self.post_error(f"Invalid decompressor_max_request_bytes {decompressor_max_request_bytes}: must be a non-negative integer")
+                return False
+
+            if not self.ir.ambassador_module.get('decompressor_limit', None):
+                self.post_error("decompressor_max_request_bytes needs decompressor max_request_bytes (and decompress_requests) on the Ambassador Module")
+                return False
+
+        response_flag_stats = self.get('response_flag_stats', None)
+
+        if response_flag_stats is not None:
+            supported = ', '.join(IRHTTPMapping.ResponseFlagStats.keys())
+
+            if not isinstance(response_flag_stats, list) or not response_flag_stats:
+                self.post_error(f"Invalid response_flag_stats {response_flag_stats}: must be a list of response flags ({supported})")
+                return False
+
+            for flag in response_flag_stats:
+                if flag not in IRHTTPMapping.ResponseFlagStats:
+                    self.post_error(f"Invalid response_flag_stats flag {flag}: Envoy can only count {supported} per Mapping; other flags show up in cluster stats and in %RESPONSE_FLAGS% in the access log")
+                    return False
+
+        stats_dimensions = self.get('stats_dimensions', None)
+
+        if stats_dimensions is not None:
+            error = IRHTTPMapping.check_stats_dimensions(stats_dimensions)
+
+            if error:
+                self.post_error(f"Invalid stats_dimensions: {error}")
+                return False
+
+            # Envoy sees header names in lowercase.
+            self.stats_dimensions = [ dict(dimension, header=dimension['header'].lower()) for dimension in stats_dimensions ]
+
+            # Every combination of values (and "other") is a virtual cluster, and so another
+            # couple dozen time series in every scrape.
+            limit = self.ir.ambassador_module.get('stats_dimensions_limit', IRHTTPMapping.DefaultStatsDimensionsLimit)
+            combinations = 1
+
+            for dimension in stats_dimensions:
+                combinations *= len(dimension['values']) + 1
+
+            if combinations > limit:
+                self.ir.post_error(f"stats_dimensions would need {combinations} virtual clusters, over stats_dimensions_limit ({limit}), ignoring", resource=self)
+                del self['stats_dimensions']
+
+        case_insensitive_headers = self.get('case_insensitive_headers', None)
+
+        if case_insensitive_headers is not None:
+            if not isinstance(case_insensitive_headers, list) or not all(isinstance(hdr, str) for hdr in case_insensitive_headers):
+                self.post_error(f"Invalid case_insensitive_headers {case_insensitive_headers}: must be a list of header names")
+                return False
+
+            matched = { hdr.name.lower() for hdr in self.headers if hdr.ignore_case }
+
+            for hdr in case_insensitive_headers:
+                if hdr.lower() == ':authority':
+                    self.ir.aconf.post_notice("case_insensitive_headers: hosts are matched by hostname, so :authority is ignored", resource=self)
+                elif hdr.lower() not in matched:
+                    self.ir.aconf.post_notice(f"case_insensitive_headers: {hdr} has no value to match in headers or regex_headers, so it does nothing", resource=self)
+
+        preserve_sensitive_headers = self.get('preserve_sensitive_headers', None)
+
+        if preserve_sensitive_headers is not None:
+            if not isinstance(preserve_sensitive_headers, list) or not all(isinstance(hdr, str) for hdr in preserve_sensitive_headers):
+                self.post_error(f"Invalid preserve_sensitive_headers {preserve_sensitive_headers}: must be a list of header names")
+                return False
+
+            if not self.ir.ambassador_module.get('header_sanitization', None):
+                self.ir.aconf.post_notice("preserve_sensitive_headers does nothing without header_sanitization on the Ambassador Module", resource=self)
+
+        cookie_attributes = self.get('cookie_attributes', None)
+
+        if cookie_attributes is not None:
+            error = IRHTTPMapping.check_cookie_attributes(cookie_attributes)
+
+            if error:
+                self.post_error(f"Invalid cookie_attributes: {error}")
+                return False
+
+        regex_rewrite = self.get('regex_rewrite', None)
+
+        if regex_rewrite:
+            error = IRHTTPMapping.check_regex_rewrite(regex_rewrite)
+
+            if error:
+                self.post_error(f"Invalid regex_rewrite: {error}")
+                return False
+
+        weight_runtime_key_prefix = self.get('weight_runtime_key_prefix', None)
+
+        if weight_runtime_key_prefix is not None:
+            if not isinstance(weight_runtime_key_prefix, str) or not re.match(r'^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$', weight_runtime_key_prefix):
+                self.post_error(f"Invalid weight_runtime_key_prefix {weight_runtime_key_prefix}: must be a dotted runtime key, like routing.canary.my-service")
+                return False
+
+        priority = self.get('priority', None)
+
+        if priority is not None:
+            if not isinstance(priority, str) or (priority.lower() not in [ 'default', 'high' ]):
+                self.post_error(f"Invalid priority {priority}: must be default or high")
+                return False
+
+            # High-priority requests count against the cluster's high-priority circuit breaker
+            # thresholds, not the default ones. Without their own, they get Envoy's defaults.
+            if priority.lower() == 'high':
+                breakers = self.get('circuit_breakers', None) or []
+
+                if not any(str(cb.get('priority', '')).lower() == 'high' for cb in breakers):
+                    self.ir.aconf.post_notice("priority high uses Envoy's default high-priority circuit breaker thresholds; add circuit_breakers with priority high to set them", resource=self)
+
+        trailing_slash = self.get('trailing_slash', None)
+
+        if (trailing_slash is not None) and (trailing_slash not in IRHTTPMapping.TrailingSlashModes):
+            self.post_error(f"Invalid trailing_slash {trailing_slash}: must be one of {', '.join(IRHTTPMapping.TrailingSlashModes)}")
+            return False
+
+        auto_sni = self.get('auto_sni', None)
+
+        if (auto_sni is not None) and not isinstance(auto_sni, bool):
+            self.post_error(f"Invalid auto_sni {auto_sni}: must be true or false")
+            return False
+
+        # Envoy picks the SNI from the Host as the route leaves it, so host_rewrite shows up
+        # in it -- but auto_host_rewrite happens later, once the upstream host is chosen.
+        if auto_sni and self.get('auto_host_rewrite', False):
+            self.ir.aconf.post_notice("auto_sni uses the Host before auto_host_rewrite changes it", resource=self)
+
+        auto_http_protocol = self.get('auto_http_protocol', None)
+
+        if (auto_http_protocol is not None) and not isinstance(auto_http_protocol, bool):
+            self.post_error(f"Invalid auto_http_protocol {auto_http_protocol}: must be true or false")
+            return False
+
+        # A gRPC upstream only speaks HTTP/2, so falling back to HTTP/1.1 can't work.
+        if auto_http_protocol and self.get('grpc', False):
+            self.ir.aconf.post_notice("grpc always uses HTTP/2, ignoring auto_http_protocol", resource=self)
+            del self['auto_http_protocol']
+
+        # 1 is what turns reuse off: every request gets a connection of its own.
+        max_requests_per_connection = self.get('max_requests_per_connection', None)
+
+        if (max_requests_per_connection is not None) and \
+           (isinstance(max_requests_per_connection, bool) or not isinstance(max_requests_per_connection, int) or (max_requests_per_connection < 1)):
+            self.post_error(f"Invalid max_requests_per_connection {max_requests_per_connection}: must be a positive integer")
+            return False
+
+        ab_header = self.get('ab_header', None)
+
+        if ab_header is not None:
+            error = IRHTTPMapping.check_ab_header(ab_header)
+
+            if error:
+                self.post_error(f"Invalid ab_header: {error}")
+                return False
+
+        # A Mapping without a resolver_fallback of its own uses the Ambassador Module's, minus
+        # its own resolver, which is tried first anyway.
+        resolver_fallback = self.get('resolver_fallback', None)
+
+        if resolver_fallback is None:
+            resolver_fallback = [ name for name in ir.ambassador_module.get('resolver_fallback', None) or []
+                                  if name != self.resolver ]
+
+            if resolver_fallback:
+                self['resolver_fallback'] = resolver_fallback
+
+        if resolver_fallback:
+            error = IRHTTPMapping.check_resolver_fallback(resolver_fallback)
+
+            if not error:
+                if self.resolver in resolver_fallback:
+                    error = f"it can't include the Mapping's own resolver {self.resolver}"
+                else:
+                    unknown = [ name for name in resolver_fallback if not ir.get_resolver(name) ]
+
+                    if unknown:
+                        error = f"unknown resolver {', '.join(unknown)}"
+
+            if error:
+                self.post_error(f"Invalid resolver_fallback: {error}")
+                return False
+
+            if self.get('dynamic_forward_proxy', False):
+                self.ir.aconf.post_notice("dynamic_forward_proxy doesn't use a resolver, ignoring resolver_fallback", resource=self)
+                del self['resolver_fallback']
+        elif 'resolver_fallback' in self:
+            del self['resolver_fallback']
+
+        zero_replicas = self.get('zero_replicas', None)
+
+        if zero_replicas is not None:
+            error = IRHTTPMapping.check_zero_replicas(zero_replicas)
+
+            if not error and ('respond' in zero_replicas) and (self.get('static_fallback', None) is not None):
+                error = "respond and static_fallback can't both be set"
+
+            if error:
+                self.post_error(f"Invalid zero_replicas: {error}")
+                return False
+
+            unusable = self.zero_replicas_unusable(zero_replicas)
+
+            if unusable:
+                self.ir.aconf.post_notice(f"{unusable}, ignoring zero_replicas", resource=self)
+                del self['zero_replicas']
+        else:
+            # The Ambassador Module's zero_replicas is for every Mapping that can use it, so one
+            # that can't, or that answers for itself with a static_fallback, just does without.
+            zero_replicas = ir.ambassador_module.get('zero_replicas', None)
+
+            if zero_replicas and not self.zero_replicas_unusable(zero_replicas) and \
+               not (('respond' in zero_replicas) and (self.get('static_fallback', None) is not None)):
+                self['zero_replicas'] = zero_replicas
+
+        echo_request_id = self.get('echo_request_id', None)
+
+        if (echo_request_id is not None) and not isinstance(echo_request_id, bool):
+            self.post_error(f"Invalid echo_request_id {echo_request_id}: must be true or false")
+            return False
+
+        health_rebalance = self.get('health_rebalance', None)
+
+        if (health_rebalance is not None) and not isinstance(health_rebalance, bool):
+            self.post_error(f"Invalid health_rebalance {health_rebalance}: must be true or false")
+            return False
+
+        health_rebalance_recovery_ms = self.get('health_rebalance_recovery_ms', None)
+
+        if (health_rebalance_recovery_ms is not None) and \
+           ((not isinstance(health_rebalance_recovery_ms, int)) or (health_rebalance_recovery_ms < 0)):
+            self.post_error(f"Invalid health_rebalance_recovery_ms {health_rebalance_recovery_ms}: must be a non-negative integer")
+            return False
+
+        histogram_buckets = self.get('histogram_buckets', None)
+
+        if histogram_buckets is not None:
+            error = IRHTTPMapping.check_histogram_buckets(histogram_buckets)
+
+            if error:
+                self.post_error(f"Invalid histogram_buckets: {error}")
+                return False
+
+        disable_histograms = self.get('disable_histograms', None)
+
+        if disable_histograms is not None:
+            if not isinstance(disable_histograms, bool):
+                self.post_error(f"Invalid disable_histograms {disable_histograms}: must be true or false")
+                return False
+
+            if disable_histograms and (histogram_buckets is not None):
+                self.post_error("histogram_buckets and disable_histograms can't both be set")
+                return False
+
+        body_transform = self.get('body_transform', None)
+
+        if body_transform is not None:
+            buffer_limit_bytes = self.ir.ambassador_module.get('buffer_limit_bytes', None) or IRHTTPMapping.DefaultBufferLimitBytes
+            error = IRHTTPMapping.check_body_transform(body_transform, buffer_limit_bytes)
+
+            if error:
+                self.post_error(f"Invalid body_transform: {error}")
+                return False
+
+        static_fallback = self.get('static_fallback', None)
+
+        if static_fallback is not None:
+            error = IRHTTPMapping.check_static_fallback(static_fallback)
+
+            if error:
+                self.post_error(f"Invalid static_fallback: {error}")
+                return False
+
+        log_sampling = self.get('log_sampling', None)
+
+        if log_sampling is not None:
+            error = IRHTTPMapping.check_log_sampling(log_sampling)
+
+            if error:
+                self.post_error(f"Invalid log_sampling: {error}")
+                return False
+
+        jwt = self.get('jwt', None)
+
+        if jwt is not None:
+            error = IRHTTPMapping.check_jwt(jwt, self.ir.ambassador_module.get('jwt_providers', None) or {})
+
+            if error:
+                self.post_error(f"Invalid jwt: {error}")
+                return False
+
+        request_timeout_header = self.get('request_timeout_header', None)
+
+        if request_timeout_header is not None:
+            error = IRHTTPMapping.check_request_timeout_header(request_timeout_header)
+
+            if error:
+                self.post_error(f"Invalid request_timeout_header: {error}")
+                return False
+
+        # Envoy can only retry a request whose body it still has. A body that outgrows this
+        # is sent on unbuffered and gets just the one try (which per_try_timeout_ms still
+        # bounds); Envoy counts those in the cluster's retry_or_shadow_abandoned stat.
+        retry_buffer_limit_bytes = self.get('retry_buffer_limit_bytes', None)
+
+        if retry_buffer_limit_bytes is not None:
+            if not isinstance(retry_buffer_limit_bytes, int) or (retry_buffer_limit_bytes < 0):
+                self.post_error(f"Invalid retry_buffer_limit_bytes {retry_buffer_limit_bytes}: must be a non-negative integer")
+                return False
+
+            if retry_buffer_limit_bytes == 0:
+                self.ir.aconf.post_notice("retry_buffer_limit_bytes 0 means requests with a body are never retried (or shadowed)", resource=self)
+
+        # We can't check what's inside cluster_protocol_options (that's Envoy's job), but
+        # we can make sure it has the shape typed_extension_protocol_options needs.
+        if 'cluster_protocol_options' in self:
+            protocol_options = self.cluster_protocol_options
+
+            if not isinstance(protocol_options, dict):
+                self.post_error(f"cluster_protocol_options must be a dictionary, not {protocol_options}")
+                return False
+
+            for extension_name, extension_config in protocol_options.items():
+                if not isinstance(extension_config, dict) or not isinstance(extension_config.get('@type', None), str):
+                    self.post_error(f"cluster_protocol_options {extension_name} must be a dictionary with an @type")
+                    return False
+
+        # The same goes for typed_per_filter_config: each filter's config needs an @type.
+        if 'typed_per_filter_config' in self:
+            per_filter_config = self.typed_per_filter_config
+
+            if not isinstance(per_filter_config, dict):
+                self.post_error(f"typed_per_filter_config must be a dictionary, not {per_filter_config}")
+                return False
+
+            for filter_name, filter_config in per_filter_config.items():
+                if not isinstance(filter_config, dict) or not isinstance(filter_config.get('@type', None), str):
+                    self.post_error(f"typed_per_filter_config {filter_name} must be a dictionary with an @type")
+                    return False
+
+        # Health checks go to the cluster as plain data, once they're valid.
+        if 'health_checks' in self:
+            health_checks = self.health_checks
+
+            if not isinstance(health_checks, list):
+                self.post_error(f"health_checks must be a list, not {health_checks}")
+                return False
+
+            normalized_checks = []
+
+            for health_check in health_checks:
+                ir_health_check = IRHealthCheck(ir=ir, aconf=aconf, location=self.location, **health_check)
+
+                if not ir_health_check:
+                    return False
+
+                # Envoy can only speak the gRPC health checking protocol over HTTP/2.
+                if ('grpc' in ir_health_check.as_dict()) and not self.get('grpc', False):
+                    self.post_error("gRPC health checks require grpc: true")
+                    return False
+
+                ir_health_check.referenced_by(self)
+                normalized_checks.append(ir_health_check.as_dict())
+
+            self.health_checks = normalized_checks
+
         # If we have error response overrides, generate an IR for that too.
         if 'error_response_overrides' in self:
             self.error_response_overrides = IRErrorResponse(self.ir, aconf,
@@ -478,10 +1195,53 @@ class IRHTTPMapping (IRBaseMapping):
             return False
 
         if self.get('load_balancer', None) is not None:
+            error = IRHTTPMapping.check_choice_count(self['load_balancer'])
+
+            if error:
+                self.post_error(f"Invalid load_balancer specified: {error}, invalidating mapping")
+                return False
+
             if not self.validate_load_balancer(self['load_balancer']):
                 self.post_error("Invalid load_balancer specified: {}, invalidating mapping".format(self['load_balancer']))
                 return False
 
+        # A non-zero timeout_ms caps the whole request, so an idle_timeout_ms longer than it
+        # can never fire. That's almost always a streaming Mapping that meant timeout_ms: 0.
+        timeout_ms = self.get('timeout_ms', None)
+        idle_timeout_ms = self.get('idle_timeout_ms', None)
+
+        if timeout_ms and idle_timeout_ms and (idle_timeout_ms > timeout_ms):
+            self.ir.aconf.post_notice(f"idle_timeout_ms {idle_timeout_ms} is longer than timeout_ms {timeout_ms}, so timeout_ms will always win; use timeout_ms: 0 to disable the overall timeout for streaming", resource=self)
+
+        # The same goes for a retry_policy's per_try_timeout: timeout_ms is the budget for the
+        # whole request, every retry (and the backoff between them) included, and each try
+        # only gets whatever of it is left.
+        retry_policy = self.get('retry_policy', None) or self.ir.ambassador_module.get('retry_policy', None)
+        per_try_timeout_ms = retry_policy.get('per_try_timeout_ms', None) if retry_policy else None
+        budget_ms = timeout_ms if (timeout_ms is not None) else self.ir.ambassador_module.get('cluster_request_timeout_ms', 3000)
+
+        if per_try_timeout_ms and budget_ms and (per_try_timeout_ms > budget_ms):
+            self.ir.aconf.post_notice(f"retry_policy per_try_timeout of {per_try_timeout_ms}ms is longer than timeout_ms {budget_ms}, so timeout_ms will always cut off the first try, and nothing will be retried after a per-try timeout", resource=self)
+
+        # The router filter drops Envoy's headers for every route once the Module says so, and
+        # there's no per-route way to put them back.
+        if (self.get('suppress_envoy_headers', None) is False) and \
+           parse_bool(self.ir.ambassador_module.get('suppress_envoy_headers', 'false')):
+            self.ir.aconf.post_notice("suppress_envoy_headers: false is ignored because the Ambassador Module suppresses Envoy's headers everywhere", resource=self)
+
+        # Envoy only applies the offset while it's honoring the grpc-timeout header, which
+        # takes max_grpc_timeout_ms.
+        if ('grpc_timeout_offset_ms' in self) and ('max_grpc_timeout_ms' not in self):
+            self.ir.aconf.post_notice("grpc_timeout_offset_ms is ignored without max_grpc_timeout_ms", resource=self)
+            del self['grpc_timeout_offset_ms']
+
+        for key in ('max_grpc_timeout_ms', 'grpc_timeout_offset_ms'):
+            value = self.get(key, None)
+
+            if (value is not None) and (not isinstance(value, int) or (value < 0)):
+                self.post_error(f"Invalid {key} {value}: must be a non-negative integer")
+                return False
+
         # All three redirect fields are mutually exclusive.
         #
         # Prefer path_redirect over the other two. If only prefix_redirect and
@@ -491,11 +1251,793 @@ class IRHTTPMapping (IRBaseMapping):
         self._enforce_mutual_exclusion('path_redirect', 'regex_redirect')
         self._enforce_mutual_exclusion('prefix_redirect', 'regex_redirect')
 
+        # Likewise, Envoy can only rewrite the Host one way. An explicit host_rewrite wins,
+        # then auto_host_rewrite, then host_rewrite_from_sni.
+        if not self.get('host_rewrite_from_sni', False):
+            self.pop('host_rewrite_from_sni', None)
+
+        self._enforce_mutual_exclusion('host_rewrite', 'host_rewrite_from_sni')
+        self._enforce_mutual_exclusion('auto_host_rewrite', 'host_rewrite_from_sni')
+
         ir.logger.debug("Mapping %s: setup OK: host %s hostname %s regex %s",
                         self.name, self.get('host'), self.get('hostname'), self.get('host_regex'))
 
         return True
 
+    @staticmethod
+    def allowed_hosts_regex(allowed_hosts: List[str]) -> Optional[str]:
+        """
+        Turn dynamic_forward_proxy allowed_hosts into a regex for the :authority header. Returns
+        "" if every host is allowed, or None if an entry isn't a host we know how to match.
+        """
+        alternatives = []
+
+        for host in allowed_hosts:
+            if not isinstance(host, str) or not host:
+                return None
+
+            if host == '*':
+                return ""
+
+            if host.startswith('*.'):
+                alternatives.append(r'[^:]+' + re.escape(host[1:]))
+            elif '*' in host:
+                return None
+            else:
+                alternatives.append(re.escape(host))
+
+        # The client may include the port, and host names aren't case-sensitive.
+        return '(?i)(?:' + '|'.join(alternatives) + ')(?::[0-9]+)?'
+
+    @staticmethod
+    def check_request_timeout_header(request_timeout_header: Any) -> Optional[str]:
+        """
+        Return what's wrong with a request_timeout_header, or None if nothing is.
+        """
+
+        if not isinstance(request_timeout_header, dict):
+            return f"{request_timeout_header} must be a dictionary"
+
+        unknown = set(request_timeout_header.keys()) - { 'honor', 'max_ms' }
+
+        if unknown:
+            return f"unknown keys {', '.join(sorted(unknown))}"
+
+        honor = request_timeout_header.get('honor', True)
+        max_ms = request_timeout_header.get('max_ms', None)
+
+        if not isinstance(honor, bool):
+            return f"honor must be true or false, not {honor}"
+
+        if max_ms is not None:
+            if not isinstance(max_ms, int) or (max_ms <= 0):
+                return f"max_ms must be a positive integer, not {max_ms}"
+
+            if not honor:
+                return "max_ms can't be set if the header isn't honored"
+
+        return None
+
+    @staticmethod
+    def check_body_transform(body_transform: Any, buffer_limit_bytes: int) -> Optional[str]:
+        """
+        Return what's wrong with a body_transform, or None if nothing is.
+        """
+
+        if not isinstance(body_transform, dict):
+            return f"{body_transform} must be a dictionary"
+
+        unknown = set(body_transform.keys()) - { 'request', 'response', 'content_types', 'max_bytes' }
+
+        if unknown:
+            return f"unknown keys {', '.join(sorted(unknown))}"
+
+        templates = [ body_transform.get(key, None) for key in [ 'request', 'response' ] ]
+
+        if not any(templates):
+            return "at least one of request or response is required"
+
+        for template in templates:
+            if (template is not None) and not isinstance(template, str):
+                return f"templates must be strings, not {template}"
+
+        content_types = body_transform.get('content_types', None)
+
+        if content_types is not None:
+            if (not isinstance(content_types, list) or not content_types or
+                not all(isinstance(ct, str) and re.match(r'^[^/\s;]+/[^/\s;]+$', ct) for ct in content_types)):
+                return f"content_types must be a list of media types, like application/json, not {content_types}"
+
+        max_bytes = body_transform.get('max_bytes', None)
+
+        if max_bytes is not None:
+            if isinstance(max_bytes, bool) or not isinstance(max_bytes, int) or (max_bytes <= 0):
+                return f"max_bytes must be a positive integer, not {max_bytes}"
+
+            if max_bytes > buffer_limit_bytes:
+                return f"max_bytes {max_bytes} is more than the buffer limit of {buffer_limit_bytes} bytes"
+
+        return None
+
+    def body_transform_policy(self) -> Optional[Dict[str, Any]]:
+        """
+        Return this Mapping's body_transform with the defaults filled in, or None if it
+        doesn't have one.
+        """
+        policy = self.get('body_transform', None)
+
+        if not policy:
+            return None
+
+        return {
+            'request': policy.get('request', None),
+            'response': policy.get('response', None),
+            'content_types': [ ct.lower() for ct in policy.get('content_types', [ 'application/json' ]) ],
+            'max_bytes': policy.get('max_bytes', IRHTTPMapping.DefaultBodyTransformMaxBytes),
+        }
+
+    @staticmethod
+    def check_static_fallback(static_fallback: Any) -> Optional[str]:
+        """
+        Return what's wrong with a static_fallback, or None if nothing is.
+        """
+
+        if not isinstance(static_fallback, dict):
+            return f"{static_fallback} must be a dictionary"
+
+        unknown = set(static_fallback.keys()) - { 'status_code', 'body', 'content_type' }
+
+        if unknown:
+            return f"unknown keys {', '.join(sorted(unknown))}"
+
+        status_code = static_fallback.get('status_code', 503)
+
+        if isinstance(status_code, bool) or not isinstance(status_code, int) or not (200 <= status_code <= 599):
+            return f"status_code {status_code} must be between 200 and 599"
+
+        body = static_fallback.get('body', None)
+
+        if not isinstance(body, str):
+            return "body is required, and must be a string"
+
+        content_type = static_fallback.get('content_type', None)
+
+        if (content_type is not None) and (not isinstance(content_type, str) or not content_type):
+            return f"content_type must be a string, not {content_type}"
+
+        return None
+
+    def static_fallback_mapper(self) -> Optional[Dict[str, Any]]:
+        """
+        Return the response_map mapper for this Mapping's static_fallback, or for its
+        zero_replicas respond (they can't both be set), or None if it has neither.
+        """
+        static_fallback = self.get('static_fallback', None) or (self.get('zero_replicas', None) or {}).get('respond', None)
+
+        if not static_fallback:
+            return None
+
+        # Envoy flags a request UH when the cluster had no healthy endpoint to send it to.
+        # That's the same whether the cluster has no endpoints at all or has only unhealthy
+        # ones; either way, this answers until an endpoint is healthy again. (A circuit
+        # breaker tripping is UO, which this leaves alone: the upstream is still there.)
+        return {
+            'filter': {
+                'response_flag_filter': {
+                    'flags': [ 'UH' ]
+                }
+            },
+            'status_code': static_fallback.get('status_code', 503),
+            'body': {
+                'inline_string': static_fallback['body']
+            },
+            'body_format_override': {
+                'text_format': '%LOCAL_REPLY_BODY%',
+                'content_type': static_fallback.get('content_type', 'text/plain'),
+            }
+        }
+
+    @staticmethod
+    def check_log_sampling(log_sampling: Any) -> Optional[str]:
+        """
+        Return what's wrong with a log_sampling, or None if nothing is.
+        """
+
+        if not isinstance(log_sampling, dict):
+            return f"{log_sampling} must be a dictionary"
+
+        unknown = set(log_sampling.keys()) - { 'percent', 'always_log_status' }
+
+        if unknown:
+            return f"unknown keys {', '.join(sorted(unknown))}"
+
+        percent = log_sampling.get('percent', None)
+
+        if isinstance(percent, bool) or not isinstance(percent, int) or not (0 <= percent <= 100):
+            return f"percent {percent} must be between 0 and 100"
+
+        always_log_status = log_sampling.get('always_log_status', IRHTTPMapping.DefaultLogSamplingAlwaysLogStatus)
+
+        if isinstance(always_log_status, bool) or not isinstance(always_log_status, int) or not (100 <= always_log_status <= 599):
+            return f"always_log_status {always_log_status} must be between 100 and 599"
+
+        return None
+
+    def log_sampling_policy(self) -> Optional[Dict[str, Any]]:
+        """
+        Return this Mapping's log_sampling with the defaults filled in, or None if it logs
+        every request. The id is what its route marks its requests with, for the access log
+        filter to find, and the base of its runtime keys.
+        """
+        log_sampling = self.get('log_sampling', None)
+
+        if not log_sampling:
+            return None
+
+        return {
+            'id': f"{self.name}.{self.namespace}",
+            'percent': log_sampling['percent'],
+            'always_log_status': log_sampling.get('always_log_status', IRHTTPMapping.DefaultLogSamplingAlwaysLogStatus),
+        }
+
+    @staticmethod
+    def check_jwt(jwt: Any, jwt_providers: Dict[str, Dict[str, Any]]) -> Optional[str]:
+        """
+        Return what's wrong with a jwt, or None if nothing is.
+        """
+
+        if not isinstance(jwt, dict):
+            return f"{jwt} must be a dictionary"
+
+        unknown = set(jwt.keys()) - { 'provider', 'claims' }
+
+        if unknown:
+            return f"unknown keys {', '.join(sorted(unknown))}"
+
+        if not jwt_providers:
+            return "the Ambassador Module has no jwt_providers"
+
+        provider = jwt.get('provider', None)
+
+        if provider is None:
+            if len(jwt_providers) > 1:
+                return f"provider is required when there's more than one of jwt_providers ({', '.join(sorted(jwt_providers.keys()))})"
+        elif provider not in jwt_providers:
+            return f"provider {provider} is not one of jwt_providers ({', '.join(sorted(jwt_providers.keys()))})"
+
+        claims = jwt.get('claims', None)
+
+        if claims is not None:
+            if not isinstance(claims, list):
+                return f"claims must be a list, not {claims}"
+
+            for claim in claims:
+                if not isinstance(claim, dict) or (set(claim.keys()) != { 'name', 'values' }):
+                    return f"each of claims must have just a name and values, not {claim}"
+
+                if not isinstance(claim['name'], str) or not claim['name']:
+                    return f"claim name {claim['name']} must be a string"
+
+                values = claim['values']
+
+                if not isinstance(values, list) or not values or not all(isinstance(v, str) for v in values):
+                    return f"claim {claim['name']} values must be a list of strings, not {values}"
+
+        return None
+
+    def jwt_policy(self) -> Optional[Dict[str, Any]]:
+        """
+        Return this Mapping's jwt with the provider filled in, or None if it doesn't require
+        a JWT.
+        """
+        jwt = self.get('jwt', None)
+
+        if jwt is None:
+            return None
+
+        return {
+            'provider': jwt.get('provider', None) or list(self.ir.ambassador_module.jwt_providers.keys())[0],
+            'claims': [ dict(claim) for claim in jwt.get('claims', None) or [] ],
+        }
+
+    @staticmethod
+    def check_histogram_buckets(histogram_buckets: Any) -> Optional[str]:
+        """
+        Return what's wrong with a histogram_buckets, or None if nothing is.
+        """
+
+        # Envoy times requests and connections in whole milliseconds, so finer buckets than
+        # that wouldn't tell anyone anything.
+        if not isinstance(histogram_buckets, list) or \
+           not all(isinstance(b, int) and not isinstance(b, bool) for b in histogram_buckets):
+            return f"{histogram_buckets} must be a list of bucket upper bounds, in whole milliseconds"
+
+        if not histogram_buckets:
+            return "there must be at least one bucket"
+
+        if len(histogram_buckets) > IRHTTPMapping.MaxHistogramBuckets:
+            return f"{len(histogram_buckets)} buckets is more than the {IRHTTPMapping.MaxHistogramBuckets} allowed"
+
+        if any(b <= 0 for b in histogram_buckets):
+            return "bucket upper bounds must be positive"
+
+        if any(a >= b for a, b in zip(histogram_buckets, histogram_buckets[1:])):
+            return "bucket upper bounds must be in increasing order"
+
+        return None
+
+    @staticmethod
+    def check_shadow_on_circuit_break(shadow_on_circuit_break: Any) -> Optional[str]:
+        """
+        Return what's wrong with a shadow_on_circuit_break, or None if nothing is.
+        """
+
+        if not isinstance(shadow_on_circuit_break, dict):
+            return f"{shadow_on_circuit_break} must be a dictionary"
+
+        unknown = sorted(set(shadow_on_circuit_break.keys()) - { 'runtime_key', 'max_requests' })
+
+        if unknown:
+            return f"unknown key(s) {', '.join(unknown)}"
+
+        runtime_key = shadow_on_circuit_break.get('runtime_key', None)
+
+        if (runtime_key is not None) and \
+           (not isinstance(runtime_key, str) or not re.match(r'^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$', runtime_key)):
+            return f"runtime_key {runtime_key} must be a dotted runtime key, like mirror.my-service"
+
+        max_requests = shadow_on_circuit_break.get('max_requests', None)
+
+        if (max_requests is not None) and \
+           (not isinstance(max_requests, int) or isinstance(max_requests, bool) or (max_requests < 1)):
+            return f"max_requests {max_requests} must be a positive integer"
+
+        return None
+
+    @staticmethod
+    def regex_group_count(pattern: str) -> int:
+        """
+        Count the capture groups in an RE2 pattern: every '(' that isn't escaped, isn't in a
+        character class, and doesn't start a non-capturing group. Named groups capture too.
+        """
+
+        count = 0
+        in_class = False
+        i = 0
+
+        while i < len(pattern):
+            c = pattern[i]
+
+            if c == '\\':
+                i += 2
+                continue
+
+            if in_class:
+                if c == ']':
+                    in_class = False
+            elif c == '[':
+                in_class = True
+
+                # A ']' right at the start of a class is part of it.
+                if pattern[i+1:i+2] == '^':
+                    i += 1
+
+                if pattern[i+1:i+2] == ']':
+                    i += 1
+            elif c == '(':
+                if (pattern[i+1:i+2] != '?') or pattern.startswith('(?P<', i):
+                    count += 1
+
+            i += 1
+
+        return count
+
+    @staticmethod
+    def check_regex_rewrite(regex_rewrite: Dict[str, str]) -> Optional[str]:
+        """
+        Return what's wrong with a regex_rewrite, or None if nothing is. Envoy replaces every
+        match of the pattern in the path, so one pattern can rewrite several segments; the
+        substitution can use \\0 for the whole match and \\1 through \\9 for the pattern's
+        capture groups, numbered by where their '(' is (so nested groups count too).
+        """
+
+        pattern = regex_rewrite.get('pattern', None)
+        substitution = regex_rewrite.get('substitution', None)
+
+        if not isinstance(pattern, str) or not pattern:
+            return "pattern must be a non-empty regex"
+
+        if not isinstance(substitution, str):
+            return f"substitution must be a string, not {substitution}"
+
+        groups = IRHTTPMapping.regex_group_count(pattern)
+
+        for ref in re.findall(r'\\(.)', substitution):
+            if ref == '\\':
+                continue
+
+            if not ref.isdigit():
+                return f"substitution {substitution} can only use \\ for \\0-\\9 or \\\\"
+
+            if int(ref) > groups:
+                return f"substitution {substitution} uses \\{ref}, but pattern {pattern} only has {groups} capture groups"
+
+        return None
+
+    def apply_hedging_budget(self, hedging: Dict[str, Any]) -> None:
+        """
+        Put the hedging budget on the default-priority circuit breaker for this Mapping's
+        cluster, as Envoy's retry budget: hedges are retries as far as Envoy is concerned.
+        """
+
+        # The breakers may well be the Ambassador Module's, so work on copies, and let them
+        # be named again for the budget.
+        breakers = [ { k: v for k, v in breaker.items() if k != '_name' }
+                     for breaker in (self.get('circuit_breakers', None) or []) ]
+
+        default = None
+
+        for breaker in breakers:
+            if str(breaker.get('priority', 'default')).lower() == 'default':
+                default = breaker
+
+        if default is None:
+            default = {}
+            breakers.append(default)
+
+        if 'max_retries' in default:
+            self.ir.aconf.post_notice("circuit_breakers max_retries is replaced by the hedging budget, which covers retries as well as hedged requests", resource=self)
+
+        default['retry_budget'] = {
+            'budget_percent': hedging['budget_percent'],
+            'min_retry_concurrency': hedging.get('min_concurrency', IRHTTPMapping.DefaultHedgingMinConcurrency),
+        }
+
+        self['circuit_breakers'] = breakers
+        IRBaseMapping.validate_circuit_breakers(self.ir, breakers)
+
+    @staticmethod
+    def check_hedging(hedging: Any) -> Optional[str]:
+        """
+        Return what's wrong with a hedging, or None if nothing is.
+        """
+
+        if not isinstance(hedging, dict):
+            return f"{hedging} must be a dictionary"
+
+        unknown = set(hedging.keys()) - { 'budget_percent', 'min_concurrency' }
+
+        if unknown:
+            return f"unknown keys {', '.join(sorted(unknown))}"
+
+        budget_percent = hedging.get('budget_percent', None)
+
+        if not isinstance(budget_percent, int) or isinstance(budget_percent, bool) or \
+           not (0 <= budget_percent <= 100):
+            return f"budget_percent {budget_percent} must be an integer from 0 to 100"
+
+        min_concurrency = hedging.get('min_concurrency', None)
+
+        if (min_concurrency is not None) and \
+           (not isinstance(min_concurrency, int) or isinstance(min_concurrency, bool) or (min_concurrency < 0)):
+            return f"min_concurrency {min_concurrency} must be a non-negative integer"
+
+        return None
+
+    @staticmethod
+    def check_stats_dimensions(stats_dimensions: Any) -> Optional[str]:
+        """
+        Return what's wrong with stats_dimensions, or None if nothing is.
+        """
+
+        if not isinstance(stats_dimensions, list) or not stats_dimensions:
+            return f"{stats_dimensions} must be a non-empty list of dimensions"
+
+        names: Set[str] = set()
+
+        for dimension in stats_dimensions:
+            if not isinstance(dimension, dict):
+                return f"{dimension} must be a dictionary"
+
+            unknown = set(dimension.keys()) - { 'name', 'header', 'values' }
+
+            if unknown:
+                return f"unknown keys {', '.join(sorted(unknown))} in {dimension}"
+
+            name = dimension.get('name', None)
+
+            # The name is a stats tag, and part of the virtual cluster name, for all the
+            # Mappings that use it.
+            if not isinstance(name, str) or not re.fullmatch(r'[a-z][a-z0-9_]*', name):
+                return f"name {name} must be lowercase letters, digits, and underscores, starting with a letter"
+
+            if name in names:
+                return f"{name} is there more than once"
+
+            names.add(name)
+
+            header = dimension.get('header', None)
+
+            if not isinstance(header, str) or not header:
+                return f"{name}'s header must be a header name"
+
+            values = dimension.get('values', None)
+
+            if not isinstance(values, list) or not values or \
+               not all(isinstance(value, str) and value for value in values):
+                return f"{name}'s values must be a non-empty list of strings"
+
+            # Values go into the stats names with anything but letters, digits, and
+            # underscores turned into underscores, so they have to stay distinct after that.
+            tags = [ re.sub(r'[^0-9A-Za-z_]', '_', value) for value in values ]
+
+            if len(set(tags)) != len(tags):
+                return f"{name}'s values {values} aren't distinct once made safe for stats names"
+
+            if IRHTTPMapping.StatsDimensionOther in tags:
+                return f"{name}'s values can't include {IRHTTPMapping.StatsDimensionOther}, which is for requests that match none of them"
+
+        return None
+
+    @staticmethod
+    def check_resolver_fallback(resolver_fallback: Any) -> Optional[str]:
+        """
+        Return what's wrong with a resolver_fallback, or None if nothing is. Whether the
+        resolvers exist is up to the caller.
+        """
+
+        if not isinstance(resolver_fallback, list) or \
+           not all(isinstance(name, str) and name for name in resolver_fallback):
+            return f"{resolver_fallback} must be a list of resolver names"
+
+        if len(set(resolver_fallback)) != len(resolver_fallback):
+            return f"{resolver_fallback} names a resolver more than once"
+
+        return None
+
+    @staticmethod
+    def check_zero_replicas(zero_replicas: Any) -> Optional[str]:
+        """
+        Return what's wrong with a zero_replicas, or None if nothing is.
+        """
+
+        if not isinstance(zero_replicas, dict):
+            return f"{zero_replicas} must be a dictionary"
+
+        unknown = set(zero_replicas.keys()) - { 'respond', 'hold' }
+
+        if unknown:
+            return f"unknown keys {', '.join(sorted(unknown))}"
+
+        if len(zero_replicas) != 1:
+            return "exactly one of respond and hold is required"
+
+        respond = zero_replicas.get('respond', None)
+
+        if respond is not None:
+            error = IRHTTPMapping.check_static_fallback(respond)
+
+            return f"respond: {error}" if error else None
+
+        hold = zero_replicas['hold']
+
+        if not isinstance(hold, dict):
+            return f"hold {hold} must be a dictionary"
+
+        unknown = set(hold.keys()) - { 'service' }
+
+        if unknown:
+            return f"hold: unknown keys {', '.join(sorted(unknown))}"
+
+        service = hold.get('service', None)
+
+        if not isinstance(service, str) or not service:
+            return "hold needs a service"
+
+        return None
+
+    def zero_replicas_unusable(self, zero_replicas: Dict[str, Any]) -> Optional[str]:
+        """
+        Return why this Mapping can't use a (valid) zero_replicas, or None if it can.
+        """
+
+        if self.get('dynamic_forward_proxy', False):
+            return "dynamic_forward_proxy doesn't use a resolver"
+
+        # Through a Kubernetes Service, Envoy only ever sees the Service's own address, so it
+        # can't tell when there's nothing behind it: the request just fails to connect.
+        resolver = self.ir.get_resolver(self.resolver)
+
+        if resolver and (resolver.kind == 'KubernetesServiceResolver'):
+            return f"resolver {self.resolver} doesn't watch endpoints"
+
+        hold = zero_replicas.get('hold', None)
+
+        if hold and (hold['service'] == self.service):
+            return f"{self.service} can't hold requests for itself"
+
+        return None
+
+    @staticmethod
+    def check_ab_header(ab_header: Any) -> Optional[str]:
+        """
+        Return what's wrong with an ab_header, or None if nothing is.
+        """
+
+        if not isinstance(ab_header, dict):
+            return f"{ab_header} must be a dictionary"
+
+        unknown = set(ab_header.keys()) - { 'name', 'value', 'percent', 'cookie' }
+
+        if unknown:
+            return f"unknown keys {', '.join(sorted(unknown))}"
+
+        # Header and cookie names are both RFC 7230 tokens.
+        token = r"[!#$%&'*+.^_`|~0-9A-Za-z-]+"
+
+        name = ab_header.get('name', None)
+
+        if not isinstance(name, str) or not re.fullmatch(token, name):
+            return f"name must be a header name, not {name}"
+
+        value = ab_header.get('value', None)
+
+        if not isinstance(value, str):
+            return f"value must be a string, not {value}"
+
+        percent = ab_header.get('percent', None)
+
+        if isinstance(percent, bool) or not isinstance(percent, int) or not (0 <= percent <= 100):
+            return f"percent must be an integer from 0 to 100, not {percent}"
+
+        cookie = ab_header.get('cookie', None)
+
+        if (cookie is not None) and (not isinstance(cookie, str) or not re.fullmatch(token, cookie)):
+            return f"cookie must be a cookie name, not {cookie}"
+
+        return None
+
+    @staticmethod
+    def check_cookie_attributes(cookie_attributes: Any) -> Optional[str]:
+        """
+        Return what's wrong with a cookie_attributes, or None if nothing is.
+        """
+
+        if not isinstance(cookie_attributes, dict):
+            return f"{cookie_attributes} must be a dictionary"
+
+        unknown = set(cookie_attributes.keys()) - { 'secure', 'same_site' }
+
+        if unknown:
+            return f"unknown keys {', '.join(sorted(unknown))}"
+
+        secure = cookie_attributes.get('secure', False)
+        same_site = cookie_attributes.get('same_site', None)
+
+        if not isinstance(secure, bool):
+            return f"secure must be true or false, not {secure}"
+
+        if (same_site is not None) and (same_site not in IRHTTPMapping.CookieSameSiteValues):
+            return f"same_site must be one of {', '.join(IRHTTPMapping.CookieSameSiteValues)}, not {same_site}"
+
+        # Browsers throw these away.
+        if (same_site == 'None') and not secure:
+            return "same_site None needs secure"
+
+        return None
+
+    def cookie_attributes_policy(self) -> Optional[Dict[str, Any]]:
+        """
+        Return this Mapping's cookie_attributes (or else the Module's), or None if there's
+        nothing to add to cookies.
+        """
+        policy = self.get('cookie_attributes', None)
+
+        if policy is None:
+            policy = self.ir.ambassador_module.get('cookie_attributes', None)
+
+        if (not policy) or not (policy.get('secure', False) or policy.get('same_site', None)):
+            return None
+
+        return policy
+
+    def request_timeout_header_policy(self) -> Optional[Dict[str, Any]]:
+        """
+        Return this Mapping's request_timeout_header (or else the Module's) if it actually
+        changes what Envoy does with the header, or None if Envoy can just do the default.
+        """
+        policy = self.get('request_timeout_header', None)
+
+        if policy is None:
+            policy = self.ir.ambassador_module.get('request_timeout_header', None)
+
+        if (not policy) or (policy.get('honor', True) and (policy.get('max_ms', None) is None)):
+            return None
+
+        return policy
+
+    @staticmethod
+    def normalize_methods(methods: Any) -> Optional[List[str]]:
+        """
+        Uppercase, dedupe, and sort a list of HTTP methods, so that Mappings that allow the
+        same methods end up in the same group. Returns None if it's not a non-empty list of
+        method tokens. Methods Envoy doesn't know (PURGE, say) are fine.
+        """
+        if not isinstance(methods, list) or not methods:
+            return None
+
+        normalized = set()
+
+        for method in methods:
+            if not isinstance(method, str) or not re.fullmatch(r'[A-Za-z]+', method):
+                return None
+
+            normalized.add(method.upper())
+
+        return sorted(normalized)
+
+    @staticmethod
+    def content_types_regex(content_types: List[str]) -> Optional[str]:
+        """
+        Turn content_types into a regex for the Content-Type header. Returns "" if every type
+        is allowed, or None if an entry isn't a media type we know how to match. A subtype may
+        end with "*", so that application/grpc-web* gets all the grpc-web variants.
+        """
+        alternatives = []
+
+        for content_type in content_types:
+            if not isinstance(content_type, str) or not content_type:
+                return None
+
+            if content_type == '*/*':
+                return ""
+
+            match = re.fullmatch(r'([A-Za-z0-9][-\w.+]*)/(\*|[A-Za-z0-9][-\w.+]*\*?)', content_type)
+
+            if not match:
+                return None
+
+            media_type, subtype = match.groups()
+
+            if subtype == '*':
+                alternatives.append(re.escape(media_type) + r'/[^;\s]+')
+            elif subtype.endswith('*'):
+                alternatives.append(re.escape(f"{media_type}/{subtype[:-1]}") + r'[^;\s]*')
+            else:
+                alternatives.append(re.escape(f"{media_type}/{subtype}"))
+
+        if not alternatives:
+            return None
+
+        # Media types aren't case-sensitive, and may have parameters (like charset).
+        return '(?i)(?:' + '|'.join(alternatives) + r')(?:\s*;.*)?'
+
+    @staticmethod
+    def check_choice_count(load_balancer: Dict[str, Any]) -> Optional[str]:
+        """
+        Return what's wrong with a load_balancer's choice_count, or None if nothing is (or
+        there isn't one). least_request picks the host with the fewest active requests out
+        of choice_count random ones; more choices get closer to the least loaded host in a
+        big pool, at the cost of looking at more hosts for every request.
+        """
+
+        if 'choice_count' not in load_balancer:
+            return None
+
+        choice_count = load_balancer['choice_count']
+
+        if load_balancer.get('policy', None) != 'least_request':
+            return "choice_count only applies to policy least_request"
+
+        if isinstance(choice_count, bool) or not isinstance(choice_count, int):
+            return f"choice_count must be an integer, not {choice_count}"
+
+        # Envoy won't take 1, which would just be a random pick.
+        if choice_count < 2:
+            return f"choice_count {choice_count} must be at least 2; with fewer there's nothing to compare"
+
+        return None
+
     @staticmethod
     def validate_load_balancer(load_balancer) -> bool:
         lb_policy = load_balancer.get('policy', None)
@@ -504,6 +2046,8 @@ class IRHTTPMapping (IRBaseMapping):
         if lb_policy in ['round_robin', 'least_request']:
             if len(load_balancer) == 1:
                 is_valid = True
+            elif (lb_policy == 'least_request') and (len(load_balancer) == 2) and ('choice_count' in load_balancer):
+                is_valid = True
         elif lb_policy in ['ring_hash', 'maglev']:
             if len(load_balancer) == 2:
                 if 'cookie' in load_balancer:
@@ -514,6 +2058,15 @@ class IRHTTPMapping (IRBaseMapping):
                     is_valid = True
                 elif 'source_ip' in load_balancer:
                     is_valid = True
+                elif 'path' in load_balancer:
+                    path = load_balancer.get('path')
+
+                    if isinstance(path, dict) and set(path.keys()) <= { 'segments' }:
+                        segments = path.get('segments', None)
+
+                        if (segments is None) or \
+                           (isinstance(segments, int) and not isinstance(segments, bool) and (segments >= 1)):
+                            is_valid = True
 
         return is_valid
 
@@ -538,6 +2091,10 @@ class IRHTTPMapping (IRBaseMapping):
             if hdr.value is not None:
                 h.update(hdr.value.encode('utf-8'))
 
+            # Matching a value in any case isn't the same route as matching it exactly.
+            if hdr.ignore_case:
+                h.update('-i'.encode('utf-8'))
+
         for query_parameter in self.query_parameters:
             h.update(query_parameter.name.encode('utf-8'))
 
@@ -550,6 +2107,10 @@ class IRHTTPMapping (IRBaseMapping):
         return h.hexdigest()
 
     def _route_weight(self) -> List[Union[str, int]]:
+        # Routes go out heaviest first, so without an explicit precedence the longest prefix
+        # wins, and on equal prefixes the Mapping with more header and query parameter
+        # matching goes first. A regex prefix counts by the length of the pattern: we can't
+        # tell how specific a regex is, so that's on whoever sets precedence.
         len_headers = 0
         len_query_parameters = 0
 
@@ -7,7 +7,7 @@ from ..config import Config
 from .irresource import IRResource
 from .ircluster import IRCluster
 from .irbasemappinggroup import IRBaseMappingGroup
-from .irbasemapping import IRBaseMapping
+from .irbasemapping import IRBaseMapping, normalize_service_name
 
 if TYPE_CHECKING:
     from .ir import IR # pragma: no cover
@@ -34,6 +34,8 @@ class IRHTTPMappingGroup (IRBaseMappingGroup):
         'cluster_max_connection_lifetime_ms': True,
         'group_id': True,
         'headers': True,
+        'health_rebalance': True,
+        'health_rebalance_recovery_ms': True,
         # 'host_rewrite': True,
         # 'idle_timeout_ms': True,
         'keepalive': True,
@@ -44,11 +46,13 @@ class IRHTTPMappingGroup (IRBaseMappingGroup):
         # 'metadata_labels' will get flattened by merging. The group gets all the labels that all its
         # Mappings have.
         'method': True,
+        'methods': True,
         'prefix': True,
         'prefix_regex': True,
         'prefix_exact': True,
         # 'rewrite': True,
         # 'timeout_ms': True
+        'weight_runtime_key_prefix': True,
     }
 
     # We don't flatten cluster_key and stats_name because the whole point of those
@@ -73,6 +77,66 @@ class IRHTTPMappingGroup (IRBaseMappingGroup):
         'weight': True,
     })
 
+    # With weight_runtime_key_prefix or health_rebalance, the whole group is a single Envoy route
+    # that splits its traffic with weighted_clusters, and it's built from just one of the
+    # Mappings. These are the route settings that come from each Mapping, rather than the group,
+    # and that weighted_clusters can't set per cluster, so the Mappings have to agree on them.
+    WeightedRouteSettingKeys: ClassVar[List[str]] = [
+        'ab_header',
+        'auth_context_extensions',
+        'auto_host_rewrite',
+        'body_transform',
+        'bypass_compression',
+        'case_sensitive',
+        'cookie_attributes',
+        'decompressor_max_request_bytes',
+        'error_response_overrides',
+        'grpc_timeout_offset_ms',
+        'host_rewrite',
+        'host_rewrite_from_sni',
+        'idle_timeout_ms',
+        'jwt',
+        'log_sampling',
+        'max_grpc_timeout_ms',
+        'request_timeout_header',
+        'response_flag_stats',
+        'rewrite',
+        'static_fallback',
+        'stats_dimensions',
+        'stats_name',
+        'timeout_ms',
+        'typed_per_filter_config',
+        'virtual_cluster',
+        'zero_replicas',
+    ]
+
+    # With shadow_clone_cluster, these are the cluster settings a shadow takes from the Mappings
+    # it mirrors. They're all about how to talk to an upstream rather than which one it is, so
+    # they make sense even when the shadow points at a different service; things like the
+    # resolver, health_checks, host_rewrite and stats_name don't, so the shadow keeps its own.
+    ShadowCloneKeys: ClassVar[List[str]] = [
+        'auto_http_protocol',
+        'auto_sni',
+        'circuit_breakers',
+        'cluster_drain_time_ms',
+        'cluster_idle_timeout_ms',
+        'cluster_max_connection_lifetime_ms',
+        'cluster_protocol_options',
+        'connect_timeout_ms',
+        'dns_failure_refresh_rate_ms',
+        'dns_type',
+        'enable_ipv4',
+        'enable_ipv6',
+        'grpc',
+        'http2_keepalive',
+        'keepalive',
+        'load_balancer',
+        'max_requests_per_connection',
+        'respect_dns_ttl',
+        'tls',
+        'upstream_bind_address',
+    ]
+
     @staticmethod
     def helper_mappings(res: IRResource, k: str) -> Tuple[str, List[dict]]:
         return k, list(reversed(sorted([ x.as_dict() for x in res.mappings ],
@@ -215,15 +279,72 @@ class IRHTTPMappingGroup (IRBaseMappingGroup):
 
         # self.ir.logger.debug("%s: group now %s" % (self, self.as_json()))
 
+    def check_conflicts(self) -> None:
+        """
+        Post a notice about every Mapping in this group that shares it with another Mapping, but
+        without either of them saying how much traffic it wants. Mappings only share a group
+        when they match exactly the same requests (same method, prefix, host, headers, query
+        parameters, and precedence), so the split is usually someone's copy-and-paste rather
+        than a canary. Giving any of them a weight says it's on purpose.
+        """
+
+        weightless = [ mapping for mapping in self.mappings if 'weight' not in mapping ]
+
+        for mapping in weightless[1:]:
+            first = weightless[0]
+
+            self.ir.aconf.post_notice(f"Mapping {mapping.name}.{mapping.namespace} matches exactly the same requests as Mapping {first.name}.{first.namespace} (prefix {first.prefix}), and neither sets a weight, so they split its traffic evenly; set weight on them if that's intended", resource=mapping)
+
+    def check_weighted_route_settings(self) -> None:
+        """
+        If this group would be one weighted_clusters route, but its Mappings don't agree on the
+        route settings in WeightedRouteSettingKeys, post an error about it on each of them, and
+        drop weight_runtime_key_prefix and health_rebalance, so that each Mapping gets its own
+        route (and its own settings) again.
+        """
+
+        if not (self.get('weight_runtime_key_prefix', None) or self.get('health_rebalance', False)):
+            return
+
+        first = self.mappings[0]
+        keys = [ k for k in IRHTTPMappingGroup.WeightedRouteSettingKeys
+                 if any(mapping.get(k, None) != first.get(k, None) for mapping in self.mappings[1:]) ]
+
+        if not keys:
+            return
+
+        for mapping in self.mappings:
+            self.ir.post_error(f"the Mappings for prefix {first.prefix} set {', '.join(keys)} differently, so they can't share one weighted route; ignoring weight_runtime_key_prefix and health_rebalance", resource=mapping)
+
+        self.pop('weight_runtime_key_prefix', None)
+        self.pop('health_rebalance', None)
+        self.pop('health_rebalance_recovery_ms', None)
+
     def add_cluster_for_mapping(self, mapping: IRBaseMapping,
-                                marker: Optional[str] = None) -> IRCluster:
-        # Find or create the cluster for this Mapping...
+                                marker: Optional[str] = None,
+                                inherited: Optional[Dict[str, Any]] = None) -> IRCluster:
+        # Find or create the cluster for this Mapping, with the inherited settings (which never
+        # include any the Mapping set itself) in place of its own...
+
+        def setting(key: str, default: Any = None) -> Any:
+            if inherited and (key in inherited):
+                return inherited[key]
+
+            return mapping.get(key, default)
 
         self.ir.logger.debug(f"IRHTTPMappingGroup: {self.group_id} adding cluster for Mapping {mapping.name} (key {mapping.cluster_key})")
 
         cluster: Optional[IRCluster] = None
 
-        if mapping.cluster_key:
+        # A resolver_fallback cluster is an aggregate of one cluster per resolver (and a
+        # zero_replicas hold one is an aggregate with the hold service's at the end), which
+        # isn't something the cache can put back together, so it's built fresh every time
+        # and never cached.
+        resolver_fallback = mapping.get('resolver_fallback', None) or []
+        hold = (mapping.get('zero_replicas', None) or {}).get('hold', None)
+        aggregate = bool(resolver_fallback or hold)
+
+        if mapping.cluster_key and not aggregate:
             # Aha. Is our cluster already in the cache?
             cached_cluster = self.ir.cache_fetch(mapping.cluster_key)
 
@@ -238,33 +359,84 @@ class IRHTTPMappingGroup (IRBaseMappingGroup):
         if not cluster:
             # OK, we have to actually do some work.
             self.ir.logger.debug(f"IRHTTPMappingGroup: synthesizing Cluster for {mapping.name}")
-            cluster = IRCluster(ir=self.ir, aconf=self.ir.aconf,
-                                parent_ir_resource=mapping,
-                                location=mapping.location,
-                                service=mapping.service,
-                                resolver=mapping.resolver,
-                                ctx_name=mapping.get('tls', None),
-                                dns_type=mapping.get('dns_type', 'strict_dns'),
-                                host_rewrite=mapping.get('host_rewrite', False),
-                                enable_ipv4=mapping.get('enable_ipv4', None),
-                                enable_ipv6=mapping.get('enable_ipv6', None),
-                                grpc=mapping.get('grpc', False),
-                                load_balancer=mapping.get('load_balancer', None),
-                                keepalive=mapping.get('keepalive', None),
-                                connect_timeout_ms=mapping.get('connect_timeout_ms', 3000),
-                                cluster_idle_timeout_ms=mapping.get('cluster_idle_timeout_ms', None),
-                                cluster_max_connection_lifetime_ms=mapping.get('cluster_max_connection_lifetime_ms', None),
-                                circuit_breakers=mapping.get('circuit_breakers', None),
-                                marker=marker,
-                                stats_name=mapping.get('stats_name'),
-                                respect_dns_ttl=mapping.get('respect_dns_ttl', False))
+            cluster_args: Dict[str, Any] = dict(
+                parent_ir_resource=mapping,
+                location=mapping.location,
+                service=mapping.service,
+                ctx_name=setting('tls', None),
+                dns_type=setting('dns_type', 'strict_dns'),
+                host_rewrite=mapping.get('host_rewrite', False),
+                auto_sni=setting('auto_sni', False),
+                auto_http_protocol=setting('auto_http_protocol', False),
+                enable_ipv4=setting('enable_ipv4', None),
+                enable_ipv6=setting('enable_ipv6', None),
+                grpc=setting('grpc', False),
+                load_balancer=setting('load_balancer', None),
+                keepalive=setting('keepalive', None),
+                connect_timeout_ms=setting('connect_timeout_ms', 3000),
+                cluster_idle_timeout_ms=setting('cluster_idle_timeout_ms', None),
+                cluster_max_connection_lifetime_ms=setting('cluster_max_connection_lifetime_ms', None),
+                max_requests_per_connection=setting('max_requests_per_connection', None),
+                circuit_breakers=setting('circuit_breakers', None),
+                marker=marker,
+                stats_name=mapping.get('stats_name'),
+                respect_dns_ttl=setting('respect_dns_ttl', False),
+                upstream_bind_address=setting('upstream_bind_address', None),
+                dns_failure_refresh_rate_ms=setting('dns_failure_refresh_rate_ms', None),
+                initial_fetch_timeout_ms=mapping.get('initial_fetch_timeout_ms', None),
+                cluster_drain_time_ms=setting('cluster_drain_time_ms', None),
+                http2_keepalive=setting('http2_keepalive', None),
+                health_checks=mapping.get('health_checks', None),
+                cluster_protocol_options=setting('cluster_protocol_options', None),
+                histogram_buckets=[] if mapping.get('disable_histograms', False) else mapping.get('histogram_buckets', None),
+                dynamic_forward_proxy=bool(mapping.get('dynamic_forward_proxy', None))
+            )
+
+            if aggregate:
+                # Envoy's aggregate cluster sends everything to the first of these that has
+                # any healthy endpoints, so if the Mapping's own resolver comes up empty, the
+                # next one takes over until it's back. Each resolver gets the service the way
+                # it would want it if it were the Mapping's own, namespace and all.
+                members: List[IRCluster] = []
+                member_services = [ (resolver, mapping.service) for resolver in [ mapping.resolver ] + resolver_fallback ]
+
+                # When they're all empty, the hold service gets the requests, found with the
+                # Mapping's own resolver. It's a different service, so the Mapping's TLS and
+                # health checks aren't for it.
+                if hold:
+                    member_services.append((mapping.resolver, hold['service']))
+
+                for resolver, service in member_services:
+                    resolver_kind = self.ir.resolvers[resolver].kind
+                    member_args = dict(cluster_args)
+                    member_args['service'] = normalize_service_name(self.ir, service, mapping.namespace, resolver_kind, rkey=mapping.rkey)
+
+                    if service != mapping.service:
+                        member_args['ctx_name'] = None
+                        member_args['health_checks'] = None
+
+                    member = self.ir.add_cluster(IRCluster(ir=self.ir, aconf=self.ir.aconf, resolver=resolver, **member_args))
+                    member.referenced_by(mapping)
+                    members.append(member)
+
+                cluster = IRCluster(ir=self.ir, aconf=self.ir.aconf, resolver=mapping.resolver,
+                                    aggregate_clusters=[ member.name for member in members ],
+                                    **cluster_args)
+            else:
+                cluster = IRCluster(ir=self.ir, aconf=self.ir.aconf, resolver=mapping.resolver, **cluster_args)
 
         # Make sure that the cluster is actually in our IR...
         stored = self.ir.add_cluster(cluster)
         stored.referenced_by(mapping)
 
+        # Mappings that share a cluster share its stats, too.
+        histogram_buckets = [] if mapping.get('disable_histograms', False) else mapping.get('histogram_buckets', None)
+
+        if (histogram_buckets is not None) and (histogram_buckets != stored.get('histogram_buckets', None)):
+            self.ir.aconf.post_notice(f"histogram_buckets is ignored: cluster {stored.name} already has histogram_buckets {stored.get('histogram_buckets', None)}", resource=mapping)
+
         # ...and then check if we just synthesized this cluster.
-        if not mapping.cluster_key:
+        if not mapping.cluster_key and not aggregate:
             # Yes. The mapping is already in the cache, but we need to cache the cluster...
             self.ir.cache_add(stored)
 
@@ -288,6 +460,37 @@ class IRHTTPMappingGroup (IRBaseMappingGroup):
         self.ir.logger.debug(f"IRHTTPMappingGroup: %s returning cluster %s for Mapping %s", self.group_id, stored, mapping.name)
         return stored
 
+    def shadow_cluster_settings(self, shadow: IRBaseMapping) -> Dict[str, Any]:
+        """
+        Work out which cluster settings a shadow with shadow_clone_cluster inherits: every one of
+        the ShadowCloneKeys that the mirrored Mappings agree on and the shadow didn't set itself.
+        The shadow's own settings were recorded during its setup, since by now it also has the
+        Module's defaults, and those mustn't win over what it's cloning.
+
+        :param shadow: the shadow Mapping
+        :return: the settings to use wherever the shadow doesn't have its own
+        """
+
+        own = shadow.get('shadow_own_settings', [])
+        inherited: Dict[str, Any] = {}
+
+        for key in IRHTTPMappingGroup.ShadowCloneKeys:
+            if key in own:
+                continue
+
+            values = [ mapping.get(key, None) for mapping in self.mappings ]
+
+            if any(value != values[0] for value in values[1:]):
+                # Canary Mappings in the same group can differ on anything that isn't a core
+                # key, and there's no telling which of them the shadow is meant to look like.
+                self.ir.aconf.post_notice(f"shadow_clone_cluster doesn't clone {key}: the Mappings in group {self.group_id} don't agree on it", resource=shadow)
+                continue
+
+            if values and (values[0] is not None):
+                inherited[key] = values[0]
+
+        return inherited
+
     def finalize(self, ir: 'IR', aconf: Config) -> List[IRCluster]:
         """
         Finalize a MappingGroup based on the attributes of its Mappings. Core elements get lifted into
@@ -301,6 +504,7 @@ class IRHTTPMappingGroup (IRBaseMappingGroup):
         add_request_headers: Dict[str, Any] = {}
         add_response_headers: Dict[str, Any] = {}
         metadata_labels: Dict[str, str] = {}
+        metadata_annotations: Dict[str, str] = {}
 
         self.ir.logger.debug(f"IRHTTPMappingGroup: finalize %s", self.group_id)
 
@@ -325,6 +529,7 @@ class IRHTTPMappingGroup (IRBaseMappingGroup):
             # Should we have higher weights win over lower if there are conflicts?
             # Should we disallow conflicts?
             metadata_labels.update(mapping.get('metadata_labels') or {})
+            metadata_annotations.update(mapping.get('metadata_annotations') or {})
 
         if add_request_headers:
             self.add_request_headers = add_request_headers
@@ -334,6 +539,9 @@ class IRHTTPMappingGroup (IRBaseMappingGroup):
         if metadata_labels:
             self.metadata_labels = metadata_labels
 
+        if metadata_annotations:
+            self.metadata_annotations = metadata_annotations
+
         if self.get('load_balancer', None) is None:
             self['load_balancer'] = ir.ambassador_module.load_balancer
 
@@ -407,8 +615,10 @@ class IRHTTPMappingGroup (IRBaseMappingGroup):
             # Only one shadow is supported right now.
             shadow = self.shadows[0]
 
-            # The shadow is an IRMapping. Save the cluster for it.
-            shadow.cluster = self.add_cluster_for_mapping(shadow, marker='shadow')
+            # The shadow is an IRMapping. Save the cluster for it, cloning the settings of the
+            # Mappings it mirrors if it asked for that.
+            inherited = self.shadow_cluster_settings(shadow) if shadow.get('shadow_clone_cluster', False) else None
+            shadow.cluster = self.add_cluster_for_mapping(shadow, marker='shadow', inherited=inherited)
 
         # We don't need a cluster for host_redirect: it's just a name to redirect to.
 
@@ -420,6 +630,11 @@ class IRHTTPMappingGroup (IRBaseMappingGroup):
             for mapping in self.mappings:
                 mapping.cluster = self.add_cluster_for_mapping(mapping, mapping.cluster_tag)
 
+            if ir.ambassador_module.get('warn_on_mapping_conflicts', True):
+                self.check_conflicts()
+
+            self.check_weighted_route_settings()
+
             self.ir.logger.debug(f"IRHTTPMappingGroup: normalizing weights for %s", self.group_id)
 
             if not self.normalize_weights_in_mappings():
@@ -1,4 +1,4 @@
-from typing import Dict, List, Optional, Tuple, TYPE_CHECKING
+from typing import Any, ClassVar, Dict, List, Optional, Tuple, TYPE_CHECKING
 
 import copy
 import json
@@ -6,6 +6,7 @@ import json
 from ..config import Config
 from ..utils import dump_json
 
+from .irambassador import IRAmbassador
 from .irhost import IRHost
 from .irresource import IRResource
 from .irtlscontext import IRTLSContext
@@ -33,15 +34,28 @@ class IRListener (IRResource):
 
     AllowedKeys = {
         'bind_address',
+        'codecType',
+        'connectionRateLimit',
+        'destinationPort',
+        'forwardedProto',
         'l7Depth',
         'hostBinding',  # Note that hostBinding gets processed and deleted in setup.
+        'keepAlive',
         'port',
         'protocol',
         'protocolStack',
+        'requestHeadersTimeoutMs',
+        'requestSmugglingProtection',
         'securityModel',
         'statsPrefix',
+        'tlsDetection',
+        'uploadBuffering',
     }
 
+    # An uploadBuffering connectionBufferBytes over this gets a notice: it's how much each
+    # connection can hold onto, and there can be a lot of connections.
+    LargeConnectionBufferBytes: ClassVar[int] = 64 * 1024 * 1024
+
     ProtocolStacks: Dict[str, List[str]] = {
         # HTTP: accepts cleartext HTTP/1.1 sessions over TCP.
         "HTTP": [ "HTTP", "TCP" ],
@@ -65,6 +79,10 @@ class IRListener (IRResource):
         # "UDP": [ "UDP" ],
     }
 
+    # Addresses that bind every interface, and so collide with any other address on the
+    # same port.
+    WildcardAddresses = { "0.0.0.0", "::" }
+
     def __init__(self, ir: 'IR', aconf: Config,
                  rkey: str,      # REQUIRED
                  name: str,      # REQUIRED
@@ -107,6 +125,15 @@ class IRListener (IRResource):
 
         ir.logger.debug(f"Listener {self.name} setting up on {self.bind_address}:{self.port}")
 
+        # Envoy's admin interface is on 127.0.0.1, on the Ambassador Module's admin_port, and
+        # if a Listener grabs that first, Envoy won't start at all.
+        admin_port = ir.ambassador_module.get('admin_port', None)
+
+        if (self.port == admin_port) and \
+           ((self.bind_address == "127.0.0.1") or (self.bind_address in IRListener.WildcardAddresses)):
+            self.post_error(f"port {self.port} is the Envoy admin port (set by the Ambassador Module's admin_port); ignoring this Listener")
+            return False
+
         pstack = self.get("protocolStack", None)
         protocol = self.get("protocol", None)
         securityModel = self.get("securityModel", None)
@@ -153,6 +180,96 @@ class IRListener (IRResource):
             else:
                 self.statsPrefix = f"ingress_tcp_{self.port}"
 
+        # codecType only means anything for HTTP.
+        codec_type = self.get("codecType", None)
+
+        if codec_type:
+            if "HTTP" not in self.protocolStack:
+                self.post_error(f"codecType {codec_type} only applies to HTTP listeners; ignoring it")
+                del(self["codecType"])
+            elif codec_type not in ( "AUTO", "HTTP1", "HTTP2" ):
+                self.post_error(f"codecType must be AUTO, HTTP1, or HTTP2, not {codec_type}; ignoring it")
+                del(self["codecType"])
+
+        # So does forwardedProto.
+        forwarded_proto = self.get("forwardedProto", None)
+
+        if forwarded_proto:
+            if "HTTP" not in self.protocolStack:
+                self.post_error(f"forwardedProto {forwarded_proto} only applies to HTTP listeners; ignoring it")
+                del(self["forwardedProto"])
+            elif forwarded_proto not in ( "http", "https" ):
+                self.post_error(f"forwardedProto must be http or https, not {forwarded_proto}; ignoring it")
+                del(self["forwardedProto"])
+            elif securityModel == "XFP":
+                # Legal, but the Listener will decide what's secure from one X-Forwarded-Proto
+                # and tell the upstream another, which is probably not what anyone wants.
+                ir.aconf.post_notice(f"Listener {self.name}: forwardedProto {forwarded_proto} doesn't change how securityModel XFP routes requests", resource=self)
+
+        # And requestHeadersTimeoutMs.
+        request_headers_timeout_ms = self.get("requestHeadersTimeoutMs", None)
+
+        if request_headers_timeout_ms is not None:
+            error = IRAmbassador.check_request_headers_timeout(request_headers_timeout_ms)
+
+            if "HTTP" not in self.protocolStack:
+                self.post_error(f"requestHeadersTimeoutMs only applies to HTTP listeners; ignoring it")
+                del(self["requestHeadersTimeoutMs"])
+            elif error:
+                self.post_error(f"requestHeadersTimeoutMs {error}; ignoring it")
+                del(self["requestHeadersTimeoutMs"])
+            elif 0 < request_headers_timeout_ms < IRAmbassador.ShortRequestHeadersTimeoutMs:
+                ir.aconf.post_notice(f"Listener {self.name}: requestHeadersTimeoutMs {request_headers_timeout_ms} is short enough to cut off slow clients", resource=self)
+
+        # And requestSmugglingProtection.
+        request_smuggling_protection = self.get("requestSmugglingProtection", None)
+
+        if request_smuggling_protection:
+            if "HTTP" not in self.protocolStack:
+                self.post_error(f"requestSmugglingProtection {request_smuggling_protection} only applies to HTTP listeners; ignoring it")
+                del(self["requestSmugglingProtection"])
+            elif request_smuggling_protection not in IRAmbassador.RequestSmugglingProtections:
+                self.post_error(f"requestSmugglingProtection must be strict or legacy, not {request_smuggling_protection}; ignoring it")
+                del(self["requestSmugglingProtection"])
+            elif request_smuggling_protection == 'legacy':
+                ir.aconf.post_notice(f"Listener {self.name}: requestSmugglingProtection legacy lets requests with both Content-Length and Transfer-Encoding through, which can allow request smuggling", resource=self)
+
+        # And tlsDetection, which also needs TLS.
+        tls_detection = self.get("tlsDetection", None)
+
+        if tls_detection:
+            if ("HTTP" not in self.protocolStack) or ("TLS" not in self.protocolStack):
+                self.post_error(f"tlsDetection {tls_detection} only applies to listeners with both TLS and HTTP; ignoring it")
+                del(self["tlsDetection"])
+            elif tls_detection not in ( "SNI", "Split" ):
+                self.post_error(f"tlsDetection must be SNI or Split, not {tls_detection}; ignoring it")
+                del(self["tlsDetection"])
+
+        # And uploadBuffering, for Listeners taking large uploads.
+        upload_buffering = self.get("uploadBuffering", None)
+
+        if upload_buffering is not None:
+            error = IRListener.check_upload_buffering(upload_buffering)
+
+            if "HTTP" not in self.protocolStack:
+                self.post_error(f"uploadBuffering only applies to HTTP listeners; ignoring it")
+                del(self["uploadBuffering"])
+            elif error:
+                self.post_error(f"uploadBuffering {error}; ignoring it")
+                del(self["uploadBuffering"])
+            elif upload_buffering.get('connectionBufferBytes', 0) > IRListener.LargeConnectionBufferBytes:
+                ir.aconf.post_notice(f"Listener {self.name}: uploadBuffering connectionBufferBytes {upload_buffering['connectionBufferBytes']} is how much memory every connection can use", resource=self)
+
+        # And connectionRateLimit, which works the same for every protocol.
+        connection_rate_limit = self.get("connectionRateLimit", None)
+
+        if connection_rate_limit is not None:
+            error = IRListener.check_connection_rate_limit(connection_rate_limit)
+
+            if error:
+                self.post_error(f"connectionRateLimit {error}; ignoring it")
+                del(self["connectionRateLimit"])
+
         # Deal with hostBinding. First up, namespaces.
         hostbinding = self.get("hostBinding", None)
 
@@ -228,6 +345,65 @@ class IRListener (IRResource):
 
         return True
 
+    @staticmethod
+    def check_upload_buffering(upload_buffering: Any) -> Optional[str]:
+        """
+        Return what's wrong with an uploadBuffering, or None if nothing is.
+        """
+
+        if not isinstance(upload_buffering, dict):
+            return f"{upload_buffering} must be a dictionary"
+
+        unknown = set(upload_buffering.keys()) - { 'connectionBufferBytes', 'stream' }
+
+        if unknown:
+            return f"unknown keys {', '.join(sorted(unknown))}"
+
+        connection_buffer_bytes = upload_buffering.get('connectionBufferBytes', None)
+
+        if (connection_buffer_bytes is not None) and \
+           (isinstance(connection_buffer_bytes, bool) or not isinstance(connection_buffer_bytes, int) or (connection_buffer_bytes < 1)):
+            return f"connectionBufferBytes {connection_buffer_bytes} must be a positive integer"
+
+        stream = upload_buffering.get('stream', None)
+
+        if (stream is not None) and not isinstance(stream, bool):
+            return f"stream {stream} must be true or false"
+
+        return None
+
+    @staticmethod
+    def check_connection_rate_limit(connection_rate_limit: Any) -> Optional[str]:
+        """
+        Return what's wrong with a connectionRateLimit, or None if nothing is.
+        """
+
+        if not isinstance(connection_rate_limit, dict):
+            return f"{connection_rate_limit} must be a dictionary"
+
+        unknown = set(connection_rate_limit.keys()) - { 'connectionsPerSecond', 'burst' }
+
+        if unknown:
+            return f"unknown keys {', '.join(sorted(unknown))}"
+
+        for key in [ 'connectionsPerSecond', 'burst' ]:
+            value = connection_rate_limit.get(key, None)
+
+            if (key == 'connectionsPerSecond') and (value is None):
+                return "connectionsPerSecond is required"
+
+            if (value is not None) and \
+               (isinstance(value, bool) or not isinstance(value, int) or (value < 1)):
+                return f"{key} {value} must be a positive integer"
+
+        # The bucket only holds burst connections, so a smaller burst would be the real rate.
+        burst = connection_rate_limit.get('burst', None)
+
+        if (burst is not None) and (burst < connection_rate_limit['connectionsPerSecond']):
+            return f"burst {burst} can't be less than connectionsPerSecond {connection_rate_limit['connectionsPerSecond']}"
+
+        return None
+
     def matches_host(self, host: IRHost) -> bool:
         """
         Returns True IFF this Listener wants to take the given IRHost -- meaning,
@@ -272,6 +448,29 @@ class IRListener (IRResource):
     def bind_to(self) -> str:
         return f"{self.bind_address}-{self.port}"
 
+    def listener_key(self) -> str:
+        # Listeners that share a port but match different destination ports are distinct;
+        # V3Listener merges them back into a single Envoy listener.
+        dport = self.get('destinationPort', None)
+
+        if dport:
+            return f"{self.bind_to()}-dport-{dport}"
+
+        return self.bind_to()
+
+    def conflicts_with(self, other: 'IRListener') -> bool:
+        # Envoy can't bind two listeners to overlapping addresses on the same port. The one
+        # exception is Listeners on the same address with different destination ports, since
+        # V3Listener merges those into one Envoy listener.
+        if self.port != other.port:
+            return False
+
+        if self.bind_address == other.bind_address:
+            return self.listener_key() == other.listener_key()
+
+        return (self.bind_address in IRListener.WildcardAddresses) or \
+               (other.bind_address in IRListener.WildcardAddresses)
+
 
 class ListenerFactory:
     @classmethod
@@ -384,6 +583,44 @@ class ListenerFactory:
         #                 }
         #             ))
 
+        # With no Listeners and no Hosts, nothing would be listening at all, and clients would
+        # just get their connections refused. If the Ambassador Module asks for it, listen
+        # anyway, and answer everything with a default response (except ACME challenges, so
+        # that getting a first cert still works). Once there's a real Listener or Host, the
+        # user's config takes over and this goes away.
+        default_listener = amod.get('default_listener', None)
+
+        if (default_listener is not None) and not ir.listeners:
+            user_hosts = [ host for host in (ir.get_hosts() or [])
+                           if not ((host.rkey == "-internal") and (host.name == "default-host")) ]
+
+            if user_hosts:
+                ir.aconf.post_notice("default_listener is ignored because Hosts are defined; add a Listener for them")
+            else:
+                port = default_listener.get('port', None) or 8080
+
+                ir.logger.debug("ListenerFactory: synthesizing default listener on port %d", port)
+
+                listener = IRListener(
+                    ir, aconf, "-internal-", f"ambassador-default-listener-{port}", "-internal-",
+                    port=port,
+                    protocol="HTTP",
+                    securityModel="XFP",
+                    hostBinding={
+                        "namespace": {
+                            "from": "ALL"
+                        }
+                    }
+                )
+
+                if listener.is_active():
+                    listener['default_response'] = {
+                        'status': default_listener.get('status', None) or 404,
+                        'body': default_listener.get('body', None),
+                    }
+
+                    ir.save_listener(listener)
+
         # Finally, cycle over our TCPMappingGroups and make sure we have
         # Listeners for all of them, too.
         for group in ir.ordered_groups():
@@ -3,6 +3,7 @@ from typing import Any, ClassVar, Dict, List, Optional, Tuple, Type, Union, TYPE
 from ..config import Config
 
 from .irbasemapping import IRBaseMapping
+from .irfilter import IRFilter
 from .irhttpmapping import IRHTTPMapping
 from .irtcpmapping import IRTCPMapping
 
@@ -25,6 +26,17 @@ def unique_mapping_name(aconf: Config, name: str) -> str:
 
 
 class MappingFactory:
+    @staticmethod
+    def insert_route_filter(ir: 'IR', aconf: Config, name: str, index: int) -> None:
+        """
+        Put an ir.<name> filter at this spot in the filter chain. These only do anything on
+        the routes that configure them, so they're only put in if some Mapping will.
+        """
+        route_filter = IRFilter(ir=ir, aconf=aconf, kind=f'ir.{name}', name=name, config={})
+        route_filter.sourced_by(ir.ambassador_module)
+        ir.save_resource(route_filter)
+        ir.filters.insert(index, route_filter)
+
     @classmethod
     def load_all(cls, ir: 'IR', aconf: Config) -> None:
         cls.load_config(ir, aconf, "Mapping", "mappings", IRHTTPMapping)
@@ -88,6 +100,71 @@ class MappingFactory:
 
         ir.logger.debug("IR: MappingFactory finalizing")
 
+        # Every Mapping with response_flag_stats gets a virtual cluster, and every virtual
+        # cluster is a couple dozen more stats in every scrape, so cap how many there are.
+        # Pick the survivors by name, so that which ones lose out doesn't change with the
+        # order things were loaded in.
+        limit = ir.ambassador_module.get('response_flag_stats_limit', IRHTTPMapping.DefaultResponseFlagStatsLimit)
+        tracked = sorted([ mapping for group in ir.groups.values() for mapping in group.mappings
+                           if mapping.get('response_flag_stats', None) ],
+                         key=lambda mapping: (mapping.namespace, mapping.name))
+
+        for mapping in tracked[limit:]:
+            mapping.post_error(f"response_flag_stats would go over response_flag_stats_limit ({limit} Mappings), ignoring")
+            del mapping['response_flag_stats']
+
+        http_mappings = [ mapping for group in ir.groups.values() for mapping in group.mappings
+                          if isinstance(mapping, IRHTTPMapping) ]
+
+        # Envoy can't switch the compressor off per route, so Mappings with bypass_compression
+        # use a filter of their own to mark their responses no-transform. It has to come right
+        # after gzip, so that it sees responses before gzip does.
+        gzip = ir.ambassador_module.get('gzip', None)
+
+        if gzip and any([ mapping.get('bypass_compression', False) for mapping in http_mappings ]):
+            cls.insert_route_filter(ir, aconf, 'compression_exclusion', ir.filters.index(gzip) + 1)
+
+        # Likewise, the router takes a request's x-envoy-upstream-rq-timeout-ms as it finds
+        # it, so Mappings that drop or clamp it need a filter of their own to do that first --
+        # anywhere before the router will do, since Envoy has already stripped the header from
+        # external requests by then.
+        router_index = [ f.kind for f in ir.filters ].index('ir.router')
+
+        if any([ mapping.request_timeout_header_policy() for mapping in http_mappings ]):
+            cls.insert_route_filter(ir, aconf, 'request_timeout_header', router_index)
+
+        # Rewriting Set-Cookie is the same story. Right before the router is also the first
+        # place responses come back to.
+        router_index = [ f.kind for f in ir.filters ].index('ir.router')
+
+        if any([ mapping.cookie_attributes_policy() for mapping in http_mappings ]):
+            cls.insert_route_filter(ir, aconf, 'cookie_attributes', router_index)
+
+        # Body transformation buffers the body in Lua, before the router sends the request
+        # on, and (on the way back) before gzip compresses the response.
+        router_index = [ f.kind for f in ir.filters ].index('ir.router')
+
+        if any([ mapping.body_transform_policy() for mapping in http_mappings ]):
+            cls.insert_route_filter(ir, aconf, 'body_transform', router_index)
+
+        # JWTs are checked right after CORS, so that a request without a good one is turned away
+        # before auth or ratelimiting spends any time on it. The claims check reads what
+        # jwt_authn found, so it has to come next.
+        jwt_policies = [ mapping.jwt_policy() for mapping in http_mappings ]
+
+        if any(jwt_policies):
+            cors_index = [ f.kind for f in ir.filters ].index('ir.cors')
+            cls.insert_route_filter(ir, aconf, 'jwt_authn', cors_index + 1)
+
+            if any([ policy['claims'] for policy in jwt_policies if policy ]):
+                cls.insert_route_filter(ir, aconf, 'jwt_claims', cors_index + 2)
+
+        # Access logs only get to see what's in a request's dynamic metadata, so Mappings with
+        # log_sampling mark their requests there. That goes first in the chain, so that even a
+        # request another filter answers is marked as the Mapping's.
+        if any([ mapping.log_sampling_policy() for mapping in http_mappings ]):
+            cls.insert_route_filter(ir, aconf, 'log_sampling', 0)
+
         for group in ir.groups.values():
             ir.logger.debug("IR: MappingFactory finalizing group %s", group.group_id)
             group.finalize(ir, aconf)
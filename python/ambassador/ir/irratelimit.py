@@ -1,7 +1,7 @@
 from typing import Optional, TYPE_CHECKING
 
 from ..config import Config
-from ..utils import RichStatus
+from ..utils import RichStatus, parse_bool
 
 from .irfilter import IRFilter
 from .ircluster import IRCluster
@@ -71,6 +71,13 @@ class IRRateLimit (IRFilter):
             "request_type": "both"  # XXX configurability!
         }
 
+        # Envoy lets requests through when it can't reach the RateLimitService, unless told
+        # to fail closed.
+        failure_mode_deny = config.get('failure_mode_deny', None)
+
+        if failure_mode_deny is not None:
+            self.config["failure_mode_deny"] = parse_bool(failure_mode_deny)
+
         self.sourced_by(config)
         self.referenced_by(config)
 
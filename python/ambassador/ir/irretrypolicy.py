@@ -1,4 +1,6 @@
-from typing import Any, TYPE_CHECKING
+from typing import Any, ClassVar, List, Optional, TYPE_CHECKING
+
+import durationpy
 
 from ..config import Config
 from ..utils import RichStatus
@@ -9,6 +11,14 @@ if TYPE_CHECKING:
     from .ir import IR # pragma: no cover
 
 class IRRetryPolicy (IRResource):
+    # What retry_on_reset retries: the upstream resetting the request, or never taking it at
+    # all, before sending any response.
+    ResetConditions: ClassVar[List[str]] = [ 'reset', 'connect-failure', 'refused-stream' ]
+
+    # Envoy's reset condition doesn't know whether the upstream got as far as acting on the
+    # request, so retry_on_reset sticks to the methods that are safe to repeat.
+    IdempotentMethods: ClassVar[List[str]] = [ 'GET', 'HEAD', 'OPTIONS', 'TRACE', 'PUT', 'DELETE' ]
+
     def __init__(self, ir: 'IR', aconf: Config,
 
                  rkey: str="ir.retrypolicy",
@@ -27,10 +37,69 @@ class IRRetryPolicy (IRResource):
             self.post_error("Invalid retry policy specified: {}".format(self))
             return False
 
+        if 'per_try_timeout' in self:
+            error = self.validate_per_try_timeout()
+
+            if error:
+                self.post_error("Invalid per_try_timeout specified: {}".format(error))
+                return False
+
+        if 'retry_back_off' in self:
+            error = self.validate_retry_back_off()
+
+            if error:
+                # A bad backoff shouldn't cost us the retries themselves: fall back to Envoy's
+                # default backoff instead. (self.post_error would mark the whole policy, and so
+                # the Mapping, as errored.)
+                self.ir.post_error("Invalid retry_back_off specified: {}; using the default backoff".format(error), resource=self)
+                del self['retry_back_off']
+
+        if 'host_selection_retry_max_attempts' in self:
+            attempts = self.host_selection_retry_max_attempts
+
+            if not isinstance(attempts, int) or isinstance(attempts, bool) or attempts <= 0:
+                self.ir.post_error("Invalid host_selection_retry_max_attempts {}: must be a positive integer; using the default".format(attempts), resource=self)
+                del self['host_selection_retry_max_attempts']
+            elif not self.get('retry_other_hosts', False):
+                self.ir.aconf.post_notice("host_selection_retry_max_attempts does nothing without retry_other_hosts; ignoring it", resource=self)
+                del self['host_selection_retry_max_attempts']
+
+        if 'retry_on_response_headers' in self:
+            error = self.validate_retry_on_response_headers()
+
+            if error:
+                self.post_error("Invalid retry_on_response_headers specified: {}".format(error))
+                return False
+
+        if self.get('retry_non_idempotent', False) and not self.get('retry_on_reset', False):
+            self.ir.aconf.post_notice("retry_non_idempotent does nothing without retry_on_reset; ignoring it", resource=self)
+            del self['retry_non_idempotent']
+
         return True
 
     def validate_retry_policy(self) -> bool:
         retry_on = self.get('retry_on', None)
+        grpc_retry_on = self.get('grpc_retry_on', None)
+
+        for key in [ 'retry_on_reset', 'retry_non_idempotent' ]:
+            if not isinstance(self.get(key, False), bool):
+                return False
+
+        if grpc_retry_on is not None:
+            if not isinstance(grpc_retry_on, list) or not grpc_retry_on:
+                return False
+
+            for code in grpc_retry_on:
+                if code not in {'cancelled', 'deadline-exceeded', 'internal', 'resource-exhausted', 'unavailable'}:
+                    return False
+
+            # With gRPC codes, the HTTP conditions are optional.
+            if retry_on is None:
+                return True
+
+        # The same goes for retry_on_reset and retry_on_response_headers.
+        if (retry_on is None) and (self.get('retry_on_reset', False) or self.get('retry_on_response_headers', None)):
+            return True
 
         is_valid = False
         if retry_on in {'5xx', 'gateway-error', 'connect-failure', 'retriable-4xx', 'refused-stream', 'retriable-status-codes'}:
@@ -38,6 +107,74 @@ class IRRetryPolicy (IRResource):
 
         return is_valid
 
+    def validate_retry_on_response_headers(self) -> Optional[str]:
+        headers = self.retry_on_response_headers
+
+        if not isinstance(headers, dict) or not headers:
+            return "{} is not a dictionary of header names and values".format(headers)
+
+        for name, value in headers.items():
+            if not isinstance(name, str) or not name:
+                return "header name {} is not a non-empty string".format(name)
+
+            if not isinstance(value, str):
+                return "{}'s value {} is not a string".format(name, value)
+
+        # Envoy sees header names in lowercase.
+        self.retry_on_response_headers = { name.lower(): value for name, value in headers.items() }
+
+        return None
+
+    def validate_per_try_timeout(self) -> Optional[str]:
+        # People write durations like "500ms", but Envoy only takes them in seconds, so
+        # this keeps the milliseconds and as_dict writes them out the way Envoy wants.
+        per_try_timeout = self.pop('per_try_timeout')
+
+        try:
+            per_try_timeout_ms = int(durationpy.from_str(per_try_timeout).total_seconds() * 1000)
+        except Exception:
+            return "{} is not a duration".format(per_try_timeout)
+
+        if per_try_timeout_ms < 0:
+            return "{} must not be negative".format(per_try_timeout)
+
+        # 0 is what Envoy does without one: each try gets whatever's left of the timeout.
+        if per_try_timeout_ms > 0:
+            self.per_try_timeout_ms = per_try_timeout_ms
+
+        return None
+
+    def validate_retry_back_off(self) -> Optional[str]:
+        back_off = self.retry_back_off
+
+        if not isinstance(back_off, dict):
+            return "{} is not a dictionary".format(back_off)
+
+        base_interval_ms = back_off.get('base_interval_ms', None)
+        max_interval_ms = back_off.get('max_interval_ms', None)
+
+        for key, interval_ms in (('base_interval_ms', base_interval_ms), ('max_interval_ms', max_interval_ms)):
+            if interval_ms is not None and (not isinstance(interval_ms, int) or interval_ms <= 0):
+                return "{} must be a positive integer, not {}".format(key, interval_ms)
+
+        # Envoy requires a base interval. If we only have a max, keep Envoy's default base
+        # (as long as it fits under the max).
+        if base_interval_ms is None:
+            if max_interval_ms is None:
+                return "base_interval_ms or max_interval_ms is required"
+
+            base_interval_ms = 25
+
+        if (max_interval_ms is not None) and (base_interval_ms > max_interval_ms):
+            return "base_interval_ms {} is larger than max_interval_ms {}".format(base_interval_ms, max_interval_ms)
+
+        self.retry_back_off = { 'base_interval_ms': base_interval_ms }
+
+        if max_interval_ms is not None:
+            self.retry_back_off['max_interval_ms'] = max_interval_ms
+
+        return None
+
     def as_dict(self) -> dict:
         raw_dict = super().as_dict()
 
@@ -46,4 +183,57 @@ class IRRetryPolicy (IRResource):
                        "kind", "location", "name", "namespace", "metadata_labels"]:
                 raw_dict.pop(key, None)
 
+        # Envoy takes gRPC status codes, and the reset conditions, as more retry_on conditions.
+        conditions = [ raw_dict['retry_on'] ] if raw_dict.get('retry_on') else []
+        conditions.extend(raw_dict.pop('grpc_retry_on', None) or [])
+
+        retry_non_idempotent = raw_dict.pop('retry_non_idempotent', False)
+
+        if raw_dict.pop('retry_on_reset', False):
+            conditions.extend(c for c in IRRetryPolicy.ResetConditions if c not in conditions)
+
+            # Envoy only has the one list of retriable requests, so this applies to every
+            # condition in the policy, not just the resets.
+            if not retry_non_idempotent:
+                raw_dict['retriable_request_headers'] = [ {
+                    'name': ':method',
+                    'safe_regex_match': {
+                        'google_re2': {},
+                        'regex': '|'.join(IRRetryPolicy.IdempotentMethods)
+                    }
+                } ]
+
+        # Response headers are one more condition, with its own list of what to look for.
+        retry_on_response_headers = raw_dict.pop('retry_on_response_headers', None)
+
+        if retry_on_response_headers:
+            conditions.append('retriable-headers')
+            raw_dict['retriable_headers'] = [
+                { 'name': name, 'exact_match': value } if value else { 'name': name, 'present_match': True }
+                for name, value in sorted(retry_on_response_headers.items())
+            ]
+
+        if conditions:
+            raw_dict['retry_on'] = ",".join(conditions)
+
+        per_try_timeout_ms = raw_dict.pop('per_try_timeout_ms', None)
+
+        if per_try_timeout_ms:
+            raw_dict['per_try_timeout'] = "%0.3fs" % (float(per_try_timeout_ms) / 1000.0)
+
+        retry_back_off = raw_dict.pop('retry_back_off', None)
+
+        if retry_back_off:
+            raw_dict['retry_back_off'] = {
+                'base_interval': "%0.3fs" % (float(retry_back_off['base_interval_ms']) / 1000.0)
+            }
+
+            if 'max_interval_ms' in retry_back_off:
+                raw_dict['retry_back_off']['max_interval'] = "%0.3fs" % (float(retry_back_off['max_interval_ms']) / 1000.0)
+
+        # Envoy's previous_hosts predicate is what steers retries away from hosts that
+        # already failed this request.
+        if raw_dict.pop('retry_other_hosts', False):
+            raw_dict['retry_host_predicate'] = [ { 'name': 'envoy.retry_host_predicates.previous_hosts' } ]
+
         return raw_dict
@@ -84,13 +84,68 @@ class IRServiceResolver(IRResource):
             mapping.post_error('No load_balancer setting is allowed with the KubernetesServiceResolver')
             return False
 
+        self.check_service_reference(ir, mapping)
         return True
 
     @valid_mapping.when("KubernetesEndpointResolver")
     def _k8s_valid_mapping(self, ir: 'IR', mapping: 'IRBaseMapping'):
         # There's no real validation to do here beyond what the Mapping already does.
+        self.check_service_reference(ir, mapping)
         return True
 
+    def check_service_reference(self, ir: 'IR', mapping: 'IRBaseMapping') -> None:
+        """
+        Post a notice if a Mapping's service looks like a Kubernetes Service, but there isn't one.
+        It's only a notice, since the Service may just not have been created yet: the next
+        snapshot after it is will check again. Anything that doesn't clearly name a Service --
+        an IP address, localhost, a hostname outside the cluster -- isn't checked, and neither is
+        anything at all when there are no Kubernetes Services to check against, since then
+        we're not getting them from Kubernetes.
+        """
+
+        if not ir.ambassador_module.get('validate_service_references', True) or not ir.k8s_services:
+            return
+
+        service = mapping.get('service', None)
+
+        if not service:
+            return
+
+        try:
+            parsed = urllib.parse.urlparse(service if '://' in service else f'//{service}')
+            hostname = parsed.hostname
+        except ValueError:
+            # The Mapping will have complained about this already.
+            return
+
+        if not hostname or (hostname == 'localhost') or is_ip_address(hostname):
+            return
+
+        labels = hostname.split('.')
+        in_cluster = False
+
+        if labels[-3:] == [ 'svc', 'cluster', 'local' ]:
+            labels = labels[:-3]
+            in_cluster = True
+        elif labels[-1:] == [ 'svc' ]:
+            labels = labels[:-1]
+            in_cluster = True
+
+        if len(labels) > 2:
+            # Outside the cluster, so DNS is on its own.
+            return
+
+        svc, namespace = self.parse_service(ir, '.'.join(labels), mapping.namespace)
+
+        # "svc.namespace" looks just like "example.com", so without the cluster domain, a
+        # namespace that nothing else is in is taken to be a domain.
+        if (len(labels) == 2) and not in_cluster and \
+           not any(known_namespace == namespace for _, known_namespace in ir.k8s_services):
+            return
+
+        if (svc, namespace) not in ir.k8s_services:
+            ir.aconf.post_notice(f"service {service} matches no Kubernetes Service {svc} in namespace {namespace}; requests will fail until there is one", resource=mapping)
+
     @valid_mapping.when("ConsulResolver")
     def _consul_valid_mapping(self, ir: 'IR', mapping: 'IRBaseMapping'):
         # Mappings using the Consul resolver can't use service names with '.', or port
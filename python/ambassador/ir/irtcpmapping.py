@@ -21,6 +21,7 @@ class IRTCPMapping (IRBaseMapping):
 
     AllowedKeys: ClassVar[Dict[str, bool]] = {
         "address": True,
+        "alpn_protocols": True,
         "circuit_breakers": False,
         "enable_ipv4": True,
         "enable_ipv6": True,
@@ -83,6 +84,21 @@ class IRTCPMapping (IRBaseMapping):
 
         ir.logger.debug("IRTCPMapping %s: self.host = %s", name, self.get("host") or "i'*'")
 
+    def setup(self, ir: 'IR', aconf: Config) -> bool:
+        # The ALPN protocols are part of the group ID, so they have to be sorted out before
+        # the base setup computes it.
+        alpn_protocols = self.get('alpn_protocols', None)
+
+        if alpn_protocols is not None:
+            if (not isinstance(alpn_protocols, list)) or (not alpn_protocols) or \
+               not all(isinstance(proto, str) and proto for proto in alpn_protocols):
+                self.post_error(f"Invalid alpn_protocols {alpn_protocols}: must be a non-empty list of protocol names")
+                return False
+
+            self['alpn_protocols'] = sorted(set(alpn_protocols))
+
+        return super().setup(ir, aconf)
+
     @staticmethod
     def group_class() -> Type[IRBaseMappingGroup]:
         return IRTCPMappingGroup
@@ -108,6 +124,13 @@ class IRTCPMapping (IRBaseMapping):
         host = self.get('host') or '*'
         h.update(host.encode('utf-8'))
 
+        # TCPMappings for different ALPN protocols get different filter chains, so they
+        # can't share a group.
+        alpn_protocols = self.get('alpn_protocols', None)
+
+        if alpn_protocols:
+            h.update(','.join(alpn_protocols).encode('utf-8'))
+
         return h.hexdigest()
 
     def _route_weight(self) -> List[Union[str, int]]:
@@ -20,6 +20,7 @@ if TYPE_CHECKING:
 class IRTCPMappingGroup (IRBaseMappingGroup):
     CoreMappingKeys: ClassVar[Dict[str, bool]] = {
         'address': True,
+        'alpn_protocols': True,
         'circuit_breakers': True,
         'enable_ipv4': True,
         'enable_ipv6': True,
@@ -1,9 +1,9 @@
-from typing import Optional, TYPE_CHECKING
+from typing import Any, Dict, List, Optional, TYPE_CHECKING
 
 from .ircluster import IRCluster
 from .irresource import IRResource
 from ..config import Config
-from ..utils import RichStatus
+from ..utils import RichStatus, parse_bool
 
 if TYPE_CHECKING:
     from .ir import IR # pragma: no cover
@@ -15,8 +15,34 @@ class IRTracing(IRResource):
     driver: str
     driver_config: dict
     tag_headers: list
+    custom_tags: List[Dict[str, Any]]
     host_rewrite: Optional[str]
     sampling: dict
+    propagation: Optional[str]
+    trust_incoming_trace_context: bool
+
+    # The headers that make up each propagation format. B3 can go either as the x-b3-* headers or
+    # as the single b3 header; the tracers we configure only ever write the x-b3-* ones.
+    PropagationHeaders = {
+        'b3': [ 'b3', 'x-b3-traceid', 'x-b3-spanid', 'x-b3-parentspanid', 'x-b3-sampled', 'x-b3-flags' ],
+        'w3c': [ 'traceparent', 'tracestate' ],
+    }
+
+    # ...and the ones the tracer overwrites on the way upstream, for each format it writes.
+    InjectedHeaders = {
+        'b3': [ 'x-b3-traceid', 'x-b3-spanid', 'x-b3-sampled' ],
+        'w3c': [ 'traceparent' ],
+    }
+
+    # Where a custom tag's value can come from, and the keys each source takes.
+    CustomTagSources: Dict[str, List[str]] = {
+        'literal': [ 'value' ],
+        'request_header': [ 'name', 'default_value' ],
+        'environment': [ 'name', 'default_value' ],
+        'metadata': [ 'kind', 'key', 'path', 'default_value' ],
+    }
+
+    CustomTagMetadataKinds = [ 'request', 'route', 'cluster', 'host' ]
 
     def __init__(self, ir: 'IR', aconf: Config,
                  rkey: str = "ir.tracing",
@@ -70,6 +96,36 @@ class IRTracing(IRResource):
         if driver == "datadog":
             driver = "envoy.tracers.datadog"
 
+        # The opencensus driver sends spans to an OpenCensus Agent, over gRPC.
+        if driver == "opencensus":
+            driver = "envoy.tracers.opencensus"
+            grpc = True
+
+        propagation = config.get('propagation', None)
+
+        if propagation is not None:
+            if propagation not in ['b3', 'w3c', 'both']:
+                self.post_error(RichStatus.fromError("propagation must be one of 'b3, w3c, both'"))
+                return False
+
+            if driver in [ 'lightstep', 'envoy.tracers.datadog' ]:
+                self.post_error(RichStatus.fromError("propagation is not supported by the %s driver, which always uses its own headers" % config.get('driver')))
+                return False
+
+            if driver == 'zipkin' and propagation != 'b3':
+                self.post_error(RichStatus.fromError("the zipkin driver only supports b3 propagation; use the opencensus driver for %s" % propagation))
+                return False
+        elif driver == 'zipkin':
+            propagation = 'b3'
+        elif driver == 'envoy.tracers.opencensus':
+            propagation = 'both'
+
+        trust_incoming_trace_context = parse_bool(config.get('trust_incoming_trace_context', True))
+
+        if not trust_incoming_trace_context and driver != 'envoy.tracers.opencensus':
+            self.post_error(RichStatus.fromError("trust_incoming_trace_context: false is only supported by the opencensus driver"))
+            return False
+
         # This "config" is a field on the aconf for the TracingService, not to be confused with the
         # envoyv2 untyped "config" field. We actually use a "typed_config" in the final Envoy
         # config, see envoy/v2/v2tracer.py.
@@ -80,6 +136,13 @@ class IRTracing(IRResource):
                     self.post_error(RichStatus.fromError("collector_endpoint_version must be one of 'HTTP_JSON_V1, HTTP_JSON, HTTP_PROTO'"))
                     return False
 
+        custom_tags = config.get('custom_tags', [])
+        error = IRTracing.check_custom_tags(custom_tags)
+
+        if error:
+            self.post_error(RichStatus.fromError(f"invalid custom_tags: {error}"))
+            return False
+
         # OK, we have a valid config.
         self.sourced_by(config)
 
@@ -89,7 +152,10 @@ class IRTracing(IRResource):
         self.cluster = None
         self.driver_config = driver_config
         self.tag_headers = config.get('tag_headers', [])
+        self.custom_tags = custom_tags
         self.sampling = config.get('sampling', {})
+        self.propagation = propagation
+        self.trust_incoming_trace_context = trust_incoming_trace_context
 
         # XXX host_rewrite actually isn't in the schema right now.
         self.host_rewrite = config.get('host_rewrite', None)
@@ -99,6 +165,103 @@ class IRTracing(IRResource):
 
         return True
 
+    @staticmethod
+    def check_custom_tags(custom_tags: Any) -> Optional[str]:
+        """
+        Return what's wrong with a custom_tags, or None if nothing is.
+        """
+
+        if not isinstance(custom_tags, list):
+            return f"{custom_tags} must be a list"
+
+        seen = set()
+
+        for custom_tag in custom_tags:
+            if not isinstance(custom_tag, dict):
+                return f"{custom_tag} must be a dictionary"
+
+            tag = custom_tag.get('tag', None)
+
+            if not isinstance(tag, str) or not tag:
+                return f"{custom_tag} needs a tag"
+
+            if tag in seen:
+                return f"tag {tag} appears more than once"
+
+            seen.add(tag)
+
+            sources = [ key for key in custom_tag.keys() if key != 'tag' ]
+
+            if (len(sources) != 1) or (sources[0] not in IRTracing.CustomTagSources):
+                return f"tag {tag} needs exactly one of {', '.join(IRTracing.CustomTagSources.keys())}"
+
+            source = sources[0]
+            source_config = custom_tag[source]
+
+            if not isinstance(source_config, dict):
+                return f"tag {tag}: {source} must be a dictionary"
+
+            unknown = set(source_config.keys()) - set(IRTracing.CustomTagSources[source])
+
+            if unknown:
+                return f"tag {tag}: unknown {source} keys {', '.join(sorted(unknown))}"
+
+            for key in ( 'value', 'name', 'key', 'default_value' ):
+                if (key in source_config) and not isinstance(source_config[key], str):
+                    return f"tag {tag}: {source} {key} must be a string"
+
+            if (source == 'literal') and ('value' not in source_config):
+                return f"tag {tag}: literal needs a value"
+
+            if (source in ( 'request_header', 'environment' )) and not source_config.get('name', None):
+                return f"tag {tag}: {source} needs a name"
+
+            if source == 'metadata':
+                if source_config.get('kind', None) not in IRTracing.CustomTagMetadataKinds:
+                    return f"tag {tag}: metadata kind must be one of {', '.join(IRTracing.CustomTagMetadataKinds)}"
+
+                if not source_config.get('key', None):
+                    return f"tag {tag}: metadata needs a key"
+
+                path = source_config.get('path', None)
+
+                if not isinstance(path, list) or not path or not all(isinstance(p, str) and p for p in path):
+                    return f"tag {tag}: metadata path must be a list of at least one key"
+
+        return None
+
+    def propagation_formats(self) -> List[str]:
+        """
+        The trace context formats the tracer reads from clients and writes to upstreams, if this
+        driver lets us choose.
+        """
+
+        if not self.propagation:
+            return []
+
+        if self.propagation == 'both':
+            return [ 'w3c', 'b3' ]
+
+        return [ self.propagation ]
+
+    def untrusted_trace_headers(self) -> List[str]:
+        """
+        The trace headers to strip from requests before they go upstream, because the client
+        isn't trusted to send them and the tracer won't overwrite them.
+        """
+
+        if self.trust_incoming_trace_context:
+            return []
+
+        formats = self.propagation_formats()
+        headers: List[str] = []
+
+        for fmt, fmt_headers in IRTracing.PropagationHeaders.items():
+            injected = IRTracing.InjectedHeaders[fmt] if fmt in formats else []
+            headers.extend([ hdr for hdr in fmt_headers if hdr not in injected ])
+
+        return headers
+
     def add_mappings(self, ir: 'IR', aconf: Config):
         cluster = ir.add_cluster(
             IRCluster(
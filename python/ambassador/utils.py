@@ -510,7 +510,7 @@ class SecretInfo:
     """
     def __init__(self, name: str, namespace: str, secret_type: str,
                  tls_crt: Optional[str]=None, tls_key: Optional[str]=None, user_key: Optional[str]=None,
-                 root_crt: Optional[str]=None, decode_b64=True) -> None:
+                 root_crt: Optional[str]=None, decode_b64=True, ocsp_staple: Optional[str]=None) -> None:
         self.name = name
         self.namespace = namespace
         self.secret_type = secret_type
@@ -537,6 +537,9 @@ class SecretInfo:
         self.user_key = user_key
         self.root_crt = root_crt
 
+        # An OCSP response is DER, not PEM, so it stays base64-encoded until it's written out.
+        self.ocsp_staple = ocsp_staple
+
     @staticmethod
     def is_decodable(b64_pem: Optional[str]) -> bool:
         if not b64_pem:
@@ -603,7 +606,8 @@ class SecretInfo:
             'tls_crt': self.fingerprint(self.tls_crt),
             'tls_key': self.fingerprint(self.tls_key),
             'user_key': self.fingerprint(self.user_key),
-            'root_crt': self.fingerprint(self.root_crt)
+            'root_crt': self.fingerprint(self.root_crt),
+            'ocsp_staple': self.fingerprint(self.ocsp_staple)
         }
 
     @classmethod
@@ -631,7 +635,8 @@ class SecretInfo:
             tls_crt,
             tls_key,
             aconf_object.get('user_key', None),
-            aconf_object.get('root-cert_pem', None)
+            aconf_object.get('root-cert_pem', None),
+            ocsp_staple=aconf_object.get('tls_ocsp-staple', None)
         )
 
     @classmethod
@@ -660,6 +665,7 @@ class SecretInfo:
         tls_crt = None
         tls_key = None
         user_key = None
+        ocsp_staple = None
 
         if not cert_data:
             resource.ir.logger.error(f"{resource.kind} {resource.name}: found no certificate in {source}?")
@@ -676,6 +682,7 @@ class SecretInfo:
                 return None
 
             tls_key = cert_data.get('tls.key', None)
+            ocsp_staple = cert_data.get('tls.ocsp-staple', None)
         elif secret_type == 'Opaque':
             user_key = cert_data.get('user.key', None)
 
@@ -688,7 +695,8 @@ class SecretInfo:
         elif secret_type == 'istio.io/key-and-cert':
             resource.ir.logger.error(f"{resource.kind} {resource.name}: found data but handler for istio key not finished yet")
 
-        return SecretInfo(secret_name, namespace, secret_type, tls_crt=tls_crt, tls_key=tls_key, user_key=user_key)
+        return SecretInfo(secret_name, namespace, secret_type, tls_crt=tls_crt, tls_key=tls_key, user_key=user_key,
+                          ocsp_staple=ocsp_staple)
 
 
 class SavedSecret:
@@ -703,7 +711,7 @@ class SavedSecret:
     def __init__(self, secret_name: str, namespace: str,
                  cert_path: Optional[str], key_path: Optional[str],
                  user_path: Optional[str], root_cert_path: Optional[str],
-                 cert_data: Optional[Dict]) -> None:
+                 cert_data: Optional[Dict], ocsp_path: Optional[str]=None) -> None:
         self.secret_name = secret_name
         self.namespace = namespace
         self.cert_path = cert_path
@@ -711,6 +719,7 @@ class SavedSecret:
         self.user_path = user_path
         self.root_cert_path = root_cert_path
         self.cert_data = cert_data
+        self.ocsp_path = ocsp_path
 
     @property
     def name(self) -> str:
@@ -819,7 +828,39 @@ class SecretHandler:
         user_key = secret_info.user_key
         root_crt = secret_info.root_crt
 
-        return self.cache_internal(name, namespace, tls_crt, tls_key, user_key, root_crt)
+        ss = self.cache_internal(name, namespace, tls_crt, tls_key, user_key, root_crt)
+
+        if ss and ss.cert_path and secret_info.ocsp_staple:
+            self.cache_ocsp_staple(ss, secret_info.ocsp_staple)
+
+        return ss
+
+    def cache_ocsp_staple(self, ss: SavedSecret, ocsp_staple: str) -> None:
+        """
+        cache_ocsp_staple: save a secret's OCSP response next to its certificate, and tell the
+        SavedSecret where it went. Envoy wants the DER, so this is the one element we decode
+        and write as binary.
+
+        :param ss: SavedSecret for the certificate the response is for
+        :param ocsp_staple: base64-encoded DER OCSP response
+        """
+
+        try:
+            der = binascii.a2b_base64(ocsp_staple)
+        except binascii.Error:
+            self.logger.error(f"secret {ss.secret_name}.{ss.namespace}: could not decode tls.ocsp-staple, ignoring it")
+            return
+
+        hd = hashlib.sha1(der).hexdigest().upper()
+        secret_dir = os.path.join(self.cache_dir, ss.namespace, "secrets-decoded", ss.secret_name)
+
+        try:
+            os.makedirs(secret_dir)
+        except FileExistsError:
+            pass
+
+        ss.ocsp_path = os.path.join(secret_dir, f'{hd}.ocsp')
+        open(ss.ocsp_path, "wb").write(der)
 
     def cache_internal(self, name: str, namespace: str,
                        tls_crt: Optional[str], tls_key: Optional[str],
@@ -38,7 +38,7 @@ import requests
 import jsonpatch
 
 from expiringdict import ExpiringDict
-from prometheus_client import CollectorRegistry, ProcessCollector, generate_latest, Info, Gauge
+from prometheus_client import CollectorRegistry, ProcessCollector, generate_latest, Info, Gauge, Counter, Histogram
 from pythonjsonlogger import jsonlogger
 
 import concurrent.futures
@@ -237,6 +237,22 @@ class DiagApp (Flask):
                                  ["level"],
                                  namespace='ambassador', registry=self.metrics_registry)
 
+        # A failed reconfiguration leaves Envoy running the last good config, so from the
+        # outside it looks just like nothing changed. Count the outcomes so that it doesn't.
+        self.reconfig_outcomes = Counter('reconfigurations', 'Number of reconfigurations, by outcome',
+                                         ["outcome"],
+                                         namespace='ambassador', registry=self.metrics_registry)
+        self.reconfig_outcomes.labels('success')
+        self.reconfig_outcomes.labels('failure')
+        self.reconfig_duration = Histogram('reconfiguration_duration_seconds', 'Time taken by reconfigurations, whatever the outcome',
+                                           buckets=(0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0, 30.0, 60.0),
+                                           namespace='ambassador', registry=self.metrics_registry)
+        self.config_generation = Gauge('config_generation', 'Snapshot generation of the configuration Envoy is running',
+                                       namespace='ambassador', registry=self.metrics_registry)
+        self.certificate_expiry = Gauge('certificate_expiry_timestamp_seconds', 'When the certificate in each TLS secret in use expires',
+                                        ["secret", "namespace"],
+                                        namespace='ambassador', registry=self.metrics_registry)
+
         if debug:
             self.logger.setLevel(logging.DEBUG)
             self.diag_log_level.labels('debug').set(1)
@@ -398,6 +414,23 @@ class DiagApp (Flask):
     def check_scout(self, what: str) -> None:
         self.watcher.post("SCOUT", (what, self.ir))
 
+    def note_reconfiguration(self, success: bool) -> None:
+        # Record the outcome of the reconfiguration that config_timer is timing. Only a
+        # successful one changes what Envoy is running, so only it moves the generation.
+        self.reconfig_outcomes.labels('success' if success else 'failure').inc()
+        self.reconfig_duration.observe(max(time.perf_counter() - self.config_timer.starttime, 0.0))
+
+        if success and self.aconf and (self.aconf.snapshot_generation is not None):
+            self.config_generation.set(self.aconf.snapshot_generation)
+
+        if success and self.aconf:
+            # Secrets come and go, so start over rather than leave stale ones behind.
+            self.certificate_expiry.clear()
+
+            for cert in self.aconf.certificates:
+                if cert.get('notAfter'):
+                    self.certificate_expiry.labels(cert.get('name'), cert.get('namespace')).set(cert['notAfter'])
+
     def post_timer_event(self) -> None:
         # Post an event to do a timer check.
         self.watcher.post("TIMER", None)
@@ -1356,6 +1389,7 @@ class AmbassadorEventWatcher(threading.Thread):
                 except Exception as e:
                     self.logger.error("could not reconfigure: %s" % e)
                     self.logger.exception(e)
+                    self.reconfiguration_failed()
                     self._respond(rqueue, 500, 'configuration from filesystem failed')
             elif cmd == 'CONFIG':
                 version, url = arg
@@ -1368,6 +1402,7 @@ class AmbassadorEventWatcher(threading.Thread):
                 except Exception as e:
                     self.logger.error("could not reconfigure: %s" % e)
                     self.logger.exception(e)
+                    self.reconfiguration_failed()
                     self._respond(rqueue, 500, 'configuration failed')
             elif cmd == 'SCOUT':
                 try:
@@ -1388,6 +1423,13 @@ class AmbassadorEventWatcher(threading.Thread):
                 self.logger.error(f"unknown event type: '{cmd}' '{arg}'")
                 self._respond(rqueue, 400, f"unknown event type '{cmd}' '{arg}'")
 
+    def reconfiguration_failed(self) -> None:
+        # If the reconfiguration timer is still running, the exception happened before the
+        # new config was in place, so Envoy is still running the last good one.
+        if self.app.config_timer.running:
+            self.app.note_reconfiguration(False)
+            self.app.config_timer.stop()
+
     def _respond(self, rqueue: queue.Queue, status: int, info='') -> None:
         # self.logger.debug("responding to query with %s %s" % (status, info))
         rqueue.put((status, info))
@@ -1587,6 +1629,7 @@ class AmbassadorEventWatcher(threading.Thread):
             self.check_scout("attempted bad update")
 
             # DO stop the reconfiguration timer before leaving.
+            self.app.note_reconfiguration(False)
             self.app.config_timer.stop()
             self._respond(rqueue, 500, 'ignoring (%s) in snapshot %s' % (econf_bad_reason, snapshot))
             return
@@ -1649,8 +1692,14 @@ class AmbassadorEventWatcher(threading.Thread):
             app.diag = None
 
         # We're finally done with the whole configuration process.
+        self.app.note_reconfiguration(True)
         self.app.config_timer.stop()
 
+        # Hand the Ambassador module's Envoy log levels to the stats manager, which pushes
+        # them out if they changed. Envoy may not be running yet, so this happens the next
+        # time we update Envoy stats.
+        app.estatsmgr.set_log_levels(ir.ambassador_module.envoy_log_levels())
+
         if app.kick:
             self.logger.debug("running '%s'" % app.kick)
             os.system(app.kick)
@@ -153,3 +153,82 @@ spec:
     require_errors(r2["ir"], [
         ( "ambassador.default.1", "'set_current_client_cert_details' value for key 'subject' may only be 'true' or 'false', not 'invalid'")
     ])
+
+@pytest.mark.compilertest
+def test_valid_envoy_log_levels():
+    yaml = """
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    envoy_log_level: info
+    envoy_component_log_levels:
+      upstream: trace
+      connection: debug
+"""
+
+    cache = Cache(logger)
+    r1 = Compile(logger, yaml, k8s=True)
+    r2 = Compile(logger, yaml, k8s=True, cache=cache)
+
+    require_no_errors(r1["ir"])
+    require_no_errors(r2["ir"])
+
+    for r in [ r1, r2 ]:
+        assert r["ir"].ambassador_module.envoy_log_levels() == [ "info", "connection:debug", "upstream:trace" ]
+
+@pytest.mark.compilertest
+def test_invalid_envoy_log_level():
+    yaml = """
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    envoy_component_log_levels:
+      connection: chatty
+"""
+
+    cache = Cache(logger)
+    r1 = Compile(logger, yaml, k8s=True)
+    r2 = Compile(logger, yaml, k8s=True, cache=cache)
+
+    require_errors(r1["ir"], [
+        ( "ambassador.default.1", "Invalid envoy_component_log_levels level for connection: chatty. Supported: trace, debug, info, warning, warn, error, critical, off")
+    ])
+    require_errors(r2["ir"], [
+        ( "ambassador.default.1", "Invalid envoy_component_log_levels level for connection: chatty. Supported: trace, debug, info, warning, warn, error, critical, off")
+    ])
+
+@pytest.mark.compilertest
+def test_module_ring_hash_needs_hash_source():
+    yaml = """
+---
+apiVersion: getambassador.io/v3alpha1
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    load_balancer:
+      policy: ring_hash
+"""
+
+    cache = Cache(logger)
+    r1 = Compile(logger, yaml, k8s=True)
+    r2 = Compile(logger, yaml, k8s=True, cache=cache)
+
+    require_errors(r1["ir"], [
+        ( "ambassador.default.1", "Invalid load_balancer specified: policy ring_hash requires a cookie, header, or source_ip to hash on")
+    ])
+    require_errors(r2["ir"], [
+        ( "ambassador.default.1", "Invalid load_balancer specified: policy ring_hash requires a cookie, header, or source_ip to hash on")
+    ])
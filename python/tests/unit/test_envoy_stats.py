@@ -83,6 +83,78 @@ def test_levels():
         'warning': [ 'lua' ]
     }
 
+def test_pushed_levels():
+    mocker = EnvoyStatsMocker()
+    requested = []
+
+    def fetch_log_levels(level: Optional[str]) -> Optional[str]:
+        requested.append(level)
+        return "active loggers:\n  upstream: info\n"
+
+    esm = EnvoyStatsMgr(logger,
+                        fetch_log_levels=fetch_log_levels,
+                        fetch_envoy_stats=mocker.fetch_envoy_stats)
+
+    esm.set_log_levels([ 'info', 'connection:debug' ])
+
+    # The first update pushes both levels, in order, then fetches the current state
+    # as usual.
+    esm.update()
+    assert requested == [ 'info', 'connection:debug', None ]
+    assert esm.pushed_log_levels == [ 'info', 'connection:debug' ]
+
+    # Once pushed, the levels aren't pushed again, even if they're set again.
+    requested.clear()
+    esm.set_log_levels([ 'info', 'connection:debug' ])
+    esm.update()
+    assert requested == [ None ]
+
+    # Without an overall level, the default comes first, so that the component we
+    # set before goes back to it.
+    requested.clear()
+    esm.set_log_levels([ 'upstream:trace' ])
+    esm.update()
+    assert requested == [ esm.default_log_level, 'upstream:trace', None ]
+
+    # Clearing the levels puts Envoy back to its default.
+    requested.clear()
+    esm.set_log_levels([])
+    esm.update()
+    assert requested == [ esm.default_log_level, None ]
+    assert esm.pushed_log_levels == []
+
+def test_pushed_levels_after_restart():
+    requested = []
+    uptimes = [ 100, 200, 5, 10 ]
+
+    def fetch_log_levels(level: Optional[str]) -> Optional[str]:
+        requested.append(level)
+        return "active loggers:\n  upstream: info\n"
+
+    def fetch_envoy_stats() -> Optional[str]:
+        return "server.uptime: %d\n" % uptimes.pop(0)
+
+    esm = EnvoyStatsMgr(logger,
+                        fetch_log_levels=fetch_log_levels,
+                        fetch_envoy_stats=fetch_envoy_stats)
+
+    esm.set_log_levels([ 'info' ])
+    esm.update()
+    assert requested == [ 'info', None ]
+
+    requested.clear()
+    esm.update()
+    assert requested == [ None ]
+
+    # Envoy's uptime went down, so it restarted and lost the level we pushed: the
+    # next update pushes it again.
+    esm.update()
+    assert esm.pushed_log_levels == []
+
+    requested.clear()
+    esm.update()
+    assert requested == [ 'info', None ]
+
 
 def test_stats():
     mocker = EnvoyStatsMocker()
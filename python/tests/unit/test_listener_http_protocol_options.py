@@ -50,3 +50,18 @@ def test_allow_chunked_length_false():
 def test_allow_chunked_length_true():
     yaml = module_and_mapping_manifests(["allow_chunked_length: true"], [])
     _test_listener_http_protocol_options(yaml, expectations={'allow_chunked_length': True}, envoy_version="V3")
+
+@pytest.mark.compilertest
+def test_request_smuggling_protection_default():
+    yaml = module_and_mapping_manifests([], [])
+    _test_listener_http_protocol_options(yaml, expectations={'allow_chunked_length': None, 'override_stream_error_on_invalid_http_message': None}, envoy_version="V3")
+
+@pytest.mark.compilertest
+def test_request_smuggling_protection_legacy():
+    yaml = module_and_mapping_manifests(["request_smuggling_protection: legacy"], [])
+    _test_listener_http_protocol_options(yaml, expectations={'allow_chunked_length': True, 'override_stream_error_on_invalid_http_message': True}, envoy_version="V3")
+
+@pytest.mark.compilertest
+def test_request_smuggling_protection_legacy_allow_chunked_length_false():
+    yaml = module_and_mapping_manifests(["request_smuggling_protection: legacy", "allow_chunked_length: false"], [])
+    _test_listener_http_protocol_options(yaml, expectations={'allow_chunked_length': False, 'override_stream_error_on_invalid_http_message': True}, envoy_version="V3")